@@ -2,30 +2,45 @@ package mywaf
 
 import (
 	"context"
-	"fmt"
+	"log/slog"
 	"os"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/wafv2"
 	"github.com/aws/aws-sdk-go-v2/service/wafv2/types"
+	common "github.com/htstinson/stinsondataapi/api/commonweb"
+	"github.com/htstinson/stinsondataapi/api/internal/metrics"
 )
 
 /*
 Block(ipSetName string, addIP string, removeIP string, region string)
 */
-func Block(ipSetName string, addIP string, removeIP string, region string) error {
+func Block(ipSetName string, addIP string, removeIP string, region string) (err error) {
+	operation := "sync"
+	switch {
+	case addIP != "" && removeIP != "":
+		operation = "replace"
+	case addIP != "":
+		operation = "add"
+	case removeIP != "":
+		operation = "remove"
+	}
+	outcome := "unchanged"
+	defer func() {
+		if err != nil {
+			outcome = "error"
+		}
+		metrics.WAFSync(ipSetName, operation, outcome)
+	}()
+
 	// Convert string to proper Scope type
 
 	var scope types.Scope = types.ScopeRegional
 
 	// Load AWS configuration - will use EC2 instance role credentials automatically
-	cfg, err := config.LoadDefaultConfig(
-		context.TODO(),
-		config.WithRegion(region),
-	)
+	cfg, err := common.LoadAWSConfig(context.TODO(), region)
 	if err != nil {
-		fmt.Printf("failed to load AWS config: %v", err)
+		slog.Error("failed to load AWS config", "error", err)
 		return err
 	}
 
@@ -41,7 +56,7 @@ func Block(ipSetName string, addIP string, removeIP string, region string) error
 
 	listResult, err := client.ListIPSets(context.TODO(), listInput)
 	if err != nil {
-		fmt.Printf("failed to list IP sets: %v", err)
+		slog.Error("failed to list IP sets", "error", err)
 	}
 
 	// Find the target IP set ID
@@ -66,7 +81,7 @@ func Block(ipSetName string, addIP string, removeIP string, region string) error
 
 	getResult, err := client.GetIPSet(context.TODO(), getInput)
 	if err != nil {
-		fmt.Printf("failed to get IP set details: %v", err)
+		slog.Error("failed to get IP set details", "ip_set", ipSetName, "error", err)
 	}
 
 	// Save the current lock token for updates
@@ -98,13 +113,12 @@ func Block(ipSetName string, addIP string, removeIP string, region string) error
 
 	// Remove IP address if specified
 	if removeIP != "" {
-		fmt.Println("mywaf main.go Block removeIP")
 		for i, addr := range addresses {
 			if addr == removeIP {
 				// Remove the IP by replacing it with the last element and truncating
 				addresses[i] = addresses[len(addresses)-1]
 				addresses = addresses[:len(addresses)-1]
-				fmt.Printf("Removing IP: %s\n", removeIP)
+				slog.Info("removing ip from WAF ip set", "ip_set", ipSetName, "ip", removeIP)
 				needsUpdate = true
 				break
 			}
@@ -123,22 +137,25 @@ func Block(ipSetName string, addIP string, removeIP string, region string) error
 
 		_, err = client.UpdateIPSet(context.TODO(), updateInput)
 		if err != nil {
-			fmt.Printf("failed to update IP set: %v\n", err)
+			slog.Error("failed to update IP set", "ip_set", ipSetName, "error", err)
+			outcome = "error"
+		} else {
+			outcome = "updated"
 		}
 		time.Sleep(500 * time.Millisecond)
-		fmt.Printf("[%v] [waf][Block] %s Adding IP\n", time.Now().Format(time.RFC3339), addIP)
+		if addIP != "" {
+			slog.Info("added ip to WAF ip set", "ip_set", ipSetName, "ip", addIP)
+		}
 
 		// Refresh IP set details after update
 		_, err = client.GetIPSet(context.TODO(), getInput)
 		if err != nil {
-			fmt.Printf("failed to get updated IP set details: %v\n", err)
+			slog.Error("failed to get updated IP set details", "ip_set", ipSetName, "error", err)
 		}
 		return err
 	}
 
-	if false {
-		fmt.Printf("ARN: %s\n", ipSetARN)
-	}
+	slog.Debug("WAF ip set", "ip_set", ipSetName, "id", ipSetId, "arn", ipSetARN)
 
 	// Output results
 	/*
@@ -157,7 +174,7 @@ func Block(ipSetName string, addIP string, removeIP string, region string) error
 	outputFile := ipSetName + "-ips.txt"
 	f, err := os.Create(outputFile)
 	if err != nil {
-		fmt.Printf("failed to create output file: %v", err)
+		slog.Error("failed to create output file", "file", outputFile, "error", err)
 	}
 	defer f.Close()
 
@@ -1,20 +1,35 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"net"
 
 	common "github.com/htstinson/stinsondataapi/api/commonweb"
 	"github.com/htstinson/stinsondataapi/api/internal/auth"
+	"github.com/htstinson/stinsondataapi/api/internal/certs"
+	"github.com/htstinson/stinsondataapi/api/internal/config"
 	"github.com/htstinson/stinsondataapi/api/internal/handler"
+	"github.com/htstinson/stinsondataapi/api/internal/health"
+	"github.com/htstinson/stinsondataapi/api/internal/logging"
+	"github.com/htstinson/stinsondataapi/api/internal/mail"
+	"github.com/htstinson/stinsondataapi/api/internal/mailbox"
+	"github.com/htstinson/stinsondataapi/api/internal/metrics"
 	"github.com/htstinson/stinsondataapi/api/internal/middleware"
 	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/htstinson/stinsondataapi/api/internal/oidc"
+	"github.com/htstinson/stinsondataapi/api/internal/openapi"
+	"github.com/htstinson/stinsondataapi/api/internal/password"
+	"github.com/htstinson/stinsondataapi/api/internal/secrets"
+	"github.com/htstinson/stinsondataapi/api/internal/totp"
+	"github.com/htstinson/stinsondataapi/api/internal/tracing"
 	"github.com/htstinson/stinsondataapi/api/pkg/database"
 
 	//searcher "github.com/htstinson/business_searcher"
 
 	"context"
 	"encoding/json"
-	"log"
+	"log/slog"
 	"mime"
 	"net/http"
 	"os"
@@ -25,6 +40,9 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
 )
 
 func init() {
@@ -36,225 +54,345 @@ func init() {
 
 func main() {
 
-	fmt.Printf("\n[%v] ---------------------------------------------------------------\n", time.Now().Format(time.RFC3339))
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML or TOML config file")
+	printConfig := flag.Bool("print-config", false, "print the effective config, secrets redacted, and exit")
+	flag.Parse()
 
-	log.SetOutput(os.Stdout)
+	// Defaults, then the config file, then the environment; secrets are resolved last
+	cfg, err := config.Load(context.Background(), *configFile)
+	if err != nil {
+		slog.Error("invalid configuration", "error", err)
+		os.Exit(1)
+	}
+
+	if *printConfig {
+		out, err := cfg.Redacted().YAML()
+		if err != nil {
+			slog.Error("config error", "error", err)
+			os.Exit(1)
+		}
+		os.Stdout.Write(out)
+		return
+	}
+
+	// Structured logging, configured with log.level (debug, info, warn, error) and log.format (json, text)
+	logger := logging.New(os.Stdout, logging.ParseLevel(cfg.Log.Level), cfg.Log.Format)
+	slog.SetDefault(logger)
+
+	logger.Info("starting", "environment", cfg.Environment)
+	logger.Info("effective config", "config", cfg.Redacted())
+
+	// Tracing, exported according to tracing.exporter (otlp, stdout or none)
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing.ServiceName, cfg.Tracing.Exporter)
+	if err != nil {
+		logger.Error("tracing error", "error", err)
+		return
+	}
+	defer shutdownTracing(context.Background())
 
 	//	fmt.Printf("[%v] [main] Initializing SalesForce.com connection.\n", time.Now().Format(time.RFC3339))
 
-	//	sf, err := salesforce.New()
+	//	sf, err := salesforce.New(context.Background(), cfg.SecretProvider())
 	//	if err != nil {
 	//		fmt.Printf("[%v] [main] SalesForce error: %s.\n", time.Now().Format(time.RFC3339), err.Error())
 	//		return
 	//	}
 
-	fmt.Printf("[%v] [main] Initializing RDS database.\n", time.Now().Format(time.RFC3339))
-	var RDSLogin = &model.RDSLogin{}
-	rdsLogin, err := common.GetSecretString("RDS/apidb", "us-west-2")
+	logger.Info("initializing RDS database")
+
+	// Initialize database. The credentials are read for every new connection
+	// so that a rotated password is used once old connections are recycled.
+	dbConfig := database.Config{
+		Search_Path: cfg.Database.SearchPath,
+		DBName:      cfg.Database.Name,
+		SSLMode:     cfg.Database.SSLMode,
+		Credentials: func(ctx context.Context) (database.Config, error) {
+			raw, err := cfg.Secret(ctx, "database.credentials")
+			if err != nil {
+				return database.Config{}, err
+			}
+			var RDSLogin model.RDSLogin
+			if err := json.Unmarshal([]byte(raw), &RDSLogin); err != nil {
+				return database.Config{}, fmt.Errorf("invalid database credentials: %w", err)
+			}
+			return database.Config{
+				Host:     RDSLogin.Host,
+				Port:     RDSLogin.Port,
+				User:     RDSLogin.Username,
+				Password: RDSLogin.Password,
+			}, nil
+		},
+	}
+
+	db, err := database.New(dbConfig)
 	if err != nil {
-		fmt.Printf("[%v] [main] RDS error: %s.\n", time.Now().Format(time.RFC3339), err.Error())
+		logger.Error("failed to connect to RDS database", "error", err)
 		return
 	}
-	json.Unmarshal(rdsLogin, RDSLogin)
+	db = database.WithAudit(database.WithTracing(db))
+	defer db.Close()
+	logger.Info("connected to RDS database")
+	metrics.RegisterDB(db, cfg.Database.Name)
+
+	// Initialize auth
+	authConfig := auth.Config{
+		SecretKey:     cfg.Auth.JWTSecret,
+		TokenDuration: cfg.Auth.TokenDuration,
+		MFADuration:   cfg.Auth.MFA.ChallengeTTL,
+	}
 
-	// Initialize database
-	config := database.Config{
-		Host:        RDSLogin.Host,
-		Port:        RDSLogin.Port,
-		User:        RDSLogin.Username,
-		Password:    RDSLogin.Password,
-		Search_Path: "common",
-		DBName:      "apidb",
-		SSLMode:     "require",
+	jwtAuth := auth.New(authConfig)
+	// Create handler with auth and SFauth
+	h := handler.NewHandler(db, *jwtAuth, logger, cfg.SecretProvider())
+
+	// Password policy, with the breached list loaded once at startup
+	passwordPolicy, err := password.NewPolicy(cfg.Auth.Password.MinLength, cfg.Auth.Password.BreachedList)
+	if err != nil {
+		logger.Error("password policy error", "error", err)
+		return
 	}
+	logger.Info("password policy", "min_length", passwordPolicy.MinLength, "breached_passwords", passwordPolicy.BreachedCount())
+	h.SetPasswords(handler.PasswordOptions{
+		Policy:   passwordPolicy,
+		Lockout:  cfg.Auth.Lockout.Options(),
+		ResetTTL: cfg.Auth.Password.ResetTTL,
+		ResetURL: cfg.Auth.Password.ResetURL,
+	})
 
-	db, err := database.New(config)
+	// TOTP secrets are sealed with their own key, or one derived from the JWT secret
+	mfaKey := cfg.Auth.MFA.Key
+	if mfaKey == "" {
+		mfaKey = cfg.Auth.JWTSecret
+	}
+	mfaCipher, err := totp.NewCipher(mfaKey)
 	if err != nil {
-		fmt.Printf("[%v] [main] Failed to connect to RDS database: %s.\n", time.Now().Format(time.RFC3339), err.Error())
+		logger.Error("mfa cipher error", "error", err)
 		return
 	}
-	defer db.Close()
-	fmt.Printf("[%v] [main] Connected to RDS database.\n", time.Now().Format(time.RFC3339))
+	h.SetMFA(handler.MFAOptions{Cipher: mfaCipher, Issuer: cfg.Auth.MFA.Issuer})
 
-	// Initialize auth
-	authConfig := auth.Config{
-		SecretKey:     os.Getenv("JWT_SECRET_KEY"), // Use environment variable
-		TokenDuration: 24 * time.Hour,              // Token valid for 24 hours
+	h.SetInvitations(handler.InvitationOptions{
+		TTL: cfg.Auth.Invitations.TTL,
+		URL: cfg.Auth.Invitations.URL,
+	})
+	h.SetRegistration(handler.RegistrationOptions{
+		Mode:         cfg.Auth.Registration.Mode,
+		VerifyTTL:    cfg.Auth.Registration.VerifyTTL,
+		VerifyURL:    cfg.Auth.Registration.VerifyURL,
+		CleanupAfter: cfg.Auth.Registration.CleanupAfter,
+	})
+
+	// API keys are accepted alongside bearer tokens
+	h.SetAPIKeys(handler.APIKeyOptions{
+		DefaultTTL: cfg.Auth.APIKeys.DefaultTTL,
+		MaxTTL:     cfg.Auth.APIKeys.MaxTTL,
+	})
+	jwtAuth.SetAPIKeys(h)
+
+	// Single sign-on through the subscribers' OpenID providers
+	h.SetSSO(handler.SSOOptions{
+		Client:      oidc.NewClient(&http.Client{Timeout: 10 * time.Second, Transport: otelhttp.NewTransport(http.DefaultTransport)}),
+		RedirectURL: cfg.Auth.OIDC.RedirectURL,
+		FrontendURL: cfg.Auth.OIDC.FrontendURL,
+		StateTTL:    cfg.Auth.OIDC.StateTTL,
+	})
+
+	// Resolve client addresses, trusting X-Forwarded-For only from our own proxies
+	trustedProxies := middleware.DefaultTrustedProxies
+	if len(cfg.TrustedProxies) > 0 {
+		trustedProxies = cfg.TrustedProxies
+	}
+	ipResolver, err := middleware.NewClientIPResolver(trustedProxies)
+	if err != nil {
+		logger.Error("trusted proxies error", "error", err)
+		return
 	}
 
-	if authConfig.SecretKey == "" {
-		authConfig.SecretKey = "your-secret-key-for-development" // Default for development
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	// Refetch cached secrets so that rotations are noticed between reads
+	if cfg.Secrets.TTL > 0 {
+		cfg.SecretProvider().Start(backgroundCtx, cfg.Secrets.TTL)
 	}
 
-	jwtAuth := auth.New(authConfig)
-	// Create handler with auth and SFauth
-	h := handler.NewHandler(db, *jwtAuth, &log.Logger{})
+	// Trusted ranges that nothing may block
+	allowList := middleware.NewAllowList(db)
+	if err := allowList.Refresh(context.Background()); err != nil {
+		logger.Error("allow list error", "error", err)
+	}
+	allowList.Start(backgroundCtx, time.Minute)
+	h.SetAllowList(allowList)
+
+	// Enforce the blocked table in process as well as in the WAF
+	blockList := middleware.NewBlockList(db, ipResolver)
+	blockList.SetAllowList(allowList)
+	if err := blockList.Refresh(context.Background()); err != nil {
+		logger.Error("block list error", "error", err)
+	}
+	blockList.Start(backgroundCtx, time.Minute)
+	h.SetBlockList(blockList)
+
+	// Send queued email, retrying failures
+	mailer, err := mail.New(context.Background(), cfg.Email.Options(cfg.Region), cfg.SecretProvider())
+	if err != nil {
+		logger.Error("email provider error", "error", err)
+		return
+	}
+	mail.NewSender(db, mailer, mail.SenderOptions{
+		From:        cfg.Email.From,
+		MaxAttempts: cfg.Email.MaxAttempts,
+	}).Start(backgroundCtx, cfg.Email.Interval)
+	h.SetEmail(handler.EmailOptions{UnsubscribeURL: cfg.Email.UnsubscribeURL})
+
+	// Notify users as their preferences say, or as configured until they set them
+	notificationDefaults := handler.DefaultNotifications
+	notificationDefaults.Login_Alerts.Email = cfg.Email.LoginNotices
+	h.SetNotifications(handler.NotificationOptions{Defaults: notificationDefaults})
+	h.StartDigests(backgroundCtx, time.Hour)
+
+	// Read mentions from the Gmail labels of subscribers' mailbox sources
+	if cfg.Mailbox.Interval > 0 {
+		gmailService := func(ctx context.Context) (*gmail.Service, error) {
+			return common.GmailService(ctx, cfg.SecretProvider(), gmail.GmailReadonlyScope)
+		}
+		if cfg.Mailbox.Endpoint != "" {
+			gmailService = func(ctx context.Context) (*gmail.Service, error) {
+				return gmail.NewService(ctx, option.WithEndpoint(cfg.Mailbox.Endpoint), option.WithoutAuthentication())
+			}
+		}
+		mailbox.NewPoller(db, gmailService, mailbox.Options{
+			Lookback: cfg.Mailbox.Lookback,
+			Notify:   h.NotifyMailboxResults,
+		}).Start(backgroundCtx, cfg.Mailbox.Interval)
+	}
+
+	// Delete abandoned registrations
+	h.StartRegistrationCleanup(backgroundCtx, time.Hour)
+
+	// Rate limit policies per route group
+	apiLimiter, err := newRateLimiter("api", cfg.RateLimits.API, ipResolver)
+	if err != nil {
+		logger.Error("rate limit error", "error", err)
+		return
+	}
+	authLimiter, err := newRateLimiter("auth", cfg.RateLimits.Auth, ipResolver)
+	if err != nil {
+		logger.Error("rate limit error", "error", err)
+		return
+	}
+	protectedLimiter, err := newRateLimiter("protected", cfg.RateLimits.Protected, ipResolver)
+	if err != nil {
+		logger.Error("rate limit error", "error", err)
+		return
+	}
+
+	// CORS policies per route group
+	publicCORS, err := middleware.NewCORSPolicy(cfg.CORS.Public.Options())
+	if err != nil {
+		logger.Error("CORS error", "error", err)
+		return
+	}
+	protectedCORS, err := middleware.NewCORSPolicy(cfg.CORS.Protected.Options())
+	if err != nil {
+		logger.Error("CORS error", "error", err)
+		return
+	}
+
+	// Liveness and readiness probes
+	credentialsSecret, _ := cfg.SecretName("database.credentials")
+	checker := newHealthChecker(db, allowList, blockList, cfg.SecretProvider(), credentialsSecret)
 
 	// Create router and handler
 	router := mux.NewRouter()
+	router.Use(tracing.Middleware)
+	router.Use(metrics.Middleware)
+	router.Use(ipResolver.Middleware)
+	router.Use(blockList.Middleware)
 
 	// Setup routes
 	api := router.PathPrefix("/api/v1").Subrouter()
+	spec := newSpec()
+	docs := openapi.DocsHandler("/api/v1/docs", "/api/v1/openapi.json")
 
 	//api.Use(middleware.IpLoggingMiddleware)
 
 	// Public routes
-	api.HandleFunc("/health", h.HealthCheck).Methods("GET")
-	api.HandleFunc("/register", h.Register).Methods("POST")
-	api.HandleFunc("/login", h.Login).Methods("POST", "OPTIONS")
-	api.HandleFunc("/", h.HealthCheck).Methods("GET")
+	public := api.NewRoute().Subrouter()
+	public.Use(publicCORS.Middleware)
+	public.Use(middleware.AuditActor)
+	addPublicRoutes(public, h, checker, spec, docs, authLimiter.Middleware)
+	publicCORS.Preflight(public)
 
 	// Protected routes
 	protected := api.PathPrefix("/").Subrouter()
 	protected.Use(middleware.IpLoggingMiddleware)
-	protected.Use(middleware.CORS) // First: Set CORS headers
+	protected.Use(protectedCORS.Middleware) // Before auth, so preflights need no token
 	protected.Use(jwtAuth.Middleware)
+	protected.Use(auth.RequireRoutePermission(routePermission(handler.Operations())))
+	protected.Use(protectedLimiter.Middleware)
+	protected.Use(middleware.AuditActor) // After auth, so changes are attributed to the user
+
+	addProtectedRoutes(protected, h)
 
-	// SearchResults
-	protected.HandleFunc("/search/{subscriber_id}/{search_definition_engine_id}", h.SelectSearchResults).Methods("GET", "OPTIONS")
-	protected.HandleFunc("/search", h.Search).Methods("POST", "OPTIONS")
-
-	// Search Definition Engines
-	protected.HandleFunc("/searchdefinitionengines/{subscriber_id}/{search_definition_engine_id}", h.DeleteSearchDefinitionEngine).Methods("DELETE", "OPTIONS")
-	protected.HandleFunc("/searchdefinitionenginesview/{subscriber_id}", h.SelectSearchDefinitionEnginesView).Methods("GET", "OPTIONS")
-	protected.HandleFunc("/searchdefinitionengines", h.CreateSearchDefinitionEngines).Methods("POST", "OPTIONS")
-
-	// Search Definitions
-	protected.HandleFunc("/searchdefinitions/{subscriber_id}/{search_definition_id}", h.DeleteSearchDefinition).Methods("DELETE", "OPTIONS")
-	protected.HandleFunc("/searchdefinitions/{subscriber_id}", h.SelectSearchDefinitions).Methods("GET", "OPTIONS")
-	protected.HandleFunc("/searchdefinitions", h.CreateSearchDefinition).Methods("POST", "OPTIONS")
-	protected.HandleFunc("/searchdefinitions", h.UpdateSearchDefinition).Methods("PUT", "OPTIONS")
-
-	//Search Engines
-	protected.HandleFunc("/searchengines/{subscriber_id}/{search_engine_id}", h.DeleteSearchEngine).Methods("DELETE", "OPTIONS")
-	protected.HandleFunc("/searchengines/{subscriber_id}", h.SelectSearchEngines).Methods("GET", "OPTIONS")
-	protected.HandleFunc("/searchengines", h.CreateSearchEngine).Methods("POST", "OPTIONS")
-
-	// Blocked
-	protected.HandleFunc("/blocked/update", h.AddBlockedFromRDSToWAF).Methods("GET", "OPTIONS")
-	protected.HandleFunc("/blocked/parse", h.AddBlockedFromLogs).Methods("GET", "OPTIONS")
-	protected.HandleFunc("/blocked/{id}", h.UpdateBlocked).Methods("PUT", "OPTIONS")
-	protected.HandleFunc("/blocked/{id}", h.GetBlocked).Methods("GET", "OPTIONS")
-	protected.HandleFunc("/blocked/{id}", h.DeleteBlocked).Methods("DELETE")
-	protected.HandleFunc("/blocked", h.CreateBlocked).Methods("POST", "OPTIONS")
-	protected.HandleFunc("/blocked", h.SelectBlocked).Methods("GET", "OPTIONS")
-
-	// Item
-	protected.HandleFunc("/items", h.CreateItem).Methods("POST", "OPTIONS")
-	protected.HandleFunc("/items/{id}", h.UpdateItem).Methods("PUT", "OPTIONS")
-	protected.HandleFunc("/items/{id}", h.GetItem).Methods("GET")
-	protected.HandleFunc("/items", h.ListItems).Methods("GET", "OPTIONS")
-	protected.HandleFunc("/items/{id}", h.DeleteItem).Methods("DELETE")
-
-	// Salesforce Account
-	//protected.HandleFunc("/accounts", sf.Handler.CreateAccount).Methods("POST", "OPTIONS")
-	//protected.HandleFunc("/accounts/{id}", sf.Handler.UpdateAccount).Methods("PATCH", "OPTIONS")
-	//protected.HandleFunc("/accounts", sf.Handler.ListAccounts).Methods("GET", "OPTIONS")
-
-	// Salesforce Contact
-	//protected.HandleFunc("/contacts", sf.Handler.ListContacts).Methods("GET", "OPTIONS")
-	//protected.HandleFunc("/contacts/{accountid}", sf.Handler.ListContacts).Methods("GET", "OPTIONS")
-	//protected.HandleFunc("/contact/{contactid}", sf.Handler.GetContactById).Methods("GET", "OPTIONS")
-
-	// User
-	protected.HandleFunc("/users", h.CreateUser).Methods("POST", "OPTIONS")
-	protected.HandleFunc("/users/{id}", h.UpdateUser).Methods("PUT", "OPTIONS")
-	protected.HandleFunc("/users/{id}/password", h.UpdatePassword).Methods("PUT", "OPTIONS")
-	protected.HandleFunc("/users/{id}", h.DeleteUser).Methods("DELETE")
-	protected.HandleFunc("/users/{id}", h.GetUser).Methods("GET")
-	protected.HandleFunc("/users", h.SelectUsers).Methods("GET", "OPTIONS")
-	protected.HandleFunc("/users/roles", h.SelectUserRoles).Methods("GET", "OPTIONS")
-	protected.HandleFunc("/profile", h.GetUser).Methods("GET", "OPTIONS")
-
-	// User_Subscriber
-	protected.HandleFunc("/usersubscriberview/user/{id}", h.SelectUserSubscriberViewByUserId).Methods("GET", "OPTIONS")
-	protected.HandleFunc("/usersubscriberview", h.SelectUserSubscriberView).Methods("GET", "OPTIONS")
-	protected.HandleFunc("/usersubscriber/{id}", h.UpdateUserSubscriber).Methods("PUT", "OPTIONS")
-	protected.HandleFunc("/usersubscriber", h.CreateUserSubscriber).Methods("POST", "OPTIONS")
-	protected.HandleFunc("/usersubscriber/{id}", h.DeleteUserSubscriber).Methods("DELETE")
-
-	// UserSubscriberRole
-	protected.HandleFunc("/usersubscriberroleview", h.SelectUserSubscriberRoleView).Methods("POST", "OPTIONS")
-	protected.HandleFunc("/usersubscriberrole", h.CreateUserSubscriberRole).Methods("POST", "OPTIONS")
-	protected.HandleFunc("/usersubscriberrole/{id}", h.UpdateUserSubscriberRole).Methods("PUT", "OPTIONS")
-	protected.HandleFunc("/usersubscriberrole/{id}", h.DeleteUserSubscriberRole).Methods("DELETE")
-
-	// Subscriber - Customer
-	protected.HandleFunc("/subscriber/customers", h.SelectSubscriberCustomers).Methods("POST", "OPTIONS")
-	protected.HandleFunc("/subscriber/customer", h.CreateCustomer).Methods("POST", "OPTIONS")
-	protected.HandleFunc("/subscriber/customer/{subscriber_id}/{customer_id}", h.DeleteCustomer).Methods("DELETE", "OPTIONS")
-	protected.HandleFunc("/subscriber/customer", h.UpdateCustomer).Methods("PUT", "OPTIONS")
-
-	// Subscriber - Profile
-	protected.HandleFunc("/subscriber/profile", h.GetSubscriberProfile).Methods("POST", "OPTIONS")
-	protected.HandleFunc("/subscriber/profile", h.UpdateSubscriberProfile).Methods("PUT", "OPTIONS")
-
-	protected.HandleFunc("/subscriber/addresses", h.SelectSubscriberAddresses).Methods("POST", "OPTIONS")
-	protected.HandleFunc("/subscriber/address", h.UpdateSubscriberAddress).Methods("PUT", "OPTIONS")
-	protected.HandleFunc("/subscriber/address", h.CreateSubscriberAddress).Methods("POST", "OPTIONS")
-	protected.HandleFunc("/subscriber/address/g", h.GetSubscriberAddress).Methods("POST", "OPTIONS")
-	protected.HandleFunc("/subscriber/address/d/{subscriber_id}/{address_id}", h.DeleteSubscriberAddress).Methods("DELETE", "OPTIONS")
-
-	protected.HandleFunc("/subscriber/backgrounds", h.SelectSubscriberBackgrounds).Methods("POST", "OPTIONS")
-	protected.HandleFunc("/subscriber/background", h.UpdateSubscriberBackground).Methods("PUT", "OPTIONS")
-	protected.HandleFunc("/subscriber/background", h.CreateSubscriberBackground).Methods("POST", "OPTIONS")
-	protected.HandleFunc("/subscriber/background/g", h.GetSubscriberBackground).Methods("POST", "OPTIONS")
-	protected.HandleFunc("/subscriber/background/d/{subscriber_id}/{background_id}", h.DeleteSubscriberBackground).Methods("DELETE", "OPTIONS")
-
-	// Subscriber - Customer - Contacts
-	protected.HandleFunc("/subscriber/customer/contacts", h.SelectContacts).Methods("POST", "OPTIONS")
-	protected.HandleFunc("/subscriber/customer/contact", h.CreateContact).Methods("POST", "OPTIONS")
-	protected.HandleFunc("/subscriber/contact/{subscriber_id}/{contact_id}", h.DeleteContact).Methods("DELETE", "OPTIONS")
-	protected.HandleFunc("/subscriber/customer/contact", h.UpdateContact).Methods("PUT", "OPTIONS")
-
-	// Subsriber - Item View
-	protected.HandleFunc("/subscriber/items/{id}", h.SelectSubscriberItemView).Methods("GET", "OPTIONS")
-	protected.HandleFunc("/subscriber/item/{id}", h.DeleteSubscriberItem).Methods("DELETE", "OPTIONS")
-	protected.HandleFunc("/subscriber/item", h.CreateSubscriberItem).Methods("POST", "OPTIONS")
-
-	// Subscribers
-	protected.HandleFunc("/subscribers", h.CreateSubscriber).Methods("POST", "OPTIONS")
-	protected.HandleFunc("/subscribers/{id}", h.UpdateSubscriber).Methods("PUT", "OPTIONS")
-	protected.HandleFunc("/subscribers", h.DeleteSubscriber).Methods("DELETE")
-	protected.HandleFunc("/subscibers/{id}", h.GetSubscriber).Methods("GET")
-	protected.HandleFunc("/subscribers/g", h.GetSubscriberP).Methods("POST", "OPTIONS")
-	protected.HandleFunc("/subscribers", h.SelectSubscribers).Methods("GET", "OPTIONS")
-
-	// Role
-	protected.HandleFunc("/roles", h.CreateRole).Methods("POST", "OPTIONS")
-	protected.HandleFunc("/roles/{id}", h.UpdateRole).Methods("PUT", "OPTIONS")
-	protected.HandleFunc("/roles/{id}", h.DeleteRole).Methods("DELETE", "OPTIONS")
-	protected.HandleFunc("/roles/{id}", h.GetRole).Methods("GET")
-	protected.HandleFunc("/roles", h.SelectRoles).Methods("GET", "OPTIONS")
-
-	// Permission
-	protected.HandleFunc("/permissions", h.CreatePermission).Methods("POST", "OPTIONS")
-	protected.HandleFunc("/permissions/{id}", h.UpdatePermission).Methods("PUT", "OPTIONS")
-	protected.HandleFunc("/permissions/{id}", h.DeletePermission).Methods("DELETE")
-	protected.HandleFunc("/permissions", h.SelectPermissions).Methods("GET", "OPTIONS")
-
-	// User Permission
-
-	// Role Permission
-	protected.HandleFunc("/rolepermissionsview", h.SelectRolePermissionsView).Methods("GET", "OPTIONS")
+	// Preflights for the protected routes above
+	protectedCORS.Preflight(protected)
+
+	// Document every route; those without handler.Operations entries are
+	// listed with no request or response schema.
+	if err := spec.AddRoutes(public, false); err != nil {
+		logger.Error("OpenAPI error", "error", err)
+		return
+	}
+	if err := spec.AddRoutes(protected, true); err != nil {
+		logger.Error("OpenAPI error", "error", err)
+		return
+	}
+	for _, route := range spec.Undocumented() {
+		logger.Warn("route missing from OpenAPI operations", "route", route)
+	}
 
 	// Add middleware
-	api.Use(middleware.Logger(&log.Logger{}))
 	api.Use(middleware.RequestID)
+	api.Use(middleware.Logger(logger))
 	api.Use(middleware.SecurityHeaders)
-	api.Use(middleware.CORS)
+	api.Use(middleware.MaxBodySize(int64(cfg.Server.MaxBodyBytes)))
+	api.Use(apiLimiter.Middleware)
+
+	// Unknown API routes get a problem response rather than the SPA
+	api.NotFoundHandler = common.ProblemHandler(http.StatusNotFound)
+	api.MethodNotAllowedHandler = common.ProblemHandler(http.StatusMethodNotAllowed)
+
+	// Metrics are served on metrics.addr when set, otherwise on the main listener
+	// only when metrics.token is set to protect them.
+	metricsAddr := cfg.Metrics.Addr
+	metricsToken := cfg.Metrics.Token
+	var metricsSrv *http.Server
+	switch {
+	case metricsAddr != "":
+		metricsRouter := http.NewServeMux()
+		metricsRouter.Handle("/metrics", metrics.Handler(metricsToken))
+		metricsSrv = &http.Server{
+			Addr:    metricsAddr,
+			Handler: metricsRouter,
+		}
+	case metricsToken != "":
+		router.Handle("/metrics", metrics.Handler(metricsToken)).Methods("GET")
+	default:
+		logger.Warn("metrics disabled, set METRICS_ADDR or METRICS_TOKEN to expose /metrics")
+	}
 
 	//static assets
-	distPath := "/home/ec2-user/go/src/stinsondata-tools-reactapp/dist"
-	fmt.Printf("[%v] [main] Serving files from: %s.\n", time.Now().Format(time.RFC3339), distPath)
+	distPath := cfg.Server.DistPath
+	logger.Info("serving files", "path", distPath)
 
 	// Handle all static assets including the index.js file
 	router.PathPrefix("/assets/").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		fmt.Printf("[%v] [main] Asset request for: %s.\n", time.Now().Format(time.RFC3339), r.URL.Path)
+		logger.DebugContext(r.Context(), "asset request", "path", r.URL.Path)
 
 		// Remove the leading /assets/ to get the file path
 		filePath := filepath.Join(distPath, r.URL.Path)
-		fmt.Printf("[%v] [main] Looking for file at: %s.\n", time.Now().Format(time.RFC3339), filePath)
+		logger.DebugContext(r.Context(), "looking for file", "file", filePath)
 
 		// Set appropriate headers based on file extension
 		switch ext := path.Ext(r.URL.Path); ext {
@@ -276,38 +414,175 @@ func main() {
 		http.ServeFile(w, r, filepath.Join(distPath, "index.html"))
 	})
 
-	// Create server with local certificates
 	srv := &http.Server{
-		Addr:    ":8080",
-		Handler: router,
+		Addr:    cfg.Server.Addr,
+		Handler: otelhttp.NewHandler(router, "http.server"),
+	}
+
+	// Serve HTTPS when configured, reloading certificates as they are renewed
+	var redirectSrv *http.Server
+	if cfg.Server.TLS.Enabled {
+		tlsCfg := cfg.Server.TLS
+		reloader, err := certs.NewReloader(tlsCfg.CertFile, tlsCfg.KeyFile, tlsCfg.ClientCAFile)
+		if err != nil {
+			logger.Error("TLS error", "error", err)
+			return
+		}
+		if tlsCfg.ReloadInterval > 0 {
+			reloader.Start(backgroundCtx, tlsCfg.ReloadInterval)
+		}
+
+		srv.TLSConfig, err = certs.ServerConfig(tlsCfg.Options(), reloader)
+		if err != nil {
+			logger.Error("TLS error", "error", err)
+			return
+		}
+
+		checker.Add("tls_certificate", false, func(ctx context.Context) error {
+			if left := time.Until(reloader.NotAfter()); left < 14*24*time.Hour {
+				return fmt.Errorf("certificate expires in %s", left.Round(time.Hour))
+			}
+			return nil
+		})
+
+		if tlsCfg.RedirectAddr != "" {
+			_, httpsPort, _ := net.SplitHostPort(cfg.Server.Addr)
+			redirectSrv = &http.Server{
+				Addr:              tlsCfg.RedirectAddr,
+				Handler:           certs.RedirectHandler(httpsPort),
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+		}
 	}
 
 	// Start server
 	go func() {
-		fmt.Printf("[%v] [main] Server starting...\n", time.Now().Format(time.RFC3339))
+		logger.Info("server starting", "addr", srv.Addr, "tls", srv.TLSConfig != nil)
 
-		//err := srv.ListenAndServeTLS("../certs/certificate.crt", "../certs/private.key")
-		err := srv.ListenAndServe()
-		if err == http.ErrServerClosed {
-			fmt.Printf("[%v] [main] Failed to start server: %v.\n", time.Now().Format(time.RFC3339), err.Error())
+		var err error
+		if srv.TLSConfig != nil {
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			logger.Error("failed to start server", "error", err)
 		}
 	}()
 
+	if redirectSrv != nil {
+		go func() {
+			logger.Info("HTTPS redirect server starting", "addr", redirectSrv.Addr)
+			if err := redirectSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Error("HTTPS redirect server failed", "error", err)
+			}
+		}()
+	}
+
+	if metricsSrv != nil {
+		go func() {
+			logger.Info("metrics server starting", "addr", metricsSrv.Addr)
+			if err := metricsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Error("metrics server failed", "error", err)
+			}
+		}()
+	}
+
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
 	// Graceful shutdown
-	fmt.Printf("[%v] [main] Server stopping...\n", time.Now().Format(time.RFC3339))
+	logger.Info("server stopping")
+
+	// Fail readiness first so load balancers stop routing here, then drain
+	checker.SetDraining(true)
+	time.Sleep(cfg.Server.DrainDelay)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
 
+	if metricsSrv != nil {
+		metricsSrv.Shutdown(ctx)
+	}
+	if redirectSrv != nil {
+		redirectSrv.Shutdown(ctx)
+	}
+
 	if err := srv.Shutdown(ctx); err != nil {
-		fmt.Printf("[%v] [main] Server forced to shutdown: %s.\n", time.Now().Format(time.RFC3339), err.Error())
+		logger.Error("server forced to shutdown", "error", err)
 		return
 	}
 
-	fmt.Printf("[%v] [main] Server stopped.\n", time.Now().Format(time.RFC3339))
+	logger.Info("server stopped")
+}
+
+// newRateLimiter builds the limiter for a route group from its configured
+// limits, written as "300/m:50".
+func newRateLimiter(group string, limits config.RateLimit, resolver *middleware.ClientIPResolver) (*middleware.RateLimiter, error) {
+	ipLimit, err := middleware.ParseLimit(limits.IP)
+	if err != nil {
+		return nil, fmt.Errorf("rate_limits.%s.ip: %w", group, err)
+	}
+	userLimit, err := middleware.ParseLimit(limits.User)
+	if err != nil {
+		return nil, fmt.Errorf("rate_limits.%s.user: %w", group, err)
+	}
+
+	policy := middleware.RateLimitPolicy{
+		Name:    group,
+		PerIP:   ipLimit,
+		PerUser: userLimit,
+	}
+
+	return middleware.NewRateLimiter(policy, resolver), nil
+}
+
+// newHealthChecker registers the readiness checks. Only the database and its
+// migrations are critical; the other components report degraded. The secrets
+// provider is checked by pinging secretName, the secret the database
+// credentials are read from, and not at all when they are not read from one.
+func newHealthChecker(db database.Repository, allowList *middleware.AllowList, blockList *middleware.BlockList, store secrets.Pinger, secretName string) *health.Checker {
+	checker := health.New(3 * time.Second)
+
+	checker.Add("database", true, db.Ping)
+
+	checker.Add("migrations", true, func(ctx context.Context) error {
+		pending, err := db.PendingMigrations(ctx)
+		if err != nil {
+			return err
+		}
+		if pending > 0 {
+			return fmt.Errorf("%d pending migrations", pending)
+		}
+		return nil
+	})
+
+	checker.Add("database_pool", false, func(ctx context.Context) error {
+		stats := db.Stats()
+		if stats.MaxOpenConnections > 0 && stats.InUse >= stats.MaxOpenConnections {
+			return fmt.Errorf("pool saturated, %d of %d connections in use", stats.InUse, stats.MaxOpenConnections)
+		}
+		return nil
+	})
+
+	if secretName != "" {
+		checker.Add("secrets", false, health.Cached(func(ctx context.Context) error {
+			return store.Ping(ctx, secretName)
+		}, 30*time.Second))
+	}
+
+	stale := func(name string, loadedAt func() time.Time) health.Check {
+		return func(ctx context.Context) error {
+			if age := time.Since(loadedAt()); age > 5*time.Minute {
+				return fmt.Errorf("%s last refreshed %s ago", name, age.Round(time.Second))
+			}
+			return nil
+		}
+	}
+	checker.Add("allow_list_refresh", false, stale("allow list", allowList.LoadedAt))
+	checker.Add("block_list_refresh", false, stale("block list", blockList.LoadedAt))
+
+	return checker
 }
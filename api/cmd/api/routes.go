@@ -0,0 +1,246 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	common "github.com/htstinson/stinsondataapi/api/commonweb"
+	"github.com/htstinson/stinsondataapi/api/internal/auth"
+	"github.com/htstinson/stinsondataapi/api/internal/handler"
+	"github.com/htstinson/stinsondataapi/api/internal/health"
+	"github.com/htstinson/stinsondataapi/api/internal/openapi"
+)
+
+// newSpec returns the generator of the OpenAPI document served at
+// /api/v1/openapi.json.
+func newSpec() *openapi.Generator {
+	return openapi.New(openapi.Info{
+		Title:       "Stinson Data API",
+		Version:     "v1",
+		Description: "Subscribers, their customers and searches, and the users and roles that manage them.",
+	}, "/api/v1", handler.Operations(), common.Problem{})
+}
+
+// routePermission returns the permission an API key needs for a request,
+// that of the operation of its route in operations.
+func routePermission(operations map[string]openapi.Operation) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		route := mux.CurrentRoute(r)
+		if route == nil {
+			return ""
+		}
+		return operations[openapi.OperationID(route)].Permission
+	}
+}
+
+// addPublicRoutes registers the routes that need no token. authLimit is the
+// stricter rate limit of the routes that take credentials.
+func addPublicRoutes(public *mux.Router, h *handler.Handler, checker *health.Checker, spec http.Handler, docs http.Handler, authLimit func(http.Handler) http.Handler) {
+	public.HandleFunc("/health", h.HealthCheck).Methods("GET")
+	public.HandleFunc("/health/live", checker.Live).Methods("GET")
+	public.HandleFunc("/health/ready", checker.Ready).Methods("GET")
+	public.Handle("/register", authLimit(http.HandlerFunc(h.Register))).Methods("POST").Name("Register")
+	public.Handle("/register/verify", authLimit(http.HandlerFunc(h.VerifyEmail))).Methods("POST").Name("VerifyEmail")
+	public.Handle("/register/resend", authLimit(http.HandlerFunc(h.ResendVerification))).Methods("POST").Name("ResendVerification")
+	public.Handle("/login", authLimit(http.HandlerFunc(h.Login))).Methods("POST").Name("Login")
+	public.Handle("/login/mfa", authLimit(http.HandlerFunc(h.LoginMFA))).Methods("POST").Name("LoginMFA")
+	public.Handle("/login/mfa/setup", authLimit(http.HandlerFunc(h.LoginMFASetup))).Methods("POST").Name("LoginMFASetup")
+	public.Handle("/sso/callback", authLimit(http.HandlerFunc(h.SSOCallback))).Methods("GET").Name("SSOCallback")
+	public.Handle("/sso/{subscriber_id}/login", authLimit(http.HandlerFunc(h.SSOLogin))).Methods("GET").Name("SSOLogin")
+	public.Handle("/invitations/accept", authLimit(http.HandlerFunc(h.AcceptInvitation))).Methods("POST").Name("AcceptInvitation")
+	public.Handle("/password/forgot", authLimit(http.HandlerFunc(h.ForgotPassword))).Methods("POST").Name("ForgotPassword")
+	public.Handle("/password/reset", authLimit(http.HandlerFunc(h.ResetPassword))).Methods("POST").Name("ResetPassword")
+	public.Handle("/email/unsubscribe", authLimit(http.HandlerFunc(h.GetUnsubscribe))).Methods("GET").Name("GetUnsubscribe")
+	public.Handle("/email/unsubscribe", authLimit(http.HandlerFunc(h.Unsubscribe))).Methods("POST").Name("Unsubscribe")
+	public.Handle("/openapi.json", spec).Methods("GET").Name("OpenAPI")
+	public.Handle("/docs", docs).Methods("GET").Name("Docs")
+	public.Handle("/docs/{asset}", docs).Methods("GET").Name("DocsAsset")
+	public.HandleFunc("/", h.HealthCheck).Methods("GET")
+}
+
+// addProtectedRoutes registers the routes that need a token or API key.
+func addProtectedRoutes(protected *mux.Router, h *handler.Handler) {
+
+	// SearchResults
+	protected.HandleFunc("/search/{subscriber_id}/{search_definition_engine_id}", h.SelectSearchResults).Methods("GET")
+	protected.HandleFunc("/search", h.Search).Methods("POST")
+
+	// Search Definition Engines
+	protected.HandleFunc("/searchdefinitionengines/{subscriber_id}/{search_definition_engine_id}", h.DeleteSearchDefinitionEngine).Methods("DELETE")
+	protected.HandleFunc("/searchdefinitionenginesview/{subscriber_id}", h.SelectSearchDefinitionEnginesView).Methods("GET")
+	protected.HandleFunc("/searchdefinitionengines", h.CreateSearchDefinitionEngines).Methods("POST")
+
+	// Search Definitions
+	protected.HandleFunc("/searchdefinitions/{subscriber_id}/{search_definition_id}", h.DeleteSearchDefinition).Methods("DELETE")
+	protected.HandleFunc("/searchdefinitions/{subscriber_id}", h.SelectSearchDefinitions).Methods("GET")
+	protected.HandleFunc("/searchdefinitions", h.CreateSearchDefinition).Methods("POST")
+	protected.HandleFunc("/searchdefinitions", h.UpdateSearchDefinition).Methods("PUT")
+
+	//Search Engines
+	protected.HandleFunc("/searchengines/{subscriber_id}/{search_engine_id}", h.DeleteSearchEngine).Methods("DELETE")
+	protected.HandleFunc("/searchengines/{subscriber_id}", h.SelectSearchEngines).Methods("GET")
+	protected.HandleFunc("/searchengines", h.CreateSearchEngine).Methods("POST")
+
+	// Blocked
+	protected.HandleFunc("/blocked/update", h.AddBlockedFromRDSToWAF).Methods("GET")
+	protected.HandleFunc("/blocked/parse", h.AddBlockedFromLogs).Methods("GET")
+	protected.HandleFunc("/blocked/{id}", h.UpdateBlocked).Methods("PUT")
+	protected.HandleFunc("/blocked/{id}", h.GetBlocked).Methods("GET")
+	protected.HandleFunc("/blocked/{id}", h.DeleteBlocked).Methods("DELETE")
+	protected.HandleFunc("/blocked", h.CreateBlocked).Methods("POST")
+	protected.HandleFunc("/blocked", h.SelectBlocked).Methods("GET")
+
+	// Allowed
+	protected.Handle("/allowed/{id}", auth.RequireRole("admin")(http.HandlerFunc(h.UpdateAllowed))).Methods("PUT").Name("UpdateAllowed")
+	protected.Handle("/allowed/{id}", auth.RequireRole("admin")(http.HandlerFunc(h.GetAllowed))).Methods("GET").Name("GetAllowed")
+	protected.Handle("/allowed/{id}", auth.RequireRole("admin")(http.HandlerFunc(h.DeleteAllowed))).Methods("DELETE").Name("DeleteAllowed")
+	protected.Handle("/allowed", auth.RequireRole("admin")(http.HandlerFunc(h.CreateAllowed))).Methods("POST").Name("CreateAllowed")
+	protected.Handle("/allowed", auth.RequireRole("admin")(http.HandlerFunc(h.SelectAllowed))).Methods("GET").Name("SelectAllowed")
+
+	// Item
+	protected.HandleFunc("/items", h.CreateItem).Methods("POST")
+	protected.HandleFunc("/items/{id}", h.UpdateItem).Methods("PUT")
+	protected.HandleFunc("/items/{id}", h.GetItem).Methods("GET")
+	protected.HandleFunc("/items", h.ListItems).Methods("GET")
+	protected.HandleFunc("/items/{id}", h.DeleteItem).Methods("DELETE")
+
+	// Salesforce Account
+	//protected.HandleFunc("/accounts", sf.Handler.CreateAccount).Methods("POST")
+	//protected.HandleFunc("/accounts/{id}", sf.Handler.UpdateAccount).Methods("PATCH")
+	//protected.HandleFunc("/accounts", sf.Handler.ListAccounts).Methods("GET")
+
+	// Salesforce Contact
+	//protected.HandleFunc("/contacts", sf.Handler.ListContacts).Methods("GET")
+	//protected.HandleFunc("/contacts/{accountid}", sf.Handler.ListContacts).Methods("GET")
+	//protected.HandleFunc("/contact/{contactid}", sf.Handler.GetContactById).Methods("GET")
+
+	// User
+	protected.HandleFunc("/users", h.CreateUser).Methods("POST")
+	protected.HandleFunc("/users/{id}", h.UpdateUser).Methods("PUT")
+	protected.Handle("/users/{id}/password", auth.RefuseAPIKeys(http.HandlerFunc(h.UpdatePassword))).Methods("PUT").Name("UpdatePassword")
+	protected.HandleFunc("/users/{id}", h.DeleteUser).Methods("DELETE")
+	protected.HandleFunc("/users/{id}", h.GetUser).Methods("GET")
+	protected.HandleFunc("/users", h.SelectUsers).Methods("GET")
+	protected.HandleFunc("/users/roles", h.SelectUserRoles).Methods("GET")
+	protected.HandleFunc("/profile", h.GetUser).Methods("GET")
+	protected.HandleFunc("/profile/notifications", h.GetNotificationPreferences).Methods("GET")
+	protected.HandleFunc("/profile/notifications", h.SaveNotificationPreferences).Methods("PUT")
+	protected.HandleFunc("/profile/notifications/subscribers", h.SelectSubscriberNotificationPreferences).Methods("GET")
+	protected.HandleFunc("/profile/notifications/subscribers/{subscriber_id}", h.GetSubscriberNotificationPreferences).Methods("GET")
+	protected.HandleFunc("/profile/notifications/subscribers/{subscriber_id}", h.SaveSubscriberNotificationPreferences).Methods("PUT")
+	protected.HandleFunc("/profile/notifications/subscribers/{subscriber_id}", h.DeleteSubscriberNotificationPreferences).Methods("DELETE")
+
+	// Session
+	protected.HandleFunc("/me", h.GetMe).Methods("GET")
+	protected.Handle("/session/subscriber", auth.RefuseAPIKeys(http.HandlerFunc(h.SelectSessionSubscriber))).Methods("POST").Name("SelectSessionSubscriber")
+
+	// MFA, which API keys cannot change
+	protected.HandleFunc("/mfa", h.GetMFA).Methods("GET")
+	protected.Handle("/mfa/totp", auth.RefuseAPIKeys(http.HandlerFunc(h.StartMFA))).Methods("POST").Name("StartMFA")
+	protected.Handle("/mfa/totp/verify", auth.RefuseAPIKeys(http.HandlerFunc(h.VerifyMFA))).Methods("POST").Name("VerifyMFA")
+	protected.Handle("/mfa/totp", auth.RefuseAPIKeys(http.HandlerFunc(h.DisableMFA))).Methods("DELETE").Name("DisableMFA")
+	protected.Handle("/mfa/recovery-codes", auth.RefuseAPIKeys(http.HandlerFunc(h.RegenerateRecoveryCodes))).Methods("POST").Name("RegenerateRecoveryCodes")
+
+	// API keys; a key cannot create others
+	protected.Handle("/apikeys", auth.RefuseAPIKeys(http.HandlerFunc(h.CreateAPIKey))).Methods("POST").Name("CreateAPIKey")
+	protected.HandleFunc("/apikeys", h.SelectAPIKeys).Methods("GET")
+	protected.HandleFunc("/apikeys/{id}", h.RevokeAPIKey).Methods("DELETE")
+
+	// Service accounts, admins only
+	protected.Handle("/service-accounts", auth.RequireRole("admin")(http.HandlerFunc(h.CreateServiceAccount))).Methods("POST").Name("CreateServiceAccount")
+	protected.Handle("/service-accounts/{id}/apikeys", auth.RequireRole("admin")(http.HandlerFunc(h.CreateServiceAccountKey))).Methods("POST").Name("CreateServiceAccountKey")
+	protected.Handle("/service-accounts/{id}/apikeys", auth.RequireRole("admin")(http.HandlerFunc(h.SelectServiceAccountKeys))).Methods("GET").Name("SelectServiceAccountKeys")
+
+	// User_Subscriber
+	protected.HandleFunc("/usersubscriberview/user/{id}", h.SelectUserSubscriberViewByUserId).Methods("GET")
+	protected.HandleFunc("/usersubscriberview", h.SelectUserSubscriberView).Methods("GET")
+	protected.HandleFunc("/usersubscriber/{id}", h.UpdateUserSubscriber).Methods("PUT")
+	protected.HandleFunc("/usersubscriber", h.CreateUserSubscriber).Methods("POST")
+	protected.HandleFunc("/usersubscriber/{id}", h.DeleteUserSubscriber).Methods("DELETE")
+
+	// UserSubscriberRole
+	protected.HandleFunc("/usersubscriberroleview", h.SelectUserSubscriberRoleView).Methods("POST")
+	protected.HandleFunc("/usersubscriberrole", h.CreateUserSubscriberRole).Methods("POST")
+	protected.HandleFunc("/usersubscriberrole/{id}", h.UpdateUserSubscriberRole).Methods("PUT")
+	protected.HandleFunc("/usersubscriberrole/{id}", h.DeleteUserSubscriberRole).Methods("DELETE")
+
+	// Subscriber - Customer
+	protected.HandleFunc("/subscriber/customers", h.SelectSubscriberCustomers).Methods("POST")
+	protected.HandleFunc("/subscriber/customer", h.CreateCustomer).Methods("POST")
+	protected.HandleFunc("/subscriber/customer/{subscriber_id}/{customer_id}", h.DeleteCustomer).Methods("DELETE")
+	protected.HandleFunc("/subscriber/customer", h.UpdateCustomer).Methods("PUT")
+
+	// Subscriber - Profile
+	protected.HandleFunc("/subscriber/profile", h.GetSubscriberProfile).Methods("POST")
+	protected.HandleFunc("/subscriber/profile", h.UpdateSubscriberProfile).Methods("PUT")
+
+	protected.HandleFunc("/subscriber/addresses", h.SelectSubscriberAddresses).Methods("POST")
+	protected.HandleFunc("/subscriber/address", h.UpdateSubscriberAddress).Methods("PUT")
+	protected.HandleFunc("/subscriber/address", h.CreateSubscriberAddress).Methods("POST")
+	protected.HandleFunc("/subscriber/address/g", h.GetSubscriberAddress).Methods("POST")
+	protected.HandleFunc("/subscriber/address/d/{subscriber_id}/{address_id}", h.DeleteSubscriberAddress).Methods("DELETE")
+
+	protected.HandleFunc("/subscriber/backgrounds", h.SelectSubscriberBackgrounds).Methods("POST")
+	protected.HandleFunc("/subscriber/background", h.UpdateSubscriberBackground).Methods("PUT")
+	protected.HandleFunc("/subscriber/background", h.CreateSubscriberBackground).Methods("POST")
+	protected.HandleFunc("/subscriber/background/g", h.GetSubscriberBackground).Methods("POST")
+	protected.HandleFunc("/subscriber/background/d/{subscriber_id}/{background_id}", h.DeleteSubscriberBackground).Methods("DELETE")
+
+	// Subscriber - Customer - Contacts
+	protected.HandleFunc("/subscriber/customer/contacts", h.SelectContacts).Methods("POST")
+	protected.HandleFunc("/subscriber/customer/contact", h.CreateContact).Methods("POST")
+	protected.HandleFunc("/subscriber/contact/{subscriber_id}/{contact_id}", h.DeleteContact).Methods("DELETE")
+	protected.HandleFunc("/subscriber/customer/contact", h.UpdateContact).Methods("PUT")
+
+	// Subsriber - Item View
+	protected.HandleFunc("/subscriber/items/{id}", h.SelectSubscriberItemView).Methods("GET")
+	protected.HandleFunc("/subscriber/item/{id}", h.DeleteSubscriberItem).Methods("DELETE")
+	protected.HandleFunc("/subscriber/item", h.CreateSubscriberItem).Methods("POST")
+
+	// Subscribers
+	protected.HandleFunc("/subscribers", h.CreateSubscriber).Methods("POST")
+	protected.HandleFunc("/subscribers/{id}", h.UpdateSubscriber).Methods("PUT")
+	protected.HandleFunc("/subscribers", h.DeleteSubscriber).Methods("DELETE")
+	protected.HandleFunc("/subscibers/{id}", h.GetSubscriber).Methods("GET")
+	protected.HandleFunc("/subscribers/g", h.GetSubscriberP).Methods("POST")
+	protected.HandleFunc("/subscribers", h.SelectSubscribers).Methods("GET")
+	protected.Handle("/subscribers/{id}/sso", auth.RequireRole("admin")(http.HandlerFunc(h.GetSubscriberSSO))).Methods("GET").Name("GetSubscriberSSO")
+	protected.Handle("/subscribers/{id}/sso", auth.RequireRole("admin")(http.HandlerFunc(h.SaveSubscriberSSO))).Methods("PUT").Name("SaveSubscriberSSO")
+	protected.Handle("/subscribers/{id}/sso", auth.RequireRole("admin")(http.HandlerFunc(h.DeleteSubscriberSSO))).Methods("DELETE").Name("DeleteSubscriberSSO")
+	protected.Handle("/subscribers/{id}/email-templates", auth.RequireRole("admin")(http.HandlerFunc(h.SelectEmailTemplates))).Methods("GET").Name("SelectEmailTemplates")
+	protected.Handle("/subscribers/{id}/email-templates/{name}", auth.RequireRole("admin")(http.HandlerFunc(h.SaveEmailTemplate))).Methods("PUT").Name("SaveEmailTemplate")
+	protected.Handle("/subscribers/{id}/email-templates/{name}", auth.RequireRole("admin")(http.HandlerFunc(h.DeleteEmailTemplate))).Methods("DELETE").Name("DeleteEmailTemplate")
+	protected.Handle("/subscribers/{id}/mailboxes", auth.RequireRole("admin")(http.HandlerFunc(h.SelectMailboxSources))).Methods("GET").Name("SelectMailboxSources")
+	protected.Handle("/subscribers/{id}/mailboxes", auth.RequireRole("admin")(http.HandlerFunc(h.CreateMailboxSource))).Methods("POST").Name("CreateMailboxSource")
+	protected.Handle("/subscribers/{id}/mailboxes/{mailbox_id}", auth.RequireRole("admin")(http.HandlerFunc(h.UpdateMailboxSource))).Methods("PUT").Name("UpdateMailboxSource")
+	protected.Handle("/subscribers/{id}/mailboxes/{mailbox_id}", auth.RequireRole("admin")(http.HandlerFunc(h.DeleteMailboxSource))).Methods("DELETE").Name("DeleteMailboxSource")
+
+	// Email
+	protected.Handle("/emails", auth.RequireRole("admin")(http.HandlerFunc(h.SelectEmails))).Methods("GET").Name("SelectEmails")
+
+	// Invitations
+	protected.Handle("/invitations", auth.RequireRole("admin")(http.HandlerFunc(h.CreateInvitation))).Methods("POST").Name("CreateInvitation")
+	protected.Handle("/invitations", auth.RequireRole("admin")(http.HandlerFunc(h.SelectInvitations))).Methods("GET").Name("SelectInvitations")
+	protected.Handle("/invitations/{id}", auth.RequireRole("admin")(http.HandlerFunc(h.RevokeInvitation))).Methods("DELETE").Name("RevokeInvitation")
+
+	// Role
+	protected.HandleFunc("/roles", h.CreateRole).Methods("POST")
+	protected.HandleFunc("/roles/{id}", h.UpdateRole).Methods("PUT")
+	protected.HandleFunc("/roles/{id}", h.DeleteRole).Methods("DELETE")
+	protected.HandleFunc("/roles/{id}", h.GetRole).Methods("GET")
+	protected.HandleFunc("/roles", h.SelectRoles).Methods("GET")
+
+	// Permission
+	protected.HandleFunc("/permissions", h.CreatePermission).Methods("POST")
+	protected.HandleFunc("/permissions/{id}", h.UpdatePermission).Methods("PUT")
+	protected.HandleFunc("/permissions/{id}", h.DeletePermission).Methods("DELETE")
+	protected.HandleFunc("/permissions", h.SelectPermissions).Methods("GET")
+
+	// User Permission
+
+	// Role Permission
+	protected.HandleFunc("/rolepermissionsview", h.SelectRolePermissionsView).Methods("GET")
+
+	// Audit log, admins only
+	protected.Handle("/audit", auth.RequireRole("admin")(http.HandlerFunc(h.SelectAuditEvents))).Methods("GET").Name("SelectAuditEvents")
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/htstinson/stinsondataapi/api/internal/auth"
+	"github.com/htstinson/stinsondataapi/api/internal/handler"
+	"github.com/htstinson/stinsondataapi/api/internal/health"
+	"github.com/htstinson/stinsondataapi/api/internal/openapi"
+)
+
+// TestRoutesDocumented fails when a route is registered without an entry in
+// handler.Operations. The handlers are never called, so a nil Handler will do.
+func TestRoutesDocumented(t *testing.T) {
+	var h *handler.Handler
+	spec := newSpec()
+	passThrough := func(next http.Handler) http.Handler { return next }
+
+	api := mux.NewRouter().PathPrefix("/api/v1").Subrouter()
+	public := api.NewRoute().Subrouter()
+	addPublicRoutes(public, h, health.New(time.Second), spec, http.NotFoundHandler(), passThrough)
+	protected := api.PathPrefix("/").Subrouter()
+	addProtectedRoutes(protected, h)
+
+	if err := spec.AddRoutes(public, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := spec.AddRoutes(protected, true); err != nil {
+		t.Fatal(err)
+	}
+	for _, route := range spec.Undocumented() {
+		t.Errorf("route missing from handler.Operations: %s", route)
+	}
+}
+
+var routeVar = regexp.MustCompile(`\{[^}]+\}`)
+
+// TestAPIKeyPermissions sends a request to every protected route as a
+// bearer token, an API key without permissions and an API key with the
+// permission of the route. A middleware after the permission check answers
+// 204 in place of the handler.
+func TestAPIKeyPermissions(t *testing.T) {
+	var h *handler.Handler
+	operations := handler.Operations()
+
+	protected := mux.NewRouter().PathPrefix("/api/v1").Subrouter()
+	protected.Use(auth.RequireRoutePermission(routePermission(operations)))
+	protected.Use(func(http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+	})
+	addProtectedRoutes(protected, h)
+
+	send := func(method, path string, claims *auth.Claims) int {
+		r := httptest.NewRequest(method, path, nil)
+		r = r.WithContext(context.WithValue(r.Context(), "user", claims))
+		rec := httptest.NewRecorder()
+		protected.ServeHTTP(rec, r)
+		return rec.Code
+	}
+
+	refused := 0
+	err := protected.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		path := routeVar.ReplaceAllString(template, "x")
+		permission := operations[openapi.OperationID(route)].Permission
+
+		for _, method := range methods {
+			if code := send(method, path, &auth.Claims{UserID: "u1"}); code != http.StatusNoContent {
+				t.Errorf("%s %s with a bearer token: %d", method, template, code)
+			}
+			if code := send(method, path, &auth.Claims{UserID: "u1", APIKeyID: "k1", Permissions: []string{}}); code != http.StatusForbidden {
+				t.Errorf("%s %s with an API key without permissions: %d", method, template, code)
+			}
+
+			want := http.StatusNoContent
+			if permission == "" {
+				want = http.StatusForbidden
+				refused++
+			}
+			key := &auth.Claims{UserID: "u1", APIKeyID: "k1", Permissions: []string{permission}}
+			if code := send(method, path, key); code != want {
+				t.Errorf("%s %s with an API key holding %q: %d, want %d", method, template, permission, code, want)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refused == 0 {
+		t.Fatal("no route refuses API keys")
+	}
+}
@@ -7,7 +7,7 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/aws/aws-sdk-go-v2/otelaws"
 )
 
 // All - Internal
@@ -35,34 +35,19 @@ func RespondJSON2(w http.ResponseWriter, status int, payload interface{}) {
 	w.Write(response)
 }
 
+// RespondError writes an application/problem+json response with message as
+// its detail and the default code for the status.
 func RespondError(w http.ResponseWriter, code int, message string) {
-	RespondJSON(w, code, map[string]string{"error": message})
+	RespondProblem(w, NewError(code, CodeForStatus(code), message))
 }
 
-func GetSecretString(secretName string, region string) ([]byte, error) {
-
-	var SecretValue []byte
-
-	config, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(region))
+// LoadAWSConfig loads the default AWS configuration for region, with a span
+// recorded for every SDK call.
+func LoadAWSConfig(ctx context.Context, region string) (aws.Config, error) {
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
 	if err != nil {
-		return SecretValue, err
-	}
-
-	// Create Secrets Manager client
-	svc := secretsmanager.NewFromConfig(config)
-
-	input := &secretsmanager.GetSecretValueInput{
-		SecretId:     aws.String(secretName),
-		VersionStage: aws.String("AWSCURRENT"), // VersionStage defaults to AWSCURRENT if unspecified
+		return cfg, err
 	}
-
-	result, err := svc.GetSecretValue(context.TODO(), input)
-	if err != nil {
-		return SecretValue, err
-	}
-
-	SecretValue = []byte(*result.SecretString)
-
-	return SecretValue, err
-
+	otelaws.AppendMiddlewares(&cfg.APIOptions)
+	return cfg, nil
 }
@@ -0,0 +1,54 @@
+package commonweb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DecodeJSON strictly decodes the request body into v: fields that v does not
+// have, a second JSON value after the first and an empty body are refused.
+// The body size is limited by the MaxBodySize middleware. Errors are *Error
+// values that say what was wrong, suitable for RespondProblem.
+func DecodeJSON(r *http.Request, v any) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(v); err != nil {
+		return decodeError(err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return NewError(http.StatusBadRequest, CodeBadRequest, "Request body must contain a single JSON value")
+	}
+	return nil
+}
+
+func decodeError(err error) *Error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var tooLarge *http.MaxBytesError
+
+	switch {
+	case errors.Is(err, io.EOF):
+		return NewError(http.StatusBadRequest, CodeBadRequest, "Request body is empty")
+	case errors.As(err, &tooLarge):
+		return WrapError(err, http.StatusRequestEntityTooLarge, CodeTooLarge, fmt.Sprintf("Request body must not be larger than %d bytes", tooLarge.Limit))
+	case errors.As(err, &syntaxErr):
+		return WrapError(err, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("Malformed JSON at offset %d", syntaxErr.Offset))
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return WrapError(err, http.StatusBadRequest, CodeBadRequest, "Malformed JSON")
+	case errors.As(err, &typeErr):
+		if typeErr.Field != "" {
+			return WrapError(err, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("Field %q must be %s", typeErr.Field, typeErr.Type))
+		}
+		return WrapError(err, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("Request body must be %s", typeErr.Type))
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no error type for unknown fields
+		field := strings.TrimPrefix(err.Error(), "json: unknown field ")
+		return WrapError(err, http.StatusBadRequest, CodeBadRequest, "Unknown field "+field)
+	}
+	return WrapError(err, http.StatusBadRequest, CodeBadRequest, "Invalid request payload")
+}
@@ -2,66 +2,54 @@ package commonweb
 
 import (
 	"context"
-	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
 
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/htstinson/stinsondataapi/api/internal/secrets"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/gmail/v1"
 	"google.golang.org/api/option"
 )
 
-func getClient(oauthConfig *oauth2.Config, region string) *http.Client {
-	tok, err := tokenFromSecret("gmail-token", region)
+// Names of the Gmail secrets: the OAuth client downloaded from the Google
+// Cloud Console, and the token of the authorized account.
+const (
+	GmailCredentialsSecret = "gmail-credentials"
+	GmailTokenSecret       = "gmail-token"
+)
+
+// ErrNoGmailToken is returned when no token has been stored for the Gmail
+// account. The API cannot complete Google's consent screen itself, so the
+// token has to be obtained once elsewhere and saved as GmailTokenSecret.
+var ErrNoGmailToken = errors.New("no gmail token is stored; authorize the account and save its token as " + GmailTokenSecret)
+
+func getClient(ctx context.Context, store secrets.ReadWriter, oauthConfig *oauth2.Config) (*http.Client, error) {
+	tok, err := tokenFromSecret(ctx, store, GmailTokenSecret)
+	if errors.Is(err, secrets.ErrNotFound) {
+		return nil, ErrNoGmailToken
+	}
 	if err != nil {
-		tok = getTokenFromWeb(oauthConfig)
-		saveTokenToSecret("gmail-token", region, tok)
+		return nil, fmt.Errorf("cannot read gmail token: %w", err)
 	}
 
-	tokenSource := oauthConfig.TokenSource(context.Background(), tok)
+	tokenSource := oauthConfig.TokenSource(ctx, tok)
 
-	// Save refreshed token back to Secrets Manager
+	// Save refreshed token back to the secret store
 	newTok, err := tokenSource.Token()
 	if err == nil && newTok.AccessToken != tok.AccessToken {
-		saveTokenToSecret("gmail-token", region, newTok)
+		if err := saveTokenToSecret(ctx, store, GmailTokenSecret, newTok); err != nil {
+			return nil, err
+		}
 	}
 
-	return oauth2.NewClient(context.Background(), tokenSource)
+	return oauth2.NewClient(ctx, tokenSource), nil
 }
 
-func getTokenFromWeb(config *oauth2.Config) *oauth2.Token {
-	config.RedirectURL = "http://localhost:8080"
-
-	authURL := config.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
-	fmt.Println("Opening browser for authorization...")
-
-	codeCh := make(chan string)
-
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		code := r.URL.Query().Get("code")
-		fmt.Fprintln(w, "Authorization successful! You can close this tab.")
-		codeCh <- code
-	})
-
-	go http.ListenAndServe(":8080", nil)
-
-	// Open browser automatically
-	fmt.Printf("Visit this URL if browser doesn't open:\n%v\n", authURL)
-
-	tok, err := config.Exchange(context.TODO(), <-codeCh)
-	if err != nil {
-		log.Fatal("Unable to exchange token:", err)
-	}
-	return tok
-}
-
-func tokenFromSecret(secretName string, region string) (*oauth2.Token, error) {
-	data, err := GetSecretString(secretName, region)
+func tokenFromSecret(ctx context.Context, store secrets.Provider, secretName string) (*oauth2.Token, error) {
+	data, err := store.GetSecret(ctx, secretName)
 	if err != nil {
 		return nil, err
 	}
@@ -69,62 +57,39 @@ func tokenFromSecret(secretName string, region string) (*oauth2.Token, error) {
 	return tok, json.Unmarshal(data, tok)
 }
 
-func saveTokenToSecret(secretName string, region string, token *oauth2.Token) {
+func saveTokenToSecret(ctx context.Context, store secrets.Writer, secretName string, token *oauth2.Token) error {
 	data, err := json.Marshal(token)
 	if err != nil {
-		log.Fatal("Could not marshal token:", err)
+		return fmt.Errorf("could not marshal token: %w", err)
 	}
 
-	cfg, _ := config.LoadDefaultConfig(context.TODO(),
-		config.WithRegion(region))
-	client := secretsmanager.NewFromConfig(cfg)
-
-	secretStr := string(data)
-	_, err = client.UpdateSecret(context.TODO(), &secretsmanager.UpdateSecretInput{
-		SecretId:     &secretName,
-		SecretString: &secretStr,
-	})
-	if err != nil {
-		log.Fatal("Could not save token to Secrets Manager:", err)
+	if err := store.PutSecret(ctx, secretName, data); err != nil {
+		return fmt.Errorf("could not save token: %w", err)
 	}
+	return nil
 }
 
-func sendEmail(service *gmail.Service, to, subject, body string) error {
-	raw := fmt.Sprintf("To: %s\r\nSubject: %s\r\n\r\n%s", to, subject, body)
-	encoded := base64.URLEncoding.EncodeToString([]byte(raw))
-	msg := &gmail.Message{Raw: encoded}
-	_, err := service.Users.Messages.Send("me", msg).Do()
-	return err
-}
-
-func SendMail(to string, subject string, body string, region string) {
-	fmt.Println("commonweb SendMail")
-	ctx := context.Background()
-
-	// Download credentials.json from Google Cloud Console
-	b, err := GetSecretString("gmail-credentials", region)
+// GmailService returns a Gmail API client acting as the authorized account,
+// with the OAuth client credentials and token read from store. scopes must
+// be among those the token was granted.
+func GmailService(ctx context.Context, store secrets.ReadWriter, scopes ...string) (*gmail.Service, error) {
+	b, err := store.GetSecret(ctx, GmailCredentialsSecret)
 	if err != nil {
-		fmt.Println("1 ", err.Error())
-		log.Fatal("Cannot read gmail credentials:", err)
+		return nil, fmt.Errorf("cannot read gmail credentials: %w", err)
 	}
 
-	config, err := google.ConfigFromJSON(b, gmail.GmailSendScope)
+	config, err := google.ConfigFromJSON(b, scopes...)
 	if err != nil {
-		fmt.Println("2 ", err.Error())
-		log.Fatal("Cannot parse credentials:", err)
+		return nil, fmt.Errorf("cannot parse gmail credentials: %w", err)
 	}
 
-	client := getClient(config, region)
-	srv, err := gmail.NewService(ctx, option.WithHTTPClient(client))
+	client, err := getClient(ctx, store, config)
 	if err != nil {
-		fmt.Println("3 ", err.Error())
-		log.Fatal("Cannot create Gmail service:", err)
+		return nil, err
 	}
-
-	err = sendEmail(srv, to, subject, body)
+	srv, err := gmail.NewService(ctx, option.WithHTTPClient(client))
 	if err != nil {
-		fmt.Println("4 ", err.Error())
-		log.Fatal("Send failed:", err)
+		return nil, fmt.Errorf("cannot create gmail service: %w", err)
 	}
-	fmt.Println("Email sent!")
+	return srv, nil
 }
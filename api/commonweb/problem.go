@@ -0,0 +1,172 @@
+package commonweb
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// Machine readable error codes, sent as the problem "code" member and as
+// the last segment of its "type".
+const (
+	CodeBadRequest         = "bad_request"
+	CodeValidation         = "validation_failed"
+	CodeUnauthorized       = "unauthorized"
+	CodeTokenExpired       = "token_expired"
+	CodeInvalidToken       = "invalid_token"
+	CodeForbidden          = "forbidden"
+	CodeNotFound           = "not_found"
+	CodeMethodNotAllowed   = "method_not_allowed"
+	CodeConflict           = "conflict"
+	CodeInvalidReference   = "invalid_reference"
+	CodeTooLarge           = "payload_too_large"
+	CodeRateLimited        = "rate_limited"
+	CodeAccountLocked      = "account_locked"
+	CodeEmailUnverified    = "email_unverified"
+	CodeSubscriberRequired = "subscriber_required"
+	CodeInternal           = "internal_error"
+	CodeUnavailable        = "service_unavailable"
+)
+
+// ProblemTypeBase prefixes the code to form the problem "type" URI.
+const ProblemTypeBase = "urn:stinsondataapi:problem:"
+
+// Problem is an RFC 7807 problem details document, extended with the code
+// and the request ID to quote when reporting the error.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+	// Errors lists the problems with each invalid field of the request,
+	// keyed by the field's JSON name.
+	Errors map[string][]string `json:"errors,omitempty"`
+}
+
+// Error is an error that knows how it should be reported to the client.
+// Detail is shown to the client; Err, the cause, is only logged.
+type Error struct {
+	Status int
+	Code   string
+	Detail string
+	Fields map[string][]string
+	Err    error
+}
+
+// NewError returns an Error with no underlying cause.
+func NewError(status int, code string, detail string) *Error {
+	return &Error{Status: status, Code: code, Detail: detail}
+}
+
+// WrapError returns an Error reporting err with the given status, code and detail.
+func WrapError(err error, status int, code string, detail string) *Error {
+	return &Error{Status: status, Code: code, Detail: detail, Err: err}
+}
+
+// ValidationError is the 422 reported when fields fail validation.
+func ValidationError(fields map[string][]string) *Error {
+	return &Error{
+		Status: http.StatusUnprocessableEntity,
+		Code:   CodeValidation,
+		Detail: "The request has invalid fields",
+		Fields: fields,
+	}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Detail + ": " + e.Err.Error()
+	}
+	return e.Detail
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// CodeForStatus is the code used when an error only has a status.
+func CodeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return CodeTooLarge
+	case http.StatusUnprocessableEntity:
+		return CodeValidation
+	case http.StatusLocked:
+		return CodeAccountLocked
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	}
+	if status >= 500 {
+		return CodeInternal
+	}
+	return CodeBadRequest
+}
+
+// RespondProblem writes err as application/problem+json. An *Error anywhere
+// in the chain sets the status, code and detail; any other error is a 500
+// whose message is not shown. The request ID is taken from the X-Request-ID
+// response header set by the RequestID middleware.
+func RespondProblem(w http.ResponseWriter, err error) {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		apiErr = WrapError(err, http.StatusInternalServerError, CodeInternal, "")
+	}
+
+	status := apiErr.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	code := apiErr.Code
+	if code == "" {
+		code = CodeForStatus(status)
+	}
+
+	problem := Problem{
+		Type:      ProblemTypeBase + code,
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    apiErr.Detail,
+		Code:      code,
+		RequestID: w.Header().Get("X-Request-ID"),
+		Errors:    apiErr.Fields,
+	}
+	if problem.RequestID != "" {
+		problem.Instance = "urn:request:" + problem.RequestID
+	}
+
+	body, err := json.Marshal(problem)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// ProblemHandler answers every request with status, for use as a router's
+// NotFoundHandler or MethodNotAllowedHandler.
+func ProblemHandler(status int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		RespondProblem(w, NewError(status, CodeForStatus(status), http.StatusText(status)))
+	})
+}
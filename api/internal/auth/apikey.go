@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	common "github.com/htstinson/stinsondataapi/api/commonweb"
+	"github.com/htstinson/stinsondataapi/api/internal/logging"
+)
+
+// APIKeyScheme is the Authorization scheme of API keys, as in
+// "Authorization: ApiKey sdk_...".
+const APIKeyScheme = "ApiKey"
+
+// ErrInvalidAPIKey is returned by an APIKeyAuthenticator for a key that is
+// unknown, expired or revoked.
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+// APIKeyAuthenticator returns the claims a request made with an API key
+// acts with.
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(r *http.Request, key string) (*Claims, error)
+}
+
+// SetAPIKeys makes Middleware accept API keys, checked by authenticator,
+// alongside bearer tokens.
+func (a *JWTAuth) SetAPIKeys(authenticator APIKeyAuthenticator) {
+	a.apiKeys = authenticator
+}
+
+func (a *JWTAuth) serveAPIKey(w http.ResponseWriter, r *http.Request, key string, next http.Handler) {
+	claims, err := a.apiKeys.AuthenticateAPIKey(r, key)
+	if errors.Is(err, ErrInvalidAPIKey) {
+		unauthorized(w, common.CodeInvalidToken, "Invalid API key")
+		return
+	}
+	if err != nil {
+		common.RespondProblem(w, err)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), "user", claims)
+	logging.SetUser(ctx, claims.UserID)
+	if claims.SubscriberID != "" {
+		logging.SetSubscriber(ctx, claims.SubscriberID)
+	}
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// IsAPIKey reports whether the request was made with an API key rather
+// than a bearer token.
+func (c *Claims) IsAPIKey() bool {
+	return c.APIKeyID != ""
+}
+
+// HasPermission reports whether the claims allow permission. API keys only
+// allow the permissions they were created with; bearer tokens are limited
+// by their user's roles alone.
+func (c *Claims) HasPermission(permission string) bool {
+	if !c.IsAPIKey() {
+		return true
+	}
+	for _, p := range c.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// RequirePermission only lets through requests whose claims allow
+// permission, answering 403 otherwise. It must come after Middleware.
+func RequirePermission(permission string) func(http.Handler) http.Handler {
+	return RequireRoutePermission(func(*http.Request) string { return permission })
+}
+
+// RequireRoutePermission is RequirePermission for a middleware shared by
+// many routes: permission returns the permission the request's route needs.
+// API keys are refused any route it returns "" for. It must come after
+// Middleware.
+func RequireRoutePermission(permission func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := r.Context().Value("user").(*Claims)
+			if !ok {
+				common.RespondProblem(w, common.NewError(http.StatusForbidden, common.CodeForbidden, "Forbidden"))
+				return
+			}
+			if claims.IsAPIKey() {
+				required := permission(r)
+				if required == "" {
+					common.RespondProblem(w, common.NewError(http.StatusForbidden, common.CodeForbidden, "This cannot be done with an API key"))
+					return
+				}
+				if !claims.HasPermission(required) {
+					common.RespondProblem(w, common.NewError(http.StatusForbidden, common.CodeForbidden, "The "+required+" permission is required"))
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RefuseAPIKeys answers 403 to requests made with an API key, for routes
+// that manage the account itself and so need the user to have logged in.
+// It must come after Middleware.
+func RefuseAPIKeys(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := r.Context().Value("user").(*Claims)
+		if ok && claims.IsAPIKey() {
+			common.RespondProblem(w, common.NewError(http.StatusForbidden, common.CodeForbidden, "This cannot be done with an API key"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
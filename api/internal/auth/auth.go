@@ -5,12 +5,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"net"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	common "github.com/htstinson/stinsondataapi/api/commonweb"
+	"github.com/htstinson/stinsondataapi/api/internal/logging"
 	"github.com/htstinson/stinsondataapi/api/internal/model"
 )
 
@@ -19,55 +21,85 @@ var (
 	ErrExpiredToken = errors.New("token has expired")
 )
 
+// Claims are the claims of a bearer token. A token is scoped to at most one
+// subscriber, the active tenant, with the user's roles in it; Roles are the
+// user's roles across all of them. Requests made with an API key carry
+// claims too, with APIKeyID and the key's Permissions set.
 type Claims struct {
-	UserID     string                            `json:"user_id"`
-	Username   string                            `json:"username"`
-	Roles      string                            `json:"roles"`
-	IP_Address string                            `json:"ip_address"`
-	Subscribed []model.User_Subscriber_Role_View `json:"subscribed"`
+	UserID          string   `json:"user_id"`
+	Username        string   `json:"username"`
+	Roles           string   `json:"roles"`
+	IP_Address      string   `json:"ip_address"`
+	SubscriberID    string   `json:"subscriber_id,omitempty"`
+	SubscriberRoles []string `json:"subscriber_roles,omitempty"`
+	APIKeyID        string   `json:"api_key_id,omitempty"`
+	Permissions     []string `json:"permissions,omitempty"`
 	jwt.RegisteredClaims
 }
 
 type Config struct {
 	SecretKey     string
 	TokenDuration time.Duration
+	MFADuration   time.Duration
 }
 
 type JWTAuth struct {
-	Config Config
+	Config  Config
+	apiKeys APIKeyAuthenticator
 }
 
 func New(config Config) *JWTAuth {
 	return &JWTAuth{Config: config}
 }
 
-func (a *JWTAuth) GenerateToken(user model.User, roles model.Roles, subscribed []model.User_Subscriber_Role_View) (string, error) {
+// GenerateToken issues a token for user, scoped to tenant when it is not
+// nil.
+func (a *JWTAuth) GenerateToken(user model.User, roles model.Roles, tenant *model.Tenant) (string, error) {
 	now := time.Now()
 
-	fmt.Println("GenerateToken")
-
-	fmt.Println(user.IP_address)
-	fmt.Println(user.ID)
-
 	claims := Claims{
 		UserID:     user.ID,
 		Username:   user.Username,
 		Roles:      roles.Names,
 		IP_Address: user.IP_address,
-		Subscribed: subscribed,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(now.Add(a.Config.TokenDuration)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
 		},
 	}
+	claims.setTenant(tenant)
 
-	fmt.Println("Generate Token, subscribed len = ", len(subscribed))
+	slog.Debug("GenerateToken", "user_id", user.ID, "subscriber_id", claims.SubscriberID)
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(a.Config.SecretKey))
 }
 
+// SwitchTenant reissues the token of claims scoped to tenant, with roles
+// read afresh. It expires when the original token does, so that switching
+// does not extend a session.
+func (a *JWTAuth) SwitchTenant(claims *Claims, roles model.Roles, tenant *model.Tenant) (string, error) {
+	now := time.Now()
+
+	switched := *claims
+	switched.Roles = roles.Names
+	switched.IssuedAt = jwt.NewNumericDate(now)
+	switched.NotBefore = jwt.NewNumericDate(now)
+	switched.setTenant(tenant)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, switched)
+	return token.SignedString([]byte(a.Config.SecretKey))
+}
+
+func (c *Claims) setTenant(tenant *model.Tenant) {
+	c.SubscriberID, c.SubscriberRoles = "", nil
+	if tenant != nil {
+		c.SubscriberID = tenant.Subscriber_Id
+		c.SubscriberRoles = tenant.Roles
+	}
+}
+
 func (a *JWTAuth) ValidateToken(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
@@ -93,35 +125,71 @@ func (a *JWTAuth) ValidateToken(tokenString string) (*Claims, error) {
 
 func (a *JWTAuth) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		_, _, err := net.SplitHostPort(r.RemoteAddr)
-		if err != nil {
-			fmt.Println("handler err", err.Error())
-		}
-
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			http.Error(w, "Authorization header required", http.StatusUnauthorized)
+			unauthorized(w, common.CodeUnauthorized, "Authorization header required")
 			return
 		}
 
 		tokenParts := strings.Split(authHeader, " ")
+		if len(tokenParts) == 2 && tokenParts[0] == APIKeyScheme && a.apiKeys != nil {
+			a.serveAPIKey(w, r, tokenParts[1], next)
+			return
+		}
 		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
-			http.Error(w, "Invalid authorization header", http.StatusUnauthorized)
+			unauthorized(w, common.CodeUnauthorized, "Invalid authorization header")
 			return
 		}
 
 		claims, err := a.ValidateToken(tokenParts[1])
 		if err != nil {
 			if err == ErrExpiredToken {
-				http.Error(w, "Token has expired", http.StatusUnauthorized)
+				unauthorized(w, common.CodeTokenExpired, "Token has expired")
 				return
 			}
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+			unauthorized(w, common.CodeInvalidToken, "Invalid token")
 			return
 		}
 
 		// Add claims to request context
 		ctx := context.WithValue(r.Context(), "user", claims)
+		logging.SetUser(ctx, claims.UserID)
+		if claims.SubscriberID != "" {
+			logging.SetSubscriber(ctx, claims.SubscriberID)
+		}
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// unauthorized answers 401 with a Bearer challenge.
+func unauthorized(w http.ResponseWriter, code string, detail string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+	common.RespondProblem(w, common.NewError(http.StatusUnauthorized, code, detail))
+}
+
+// HasRole reports whether role is one of the user's roles. Roles holds the
+// role names as Postgres prints an array, such as {admin,user}.
+func (c *Claims) HasRole(role string) bool {
+	names := strings.Trim(c.Roles, "{}")
+	for _, name := range strings.Split(names, ",") {
+		if strings.Trim(strings.TrimSpace(name), `"`) == role {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireRole only lets through requests whose claims hold role, answering
+// 403 otherwise. It must come after Middleware.
+func RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := r.Context().Value("user").(*Claims)
+			if !ok || !claims.HasRole(role) {
+				common.RespondProblem(w, common.NewError(http.StatusForbidden, common.CodeForbidden, "The "+role+" role is required"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// MFA challenge purposes
+const (
+	MFAVerify = "verify"
+	MFAEnroll = "enroll"
+)
+
+// DefaultMFADuration is how long an MFA challenge lasts when
+// Config.MFADuration is not set.
+const DefaultMFADuration = 5 * time.Minute
+
+// MFAClaims are the claims of an MFA challenge token, issued after the
+// password is checked and exchanged for a token once the second factor is.
+type MFAClaims struct {
+	UserID  string `json:"user_id"`
+	Purpose string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+// GenerateMFAToken returns a challenge token for the user. It is signed with
+// a key derived from the secret key, so it is never accepted as a bearer
+// token, nor a bearer token as a challenge.
+func (a *JWTAuth) GenerateMFAToken(userID string, purpose string) (string, error) {
+	now := time.Now()
+
+	claims := MFAClaims{
+		UserID:  userID,
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(a.MFADuration())),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(a.mfaKey())
+}
+
+// ValidateMFAToken returns the claims of a challenge token.
+func (a *JWTAuth) ValidateMFAToken(tokenString string) (*MFAClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &MFAClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return a.mfaKey(), nil
+	})
+
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrExpiredToken
+		}
+		return nil, ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(*MFAClaims)
+	if !ok || !token.Valid || claims.UserID == "" {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+// MFADuration is how long challenge tokens last.
+func (a *JWTAuth) MFADuration() time.Duration {
+	if a.Config.MFADuration <= 0 {
+		return DefaultMFADuration
+	}
+	return a.Config.MFADuration
+}
+
+func (a *JWTAuth) mfaKey() []byte {
+	mac := hmac.New(sha256.New, []byte(a.Config.SecretKey))
+	mac.Write([]byte("mfa"))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,271 @@
+// Package certs builds the server TLS configuration. Certificates and the
+// client CA bundle are read from files and reloaded when the files change,
+// so that renewed certificates are served without a restart.
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Options is the TLS policy for a listener.
+type Options struct {
+	CertFile string
+	KeyFile  string
+	// MinVersion is "1.2" or "1.3".
+	MinVersion string
+	// CipherSuites are names as listed by tls.CipherSuites. They only apply
+	// to TLS 1.2; empty keeps the Go defaults.
+	CipherSuites []string
+	// ClientAuth is "none", "request", "verify_if_given" or "require". Any
+	// mode that verifies needs ClientCAFile.
+	ClientAuth   string
+	ClientCAFile string
+}
+
+var minVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"":                tls.NoClientCert,
+	"none":            tls.NoClientCert,
+	"request":         tls.RequestClientCert,
+	"verify_if_given": tls.VerifyClientCertIfGiven,
+	"require":         tls.RequireAndVerifyClientCert,
+}
+
+// Validate reports every problem with the policy.
+func (o Options) Validate() error {
+	var errs []error
+
+	if o.CertFile == "" || o.KeyFile == "" {
+		errs = append(errs, errors.New("cert_file and key_file are required"))
+	}
+	if _, ok := minVersions[o.MinVersion]; !ok {
+		errs = append(errs, fmt.Errorf("min_version must be 1.2 or 1.3, not %q", o.MinVersion))
+	}
+	if _, err := cipherSuites(o.CipherSuites); err != nil {
+		errs = append(errs, err)
+	}
+
+	clientAuth, ok := clientAuthTypes[o.ClientAuth]
+	if !ok {
+		errs = append(errs, fmt.Errorf("client_auth must be none, request, verify_if_given or require, not %q", o.ClientAuth))
+	} else if clientAuth >= tls.VerifyClientCertIfGiven && o.ClientCAFile == "" {
+		errs = append(errs, fmt.Errorf("client_auth %s needs client_ca_file", o.ClientAuth))
+	}
+
+	return errors.Join(errs...)
+}
+
+// cipherSuites maps names to IDs, refusing suites Go considers insecure.
+func cipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	known := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+
+	var ids []uint16
+	var unknown []string
+	for _, name := range names {
+		id, ok := known[strings.TrimSpace(name)]
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown or insecure cipher suites %s", strings.Join(unknown, ", "))
+	}
+	return ids, nil
+}
+
+// Reloader holds the current certificate and client CA pool and reloads them
+// when their files change.
+type Reloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	modTimes  map[string]time.Time
+}
+
+// NewReloader loads the certificate, key and, if caFile is set, the client
+// CA bundle.
+func NewReloader(certFile string, keyFile string, caFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile, caFile: caFile}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads the files again. On error the previous certificate is kept.
+func (r *Reloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("error loading certificate: %w", err)
+	}
+	if cert.Leaf == nil && len(cert.Certificate) > 0 {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return fmt.Errorf("error parsing certificate: %w", err)
+		}
+	}
+
+	var pool *x509.CertPool
+	if r.caFile != "" {
+		pem, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return fmt.Errorf("error reading client CA file: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in %s", r.caFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.clientCAs = pool
+	r.modTimes = r.statFiles()
+	r.mu.Unlock()
+
+	slog.Info("TLS certificate loaded", "subject", cert.Leaf.Subject.String(), "not_after", cert.Leaf.NotAfter.Format(time.RFC3339))
+	return nil
+}
+
+// Start checks the files every interval until ctx is cancelled and reloads
+// them when any has been modified.
+func (r *Reloader) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if !r.changed() {
+					continue
+				}
+				if err := r.Reload(); err != nil {
+					slog.ErrorContext(ctx, "TLS certificate reload failed", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// GetCertificate serves the current certificate; use it as tls.Config.GetCertificate.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// NotAfter is when the current certificate expires.
+func (r *Reloader) NotAfter() time.Time {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert.Leaf.NotAfter
+}
+
+func (r *Reloader) clientCAPool() *x509.CertPool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.clientCAs
+}
+
+func (r *Reloader) changed() bool {
+	current := r.statFiles()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for file, modTime := range current {
+		if !modTime.Equal(r.modTimes[file]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Reloader) statFiles() map[string]time.Time {
+	modTimes := make(map[string]time.Time, 3)
+	for _, file := range []string{r.certFile, r.keyFile, r.caFile} {
+		if file == "" {
+			continue
+		}
+		if info, err := os.Stat(file); err == nil {
+			modTimes[file] = info.ModTime()
+		}
+	}
+	return modTimes
+}
+
+// ServerConfig builds the tls.Config for opts, taking the certificate and
+// client CAs from reloader on every handshake.
+func ServerConfig(opts Options, reloader *Reloader) (*tls.Config, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	suites, _ := cipherSuites(opts.CipherSuites)
+
+	base := &tls.Config{
+		MinVersion:     minVersions[opts.MinVersion],
+		CipherSuites:   suites,
+		ClientAuth:     clientAuthTypes[opts.ClientAuth],
+		GetCertificate: reloader.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}
+
+	base.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		if base.ClientAuth == tls.NoClientCert {
+			return nil, nil
+		}
+		cfg := base.Clone()
+		cfg.GetConfigForClient = nil
+		cfg.ClientCAs = reloader.clientCAPool()
+		return cfg, nil
+	}
+
+	return base, nil
+}
+
+// RedirectHandler sends every request to the same host and path over HTTPS.
+// httpsPort is left out of the URL when it is 443.
+func RedirectHandler(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != "" && httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+
+		target := url.URL{Scheme: "https", Host: host, Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+		http.Redirect(w, r, target.String(), http.StatusPermanentRedirect)
+	})
+}
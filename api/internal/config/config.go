@@ -0,0 +1,884 @@
+// Package config loads the API configuration. Values come from the built in
+// defaults, then an optional YAML or TOML file, then environment variables.
+// Fields tagged secret may hold a reference such as "env:NAME",
+// "file:/path" or "secret:name" that is resolved after merging; "secret:"
+// references are read through the configured secrets provider.
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/htstinson/stinsondataapi/api/internal/certs"
+	"github.com/htstinson/stinsondataapi/api/internal/mail"
+	"github.com/htstinson/stinsondataapi/api/internal/middleware"
+	"github.com/htstinson/stinsondataapi/api/internal/password"
+	"github.com/htstinson/stinsondataapi/api/internal/secrets"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	Development = "development"
+	Staging     = "staging"
+	Production  = "production"
+)
+
+// DevelopmentJWTSecret is only accepted when Environment is development.
+const DevelopmentJWTSecret = "your-secret-key-for-development"
+
+type Config struct {
+	Environment    string     `yaml:"environment" toml:"environment" env:"APP_ENV"`
+	Region         string     `yaml:"region" toml:"region" env:"AWS_REGION"`
+	TrustedProxies []string   `yaml:"trusted_proxies" toml:"trusted_proxies" env:"TRUSTED_PROXIES"`
+	Server         Server     `yaml:"server" toml:"server"`
+	Database       Database   `yaml:"database" toml:"database"`
+	Auth           Auth       `yaml:"auth" toml:"auth"`
+	Log            Log        `yaml:"log" toml:"log"`
+	Metrics        Metrics    `yaml:"metrics" toml:"metrics"`
+	Tracing        Tracing    `yaml:"tracing" toml:"tracing"`
+	RateLimits     RateLimits `yaml:"rate_limits" toml:"rate_limits"`
+	Secrets        Secrets    `yaml:"secrets" toml:"secrets"`
+	CORS           CORS       `yaml:"cors" toml:"cors"`
+	Email          Email      `yaml:"email" toml:"email"`
+	Mailbox        Mailbox    `yaml:"mailbox" toml:"mailbox"`
+
+	provider *secrets.Cache
+	refs     map[string]string
+}
+
+type Server struct {
+	Addr            string        `yaml:"addr" toml:"addr" env:"SERVER_ADDR"`
+	DistPath        string        `yaml:"dist_path" toml:"dist_path" env:"DIST_PATH"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+	DrainDelay      time.Duration `yaml:"drain_delay" toml:"drain_delay" env:"DRAIN_DELAY"`
+	MaxBodyBytes    int           `yaml:"max_body_bytes" toml:"max_body_bytes" env:"MAX_BODY_BYTES"`
+	TLS             TLS           `yaml:"tls" toml:"tls"`
+}
+
+// TLS turns on HTTPS for Server.Addr. Certificates are reloaded when their
+// files change. ClientAuth enables mTLS for internal clients, and
+// RedirectAddr, when set, listens for plain HTTP and redirects to HTTPS.
+type TLS struct {
+	Enabled        bool          `yaml:"enabled" toml:"enabled" env:"TLS_ENABLED"`
+	CertFile       string        `yaml:"cert_file" toml:"cert_file" env:"TLS_CERT_FILE"`
+	KeyFile        string        `yaml:"key_file" toml:"key_file" env:"TLS_KEY_FILE"`
+	MinVersion     string        `yaml:"min_version" toml:"min_version" env:"TLS_MIN_VERSION"`
+	CipherSuites   []string      `yaml:"cipher_suites" toml:"cipher_suites" env:"TLS_CIPHER_SUITES"`
+	ClientAuth     string        `yaml:"client_auth" toml:"client_auth" env:"TLS_CLIENT_AUTH"`
+	ClientCAFile   string        `yaml:"client_ca_file" toml:"client_ca_file" env:"TLS_CLIENT_CA_FILE"`
+	ReloadInterval time.Duration `yaml:"reload_interval" toml:"reload_interval" env:"TLS_RELOAD_INTERVAL"`
+	RedirectAddr   string        `yaml:"redirect_addr" toml:"redirect_addr" env:"TLS_REDIRECT_ADDR"`
+}
+
+// Options is the TLS policy in the form the certs package takes.
+func (t TLS) Options() certs.Options {
+	return certs.Options{
+		CertFile:     t.CertFile,
+		KeyFile:      t.KeyFile,
+		MinVersion:   t.MinVersion,
+		CipherSuites: t.CipherSuites,
+		ClientAuth:   t.ClientAuth,
+		ClientCAFile: t.ClientCAFile,
+	}
+}
+
+// Database holds the connection settings. Credentials is a secret reference
+// to a JSON document with host, port, username and password, as stored in
+// Secrets Manager for RDS.
+type Database struct {
+	Credentials string `yaml:"credentials" toml:"credentials" env:"DB_CREDENTIALS" secret:"true"`
+	Name        string `yaml:"name" toml:"name" env:"DB_NAME"`
+	SearchPath  string `yaml:"search_path" toml:"search_path" env:"DB_SEARCH_PATH"`
+	SSLMode     string `yaml:"ssl_mode" toml:"ssl_mode" env:"DB_SSL_MODE"`
+}
+
+type Auth struct {
+	JWTSecret     string        `yaml:"jwt_secret" toml:"jwt_secret" env:"JWT_SECRET_KEY" secret:"true"`
+	TokenDuration time.Duration `yaml:"token_duration" toml:"token_duration" env:"JWT_TOKEN_DURATION"`
+	Password      Password      `yaml:"password" toml:"password" env:"PASSWORD"`
+	Lockout       Lockout       `yaml:"lockout" toml:"lockout" env:"LOGIN_LOCKOUT"`
+	MFA           MFA           `yaml:"mfa" toml:"mfa" env:"MFA"`
+	OIDC          OIDC          `yaml:"oidc" toml:"oidc" env:"OIDC"`
+	Invitations   Invitations   `yaml:"invitations" toml:"invitations" env:"INVITATION"`
+	Registration  Registration  `yaml:"registration" toml:"registration" env:"REGISTRATION"`
+	APIKeys       APIKeys       `yaml:"api_keys" toml:"api_keys" env:"API_KEY"`
+}
+
+// APIKeys bounds how long API keys last: DefaultTTL when the key does not
+// say, and never longer than MaxTTL.
+type APIKeys struct {
+	DefaultTTL time.Duration `yaml:"default_ttl" toml:"default_ttl" env:"_DEFAULT_TTL"`
+	MaxTTL     time.Duration `yaml:"max_ttl" toml:"max_ttl" env:"_MAX_TTL"`
+}
+
+// Registration is who may register themselves: nobody ("disabled"), only
+// invited emails ("invite"), or anyone who then verifies their email
+// ("open"). Verification links are VerifyURL with the token appended, and
+// last VerifyTTL. Registrations still unverified after CleanupAfter are
+// deleted.
+type Registration struct {
+	Mode         string        `yaml:"mode" toml:"mode" env:"_MODE"`
+	VerifyTTL    time.Duration `yaml:"verify_ttl" toml:"verify_ttl" env:"_VERIFY_TTL"`
+	VerifyURL    string        `yaml:"verify_url" toml:"verify_url" env:"_VERIFY_URL"`
+	CleanupAfter time.Duration `yaml:"cleanup_after" toml:"cleanup_after" env:"_CLEANUP_AFTER"`
+}
+
+// Invitations are sent as links that are URL with the token appended as the
+// token query parameter, or as the bare token without URL, and can be
+// accepted until TTL has passed.
+type Invitations struct {
+	TTL time.Duration `yaml:"ttl" toml:"ttl" env:"_TTL"`
+	URL string        `yaml:"url" toml:"url" env:"_URL"`
+}
+
+// MFA configures TOTP second factors. Key encrypts the stored secrets and
+// is derived from the JWT secret when empty; changing it makes existing
+// enrolments unusable. Issuer is the name authenticator apps show, and
+// ChallengeTTL how long a login has to give its code.
+type MFA struct {
+	Key          string        `yaml:"key" toml:"key" env:"_KEY" secret:"true"`
+	Issuer       string        `yaml:"issuer" toml:"issuer" env:"_ISSUER"`
+	ChallengeTTL time.Duration `yaml:"challenge_ttl" toml:"challenge_ttl" env:"_CHALLENGE_TTL"`
+}
+
+// Password is the policy for new passwords and the reset flow. BreachedList
+// is a file of passwords that are refused, in the forms password.NewPolicy
+// reads. Reset links are ResetURL with the token appended as the token query
+// parameter; without ResetURL the email holds the bare token.
+type Password struct {
+	MinLength    int           `yaml:"min_length" toml:"min_length" env:"_MIN_LENGTH"`
+	BreachedList string        `yaml:"breached_list" toml:"breached_list" env:"_BREACHED_LIST"`
+	ResetTTL     time.Duration `yaml:"reset_ttl" toml:"reset_ttl" env:"_RESET_TTL"`
+	ResetURL     string        `yaml:"reset_url" toml:"reset_url" env:"_RESET_URL"`
+}
+
+// Lockout paces logins after consecutive failures, as password.Lockout
+// describes. A zero threshold never locks accounts.
+type Lockout struct {
+	DelayAfter int           `yaml:"delay_after" toml:"delay_after" env:"_DELAY_AFTER"`
+	BaseDelay  time.Duration `yaml:"base_delay" toml:"base_delay" env:"_BASE_DELAY"`
+	MaxDelay   time.Duration `yaml:"max_delay" toml:"max_delay" env:"_MAX_DELAY"`
+	Threshold  int           `yaml:"threshold" toml:"threshold" env:"_THRESHOLD"`
+	Duration   time.Duration `yaml:"duration" toml:"duration" env:"_DURATION"`
+}
+
+// Options is the lockout in the form the password package takes.
+func (l Lockout) Options() password.Lockout {
+	return password.Lockout{
+		DelayAfter: l.DelayAfter,
+		BaseDelay:  l.BaseDelay,
+		MaxDelay:   l.MaxDelay,
+		Threshold:  l.Threshold,
+		Duration:   l.Duration,
+	}
+}
+
+// OIDC configures single sign-on through the subscribers' OpenID providers.
+// RedirectURL is this API's /sso/callback as registered with the providers;
+// sign-on is off without it. Completed sign-ons are sent to FrontendURL with
+// the login response in the URL fragment, or answered as JSON when it is
+// empty. StateTTL is how long a user has to sign in at the provider.
+type OIDC struct {
+	RedirectURL string        `yaml:"redirect_url" toml:"redirect_url" env:"_REDIRECT_URL"`
+	FrontendURL string        `yaml:"frontend_url" toml:"frontend_url" env:"_FRONTEND_URL"`
+	StateTTL    time.Duration `yaml:"state_ttl" toml:"state_ttl" env:"_STATE_TTL"`
+}
+
+type Log struct {
+	Level  string `yaml:"level" toml:"level" env:"LOG_LEVEL"`
+	Format string `yaml:"format" toml:"format" env:"LOG_FORMAT"`
+}
+
+// Metrics are served on Addr when set, otherwise on the main listener only
+// when Token is set.
+type Metrics struct {
+	Addr  string `yaml:"addr" toml:"addr" env:"METRICS_ADDR"`
+	Token string `yaml:"token" toml:"token" env:"METRICS_TOKEN" secret:"true"`
+}
+
+type Tracing struct {
+	Exporter    string `yaml:"exporter" toml:"exporter" env:"OTEL_TRACES_EXPORTER"`
+	ServiceName string `yaml:"service_name" toml:"service_name" env:"OTEL_SERVICE_NAME"`
+}
+
+// RateLimit is a pair of limits written as middleware.ParseLimit expects.
+type RateLimit struct {
+	IP   string `yaml:"ip" toml:"ip" env:"_IP"`
+	User string `yaml:"user" toml:"user" env:"_USER"`
+}
+
+type RateLimits struct {
+	API       RateLimit `yaml:"api" toml:"api" env:"RATE_LIMIT_API"`
+	Auth      RateLimit `yaml:"auth" toml:"auth" env:"RATE_LIMIT_AUTH"`
+	Protected RateLimit `yaml:"protected" toml:"protected" env:"RATE_LIMIT_PROTECTED"`
+}
+
+// CORS holds the cross-origin policy of each route group. When no origins
+// are set in development, local front ends on any port are allowed.
+type CORS struct {
+	Public    CORSPolicy `yaml:"public" toml:"public" env:"CORS_PUBLIC"`
+	Protected CORSPolicy `yaml:"protected" toml:"protected" env:"CORS_PROTECTED"`
+}
+
+// CORSPolicy accepts origins in the forms middleware.CORSOptions describes.
+type CORSPolicy struct {
+	AllowedOrigins   []string      `yaml:"allowed_origins" toml:"allowed_origins" env:"_ALLOWED_ORIGINS"`
+	AllowCredentials bool          `yaml:"allow_credentials" toml:"allow_credentials" env:"_ALLOW_CREDENTIALS"`
+	AllowedHeaders   []string      `yaml:"allowed_headers" toml:"allowed_headers" env:"_ALLOWED_HEADERS"`
+	ExposedHeaders   []string      `yaml:"exposed_headers" toml:"exposed_headers" env:"_EXPOSED_HEADERS"`
+	MaxAge           time.Duration `yaml:"max_age" toml:"max_age" env:"_MAX_AGE"`
+}
+
+// DevelopmentOrigins are allowed in development when no origins are set.
+var DevelopmentOrigins = []string{`re:http://(localhost|127\.0\.0\.1)(:\d+)?`}
+
+// Options is the policy in the form the middleware package takes.
+func (p CORSPolicy) Options() middleware.CORSOptions {
+	return middleware.CORSOptions{
+		AllowedOrigins:   p.AllowedOrigins,
+		AllowCredentials: p.AllowCredentials,
+		AllowedHeaders:   p.AllowedHeaders,
+		ExposedHeaders:   p.ExposedHeaders,
+		MaxAge:           p.MaxAge,
+	}
+}
+
+// Secrets selects the provider behind "secret:" references: "aws" (at
+// Endpoint when set, otherwise in Region), "env" (variables named EnvPrefix
+// plus the upper cased name), "file" (files under Dir) or "vault".
+type Secrets struct {
+	Provider  string        `yaml:"provider" toml:"provider" env:"SECRETS_PROVIDER"`
+	Endpoint  string        `yaml:"endpoint" toml:"endpoint" env:"SECRETS_ENDPOINT"`
+	TTL       time.Duration `yaml:"ttl" toml:"ttl" env:"SECRETS_TTL"`
+	EnvPrefix string        `yaml:"env_prefix" toml:"env_prefix" env:"SECRETS_ENV_PREFIX"`
+	Dir       string        `yaml:"dir" toml:"dir" env:"SECRETS_DIR"`
+	Vault     Vault         `yaml:"vault" toml:"vault"`
+}
+
+// Vault locates a KV version 2 engine. Token may be an env: or file:
+// reference but not a secret: one.
+type Vault struct {
+	Addr      string `yaml:"addr" toml:"addr" env:"VAULT_ADDR"`
+	Token     string `yaml:"token" toml:"token" env:"VAULT_TOKEN" secret:"true"`
+	Mount     string `yaml:"mount" toml:"mount" env:"VAULT_MOUNT"`
+	Namespace string `yaml:"namespace" toml:"namespace" env:"VAULT_NAMESPACE"`
+}
+
+// Email selects how queued email is sent: "smtp" (also for a local mail
+// catcher such as Mailpit), "gmail" (the account whose token is in the
+// gmail-token secret), "ses" (in Region) or "file" (.eml files under Dir).
+// The outbox is worked through every Interval and each message tried up to
+// MaxAttempts times. Unsubscribe links are UnsubscribeURL with the token
+// appended, and are left out without it. LoginNotices emails users who have
+// not set their notification preferences when they log in.
+type Email struct {
+	Provider       string        `yaml:"provider" toml:"provider" env:"EMAIL_PROVIDER"`
+	From           string        `yaml:"from" toml:"from" env:"EMAIL_FROM"`
+	SMTP           SMTP          `yaml:"smtp" toml:"smtp"`
+	Dir            string        `yaml:"dir" toml:"dir" env:"EMAIL_DIR"`
+	Interval       time.Duration `yaml:"interval" toml:"interval" env:"EMAIL_INTERVAL"`
+	MaxAttempts    int           `yaml:"max_attempts" toml:"max_attempts" env:"EMAIL_MAX_ATTEMPTS"`
+	UnsubscribeURL string        `yaml:"unsubscribe_url" toml:"unsubscribe_url" env:"EMAIL_UNSUBSCRIBE_URL"`
+	LoginNotices   bool          `yaml:"login_notices" toml:"login_notices" env:"EMAIL_LOGIN_NOTICES"`
+}
+
+// SMTP is the server Email is sent through with the smtp provider. Without
+// Username it is sent unauthenticated.
+type SMTP struct {
+	Addr     string `yaml:"addr" toml:"addr" env:"EMAIL_SMTP_ADDR"`
+	Username string `yaml:"username" toml:"username" env:"EMAIL_SMTP_USERNAME"`
+	Password string `yaml:"password" toml:"password" env:"EMAIL_SMTP_PASSWORD" secret:"true"`
+}
+
+// Mailbox reads the Gmail labels subscribers' mailbox sources name every
+// Interval, as the account whose token is in the gmail-token secret, which
+// needs the gmail.readonly scope. A zero Interval turns reading off. A new
+// source also reads the messages of the Lookback before it was created.
+// Endpoint points the reader at another Gmail API, such as a local fake,
+// which is called without credentials.
+type Mailbox struct {
+	Interval time.Duration `yaml:"interval" toml:"interval" env:"MAILBOX_INTERVAL"`
+	Lookback time.Duration `yaml:"lookback" toml:"lookback" env:"MAILBOX_LOOKBACK"`
+	Endpoint string        `yaml:"endpoint" toml:"endpoint" env:"MAILBOX_ENDPOINT"`
+}
+
+// Options is the provider selection in the form the mail package takes.
+func (e Email) Options(region string) mail.Options {
+	return mail.Options{
+		Provider: e.Provider,
+		SMTP: mail.SMTPOptions{
+			Addr:     e.SMTP.Addr,
+			Username: e.SMTP.Username,
+			Password: e.SMTP.Password,
+		},
+		Region: region,
+		Dir:    e.Dir,
+	}
+}
+
+// Default returns the configuration used when nothing overrides it.
+func Default() Config {
+	return Config{
+		Environment: Production,
+		Region:      "us-west-2",
+		Server: Server{
+			Addr:            ":8080",
+			DistPath:        "/home/ec2-user/go/src/stinsondata-tools-reactapp/dist",
+			ShutdownTimeout: 5 * time.Second,
+			DrainDelay:      5 * time.Second,
+			MaxBodyBytes:    1 << 20,
+			TLS: TLS{
+				MinVersion:     "1.2",
+				ClientAuth:     "none",
+				ReloadInterval: time.Minute,
+			},
+		},
+		Database: Database{
+			Credentials: "secret:RDS/apidb",
+			Name:        "apidb",
+			SearchPath:  "common",
+			SSLMode:     "require",
+		},
+		Auth: Auth{
+			JWTSecret:     "env:JWT_SECRET_KEY",
+			TokenDuration: 24 * time.Hour,
+			Password: Password{
+				MinLength: 8,
+				ResetTTL:  time.Hour,
+			},
+			Lockout: Lockout{
+				DelayAfter: 3,
+				BaseDelay:  time.Second,
+				MaxDelay:   30 * time.Second,
+				Threshold:  10,
+				Duration:   15 * time.Minute,
+			},
+			MFA: MFA{
+				Issuer:       "Thousand Hills Digital",
+				ChallengeTTL: 5 * time.Minute,
+			},
+			OIDC: OIDC{
+				StateTTL: 10 * time.Minute,
+			},
+			Invitations: Invitations{
+				TTL: 7 * 24 * time.Hour,
+			},
+			Registration: Registration{
+				Mode:         "open",
+				VerifyTTL:    24 * time.Hour,
+				CleanupAfter: 7 * 24 * time.Hour,
+			},
+			APIKeys: APIKeys{
+				DefaultTTL: 90 * 24 * time.Hour,
+				MaxTTL:     365 * 24 * time.Hour,
+			},
+		},
+		Log: Log{
+			Level:  "info",
+			Format: "json",
+		},
+		Tracing: Tracing{
+			Exporter:    "none",
+			ServiceName: "stinsondataapi",
+		},
+		RateLimits: RateLimits{
+			API:       RateLimit{IP: "20/s:40"},
+			Auth:      RateLimit{IP: "10/m:5"},
+			Protected: RateLimit{User: "10/s:30"},
+		},
+		CORS: CORS{
+			Public: CORSPolicy{
+				AllowedHeaders: []string{"Content-Type", "Authorization"},
+				ExposedHeaders: []string{"Content-Type", "Authorization"},
+				MaxAge:         10 * time.Minute,
+			},
+			Protected: CORSPolicy{
+				AllowedHeaders: []string{"Content-Type", "Authorization"},
+				ExposedHeaders: []string{"Content-Type", "Authorization"},
+				MaxAge:         10 * time.Minute,
+			},
+		},
+		Secrets: Secrets{
+			Provider:  "aws",
+			TTL:       5 * time.Minute,
+			EnvPrefix: "SECRET_",
+			Dir:       "/run/secrets",
+			Vault:     Vault{Mount: "secret"},
+		},
+		Email: Email{
+			Provider:    "gmail",
+			Dir:         "mail",
+			Interval:    30 * time.Second,
+			MaxAttempts: 8,
+		},
+		Mailbox: Mailbox{
+			Interval: 5 * time.Minute,
+			Lookback: 7 * 24 * time.Hour,
+		},
+	}
+}
+
+// Load merges the defaults, the file at path (if any) and the environment,
+// resolves secret references, and validates the result.
+func Load(ctx context.Context, path string) (Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		if err := loadFile(path, &cfg); err != nil {
+			return cfg, err
+		}
+	}
+
+	if err := applyEnv(reflect.ValueOf(&cfg).Elem(), ""); err != nil {
+		return cfg, err
+	}
+
+	// The provider's own credentials cannot come from the provider
+	cfg.refs = make(map[string]string)
+	if err := cfg.resolveSecrets(ctx, reflect.ValueOf(&cfg.Secrets).Elem(), "secrets"); err != nil {
+		return cfg, err
+	}
+
+	provider, err := secrets.New(ctx, secrets.Options{
+		Provider:  cfg.Secrets.Provider,
+		Region:    cfg.Region,
+		Endpoint:  cfg.Secrets.Endpoint,
+		EnvPrefix: cfg.Secrets.EnvPrefix,
+		Dir:       cfg.Secrets.Dir,
+		Vault: secrets.VaultOptions{
+			Addr:      cfg.Secrets.Vault.Addr,
+			Token:     cfg.Secrets.Vault.Token,
+			Mount:     cfg.Secrets.Vault.Mount,
+			Namespace: cfg.Secrets.Vault.Namespace,
+		},
+		TTL: cfg.Secrets.TTL,
+	})
+	if err != nil {
+		return cfg, err
+	}
+	cfg.provider = provider
+
+	if err := cfg.resolveSecrets(ctx, reflect.ValueOf(&cfg).Elem(), ""); err != nil {
+		return cfg, err
+	}
+
+	if cfg.Environment == Development {
+		if cfg.Auth.JWTSecret == "" {
+			cfg.Auth.JWTSecret = DevelopmentJWTSecret
+		}
+		for _, policy := range []*CORSPolicy{&cfg.CORS.Public, &cfg.CORS.Protected} {
+			if len(policy.AllowedOrigins) == 0 {
+				policy.AllowedOrigins = DevelopmentOrigins
+			}
+		}
+	}
+
+	return cfg, cfg.Validate()
+}
+
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(cfg); err != nil {
+			return fmt.Errorf("error parsing %s: %w", path, err)
+		}
+	case ".toml":
+		meta, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return fmt.Errorf("error parsing %s: %w", path, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("error parsing %s: unknown keys %v", path, undecoded)
+		}
+	default:
+		return fmt.Errorf("unsupported config file %s, expected .yaml, .yml or .toml", path)
+	}
+
+	return nil
+}
+
+// applyEnv overrides fields tagged env with the variables that are set. The
+// env tag of a struct field is a prefix for the tags of its fields that start
+// with "_", so the RateLimit tagged RATE_LIMIT_API reads RATE_LIMIT_API_IP
+// and RATE_LIMIT_API_USER.
+func applyEnv(v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		value := v.Field(i)
+
+		name := field.Tag.Get("env")
+		if strings.HasPrefix(name, "_") {
+			name = prefix + name
+		}
+
+		if field.Type.Kind() == reflect.Struct {
+			if err := applyEnv(value, name); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if name == "" {
+			continue
+		}
+		s, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := setValue(value, s); err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func setValue(v reflect.Value, s string) error {
+	switch {
+	case v.Type() == reflect.TypeOf(time.Duration(0)):
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(s)
+	case v.Kind() == reflect.Int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// IsSecretRef reports whether s names a secret rather than holding its value.
+func IsSecretRef(s string) bool {
+	for _, scheme := range []string{"env:", "file:", "secret:"} {
+		if strings.HasPrefix(s, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// SecretProvider is the cached provider that "secret:" references are read
+// through, for everything else that needs a secret.
+func (c Config) SecretProvider() *secrets.Cache {
+	return c.provider
+}
+
+// Secret resolves the field at path, such as "database.credentials", again,
+// so that rotated values are picked up. A field that was not set from a
+// reference returns its loaded value.
+func (c Config) Secret(ctx context.Context, path string) (string, error) {
+	ref, ok := c.refs[path]
+	if !ok {
+		return "", fmt.Errorf("%s is not a secret field", path)
+	}
+	if !IsSecretRef(ref) {
+		return ref, nil
+	}
+	return c.resolveSecret(ctx, ref)
+}
+
+// SecretName returns the name of the secret the field at path references,
+// and false when it is not set from a "secret:" reference.
+func (c Config) SecretName(path string) (string, bool) {
+	return strings.CutPrefix(c.refs[path], "secret:")
+}
+
+// resolveSecret reads the value behind a secret reference.
+func (c Config) resolveSecret(ctx context.Context, ref string) (string, error) {
+	scheme, name, _ := strings.Cut(ref, ":")
+	switch scheme {
+	case "env":
+		return os.Getenv(name), nil
+	case "file":
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(data)), nil
+	case "secret":
+		if c.provider == nil {
+			return "", fmt.Errorf("%q cannot be read before the secrets provider is configured", ref)
+		}
+		return secrets.GetString(ctx, c.provider, name)
+	}
+	return "", fmt.Errorf("unknown secret reference %q", ref)
+}
+
+// resolveSecrets replaces the references in fields tagged secret with their
+// values, remembering each reference by its path for Secret.
+func (c Config) resolveSecrets(ctx context.Context, v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		value := v.Field(i)
+
+		path := field.Tag.Get("yaml")
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+
+		if field.Type.Kind() == reflect.Struct {
+			if err := c.resolveSecrets(ctx, value, path); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if field.Tag.Get("secret") != "true" {
+			continue
+		}
+		if _, done := c.refs[path]; done {
+			continue
+		}
+		c.refs[path] = value.String()
+
+		if !IsSecretRef(value.String()) {
+			continue
+		}
+		resolved, err := c.resolveSecret(ctx, value.String())
+		if err != nil {
+			return fmt.Errorf("error resolving %s: %w", path, err)
+		}
+		value.SetString(resolved)
+	}
+	return nil
+}
+
+// Validate checks the configuration and refuses insecure settings outside
+// development. All problems are reported together.
+func (c Config) Validate() error {
+	var errs []error
+
+	switch c.Environment {
+	case Development, Staging, Production:
+	default:
+		errs = append(errs, fmt.Errorf("environment must be %s, %s or %s, not %q", Development, Staging, Production, c.Environment))
+	}
+
+	if c.Region == "" {
+		errs = append(errs, errors.New("region is required"))
+	}
+	if c.Server.Addr == "" {
+		errs = append(errs, errors.New("server.addr is required"))
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("server.shutdown_timeout must be positive"))
+	}
+	if c.Server.DrainDelay < 0 {
+		errs = append(errs, errors.New("server.drain_delay cannot be negative"))
+	}
+	if c.Server.MaxBodyBytes <= 0 {
+		errs = append(errs, errors.New("server.max_body_bytes must be positive"))
+	}
+	if c.Server.TLS.Enabled {
+		if err := c.Server.TLS.Options().Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("server.tls: %w", err))
+		}
+		if c.Server.TLS.ReloadInterval < 0 {
+			errs = append(errs, errors.New("server.tls.reload_interval cannot be negative"))
+		}
+	} else if c.Server.TLS.RedirectAddr != "" {
+		errs = append(errs, errors.New("server.tls.redirect_addr needs server.tls.enabled"))
+	}
+	if c.Database.Credentials == "" {
+		errs = append(errs, errors.New("database.credentials is required"))
+	}
+	if c.Database.Name == "" {
+		errs = append(errs, errors.New("database.name is required"))
+	}
+	if c.Auth.TokenDuration <= 0 {
+		errs = append(errs, errors.New("auth.token_duration must be positive"))
+	}
+	if c.Auth.JWTSecret == "" {
+		errs = append(errs, errors.New("auth.jwt_secret is required"))
+	}
+	if c.Auth.Password.MinLength < 1 || c.Auth.Password.MinLength > password.MaxLength {
+		errs = append(errs, fmt.Errorf("auth.password.min_length must be between 1 and %d", password.MaxLength))
+	}
+	if c.Auth.Password.ResetTTL <= 0 {
+		errs = append(errs, errors.New("auth.password.reset_ttl must be positive"))
+	}
+	lockout := c.Auth.Lockout
+	if lockout.DelayAfter < 0 || lockout.Threshold < 0 || lockout.BaseDelay < 0 || lockout.MaxDelay < 0 || lockout.Duration < 0 {
+		errs = append(errs, errors.New("auth.lockout values cannot be negative"))
+	}
+	if lockout.Threshold > 0 && lockout.Duration == 0 {
+		errs = append(errs, errors.New("auth.lockout.duration must be set with auth.lockout.threshold"))
+	}
+	if c.Auth.MFA.ChallengeTTL <= 0 {
+		errs = append(errs, errors.New("auth.mfa.challenge_ttl must be positive"))
+	}
+	if c.Auth.MFA.Issuer == "" {
+		errs = append(errs, errors.New("auth.mfa.issuer is required"))
+	}
+	if c.Auth.Invitations.TTL <= 0 {
+		errs = append(errs, errors.New("auth.invitations.ttl must be positive"))
+	}
+	if !slices.Contains([]string{"disabled", "invite", "open"}, c.Auth.Registration.Mode) {
+		errs = append(errs, fmt.Errorf("auth.registration.mode %q must be disabled, invite or open", c.Auth.Registration.Mode))
+	}
+	if c.Auth.Registration.VerifyTTL <= 0 {
+		errs = append(errs, errors.New("auth.registration.verify_ttl must be positive"))
+	}
+	if c.Auth.Registration.CleanupAfter < c.Auth.Registration.VerifyTTL {
+		errs = append(errs, errors.New("auth.registration.cleanup_after cannot be shorter than auth.registration.verify_ttl"))
+	}
+	if c.Auth.APIKeys.DefaultTTL <= 0 {
+		errs = append(errs, errors.New("auth.api_keys.default_ttl must be positive"))
+	}
+	if c.Auth.APIKeys.MaxTTL < c.Auth.APIKeys.DefaultTTL {
+		errs = append(errs, errors.New("auth.api_keys.max_ttl cannot be shorter than auth.api_keys.default_ttl"))
+	}
+	if c.Auth.OIDC.StateTTL <= 0 {
+		errs = append(errs, errors.New("auth.oidc.state_ttl must be positive"))
+	}
+	if u, err := url.Parse(c.Auth.OIDC.RedirectURL); c.Auth.OIDC.RedirectURL != "" && (err != nil || !u.IsAbs()) {
+		errs = append(errs, errors.New("auth.oidc.redirect_url must be an absolute URL"))
+	}
+	if u, err := url.Parse(c.Auth.OIDC.FrontendURL); c.Auth.OIDC.FrontendURL != "" && (err != nil || !u.IsAbs()) {
+		errs = append(errs, errors.New("auth.oidc.frontend_url must be an absolute URL"))
+	}
+	switch c.Email.Provider {
+	case "smtp":
+		if c.Email.SMTP.Addr == "" {
+			errs = append(errs, errors.New("email.smtp.addr is required with the smtp provider"))
+		}
+		if c.Email.From == "" {
+			errs = append(errs, errors.New("email.from is required with the smtp provider"))
+		}
+	case "ses":
+		if c.Email.From == "" {
+			errs = append(errs, errors.New("email.from is required with the ses provider"))
+		}
+	case "file":
+		if c.Email.Dir == "" {
+			errs = append(errs, errors.New("email.dir is required with the file provider"))
+		}
+	case "gmail":
+	default:
+		errs = append(errs, fmt.Errorf("email.provider %q must be smtp, gmail, ses or file", c.Email.Provider))
+	}
+	if c.Email.Interval <= 0 {
+		errs = append(errs, errors.New("email.interval must be positive"))
+	}
+	if c.Email.MaxAttempts < 1 {
+		errs = append(errs, errors.New("email.max_attempts must be at least 1"))
+	}
+	if u, err := url.Parse(c.Email.UnsubscribeURL); c.Email.UnsubscribeURL != "" && (err != nil || !u.IsAbs()) {
+		errs = append(errs, errors.New("email.unsubscribe_url must be an absolute URL"))
+	}
+	if c.Mailbox.Interval < 0 {
+		errs = append(errs, errors.New("mailbox.interval cannot be negative"))
+	}
+	if c.Mailbox.Lookback < 0 {
+		errs = append(errs, errors.New("mailbox.lookback cannot be negative"))
+	}
+	if u, err := url.Parse(c.Mailbox.Endpoint); c.Mailbox.Endpoint != "" && (err != nil || !u.IsAbs()) {
+		errs = append(errs, errors.New("mailbox.endpoint must be an absolute URL"))
+	}
+	if _, err := middleware.NewCORSPolicy(c.CORS.Public.Options()); err != nil {
+		errs = append(errs, fmt.Errorf("cors.public: %w", err))
+	}
+	if _, err := middleware.NewCORSPolicy(c.CORS.Protected.Options()); err != nil {
+		errs = append(errs, fmt.Errorf("cors.protected: %w", err))
+	}
+	if c.Secrets.TTL < 0 {
+		errs = append(errs, errors.New("secrets.ttl cannot be negative"))
+	}
+	if u, err := url.Parse(c.Secrets.Endpoint); c.Secrets.Endpoint != "" && (err != nil || !u.IsAbs()) {
+		errs = append(errs, errors.New("secrets.endpoint must be an absolute URL"))
+	}
+
+	if c.Environment != Development {
+		if c.Auth.JWTSecret == DevelopmentJWTSecret {
+			errs = append(errs, fmt.Errorf("auth.jwt_secret is the development default, which is not allowed in %s", c.Environment))
+		} else if c.Auth.JWTSecret != "" && len(c.Auth.JWTSecret) < 32 {
+			errs = append(errs, fmt.Errorf("auth.jwt_secret must be at least 32 characters in %s", c.Environment))
+		}
+		if slices.Contains(c.CORS.Protected.AllowedOrigins, "*") {
+			errs = append(errs, fmt.Errorf("cors.protected.allowed_origins cannot be \"*\" in %s", c.Environment))
+		}
+		if c.Database.SSLMode == "disable" || c.Database.SSLMode == "allow" || c.Database.SSLMode == "prefer" {
+			errs = append(errs, fmt.Errorf("database.ssl_mode %q is not allowed in %s", c.Database.SSLMode, c.Environment))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// YAML renders the configuration, for printing the effective settings. Use
+// it on a Redacted copy.
+func (c Config) YAML() ([]byte, error) {
+	return yaml.Marshal(c)
+}
+
+// Redacted returns a copy with every secret value masked, for logging.
+func (c Config) Redacted() Config {
+	redact(reflect.ValueOf(&c).Elem())
+	return c
+}
+
+func redact(v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		value := v.Field(i)
+
+		if field.Type.Kind() == reflect.Struct {
+			redact(value)
+			continue
+		}
+
+		if field.Tag.Get("secret") == "true" && value.String() != "" {
+			value.SetString("[REDACTED]")
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	common "github.com/htstinson/stinsondataapi/api/commonweb"
+	"github.com/htstinson/stinsondataapi/api/internal/middleware"
+	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/htstinson/stinsondataapi/api/pkg/database"
+)
+
+// allowed
+
+func (h *Handler) SelectAllowed(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	q, ok := h.listQuery(w, r, database.AllowedList)
+	if !ok {
+		return
+	}
+
+	items, err := h.db.SelectAllowed(ctx, q)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to list allowed")
+		return
+	}
+
+	common.RespondJSON(w, http.StatusOK, items)
+}
+
+func (h *Handler) GetAllowed(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	ctx := r.Context()
+	allowed, err := h.db.GetAllowed(ctx, id)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to get allowed")
+		return
+	}
+	if allowed == nil {
+		common.RespondError(w, http.StatusNotFound, "Allowed not found")
+		return
+	}
+
+	common.RespondJSON(w, http.StatusOK, allowed)
+}
+
+func (h *Handler) CreateAllowed(w http.ResponseWriter, r *http.Request) {
+	var allowed model.Allowed
+	if !h.decode(w, r, &allowed) {
+		return
+	}
+
+	ip, err := middleware.CanonicalPrefix(allowed.IP)
+	if err != nil {
+		common.RespondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid address %q", allowed.IP))
+		return
+	}
+	allowed.IP = ip
+
+	if !h.checkNotBlocked(w, ip) {
+		return
+	}
+
+	ctx := r.Context()
+	newallowed, err := h.db.CreateAllowed(ctx, allowed)
+	if err != nil {
+		if database.IsDuplicate(err) {
+			common.RespondError(w, http.StatusConflict, fmt.Sprintf("%s is already allowed", ip))
+			return
+		}
+		h.respondError(w, r, err, "Failed to create allowed")
+		return
+	}
+
+	h.refreshAllowList(r)
+
+	common.RespondJSON(w, http.StatusCreated, newallowed)
+}
+
+func (h *Handler) UpdateAllowed(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	ctx := r.Context()
+
+	var allowed model.Allowed
+	if !h.decode(w, r, &allowed) {
+		return
+	}
+
+	ip, err := middleware.CanonicalPrefix(allowed.IP)
+	if err != nil {
+		common.RespondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid address %q", allowed.IP))
+		return
+	}
+
+	current, err := h.db.GetAllowed(ctx, id)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to get allowed")
+		return
+	}
+	if current == nil {
+		common.RespondError(w, http.StatusNotFound, "Allowed not found")
+		return
+	}
+
+	if ip != current.IP && !h.checkNotBlocked(w, ip) {
+		return
+	}
+
+	current.IP = ip
+	current.Notes = allowed.Notes
+	err = h.db.UpdateAllowed(ctx, current)
+	if err != nil {
+		h.respondError(w, r, err, "Error updating allowed")
+		return
+	}
+
+	h.refreshAllowList(r)
+
+	common.RespondJSON(w, http.StatusOK, current)
+}
+
+func (h *Handler) DeleteAllowed(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	ctx := r.Context()
+
+	allowed, err := h.db.GetAllowed(ctx, id)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to get allowed")
+		return
+	}
+	if allowed == nil {
+		common.RespondError(w, http.StatusNotFound, "Allowed not found")
+		return
+	}
+
+	err = h.db.DeleteAllowed(ctx, id)
+	if err != nil {
+		h.respondError(w, r, err, "Error deleting allowed")
+		return
+	}
+
+	h.refreshAllowList(r)
+
+	common.RespondJSON(w, http.StatusOK, allowed)
+}
+
+// checkNotBlocked refuses to allow a range that still has blocked entries inside
+// it. The caller should remove those blocks first so that nothing is unblocked silently.
+func (h *Handler) checkNotBlocked(w http.ResponseWriter, ip string) bool {
+	if h.blocklist == nil {
+		return true
+	}
+
+	overlapping, err := h.blocklist.Overlapping(ip)
+	if err != nil {
+		common.RespondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid address %q", ip))
+		return false
+	}
+	if len(overlapping) > 0 {
+		common.RespondError(w, http.StatusConflict,
+			fmt.Sprintf("Cannot allow %s, it overlaps blocked %s. Delete the blocked entries first.", ip, strings.Join(overlapping, ", ")))
+		return false
+	}
+
+	return true
+}
+
+func (h *Handler) refreshAllowList(r *http.Request) {
+	if err := h.allowlist.Refresh(r.Context()); err != nil {
+		h.logger.ErrorContext(r.Context(), "refreshAllowList", "error", err)
+	}
+}
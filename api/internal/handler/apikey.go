@@ -0,0 +1,345 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gorilla/mux"
+	common "github.com/htstinson/stinsondataapi/api/commonweb"
+	"github.com/htstinson/stinsondataapi/api/internal/auth"
+	"github.com/htstinson/stinsondataapi/api/internal/middleware"
+	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/htstinson/stinsondataapi/api/pkg/database"
+)
+
+// apiKeyPrefix starts every API key, so that leaked keys are easy to spot.
+const apiKeyPrefix = "sdk_"
+
+// APIKeyOptions are how long API keys last, as set in config.APIKeys.
+type APIKeyOptions struct {
+	DefaultTTL time.Duration
+	MaxTTL     time.Duration
+}
+
+// SetAPIKeys replaces the API key options, which default to keys lasting
+// 90 days and at most a year.
+func (h *Handler) SetAPIKeys(options APIKeyOptions) {
+	h.apiKeys = options
+}
+
+// AuthenticateAPIKey implements auth.APIKeyAuthenticator. The claims act as
+// the key's user in its subscriber, with their roles there and the key's
+// permissions but none of the user's global roles.
+func (h *Handler) AuthenticateAPIKey(r *http.Request, key string) (*auth.Claims, error) {
+	ctx := r.Context()
+	apiKey, err := h.db.LookupAPIKey(ctx, hashToken(key))
+	if database.IsNotFound(err) {
+		return nil, auth.ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := h.db.GetUser(ctx, apiKey.User_Id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, auth.ErrInvalidAPIKey
+	}
+
+	tenants, err := h.db.SelectUserTenants(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	roles := []string{}
+	for _, tenant := range tenants {
+		if tenant.Subscriber_Id == apiKey.Subscriber_Id {
+			roles = tenant.Roles
+		}
+	}
+
+	ip := ""
+	if addr, ok := middleware.ClientIPFromContext(ctx); ok {
+		ip = addr.String()
+	}
+	if err := h.db.RecordAPIKeyUse(ctx, apiKey.Id, ip); err != nil {
+		h.logger.ErrorContext(ctx, "AuthenticateAPIKey", "error", err)
+	}
+
+	return &auth.Claims{
+		UserID:          user.ID,
+		Username:        user.Username,
+		IP_Address:      ip,
+		SubscriberID:    apiKey.Subscriber_Id,
+		SubscriberRoles: roles,
+		APIKeyID:        apiKey.Id,
+		Permissions:     apiKey.Permissions,
+	}, nil
+}
+
+// CreateAPIKey creates a key for the caller in a subscriber they belong to,
+// by default the one their token is scoped to. The key is only shown in
+// this response.
+func (h *Handler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "CreateAPIKey")
+
+	var req model.APIKeyCreateRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	if req.Subscriber_Id == "" {
+		req.Subscriber_Id = r.Context().Value("user").(*auth.Claims).SubscriberID
+	}
+	if req.Subscriber_Id == "" {
+		h.respondError(w, r, common.ValidationError(map[string][]string{"subscriber_id": {"required when no subscriber is selected"}}), "Invalid API key")
+		return
+	}
+
+	h.createAPIKey(w, r, user, req)
+}
+
+// SelectAPIKeys lists the caller's keys.
+func (h *Handler) SelectAPIKeys(w http.ResponseWriter, r *http.Request) {
+	q, ok := h.listQuery(w, r, database.APIKeyList)
+	if !ok {
+		return
+	}
+	claims, ok := r.Context().Value("user").(*auth.Claims)
+	if !ok {
+		common.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	keys, err := h.db.SelectAPIKeys(r.Context(), claims.UserID, q)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to list API keys")
+		return
+	}
+
+	common.RespondJSON(w, http.StatusOK, keys)
+}
+
+// RevokeAPIKey revokes one of the caller's keys, or any key for admins.
+func (h *Handler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "RevokeAPIKey")
+	id := mux.Vars(r)["id"]
+
+	claims, ok := r.Context().Value("user").(*auth.Claims)
+	if !ok {
+		common.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	ctx := r.Context()
+	key, err := h.db.GetAPIKey(ctx, id)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to get API key")
+		return
+	}
+	// Another user's key is not found rather than forbidden, so that ids
+	// cannot be probed
+	if key == nil || key.User_Id != claims.UserID && !claims.HasRole("admin") {
+		common.RespondError(w, http.StatusNotFound, "API key not found")
+		return
+	}
+
+	if err := h.db.RevokeAPIKey(ctx, id); err != nil {
+		h.respondError(w, r, err, "API key already revoked")
+		return
+	}
+
+	key, err = h.db.GetAPIKey(ctx, id)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to get API key")
+		return
+	}
+
+	common.RespondJSON(w, http.StatusOK, key)
+}
+
+// CreateServiceAccount creates a service account in a subscriber with a
+// role. It has no password and gets keys from CreateServiceAccountKey.
+func (h *Handler) CreateServiceAccount(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "CreateServiceAccount")
+
+	var req model.ServiceAccountCreateRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	ctx := r.Context()
+	subscriber, err := h.db.GetSubscriber(ctx, req.Subscriber_Id)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to get subscriber")
+		return
+	}
+	role, err := h.db.GetRole(ctx, req.Role_Id)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to get role")
+		return
+	}
+	fields := map[string][]string{}
+	if subscriber == nil {
+		fields["subscriber_id"] = []string{"no subscriber has this id"}
+	}
+	if role == nil {
+		fields["role_id"] = []string{"no role has this id"}
+	}
+	if len(fields) > 0 {
+		h.respondError(w, r, common.ValidationError(fields), "Invalid service account")
+		return
+	}
+
+	user, err := h.db.CreateServiceAccount(ctx, req.Name, req.Subscriber_Id, req.Role_Id)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to create service account")
+		return
+	}
+
+	common.RespondJSON(w, http.StatusCreated, user)
+}
+
+// CreateServiceAccountKey creates a key for a service account, in its
+// subscriber unless the request names another it belongs to.
+func (h *Handler) CreateServiceAccountKey(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "CreateServiceAccountKey")
+
+	var req model.APIKeyCreateRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	account, ok := h.serviceAccount(w, r)
+	if !ok {
+		return
+	}
+	if req.Subscriber_Id == "" {
+		tenants, err := h.db.SelectUserTenants(r.Context(), account.ID)
+		if err != nil {
+			h.respondError(w, r, err, "Failed to list subscribers")
+			return
+		}
+		if len(tenants) != 1 {
+			h.respondError(w, r, common.ValidationError(map[string][]string{"subscriber_id": {"required for a service account in more than one subscriber"}}), "Invalid API key")
+			return
+		}
+		req.Subscriber_Id = tenants[0].Subscriber_Id
+	}
+
+	h.createAPIKey(w, r, account, req)
+}
+
+// SelectServiceAccountKeys lists the keys of a service account.
+func (h *Handler) SelectServiceAccountKeys(w http.ResponseWriter, r *http.Request) {
+	q, ok := h.listQuery(w, r, database.APIKeyList)
+	if !ok {
+		return
+	}
+	account, ok := h.serviceAccount(w, r)
+	if !ok {
+		return
+	}
+
+	keys, err := h.db.SelectAPIKeys(r.Context(), account.ID, q)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to list API keys")
+		return
+	}
+
+	common.RespondJSON(w, http.StatusOK, keys)
+}
+
+// serviceAccount returns the service account with the id in the path,
+// answering 404 when it is missing or an ordinary user.
+func (h *Handler) serviceAccount(w http.ResponseWriter, r *http.Request) (*model.User, bool) {
+	account, err := h.db.GetUser(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		h.respondError(w, r, err, "Failed to get service account")
+		return nil, false
+	}
+	if account == nil || !account.Service_Account {
+		common.RespondError(w, http.StatusNotFound, "Service account not found")
+		return nil, false
+	}
+	return account, true
+}
+
+// createAPIKey creates the key req asks for on behalf of user, who must
+// belong to its subscriber and hold each of its permissions there.
+func (h *Handler) createAPIKey(w http.ResponseWriter, r *http.Request, user *model.User, req model.APIKeyCreateRequest) {
+	ctx := r.Context()
+	tenants, err := h.db.SelectUserTenants(ctx, user.ID)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to list subscribers")
+		return
+	}
+	if !slices.ContainsFunc(tenants, func(t model.Tenant) bool { return t.Subscriber_Id == req.Subscriber_Id }) {
+		h.respondError(w, r, common.ValidationError(map[string][]string{"subscriber_id": {"the user is not a member of this subscriber"}}), "Invalid API key")
+		return
+	}
+
+	granted, err := h.db.SelectSubscriberPermissions(ctx, user.ID, req.Subscriber_Id)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to list permissions")
+		return
+	}
+	fields := map[string][]string{}
+	for _, permission := range req.Permissions {
+		if !slices.Contains(granted, permission) {
+			fields["permissions"] = append(fields["permissions"], permission+" is not a permission the user has in this subscriber")
+		}
+	}
+
+	now := time.Now()
+	expires := now.Add(h.apiKeys.DefaultTTL)
+	if req.Expires_At != nil {
+		expires = *req.Expires_At
+	}
+	switch {
+	case !expires.After(now):
+		fields["expires_at"] = []string{"must be in the future"}
+	case expires.Sub(now) > h.apiKeys.MaxTTL:
+		fields["expires_at"] = []string{"cannot be more than " + h.apiKeys.MaxTTL.String() + " away"}
+	}
+	if len(fields) > 0 {
+		h.respondError(w, r, common.ValidationError(fields), "Invalid API key")
+		return
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		h.respondError(w, r, err, "Failed to create API key")
+		return
+	}
+	secret := apiKeyPrefix + token
+
+	key := model.APIKey{
+		User_Id:       user.ID,
+		Subscriber_Id: req.Subscriber_Id,
+		Name:          req.Name,
+		Prefix:        secret[:12],
+		Permissions:   req.Permissions,
+		Expires_At:    expires,
+	}
+	if key.Permissions == nil {
+		key.Permissions = []string{}
+	}
+	if claims, ok := ctx.Value("user").(*auth.Claims); ok {
+		key.Created_By = claims.UserID
+	}
+
+	created, err := h.db.CreateAPIKey(ctx, &key, hashToken(secret))
+	if err != nil {
+		h.respondError(w, r, err, "Failed to create API key")
+		return
+	}
+
+	common.RespondJSON(w, http.StatusCreated, model.APIKeyCreated{APIKey: *created, Key: secret})
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	common "github.com/htstinson/stinsondataapi/api/commonweb"
+	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/htstinson/stinsondataapi/api/pkg/database"
+)
+
+// SelectAuditEvents lists the audit log, newest first. It is only routed
+// behind auth.RequireRole("admin").
+func (h *Handler) SelectAuditEvents(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "SelectAuditEvents")
+
+	q, ok := h.listQuery(w, r, database.AuditList)
+	if !ok {
+		return
+	}
+
+	events, err := h.db.SelectAuditEvents(r.Context(), q)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to list audit events")
+		return
+	}
+
+	common.RespondJSON(w, http.StatusOK, events)
+}
+
+// auditAccount records a login attempt or another event of a user's account
+// that does not go through the repository. user is nil when no user has the
+// username given.
+func (h *Handler) auditAccount(ctx context.Context, action string, username string, user *model.User) {
+	actor := database.ActorFrom(ctx)
+	event := model.AuditEvent{
+		Action:      action,
+		Actor_Name:  username,
+		Entity_Type: "user",
+		Request_Id:  actor.RequestID,
+		IP_Address:  actor.IP,
+	}
+	if user != nil {
+		event.Actor_Id = user.ID
+		event.Entity_Id = user.ID
+	}
+
+	if err := h.db.CreateAuditEvent(ctx, event); err != nil {
+		h.logger.ErrorContext(ctx, "audit event not recorded", "action", action, "error", err)
+	}
+}
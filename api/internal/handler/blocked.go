@@ -2,7 +2,7 @@ package handler
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -10,8 +10,10 @@ import (
 	"github.com/gorilla/mux"
 
 	"github.com/htstinson/stinsondataapi/api/aws/mywaf"
+	"github.com/htstinson/stinsondataapi/api/pkg/database"
 
 	common "github.com/htstinson/stinsondataapi/api/commonweb"
+	"github.com/htstinson/stinsondataapi/api/internal/middleware"
 	"github.com/htstinson/stinsondataapi/api/internal/model"
 	"github.com/htstinson/stinsondataapi/api/internal/parser"
 )
@@ -22,23 +24,14 @@ func (h *Handler) SelectBlocked(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 
-	order := h.ValidOrder(r)
-	sort := h.ValidSort(r)
-
-	if sort == "" {
-		sort = "ip"
-	}
-
-	if order == "" {
-		order = "ASC"
+	q, ok := h.listQuery(w, r, database.BlockedList)
+	if !ok {
+		return
 	}
 
-	limit := 3000
-	offset := 0
-
-	items, err := h.db.SelectBlocked(ctx, limit, offset, sort, order)
+	items, err := h.db.SelectBlocked(ctx, q)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to list items")
+		h.respondError(w, r, err, "Failed to list items")
 		return
 	}
 
@@ -54,46 +47,52 @@ func (h *Handler) UpdateBlocked(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	var blocked model.Blocked
-	if err := json.NewDecoder(r.Body).Decode(&blocked); err != nil {
-		fmt.Println(1, err.Error())
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &blocked) {
 		return
 	}
-	defer r.Body.Close()
 
 	current, err := h.db.GetBlocked(ctx, id)
 	if err != nil {
-		fmt.Println(2, err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get blokced")
+		h.respondError(w, r, err, "Failed to get blocked")
 		return
 	}
 	if current == nil {
-		fmt.Println(3, id, "not found")
+		h.logger.DebugContext(ctx, "UpdateBlocked not found", "id", id)
 		common.RespondError(w, http.StatusNotFound, "Blocked not found")
 		return
 	}
 
+	if blocked.IP != current.IP {
+		if err := h.allowlist.Check(blocked.IP, "UpdateBlocked"); err != nil {
+			respondAllowListError(w, err)
+			return
+		}
+	}
+
+	previousIP := current.IP
 	current.IP = blocked.IP
 	current.Notes = blocked.Notes
 	err = h.db.UpdateBlocked(ctx, current)
 	if err != nil {
-		fmt.Println(4, err.Error())
-		common.RespondError(w, http.StatusNotFound, "Error updating blocked")
+		h.respondError(w, r, err, "Error updating blocked")
 		return
 	}
 
+	h.unblockLocal(previousIP)
+	h.blockLocal(current.IP)
+
 	common.RespondJSON(w, http.StatusOK, blocked)
 }
 
 func (h *Handler) GetBlocked(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h GetBlocked (w,r)")
+	h.logger.DebugContext(r.Context(), "GetBlocked")
 	vars := mux.Vars(r)
 	id := vars["id"]
 
 	ctx := r.Context()
 	blocked, err := h.db.GetBlocked(ctx, id)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get blocked")
+		h.respondError(w, r, err, "Failed to get blocked")
 		return
 	}
 	if blocked == nil {
@@ -105,26 +104,29 @@ func (h *Handler) GetBlocked(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) CreateBlocked(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h CreateBlocked(w,r)")
+	h.logger.DebugContext(r.Context(), "CreateBlocked")
 	var blocked *model.Blocked
-	if err := json.NewDecoder(r.Body).Decode(&blocked); err != nil {
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &blocked) {
+		return
+	}
+
+	if err := h.allowlist.Check(blocked.IP, "CreateBlocked"); err != nil {
+		respondAllowListError(w, err)
 		return
 	}
-	defer r.Body.Close()
 
 	ctx := r.Context()
 	newblocked, err := h.db.CreateBlocked(ctx, *blocked)
 	if err != nil {
-		fmt.Println("h create blocked ", err.Error())
-		if err.Error() == "duplicate" {
-			fmt.Println("h create blocked duplicate address")
-			common.RespondJSON(w, 409, nil)
+		if database.IsDuplicate(err) {
+			common.RespondError(w, http.StatusConflict, fmt.Sprintf("%s is already blocked", blocked.IP))
+			return
 		}
-		common.RespondError(w, http.StatusInternalServerError, "Failed to create blocked")
+		h.respondError(w, r, err, "Failed to create blocked")
 		return
 	}
 
+	h.blockLocal(blocked.IP)
 	mywaf.Block("Blocked", blocked.IP, "", "us-west-2")
 
 	common.RespondJSON(w, http.StatusCreated, newblocked)
@@ -138,7 +140,7 @@ func (h *Handler) DeleteBlocked(w http.ResponseWriter, r *http.Request) {
 
 	blocked, err := h.db.GetBlocked(ctx, id)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get blocked")
+		h.respondError(w, r, err, "Failed to get blocked")
 		return
 	}
 	if blocked == nil {
@@ -148,11 +150,11 @@ func (h *Handler) DeleteBlocked(w http.ResponseWriter, r *http.Request) {
 
 	err = h.db.DeleteBlocked(ctx, id)
 	if err != nil {
-		common.RespondError(w, http.StatusNotFound, "Error deleting blocked")
+		h.respondError(w, r, err, "Error deleting blocked")
 		return
 	}
 
-	fmt.Println(blocked.IP)
+	h.unblockLocal(blocked.IP)
 	mywaf.Block("Blocked", "", blocked.IP, "us-west-2")
 
 	common.RespondJSON(w, http.StatusOK, blocked)
@@ -162,84 +164,118 @@ func (h *Handler) DeleteBlocked(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) AddBlockedFromLogs(w http.ResponseWriter, r *http.Request) {
 
 	// Create blocked IP addresses from entries in the log.
-	fmt.Printf("[%v] [main] Parse the log.\n", time.Now().Format(time.RFC3339))
+	h.logger.InfoContext(r.Context(), "parse the log")
 	addresses, err := parser.ExtractUniqueIPsFromHandshakeErrors("/var/log/webserver.log")
 	if err != nil {
-		fmt.Printf("[%v] [main] error: %s.\n", time.Now().Format(time.RFC3339), err.Error())
-	} else {
-		//ctx := r.Context()
-		ctx := context.Background()
-		fmt.Printf("[%v] [main] Blocked IP addresses.\n", time.Now().Format(time.RFC3339))
-		for k, v := range addresses {
-			blocked := &model.Blocked{
-				Notes:     "TLS handshake error",
-				CreatedAt: time.Now(),
-			}
-			ip := fmt.Sprintf("%s/32", v)
-			blocked.IP = ip
-			_, err := h.db.CreateBlocked(ctx, *blocked)
-			if err == nil {
-				fmt.Printf("[%v] [main] %v %s Created blocked IP.\n", time.Now().Format(time.RFC3339), k, ip)
+		h.respondError(w, r, err, "Failed to parse the log")
+		return
+	}
 
-				err = mywaf.Block("Blocked", ip, "", "us-west-2")
-				if err != nil {
-					fmt.Printf("[%v] [main] %v %s Error adding IP to WAF IP Set.\n", time.Now().Format(time.RFC3339), k, ip)
-				}
+	//ctx := r.Context()
+	ctx := context.Background()
+	h.logger.InfoContext(r.Context(), "blocking addresses from log", "count", len(addresses))
+	created := 0
+	for k, v := range addresses {
+		blocked := &model.Blocked{
+			Notes:     "TLS handshake error",
+			CreatedAt: time.Now(),
+		}
+		ip := fmt.Sprintf("%s/32", v)
+		blocked.IP = ip
+		if h.allowlist.Check(ip, "AddBlockedFromLogs") != nil {
+			continue
+		}
+		_, err := h.db.CreateBlocked(ctx, *blocked)
+		if err == nil {
+			h.logger.InfoContext(ctx, "created blocked ip", "index", k, "ip", ip)
+			h.blockLocal(ip)
+			created++
 
-			} else {
-				fmt.Printf("[%v] [main] %s error: %s.\n", time.Now().Format(time.RFC3339), ip, err.Error())
+			err = mywaf.Block("Blocked", ip, "", "us-west-2")
+			if err != nil {
+				h.logger.ErrorContext(ctx, "error adding ip to WAF ip set", "index", k, "ip", ip, "error", err)
 			}
 
-			time.Sleep(100 * time.Millisecond)
+		} else {
+			h.logger.WarnContext(ctx, "AddBlockedFromLogs", "ip", ip, "error", err)
 		}
+
+		time.Sleep(100 * time.Millisecond)
 	}
 
+	common.RespondJSON(w, http.StatusOK, map[string]int{"addresses": len(addresses), "blocked": created})
 }
 
 func (h *Handler) AddBlockedFromRDSToWAF(w http.ResponseWriter, r *http.Request) {
 
-	fmt.Println("h AddBlockedFromRDSToWAF(w,r)")
+	h.logger.DebugContext(r.Context(), "AddBlockedFromRDSToWAF")
 
-	rowcount, err := h.db.RowCount("blocked")
-	if err != nil {
-		fmt.Println(err.Error())
-	} else {
-		fmt.Println("row count=", rowcount)
-	}
-
-	go func(rowcount int) {
+	go func() {
 
-		// Create blocked IP addresses from entries in RDS.
-		fmt.Printf("[%v] [main] Add Blocked From RDS TO WAF.\n", time.Now().Format(time.RFC3339))
+		// Create blocked IP addresses from entries in RDS, a page at a time.
 		ctx := context.Background()
+		h.logger.InfoContext(ctx, "add blocked from RDS to WAF")
 
-		limit := 50
-		offset := 0
-
-		for offset <= (rowcount + limit) {
-			fmt.Printf("limit %v offset %v ", limit, offset)
+		q := database.ListQuery{Limit: 50, Sort: "ip"}
 
-			addresses, err := h.db.SelectBlocked(ctx, limit, offset, "ip", "asc")
+		for {
+			h.logger.DebugContext(ctx, "AddBlockedFromRDSToWAF", "limit", q.Limit, "cursor", q.Cursor)
 
+			page, err := h.db.SelectBlocked(ctx, q)
 			if err != nil {
-				fmt.Printf("[%v] [main] error: %s.\n", time.Now().Format(time.RFC3339), err.Error())
-			} else {
-				for k, v := range addresses {
-					fmt.Printf("%v ", k)
-					err = mywaf.Block("Blocked", v.IP, "", "us-west-2")
-					if err != nil {
-						fmt.Printf("[%v] [main] %v %s Error adding IP to WAF IP Set. %s\n", time.Now().Format(time.RFC3339), k, v.IP, err.Error())
-					}
-					time.Sleep(200 * time.Millisecond)
+				h.logger.ErrorContext(ctx, "AddBlockedFromRDSToWAF", "error", err)
+				return
+			}
+
+			for k, v := range page.Data {
+				if h.allowlist.Check(v.IP, "AddBlockedFromRDSToWAF") != nil {
+					continue
 				}
-				fmt.Println()
+				err = mywaf.Block("Blocked", v.IP, "", "us-west-2")
+				if err != nil {
+					h.logger.ErrorContext(ctx, "error adding ip to WAF ip set", "index", k, "ip", v.IP, "error", err)
+				}
+				time.Sleep(200 * time.Millisecond)
 			}
 
-			offset += limit
+			if page.NextCursor == "" {
+				return
+			}
+			q.Cursor = page.NextCursor
 		}
 
-	}(rowcount)
+	}()
 
 	common.RespondJSON(w, http.StatusOK, "udating WAF from RDS")
 
 }
+
+// respondAllowListError explains why an address could not be blocked.
+func respondAllowListError(w http.ResponseWriter, err error) {
+	var conflict *middleware.AllowListConflict
+	if errors.As(err, &conflict) {
+		common.RespondError(w, http.StatusConflict, fmt.Sprintf("Cannot block %s", conflict.Error()))
+		return
+	}
+	common.RespondError(w, http.StatusBadRequest, err.Error())
+}
+
+// blockLocal adds ip to the in-process block list, if one is configured.
+func (h *Handler) blockLocal(ip string) {
+	if h.blocklist == nil {
+		return
+	}
+	if err := h.blocklist.Add(ip); err != nil {
+		h.logger.Warn("blockLocal", "ip", ip, "error", err)
+	}
+}
+
+// unblockLocal removes ip from the in-process block list, if one is configured.
+func (h *Handler) unblockLocal(ip string) {
+	if h.blocklist == nil {
+		return
+	}
+	if err := h.blocklist.Remove(ip); err != nil {
+		h.logger.Warn("unblockLocal", "ip", ip, "error", err)
+	}
+}
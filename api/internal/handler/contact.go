@@ -1,31 +1,40 @@
 package handler
 
 import (
-	"encoding/json"
-	"fmt"
 	"net/http"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	common "github.com/htstinson/stinsondataapi/api/commonweb"
 	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/htstinson/stinsondataapi/api/pkg/database"
 )
 
 func (h *Handler) SelectContacts(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h SelectContacts")
+	h.logger.DebugContext(r.Context(), "SelectContacts")
+
+	q, ok := h.listQuery(w, r, database.ContactList)
+	if !ok {
+		return
+	}
 
 	var customer *model.Customer
-	if err := json.NewDecoder(r.Body).Decode(&customer); err != nil {
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &customer, "Id") {
 		return
 	}
-	defer r.Body.Close()
+
+	subscriber, ok := h.tenant(w, r, customer.Subscriber_Id)
+	if !ok {
+		return
+	}
+	customer.Subscriber_Id = subscriber.Id
+	customer.Schema_Name = subscriber.Schema_Name
 
 	ctx := r.Context()
 
-	contacts, err := h.db.SelectContacts(ctx, *customer, 100, 0)
+	contacts, err := h.db.SelectContacts(ctx, *customer, q)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to list contacts")
+		h.respondError(w, r, err, "Failed to list contacts")
 		return
 	}
 
@@ -34,29 +43,27 @@ func (h *Handler) SelectContacts(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) CreateContact(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h CreateContact")
+	h.logger.DebugContext(r.Context(), "CreateContact")
 
 	var contact *model.Contact
-	if err := json.NewDecoder(r.Body).Decode(&contact); err != nil {
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &contact) {
 		return
 	}
-	defer r.Body.Close()
 
 	ctx := r.Context()
 
-	subscriber, err := h.db.GetSubscriber(ctx, contact.Subscriber_Id_)
-	if err != nil {
-		fmt.Println(err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get subscriber")
+	subscriber, ok := h.tenant(w, r, contact.Subscriber_Id_)
+	if !ok {
+		return
 	}
 
 	contact.Id = uuid.New().String()
+	contact.Subscriber_Id_ = subscriber.Id
 	contact.Schema_Name_ = subscriber.Schema_Name
 
-	contact, err = h.db.CreateContact(ctx, contact)
+	contact, err := h.db.CreateContact(ctx, contact)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to create customer")
+		h.respondError(w, r, err, "Failed to create customer")
 		return
 	}
 
@@ -64,7 +71,7 @@ func (h *Handler) CreateContact(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) DeleteContact(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h DeleteContact")
+	h.logger.DebugContext(r.Context(), "DeleteContact")
 	var contact model.Contact
 
 	ctx := r.Context()
@@ -73,16 +80,16 @@ func (h *Handler) DeleteContact(w http.ResponseWriter, r *http.Request) {
 	contact.Subscriber_Id_ = vars["subscriber_id"]
 	contact.Id = vars["contact_id"]
 
-	subscriber, err := h.db.GetSubscriber(ctx, contact.Subscriber_Id_)
-	if err != nil {
-		common.RespondError(w, http.StatusNotFound, "Subscriber not found")
+	subscriber, ok := h.tenant(w, r, contact.Subscriber_Id_)
+	if !ok {
+		return
 	}
 
 	contact.Schema_Name_ = subscriber.Schema_Name
 
 	current, err := h.db.GetContact(ctx, contact)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get contact")
+		h.respondError(w, r, err, "Failed to get contact")
 		return
 	}
 	if current == nil {
@@ -91,7 +98,7 @@ func (h *Handler) DeleteContact(w http.ResponseWriter, r *http.Request) {
 	}
 	err = h.db.DeleteContact(ctx, &contact)
 	if err != nil {
-		common.RespondError(w, http.StatusNotFound, "Error deleting item")
+		h.respondError(w, r, err, "Error deleting item")
 		return
 	}
 
@@ -100,19 +107,24 @@ func (h *Handler) DeleteContact(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) UpdateContact(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h UpdateContact")
+	h.logger.DebugContext(r.Context(), "UpdateContact")
 	ctx := r.Context()
 
 	var contact model.Contact
-	if err := json.NewDecoder(r.Body).Decode(&contact); err != nil {
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &contact) {
 		return
 	}
-	defer r.Body.Close()
+
+	subscriber, ok := h.tenant(w, r, contact.Subscriber_Id_)
+	if !ok {
+		return
+	}
+	contact.Subscriber_Id_ = subscriber.Id
+	contact.Schema_Name_ = subscriber.Schema_Name
 
 	current, err := h.db.GetContact(ctx, contact)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get contact")
+		h.respondError(w, r, err, "Failed to get contact")
 		return
 	}
 	if current == nil {
@@ -122,7 +134,7 @@ func (h *Handler) UpdateContact(w http.ResponseWriter, r *http.Request) {
 
 	err = h.db.UpdateContact(ctx, &contact)
 	if err != nil {
-		common.RespondError(w, http.StatusNotFound, "Error updating contact")
+		h.respondError(w, r, err, "Error updating contact")
 		return
 	}
 
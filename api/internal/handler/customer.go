@@ -2,41 +2,33 @@ package handler
 
 import (
 	"database/sql"
-	"encoding/json"
-	"fmt"
 	"net/http"
-	"strconv"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	common "github.com/htstinson/stinsondataapi/api/commonweb"
 	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/htstinson/stinsondataapi/api/pkg/database"
 )
 
 func (h *Handler) SelectCustomers(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h SelectCustomers")
+	h.logger.DebugContext(r.Context(), "SelectCustomers")
 
-	sort := ""
-	order := ""
+	q, ok := h.listQuery(w, r, database.CustomerList)
+	if !ok {
+		return
+	}
 
-	var user *model.CurrentUser
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	subcriber, ok := h.tenant(w, r, "")
+	if !ok {
 		return
 	}
-	defer r.Body.Close()
 
 	ctx := r.Context()
 
-	subcriber, err := h.db.GetSubscriber(ctx, user.Subscribed[0].Subscriber_ID)
-	if err != nil {
-		fmt.Println(err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get subscriber")
-	}
-
-	customers, _, err := h.db.SelectCustomers(ctx, *subcriber, 100, 0, sort, order)
+	customers, err := h.db.SelectCustomers(ctx, *subcriber, q)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to select customers")
+		h.respondError(w, r, err, "Failed to select customers")
 		return
 	}
 
@@ -45,69 +37,42 @@ func (h *Handler) SelectCustomers(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) SelectSubscriberCustomers(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h SelectSubscriberCustomers")
-
-	order := h.ValidOrder(r)
-	sort := h.ValidSort(r)
+	h.logger.DebugContext(r.Context(), "SelectSubscriberCustomers")
 
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-
-	// Sensible defaults if missing/zero
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 {
-		limit = 10
+	q, ok := h.listQuery(w, r, database.CustomerList)
+	if !ok {
+		return
 	}
 
-	offset := (page - 1) * limit
-
-	var subcriber *model.Subscriber
-	if err := json.NewDecoder(r.Body).Decode(&subcriber); err != nil {
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	subcriber, ok := h.tenant(w, r, "")
+	if !ok {
 		return
 	}
-	defer r.Body.Close()
 
 	ctx := r.Context()
 
-	subcriber, err := h.db.GetSubscriber(ctx, subcriber.Id)
+	customers, err := h.db.SelectCustomers(ctx, *subcriber, q)
 	if err != nil {
-		fmt.Println(err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get subscriber")
-	}
-
-	customers, total, err := h.db.SelectCustomers(ctx, *subcriber, limit, offset, sort, order)
-	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to select customers")
+		h.respondError(w, r, err, "Failed to select customers")
 		return
 	}
 
-	//common.RespondJSON(w, http.StatusOK, customers)
-
-	common.RespondJSON2(w, http.StatusOK, map[string]any{
-		"data":  customers,
-		"total": total,
-	})
+	common.RespondJSON(w, http.StatusOK, customers)
 
 }
 
 func (h *Handler) CreateCustomer(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h CreateCustomer")
+	h.logger.DebugContext(r.Context(), "CreateCustomer")
 
 	var customer *model.Customer
-	if err := json.NewDecoder(r.Body).Decode(&customer); err != nil {
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &customer) {
 		return
 	}
-	defer r.Body.Close()
 	ctx := r.Context()
 
-	subscriber, err := h.db.GetSubscriber(ctx, customer.Subscriber_Id)
-	if err != nil {
-		fmt.Println(err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get subscriber")
+	subscriber, ok := h.tenant(w, r, customer.Subscriber_Id)
+	if !ok {
+		return
 	}
 
 	profile, err := h.db.GetProfile(ctx, subscriber)
@@ -119,7 +84,7 @@ func (h *Handler) CreateCustomer(w http.ResponseWriter, r *http.Request) {
 
 	customer, err = h.db.CreateCustomer(ctx, customer, subscriber)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to create customer")
+		h.respondError(w, r, err, "Failed to create customer")
 		return
 	}
 
@@ -127,55 +92,53 @@ func (h *Handler) CreateCustomer(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) DeleteCustomer(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h DeleteCustomer")
+	h.logger.DebugContext(r.Context(), "DeleteCustomer")
 
 	vars := mux.Vars(r)
 	id := vars["customer_id"]
-	subscriber_id := vars["subscriber_id"]
 	ctx := r.Context()
 
-	subscriber, err := h.db.GetSubscriber(ctx, subscriber_id)
-	if err != nil {
-		fmt.Println(err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get subscriber")
+	subscriber, ok := h.tenant(w, r, vars["subscriber_id"])
+	if !ok {
+		return
 	}
 
 	var customer = model.Customer{
 		Id:            id,
-		Subscriber_Id: subscriber_id,
+		Subscriber_Id: subscriber.Id,
 		Schema_Name:   subscriber.Schema_Name,
 	}
 
 	current, err := h.db.GetCustomer(ctx, customer)
 	if err != nil {
-		fmt.Println(err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get customer")
+		h.respondError(w, r, err, "Failed to get customer")
 		return
 	}
 	if current == nil {
-		fmt.Println("Customer not found")
+		h.logger.DebugContext(ctx, "Customer not found")
 		common.RespondError(w, http.StatusNotFound, "Customer not found")
 		return
 	}
 
-	contacts, err := h.db.SelectContacts(ctx, *current, 100, 0)
+	// One contact is enough to refuse
+	contacts, err := h.db.SelectContacts(ctx, *current, database.ListQuery{Limit: 1})
 
 	if err != nil {
-		fmt.Println(err.Error())
+		h.logger.ErrorContext(ctx, "Error locating contacts", "error", err)
 		if err != sql.ErrNoRows {
 			common.RespondError(w, http.StatusOK, "Error locating contacts")
 			return
 		}
 	}
 
-	if len(contacts) > 0 {
+	if contacts != nil && contacts.Total > 0 {
 		common.RespondError(w, http.StatusConflict, "Cannot delete customer: customer has associated contacts")
 		return
 	}
 
 	err = h.db.DeleteCustomer(ctx, &customer)
 	if err != nil {
-		common.RespondError(w, http.StatusNotFound, "Error deleting Customer")
+		h.respondError(w, r, err, "Error deleting Customer")
 		return
 	}
 
@@ -184,21 +147,24 @@ func (h *Handler) DeleteCustomer(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) UpdateCustomer(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h UpdateCustomer")
+	h.logger.DebugContext(r.Context(), "UpdateCustomer")
 	ctx := r.Context()
 
 	var customer model.Customer
-	if err := json.NewDecoder(r.Body).Decode(&customer); err != nil {
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &customer) {
 		return
 	}
-	defer r.Body.Close()
 
-	fmt.Println(customer.Name)
+	subscriber, ok := h.tenant(w, r, customer.Subscriber_Id)
+	if !ok {
+		return
+	}
+	customer.Subscriber_Id = subscriber.Id
+	customer.Schema_Name = subscriber.Schema_Name
 
 	current, err := h.db.GetCustomer(ctx, customer)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get customer")
+		h.respondError(w, r, err, "Failed to get customer")
 		return
 	}
 	if current == nil {
@@ -208,7 +174,7 @@ func (h *Handler) UpdateCustomer(w http.ResponseWriter, r *http.Request) {
 
 	err = h.db.UpdateCustomer(ctx, &customer)
 	if err != nil {
-		common.RespondError(w, http.StatusNotFound, "Error updating customer")
+		h.respondError(w, r, err, "Error updating customer")
 		return
 	}
 
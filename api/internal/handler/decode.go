@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	common "github.com/htstinson/stinsondataapi/api/commonweb"
+	"github.com/htstinson/stinsondataapi/api/internal/validate"
+)
+
+// decode strictly decodes the request body into v and validates it. When
+// fields are given only those are validated, for bodies that just select a
+// record. On failure it responds with a problem document and returns false.
+func (h *Handler) decode(w http.ResponseWriter, r *http.Request, v any, fields ...string) bool {
+	defer r.Body.Close()
+
+	if err := common.DecodeJSON(r, v); err != nil {
+		h.respondError(w, r, err, "Invalid request payload")
+		return false
+	}
+
+	var err error
+	if len(fields) > 0 {
+		err = validate.Fields(v, fields...)
+	} else {
+		err = validate.Struct(v)
+	}
+
+	var fieldErrs validate.Errors
+	switch {
+	case errors.As(err, &fieldErrs):
+		h.respondError(w, r, common.ValidationError(fieldErrs), "Invalid request payload")
+		return false
+	case err != nil:
+		h.respondError(w, r, err, "Failed to validate request")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"github.com/htstinson/stinsondataapi/api/internal/mail"
+	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/htstinson/stinsondataapi/api/pkg/database"
+)
+
+// maxDigestMentions is the most search results listed in a digest; the
+// rest are only counted.
+const maxDigestMentions = 20
+
+// digestPeriod is how long a digest of frequency covers, 0 when it is off.
+func digestPeriod(frequency string) time.Duration {
+	switch frequency {
+	case model.DigestDaily:
+		return 24 * time.Hour
+	case model.DigestWeekly:
+		return 7 * 24 * time.Hour
+	}
+	return 0
+}
+
+// StartDigests sends the digests that are due every interval until ctx is
+// cancelled.
+func (h *Handler) StartDigests(ctx context.Context, interval time.Duration) {
+	ctx = database.WithActor(ctx, database.Actor{Username: "notification digests"})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				sent, err := h.SendDigests(ctx, time.Now())
+				if err != nil {
+					h.logger.ErrorContext(ctx, "notification digests failed", "error", err)
+				} else if sent > 0 {
+					h.logger.InfoContext(ctx, "notification digests sent", "count", sent)
+				}
+			}
+		}
+	}()
+}
+
+// SendDigests sends each user the digests of their subscribers that are due
+// at now, on the channels and as often as their preferences there say, and
+// returns how many were sent. A digest lists the search results found since
+// the last one, or over its period for the first, and is not sent when
+// there are none.
+func (h *Handler) SendDigests(ctx context.Context, now time.Time) (int, error) {
+	recipients, err := h.db.SelectDigestRecipients(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	subscribers := map[string]*model.Subscriber{}
+	for _, recipient := range recipients {
+		preferences, err := h.notificationPreferences(ctx, recipient.User_Id, recipient.Subscriber_Id)
+		if err != nil {
+			h.logger.ErrorContext(ctx, "SendDigests", "user_id", recipient.User_Id, "error", err)
+			continue
+		}
+
+		for _, channel := range []struct {
+			name      string
+			frequency string
+		}{
+			{"email", preferences.Digest.Email},
+			{"webhook", preferences.Digest.Webhook},
+		} {
+			period := digestPeriod(channel.frequency)
+			if period == 0 || (channel.name == "webhook" && preferences.Webhook_URL == "") {
+				continue
+			}
+
+			subscriber, ok := subscribers[recipient.Subscriber_Id]
+			if !ok {
+				if subscriber, err = h.db.GetSubscriber(ctx, recipient.Subscriber_Id); err != nil {
+					return sent, err
+				}
+				subscribers[recipient.Subscriber_Id] = subscriber
+			}
+			if subscriber == nil {
+				continue
+			}
+
+			claimed, previous, err := h.db.ClaimNotificationDigest(ctx, recipient.User_Id, recipient.Subscriber_Id, channel.name, now.Add(-period), now)
+			if err != nil {
+				return sent, err
+			}
+			if !claimed {
+				continue
+			}
+			since := now.Add(-period)
+			if previous != nil {
+				since = *previous
+			}
+
+			mentions, total, err := h.db.SelectDigestMentions(ctx, *subscriber, since, maxDigestMentions)
+			if err != nil {
+				h.logger.ErrorContext(ctx, "SendDigests", "subscriber_id", subscriber.Id, "error", err)
+				continue
+			}
+			if total == 0 {
+				continue
+			}
+
+			data := map[string]any{
+				"Subscriber": subscriber.Name,
+				"Frequency":  channel.frequency,
+				"Since":      since.UTC().Format(time.RFC3339),
+				"Count":      total,
+				"Mentions":   mentions,
+				"More":       total - len(mentions),
+			}
+			if channel.name == "email" {
+				err = h.queueMail(ctx, recipient.Username, subscriber.Id, mail.TemplateMentionDigest, data)
+			} else {
+				err = h.postWebhook(ctx, preferences, model.Notification{
+					Event:         model.NotificationMentionDigest,
+					Template:      mail.TemplateMentionDigest,
+					User_Id:       recipient.User_Id,
+					Subscriber_Id: subscriber.Id,
+					Time:          now.UTC(),
+					Data:          data,
+				})
+			}
+			if err != nil {
+				h.logger.WarnContext(ctx, "SendDigests", "channel", channel.name, "user_id", recipient.User_Id, "error", err)
+				continue
+			}
+			sent++
+		}
+	}
+	return sent, nil
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/htstinson/stinsondataapi/api/internal/auth"
+	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/htstinson/stinsondataapi/api/pkg/database"
+)
+
+// digests has one subscriber whose search results are mentions, the
+// preferences in preferences keyed by user id + "/" + subscriber id, and
+// keeps the digests claimed and the email queued.
+type digests struct {
+	database.Repository
+	recipients  []model.DigestRecipient
+	preferences map[string]model.NotificationPreferences
+	mentions    []model.DigestMention
+	sent        map[string]time.Time
+	emails      []model.Email
+}
+
+func (d *digests) SelectDigestRecipients(ctx context.Context) ([]model.DigestRecipient, error) {
+	return d.recipients, nil
+}
+
+func (d *digests) GetNotificationPreferences(ctx context.Context, user_id string, subscriber_id string) (*model.NotificationPreferences, error) {
+	p, ok := d.preferences[user_id+"/"+subscriber_id]
+	if !ok {
+		return nil, nil
+	}
+	return &p, nil
+}
+
+func (d *digests) GetSubscriber(ctx context.Context, id string) (*model.Subscriber, error) {
+	return &model.Subscriber{Id: id, Name: "Acme", Schema_Name: "acme"}, nil
+}
+
+func (d *digests) ClaimNotificationDigest(ctx context.Context, user_id string, subscriber_id string, channel string, due time.Time, now time.Time) (bool, *time.Time, error) {
+	key := user_id + "/" + subscriber_id + "/" + channel
+	previous, ok := d.sent[key]
+	if ok && previous.After(due) {
+		return false, nil, nil
+	}
+	d.sent[key] = now
+	if !ok {
+		return true, nil, nil
+	}
+	return true, &previous, nil
+}
+
+func (d *digests) SelectDigestMentions(ctx context.Context, subscriber model.Subscriber, since time.Time, limit int) ([]model.DigestMention, int, error) {
+	var found []model.DigestMention
+	for _, m := range d.mentions {
+		if m.Created_At.After(since) {
+			found = append(found, m)
+		}
+	}
+	return found[:min(limit, len(found))], len(found), nil
+}
+
+func (d *digests) IsUnsubscribed(ctx context.Context, email string, category string) (bool, error) {
+	return false, nil
+}
+
+func (d *digests) GetEmailTemplate(ctx context.Context, subscriber_id string, name string) (*model.EmailTemplate, error) {
+	return nil, nil
+}
+
+func (d *digests) CreateEmail(ctx context.Context, email *model.Email) (*model.Email, error) {
+	d.emails = append(d.emails, *email)
+	return email, nil
+}
+
+func TestSendDigests(t *testing.T) {
+	var webhooks []model.Notification
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n model.Notification
+		if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+			t.Error(err)
+		}
+		webhooks = append(webhooks, n)
+	}))
+	defer srv.Close()
+
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	db := &digests{
+		recipients: []model.DigestRecipient{
+			{User_Id: "daily", Username: "daily@example.com", Subscriber_Id: "s1"},
+			{User_Id: "weekly", Username: "weekly@example.com", Subscriber_Id: "s1"},
+			{User_Id: "off", Username: "off@example.com", Subscriber_Id: "s1"},
+		},
+		preferences: map[string]model.NotificationPreferences{
+			// Set in the subscriber
+			"daily/s1": {Digest: model.NotificationDigest{Email: model.DigestDaily, Webhook: model.DigestOff}},
+			// The user's defaults, which apply in the subscriber
+			"weekly/": {Digest: model.NotificationDigest{Email: model.DigestOff, Webhook: model.DigestWeekly}, Webhook_URL: srv.URL},
+			// The subscriber's preferences win over the defaults
+			"off/s1": {Digest: model.NotificationDigest{Email: model.DigestOff, Webhook: model.DigestOff}},
+			"off/":   {Digest: model.NotificationDigest{Email: model.DigestDaily}},
+		},
+		mentions: []model.DigestMention{
+			{Search: "Acme", Title: "Acme wins award", Link: "https://news.example.com/award", Created_At: now.Add(-2 * time.Hour)},
+			{Search: "Acme", Title: "Old news", Link: "https://news.example.com/old", Created_At: now.Add(-30 * time.Hour)},
+		},
+		sent: map[string]time.Time{},
+	}
+	h := NewHandler(db, auth.JWTAuth{}, slog.New(slog.NewTextHandler(io.Discard, nil)), nil)
+	h.SetNotifications(NotificationOptions{Client: srv.Client()})
+
+	sent, err := h.SendDigests(context.Background(), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent != 2 || len(db.emails) != 1 || len(webhooks) != 1 {
+		t.Fatalf("sent %d: %d emails, %d webhooks", sent, len(db.emails), len(webhooks))
+	}
+	email := db.emails[0]
+	if email.To != "daily@example.com" || !strings.Contains(email.Text_Body, "https://news.example.com/award") || strings.Contains(email.Text_Body, "/old") {
+		t.Fatalf("email to %s:\n%s", email.To, email.Text_Body)
+	}
+	if n := webhooks[0]; n.Event != model.NotificationMentionDigest || n.User_Id != "weekly" || n.Data["Count"] != float64(2) {
+		t.Fatalf("webhook %+v", n)
+	}
+
+	// Not due again within the day
+	if sent, err := h.SendDigests(context.Background(), now.Add(time.Hour)); err != nil || sent != 0 {
+		t.Fatalf("an hour later sent %d, %v", sent, err)
+	}
+
+	// The next daily digest only lists what was found since the last
+	db.mentions = append(db.mentions, model.DigestMention{Search: "Acme", Title: "Acme hires", Link: "https://news.example.com/hires", Created_At: now.Add(3 * time.Hour)})
+	sent, err = h.SendDigests(context.Background(), now.Add(25*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent != 1 || len(db.emails) != 2 || len(webhooks) != 1 {
+		t.Fatalf("next day sent %d: %d emails, %d webhooks", sent, len(db.emails), len(webhooks))
+	}
+	if body := db.emails[1].Text_Body; !strings.Contains(body, "/hires") || strings.Contains(body, "/award") {
+		t.Fatalf("second digest:\n%s", body)
+	}
+}
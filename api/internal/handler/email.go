@@ -0,0 +1,259 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	common "github.com/htstinson/stinsondataapi/api/commonweb"
+	"github.com/htstinson/stinsondataapi/api/internal/mail"
+	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/htstinson/stinsondataapi/api/pkg/database"
+)
+
+// EmailOptions are where unsubscribe links point, with the token appended,
+// as set in config.Email, and the key that signs the tokens. Without
+// UnsubscribeURL email carries no unsubscribe link, though earlier
+// unsubscribes are still honoured.
+type EmailOptions struct {
+	UnsubscribeURL string
+	UnsubscribeKey []byte
+}
+
+// SetEmail replaces the email options. The unsubscribe key, derived from the
+// JWT secret by default, is kept when options has none.
+func (h *Handler) SetEmail(options EmailOptions) {
+	if options.UnsubscribeKey == nil {
+		options.UnsubscribeKey = h.email.UnsubscribeKey
+	}
+	h.email = options
+}
+
+// sendMail queues the email of the template called name in the background,
+// so that the response neither waits for it nor reveals by its timing
+// whether one was sent. The subscriber's own template is used when it has
+// one.
+func (h *Handler) sendMail(ctx context.Context, to string, subscriberID string, name string, data map[string]any) {
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		if err := h.queueMail(ctx, to, subscriberID, name, data); err != nil {
+			h.logger.ErrorContext(ctx, "sendMail", "template", name, "error", err)
+		}
+	}()
+}
+
+// queueMail renders the email and adds it to the outbox, suppressed when the
+// recipient has unsubscribed from its category.
+func (h *Handler) queueMail(ctx context.Context, to string, subscriberID string, name string, data map[string]any) error {
+	builtin, ok := mail.Template(name)
+	if !ok {
+		return fmt.Errorf("unknown email template %q", name)
+	}
+
+	email := model.Email{
+		Subscriber_Id: subscriberID,
+		Template:      name,
+		Category:      builtin.Category,
+		To:            to,
+	}
+	if mail.Unsubscribable(email.Category) {
+		unsubscribed, err := h.db.IsUnsubscribed(ctx, to, email.Category)
+		if err != nil {
+			return err
+		}
+		if unsubscribed {
+			email.Status = model.EmailSuppressed
+		}
+		if h.email.UnsubscribeURL != "" {
+			email.Unsubscribe_URL = tokenLink(h.email.UnsubscribeURL, mail.UnsubscribeToken(h.email.UnsubscribeKey, to, email.Category))
+		}
+	}
+
+	if data == nil {
+		data = map[string]any{}
+	}
+	data["Unsubscribe"] = email.Unsubscribe_URL
+
+	template := builtin
+	if subscriberID != "" {
+		own, err := h.db.GetEmailTemplate(ctx, subscriberID, name)
+		if err != nil {
+			return err
+		}
+		if own != nil {
+			template = *own
+		}
+	}
+
+	var err error
+	email.Subject, email.Text_Body, email.Html_Body, err = mail.Render(template, data)
+	if err != nil && template.Subscriber_Id != "" {
+		// A subscriber's template that cannot render should not stop the email
+		h.logger.WarnContext(ctx, "queueMail", "template", name, "subscriber_id", subscriberID, "error", err)
+		email.Subject, email.Text_Body, email.Html_Body, err = mail.Render(builtin, data)
+	}
+	if err != nil {
+		return fmt.Errorf("error rendering email %s: %w", name, err)
+	}
+
+	_, err = h.db.CreateEmail(ctx, &email)
+	return err
+}
+
+// SelectEmails lists the outbox: queued, sent, failed and suppressed email.
+func (h *Handler) SelectEmails(w http.ResponseWriter, r *http.Request) {
+	q, ok := h.listQuery(w, r, database.EmailList)
+	if !ok {
+		return
+	}
+
+	emails, err := h.db.SelectEmails(r.Context(), q)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to list emails")
+		return
+	}
+
+	common.RespondJSON(w, http.StatusOK, emails)
+}
+
+// SelectEmailTemplates lists the templates email of the subscriber is sent
+// with: its own where it has one, and the built in ones otherwise.
+func (h *Handler) SelectEmailTemplates(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "SelectEmailTemplates")
+	id := mux.Vars(r)["id"]
+
+	ctx := r.Context()
+	own, err := h.db.SelectEmailTemplates(ctx, id)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to list email templates")
+		return
+	}
+
+	templates := mail.Templates()
+	for i := range templates {
+		for _, t := range own {
+			if t.Name == templates[i].Name {
+				t.Category = templates[i].Category
+				templates[i] = t
+			}
+		}
+	}
+
+	common.RespondJSON(w, http.StatusOK, templates)
+}
+
+// SaveEmailTemplate sets the subscriber's own version of a built in
+// template.
+func (h *Handler) SaveEmailTemplate(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "SaveEmailTemplate")
+	vars := mux.Vars(r)
+
+	builtin, ok := mail.Template(vars["name"])
+	if !ok {
+		common.RespondError(w, http.StatusNotFound, "Email template not found")
+		return
+	}
+
+	var template model.EmailTemplate
+	if !h.decode(w, r, &template, "Subject", "Text_Body", "Html_Body") {
+		return
+	}
+	if err := mail.Parse(template); err != nil {
+		h.respondError(w, r, common.ValidationError(map[string][]string{"template": {err.Error()}}), "Invalid email template")
+		return
+	}
+
+	ctx := r.Context()
+	subscriber, err := h.db.GetSubscriber(ctx, vars["id"])
+	if err != nil {
+		h.respondError(w, r, err, "Failed to get subscriber")
+		return
+	}
+	if subscriber == nil {
+		common.RespondError(w, http.StatusNotFound, "Subscriber not found")
+		return
+	}
+
+	template.Subscriber_Id = subscriber.Id
+	template.Name = builtin.Name
+	saved, err := h.db.SaveEmailTemplate(ctx, &template)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to save email template")
+		return
+	}
+	saved.Category = builtin.Category
+
+	common.RespondJSON(w, http.StatusOK, saved)
+}
+
+// DeleteEmailTemplate returns the subscriber to the built in template.
+func (h *Handler) DeleteEmailTemplate(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "DeleteEmailTemplate")
+	vars := mux.Vars(r)
+
+	if err := h.db.DeleteEmailTemplate(r.Context(), vars["id"], vars["name"]); err != nil {
+		h.respondError(w, r, err, "Email template not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+var errUnsubscribeInvalid = common.NewError(http.StatusBadRequest, common.CodeBadRequest, "The unsubscribe link is invalid")
+
+// GetUnsubscribe says what an unsubscribe link is for, without acting on
+// it, so that link scanners opening it do not unsubscribe anyone.
+func (h *Handler) GetUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "GetUnsubscribe")
+
+	email, category, ok := h.unsubscribeToken(w, r, r.URL.Query().Get("token"))
+	if !ok {
+		return
+	}
+
+	unsubscribed, err := h.db.IsUnsubscribed(r.Context(), email, category)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to check unsubscribe")
+		return
+	}
+
+	common.RespondJSON(w, http.StatusOK, model.EmailUnsubscribe{Email: email, Category: category, Unsubscribed: unsubscribed})
+}
+
+// Unsubscribe acts on an unsubscribe link. The token is read from the token
+// query parameter, as mail clients send one-click unsubscribes to the link
+// itself, or from the body.
+func (h *Handler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "Unsubscribe")
+
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		var req model.EmailUnsubscribeRequest
+		if !h.decode(w, r, &req) {
+			return
+		}
+		token = req.Token
+	}
+
+	email, category, ok := h.unsubscribeToken(w, r, token)
+	if !ok {
+		return
+	}
+
+	if err := h.db.Unsubscribe(r.Context(), email, category); err != nil {
+		h.respondError(w, r, err, "Failed to unsubscribe")
+		return
+	}
+
+	common.RespondJSON(w, http.StatusOK, model.EmailUnsubscribe{Email: email, Category: category, Unsubscribed: true})
+}
+
+func (h *Handler) unsubscribeToken(w http.ResponseWriter, r *http.Request, token string) (string, string, bool) {
+	email, category, err := mail.ParseUnsubscribeToken(h.email.UnsubscribeKey, token)
+	if err != nil || !mail.Unsubscribable(category) {
+		h.respondError(w, r, errUnsubscribeInvalid, "Invalid unsubscribe token")
+		return "", "", false
+	}
+	return email, category, true
+}
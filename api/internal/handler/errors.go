@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	common "github.com/htstinson/stinsondataapi/api/commonweb"
+	"github.com/htstinson/stinsondataapi/api/pkg/database"
+)
+
+// respondError reports err as a problem document. An *common.Error keeps its
+// status and code, database errors are mapped to 404, 409 or 400, and
+// anything else is a 500 with message as its detail. Server errors are
+// logged with their cause, which is never shown to the client.
+func (h *Handler) respondError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	var apiErr *common.Error
+	switch {
+	case errors.As(err, &apiErr):
+	case database.IsNotFound(err):
+		apiErr = common.WrapError(err, http.StatusNotFound, common.CodeNotFound, message)
+	case database.IsDuplicate(err):
+		apiErr = common.WrapError(err, http.StatusConflict, common.CodeConflict, message+": already exists")
+	case database.IsForeignKeyViolation(err):
+		apiErr = common.WrapError(err, http.StatusConflict, common.CodeInvalidReference, message+": a referenced record does not exist or is still in use")
+	case database.IsInvalidInput(err):
+		apiErr = common.WrapError(err, http.StatusBadRequest, common.CodeBadRequest, message+": invalid value")
+	default:
+		apiErr = common.WrapError(err, http.StatusInternalServerError, common.CodeInternal, message)
+	}
+
+	if apiErr.Status >= 500 {
+		h.logger.ErrorContext(r.Context(), message, "error", err)
+	} else {
+		h.logger.DebugContext(r.Context(), message, "status", apiErr.Status, "error", err)
+	}
+
+	common.RespondProblem(w, apiErr)
+}
@@ -1,33 +1,83 @@
 package handler
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
-	"github.com/htstinson/stinsondataapi/api/commonweb"
 	common "github.com/htstinson/stinsondataapi/api/commonweb"
 	"github.com/htstinson/stinsondataapi/api/internal/auth"
+	"github.com/htstinson/stinsondataapi/api/internal/mail"
+	"github.com/htstinson/stinsondataapi/api/internal/middleware"
 	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/htstinson/stinsondataapi/api/internal/password"
+	"github.com/htstinson/stinsondataapi/api/internal/secrets"
+	"github.com/htstinson/stinsondataapi/api/internal/totp"
 	"github.com/htstinson/stinsondataapi/api/pkg/database"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
 type Handler struct {
-	db     database.Repository
-	auth   auth.JWTAuth
-	logger *log.Logger
+	db            database.Repository
+	auth          auth.JWTAuth
+	logger        *slog.Logger
+	blocklist     *middleware.BlockList
+	allowlist     *middleware.AllowList
+	secrets       secrets.ReadWriter
+	passwords     PasswordOptions
+	mfa           MFAOptions
+	sso           SSOOptions
+	invitations   InvitationOptions
+	registration  RegistrationOptions
+	apiKeys       APIKeyOptions
+	email         EmailOptions
+	notifications NotificationOptions
 }
 
-func NewHandler(db database.Repository, auth auth.JWTAuth, logger *log.Logger) *Handler {
-	return &Handler{db: db, auth: auth, logger: logger}
+func NewHandler(db database.Repository, auth auth.JWTAuth, logger *slog.Logger, secrets secrets.ReadWriter) *Handler {
+	policy, _ := password.NewPolicy(8, "")
+	cipher, _ := totp.NewCipher(auth.Config.SecretKey)
+	return &Handler{
+		db:          db,
+		auth:        auth,
+		logger:      logger,
+		allowlist:   middleware.NewAllowList(db),
+		secrets:     secrets,
+		passwords:   PasswordOptions{Policy: policy, ResetTTL: time.Hour},
+		mfa:         MFAOptions{Cipher: cipher, Issuer: "Thousand Hills Digital"},
+		invitations: InvitationOptions{TTL: 7 * 24 * time.Hour},
+		registration: RegistrationOptions{
+			Mode:         RegistrationOpen,
+			VerifyTTL:    24 * time.Hour,
+			CleanupAfter: 7 * 24 * time.Hour,
+		},
+		apiKeys: APIKeyOptions{DefaultTTL: 90 * 24 * time.Hour, MaxTTL: 365 * 24 * time.Hour},
+		email:   EmailOptions{UnsubscribeKey: mail.UnsubscribeKey(auth.Config.SecretKey)},
+		notifications: NotificationOptions{
+			Client:   NewWebhookClient(10 * time.Second),
+			Defaults: DefaultNotifications,
+		},
+	}
+}
+
+// SetBlockList makes changes to the blocked table take effect in the
+// in-process block list straight away instead of on its next refresh.
+func (h *Handler) SetBlockList(blocklist *middleware.BlockList) {
+	h.blocklist = blocklist
+}
+
+// SetAllowList replaces the allow list consulted before anything is blocked.
+func (h *Handler) SetAllowList(allowlist *middleware.AllowList) {
+	h.allowlist = allowlist
 }
 
 func (h *Handler) HealthCheck(w http.ResponseWriter, r *http.Request) {
-	fmt.Printf("[%v] HealthCheck\n", time.Now().Format(time.RFC3339))
+	h.logger.DebugContext(r.Context(), "HealthCheck")
 	common.RespondJSON(w, http.StatusOK, map[string]string{
 		"status": "healthy",
 		"time":   time.Now().Format(time.RFC3339),
@@ -35,24 +85,39 @@ func (h *Handler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 }
 
 // All - UI
+
+// Register creates an account for an email when registration is open. The
+// account cannot log in until the email is verified with the link sent to
+// it. When registration is by invitation, accounts are only made by
+// accepting one.
 func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
-	var req model.LoginRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	h.logger.DebugContext(r.Context(), "Register")
+
+	switch h.registration.Mode {
+	case RegistrationOpen:
+	case RegistrationInvite:
+		h.respondError(w, r, common.NewError(http.StatusForbidden, common.CodeForbidden, "Registration is by invitation only"), "Registration refused")
+		return
+	default:
+		h.respondError(w, r, common.NewError(http.StatusForbidden, common.CodeForbidden, "Registration is closed"), "Registration refused")
 		return
 	}
-	defer r.Body.Close()
 
-	// Validate input
-	if req.Username == "" || req.Password == "" {
-		common.RespondError(w, http.StatusBadRequest, "Username and password required")
+	var req model.RegisterRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+	req.Username = model.NormalizeUsername(req.Username)
+
+	if !h.checkPassword(w, r, req.Password, req.Username) {
 		return
 	}
 
 	// Check if user exists
-	existingUser, err := h.db.GetUserByUsername(r.Context(), req.Username)
+	ctx := r.Context()
+	existingUser, err := h.db.GetUserByUsername(ctx, req.Username)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Error checking username")
+		h.respondError(w, r, err, "Error checking username")
 		return
 	}
 	if existingUser != nil {
@@ -60,137 +125,188 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Create user
-	user, err := h.db.CreateUser(r.Context(), req.Username, req.Password)
+	user, err := h.db.RegisterUser(ctx, req.Username, req.Password)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Error creating user")
+		h.respondError(w, r, err, "Error creating user")
 		return
 	}
 
+	if err := h.sendVerification(ctx, user); err != nil {
+		h.logger.ErrorContext(ctx, "Register", "error", err)
+	}
+
 	common.RespondJSON(w, http.StatusCreated, user)
 }
 
 func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
-	fmt.Printf("[%v] [Login]\n", time.Now().Format(time.RFC3339))
+	h.logger.DebugContext(r.Context(), "Login")
 	var req model.LoginRequest
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		fmt.Printf("[%v] Error: %s\n", time.Now().Format(time.RFC3339), err.Error())
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &req) {
 		return
 	}
-	defer r.Body.Close()
 
 	// Get user
 	user, err := h.db.GetUserByUsername(r.Context(), req.Username)
 	if err != nil {
-		fmt.Printf("[%v] Error: %s\n", time.Now().Format(time.RFC3339), err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Error finding user")
+		h.respondError(w, r, err, "Error finding user")
 		return
 	}
-	if user == nil {
+	// Service accounts only act through their API keys
+	if user == nil || user.Service_Account {
+		h.auditAccount(r.Context(), model.AuditLoginFailed, req.Username, nil)
 		common.RespondError(w, http.StatusUnauthorized, "Invalid credentials")
 		return
 	}
 
-	fmt.Println("Login user.IP", user.IP_address)
+	h.logger.DebugContext(r.Context(), "Login", "login_user_ip", user.IP_address)
+
+	if h.refuseLocked(w, r, user) {
+		return
+	}
 
 	// Check password
 	if err := bcrypt.CompareHashAndPassword(
 		[]byte(user.PasswordHash),
 		[]byte(req.Password),
 	); err != nil {
-		fmt.Printf("[%v] Error: %s\n", time.Now().Format(time.RFC3339), err.Error())
+		h.logger.InfoContext(r.Context(), "Invalid credentials", "username", req.Username)
+		h.auditAccount(r.Context(), model.AuditLoginFailed, req.Username, user)
+		h.loginFailed(r.Context(), user)
 		common.RespondError(w, http.StatusUnauthorized, "Invalid credentials")
 		return
 	}
 
-	// Generate token
-	// TODO replace this with roles per user_subscription
-	roles, err := h.db.SelectRolesByUser(r.Context(), user.ID)
-	if err != nil {
-		fmt.Println(err.Error())
+	// Only said once the password is right, so it reveals nothing more
+	if user.Email_Verified_At == nil {
+		h.respondError(w, r, common.NewError(http.StatusForbidden, common.CodeEmailUnverified, "The email of the account has not been verified"), "Email not verified")
 		return
 	}
 
-	var user_subscriber_view = model.User_Subscriber_View{
-		User_ID: user.ID,
-	}
-
-	fmt.Println("handler login user.ID = ", user.ID)
-
-	user_subscriber_role_view, err := h.db.SelectUserSubscriberRoleView(r.Context(), user_subscriber_view, 100, 0)
+	// With MFA enabled or required, the token is only issued by LoginMFA
+	purpose, err := h.mfaChallenge(r.Context(), user)
 	if err != nil {
-		fmt.Println(err.Error())
+		h.respondError(w, r, err, "Failed to check MFA")
+		return
+	}
+	if purpose != "" {
+		mfaToken, err := h.auth.GenerateMFAToken(user.ID, purpose)
+		if err != nil {
+			h.respondError(w, r, err, "Error generating token")
+			return
+		}
+		common.RespondJSON(w, http.StatusOK, model.LoginResponse{
+			MFA_Token: mfaToken,
+			ExpiresIn: int64(h.auth.MFADuration().Seconds()),
+			MFA:       purpose,
+		})
+		return
 	}
 
-	token, err := h.auth.GenerateToken(*user, roles, user_subscriber_role_view)
+	h.issueToken(w, r, user, nil)
+}
+
+// issueToken completes the login of user, answering their token. Recovery
+// codes are included when the login has just enabled MFA.
+func (h *Handler) issueToken(w http.ResponseWriter, r *http.Request, user *model.User, recoveryCodes []string) {
+	token, err := h.loginToken(r.Context(), user)
 	if err != nil {
-		fmt.Printf("[%v] Error: %s\n", time.Now().Format(time.RFC3339), err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Error generating token")
+		h.respondError(w, r, err, "Error generating token")
 		return
 	}
 
 	w.Header().Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	common.RespondJSON(w, http.StatusOK, model.LoginResponse{
+		Token:         token,
+		ExpiresIn:     int64(h.auth.Config.TokenDuration.Seconds()),
+		RecoveryCodes: recoveryCodes,
+	})
+}
 
-	login_time := time.Now().Format(time.RFC3339)
-
-	body := fmt.Sprintf(
-		`Your account logged into Thousand Hills Digital at %v.
-
-If this was not you, please contact support@stinsondata.com.
+// loginToken records the login of user, whose credentials have all been
+// checked, and returns their token.
+func (h *Handler) loginToken(ctx context.Context, user *model.User) (string, error) {
+	if user.FailedLogins > 0 || user.LockedUntil != nil {
+		if err := h.db.ResetFailedLogins(ctx, user.ID); err != nil {
+			h.logger.ErrorContext(ctx, "Login", "error", err)
+		}
+	}
 
-Thank you!`, login_time)
+	// Generate token
+	roles, err := h.db.SelectRolesByUser(ctx, user.ID)
+	if err != nil {
+		return "", fmt.Errorf("error getting roles: %w", err)
+	}
 
-	region := "us-west-2"
-	/* TODO - Create a profile setting to determine if this is sent.
-	    Need to have a choice of accounts to send from
-		Also need to create the GCP connection to gmail automatically
-		Also need to profile the body and subject of the message
-		Full functionality will need to be able to read a mailbox
-		Need e-mail address verification
-		Need unsubscribe functionality
-		Need to log e-mails that are sent
-	*/
-	if false {
-		commonweb.SendMail(user.Username, "Thousand Hills Digital - Login", body, region)
+	// A user of one subscriber starts scoped to it; others select one
+	tenants, err := h.db.SelectUserTenants(ctx, user.ID)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "Login", "error", err)
+	}
+	var tenant *model.Tenant
+	if len(tenants) == 1 {
+		tenant = &tenants[0]
 	}
 
-	common.RespondJSON(w, http.StatusOK, model.LoginResponse{
-		Token:     token,
-		ExpiresIn: int64(h.auth.Config.TokenDuration.Seconds()),
-	})
-}
+	token, err := h.auth.GenerateToken(*user, roles, tenant)
+	if err != nil {
+		return "", err
+	}
 
-func (h *Handler) ValidSort(r *http.Request) string {
-	fmt.Println("h ValidSort")
+	h.auditAccount(ctx, model.AuditLogin, user.Username, user)
 
-	allowed := map[string]bool{"name": true, "created_at": true}
-	sort := r.URL.Query().Get("sort")
-	if sort == "" {
-		sort = "id"
+	subscriberID := ""
+	if tenant != nil {
+		subscriberID = tenant.Subscriber_Id
 	}
+	h.notify(ctx, user, subscriberID, model.NotificationLogin, mail.TemplateLoginNotice, map[string]any{
+		"Time": time.Now().Format(time.RFC3339),
+	})
 
-	fmt.Println("sort = ", sort)
+	return token, nil
+}
 
-	if allowed[sort] {
-		return sort
+// refuseLocked refuses the login and returns true while the account is
+// locked or too soon after a failure.
+func (h *Handler) refuseLocked(w http.ResponseWriter, r *http.Request, user *model.User) bool {
+	now := time.Now()
+	if user.LockedUntil != nil && now.Before(*user.LockedUntil) {
+		h.refuseLogin(w, r, http.StatusLocked, common.CodeAccountLocked, "The account is locked after repeated failed logins", user.LockedUntil.Sub(now))
+		return true
+	}
+	if user.LastFailedLogin != nil {
+		if wait := h.passwords.Lockout.Delay(user.FailedLogins) - now.Sub(*user.LastFailedLogin); wait > 0 {
+			h.refuseLogin(w, r, http.StatusTooManyRequests, common.CodeRateLimited, "Too many failed logins, try again later", wait)
+			return true
+		}
 	}
-	return "id"
+	return false
 }
 
-func (h *Handler) ValidOrder(r *http.Request) string {
-	fmt.Println("h ValidOrder")
-
-	order := r.URL.Query().Get("order")
-	if order == "" {
-		order = "asc"
+// loginFailed counts a failed login of user and locks the account once the
+// failures reach the lockout threshold.
+func (h *Handler) loginFailed(ctx context.Context, user *model.User) {
+	failures, err := h.db.RecordFailedLogin(ctx, user.ID)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "loginFailed", "error", err)
+		return
+	}
+	if !h.passwords.Lockout.Locks(failures) {
+		return
 	}
 
-	fmt.Println("order = ", order)
-
-	if order == "desc" {
-		return "DESC"
+	until := time.Now().Add(h.passwords.Lockout.Duration)
+	if err := h.db.LockUser(ctx, user.ID, until); err != nil {
+		h.logger.ErrorContext(ctx, "loginFailed", "error", err)
+		return
 	}
-	return "ASC"
+	h.logger.WarnContext(ctx, "account locked", "user_id", user.ID, "failures", failures, "until", until)
+	h.auditAccount(ctx, model.AuditLockout, user.Username, user)
+	h.notifyLocked(ctx, user, until)
+}
+
+// refuseLogin answers a login that is not tried, with Retry-After set to wait.
+func (h *Handler) refuseLogin(w http.ResponseWriter, r *http.Request, status int, code string, detail string, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	h.respondError(w, r, common.NewError(status, code, detail), "Login refused")
 }
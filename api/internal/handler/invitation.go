@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	common "github.com/htstinson/stinsondataapi/api/commonweb"
+	"github.com/htstinson/stinsondataapi/api/internal/auth"
+	"github.com/htstinson/stinsondataapi/api/internal/mail"
+	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/htstinson/stinsondataapi/api/pkg/database"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// InvitationOptions are how long invitations last and the page their links
+// open, as set in config.Invitations.
+type InvitationOptions struct {
+	TTL time.Duration
+	URL string
+}
+
+// SetInvitations replaces the invitation options, which default to week
+// long invitations sent as bare tokens.
+func (h *Handler) SetInvitations(options InvitationOptions) {
+	h.invitations = options
+}
+
+// CreateInvitation invites an email to a subscriber with a role and emails
+// the link. A pending invitation for the same email and subscriber is
+// revoked.
+func (h *Handler) CreateInvitation(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "CreateInvitation")
+
+	var invitation model.Invitation
+	if !h.decode(w, r, &invitation, "Email", "Subscriber_Id", "Role_Id") {
+		return
+	}
+	invitation.Email = strings.ToLower(strings.TrimSpace(invitation.Email))
+
+	ctx := r.Context()
+	subscriber, err := h.db.GetSubscriber(ctx, invitation.Subscriber_Id)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to get subscriber")
+		return
+	}
+	role, err := h.db.GetRole(ctx, invitation.Role_Id)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to get role")
+		return
+	}
+	fields := map[string][]string{}
+	if subscriber == nil {
+		fields["subscriber_id"] = []string{"no subscriber has this id"}
+	}
+	if role == nil {
+		fields["role_id"] = []string{"no role has this id"}
+	}
+	if len(fields) > 0 {
+		h.respondError(w, r, common.ValidationError(fields), "Invalid invitation")
+		return
+	}
+
+	if claims, ok := ctx.Value("user").(*auth.Claims); ok {
+		invitation.Invited_By = claims.UserID
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		h.respondError(w, r, err, "Failed to create invitation")
+		return
+	}
+	invitation.Expires_At = time.Now().Add(h.invitations.TTL)
+
+	created, err := h.db.CreateInvitation(ctx, &invitation, hashToken(token))
+	if err != nil {
+		h.respondError(w, r, err, "Failed to create invitation")
+		return
+	}
+
+	h.sendInvitation(ctx, created, subscriber, role, token)
+
+	common.RespondJSON(w, http.StatusCreated, created)
+}
+
+// SelectInvitations lists invitations; filter[status]=pending lists those
+// that can still be accepted.
+func (h *Handler) SelectInvitations(w http.ResponseWriter, r *http.Request) {
+	q, ok := h.listQuery(w, r, database.InvitationList)
+	if !ok {
+		return
+	}
+
+	invitations, err := h.db.SelectInvitations(r.Context(), q)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to list invitations")
+		return
+	}
+
+	common.RespondJSON(w, http.StatusOK, invitations)
+}
+
+// RevokeInvitation withdraws a pending invitation, so that its link no
+// longer works.
+func (h *Handler) RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "RevokeInvitation")
+	id := mux.Vars(r)["id"]
+
+	ctx := r.Context()
+	if err := h.db.RevokeInvitation(ctx, id); err != nil {
+		h.respondError(w, r, err, "No pending invitation")
+		return
+	}
+
+	invitation, err := h.db.GetInvitation(ctx, id)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to get invitation")
+		return
+	}
+
+	common.RespondJSON(w, http.StatusOK, invitation)
+}
+
+// AcceptInvitation accepts an invitation with the token of its link. When no
+// user has the invited email one is created with the password given;
+// otherwise the existing user is added and the password is not used.
+func (h *Handler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "AcceptInvitation")
+
+	var req model.InvitationAcceptRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	ctx := r.Context()
+	tokenHash := hashToken(req.Token)
+	invitation, err := h.db.GetPendingInvitation(ctx, tokenHash)
+	if database.IsNotFound(err) {
+		h.respondError(w, r, errInvitationInvalid, "Invalid invitation")
+		return
+	}
+	if err != nil {
+		h.respondError(w, r, err, "Failed to get invitation")
+		return
+	}
+
+	user, err := h.db.GetUserByUsername(ctx, invitation.Email)
+	if err != nil {
+		h.respondError(w, r, err, "Error finding user")
+		return
+	}
+
+	userID, passwordHash := "", ""
+	if user != nil {
+		userID = user.ID
+	} else {
+		if req.Password == "" {
+			h.respondError(w, r, common.ValidationError(map[string][]string{"password": {"is required to create the account"}}), "Password required")
+			return
+		}
+		if !h.checkPassword(w, r, req.Password, invitation.Email) {
+			return
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			h.respondError(w, r, err, "Error hashing password")
+			return
+		}
+		passwordHash = string(hash)
+	}
+
+	accepted, err := h.db.AcceptInvitation(ctx, tokenHash, userID, passwordHash)
+	if database.IsNotFound(err) {
+		h.respondError(w, r, errInvitationInvalid, "Invalid invitation")
+		return
+	}
+	if err != nil {
+		h.respondError(w, r, err, "Failed to accept invitation")
+		return
+	}
+
+	common.RespondJSON(w, http.StatusOK, accepted)
+}
+
+var errInvitationInvalid = common.NewError(http.StatusBadRequest, common.CodeBadRequest, "The invitation is invalid, revoked, accepted or expired")
+
+// sendInvitation emails the invitation's link.
+func (h *Handler) sendInvitation(ctx context.Context, invitation *model.Invitation, subscriber *model.Subscriber, role *model.Role, token string) {
+	h.sendMail(ctx, invitation.Email, subscriber.Id, mail.TemplateInvitation, map[string]any{
+		"Subscriber": subscriber.Name,
+		"Role":       role.Name,
+		"Link":       tokenLink(h.invitations.URL, token),
+		"Expires":    invitation.Expires_At.Format(time.RFC1123),
+	})
+}
@@ -1,27 +1,25 @@
 package handler
 
 import (
-	"encoding/json"
 	"net/http"
 
 	"github.com/gorilla/mux"
 	common "github.com/htstinson/stinsondataapi/api/commonweb"
 	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/htstinson/stinsondataapi/api/pkg/database"
 )
 
 // Item - Create, Update, Delete, Get, List
 
 func (h *Handler) CreateItem(w http.ResponseWriter, r *http.Request) {
 	var item model.Item
-	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &item) {
 		return
 	}
-	defer r.Body.Close()
 
 	ctx := r.Context()
 	if err := h.db.CreateItem(ctx, &item); err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to create item")
+		h.respondError(w, r, err, "Failed to create item")
 		return
 	}
 
@@ -35,15 +33,13 @@ func (h *Handler) UpdateItem(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	var item model.Item
-	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &item) {
 		return
 	}
-	defer r.Body.Close()
 
 	currentitem, err := h.db.GetItem(ctx, id)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get item")
+		h.respondError(w, r, err, "Failed to get item")
 		return
 	}
 	if currentitem == nil {
@@ -54,7 +50,7 @@ func (h *Handler) UpdateItem(w http.ResponseWriter, r *http.Request) {
 	currentitem.Name = item.Name
 	err = h.db.UpdateItem(ctx, currentitem)
 	if err != nil {
-		common.RespondError(w, http.StatusNotFound, "Error updating item")
+		h.respondError(w, r, err, "Error updating item")
 		return
 	}
 
@@ -69,7 +65,7 @@ func (h *Handler) DeleteItem(w http.ResponseWriter, r *http.Request) {
 
 	item, err := h.db.GetItem(ctx, id)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get item")
+		h.respondError(w, r, err, "Failed to get item")
 		return
 	}
 	if item == nil {
@@ -78,7 +74,7 @@ func (h *Handler) DeleteItem(w http.ResponseWriter, r *http.Request) {
 	}
 	err = h.db.DeleteItem(ctx, id)
 	if err != nil {
-		common.RespondError(w, http.StatusNotFound, "Error deleting item")
+		h.respondError(w, r, err, "Error deleting item")
 		return
 	}
 
@@ -93,7 +89,7 @@ func (h *Handler) GetItem(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	item, err := h.db.GetItem(ctx, id)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get item")
+		h.respondError(w, r, err, "Failed to get item")
 		return
 	}
 	if item == nil {
@@ -105,10 +101,15 @@ func (h *Handler) GetItem(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) ListItems(w http.ResponseWriter, r *http.Request) {
+	q, ok := h.listQuery(w, r, database.ItemList)
+	if !ok {
+		return
+	}
+
 	ctx := r.Context()
-	items, err := h.db.SelectItems(ctx, 100, 0)
+	items, err := h.db.SelectItems(ctx, q)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to list items")
+		h.respondError(w, r, err, "Failed to list items")
 		return
 	}
 
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	common "github.com/htstinson/stinsondataapi/api/commonweb"
+	"github.com/htstinson/stinsondataapi/api/pkg/database"
+)
+
+// listQuery reads the paging, sorting and filtering parameters of a list
+// request:
+//
+//	limit             page size, database.DefaultLimit when missing
+//	offset or page    rows to skip, or the 1-based page of limit rows
+//	cursor            next_cursor of the previous page, instead of offset
+//	sort              a field of spec.Sorts; a leading "-" sorts descending
+//	order             asc or desc
+//	filter[field]     rows whose field equals the value
+//	filter[field][op] rows whose field compares to the value by op, one of
+//	                  eq, ne, lt, lte, gt, gte or like
+//
+// It responds 422 and returns false when they are malformed or not allowed
+// by spec.
+func (h *Handler) listQuery(w http.ResponseWriter, r *http.Request, spec database.ListSpec) (database.ListQuery, bool) {
+	q, errs := parseListQuery(r.URL.Query())
+	q = spec.Normalize(q)
+	for field, messages := range spec.Validate(q) {
+		errs[field] = append(errs[field], messages...)
+	}
+
+	if len(errs) > 0 {
+		h.respondError(w, r, common.ValidationError(errs), "Invalid list query")
+		return q, false
+	}
+
+	h.logger.DebugContext(r.Context(), "listQuery", "limit", q.Limit, "offset", q.Offset, "sort", q.Sort, "desc", q.Desc, "filters", len(q.Filters))
+	return q, true
+}
+
+func parseListQuery(values url.Values) (database.ListQuery, map[string][]string) {
+	var q database.ListQuery
+	errs := make(map[string][]string)
+
+	number := func(param string) int {
+		s := values.Get(param)
+		if s == "" {
+			return 0
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			errs[param] = append(errs[param], "must be a whole number")
+		}
+		return n
+	}
+
+	q.Limit = number("limit")
+	q.Offset = number("offset")
+	if page := number("page"); page != 0 {
+		switch {
+		case page < 1:
+			errs["page"] = append(errs["page"], "must be 1 or more")
+		case values.Has("offset"):
+			errs["page"] = append(errs["page"], "cannot be combined with offset")
+		default:
+			limit := q.Limit
+			if limit == 0 {
+				limit = database.DefaultLimit
+			}
+			q.Offset = (page - 1) * limit
+		}
+	}
+	q.Cursor = values.Get("cursor")
+
+	q.Sort = values.Get("sort")
+	if strings.HasPrefix(q.Sort, "-") {
+		q.Sort = q.Sort[1:]
+		q.Desc = true
+	}
+	switch strings.ToLower(values.Get("order")) {
+	case "":
+	case "asc":
+		q.Desc = false
+	case "desc":
+		q.Desc = true
+	default:
+		errs["order"] = append(errs["order"], "must be asc or desc")
+	}
+
+	for param, vals := range values {
+		rest, ok := strings.CutPrefix(param, "filter[")
+		if !ok {
+			continue
+		}
+		field, op, ok := strings.Cut(rest, "]")
+		if ok && op != "" {
+			var opened bool
+			op, opened = strings.CutPrefix(op, "[")
+			op, ok = strings.CutSuffix(op, "]")
+			ok = ok && opened
+		}
+		if !ok || field == "" {
+			errs[param] = append(errs[param], "must be filter[field] or filter[field][op]")
+			continue
+		}
+		for _, value := range vals {
+			q.Filters = append(q.Filters, database.Filter{Field: field, Op: op, Value: value})
+		}
+	}
+
+	return q, errs
+}
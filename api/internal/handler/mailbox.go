@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	common "github.com/htstinson/stinsondataapi/api/commonweb"
+	"github.com/htstinson/stinsondataapi/api/internal/mail"
+	"github.com/htstinson/stinsondataapi/api/internal/model"
+)
+
+// SelectMailboxSources lists the Gmail labels the subscriber reads mentions
+// from.
+func (h *Handler) SelectMailboxSources(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "SelectMailboxSources")
+
+	sources, err := h.db.SelectMailboxSources(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		h.respondError(w, r, err, "Failed to list mailbox sources")
+		return
+	}
+
+	common.RespondJSON(w, http.StatusOK, sources)
+}
+
+// CreateMailboxSource starts reading a Gmail label into one of the
+// subscriber's search definition engines.
+func (h *Handler) CreateMailboxSource(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "CreateMailboxSource")
+
+	var source model.MailboxSource
+	if !h.decode(w, r, &source) {
+		return
+	}
+
+	if !h.checkMailboxSource(w, r, mux.Vars(r)["id"], &source) {
+		return
+	}
+
+	created, err := h.db.CreateMailboxSource(r.Context(), &source)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to create mailbox source")
+		return
+	}
+
+	common.RespondJSON(w, http.StatusCreated, created)
+}
+
+// UpdateMailboxSource changes the label or search definition engine of a
+// source, or pauses it.
+func (h *Handler) UpdateMailboxSource(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "UpdateMailboxSource")
+	vars := mux.Vars(r)
+
+	var source model.MailboxSource
+	if !h.decode(w, r, &source) {
+		return
+	}
+
+	if _, ok := h.mailboxSource(w, r, vars["id"], vars["mailbox_id"]); !ok {
+		return
+	}
+	if !h.checkMailboxSource(w, r, vars["id"], &source) {
+		return
+	}
+
+	source.Id = vars["mailbox_id"]
+	updated, err := h.db.UpdateMailboxSource(r.Context(), &source)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to update mailbox source")
+		return
+	}
+
+	common.RespondJSON(w, http.StatusOK, updated)
+}
+
+// DeleteMailboxSource stops reading a label. Results already read are kept.
+func (h *Handler) DeleteMailboxSource(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "DeleteMailboxSource")
+	vars := mux.Vars(r)
+
+	if _, ok := h.mailboxSource(w, r, vars["id"], vars["mailbox_id"]); !ok {
+		return
+	}
+
+	if err := h.db.DeleteMailboxSource(r.Context(), vars["mailbox_id"]); err != nil {
+		h.respondError(w, r, err, "Mailbox source not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// NotifyMailboxResults tells the subscriber's users of results read from a
+// mailbox source, as mention alerts. It is the mailbox poller's Notify.
+func (h *Handler) NotifyMailboxResults(ctx context.Context, source model.MailboxSource, results int) {
+	subscriber, err := h.db.GetSubscriber(ctx, source.Subscriber_Id)
+	if err != nil || subscriber == nil {
+		h.logger.ErrorContext(ctx, "NotifyMailboxResults", "subscriber_id", source.Subscriber_Id, "error", err)
+		return
+	}
+
+	engine, err := h.db.GetSearchDefinitionEnginesView(ctx, *subscriber, source.Search_Definition_Engine_Id)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "NotifyMailboxResults", "subscriber_id", source.Subscriber_Id, "error", err)
+		return
+	}
+
+	h.notifySubscriber(ctx, subscriber.Id, model.NotificationMention, mail.TemplateMentionAlert, map[string]any{
+		"Search":     engine.SearchDefinitionName,
+		"Count":      results,
+		"Subscriber": subscriber.Name,
+	})
+}
+
+// mailboxSource returns the source, which must be the subscriber's.
+func (h *Handler) mailboxSource(w http.ResponseWriter, r *http.Request, subscriberID string, id string) (*model.MailboxSource, bool) {
+	source, err := h.db.GetMailboxSource(r.Context(), id)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to get mailbox source")
+		return nil, false
+	}
+	if source == nil || source.Subscriber_Id != subscriberID {
+		common.RespondError(w, http.StatusNotFound, "Mailbox source not found")
+		return nil, false
+	}
+	return source, true
+}
+
+// checkMailboxSource sets the source's subscriber and checks that its
+// search definition engine is one of the subscriber's.
+func (h *Handler) checkMailboxSource(w http.ResponseWriter, r *http.Request, subscriberID string, source *model.MailboxSource) bool {
+	ctx := r.Context()
+	subscriber, err := h.db.GetSubscriber(ctx, subscriberID)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to get subscriber")
+		return false
+	}
+	if subscriber == nil {
+		common.RespondError(w, http.StatusNotFound, "Subscriber not found")
+		return false
+	}
+
+	engine, err := h.db.GetSearchDefinitionEnginesView(ctx, *subscriber, source.Search_Definition_Engine_Id)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to get search definition engine")
+		return false
+	}
+	if engine.Id == "" {
+		h.respondError(w, r, common.ValidationError(map[string][]string{
+			"search_definition_engine_id": {"is not a search definition engine of the subscriber"},
+		}), "Invalid mailbox source")
+		return false
+	}
+
+	source.Subscriber_Id = subscriber.Id
+	return true
+}
@@ -0,0 +1,464 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	common "github.com/htstinson/stinsondataapi/api/commonweb"
+	"github.com/htstinson/stinsondataapi/api/internal/auth"
+	"github.com/htstinson/stinsondataapi/api/internal/mail"
+	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/htstinson/stinsondataapi/api/internal/totp"
+	"github.com/htstinson/stinsondataapi/api/pkg/database"
+)
+
+// recoveryCodeCount is how many recovery codes a user is given at a time.
+const recoveryCodeCount = 10
+
+var errMFANotConfigured = errors.New("MFA cipher is not configured")
+
+// MFAOptions seal the TOTP secrets and name the issuer authenticator apps
+// show, as set in config.MFA.
+type MFAOptions struct {
+	Cipher *totp.Cipher
+	Issuer string
+}
+
+// SetMFA replaces the MFA options, which default to a cipher keyed with the
+// JWT secret.
+func (h *Handler) SetMFA(options MFAOptions) {
+	h.mfa = options
+}
+
+// GetMFA reports whether the user has enrolled and how many recovery codes
+// are left.
+func (h *Handler) GetMFA(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "GetMFA")
+
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	mfa, err := h.db.GetUserMFA(r.Context(), user.ID)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to get MFA")
+		return
+	}
+	if mfa == nil {
+		mfa = &model.UserMFA{User_Id: user.ID}
+	}
+
+	common.RespondJSON(w, http.StatusOK, mfa)
+}
+
+// StartMFA starts a TOTP enrolment, which VerifyMFA completes. Starting
+// again replaces an enrolment that has not been verified.
+func (h *Handler) StartMFA(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "StartMFA")
+
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	setup, err := h.startEnrolment(r.Context(), user)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to start MFA enrolment")
+		return
+	}
+
+	common.RespondJSON(w, http.StatusCreated, setup)
+}
+
+// VerifyMFA enables MFA once the user gives a code of the secret StartMFA
+// returned, and answers the recovery codes.
+func (h *Handler) VerifyMFA(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "VerifyMFA")
+
+	var req model.MFACodeRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	codes, err := h.completeEnrolment(r.Context(), user, req.Code)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to enable MFA")
+		return
+	}
+
+	common.RespondJSON(w, http.StatusOK, model.MFARecoveryCodes{Codes: codes})
+}
+
+// DisableMFA removes the user's enrolment, given a current code or a
+// recovery code. Users of a subscriber that requires MFA cannot.
+func (h *Handler) DisableMFA(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "DisableMFA")
+
+	var req model.MFACodeRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	required, err := h.db.MFARequired(ctx, user.ID)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to check MFA policy")
+		return
+	}
+	if required {
+		h.respondError(w, r, common.NewError(http.StatusForbidden, common.CodeForbidden, "A subscriber you belong to requires MFA"), "MFA required")
+		return
+	}
+
+	if !h.checkMFACode(w, r, user, req.Code) {
+		return
+	}
+
+	if err := h.db.DeleteUserMFA(ctx, user.ID); err != nil {
+		h.respondError(w, r, err, "Failed to disable MFA")
+		return
+	}
+	h.auditAccount(ctx, model.AuditMFADisabled, user.Username, user)
+
+	common.RespondJSON(w, http.StatusOK, &model.UserMFA{User_Id: user.ID})
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes, given a
+// current code or a recovery code.
+func (h *Handler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "RegenerateRecoveryCodes")
+
+	var req model.MFACodeRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.checkMFACode(w, r, user, req.Code) {
+		return
+	}
+
+	ctx := r.Context()
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		h.respondError(w, r, err, "Failed to create recovery codes")
+		return
+	}
+	if err := h.db.ReplaceRecoveryCodes(ctx, user.ID, hashes); err != nil {
+		h.respondError(w, r, err, "Failed to save recovery codes")
+		return
+	}
+	h.auditAccount(ctx, model.AuditMFARecoveryCodes, user.Username, user)
+
+	common.RespondJSON(w, http.StatusOK, model.MFARecoveryCodes{Codes: codes})
+}
+
+// LoginMFASetup starts the enrolment of a user whose subscriber requires
+// MFA, with the mfa_token Login returned for that.
+func (h *Handler) LoginMFASetup(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "LoginMFASetup")
+
+	var req model.MFATokenRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	user, claims, ok := h.challengeUser(w, r, req.MFA_Token)
+	if !ok {
+		return
+	}
+	if claims.Purpose != auth.MFAEnroll {
+		h.respondError(w, r, common.NewError(http.StatusBadRequest, common.CodeBadRequest, "The account has enrolled already"), "MFA setup refused")
+		return
+	}
+
+	setup, err := h.startEnrolment(r.Context(), user)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to start MFA enrolment")
+		return
+	}
+
+	common.RespondJSON(w, http.StatusCreated, setup)
+}
+
+// LoginMFA completes a login with the mfa_token Login returned and a code.
+// For a "verify" challenge the code is from the authenticator app or a
+// recovery code; for "enroll" it completes the enrolment LoginMFASetup
+// started, and the response carries the new recovery codes. Wrong codes
+// count as failed logins.
+func (h *Handler) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "LoginMFA")
+
+	var req model.MFALoginRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	user, claims, ok := h.challengeUser(w, r, req.MFA_Token)
+	if !ok {
+		return
+	}
+	if h.refuseLocked(w, r, user) {
+		return
+	}
+
+	ctx := r.Context()
+	var codes []string
+	if claims.Purpose == auth.MFAEnroll {
+		var err error
+		codes, err = h.completeEnrolment(ctx, user, req.Code)
+		if err != nil {
+			var apiErr *common.Error
+			if errors.As(err, &apiErr) && apiErr.Status == http.StatusUnauthorized {
+				h.auditAccount(ctx, model.AuditLoginFailed, user.Username, user)
+				h.loginFailed(ctx, user)
+			}
+			h.respondError(w, r, err, "Failed to enable MFA")
+			return
+		}
+	} else if !h.checkMFACode(w, r, user, req.Code) {
+		h.auditAccount(ctx, model.AuditLoginFailed, user.Username, user)
+		h.loginFailed(ctx, user)
+		return
+	}
+
+	h.issueToken(w, r, user, codes)
+}
+
+// mfaChallenge returns the purpose of the challenge user must pass before
+// a token is issued, or "" when none is needed.
+func (h *Handler) mfaChallenge(ctx context.Context, user *model.User) (string, error) {
+	mfa, err := h.db.GetUserMFA(ctx, user.ID)
+	if err != nil {
+		return "", err
+	}
+	if mfa != nil && mfa.Enabled {
+		return auth.MFAVerify, nil
+	}
+
+	required, err := h.db.MFARequired(ctx, user.ID)
+	if err != nil {
+		return "", err
+	}
+	if required {
+		return auth.MFAEnroll, nil
+	}
+	return "", nil
+}
+
+// challengeUser responds 401 and returns false unless token is a valid MFA
+// challenge of an existing user.
+func (h *Handler) challengeUser(w http.ResponseWriter, r *http.Request, token string) (*model.User, *auth.MFAClaims, bool) {
+	claims, err := h.auth.ValidateMFAToken(token)
+	if err == auth.ErrExpiredToken {
+		h.respondError(w, r, common.NewError(http.StatusUnauthorized, common.CodeTokenExpired, "The MFA challenge has expired, log in again"), "MFA challenge expired")
+		return nil, nil, false
+	}
+	if err != nil {
+		h.respondError(w, r, common.NewError(http.StatusUnauthorized, common.CodeInvalidToken, "Invalid MFA token"), "Invalid MFA token")
+		return nil, nil, false
+	}
+
+	user, err := h.db.GetUser(r.Context(), claims.UserID)
+	if err != nil {
+		h.respondError(w, r, err, "Error finding user")
+		return nil, nil, false
+	}
+	if user == nil {
+		h.respondError(w, r, common.NewError(http.StatusUnauthorized, common.CodeInvalidToken, "Invalid MFA token"), "MFA user not found")
+		return nil, nil, false
+	}
+
+	return user, claims, true
+}
+
+// currentUser responds and returns false unless the request's claims are of
+// an existing user.
+func (h *Handler) currentUser(w http.ResponseWriter, r *http.Request) (*model.User, bool) {
+	claims, ok := r.Context().Value("user").(*auth.Claims)
+	if !ok {
+		common.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return nil, false
+	}
+
+	user, err := h.db.GetUser(r.Context(), claims.UserID)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to get user")
+		return nil, false
+	}
+	if user == nil {
+		common.RespondError(w, http.StatusNotFound, "User not found")
+		return nil, false
+	}
+
+	return user, true
+}
+
+// startEnrolment saves a new sealed secret for user and returns what their
+// authenticator app needs. It fails with a conflict when MFA is enabled.
+func (h *Handler) startEnrolment(ctx context.Context, user *model.User) (*model.MFASetup, error) {
+	if h.mfa.Cipher == nil {
+		return nil, errMFANotConfigured
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := h.mfa.Cipher.Seal(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.db.SaveUserMFA(ctx, user.ID, sealed); err != nil {
+		if database.IsDuplicate(err) {
+			return nil, common.NewError(http.StatusConflict, common.CodeConflict, "MFA is already enabled")
+		}
+		return nil, err
+	}
+
+	return &model.MFASetup{
+		Secret: secret,
+		URI:    totp.URI(h.mfa.Issuer, user.Username, secret),
+	}, nil
+}
+
+// completeEnrolment enables the enrolment of user when code is a current
+// code of its secret, and returns the new recovery codes.
+func (h *Handler) completeEnrolment(ctx context.Context, user *model.User, code string) ([]string, error) {
+	mfa, err := h.db.GetUserMFA(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa == nil {
+		return nil, common.NewError(http.StatusBadRequest, common.CodeBadRequest, "MFA enrolment has not been started")
+	}
+	if mfa.Enabled {
+		return nil, common.NewError(http.StatusConflict, common.CodeConflict, "MFA is already enabled")
+	}
+
+	ok, err := h.validateTOTP(ctx, mfa, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, common.NewError(http.StatusUnauthorized, common.CodeUnauthorized, "Invalid code")
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := h.db.EnableUserMFA(ctx, user.ID, hashes); err != nil {
+		return nil, err
+	}
+	h.auditAccount(ctx, model.AuditMFAEnabled, user.Username, user)
+	h.notifyMFAEnabled(ctx, user)
+
+	return codes, nil
+}
+
+// checkMFACode responds and returns false unless user has enabled MFA and
+// code is a current code or an unused recovery code, which is then spent.
+func (h *Handler) checkMFACode(w http.ResponseWriter, r *http.Request, user *model.User, code string) bool {
+	ctx := r.Context()
+	mfa, err := h.db.GetUserMFA(ctx, user.ID)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to get MFA")
+		return false
+	}
+	if mfa == nil || !mfa.Enabled {
+		h.respondError(w, r, common.NewError(http.StatusBadRequest, common.CodeBadRequest, "MFA is not enabled"), "MFA not enabled")
+		return false
+	}
+
+	ok, err := h.validateTOTP(ctx, mfa, code)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to check code")
+		return false
+	}
+	if ok {
+		return true
+	}
+
+	ok, err = h.db.UseRecoveryCode(ctx, user.ID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		h.respondError(w, r, err, "Failed to check code")
+		return false
+	}
+	if !ok {
+		h.respondError(w, r, common.NewError(http.StatusUnauthorized, common.CodeUnauthorized, "Invalid code"), "Invalid MFA code")
+		return false
+	}
+
+	h.logger.InfoContext(ctx, "recovery code used", "user_id", user.ID, "left", mfa.Recovery_Codes_Left-1)
+	return true
+}
+
+// validateTOTP reports whether code is a current code of the enrolment's
+// secret that has not been used before.
+func (h *Handler) validateTOTP(ctx context.Context, mfa *model.UserMFA, code string) (bool, error) {
+	if h.mfa.Cipher == nil {
+		return false, errMFANotConfigured
+	}
+	secret, err := h.mfa.Cipher.Open(mfa.Secret)
+	if err != nil {
+		return false, err
+	}
+
+	step, ok := totp.Validate(secret, strings.ReplaceAll(code, " ", ""), time.Now())
+	if !ok {
+		return false, nil
+	}
+	// Each code is accepted once, so one seen in passing cannot be replayed
+	return h.db.UseMFAStep(ctx, mfa.User_Id, step)
+}
+
+// newRecoveryCodes returns fresh recovery codes, written as two groups of
+// five letters and digits, and their hashes.
+func newRecoveryCodes() ([]string, []string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(encoding.EncodeToString(b))[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = hashToken(code)
+	}
+	return codes, hashes, nil
+}
+
+// notifyMFAEnabled tells the user that MFA was enabled on their account.
+func (h *Handler) notifyMFAEnabled(ctx context.Context, user *model.User) {
+	h.notify(ctx, user, "", model.NotificationSecurity, mail.TemplateMFAEnabled, map[string]any{
+		"Time": time.Now().Format(time.RFC3339),
+	})
+}
+
+// normalizeRecoveryCode drops the case, spaces and dashes a user may type.
+func normalizeRecoveryCode(code string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(code))
+}
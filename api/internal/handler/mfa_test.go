@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/htstinson/stinsondataapi/api/internal/auth"
+	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/htstinson/stinsondataapi/api/internal/password"
+	"github.com/htstinson/stinsondataapi/api/pkg/database"
+)
+
+// pacedUsers serves one user by id. Any other repository call panics, which
+// shows the request went further than it should have.
+type pacedUsers struct {
+	database.Repository
+	user model.User
+}
+
+func (p *pacedUsers) GetUser(ctx context.Context, id string) (*model.User, error) {
+	if id != p.user.ID {
+		return nil, nil
+	}
+	user := p.user
+	return &user, nil
+}
+
+func TestLoginMFARefusedWhileLocked(t *testing.T) {
+	now := time.Now()
+	lockedUntil := now.Add(time.Hour)
+	lastFailure := now.Add(-time.Second)
+
+	tests := []struct {
+		name   string
+		user   model.User
+		status int
+	}{
+		{"locked", model.User{ID: "u1", Username: "a@example.com", FailedLogins: 10, LastFailedLogin: &lastFailure, LockedUntil: &lockedUntil}, http.StatusLocked},
+		{"paced", model.User{ID: "u1", Username: "a@example.com", FailedLogins: 3, LastFailedLogin: &lastFailure}, http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jwt := auth.New(auth.Config{SecretKey: "test-secret", TokenDuration: time.Hour, MFADuration: 5 * time.Minute})
+			h := NewHandler(&pacedUsers{user: tt.user}, *jwt, slog.New(slog.NewTextHandler(io.Discard, nil)), nil)
+			h.SetPasswords(PasswordOptions{
+				Lockout: password.Lockout{DelayAfter: 3, BaseDelay: time.Minute, Threshold: 10, Duration: time.Hour},
+			})
+
+			token, err := jwt.GenerateMFAToken(tt.user.ID, auth.MFAVerify)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body := `{"mfa_token":"` + token + `","code":"123456"}`
+			rec := httptest.NewRecorder()
+			h.LoginMFA(rec, httptest.NewRequest(http.MethodPost, "/login/mfa", strings.NewReader(body)))
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if rec.Header().Get("Retry-After") == "" {
+				t.Fatal("Retry-After not set")
+			}
+		})
+	}
+}
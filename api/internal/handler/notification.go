@@ -0,0 +1,352 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/mux"
+	common "github.com/htstinson/stinsondataapi/api/commonweb"
+	"github.com/htstinson/stinsondataapi/api/internal/model"
+)
+
+// NotificationOptions are the client webhooks are posted with and the
+// preferences of users who have set none.
+type NotificationOptions struct {
+	Client   *http.Client
+	Defaults model.NotificationPreferences
+}
+
+// DefaultNotifications are the preferences of users who have set none:
+// security events by email and nothing else.
+var DefaultNotifications = model.NotificationPreferences{
+	Security_Events: model.NotificationChannels{Email: true},
+	Digest:          model.NotificationDigest{Email: model.DigestOff, Webhook: model.DigestOff},
+}
+
+// SetNotifications replaces the notification options. The client, which
+// only connects to public addresses by default, is kept when options has
+// none.
+func (h *Handler) SetNotifications(options NotificationOptions) {
+	if options.Client == nil {
+		options.Client = h.notifications.Client
+	}
+	h.notifications = options
+}
+
+var errWebhookAddress = errors.New("webhooks can only be sent to public addresses")
+
+// NewWebhookClient returns a client that refuses to connect to loopback,
+// private and link-local addresses, so that webhook URLs cannot reach the
+// network the API runs in.
+func NewWebhookClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			addr, err := netip.ParseAddr(host)
+			if err != nil {
+				return err
+			}
+			if addr = addr.Unmap(); !addr.IsGlobalUnicast() || addr.IsPrivate() {
+				return errWebhookAddress
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// notify tells the user of event on the channels their preferences in the
+// subscriber choose: by email with the template called name, and by
+// webhook. Security events concern the account rather than a subscriber
+// and are sent with subscriberID empty, so the user's defaults apply. As
+// with sendMail, nothing waits for it.
+func (h *Handler) notify(ctx context.Context, user *model.User, subscriberID string, event string, name string, data map[string]any) {
+	if user.Service_Account {
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		preferences, err := h.notificationPreferences(ctx, user.ID, subscriberID)
+		if err != nil {
+			h.logger.ErrorContext(ctx, "notify", "event", event, "error", err)
+			return
+		}
+
+		channels := preferences.Channels(event)
+		if channels.Email {
+			if err := h.queueMail(ctx, user.Username, subscriberID, name, maps.Clone(data)); err != nil {
+				h.logger.ErrorContext(ctx, "notify", "event", event, "channel", "email", "error", err)
+			}
+		}
+		if channels.Webhook && preferences.Webhook_URL != "" {
+			notification := model.Notification{
+				Event:         event,
+				Template:      name,
+				User_Id:       user.ID,
+				Subscriber_Id: subscriberID,
+				Time:          time.Now().UTC(),
+				Data:          data,
+			}
+			if err := h.postWebhook(ctx, preferences, notification); err != nil {
+				h.logger.WarnContext(ctx, "notify", "event", event, "channel", "webhook", "user_id", user.ID, "error", err)
+			}
+		}
+	}()
+}
+
+// notifySubscriber notifies every user of the subscriber of event.
+func (h *Handler) notifySubscriber(ctx context.Context, subscriberID string, event string, name string, data map[string]any) {
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		users, err := h.db.SelectSubscriberUsers(ctx, subscriberID)
+		if err != nil {
+			h.logger.ErrorContext(ctx, "notifySubscriber", "event", event, "error", err)
+			return
+		}
+		for i := range users {
+			h.notify(ctx, &users[i], subscriberID, event, name, data)
+		}
+	}()
+}
+
+// postWebhook posts the notification to the preferences' webhook, signed
+// with their secret when they have one. The webhook is tried once.
+func (h *Handler) postWebhook(ctx context.Context, preferences model.NotificationPreferences, notification model.Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, preferences.Webhook_URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Notification-Event", notification.Event)
+	if preferences.Webhook_Secret != "" {
+		mac := hmac.New(sha256.New, []byte(preferences.Webhook_Secret))
+		mac.Write(body)
+		req.Header.Set("X-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := h.notifications.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
+
+// notificationPreferences returns the preferences that apply to the user in
+// the subscriber: those they set there, their defaults, or the defaults of
+// users who set none, in that order.
+func (h *Handler) notificationPreferences(ctx context.Context, userID string, subscriberID string) (model.NotificationPreferences, error) {
+	ids := []string{""}
+	if subscriberID != "" {
+		ids = []string{subscriberID, ""}
+	}
+	for _, id := range ids {
+		preferences, err := h.db.GetNotificationPreferences(ctx, userID, id)
+		if err != nil {
+			return model.NotificationPreferences{}, err
+		}
+		if preferences != nil {
+			preferences.Subscriber_Id = subscriberID
+			preferences.Inherited = id != subscriberID
+			return *preferences, nil
+		}
+	}
+
+	preferences := h.notifications.Defaults
+	preferences.User_Id = userID
+	preferences.Subscriber_Id = subscriberID
+	preferences.Inherited = true
+	return preferences, nil
+}
+
+// GetNotificationPreferences returns the current user's default
+// notification preferences.
+func (h *Handler) GetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "GetNotificationPreferences")
+	h.getNotificationPreferences(w, r, "")
+}
+
+// SaveNotificationPreferences sets the current user's default notification
+// preferences.
+func (h *Handler) SaveNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "SaveNotificationPreferences")
+	h.saveNotificationPreferences(w, r, "")
+}
+
+// SelectSubscriberNotificationPreferences lists the notification
+// preferences the current user set in their subscribers.
+func (h *Handler) SelectSubscriberNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "SelectSubscriberNotificationPreferences")
+
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	preferences, err := h.db.SelectNotificationPreferences(r.Context(), user.ID)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to list notification preferences")
+		return
+	}
+
+	common.RespondJSON(w, http.StatusOK, preferences)
+}
+
+// GetSubscriberNotificationPreferences returns the notification preferences
+// that apply to the current user in a subscriber of theirs.
+func (h *Handler) GetSubscriberNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "GetSubscriberNotificationPreferences")
+	h.getNotificationPreferences(w, r, mux.Vars(r)["subscriber_id"])
+}
+
+// SaveSubscriberNotificationPreferences sets the current user's
+// notification preferences in a subscriber of theirs, in place of their
+// defaults.
+func (h *Handler) SaveSubscriberNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "SaveSubscriberNotificationPreferences")
+	h.saveNotificationPreferences(w, r, mux.Vars(r)["subscriber_id"])
+}
+
+// DeleteSubscriberNotificationPreferences returns the current user to their
+// defaults in a subscriber.
+func (h *Handler) DeleteSubscriberNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "DeleteSubscriberNotificationPreferences")
+
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.db.DeleteNotificationPreferences(r.Context(), user.ID, mux.Vars(r)["subscriber_id"]); err != nil {
+		h.respondError(w, r, err, "Notification preferences not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) getNotificationPreferences(w http.ResponseWriter, r *http.Request, subscriberID string) {
+	user, ok := h.notificationUser(w, r, subscriberID)
+	if !ok {
+		return
+	}
+
+	preferences, err := h.notificationPreferences(r.Context(), user.ID, subscriberID)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to get notification preferences")
+		return
+	}
+
+	common.RespondJSON(w, http.StatusOK, preferences)
+}
+
+func (h *Handler) saveNotificationPreferences(w http.ResponseWriter, r *http.Request, subscriberID string) {
+	user, ok := h.notificationUser(w, r, subscriberID)
+	if !ok {
+		return
+	}
+
+	var req model.NotificationPreferencesRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	fieldErrs := map[string][]string{}
+	if u, err := url.Parse(req.Webhook_URL); req.Webhook_URL != "" && (err != nil || u.Scheme != "https") {
+		fieldErrs["webhook_url"] = append(fieldErrs["webhook_url"], "must be an https URL")
+	}
+	if req.Webhook_URL == "" && (req.Login_Alerts.Webhook || req.Mention_Alerts.Webhook || req.Security_Events.Webhook || (req.Digest.Webhook != "" && req.Digest.Webhook != model.DigestOff)) {
+		fieldErrs["webhook_url"] = append(fieldErrs["webhook_url"], "is required to notify by webhook")
+	}
+	if len(fieldErrs) > 0 {
+		h.respondError(w, r, common.ValidationError(fieldErrs), "Invalid notification preferences")
+		return
+	}
+
+	ctx := r.Context()
+	preferences := model.NotificationPreferences{
+		User_Id:         user.ID,
+		Subscriber_Id:   subscriberID,
+		Login_Alerts:    req.Login_Alerts,
+		Mention_Alerts:  req.Mention_Alerts,
+		Security_Events: req.Security_Events,
+		Digest:          req.Digest,
+		Webhook_URL:     req.Webhook_URL,
+	}
+	if preferences.Digest.Email == "" {
+		preferences.Digest.Email = model.DigestOff
+	}
+	if preferences.Digest.Webhook == "" {
+		preferences.Digest.Webhook = model.DigestOff
+	}
+	if req.Webhook_Secret != nil {
+		preferences.Webhook_Secret = *req.Webhook_Secret
+	} else {
+		current, err := h.db.GetNotificationPreferences(ctx, user.ID, subscriberID)
+		if err != nil {
+			h.respondError(w, r, err, "Failed to get notification preferences")
+			return
+		}
+		if current != nil {
+			preferences.Webhook_Secret = current.Webhook_Secret
+		}
+	}
+
+	saved, err := h.db.SaveNotificationPreferences(ctx, &preferences)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to save notification preferences")
+		return
+	}
+
+	common.RespondJSON(w, http.StatusOK, saved)
+}
+
+// notificationUser returns the current user, who must belong to the
+// subscriber when one is given.
+func (h *Handler) notificationUser(w http.ResponseWriter, r *http.Request, subscriberID string) (*model.User, bool) {
+	user, ok := h.currentUser(w, r)
+	if !ok || subscriberID == "" {
+		return user, ok
+	}
+
+	if _, err := h.db.LookupUserSubscriber(r.Context(), user.ID, subscriberID); err != nil {
+		h.respondError(w, r, err, "Subscriber not found")
+		return nil, false
+	}
+	return user, true
+}
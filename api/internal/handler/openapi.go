@@ -0,0 +1,235 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/htstinson/stinsondataapi/api/internal/health"
+	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/htstinson/stinsondataapi/api/internal/openapi"
+	"github.com/htstinson/stinsondataapi/api/pkg/database"
+)
+
+// listParams documents the query parameters listQuery reads for spec.
+func listParams(spec database.ListSpec) []openapi.Parameter {
+	order := "asc"
+	if spec.DefaultDesc {
+		order = "desc"
+	}
+	return []openapi.Parameter{
+		{Name: "limit", Type: "integer", Description: fmt.Sprintf("Page size, %d when missing and at most %d", database.DefaultLimit, database.MaxLimit)},
+		{Name: "offset", Type: "integer", Description: "Rows to skip"},
+		{Name: "page", Type: "integer", Description: "Page number starting at 1, instead of offset"},
+		{Name: "cursor", Description: "next_cursor of the previous page, instead of offset or page"},
+		{Name: "sort", Description: fmt.Sprintf("One of %s, %s when missing; a leading - sorts descending", strings.Join(spec.SortFields(), ", "), spec.DefaultSort)},
+		{Name: "order", Description: "asc or desc, " + order + " when missing"},
+		{Name: "filter[field][op]", Description: fmt.Sprintf("Rows whose field, one of %s, compares to the value by op: eq (the default when [op] is left out), ne, lt, lte, gt, gte or like", strings.Join(spec.FilterFields(), ", "))},
+	}
+}
+
+// Operations documents the handlers for the OpenAPI document, keyed by
+// handler method name. Routes whose handler is wrapped in middleware are
+// named after the method instead.
+// An operation's Permission is what an API key needs to call its route;
+// API keys are refused the routes of operations without one.
+func Operations() map[string]openapi.Operation {
+	return map[string]openapi.Operation{
+		// Public
+		"HealthCheck":        {Summary: "Report that the API is up", Response: map[string]string{}},
+		"Live":               {Summary: "Liveness probe", Response: health.Report{}},
+		"Ready":              {Summary: "Readiness probe with a report per component", Response: health.Report{}, Description: "Responds 503 with the same report when a critical component is down or the server is draining."},
+		"Register":           {Summary: "Register a user", Request: model.RegisterRequest{}, Response: model.User{}, Status: http.StatusCreated, Description: "The username is an email, which is sent a link to verify it; the user cannot log in until then. Responds 403 when registration is closed or by invitation only. Registrations left unverified are deleted."},
+		"VerifyEmail":        {Summary: "Verify a registered email with the token of its link", Request: model.EmailVerificationRequest{}, Response: model.User{}, Description: "Tokens can be used once and expire."},
+		"ResendVerification": {Summary: "Email another verification link", Request: model.EmailVerificationResendRequest{}, Response: map[string]string{}, Status: http.StatusAccepted, Description: "Responds the same whether or not the username exists or awaits verification."},
+		"Login":              {Summary: "Log in and receive a bearer token", Request: model.LoginRequest{}, Response: model.LoginResponse{}, Description: "When the user has enabled MFA, or a subscriber of theirs requires it, responds with an mfa_token and mfa set to verify or enroll instead of a token; complete the login with /login/mfa. Responds 429 with Retry-After when tried too soon after failed logins, 423 while the account is locked, and 403 with code email_unverified until a registered user has verified their email."},
+		"LoginMFA":           {Summary: "Complete a login with the MFA challenge and a code", Request: model.MFALoginRequest{}, Response: model.LoginResponse{}, Description: "For verify challenges the code is from the authenticator app or a recovery code. For enroll challenges it is a code of the secret from /login/mfa/setup, and the response carries the recovery codes. Wrong codes count as failed logins."},
+		"LoginMFASetup":      {Summary: "Start the MFA enrolment an enroll challenge asks for", Request: model.MFATokenRequest{}, Response: model.MFASetup{}, Status: http.StatusCreated},
+		"SSOLogin":           {Summary: "Start a single sign-on with the subscriber's OpenID provider", Status: http.StatusFound, Description: "Redirects the browser to the provider. An optional login_hint query parameter is passed on."},
+		"SSOCallback":        {Summary: "Complete a single sign-on when the provider redirects back", Response: model.LoginResponse{}, Status: http.StatusFound, Description: "Redirects to the configured frontend URL with the login response, or error and error_description, in the URL fragment; without one, answers the login response as JSON. The response holds an mfa_token instead of a token when MFA is needed, as for /login."},
+		"ForgotPassword":     {Summary: "Email a password reset link", Request: model.PasswordForgotRequest{}, Response: map[string]string{}, Status: http.StatusAccepted, Description: "Responds the same whether or not the username exists."},
+		"ResetPassword":      {Summary: "Set a new password with a reset token", Request: model.PasswordResetRequest{}, Response: model.User{}, Description: "Tokens can be used once and expire; a reset also unlocks the account."},
+		"GetUnsubscribe":     {Summary: "Describe the unsubscribe link a token is from", Response: model.EmailUnsubscribe{}, Query: []openapi.Parameter{{Name: "token", Description: "Token of the unsubscribe link"}}, Description: "Does not unsubscribe, so that scanners following links in email do not. Responds 400 for a token that was not issued."},
+		"Unsubscribe":        {Summary: "Stop email of a category to an address", Request: model.EmailUnsubscribeRequest{}, Response: model.EmailUnsubscribe{}, Query: []openapi.Parameter{{Name: "token", Description: "Token of the unsubscribe link, instead of the body"}}, Description: "Mail clients post one-click unsubscribes (RFC 8058) to the link itself, so the token may be in the query. Later email of the category to the address is kept in the outbox as suppressed. Account email cannot be unsubscribed from."},
+		"AcceptInvitation":   {Summary: "Accept an invitation with the token of its link", Request: model.InvitationAcceptRequest{}, Response: model.Invitation{}, Description: "Creates the user with the password given when none has the invited email, and adds the user to the subscriber with the role, in one step."},
+		"OpenAPI":            {Summary: "This OpenAPI document"},
+		"Docs":               {Summary: "Documentation UI for this document"},
+		"DocsAsset":          {Summary: "Script and stylesheet of the documentation UI"},
+
+		// Search results
+		"SelectSearchResults": {Summary: "List the results of a search definition engine", Response: database.Page[model.CalibrateSearchResultView]{}, Query: listParams(database.SearchResultList), Permission: "searches.read"},
+		"Search":              {Summary: "Run a search definition on its engines and store the results", Request: model.SearchDefinition{}, Response: map[string]int{}, Description: "Only the id of the body is used; the search definition is the token's subscriber's. Users of the subscriber with mention alerts on are told of new results.", Permission: "searches.write"},
+
+		// Search definition engines
+		"DeleteSearchDefinitionEngine":      {Summary: "Delete a search definition engine", Response: model.SearchDefinitionEnginesView{}, Permission: "searches.write"},
+		"SelectSearchDefinitionEnginesView": {Summary: "List a subscriber's search definition engines", Response: database.Page[model.SearchDefinitionEnginesView]{}, Query: listParams(database.SearchDefinitionEngineList), Permission: "searches.read"},
+		"CreateSearchDefinitionEngines":     {Summary: "Attach a search engine to a search definition", Request: model.SearchDefinitionEngines{}, Response: model.SearchDefinitionEngines{}, Status: http.StatusCreated, Permission: "searches.write"},
+
+		// Search definitions
+		"DeleteSearchDefinition":  {Summary: "Delete a search definition", Response: model.SearchDefinition{}, Permission: "searches.write"},
+		"SelectSearchDefinitions": {Summary: "List a subscriber's search definitions", Response: database.Page[model.SearchDefinition]{}, Query: listParams(database.SearchDefinitionList), Permission: "searches.read"},
+		"CreateSearchDefinition":  {Summary: "Create a search definition", Request: model.SearchDefinition{}, Response: model.SearchDefinition{}, Status: http.StatusCreated, Permission: "searches.write"},
+		"UpdateSearchDefinition":  {Summary: "Update a search definition", Request: model.SearchDefinition{}, Response: model.SearchDefinition{}, Status: http.StatusCreated, Permission: "searches.write"},
+
+		// Search engines
+		"DeleteSearchEngine":  {Summary: "Delete a search engine", Response: model.SearchEngine{}, Permission: "searches.write"},
+		"SelectSearchEngines": {Summary: "List a subscriber's search engines", Response: database.Page[model.SearchEngine]{}, Query: listParams(database.SearchEngineList), Permission: "searches.read"},
+		"CreateSearchEngine":  {Summary: "Create a search engine", Request: model.SearchEngine{}, Response: model.SearchEngine{}, Status: http.StatusCreated, Permission: "searches.write"},
+
+		// Blocked
+		"AddBlockedFromRDSToWAF": {Summary: "Copy the blocked addresses to the WAF IP set in the background", Response: "", Permission: "blocked.write"},
+		"AddBlockedFromLogs":     {Summary: "Block the addresses with TLS handshake errors in the web server log", Response: map[string]int{}, Permission: "blocked.write"},
+		"UpdateBlocked":          {Summary: "Update a blocked address", Request: model.Blocked{}, Response: model.Blocked{}, Permission: "blocked.write"},
+		"GetBlocked":             {Summary: "Get a blocked address", Response: model.Blocked{}, Permission: "blocked.read"},
+		"DeleteBlocked":          {Summary: "Unblock an address", Response: model.Blocked{}, Permission: "blocked.write"},
+		"CreateBlocked":          {Summary: "Block an address or range", Request: model.Blocked{}, Response: model.Blocked{}, Status: http.StatusCreated, Permission: "blocked.write"},
+		"SelectBlocked":          {Summary: "List blocked addresses", Response: database.Page[model.Blocked]{}, Query: listParams(database.BlockedList), Permission: "blocked.read"},
+
+		// Allowed
+		"UpdateAllowed": {Summary: "Update an allowed address", Request: model.Allowed{}, Response: model.Allowed{}, Permission: "allowed.write"},
+		"GetAllowed":    {Summary: "Get an allowed address", Response: model.Allowed{}, Permission: "allowed.read"},
+		"DeleteAllowed": {Summary: "Remove an allowed address", Response: model.Allowed{}, Permission: "allowed.write"},
+		"CreateAllowed": {Summary: "Allow an address or range", Request: model.Allowed{}, Response: model.Allowed{}, Status: http.StatusCreated, Description: "Allowed ranges cannot be blocked. Only users with the admin role may manage the allow list.", Permission: "allowed.write"},
+		"SelectAllowed": {Summary: "List allowed addresses", Response: database.Page[model.Allowed]{}, Query: listParams(database.AllowedList), Permission: "allowed.read"},
+
+		// Items
+		"CreateItem": {Summary: "Create an item", Request: model.Item{}, Response: model.Item{}, Status: http.StatusCreated, Permission: "items.write"},
+		"UpdateItem": {Summary: "Update an item", Request: model.Item{}, Response: model.Item{}, Permission: "items.write"},
+		"GetItem":    {Summary: "Get an item", Response: model.Item{}, Permission: "items.read"},
+		"ListItems":  {Summary: "List items", Response: database.Page[model.Item]{}, Query: listParams(database.ItemList), Permission: "items.read"},
+		"DeleteItem": {Summary: "Delete an item", Response: model.Item{}, Permission: "items.write"},
+
+		// Users
+		"CreateUser": {Summary: "Create a user", Request: model.CreateUserRequest{}, Response: model.User{}, Status: http.StatusCreated, Description: "Without a password the user is emailed a link to choose one.", Permission: "users.write"},
+		"UpdateUser": {Summary: "Update a user", Request: model.User{}, Response: model.User{}, Permission: "users.write"},
+		"UpdatePassword": {Summary: "Set a user's password", Request: struct {
+			Password string `json:"password" validate:"required,max=72"`
+		}{}, Response: model.User{}, Description: "The password must meet the password policy; the user is notified by email."},
+		"DeleteUser":      {Summary: "Delete a user", Response: model.User{}, Permission: "users.write"},
+		"GetUser":         {Summary: "Get a user, or the current user without an id", Response: model.User{}, Permission: "users.read"},
+		"SelectUsers":     {Summary: "List users", Response: database.Page[model.User]{}, Query: listParams(database.UserList), Permission: "users.read"},
+		"SelectUserRoles": {Summary: "List users with their roles", Response: database.Page[model.User]{}, Query: listParams(database.UserRoleList), Permission: "users.read"},
+
+		// User subscribers
+		"SelectUserSubscriberViewByUserId": {Summary: "List a user's subscribers", Response: database.Page[model.User_Subscriber_View]{}, Query: listParams(database.UserSubscriberViewList), Permission: "users.read"},
+		"SelectUserSubscriberView":         {Summary: "List the current user's subscribers", Response: database.Page[model.User_Subscriber_View]{}, Query: listParams(database.UserSubscriberViewList), Permission: "users.read"},
+		"UpdateUserSubscriber":             {Summary: "Update a user subscriber", Request: model.User_Subscriber{}, Response: model.User_Subscriber{}, Permission: "users.write"},
+		"CreateUserSubscriber":             {Summary: "Add a user to a subscriber", Request: model.User_Subscriber{}, Response: model.User_Subscriber{}, Status: http.StatusCreated, Permission: "users.write"},
+		"DeleteUserSubscriber":             {Summary: "Remove a user from a subscriber", Response: model.User_Subscriber{}, Permission: "users.write"},
+
+		// User subscriber roles
+		"SelectUserSubscriberRoleView": {Summary: "List the roles of user subscribers", Request: model.User_Subscriber_View{}, Response: database.Page[model.User_Subscriber_Role_View]{}, Query: listParams(database.UserSubscriberRoleViewList), Permission: "users.read"},
+		"CreateUserSubscriberRole":     {Summary: "Give a user subscriber a role", Request: model.User_Subscriber_Role{}, Response: model.User_Subscriber_Role{}, Status: http.StatusCreated, Permission: "users.write"},
+		"UpdateUserSubscriberRole":     {Summary: "Update a user subscriber role", Request: model.User_Subscriber_Role{}, Response: model.User_Subscriber_Role{}, Permission: "users.write"},
+		"DeleteUserSubscriberRole":     {Summary: "Remove a user subscriber role", Response: model.User_Subscriber_Role{}, Permission: "users.write"},
+
+		// Subscriber customers
+		"SelectSubscriberCustomers": {Summary: "List the customers of the token's subscriber", Response: database.Page[model.Customer]{}, Query: listParams(database.CustomerList), Permission: "customers.read"},
+		"CreateCustomer":            {Summary: "Create a customer", Request: model.Customer{}, Response: model.Customer{}, Status: http.StatusCreated, Permission: "customers.write"},
+		"DeleteCustomer":            {Summary: "Delete a customer", Response: model.Customer{}, Permission: "customers.write"},
+		"UpdateCustomer":            {Summary: "Update a customer", Request: model.Customer{}, Response: model.Customer{}, Permission: "customers.write"},
+
+		// Subscriber profile
+		"GetSubscriberProfile":    {Summary: "Get the profile of the token's subscriber", Response: model.Profile{}, Permission: "subscribers.read"},
+		"UpdateSubscriberProfile": {Summary: "Update a subscriber's profile", Request: model.Profile{}, Response: model.Subscriber{}, Permission: "subscribers.write"},
+
+		// Subscriber addresses
+		"SelectSubscriberAddresses": {Summary: "List the addresses of the token's subscriber", Response: database.Page[model.Address]{}, Query: listParams(database.AddressList), Permission: "subscribers.read"},
+		"UpdateSubscriberAddress":   {Summary: "Update a subscriber address", Request: model.Address{}, Response: model.Subscriber{}, Permission: "subscribers.write"},
+		"CreateSubscriberAddress":   {Summary: "Create a subscriber address", Request: model.Address{}, Response: model.Subscriber{}, Permission: "subscribers.write"},
+		"GetSubscriberAddress":      {Summary: "Get a subscriber address", Request: model.Address{}, Response: model.Address{}, Description: "Only the id of the body is used.", Permission: "subscribers.read"},
+		"DeleteSubscriberAddress":   {Summary: "Delete a subscriber address", Permission: "subscribers.write"},
+
+		// Subscriber backgrounds
+		"SelectSubscriberBackgrounds": {Summary: "List the backgrounds of the token's subscriber", Response: database.Page[model.Background]{}, Query: listParams(database.BackgroundList), Permission: "subscribers.read"},
+		"UpdateSubscriberBackground":  {Summary: "Update a subscriber background", Request: model.Background{}, Response: model.Subscriber{}, Permission: "subscribers.write"},
+		"CreateSubscriberBackground":  {Summary: "Create a subscriber background", Request: model.Background{}, Response: model.Subscriber{}, Permission: "subscribers.write"},
+		"GetSubscriberBackground":     {Summary: "Get a subscriber background", Request: model.Background{}, Response: model.Background{}, Description: "Only the id of the body is used.", Permission: "subscribers.read"},
+		"DeleteSubscriberBackground":  {Summary: "Delete a subscriber background", Permission: "subscribers.write"},
+
+		// Contacts
+		"SelectContacts": {Summary: "List a customer's contacts", Request: model.Customer{}, Response: database.Page[model.Contact]{}, Description: "Only the id of the body is used.", Query: listParams(database.ContactList), Permission: "customers.read"},
+		"CreateContact":  {Summary: "Create a contact", Request: model.Contact{}, Response: model.Contact{}, Status: http.StatusCreated, Permission: "customers.write"},
+		"DeleteContact":  {Summary: "Delete a contact", Response: model.Contact{}, Permission: "customers.write"},
+		"UpdateContact":  {Summary: "Update a contact", Request: model.Contact{}, Response: model.Contact{}, Permission: "customers.write"},
+
+		// Subscriber items
+		"SelectSubscriberItemView": {Summary: "List a subscriber's items", Response: database.Page[model.Subscriber_Item_View]{}, Query: listParams(database.SubscriberItemViewList), Permission: "items.read"},
+		"DeleteSubscriberItem":     {Summary: "Remove an item from a subscriber", Response: model.Subscriber_Item{}, Permission: "items.write"},
+		"CreateSubscriberItem":     {Summary: "Add an item to a subscriber", Request: model.Subscriber_Item{}, Response: model.Subscriber_Item{}, Status: http.StatusCreated, Permission: "items.write"},
+
+		// Subscribers
+		"CreateSubscriber":  {Summary: "Create a subscriber with its schema, profile and default customer", Request: model.Subscriber{}, Response: model.Subscriber{}, Status: http.StatusCreated, Permission: "subscribers.write"},
+		"UpdateSubscriber":  {Summary: "Update a subscriber", Request: model.Subscriber{}, Response: model.Subscriber{}, Permission: "subscribers.write"},
+		"DeleteSubscriber":  {Summary: "Delete a subscriber", Request: model.Subscriber{}, Response: model.Subscriber{}, Description: "Only the id of the body is used.", Permission: "subscribers.write"},
+		"GetSubscriber":     {Summary: "Get a subscriber", Response: model.Subscriber{}, Permission: "subscribers.read"},
+		"GetSubscriberP":    {Summary: "Get a subscriber by the id in the body", Request: model.Subscriber{}, Response: model.Subscriber{}, Description: "Only the id of the body is used.", Permission: "subscribers.read"},
+		"SelectSubscribers": {Summary: "List subscribers", Response: database.Page[model.Subscriber]{}, Query: listParams(database.SubscriberList), Permission: "subscribers.read"},
+
+		// Roles
+		"CreateRole":  {Summary: "Create a role", Request: model.Role{}, Response: model.Role{}, Status: http.StatusCreated, Permission: "roles.write"},
+		"UpdateRole":  {Summary: "Update a role", Request: model.Role{}, Response: model.Role{}, Permission: "roles.write"},
+		"DeleteRole":  {Summary: "Delete a role", Response: model.Role{}, Permission: "roles.write"},
+		"GetRole":     {Summary: "Get a role", Response: model.Role{}, Permission: "roles.read"},
+		"SelectRoles": {Summary: "List roles", Response: database.Page[model.Role]{}, Query: listParams(database.RoleList), Permission: "roles.read"},
+
+		// Permissions
+		"CreatePermission":  {Summary: "Create a permission", Request: model.Permission{}, Response: model.Permission{}, Status: http.StatusCreated, Permission: "permissions.write"},
+		"UpdatePermission":  {Summary: "Update a permission", Request: model.Permission{}, Response: model.Permission{}, Permission: "permissions.write"},
+		"DeletePermission":  {Summary: "Delete a permission", Response: model.Permission{}, Permission: "permissions.write"},
+		"SelectPermissions": {Summary: "List permissions", Response: database.Page[model.Permission_View]{}, Query: listParams(database.PermissionViewList), Permission: "permissions.read"},
+
+		// Role permissions
+		"SelectRolePermissionsView": {Summary: "List the permissions of every role", Response: database.Page[model.Role_Permission_View]{}, Query: listParams(database.RolePermissionViewList), Permission: "roles.read"},
+
+		// Audit
+		// Invitations
+		"CreateInvitation":  {Summary: "Invite an email to a subscriber with a role", Request: model.Invitation{}, Response: model.Invitation{}, Status: http.StatusCreated, Description: "Only email, subscriber_id and role_id are read. The link is emailed, and a pending invitation for the same email and subscriber is revoked. Only users with the admin role may manage invitations.", Permission: "invitations.write"},
+		"SelectInvitations": {Summary: "List invitations", Response: database.Page[model.Invitation]{}, Query: listParams(database.InvitationList), Description: "filter[status]=pending lists those that can still be accepted.", Permission: "invitations.read"},
+		"RevokeInvitation":  {Summary: "Revoke a pending invitation", Response: model.Invitation{}, Permission: "invitations.write"},
+
+		// Single sign-on
+		"GetSubscriberSSO":    {Summary: "Get the subscriber's OpenID provider", Response: model.SubscriberOIDC{}, Description: "Only users with the admin role may manage single sign-on.", Permission: "sso.read"},
+		"SaveSubscriberSSO":   {Summary: "Set the subscriber's OpenID provider", Request: model.SubscriberOIDC{}, Response: model.SubscriberOIDC{}, Description: "The issuer is discovered before saving. client_secret_name names the client secret in the secrets provider. default_role_id is required with jit_provisioning.", Permission: "sso.write"},
+		"DeleteSubscriberSSO": {Summary: "Turn off single sign-on for the subscriber", Status: http.StatusNoContent, Permission: "sso.write"},
+
+		// Email
+		"SelectEmails":         {Summary: "List the email outbox", Response: database.Page[model.Email]{}, Query: listParams(database.EmailList), Description: "Email is pending until sent, retried with backoff when sending fails, failed once attempts run out, and suppressed when the address unsubscribed from its category. Only users with the admin role may read the outbox.", Permission: "email.read"},
+		"SelectEmailTemplates": {Summary: "List the email templates of the subscriber", Response: []model.EmailTemplate{}, Description: "The subscriber's own templates where it has them and the built in ones otherwise; those with a subscriber_id are its own.", Permission: "email.read"},
+		"SaveEmailTemplate":    {Summary: "Set the subscriber's own version of an email template", Request: model.EmailTemplate{}, Response: model.EmailTemplate{}, Description: "Only subject, text_body and html_body are read. They are Go templates over the data the built in template uses, and html_body is escaped as HTML. Email of the subscriber falls back to the built in template when its own fails to render.", Permission: "email.write"},
+		"DeleteEmailTemplate":  {Summary: "Return the subscriber to the built in email template", Status: http.StatusNoContent, Permission: "email.write"},
+		"SelectMailboxSources": {Summary: "List the Gmail labels the subscriber reads mentions from", Response: []model.MailboxSource{}, Description: "messages is how many messages have been read from the label. last_error is what stopped the last poll, if anything.", Permission: "mailboxes.read"},
+		"CreateMailboxSource":  {Summary: "Read a Gmail label into one of the subscriber's search definition engines", Request: model.MailboxSource{}, Response: model.MailboxSource{}, Status: http.StatusCreated, Description: "The label is read from the configured Gmail account, which needs the gmail.readonly scope. Each link in a message, such as a Google Alerts result, is stored as a search result with its link text as the title and the text after it as the snippet. Every message is read once, and messages received more than the configured lookback before the source was created are not read.", Permission: "mailboxes.write"},
+		"UpdateMailboxSource":  {Summary: "Change or pause a mailbox source", Request: model.MailboxSource{}, Response: model.MailboxSource{}, Description: "Messages already read are not read again under a new label or search definition engine.", Permission: "mailboxes.write"},
+		"DeleteMailboxSource":  {Summary: "Stop reading a Gmail label", Status: http.StatusNoContent, Description: "Search results already read from it are kept.", Permission: "mailboxes.write"},
+
+		// Session
+		"GetMe":                   {Summary: "Get the current user, the active subscriber and the subscribers they can select", Response: model.Me{}, Permission: "profile.read"},
+		"SelectSessionSubscriber": {Summary: "Scope the session to a subscriber", Request: model.SessionSubscriberRequest{}, Response: model.LoginResponse{}, Description: "Answers a token scoped to the subscriber and the user's roles in it, expiring with the current one. Admins may select any subscriber. Routes of a subscriber's data act on the token's subscriber and answer 403 with code subscriber_required without one; a subscriber_id in their path or body must be the token's."},
+
+		// Notification preferences
+		"GetNotificationPreferences":              {Summary: "Get the current user's default notification preferences", Response: model.NotificationPreferences{}, Description: "inherited is true until the user sets them. Security events concern the account, so only these defaults apply to them.", Permission: "profile.read"},
+		"SaveNotificationPreferences":             {Summary: "Set the current user's default notification preferences", Request: model.NotificationPreferencesRequest{}, Response: model.NotificationPreferences{}, Description: "Login alerts, mention alerts and security events are each sent by email, webhook, both or neither. Webhooks are posted as JSON to webhook_url, which must be https, and are signed in X-Signature-256 with webhook_secret when set. webhook_secret is never returned; leaving it out keeps the current one. Digest frequency is off, daily or weekly per channel; a digest lists the search results of the subscriber found since the last one and is not sent when there are none.", Permission: "profile.write"},
+		"SelectSubscriberNotificationPreferences": {Summary: "List the notification preferences the current user set in their subscribers", Response: []model.NotificationPreferences{}, Permission: "profile.read"},
+		"GetSubscriberNotificationPreferences":    {Summary: "Get the notification preferences that apply to the current user in a subscriber", Response: model.NotificationPreferences{}, Description: "Those set in the subscriber, or the user's defaults with inherited set to true.", Permission: "profile.read"},
+		"SaveSubscriberNotificationPreferences":   {Summary: "Set the current user's notification preferences in a subscriber", Request: model.NotificationPreferencesRequest{}, Response: model.NotificationPreferences{}, Description: "They replace the user's defaults for login and mention alerts in the subscriber. The user must belong to the subscriber.", Permission: "profile.write"},
+		"DeleteSubscriberNotificationPreferences": {Summary: "Return to the default notification preferences in a subscriber", Status: http.StatusNoContent, Permission: "profile.write"},
+
+		// MFA
+		"GetMFA":                  {Summary: "Report the user's MFA enrolment", Response: model.UserMFA{}},
+		"StartMFA":                {Summary: "Start a TOTP enrolment", Response: model.MFASetup{}, Status: http.StatusCreated, Description: "uri is the otpauth provisioning URI to show as a QR code. Responds 409 when MFA is enabled."},
+		"VerifyMFA":               {Summary: "Enable MFA with a code of the new secret", Request: model.MFACodeRequest{}, Response: model.MFARecoveryCodes{}, Description: "The recovery codes are only shown here."},
+		"DisableMFA":              {Summary: "Disable MFA with a code or recovery code", Request: model.MFACodeRequest{}, Response: model.UserMFA{}, Description: "Responds 403 when a subscriber of the user requires MFA."},
+		"RegenerateRecoveryCodes": {Summary: "Replace the recovery codes, given a code or recovery code", Request: model.MFACodeRequest{}, Response: model.MFARecoveryCodes{}},
+
+		// API keys and service accounts
+		"CreateAPIKey":             {Summary: "Create an API key for the current user", Request: model.APIKeyCreateRequest{}, Response: model.APIKeyCreated{}, Status: http.StatusCreated, Description: "The key acts in subscriber_id, by default the token's subscriber, with the permissions listed, each of which the user must have there. expires_at defaults to the configured lifetime and cannot exceed its maximum. The key is only shown in this response; send it as \"Authorization: ApiKey <key>\". A key can only call the operations whose permission, the apiKeyAuth scope, is one of its own; none lets it create keys, select a subscriber, change MFA or change passwords."},
+		"SelectAPIKeys":            {Summary: "List the current user's API keys", Response: database.Page[model.APIKey]{}, Query: listParams(database.APIKeyList), Permission: "apikeys.read"},
+		"RevokeAPIKey":             {Summary: "Revoke an API key", Response: model.APIKey{}, Description: "Users may revoke their own keys; admins may revoke any.", Permission: "apikeys.write"},
+		"CreateServiceAccount":     {Summary: "Create a service account in a subscriber with a role", Request: model.ServiceAccountCreateRequest{}, Response: model.User{}, Status: http.StatusCreated, Description: "Service accounts cannot log in and only act through their API keys. Only users with the admin role may manage service accounts; list them with filter[service_account]=true on /users."},
+		"CreateServiceAccountKey":  {Summary: "Create an API key for a service account", Request: model.APIKeyCreateRequest{}, Response: model.APIKeyCreated{}, Status: http.StatusCreated, Description: "subscriber_id defaults to the service account's subscriber. The key is only shown in this response."},
+		"SelectServiceAccountKeys": {Summary: "List a service account's API keys", Response: database.Page[model.APIKey]{}, Query: listParams(database.APIKeyList), Permission: "apikeys.read"},
+
+		"SelectAuditEvents": {Summary: "List the audit log of changes and logins", Response: database.Page[model.AuditEvent]{}, Query: listParams(database.AuditList), Description: "Only users with the admin role may read the audit log.", Permission: "audit.read"},
+	}
+}
@@ -0,0 +1,216 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	common "github.com/htstinson/stinsondataapi/api/commonweb"
+	"github.com/htstinson/stinsondataapi/api/internal/mail"
+	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/htstinson/stinsondataapi/api/internal/password"
+	"github.com/htstinson/stinsondataapi/api/pkg/database"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordOptions are the password policy, the pacing of failed logins and
+// the reset links, as set in config.Password and config.Lockout.
+type PasswordOptions struct {
+	Policy   *password.Policy
+	Lockout  password.Lockout
+	ResetTTL time.Duration
+	ResetURL string
+}
+
+// SetPasswords replaces the password options, which default to an eight
+// character minimum, no lockout and hour long reset links.
+func (h *Handler) SetPasswords(options PasswordOptions) {
+	h.passwords = options
+}
+
+// checkPassword responds 422 and returns false when pw does not meet the
+// policy for username.
+func (h *Handler) checkPassword(w http.ResponseWriter, r *http.Request, pw string, username string) bool {
+	if problems := h.passwords.Policy.Check(pw, username); len(problems) > 0 {
+		h.respondError(w, r, common.ValidationError(map[string][]string{"password": problems}), "Password does not meet the policy")
+		return false
+	}
+	return true
+}
+
+// setPassword stores pw as the user's password.
+func (h *Handler) setPassword(ctx context.Context, user *model.User, pw string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(pw), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("error hashing password: %w", err)
+	}
+	user.PasswordHash = string(hash)
+	return h.db.UpdateUser(ctx, user)
+}
+
+// ForgotPassword emails a reset link to the user. It answers 202 whether or
+// not the username exists, so that it cannot be used to find accounts.
+func (h *Handler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "ForgotPassword")
+
+	var req model.PasswordForgotRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	ctx := r.Context()
+	user, err := h.db.GetUserByUsername(ctx, model.NormalizeUsername(req.Username))
+	if err != nil {
+		h.respondError(w, r, err, "Error finding user")
+		return
+	}
+
+	if user != nil && !user.Service_Account {
+		if err := h.sendPasswordReset(ctx, user, "Someone asked to reset the password of your account."); err != nil {
+			h.respondError(w, r, err, "Error creating password reset")
+			return
+		}
+	}
+
+	common.RespondJSON(w, http.StatusAccepted, map[string]string{
+		"status": "If the account exists, a reset link has been sent to it.",
+	})
+}
+
+// ResetPassword sets a new password with the token of a reset link. Tokens
+// can be used once and expire after ResetTTL. A reset also unlocks the
+// account.
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "ResetPassword")
+
+	var req model.PasswordResetRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	ctx := r.Context()
+	invalid := common.NewError(http.StatusBadRequest, common.CodeBadRequest, "The reset link is invalid, used or expired")
+
+	// The token is only spent once the password is acceptable, so that a
+	// rejected password leaves the link working
+	user_id, err := h.db.GetPasswordReset(ctx, hashToken(req.Token))
+	if err != nil {
+		h.respondError(w, r, err, "Error getting password reset")
+		return
+	}
+	if user_id == "" {
+		h.respondError(w, r, invalid, "Invalid password reset")
+		return
+	}
+
+	user, err := h.db.GetUser(ctx, user_id)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to get user")
+		return
+	}
+	if user == nil {
+		common.RespondError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if !h.checkPassword(w, r, req.Password, user.Username) {
+		return
+	}
+
+	// Spending fails when another request used the token in the meantime
+	used, err := h.db.UsePasswordReset(ctx, hashToken(req.Token))
+	if database.IsNotFound(err) || (err == nil && used != user.ID) {
+		h.respondError(w, r, invalid, "Invalid password reset")
+		return
+	}
+	if err != nil {
+		h.respondError(w, r, err, "Error using password reset")
+		return
+	}
+
+	if err := h.setPassword(ctx, user, req.Password); err != nil {
+		h.respondError(w, r, err, "Error updating password")
+		return
+	}
+	if err := h.db.ResetFailedLogins(ctx, user.ID); err != nil {
+		h.logger.ErrorContext(ctx, "ResetPassword", "error", err)
+	}
+
+	h.auditAccount(ctx, model.AuditPasswordReset, user.Username, user)
+	h.notifyPasswordChanged(ctx, user)
+
+	common.RespondJSON(w, http.StatusOK, user)
+}
+
+// sendPasswordReset creates a reset token for user and emails its link,
+// introduced by reason.
+func (h *Handler) sendPasswordReset(ctx context.Context, user *model.User, reason string) error {
+	token, err := randomToken()
+	if err != nil {
+		return fmt.Errorf("error creating reset token: %w", err)
+	}
+
+	expires := time.Now().Add(h.passwords.ResetTTL)
+	if err := h.db.CreatePasswordReset(ctx, user.ID, hashToken(token), expires); err != nil {
+		return err
+	}
+
+	h.sendMail(ctx, user.Username, "", mail.TemplatePasswordReset, map[string]any{
+		"Reason":  reason,
+		"Link":    tokenLink(h.passwords.ResetURL, token),
+		"Expires": expires.Format(time.RFC1123),
+	})
+	return nil
+}
+
+// notifyPasswordChanged tells the user that their password was changed.
+func (h *Handler) notifyPasswordChanged(ctx context.Context, user *model.User) {
+	h.notify(ctx, user, "", model.NotificationSecurity, mail.TemplatePasswordChanged, map[string]any{
+		"Time": time.Now().Format(time.RFC3339),
+	})
+}
+
+// notifyLocked tells the user that their account was locked.
+func (h *Handler) notifyLocked(ctx context.Context, user *model.User, until time.Time) {
+	h.notify(ctx, user, "", model.NotificationSecurity, mail.TemplateAccountLocked, map[string]any{
+		"Until": until.Format(time.RFC1123),
+	})
+}
+
+// tokenLink is base with token added as its token query parameter, or the
+// bare token when there is no base.
+func tokenLink(base string, token string) string {
+	if base == "" {
+		return token
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return token
+	}
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// randomToken returns 32 random bytes, encoded to go in a URL.
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken is how reset tokens and other one-time secrets are stored, so
+// that the tables alone cannot be used to redeem them.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,31 +1,27 @@
 package handler
 
 import (
-	"encoding/json"
-	"fmt"
 	"net/http"
 
 	"github.com/gorilla/mux"
 	common "github.com/htstinson/stinsondataapi/api/commonweb"
 	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/htstinson/stinsondataapi/api/pkg/database"
 )
 
 func (h *Handler) CreatePermission(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h CreatePermission")
+	h.logger.DebugContext(r.Context(), "CreatePermission")
 	var permission *model.Permission
-	if err := json.NewDecoder(r.Body).Decode(&permission); err != nil {
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &permission) {
 		return
 	}
-	defer r.Body.Close()
 
-	fmt.Println("h Create Permission ", permission.Id, permission.Name, permission.Description, permission.Object_Id)
+	h.logger.DebugContext(r.Context(), "CreatePermission", "name", permission.Name, "object_id", permission.Object_Id)
 
 	ctx := r.Context()
 	newpermission, err := h.db.CreatePermission(ctx, permission.Name, permission.Description, permission.Object_Id)
 	if err != nil {
-		fmt.Println(err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Failed to create permission")
+		h.respondError(w, r, err, "Failed to create permission")
 		return
 	}
 
@@ -33,29 +29,24 @@ func (h *Handler) CreatePermission(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) UpdatePermission(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h UpdatePermission")
+	h.logger.DebugContext(r.Context(), "UpdatePermission")
 	vars := mux.Vars(r)
 	id := vars["id"]
 
 	ctx := r.Context()
 
 	var permission model.Permission
-	if err := json.NewDecoder(r.Body).Decode(&permission); err != nil {
-		fmt.Println(1, err.Error())
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &permission) {
 		return
 	}
-	defer r.Body.Close()
 
 	currentpermission, err := h.db.GetPermission(ctx, id)
 	if err != nil {
-		fmt.Println(2, err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get permission")
+		h.respondError(w, r, err, "Failed to get permission")
 		return
 	}
 
 	if currentpermission == nil {
-		fmt.Println(3)
 		common.RespondError(w, http.StatusNotFound, "Permission not found")
 		return
 	}
@@ -65,13 +56,10 @@ func (h *Handler) UpdatePermission(w http.ResponseWriter, r *http.Request) {
 
 	err = h.db.UpdatePermission(ctx, currentpermission)
 	if err != nil {
-		fmt.Println(4, err.Error())
-		common.RespondError(w, http.StatusNotFound, "Error updating permission")
+		h.respondError(w, r, err, "Error updating permission")
 		return
 	}
 
-	fmt.Println(5)
-
 	common.RespondJSON(w, http.StatusOK, permission)
 }
 
@@ -83,7 +71,7 @@ func (h *Handler) DeletePermission(w http.ResponseWriter, r *http.Request) {
 
 	permission, err := h.db.GetPermission(ctx, id)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get permission")
+		h.respondError(w, r, err, "Failed to get permission")
 		return
 	}
 	if permission == nil {
@@ -93,7 +81,7 @@ func (h *Handler) DeletePermission(w http.ResponseWriter, r *http.Request) {
 
 	err = h.db.DeletePermission(ctx, id)
 	if err != nil {
-		common.RespondError(w, http.StatusNotFound, "Error deleting permission")
+		h.respondError(w, r, err, "Error deleting permission")
 		return
 	}
 
@@ -102,17 +90,17 @@ func (h *Handler) DeletePermission(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) SelectPermissions(w http.ResponseWriter, r *http.Request) {
+	q, ok := h.listQuery(w, r, database.PermissionViewList)
+	if !ok {
+		return
+	}
 
 	ctx := r.Context()
-	permissions_view, err := h.db.SelectPermissions_View(ctx, 100, 0)
+	permissions_view, err := h.db.SelectPermissions_View(ctx, q)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to select permissions")
+		h.respondError(w, r, err, "Failed to select permissions")
 		return
 	}
-	for k, v := range permissions_view {
-		fmt.Println(k, v.Id, v.Name, v.Description, v.Object_Id, v.V_Object_Name, v.V_Object_Description, v.V_Object_Type)
-	}
-
 	common.RespondJSON(w, http.StatusOK, permissions_view)
 
 }
@@ -1,8 +1,6 @@
 package handler
 
 import (
-	"encoding/json"
-	"fmt"
 	"net/http"
 
 	common "github.com/htstinson/stinsondataapi/api/commonweb"
@@ -10,28 +8,18 @@ import (
 )
 
 func (h *Handler) GetSubscriberProfile(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h GetSubscriberProfile")
+	h.logger.DebugContext(r.Context(), "GetSubscriberProfile")
 
-	var subcriber *model.Subscriber
-	if err := json.NewDecoder(r.Body).Decode(&subcriber); err != nil {
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	subcriber, ok := h.tenant(w, r, "")
+	if !ok {
 		return
 	}
-	defer r.Body.Close()
 
 	ctx := r.Context()
 
-	subcriber, err := h.db.GetSubscriber(ctx, subcriber.Id)
-	if err != nil {
-		fmt.Println(err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get subscriber")
-		return
-	}
-
 	profile, err := h.db.GetProfile(ctx, subcriber)
 	if err != nil {
-		fmt.Println(err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get profile")
+		h.respondError(w, r, err, "Failed to get profile")
 		return
 	}
 
@@ -39,34 +27,30 @@ func (h *Handler) GetSubscriberProfile(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) UpdateSubscriberProfile(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h UpdateSubscriberProfile")
+	h.logger.DebugContext(r.Context(), "UpdateSubscriberProfile")
 
 	ctx := r.Context()
 
 	var profile model.Profile
-	if err := json.NewDecoder(r.Body).Decode(&profile); err != nil {
-		fmt.Println(1, err.Error())
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &profile) {
 		return
 	}
-	defer r.Body.Close()
 
-	var subscriber, err = h.db.GetSubscriber(ctx, profile.Subscriber_Id)
-	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get subscriber")
+	subscriber, ok := h.tenant(w, r, profile.Subscriber_Id)
+	if !ok {
 		return
 	}
+	profile.Subscriber_Id = subscriber.Id
 
-	_, err = h.db.GetProfile(ctx, subscriber)
+	_, err := h.db.GetProfile(ctx, subscriber)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get profile")
+		h.respondError(w, r, err, "Failed to get profile")
 		return
 	}
 
 	err = h.db.UpdateProfile(ctx, subscriber, &profile)
 	if err != nil {
-		fmt.Println(err.Error())
-		common.RespondError(w, http.StatusNotFound, "Error updating subscriber")
+		h.respondError(w, r, err, "Error updating subscriber")
 		return
 	}
 
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	common "github.com/htstinson/stinsondataapi/api/commonweb"
+	"github.com/htstinson/stinsondataapi/api/internal/mail"
+	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/htstinson/stinsondataapi/api/pkg/database"
+)
+
+// Registration modes
+const (
+	RegistrationDisabled = "disabled"
+	RegistrationInvite   = "invite"
+	RegistrationOpen     = "open"
+)
+
+// RegistrationOptions are who may register, the verification links and when
+// unverified registrations are deleted, as set in config.Registration.
+type RegistrationOptions struct {
+	Mode         string
+	VerifyTTL    time.Duration
+	VerifyURL    string
+	CleanupAfter time.Duration
+}
+
+// SetRegistration replaces the registration options, which default to open
+// registration with day long verification links.
+func (h *Handler) SetRegistration(options RegistrationOptions) {
+	h.registration = options
+}
+
+var errEmailVerificationInvalid = common.NewError(http.StatusBadRequest, common.CodeBadRequest, "The verification link is invalid, used or expired")
+
+// VerifyEmail confirms a registered user's email with the token of the link
+// sent to it, after which they can log in.
+func (h *Handler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "VerifyEmail")
+
+	var req model.EmailVerificationRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	ctx := r.Context()
+	user_id, err := h.db.UseEmailVerification(ctx, hashToken(req.Token))
+	if database.IsNotFound(err) {
+		h.respondError(w, r, errEmailVerificationInvalid, "Invalid email verification")
+		return
+	}
+	if err != nil {
+		h.respondError(w, r, err, "Error using email verification")
+		return
+	}
+
+	user, err := h.db.GetUser(ctx, user_id)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to get user")
+		return
+	}
+	if user == nil {
+		h.respondError(w, r, errEmailVerificationInvalid, "Invalid email verification")
+		return
+	}
+
+	h.auditAccount(ctx, model.AuditEmailVerified, user.Username, user)
+
+	common.RespondJSON(w, http.StatusOK, user)
+}
+
+// ResendVerification emails another verification link to a user who has not
+// verified their email. Like ForgotPassword it answers 202 either way.
+func (h *Handler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "ResendVerification")
+
+	var req model.EmailVerificationResendRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	ctx := r.Context()
+	user, err := h.db.GetUserByUsername(ctx, model.NormalizeUsername(req.Username))
+	if err != nil {
+		h.respondError(w, r, err, "Error finding user")
+		return
+	}
+
+	if user != nil && user.Email_Verified_At == nil {
+		if err := h.sendVerification(ctx, user); err != nil {
+			h.respondError(w, r, err, "Error creating email verification")
+			return
+		}
+	}
+
+	common.RespondJSON(w, http.StatusAccepted, map[string]string{
+		"status": "If the account is awaiting verification, a link has been sent to it.",
+	})
+}
+
+// sendVerification creates a verification token for user, replacing any
+// earlier one, and emails its link.
+func (h *Handler) sendVerification(ctx context.Context, user *model.User) error {
+	token, err := randomToken()
+	if err != nil {
+		return fmt.Errorf("error creating verification token: %w", err)
+	}
+
+	expires := time.Now().Add(h.registration.VerifyTTL)
+	if err := h.db.CreateEmailVerification(ctx, user.ID, hashToken(token), expires); err != nil {
+		return err
+	}
+
+	h.sendMail(ctx, user.Username, "", mail.TemplateEmailVerification, map[string]any{
+		"Link":    tokenLink(h.registration.VerifyURL, token),
+		"Expires": expires.Format(time.RFC1123),
+	})
+	return nil
+}
+
+// StartRegistrationCleanup deletes registrations left unverified for longer
+// than CleanupAfter every interval until ctx is cancelled.
+func (h *Handler) StartRegistrationCleanup(ctx context.Context, interval time.Duration) {
+	ctx = database.WithActor(ctx, database.Actor{Username: "registration cleanup"})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				ids, err := h.db.DeleteUnverifiedUsers(ctx, time.Now().Add(-h.registration.CleanupAfter))
+				if err != nil {
+					h.logger.ErrorContext(ctx, "registration cleanup failed", "error", err)
+				} else if len(ids) > 0 {
+					h.logger.InfoContext(ctx, "unverified registrations deleted", "count", len(ids))
+				}
+			}
+		}
+	}()
+}
@@ -1,21 +1,25 @@
 package handler
 
 import (
-	"encoding/json"
-	"fmt"
 	"net/http"
 
 	"github.com/gorilla/mux"
 	common "github.com/htstinson/stinsondataapi/api/commonweb"
 	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/htstinson/stinsondataapi/api/pkg/database"
 )
 
 func (h *Handler) SelectRoles(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h SelectRoles")
+	h.logger.DebugContext(r.Context(), "SelectRoles")
+
+	q, ok := h.listQuery(w, r, database.RoleList)
+	if !ok {
+		return
+	}
 	ctx := r.Context()
-	customers, err := h.db.SelectRoles(ctx, 100, 0)
+	customers, err := h.db.SelectRoles(ctx, q)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to list customers")
+		h.respondError(w, r, err, "Failed to list customers")
 		return
 	}
 
@@ -24,19 +28,17 @@ func (h *Handler) SelectRoles(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) CreateRole(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h CreateRole")
+	h.logger.DebugContext(r.Context(), "CreateRole")
 
 	var role *model.Role
-	if err := json.NewDecoder(r.Body).Decode(&role); err != nil {
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &role) {
 		return
 	}
-	defer r.Body.Close()
 
 	ctx := r.Context()
 	role, err := h.db.CreateRole(ctx, role.Name)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to create role")
+		h.respondError(w, r, err, "Failed to create role")
 		return
 	}
 
@@ -44,7 +46,7 @@ func (h *Handler) CreateRole(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) UpdateRole(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h UpdateRole")
+	h.logger.DebugContext(r.Context(), "UpdateRole")
 
 	vars := mux.Vars(r)
 	id := vars["id"]
@@ -52,22 +54,17 @@ func (h *Handler) UpdateRole(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	var role model.Role
-	if err := json.NewDecoder(r.Body).Decode(&role); err != nil {
-		fmt.Println(1, err.Error())
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &role) {
 		return
 	}
-	defer r.Body.Close()
 
 	currentrole, err := h.db.GetRole(ctx, id)
 	if err != nil {
-		fmt.Println(2, err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get role")
+		h.respondError(w, r, err, "Failed to get role")
 		return
 	}
 
 	if currentrole == nil {
-		fmt.Println(3)
 		common.RespondError(w, http.StatusNotFound, "Role not found")
 		return
 	}
@@ -76,18 +73,15 @@ func (h *Handler) UpdateRole(w http.ResponseWriter, r *http.Request) {
 
 	err = h.db.UpdateRole(ctx, currentrole)
 	if err != nil {
-		fmt.Println(4, err.Error())
-		common.RespondError(w, http.StatusNotFound, "Error updating role")
+		h.respondError(w, r, err, "Error updating role")
 		return
 	}
 
-	fmt.Println(5)
-
 	common.RespondJSON(w, http.StatusOK, role)
 }
 
 func (h *Handler) DeleteRole(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h DeleteRole")
+	h.logger.DebugContext(r.Context(), "DeleteRole")
 
 	vars := mux.Vars(r)
 	id := vars["id"]
@@ -96,7 +90,7 @@ func (h *Handler) DeleteRole(w http.ResponseWriter, r *http.Request) {
 
 	role, err := h.db.GetRole(ctx, id)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get role")
+		h.respondError(w, r, err, "Failed to get role")
 		return
 	}
 	if role == nil {
@@ -106,7 +100,7 @@ func (h *Handler) DeleteRole(w http.ResponseWriter, r *http.Request) {
 
 	err = h.db.DeleteRole(ctx, id)
 	if err != nil {
-		common.RespondError(w, http.StatusNotFound, "Error deleting fole")
+		h.respondError(w, r, err, "Error deleting role")
 		return
 	}
 
@@ -115,7 +109,7 @@ func (h *Handler) DeleteRole(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) GetRole(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h GetRole")
+	h.logger.DebugContext(r.Context(), "GetRole")
 
 	vars := mux.Vars(r)
 	id := vars["id"]
@@ -123,7 +117,7 @@ func (h *Handler) GetRole(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	role, err := h.db.GetRole(ctx, id)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get fole")
+		h.respondError(w, r, err, "Failed to get role")
 		return
 	}
 	if role == nil {
@@ -1,23 +1,24 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"regexp"
 	"strconv"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
 	"github.com/google/uuid"
 	searcher "github.com/htstinson/business_searcher"
 	common "github.com/htstinson/stinsondataapi/api/commonweb"
+	"github.com/htstinson/stinsondataapi/api/internal/mail"
+	"github.com/htstinson/stinsondataapi/api/internal/metrics"
 	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/htstinson/stinsondataapi/api/internal/secrets"
+	"github.com/htstinson/stinsondataapi/api/internal/tracing"
+	"github.com/htstinson/stinsondataapi/api/pkg/database"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type key struct {
@@ -25,13 +26,13 @@ type key struct {
 }
 
 func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h Search")
+	h.logger.DebugContext(r.Context(), "Search")
 
 	ctx := r.Context()
 
-	apiKey, err := getSecret("Google_Custom_Search")
+	apiKey, err := secrets.GetString(ctx, h.secrets, "Google_Custom_Search")
 	if err != nil {
-		fmt.Println(err.Error())
+		h.respondError(w, r, err, "search is not configured")
 		return
 	}
 
@@ -39,28 +40,30 @@ func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
 
 	err = json.Unmarshal([]byte(apiKey), &k)
 	if err != nil {
-		fmt.Println(err.Error())
+		h.logger.ErrorContext(ctx, "Search", "error", err)
 	}
 
 	var search_definition model.SearchDefinition
-	if err := json.NewDecoder(r.Body).Decode(&search_definition); err != nil {
-		fmt.Println(1, err.Error())
-		common.RespondError(w, http.StatusBadRequest, "invalid search_definition")
+	if !h.decode(w, r, &search_definition, "Id") {
 		return
 	}
-	defer r.Body.Close()
 
-	subscriber, err := h.db.GetSubscriber(ctx, search_definition.SubscriberId)
-	if err != nil {
-		fmt.Println(err.Error())
+	subscriber, ok := h.tenant(w, r, search_definition.SubscriberId)
+	if !ok {
 		return
 	}
 
 	search_definition, err = h.db.GetSearchDefinition(ctx, *subscriber, search_definition.Id, 10, 0)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to get search definition")
+		return
+	}
 
-	search_engine_list, err := h.db.SelectSearchDefinitionEnginesView(ctx, search_definition, 10, 0)
+	search_engine_list, err := database.SelectAll(database.ListQuery{}, func(q database.ListQuery) (*database.Page[model.SearchDefinitionEnginesView], error) {
+		return h.db.SelectSearchDefinitionEnginesView(ctx, search_definition, q)
+	})
 	if err != nil {
-		fmt.Println(err.Error())
+		h.respondError(w, r, err, "Failed to get search engines")
 		return
 	}
 
@@ -70,7 +73,9 @@ func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
 		EndDate:   search_definition.EndDate.Format("2006-01-02"),
 	}
 
-	fmt.Println("search engines count:", len(search_engine_list))
+	h.logger.DebugContext(ctx, "Search", "search_engines_count", len(search_engine_list))
+
+	var total int
 
 	//Load each search
 	for _, v := range search_engine_list {
@@ -108,10 +113,17 @@ func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
 			Searches:      searches,
 		}
 
+		searchCtx, span := tracing.Start(ctx, "searcher.ExecuteAllSearches",
+			attribute.String("search.engine", v.SearchEngineName),
+			attribute.String("search.subscriber_id", subscriber.Id),
+		)
+
 		// Create Google Search CLient
 		client, err = searcher.NewSearchClient(k.Value, &config)
 		if err != nil {
-			fmt.Println(err.Error())
+			tracing.End(span, err)
+			metrics.SearchRun(v.SearchEngineName, subscriber.Id, err)
+			h.respondError(w, r, err, "Failed to create search client")
 			return
 		}
 
@@ -121,30 +133,34 @@ func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
 			Configuration: client.BuildConfigurationOutput(),
 			Searches:      client.ExecuteAllSearches(),
 		}
+		span.SetAttributes(attribute.Int("search.queries", len(output.Searches)))
+		tracing.End(span, nil)
+		metrics.SearchRun(v.SearchEngineName, subscriber.Id, nil)
+		h.logger.DebugContext(searchCtx, "Search", "search_engine", v.SearchEngineName)
 
 		count = 0
-		for _, w := range output.Searches {
+		for _, search := range output.Searches {
 
-			for _, n := range w.Results {
+			for _, n := range search.Results {
 				for _, b := range n.Items {
 
 					subscriberId, err := uuid.Parse(subscriber.Id)
 					if err != nil {
-						fmt.Println(err.Error())
+						h.logger.ErrorContext(ctx, "Search", "error", err)
 					}
 					search_definition_engine_id, err := uuid.Parse(v.Id)
 					if err != nil {
-						fmt.Println(err.Error())
+						h.logger.ErrorContext(ctx, "Search", "error", err)
 					}
 
 					search_time, err := time.Parse(time.RFC3339, output.Timestamp)
 					if err != nil {
-						fmt.Printf("error parsing time: %v\n", err)
+						h.logger.WarnContext(ctx, "error parsing time", "error", err)
 					}
 
 					published, err := extractdate(b.Snippet)
 					if err != nil {
-						fmt.Println(err.Error())
+						h.logger.ErrorContext(ctx, "Search", "error", err)
 					}
 
 					calbrate_search_result := model.CalibrateSearchResult{
@@ -158,20 +174,29 @@ func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
 					}
 					_, err = h.db.CreateSearchResult(ctx, *subscriber, calbrate_search_result)
 					if err != nil {
-						fmt.Println(err.Error())
+						h.logger.ErrorContext(ctx, "Search", "error", err)
 					}
 					count++
-					fmt.Println(calbrate_search_result.Published.Format(time.RFC3339))
 				}
-				fmt.Println("Total Results", count)
-				fmt.Println("------------------------------------------------------------------------------------------", v.Id)
-				fmt.Println()
+				h.logger.DebugContext(ctx, "Search", "total_results", count)
+				h.logger.DebugContext(ctx, "Search", "search_definition_engine_id", v.Id)
 			}
+			metrics.SearchResults(v.SearchEngineName, subscriber.Id, count)
+			total += count
 			count = 0
 		}
 
 	}
 
+	if total > 0 {
+		h.notifySubscriber(ctx, subscriber.Id, model.NotificationMention, mail.TemplateMentionAlert, map[string]any{
+			"Search":     search_definition.Name,
+			"Count":      total,
+			"Subscriber": subscriber.Name,
+		})
+	}
+
+	common.RespondJSON(w, http.StatusOK, map[string]int{"results": total})
 }
 
 func extractdate(input string) (time.Time, error) {
@@ -226,33 +251,3 @@ func extractdate(input string) (time.Time, error) {
 
 	return t, errors.New("no date found")
 }
-
-func getSecret(secret_name string) (string, error) {
-	secretName := secret_name
-	region := "us-west-2"
-
-	config, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(region))
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	// Create Secrets Manager client
-	svc := secretsmanager.NewFromConfig(config)
-
-	input := &secretsmanager.GetSecretValueInput{
-		SecretId:     aws.String(secretName),
-		VersionStage: aws.String("AWSCURRENT"), // VersionStage defaults to AWSCURRENT if unspecified
-	}
-
-	result, err := svc.GetSecretValue(context.TODO(), input)
-	if err != nil {
-		// For a list of exceptions thrown, see
-		// https://docs.aws.amazon.com/secretsmanager/latest/apireference/API_GetSecretValue.html
-		log.Fatal(err.Error())
-	}
-
-	// Decrypts secret using the associated KMS key.
-	var secretString string = *result.SecretString
-
-	return secretString, err
-}
@@ -1,34 +1,34 @@
 package handler
 
 import (
-	"encoding/json"
-	"fmt"
 	"net/http"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	common "github.com/htstinson/stinsondataapi/api/commonweb"
 	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/htstinson/stinsondataapi/api/pkg/database"
 )
 
 func (h *Handler) SelectSearchDefinitionEnginesView(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h SelecttSearchDefinitionEnginesView")
+	h.logger.DebugContext(r.Context(), "SelecttSearchDefinitionEnginesView")
+
+	q, ok := h.listQuery(w, r, database.SearchDefinitionEngineList)
+	if !ok {
+		return
+	}
 
 	ctx := r.Context()
 
 	vars := mux.Vars(r)
-	subscriber_Id := vars["subscriber_id"]
-
-	subscriber, err := h.db.GetSubscriber(ctx, subscriber_Id)
-	if err != nil {
-		fmt.Println(err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Failed to select subcriber")
+	subscriber, ok := h.tenant(w, r, vars["subscriber_id"])
+	if !ok {
 		return
 	}
 
-	results, err := h.db.SelectSearchDefinitionEnginesSubscriberView(ctx, *subscriber, 100, 0)
+	results, err := h.db.SelectSearchDefinitionEnginesSubscriberView(ctx, *subscriber, q)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to select search definition engines view")
+		h.respondError(w, r, err, "Failed to select search definition engines view")
 		return
 	}
 
@@ -37,7 +37,7 @@ func (h *Handler) SelectSearchDefinitionEnginesView(w http.ResponseWriter, r *ht
 }
 
 func (h *Handler) DeleteSearchDefinitionEngine(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h DeleteSearchDefinitionEngine")
+	h.logger.DebugContext(r.Context(), "DeleteSearchDefinitionEngine")
 
 	vars := mux.Vars(r)
 	subscriber_id := vars["subscriber_id"]
@@ -45,21 +45,20 @@ func (h *Handler) DeleteSearchDefinitionEngine(w http.ResponseWriter, r *http.Re
 
 	ctx := r.Context()
 
-	subscriber, err := h.db.GetSubscriber(ctx, subscriber_id)
-	if err != nil {
-		fmt.Println(err.Error())
+	subscriber, ok := h.tenant(w, r, subscriber_id)
+	if !ok {
 		return
 	}
 
 	search_engine, err := h.db.GetSearchDefinitionEnginesView(ctx, *subscriber, search_definition_engine_id)
 	if err != nil {
-		fmt.Println(err.Error())
+		h.respondError(w, r, err, "Failed to get search definition engine")
 		return
 	}
 
 	err = h.db.DeleteSearchDefinitionEngine(ctx, subscriber, search_definition_engine_id)
 	if err != nil {
-		common.RespondError(w, http.StatusNotFound, "Error deleting Search Definition Engine")
+		h.respondError(w, r, err, "Error deleting Search Definition Engine")
 		return
 	}
 
@@ -68,28 +67,25 @@ func (h *Handler) DeleteSearchDefinitionEngine(w http.ResponseWriter, r *http.Re
 }
 
 func (h *Handler) CreateSearchDefinitionEngines(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h CreateSearchDefinitionEngines")
+	h.logger.DebugContext(r.Context(), "CreateSearchDefinitionEngines")
 
 	var row *model.SearchDefinitionEngines
-	if err := json.NewDecoder(r.Body).Decode(&row); err != nil {
-		fmt.Println(err.Error())
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &row) {
 		return
 	}
-	defer r.Body.Close()
 	ctx := r.Context()
 
 	row.Id = uuid.New().String()
 
-	subcriber, err := h.db.GetSubscriber(ctx, row.SubscriberId)
-	if err != nil {
-		fmt.Println(err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get subscriber")
+	subcriber, ok := h.tenant(w, r, row.SubscriberId)
+	if !ok {
+		return
 	}
+	row.SubscriberId = subcriber.Id
 
-	row, err = h.db.CreateSearchDefinitionEngine(ctx, *subcriber, *row)
+	row, err := h.db.CreateSearchDefinitionEngine(ctx, *subcriber, *row)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to create row")
+		h.respondError(w, r, err, "Failed to create row")
 		return
 	}
 
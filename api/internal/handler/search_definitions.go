@@ -1,34 +1,34 @@
 package handler
 
 import (
-	"encoding/json"
-	"fmt"
 	"net/http"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	common "github.com/htstinson/stinsondataapi/api/commonweb"
 	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/htstinson/stinsondataapi/api/pkg/database"
 )
 
 func (h *Handler) SelectSearchDefinitions(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h SelectSearchDefinitions")
+	h.logger.DebugContext(r.Context(), "SelectSearchDefinitions")
+
+	q, ok := h.listQuery(w, r, database.SearchDefinitionList)
+	if !ok {
+		return
+	}
 
 	ctx := r.Context()
 
 	vars := mux.Vars(r)
-	subscriber_Id := vars["subscriber_id"]
-
-	subscriber, err := h.db.GetSubscriber(ctx, subscriber_Id)
-	if err != nil {
-		fmt.Println(err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Failed to select subcriber")
+	subscriber, ok := h.tenant(w, r, vars["subscriber_id"])
+	if !ok {
 		return
 	}
 
-	results, err := h.db.SelectSearchDefinitions(ctx, *subscriber, 100, 0)
+	results, err := h.db.SelectSearchDefinitions(ctx, *subscriber, q)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to select search definitions")
+		h.respondError(w, r, err, "Failed to select search definitions")
 		return
 	}
 
@@ -37,7 +37,7 @@ func (h *Handler) SelectSearchDefinitions(w http.ResponseWriter, r *http.Request
 }
 
 func (h *Handler) DeleteSearchDefinition(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h DeleteSearchDefinition")
+	h.logger.DebugContext(r.Context(), "DeleteSearchDefinition")
 
 	vars := mux.Vars(r)
 	subscriber_id := vars["subscriber_id"]
@@ -45,21 +45,20 @@ func (h *Handler) DeleteSearchDefinition(w http.ResponseWriter, r *http.Request)
 
 	ctx := r.Context()
 
-	subscriber, err := h.db.GetSubscriber(ctx, subscriber_id)
-	if err != nil {
-		fmt.Println(err.Error())
+	subscriber, ok := h.tenant(w, r, subscriber_id)
+	if !ok {
 		return
 	}
 
 	search_engine, err := h.db.GetSearchDefinition(ctx, *subscriber, search_definition_id, 1, 0)
 	if err != nil {
-		fmt.Println(err.Error())
+		h.respondError(w, r, err, "Failed to get search definition")
 		return
 	}
 
 	err = h.db.DeleteSearchDefinition(ctx, subscriber, search_definition_id)
 	if err != nil {
-		common.RespondError(w, http.StatusNotFound, "Error deleting Search Engine")
+		h.respondError(w, r, err, "Error deleting Search Engine")
 		return
 	}
 
@@ -68,29 +67,26 @@ func (h *Handler) DeleteSearchDefinition(w http.ResponseWriter, r *http.Request)
 }
 
 func (h *Handler) CreateSearchDefinition(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h CreateSearchDefinition")
+	h.logger.DebugContext(r.Context(), "CreateSearchDefinition")
 
 	var row *model.SearchDefinition
-	if err := json.NewDecoder(r.Body).Decode(&row); err != nil {
-		fmt.Println(err.Error())
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &row) {
 		return
 	}
-	defer r.Body.Close()
 	ctx := r.Context()
 
 	row.Id = uuid.New().String()
 	row.SearchType = "custom"
 
-	subcriber, err := h.db.GetSubscriber(ctx, row.SubscriberId)
-	if err != nil {
-		fmt.Println(err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get subscriber")
+	subcriber, ok := h.tenant(w, r, row.SubscriberId)
+	if !ok {
+		return
 	}
+	row.SubscriberId = subcriber.Id
 
-	row, err = h.db.CreateSearchDefinition(ctx, *subcriber, *row)
+	row, err := h.db.CreateSearchDefinition(ctx, *subcriber, *row)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to create row")
+		h.respondError(w, r, err, "Failed to create row")
 		return
 	}
 
@@ -98,32 +94,27 @@ func (h *Handler) CreateSearchDefinition(w http.ResponseWriter, r *http.Request)
 }
 
 func (h *Handler) UpdateSearchDefinition(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h UpdateSearchDefinition")
+	h.logger.DebugContext(r.Context(), "UpdateSearchDefinition")
 
 	var row *model.SearchDefinition
-	if err := json.NewDecoder(r.Body).Decode(&row); err != nil {
-		fmt.Println(err.Error())
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &row) {
 		return
 	}
-	defer r.Body.Close()
 	ctx := r.Context()
 
 	row.SearchType = "custom"
 
-	subcriber, err := h.db.GetSubscriber(ctx, row.SubscriberId)
-	if err != nil {
-		fmt.Println(err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get subscriber")
+	subcriber, ok := h.tenant(w, r, row.SubscriberId)
+	if !ok {
+		return
 	}
+	row.SubscriberId = subcriber.Id
 
-	row, err = h.db.UpdateSearchDefinition(ctx, subcriber, *row)
+	row, err := h.db.UpdateSearchDefinition(ctx, subcriber, *row)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to create row")
+		h.respondError(w, r, err, "Failed to create row")
 		return
 	}
 
-	fmt.Println(row.Name)
-
 	common.RespondJSON(w, http.StatusCreated, row)
 }
@@ -1,34 +1,34 @@
 package handler
 
 import (
-	"encoding/json"
-	"fmt"
 	"net/http"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	common "github.com/htstinson/stinsondataapi/api/commonweb"
 	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/htstinson/stinsondataapi/api/pkg/database"
 )
 
 func (h *Handler) SelectSearchEngines(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h SelecttSearchEngines")
+	h.logger.DebugContext(r.Context(), "SelecttSearchEngines")
+
+	q, ok := h.listQuery(w, r, database.SearchEngineList)
+	if !ok {
+		return
+	}
 
 	ctx := r.Context()
 
 	vars := mux.Vars(r)
-	subscriber_Id := vars["subscriber_id"]
-
-	subscriber, err := h.db.GetSubscriber(ctx, subscriber_Id)
-	if err != nil {
-		fmt.Println(err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Failed to select subcriber")
+	subscriber, ok := h.tenant(w, r, vars["subscriber_id"])
+	if !ok {
 		return
 	}
 
-	search_engines, err := h.db.SelectSearchEngines(ctx, *subscriber, 100, 0)
+	search_engines, err := h.db.SelectSearchEngines(ctx, *subscriber, q)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to select search engines")
+		h.respondError(w, r, err, "Failed to select search engines")
 		return
 	}
 
@@ -37,27 +37,25 @@ func (h *Handler) SelectSearchEngines(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) CreateSearchEngine(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h CreateSearchEngine")
+	h.logger.DebugContext(r.Context(), "CreateSearchEngine")
 
 	var search_engine *model.SearchEngine
-	if err := json.NewDecoder(r.Body).Decode(&search_engine); err != nil {
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &search_engine) {
 		return
 	}
-	defer r.Body.Close()
 	ctx := r.Context()
 
 	search_engine.Id = uuid.New().String()
 
-	subcriber, err := h.db.GetSubscriber(ctx, search_engine.SubscriberId)
-	if err != nil {
-		fmt.Println(err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get subscriber")
+	subcriber, ok := h.tenant(w, r, search_engine.SubscriberId)
+	if !ok {
+		return
 	}
+	search_engine.SubscriberId = subcriber.Id
 
-	search_engine, err = h.db.CreateSearchEngine(ctx, *search_engine, *subcriber)
+	search_engine, err := h.db.CreateSearchEngine(ctx, *search_engine, *subcriber)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to create search engine")
+		h.respondError(w, r, err, "Failed to create search engine")
 		return
 	}
 
@@ -65,7 +63,7 @@ func (h *Handler) CreateSearchEngine(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) DeleteSearchEngine(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h DeleteSearchEngine")
+	h.logger.DebugContext(r.Context(), "DeleteSearchEngine")
 
 	vars := mux.Vars(r)
 	subscriber_id := vars["subscriber_id"]
@@ -73,21 +71,20 @@ func (h *Handler) DeleteSearchEngine(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 
-	subscriber, err := h.db.GetSubscriber(ctx, subscriber_id)
-	if err != nil {
-		fmt.Println(err.Error())
+	subscriber, ok := h.tenant(w, r, subscriber_id)
+	if !ok {
 		return
 	}
 
 	search_engine, err := h.db.GetSearchEngine(ctx, *subscriber, search_engine_id, 1, 0)
 	if err != nil {
-		fmt.Println(err.Error())
+		h.respondError(w, r, err, "Failed to get search engine")
 		return
 	}
 
 	err = h.db.DeleteSearchEngine(ctx, subscriber, search_engine)
 	if err != nil {
-		common.RespondError(w, http.StatusNotFound, "Error deleting Search Engine")
+		h.respondError(w, r, err, "Error deleting Search Engine")
 		return
 	}
 
@@ -1,32 +1,34 @@
 package handler
 
 import (
-	"fmt"
 	"net/http"
 
 	"github.com/gorilla/mux"
 	common "github.com/htstinson/stinsondataapi/api/commonweb"
+	"github.com/htstinson/stinsondataapi/api/pkg/database"
 )
 
 func (h *Handler) SelectSearchResults(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h SelectSearchResults")
+	h.logger.DebugContext(r.Context(), "SelectSearchResults")
+
+	q, ok := h.listQuery(w, r, database.SearchResultList)
+	if !ok {
+		return
+	}
 
 	ctx := r.Context()
 
 	vars := mux.Vars(r)
-	subscriber_Id := vars["subscriber_id"]
 	searchDefinitionEngineId := vars["search_definition_engine_id"]
 
-	subscriber, err := h.db.GetSubscriber(ctx, subscriber_Id)
-	if err != nil {
-		fmt.Println(err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Failed to select subcriber")
+	subscriber, ok := h.tenant(w, r, vars["subscriber_id"])
+	if !ok {
 		return
 	}
 
-	results, err := h.db.SelectSearchResultView(ctx, *subscriber, searchDefinitionEngineId)
+	results, err := h.db.SelectSearchResultView(ctx, *subscriber, searchDefinitionEngineId, q)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to select search definitions")
+		h.respondError(w, r, err, "Failed to select search definitions")
 		return
 	}
 
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	common "github.com/htstinson/stinsondataapi/api/commonweb"
+	"github.com/htstinson/stinsondataapi/api/internal/auth"
+	"github.com/htstinson/stinsondataapi/api/internal/model"
+)
+
+// GetMe returns the current user, the subscriber their token is scoped to
+// and the subscribers they can select with SelectSessionSubscriber.
+func (h *Handler) GetMe(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "GetMe")
+
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	claims := r.Context().Value("user").(*auth.Claims)
+
+	tenants, err := h.db.SelectUserTenants(r.Context(), user.ID)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to list subscribers")
+		return
+	}
+
+	common.RespondJSON(w, http.StatusOK, model.Me{
+		User:                 user,
+		Active_Subscriber_Id: claims.SubscriberID,
+		Roles:                claims.Roles,
+		Tenants:              tenants,
+	})
+}
+
+// SelectSessionSubscriber reissues the caller's token scoped to one of their
+// subscribers and their roles in it. Admins may select any subscriber. The
+// new token expires with the old one.
+func (h *Handler) SelectSessionSubscriber(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "SelectSessionSubscriber")
+
+	var req model.SessionSubscriberRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	claims := r.Context().Value("user").(*auth.Claims)
+
+	ctx := r.Context()
+	tenants, err := h.db.SelectUserTenants(ctx, user.ID)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to list subscribers")
+		return
+	}
+
+	var tenant *model.Tenant
+	for i := range tenants {
+		if tenants[i].Subscriber_Id == req.Subscriber_Id {
+			tenant = &tenants[i]
+			break
+		}
+	}
+	if tenant == nil && claims.HasRole("admin") {
+		subscriber, err := h.db.GetSubscriber(ctx, req.Subscriber_Id)
+		if err != nil {
+			h.respondError(w, r, err, "Failed to get subscriber")
+			return
+		}
+		if subscriber != nil {
+			tenant = &model.Tenant{Subscriber_Id: subscriber.Id, Subscriber_Name: subscriber.Name, Roles: []string{}}
+		}
+	}
+	if tenant == nil {
+		h.respondError(w, r, common.NewError(http.StatusForbidden, common.CodeForbidden, "You are not a member of this subscriber"), "Subscriber not selectable")
+		return
+	}
+
+	roles, err := h.db.SelectRolesByUser(ctx, user.ID)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "SelectSessionSubscriber", "error", err)
+	}
+
+	token, err := h.auth.SwitchTenant(claims, roles, tenant)
+	if err != nil {
+		h.respondError(w, r, err, "Error generating token")
+		return
+	}
+
+	w.Header().Set("Authorization", "Bearer "+token)
+	common.RespondJSON(w, http.StatusOK, model.LoginResponse{
+		Token:     token,
+		ExpiresIn: int64(time.Until(claims.ExpiresAt.Time).Seconds()),
+	})
+}
+
+// tenant returns the subscriber the request's token is scoped to. A
+// subscriber also named in the path or body must be the same one, so that
+// naming another cannot reach its data; an empty named is not checked.
+func (h *Handler) tenant(w http.ResponseWriter, r *http.Request, named string) (*model.Subscriber, bool) {
+	claims, ok := r.Context().Value("user").(*auth.Claims)
+	if !ok {
+		common.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return nil, false
+	}
+	if claims.SubscriberID == "" {
+		h.respondError(w, r, common.NewError(http.StatusForbidden, common.CodeSubscriberRequired, "No subscriber is selected; select one with /session/subscriber"), "No active subscriber")
+		return nil, false
+	}
+	if named != "" && named != claims.SubscriberID {
+		h.respondError(w, r, common.NewError(http.StatusForbidden, common.CodeForbidden, "The token is scoped to another subscriber"), "Subscriber mismatch")
+		return nil, false
+	}
+
+	subscriber, err := h.db.GetSubscriber(r.Context(), claims.SubscriberID)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to get subscriber")
+		return nil, false
+	}
+	if subscriber == nil {
+		common.RespondError(w, http.StatusNotFound, "Subscriber not found")
+		return nil, false
+	}
+
+	return subscriber, true
+}
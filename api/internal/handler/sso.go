@@ -0,0 +1,445 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	common "github.com/htstinson/stinsondataapi/api/commonweb"
+	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/htstinson/stinsondataapi/api/internal/oidc"
+	"github.com/htstinson/stinsondataapi/api/internal/secrets"
+	"github.com/htstinson/stinsondataapi/api/pkg/database"
+	"golang.org/x/oauth2"
+)
+
+// SSOOptions configure single sign-on, as set in config.OIDC. Sign-on is off
+// while RedirectURL is empty.
+type SSOOptions struct {
+	Client      *oidc.Client
+	RedirectURL string
+	FrontendURL string
+	StateTTL    time.Duration
+}
+
+// SetSSO replaces the single sign-on options, which default to off.
+func (h *Handler) SetSSO(options SSOOptions) {
+	h.sso = options
+}
+
+// GetSubscriberSSO returns the subscriber's OpenID provider.
+func (h *Handler) GetSubscriberSSO(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "GetSubscriberSSO")
+	id := mux.Vars(r)["id"]
+
+	cfg, err := h.db.GetSubscriberOIDC(r.Context(), id)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to get single sign-on")
+		return
+	}
+	if cfg == nil {
+		common.RespondError(w, http.StatusNotFound, "single sign-on not configured")
+		return
+	}
+
+	common.RespondJSON(w, http.StatusOK, cfg)
+}
+
+// SaveSubscriberSSO sets the subscriber's OpenID provider. The issuer is
+// discovered before it is saved, so that a mistyped one is refused.
+func (h *Handler) SaveSubscriberSSO(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "SaveSubscriberSSO")
+	id := mux.Vars(r)["id"]
+
+	var cfg model.SubscriberOIDC
+	if !h.decode(w, r, &cfg) {
+		return
+	}
+	cfg.Subscriber_Id = id
+	for i, domain := range cfg.Allowed_Domains {
+		cfg.Allowed_Domains[i] = strings.ToLower(domain)
+	}
+
+	ctx := r.Context()
+	subscriber, err := h.db.GetSubscriber(ctx, id)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to get subscriber")
+		return
+	}
+	if subscriber == nil {
+		common.RespondError(w, http.StatusNotFound, "subscriber not found")
+		return
+	}
+
+	if cfg.Default_Role_Id != "" {
+		role, err := h.db.GetRole(ctx, cfg.Default_Role_Id)
+		if err != nil {
+			h.respondError(w, r, err, "Failed to get role")
+			return
+		}
+		if role == nil {
+			h.respondError(w, r, common.ValidationError(map[string][]string{"default_role_id": {"no role has this id"}}), "Invalid single sign-on")
+			return
+		}
+	}
+	if cfg.JIT_Provisioning && cfg.Default_Role_Id == "" {
+		h.respondError(w, r, common.ValidationError(map[string][]string{"default_role_id": {"is required with jit_provisioning"}}), "Invalid single sign-on")
+		return
+	}
+
+	if h.sso.Client != nil {
+		if _, err := h.sso.Client.Provider(ctx, cfg.Issuer); err != nil {
+			h.respondError(w, r, common.ValidationError(map[string][]string{"issuer": {err.Error()}}), "Invalid single sign-on")
+			return
+		}
+	}
+
+	if err := h.db.SaveSubscriberOIDC(ctx, &cfg); err != nil {
+		h.respondError(w, r, err, "Failed to save single sign-on")
+		return
+	}
+
+	saved, err := h.db.GetSubscriberOIDC(ctx, id)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to get single sign-on")
+		return
+	}
+
+	common.RespondJSON(w, http.StatusOK, saved)
+}
+
+// DeleteSubscriberSSO turns off single sign-on for the subscriber.
+func (h *Handler) DeleteSubscriberSSO(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "DeleteSubscriberSSO")
+	id := mux.Vars(r)["id"]
+
+	if err := h.db.DeleteSubscriberOIDC(r.Context(), id); err != nil {
+		h.respondError(w, r, err, "Failed to delete single sign-on")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SSOLogin sends the browser to the subscriber's OpenID provider, with the
+// authorization code flow and PKCE. A login_hint query parameter is passed
+// on to the provider.
+func (h *Handler) SSOLogin(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "SSOLogin")
+	subscriberID := mux.Vars(r)["subscriber_id"]
+
+	ctx := r.Context()
+	cfg, provider, ok := h.ssoProvider(w, r, subscriberID)
+	if !ok {
+		return
+	}
+
+	state, err := randomToken()
+	if err != nil {
+		h.respondError(w, r, err, "Failed to start single sign-on")
+		return
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		h.respondError(w, r, err, "Failed to start single sign-on")
+		return
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	err = h.db.CreateOIDCState(ctx, model.OIDCState{
+		State_Hash:    hashToken(state),
+		Subscriber_Id: subscriberID,
+		Code_Verifier: verifier,
+		Nonce:         nonce,
+		Expires_At:    time.Now().Add(h.sso.StateTTL),
+	})
+	if err != nil {
+		h.respondError(w, r, err, "Failed to start single sign-on")
+		return
+	}
+
+	options := []oauth2.AuthCodeOption{
+		oauth2.S256ChallengeOption(verifier),
+		oauth2.SetAuthURLParam("nonce", nonce),
+	}
+	if hint := r.URL.Query().Get("login_hint"); hint != "" {
+		options = append(options, oauth2.SetAuthURLParam("login_hint", hint))
+	}
+
+	oauthConfig := h.oauthConfig(cfg, provider, "")
+	http.Redirect(w, r, oauthConfig.AuthCodeURL(state, options...), http.StatusFound)
+}
+
+// SSOCallback completes a single sign-on when the provider sends the browser
+// back. The code is exchanged for an ID token, whose user is found or
+// provisioned as SubscriberOIDC describes, and the login continues as
+// Login does, with an MFA challenge when one is needed.
+func (h *Handler) SSOCallback(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "SSOCallback")
+
+	query := r.URL.Query()
+	if providerErr := query.Get("error"); providerErr != "" {
+		h.ssoFinish(w, r, nil, common.NewError(http.StatusUnauthorized, common.CodeUnauthorized, "The provider refused the sign-on: "+providerErr))
+		return
+	}
+
+	ctx := r.Context()
+	state, err := h.db.UseOIDCState(ctx, hashToken(query.Get("state")))
+	if database.IsNotFound(err) {
+		h.ssoFinish(w, r, nil, common.NewError(http.StatusBadRequest, common.CodeBadRequest, "The sign-on is unknown or has expired, start again"))
+		return
+	}
+	if err != nil {
+		h.ssoFinish(w, r, nil, err)
+		return
+	}
+
+	cfg, provider, ok := h.ssoProvider(w, r, state.Subscriber_Id)
+	if !ok {
+		return
+	}
+
+	clientSecret := ""
+	if cfg.Client_Secret_Name != "" {
+		if clientSecret, err = secrets.GetString(ctx, h.secrets, cfg.Client_Secret_Name); err != nil {
+			h.ssoFinish(w, r, nil, err)
+			return
+		}
+	}
+
+	exchangeCtx := context.WithValue(ctx, oauth2.HTTPClient, h.sso.Client.HTTPClient())
+	token, err := h.oauthConfig(cfg, provider, clientSecret).Exchange(exchangeCtx, query.Get("code"), oauth2.VerifierOption(state.Code_Verifier))
+	if err != nil {
+		h.logger.InfoContext(ctx, "sso code exchange failed", "subscriber_id", cfg.Subscriber_Id, "error", err)
+		h.ssoFinish(w, r, nil, common.NewError(http.StatusUnauthorized, common.CodeUnauthorized, "The provider did not accept the sign-on"))
+		return
+	}
+	rawIDToken, _ := token.Extra("id_token").(string)
+	claims, err := provider.Verify(ctx, rawIDToken, cfg.Client_Id, state.Nonce)
+	if err != nil {
+		h.logger.InfoContext(ctx, "sso id token refused", "subscriber_id", cfg.Subscriber_Id, "error", err)
+		h.ssoFinish(w, r, nil, common.NewError(http.StatusUnauthorized, common.CodeInvalidToken, "The provider's ID token is not valid"))
+		return
+	}
+
+	user, err := h.ssoUser(ctx, cfg, claims)
+	if err != nil {
+		h.auditAccount(ctx, model.AuditLoginFailed, claims.EmailAddress(), nil)
+		h.ssoFinish(w, r, nil, err)
+		return
+	}
+
+	purpose, err := h.mfaChallenge(ctx, user)
+	if err != nil {
+		h.ssoFinish(w, r, nil, err)
+		return
+	}
+	if purpose != "" {
+		mfaToken, err := h.auth.GenerateMFAToken(user.ID, purpose)
+		if err != nil {
+			h.ssoFinish(w, r, nil, err)
+			return
+		}
+		h.ssoFinish(w, r, &model.LoginResponse{
+			MFA_Token: mfaToken,
+			ExpiresIn: int64(h.auth.MFADuration().Seconds()),
+			MFA:       purpose,
+		}, nil)
+		return
+	}
+
+	bearer, err := h.loginToken(ctx, user)
+	if err != nil {
+		h.ssoFinish(w, r, nil, err)
+		return
+	}
+	h.ssoFinish(w, r, &model.LoginResponse{
+		Token:     bearer,
+		ExpiresIn: int64(h.auth.Config.TokenDuration.Seconds()),
+	}, nil)
+}
+
+// ssoProvider responds and returns false unless single sign-on is on and
+// the subscriber has an enabled provider that can be reached.
+func (h *Handler) ssoProvider(w http.ResponseWriter, r *http.Request, subscriberID string) (*model.SubscriberOIDC, *oidc.Provider, bool) {
+	if h.sso.RedirectURL == "" || h.sso.Client == nil {
+		h.ssoFinish(w, r, nil, common.NewError(http.StatusNotFound, common.CodeNotFound, "Single sign-on is not available"))
+		return nil, nil, false
+	}
+
+	cfg, err := h.db.GetSubscriberOIDC(r.Context(), subscriberID)
+	if err != nil {
+		h.ssoFinish(w, r, nil, err)
+		return nil, nil, false
+	}
+	if cfg == nil || !cfg.Enabled {
+		h.ssoFinish(w, r, nil, common.NewError(http.StatusNotFound, common.CodeNotFound, "Single sign-on is not configured for this subscriber"))
+		return nil, nil, false
+	}
+
+	provider, err := h.sso.Client.Provider(r.Context(), cfg.Issuer)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "sso provider unavailable", "issuer", cfg.Issuer, "error", err)
+		h.ssoFinish(w, r, nil, common.NewError(http.StatusBadGateway, common.CodeUnavailable, "The sign-on provider cannot be reached"))
+		return nil, nil, false
+	}
+
+	return cfg, provider, true
+}
+
+func (h *Handler) oauthConfig(cfg *model.SubscriberOIDC, provider *oidc.Provider, clientSecret string) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     cfg.Client_Id,
+		ClientSecret: clientSecret,
+		Endpoint:     provider.Endpoint,
+		RedirectURL:  h.sso.RedirectURL,
+		Scopes:       []string{"openid", "email", "profile"},
+	}
+}
+
+// ssoUser finds the user the ID token is of, linking or provisioning them
+// as cfg allows, and makes sure they belong to the subscriber.
+func (h *Handler) ssoUser(ctx context.Context, cfg *model.SubscriberOIDC, claims *oidc.Claims) (*model.User, error) {
+	email := claims.EmailAddress()
+	if len(cfg.Allowed_Domains) > 0 {
+		domain := email[strings.LastIndex(email, "@")+1:]
+		if !slices.Contains(cfg.Allowed_Domains, domain) {
+			return nil, common.NewError(http.StatusForbidden, common.CodeForbidden, "Accounts of this email domain cannot sign on to this subscriber")
+		}
+	}
+
+	identity, err := h.db.GetUserIdentity(ctx, cfg.Issuer, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	var user *model.User
+	if identity != nil {
+		if user, err = h.db.GetUser(ctx, identity.User_Id); err != nil {
+			return nil, err
+		}
+		if err := h.db.RecordIdentityLogin(ctx, identity.Id, email); err != nil {
+			h.logger.ErrorContext(ctx, "SSOCallback", "error", err)
+		}
+	}
+
+	if user == nil {
+		// Accounts are only found or created by email on the provider's
+		// word that the email is the user's
+		if email == "" || !claims.Verified() {
+			return nil, common.NewError(http.StatusForbidden, common.CodeForbidden, "The provider did not give a verified email address")
+		}
+		if user, err = h.db.GetUserByUsername(ctx, email); err != nil {
+			return nil, err
+		}
+		// Nor can one subscriber's provider take over the accounts of others
+		if user != nil {
+			_, err := h.db.LookupUserSubscriber(ctx, user.ID, cfg.Subscriber_Id)
+			if database.IsNotFound(err) {
+				return nil, common.NewError(http.StatusConflict, common.CodeConflict, "An account with this email exists and does not belong to this subscriber")
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		if user == nil {
+			if !cfg.JIT_Provisioning {
+				return nil, common.NewError(http.StatusForbidden, common.CodeForbidden, "No account exists for this email")
+			}
+			password, err := randomToken()
+			if err != nil {
+				return nil, err
+			}
+			if user, err = h.db.CreateUser(ctx, email, password); err != nil {
+				return nil, err
+			}
+			h.logger.InfoContext(ctx, "sso user provisioned", "user_id", user.ID, "subscriber_id", cfg.Subscriber_Id)
+		}
+
+		_, err = h.db.CreateUserIdentity(ctx, &model.UserIdentity{
+			User_Id:       user.ID,
+			Subscriber_Id: cfg.Subscriber_Id,
+			Issuer:        cfg.Issuer,
+			Subject:       claims.Subject,
+			Email:         email,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := h.ensureMember(ctx, cfg, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ensureMember adds user to the subscriber with its default role when they
+// do not belong to it, if cfg provisions users.
+func (h *Handler) ensureMember(ctx context.Context, cfg *model.SubscriberOIDC, user *model.User) error {
+	_, err := h.db.LookupUserSubscriber(ctx, user.ID, cfg.Subscriber_Id)
+	if err == nil {
+		return nil
+	}
+	if !database.IsNotFound(err) {
+		return err
+	}
+	if !cfg.JIT_Provisioning {
+		return common.NewError(http.StatusForbidden, common.CodeForbidden, "The account does not belong to this subscriber")
+	}
+
+	if _, err := h.db.CreateUserSubscriber(ctx, user.ID, cfg.Subscriber_Id); err != nil {
+		return err
+	}
+	if cfg.Default_Role_Id == "" {
+		return nil
+	}
+	member, err := h.db.LookupUserSubscriber(ctx, user.ID, cfg.Subscriber_Id)
+	if err != nil {
+		return err
+	}
+	_, err = h.db.CreateUserSubscriberRole(ctx, member.Id, cfg.Default_Role_Id)
+	return err
+}
+
+// ssoFinish ends a sign-on. With a frontend URL the browser is sent there
+// with the login response, or the error, in the URL fragment, which is
+// never sent to servers; otherwise they are answered as JSON.
+func (h *Handler) ssoFinish(w http.ResponseWriter, r *http.Request, resp *model.LoginResponse, err error) {
+	if h.sso.FrontendURL == "" {
+		if err != nil {
+			h.respondError(w, r, err, "Single sign-on failed")
+			return
+		}
+		common.RespondJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	fragment := url.Values{}
+	if err != nil {
+		var apiErr *common.Error
+		if !errors.As(err, &apiErr) {
+			h.logger.ErrorContext(r.Context(), "Single sign-on failed", "error", err)
+			apiErr = common.NewError(http.StatusInternalServerError, common.CodeInternal, "Single sign-on failed")
+		}
+		fragment.Set("error", apiErr.Code)
+		fragment.Set("error_description", apiErr.Detail)
+	} else {
+		if resp.Token != "" {
+			fragment.Set("token", resp.Token)
+		}
+		if resp.MFA_Token != "" {
+			fragment.Set("mfa_token", resp.MFA_Token)
+			fragment.Set("mfa", resp.MFA)
+		}
+		fragment.Set("expires_in", strconv.FormatInt(resp.ExpiresIn, 10))
+	}
+
+	http.Redirect(w, r, strings.SplitN(h.sso.FrontendURL, "#", 2)[0]+"#"+fragment.Encode(), http.StatusFound)
+}
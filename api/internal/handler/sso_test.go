@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+	common "github.com/htstinson/stinsondataapi/api/commonweb"
+	"github.com/htstinson/stinsondataapi/api/internal/auth"
+	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/htstinson/stinsondataapi/api/internal/oidc"
+	"github.com/htstinson/stinsondataapi/api/pkg/database"
+)
+
+// ssoUsers has one account, which belongs to the subscribers in member.
+type ssoUsers struct {
+	database.Repository
+	user   model.User
+	member map[string]bool
+	linked []model.UserIdentity
+}
+
+func (s *ssoUsers) GetUserIdentity(ctx context.Context, issuer string, subject string) (*model.UserIdentity, error) {
+	return nil, nil
+}
+
+func (s *ssoUsers) GetUserByUsername(ctx context.Context, username string) (*model.User, error) {
+	if username != s.user.Username {
+		return nil, nil
+	}
+	user := s.user
+	return &user, nil
+}
+
+func (s *ssoUsers) LookupUserSubscriber(ctx context.Context, user_id string, subscriber_id string) (*model.User_Subscriber, error) {
+	if user_id != s.user.ID || !s.member[subscriber_id] {
+		return nil, database.ErrNotFound
+	}
+	return &model.User_Subscriber{}, nil
+}
+
+func (s *ssoUsers) CreateUserIdentity(ctx context.Context, identity *model.UserIdentity) (*model.UserIdentity, error) {
+	s.linked = append(s.linked, *identity)
+	return identity, nil
+}
+
+func TestSSOUserLinking(t *testing.T) {
+	verified, unverified := true, false
+
+	tests := []struct {
+		name   string
+		claims oidc.Claims
+		member map[string]bool
+		status int // 0 when the account is linked
+	}{
+		{"verified member", oidc.Claims{Email: "Admin@Example.com", EmailVerified: &verified}, map[string]bool{"s1": true}, 0},
+		{"verified non-member", oidc.Claims{Email: "admin@example.com", EmailVerified: &verified}, map[string]bool{"s2": true}, http.StatusConflict},
+		{"unverified", oidc.Claims{Email: "admin@example.com", EmailVerified: &unverified}, map[string]bool{"s1": true}, http.StatusForbidden},
+		{"verification missing", oidc.Claims{Email: "admin@example.com"}, map[string]bool{"s1": true}, http.StatusForbidden},
+		{"preferred_username only", oidc.Claims{PreferredUsername: "admin@example.com", EmailVerified: &verified}, map[string]bool{"s1": true}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &ssoUsers{user: model.User{ID: "u1", Username: "admin@example.com"}, member: tt.member}
+			h := NewHandler(db, auth.JWTAuth{}, slog.New(slog.NewTextHandler(io.Discard, nil)), nil)
+			cfg := &model.SubscriberOIDC{
+				Subscriber_Id:   "s1",
+				Issuer:          "https://idp.example.com",
+				Allowed_Domains: []string{"example.com"},
+			}
+			tt.claims.RegisteredClaims = jwt.RegisteredClaims{Subject: "sub-1"}
+
+			user, err := h.ssoUser(context.Background(), cfg, &tt.claims)
+
+			if tt.status == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if user.ID != "u1" || len(db.linked) != 1 || db.linked[0].Subject != "sub-1" {
+					t.Fatalf("user = %+v, linked = %+v", user, db.linked)
+				}
+				return
+			}
+			var apiErr *common.Error
+			if !errors.As(err, &apiErr) || apiErr.Status != tt.status {
+				t.Fatalf("err = %v, want status %d", err, tt.status)
+			}
+			if len(db.linked) != 0 {
+				t.Fatalf("linked %+v", db.linked)
+			}
+		})
+	}
+}
@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"strings"
@@ -17,27 +16,28 @@ import (
 // Subscriber - Create, Update, Delete, Get, List
 
 func (h *Handler) CreateSubscriber(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h CreateSubscriber")
+	h.logger.DebugContext(r.Context(), "CreateSubscriber")
 	var subscriber *model.Subscriber
-	if err := json.NewDecoder(r.Body).Decode(&subscriber); err != nil {
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &subscriber) {
 		return
 	}
-	defer r.Body.Close()
 
 	subscriber.Id = uuid.New().String()
-	schema_name := fmt.Sprintf("%s_", strings.ToLower(subscriber.Name[:3]))
-	schema_name += strings.ReplaceAll(subscriber.Id, "-", "_")
+	schema_name := schemaName(subscriber)
 	subscriber.Schema_Name = schema_name
 
 	ctx := r.Context()
 	newsubscriber, err := h.db.CreateSubscriber(ctx, subscriber)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to create subscriber")
+		h.respondError(w, r, err, "Failed to create subscriber")
 		return
 	}
 
-	db := h.db.(*database.Database).DB
+	db, err := database.SQLDB(h.db)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to create subscriber schema")
+		return
+	}
 
 	schema := schema.Schema{
 		DB:             db,
@@ -47,7 +47,8 @@ func (h *Handler) CreateSubscriber(w http.ResponseWriter, r *http.Request) {
 
 	err = schema.CopySchema(ctx)
 	if err != nil {
-		fmt.Println(err.Error())
+		h.respondError(w, r, err, "Failed to create subscriber schema")
+		return
 	}
 
 	newprofile := model.Profile{
@@ -57,7 +58,8 @@ func (h *Handler) CreateSubscriber(w http.ResponseWriter, r *http.Request) {
 
 	profile, err := h.db.CreateProfile(ctx, *newsubscriber, newprofile)
 	if err != nil {
-		fmt.Println(err.Error())
+		h.respondError(w, r, err, "Failed to create subscriber profile")
+		return
 	}
 
 	newsubscriber.Profile = profile
@@ -70,70 +72,64 @@ func (h *Handler) CreateSubscriber(w http.ResponseWriter, r *http.Request) {
 		Schema_Name:   newsubscriber.Schema_Name,
 	}
 
-	h.db.CreateCustomer(ctx, &customer, newsubscriber)
+	if _, err := h.db.CreateCustomer(ctx, &customer, newsubscriber); err != nil {
+		h.respondError(w, r, err, "Failed to create subscriber customer")
+		return
+	}
 
 	common.RespondJSON(w, http.StatusCreated, newsubscriber)
 }
 
 func (h *Handler) UpdateSubscriber(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h UpdateSubscriber")
+	h.logger.DebugContext(r.Context(), "UpdateSubscriber")
 	vars := mux.Vars(r)
 	id := vars["id"]
 
 	ctx := r.Context()
 
 	var subscriber model.Subscriber
-	if err := json.NewDecoder(r.Body).Decode(&subscriber); err != nil {
-		fmt.Println(1, err.Error())
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &subscriber) {
 		return
 	}
-	defer r.Body.Close()
 
-	fmt.Println("h UpdateSubscriber", subscriber.CreatedAt, subscriber.Name)
+	h.logger.DebugContext(r.Context(), "UpdateSubscriber", "name", subscriber.Name)
 
 	currentsubscriber, err := h.db.GetSubscriber(ctx, id)
 	if err != nil {
-		fmt.Println(2, err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get subscriber")
+		h.respondError(w, r, err, "Failed to get subscriber")
 		return
 	}
 
 	if currentsubscriber == nil {
-		fmt.Println(3)
 		common.RespondError(w, http.StatusNotFound, "subscriber not found")
 		return
 	}
 
 	currentsubscriber.Name = subscriber.Name
+	currentsubscriber.MFA_Required = subscriber.MFA_Required
 
 	err = h.db.UpdateSubscriber(ctx, currentsubscriber)
 	if err != nil {
-		fmt.Println(4, err.Error())
-		common.RespondError(w, http.StatusNotFound, "Error updating subscriber")
+		h.respondError(w, r, err, "Error updating subscriber")
 		return
 	}
 
-	fmt.Println(5)
-
 	common.RespondJSON(w, http.StatusOK, subscriber)
 }
 
 func (h *Handler) DeleteSubscriber(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h DeleteSubscriber")
+	h.logger.DebugContext(r.Context(), "DeleteSubscriber")
 
 	var subscriber *model.Subscriber
-	if err := json.NewDecoder(r.Body).Decode(&subscriber); err != nil {
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &subscriber, "Id") {
 		return
 	}
-	defer r.Body.Close()
 
 	ctx := r.Context()
 
 	_, err := h.db.GetSubscriber(ctx, subscriber.Id)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get subscriber")
+		h.respondError(w, r, err, "Failed to get subscriber")
 		return
 	}
 	if subscriber == nil {
@@ -143,7 +139,7 @@ func (h *Handler) DeleteSubscriber(w http.ResponseWriter, r *http.Request) {
 
 	err = h.db.DeleteSubscriber(ctx, subscriber)
 	if err != nil {
-		common.RespondError(w, http.StatusNotFound, "Error deleting subscriber")
+		h.respondError(w, r, err, "Error deleting subscriber")
 		return
 	}
 
@@ -158,7 +154,7 @@ func (h *Handler) GetSubscriber(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	subscriber, err := h.db.GetSubscriber(ctx, id)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get subscriber")
+		h.respondError(w, r, err, "Failed to get subscriber")
 		return
 	}
 	if subscriber == nil {
@@ -172,16 +168,14 @@ func (h *Handler) GetSubscriber(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) GetSubscriberP(w http.ResponseWriter, r *http.Request) {
 
 	var subscriber *model.Subscriber
-	if err := json.NewDecoder(r.Body).Decode(&subscriber); err != nil {
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &subscriber, "Id") {
 		return
 	}
-	defer r.Body.Close()
 
 	ctx := r.Context()
 	subscriber, err := h.db.GetSubscriber(ctx, subscriber.Id)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get subscriber")
+		h.respondError(w, r, err, "Failed to get subscriber")
 		return
 	}
 	if subscriber == nil {
@@ -193,11 +187,15 @@ func (h *Handler) GetSubscriberP(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) SelectSubscribers(w http.ResponseWriter, r *http.Request) {
+	q, ok := h.listQuery(w, r, database.SubscriberList)
+	if !ok {
+		return
+	}
 
 	ctx := r.Context()
-	subscribers, err := h.db.SelectSubscribers(ctx, 100, 0)
+	subscribers, err := h.db.SelectSubscribers(ctx, q)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to list subscribers")
+		h.respondError(w, r, err, "Failed to list subscribers")
 		return
 	}
 
@@ -206,14 +204,12 @@ func (h *Handler) SelectSubscribers(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) Create_Schema(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h Create_Schema")
+	h.logger.DebugContext(r.Context(), "Create_Schema")
 
 	var subscriber *model.Subscriber
-	if err := json.NewDecoder(r.Body).Decode(&subscriber); err != nil {
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &subscriber, "Id") {
 		return
 	}
-	defer r.Body.Close()
 
 	id := subscriber.Id
 	if id == "" {
@@ -225,7 +221,7 @@ func (h *Handler) Create_Schema(w http.ResponseWriter, r *http.Request) {
 
 	subscriber, err := h.db.GetSubscriber(ctx, id)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get subscriber")
+		h.respondError(w, r, err, "Failed to get subscriber")
 		return
 	}
 	if subscriber == nil {
@@ -233,11 +229,13 @@ func (h *Handler) Create_Schema(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db := h.db.(*database.Database).DB
-
-	schema_name := fmt.Sprintf("%s_", strings.ToLower(subscriber.Name[:3]))
+	db, err := database.SQLDB(h.db)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to create subscriber schema")
+		return
+	}
 
-	schema_name += strings.ReplaceAll(subscriber.Id, "-", "_")
+	schema_name := schemaName(subscriber)
 
 	schema := schema.Schema{
 		DB:             db,
@@ -247,7 +245,28 @@ func (h *Handler) Create_Schema(w http.ResponseWriter, r *http.Request) {
 
 	err = schema.CopySchema(ctx)
 	if err != nil {
-		fmt.Println(err.Error())
+		h.respondError(w, r, err, "Failed to create subscriber schema")
+		return
 	}
 
+	common.RespondJSON(w, http.StatusCreated, subscriber)
+}
+
+// schemaName is the subscriber's schema: up to three letters of its name
+// followed by its id, e.g. "acm_6f1c..." for "Acme". Names with fewer
+// letters still give a valid identifier.
+func schemaName(subscriber *model.Subscriber) string {
+	prefix := make([]rune, 0, 3)
+	for _, r := range strings.ToLower(subscriber.Name) {
+		if r >= 'a' && r <= 'z' {
+			prefix = append(prefix, r)
+		}
+		if len(prefix) == 3 {
+			break
+		}
+	}
+	if len(prefix) == 0 {
+		prefix = []rune("sub")
+	}
+	return fmt.Sprintf("%s_%s", string(prefix), strings.ReplaceAll(subscriber.Id, "-", "_"))
 }
@@ -1,89 +1,59 @@
 package handler
 
 import (
-	"encoding/json"
-	"fmt"
 	"net/http"
-	"strconv"
 
 	"github.com/gorilla/mux"
 	common "github.com/htstinson/stinsondataapi/api/commonweb"
 	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/htstinson/stinsondataapi/api/pkg/database"
 )
 
 func (h *Handler) SelectSubscriberAddresses(w http.ResponseWriter, r *http.Request) {
 	// TODO
-	fmt.Println("h Select Subscriber Addresses")
+	h.logger.DebugContext(r.Context(), "Select Subscriber Addresses")
 
-	order := h.ValidOrder(r)
-	sort := h.ValidSort(r)
-
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-
-	// Sensible defaults if missing/zero
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 {
-		limit = 10
+	q, ok := h.listQuery(w, r, database.AddressList)
+	if !ok {
+		return
 	}
 
-	offset := (page - 1) * limit
-
-	var subcriber *model.Subscriber
-	if err := json.NewDecoder(r.Body).Decode(&subcriber); err != nil {
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	subcriber, ok := h.tenant(w, r, "")
+	if !ok {
 		return
 	}
-	defer r.Body.Close()
 
 	ctx := r.Context()
 
-	subcriber, err := h.db.GetSubscriber(ctx, subcriber.Id)
+	addresses, err := h.db.SelectSubscriberAddresses(ctx, *subcriber, q)
 	if err != nil {
-		fmt.Println(err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get subscriber")
+		h.respondError(w, r, err, "Failed to select addresses")
 		return
 	}
 
-	addresses, total, err := h.db.SelectSubscriberAddresses(ctx, *subcriber, limit, offset, sort, order)
-	if err != nil {
-		fmt.Println(err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Failed to select addresses")
-		return
-	}
-
-	common.RespondJSON2(w, http.StatusOK, map[string]any{
-		"data":  addresses,
-		"total": total,
-	})
+	common.RespondJSON(w, http.StatusOK, addresses)
 }
 
 func (h *Handler) GetSubscriberAddress(w http.ResponseWriter, r *http.Request) {
 	// TODO
-	fmt.Println("h Get Subscriber Address")
+	h.logger.DebugContext(r.Context(), "Get Subscriber Address")
 
 	var address *model.Address
-	if err := json.NewDecoder(r.Body).Decode(&address); err != nil {
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &address, "Id") {
 		return
 	}
-	defer r.Body.Close()
 
 	ctx := r.Context()
 
-	subcriber, err := h.db.GetSubscriber(ctx, address.SubscriberId)
-	if err != nil {
-		fmt.Println(err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get subscriber")
+	subcriber, ok := h.tenant(w, r, address.SubscriberId)
+	if !ok {
 		return
 	}
+	address.SubscriberId = subcriber.Id
 
-	address, err = h.db.GetSubscriberAddress(ctx, subcriber.Schema_Name, address.Id)
+	address, err := h.db.GetSubscriberAddress(ctx, subcriber.Schema_Name, address.Id)
 	if err != nil {
-		fmt.Println(err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Failed to select addresses")
+		h.respondError(w, r, err, "Failed to select addresses")
 		return
 	}
 
@@ -91,26 +61,23 @@ func (h *Handler) GetSubscriberAddress(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) UpdateSubscriberAddress(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h UpdateSubscriberAddress")
+	h.logger.DebugContext(r.Context(), "UpdateSubscriberAddress")
 	ctx := r.Context()
 
 	var address model.Address
-	if err := json.NewDecoder(r.Body).Decode(&address); err != nil {
-		fmt.Println(err.Error())
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &address) {
 		return
 	}
-	defer r.Body.Close()
 
-	subscriber, err := h.db.GetSubscriber(ctx, address.SubscriberId)
-	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get subscriber")
+	subscriber, ok := h.tenant(w, r, address.SubscriberId)
+	if !ok {
 		return
 	}
+	address.SubscriberId = subscriber.Id
 
-	err = h.db.UpdateSubscriberAddress(ctx, subscriber, address)
+	err := h.db.UpdateSubscriberAddress(ctx, subscriber, address)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to update subscriber address")
+		h.respondError(w, r, err, "Failed to update subscriber address")
 		return
 	}
 
@@ -118,26 +85,23 @@ func (h *Handler) UpdateSubscriberAddress(w http.ResponseWriter, r *http.Request
 }
 
 func (h *Handler) CreateSubscriberAddress(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h CreateSubscriberAddress")
+	h.logger.DebugContext(r.Context(), "CreateSubscriberAddress")
 	ctx := r.Context()
 
 	var address model.Address
-	if err := json.NewDecoder(r.Body).Decode(&address); err != nil {
-		fmt.Println(err.Error())
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &address) {
 		return
 	}
-	defer r.Body.Close()
 
-	subscriber, err := h.db.GetSubscriber(ctx, address.SubscriberId)
-	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get subscriber")
+	subscriber, ok := h.tenant(w, r, address.SubscriberId)
+	if !ok {
 		return
 	}
+	address.SubscriberId = subscriber.Id
 
-	err = h.db.CreateSubscriberAddress(ctx, subscriber, address)
+	err := h.db.CreateSubscriberAddress(ctx, subscriber, address)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to update subscriber address")
+		h.respondError(w, r, err, "Failed to update subscriber address")
 		return
 	}
 
@@ -145,7 +109,7 @@ func (h *Handler) CreateSubscriberAddress(w http.ResponseWriter, r *http.Request
 }
 
 func (h *Handler) DeleteSubscriberAddress(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h Delete Subscriber Address")
+	h.logger.DebugContext(r.Context(), "Delete Subscriber Address")
 
 	vars := mux.Vars(r)
 	subscriber_id := vars["subscriber_id"]
@@ -153,19 +117,14 @@ func (h *Handler) DeleteSubscriberAddress(w http.ResponseWriter, r *http.Request
 
 	ctx := r.Context()
 
-	subscriber, err := h.db.GetSubscriber(ctx, subscriber_id)
-	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get subscriber")
-		return
-	}
-	if subscriber == nil {
-		common.RespondError(w, http.StatusNotFound, "Subscriber not found")
+	subscriber, ok := h.tenant(w, r, subscriber_id)
+	if !ok {
 		return
 	}
 
-	err = h.db.DeleteSubscriberAddress(ctx, subscriber.Schema_Name, address_id)
+	err := h.db.DeleteSubscriberAddress(ctx, subscriber.Schema_Name, address_id)
 	if err != nil {
-		common.RespondError(w, http.StatusNotFound, "Error deleting subscriber address")
+		h.respondError(w, r, err, "Error deleting subscriber address")
 		return
 	}
 
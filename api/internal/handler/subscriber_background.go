@@ -1,90 +1,59 @@
 package handler
 
 import (
-	"encoding/json"
-	"fmt"
 	"net/http"
-	"strconv"
 
 	"github.com/gorilla/mux"
 	common "github.com/htstinson/stinsondataapi/api/commonweb"
 	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/htstinson/stinsondataapi/api/pkg/database"
 )
 
 func (h *Handler) SelectSubscriberBackgrounds(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h Select Subscriber Backgrounds")
+	h.logger.DebugContext(r.Context(), "Select Subscriber Backgrounds")
 
-	order := h.ValidOrder(r)
-	sort := h.ValidSort(r)
-
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 {
-		limit = 10
+	q, ok := h.listQuery(w, r, database.BackgroundList)
+	if !ok {
+		return
 	}
 
-	offset := (page - 1) * limit
-
-	var subscriber *model.Subscriber
-	if err := json.NewDecoder(r.Body).Decode(&subscriber); err != nil {
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	subscriber, ok := h.tenant(w, r, "")
+	if !ok {
 		return
 	}
-	defer r.Body.Close()
 
 	ctx := r.Context()
 
-	fmt.Println("subscriber.Id", subscriber.Id)
-
-	subscriber, err := h.db.GetSubscriber(ctx, subscriber.Id)
-	if err != nil {
-		fmt.Println(err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get subscriber")
-		return
-	}
-
-	backgrounds, total, err := h.db.SelectSubscriberBackgrounds(ctx, *subscriber, limit, offset, sort, order)
+	backgrounds, err := h.db.SelectSubscriberBackgrounds(ctx, *subscriber, q)
 	if err != nil {
-		fmt.Println(err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Failed to select backgrounds")
+		h.respondError(w, r, err, "Failed to select backgrounds")
 		return
 	}
 
-	common.RespondJSON2(w, http.StatusOK, map[string]any{
-		"data":  backgrounds,
-		"total": total,
-	})
+	common.RespondJSON(w, http.StatusOK, backgrounds)
 }
 
 func (h *Handler) GetSubscriberBackground(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h Get Subscriber Background")
+	h.logger.DebugContext(r.Context(), "Get Subscriber Background")
 
 	var background *model.Background
-	if err := json.NewDecoder(r.Body).Decode(&background); err != nil {
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &background, "Id") {
 		return
 	}
-	defer r.Body.Close()
 
 	ctx := r.Context()
 
-	subscriber, err := h.db.GetSubscriber(ctx, background.SubscriberId)
-	if err != nil {
-		fmt.Println(err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get subscriber")
+	subscriber, ok := h.tenant(w, r, background.SubscriberId)
+	if !ok {
 		return
 	}
+	background.SubscriberId = subscriber.Id
 
-	fmt.Println("schema", subscriber.Schema_Name)
-	fmt.Println("background.Id", background.Id)
-	background, err = h.db.GetSubscriberBackground(ctx, subscriber.Schema_Name, background.Id)
+	h.logger.DebugContext(ctx, "GetSubscriberBackground", "schema", subscriber.Schema_Name)
+	h.logger.DebugContext(ctx, "GetSubscriberBackground", "background_id", background.Id)
+	background, err := h.db.GetSubscriberBackground(ctx, subscriber.Schema_Name, background.Id)
 	if err != nil {
-		fmt.Println(err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get background")
+		h.respondError(w, r, err, "Failed to get background")
 		return
 	}
 
@@ -92,26 +61,23 @@ func (h *Handler) GetSubscriberBackground(w http.ResponseWriter, r *http.Request
 }
 
 func (h *Handler) UpdateSubscriberBackground(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h UpdateSubscriberBackground")
+	h.logger.DebugContext(r.Context(), "UpdateSubscriberBackground")
 	ctx := r.Context()
 
 	var background model.Background
-	if err := json.NewDecoder(r.Body).Decode(&background); err != nil {
-		fmt.Println(err.Error())
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &background) {
 		return
 	}
-	defer r.Body.Close()
 
-	subscriber, err := h.db.GetSubscriber(ctx, background.SubscriberId)
-	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get subscriber")
+	subscriber, ok := h.tenant(w, r, background.SubscriberId)
+	if !ok {
 		return
 	}
+	background.SubscriberId = subscriber.Id
 
-	err = h.db.UpdateSubscriberBackground(ctx, subscriber, background)
+	err := h.db.UpdateSubscriberBackground(ctx, subscriber, background)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to update subscriber background")
+		h.respondError(w, r, err, "Failed to update subscriber background")
 		return
 	}
 
@@ -119,26 +85,23 @@ func (h *Handler) UpdateSubscriberBackground(w http.ResponseWriter, r *http.Requ
 }
 
 func (h *Handler) CreateSubscriberBackground(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h CreateSubscriberBackground")
+	h.logger.DebugContext(r.Context(), "CreateSubscriberBackground")
 	ctx := r.Context()
 
 	var background model.Background
-	if err := json.NewDecoder(r.Body).Decode(&background); err != nil {
-		fmt.Println(err.Error())
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &background) {
 		return
 	}
-	defer r.Body.Close()
 
-	subscriber, err := h.db.GetSubscriber(ctx, background.SubscriberId)
-	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get subscriber")
+	subscriber, ok := h.tenant(w, r, background.SubscriberId)
+	if !ok {
 		return
 	}
+	background.SubscriberId = subscriber.Id
 
-	err = h.db.CreateSubscriberBackground(ctx, subscriber, background)
+	err := h.db.CreateSubscriberBackground(ctx, subscriber, background)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to create subscriber background")
+		h.respondError(w, r, err, "Failed to create subscriber background")
 		return
 	}
 
@@ -146,7 +109,7 @@ func (h *Handler) CreateSubscriberBackground(w http.ResponseWriter, r *http.Requ
 }
 
 func (h *Handler) DeleteSubscriberBackground(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h Delete Subscriber Background")
+	h.logger.DebugContext(r.Context(), "Delete Subscriber Background")
 
 	vars := mux.Vars(r)
 	subscriber_id := vars["subscriber_id"]
@@ -154,19 +117,14 @@ func (h *Handler) DeleteSubscriberBackground(w http.ResponseWriter, r *http.Requ
 
 	ctx := r.Context()
 
-	subscriber, err := h.db.GetSubscriber(ctx, subscriber_id)
-	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get subscriber")
-		return
-	}
-	if subscriber == nil {
-		common.RespondError(w, http.StatusNotFound, "Subscriber not found")
+	subscriber, ok := h.tenant(w, r, subscriber_id)
+	if !ok {
 		return
 	}
 
-	err = h.db.DeleteSubscriberBackground(ctx, subscriber.Schema_Name, background_id)
+	err := h.db.DeleteSubscriberBackground(ctx, subscriber.Schema_Name, background_id)
 	if err != nil {
-		common.RespondError(w, http.StatusNotFound, "Error deleting subscriber background")
+		h.respondError(w, r, err, "Error deleting subscriber background")
 		return
 	}
 
@@ -1,25 +1,29 @@
 package handler
 
 import (
-	"encoding/json"
-	"fmt"
 	"net/http"
 
 	"github.com/gorilla/mux"
 	common "github.com/htstinson/stinsondataapi/api/commonweb"
 	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/htstinson/stinsondataapi/api/pkg/database"
 )
 
 func (h *Handler) SelectSubscriberItemView(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h SelectSubscriberItemView")
+	h.logger.DebugContext(r.Context(), "SelectSubscriberItemView")
+
+	q, ok := h.listQuery(w, r, database.SubscriberItemViewList)
+	if !ok {
+		return
+	}
 
 	vars := mux.Vars(r)
 	id := vars["id"]
 
 	ctx := r.Context()
-	subscriber_item_views, err := h.db.SelectSubscriberItemView(ctx, id, 100, 0)
+	subscriber_item_views, err := h.db.SelectSubscriberItemView(ctx, id, q)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to select user_subscriber_view")
+		h.respondError(w, r, err, "Failed to select user_subscriber_view")
 		return
 	}
 
@@ -28,35 +32,29 @@ func (h *Handler) SelectSubscriberItemView(w http.ResponseWriter, r *http.Reques
 }
 
 func (h *Handler) CreateSubscriberItem(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h CreateSubscriberItem")
+	h.logger.DebugContext(r.Context(), "CreateSubscriberItem")
 
 	var subscriber_item *model.Subscriber_Item
-	if err := json.NewDecoder(r.Body).Decode(&subscriber_item); err != nil {
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &subscriber_item) {
 		return
 	}
-	defer r.Body.Close()
 
 	ctx := r.Context()
 
 	_, err := h.db.LookupSubscriberItem(ctx, subscriber_item.Item_ID, subscriber_item.Subscriber_Id)
-	if err != nil {
-		if err.Error() == "not found" {
-			fmt.Println("subscriber item not found")
-			// do nothing
-		} else {
-			fmt.Println(err.Error())
-			fmt.Println("duplicate subscriber item")
-			return
-		}
+	if err == nil {
+		common.RespondError(w, http.StatusConflict, "Subscriber item already exists")
+		return
+	}
+	if !database.IsNotFound(err) {
+		h.respondError(w, r, err, "Failed to look up subscriber_item")
+		return
 	}
-
-	fmt.Println("ok")
 
 	new_user_subscriber, err := h.db.CreateSubscriberItem(ctx, subscriber_item.Item_ID, subscriber_item.Subscriber_Id)
 	if err != nil {
-		fmt.Println("Could not create subscriber_item")
-		common.RespondError(w, http.StatusInternalServerError, "Failed to create subscriber_item")
+		h.logger.DebugContext(ctx, "Could not create subscriber_item")
+		h.respondError(w, r, err, "Failed to create subscriber_item")
 		return
 	}
 
@@ -64,18 +62,16 @@ func (h *Handler) CreateSubscriberItem(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) DeleteSubscriberItem(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h DeleteSubscriberItem")
+	h.logger.DebugContext(r.Context(), "DeleteSubscriberItem")
 
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	fmt.Println(id)
-
 	ctx := r.Context()
 
 	subscriberitem, err := h.db.GetSubscriberItem(ctx, id)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get subscriber_item")
+		h.respondError(w, r, err, "Failed to get subscriber_item")
 		return
 	}
 	if subscriberitem == nil {
@@ -85,7 +81,7 @@ func (h *Handler) DeleteSubscriberItem(w http.ResponseWriter, r *http.Request) {
 
 	err = h.db.DeleteSubscriberItem(ctx, id)
 	if err != nil {
-		common.RespondError(w, http.StatusNotFound, "Error deleting subscriber_item")
+		h.respondError(w, r, err, "Error deleting subscriber_item")
 		return
 	}
 
@@ -95,7 +91,7 @@ func (h *Handler) DeleteSubscriberItem(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) GetSubscriberItem(w http.ResponseWriter, r *http.Request) {
 
-	fmt.Println("h GetSubscriberItem")
+	h.logger.DebugContext(r.Context(), "GetSubscriberItem")
 
 	vars := mux.Vars(r)
 	id := vars["id"]
@@ -103,7 +99,7 @@ func (h *Handler) GetSubscriberItem(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	subscriberitem, err := h.db.GetSubscriberItem(ctx, id)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get subscriberitem")
+		h.respondError(w, r, err, "Failed to get subscriberitem")
 		return
 	}
 	if subscriberitem == nil {
@@ -1,156 +1,149 @@
 package handler
 
 import (
-	"encoding/json"
-	"fmt"
 	"net/http"
-	"strconv"
 
 	"github.com/gorilla/mux"
 	common "github.com/htstinson/stinsondataapi/api/commonweb"
 	"github.com/htstinson/stinsondataapi/api/internal/auth"
 	"github.com/htstinson/stinsondataapi/api/internal/model"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/htstinson/stinsondataapi/api/pkg/database"
 )
 
 // User - Create, Update, Delete, Get, List
 
+// CreateUser creates a user with the password given or, without one, with a
+// random password and emails the user a link to choose their own.
 func (h *Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
-	var user *model.User
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	var req model.CreateUserRequest
+	if !h.decode(w, r, &req) {
 		return
 	}
-	defer r.Body.Close()
+	req.Username = model.NormalizeUsername(req.Username)
 
-	password := "password"
+	password := req.Password
+	if password != "" {
+		if !h.checkPassword(w, r, password, req.Username) {
+			return
+		}
+	} else {
+		var err error
+		if password, err = randomToken(); err != nil {
+			h.respondError(w, r, err, "Failed to create password")
+			return
+		}
+	}
 
 	ctx := r.Context()
-	user, err := h.db.CreateUser(ctx, user.Username, password)
+	user, err := h.db.CreateUser(ctx, req.Username, password)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to create user")
+		h.respondError(w, r, err, "Failed to create user")
 		return
 	}
 
+	if req.Password == "" {
+		if err := h.sendPasswordReset(ctx, user, "An account has been created for you."); err != nil {
+			h.logger.ErrorContext(ctx, "CreateUser", "error", err)
+		}
+	}
+
 	common.RespondJSON(w, http.StatusCreated, user)
 }
 
 func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h UpdateUser")
+	h.logger.DebugContext(r.Context(), "UpdateUser")
 	vars := mux.Vars(r)
 	id := vars["id"]
 
 	ctx := r.Context()
 
 	var user model.User
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		fmt.Println(1, err.Error())
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &user) {
 		return
 	}
-	defer r.Body.Close()
 
-	fmt.Println("h UpdateUser", user.CreatedAt, user.Username, user.IP_address)
+	h.logger.DebugContext(r.Context(), "UpdateUser", "username", user.Username)
 
 	currentuser, err := h.db.GetUser(ctx, id)
 	if err != nil {
-		fmt.Println(2, err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get user")
+		h.respondError(w, r, err, "Failed to get user")
 		return
 	}
 
 	if currentuser == nil {
-		fmt.Println(3)
 		common.RespondError(w, http.StatusNotFound, "User not found")
 		return
 	}
 
-	currentuser.Username = user.Username
+	currentuser.Username = model.NormalizeUsername(user.Username)
 	currentuser.IP_address = user.IP_address
 	err = h.db.UpdateUser(ctx, currentuser)
 	if err != nil {
-		fmt.Println(4, err.Error())
-		common.RespondError(w, http.StatusNotFound, "Error updating user")
+		h.respondError(w, r, err, "Error updating user")
 		return
 	}
 
-	fmt.Println(5)
-
 	common.RespondJSON(w, http.StatusOK, user)
 }
 
 func (h *Handler) UpdatePassword(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h UpdatePassword")
+	h.logger.DebugContext(r.Context(), "UpdatePassword")
 	vars := mux.Vars(r)
 	id := vars["id"]
 
 	var password struct {
-		Password string
+		Password string `json:"password" validate:"required,max=72"`
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&password); err != nil {
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &password) {
 		return
 	}
-	defer r.Body.Close()
 
 	ctx := r.Context()
 
 	user, err := h.db.GetUser(ctx, id)
 	if err != nil {
-		fmt.Println(1, err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get user")
+		h.respondError(w, r, err, "Failed to get user")
 		return
 	}
 
 	if user == nil {
-		fmt.Println(2)
 		common.RespondError(w, http.StatusNotFound, "User not found")
 		return
 	}
 
-	hash, err := bcrypt.GenerateFromPassword([]byte(password.Password), bcrypt.DefaultCost)
-	if err != nil {
-		fmt.Println(3, err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Failed to hash password")
+	if !h.checkPassword(w, r, password.Password, user.Username) {
+		return
 	}
 
-	user.PasswordHash = string(hash)
-
-	err = h.db.UpdateUser(ctx, user)
+	err = h.setPassword(ctx, user, password.Password)
 	if err != nil {
-		fmt.Println(4, err.Error())
-		common.RespondError(w, http.StatusNotFound, "Error updating user")
+		h.respondError(w, r, err, "Error updating user")
 		return
 	}
-
-	fmt.Println(6)
+	h.notifyPasswordChanged(ctx, user)
 
 	common.RespondJSON(w, http.StatusOK, user)
 }
 
 func (h *Handler) GetUserByUserName(w http.ResponseWriter, r *http.Request) {
 
-	fmt.Println("h GetUserByUserName")
+	h.logger.DebugContext(r.Context(), "GetUserByUserName")
 
 	var user *model.User
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &user, "Username") {
 		return
 	}
-	defer r.Body.Close()
 
 	ctx := r.Context()
 
 	user, err := h.db.GetUserByUsername(ctx, user.Username)
 	if err != nil {
-		fmt.Println(2, err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get user")
+		h.respondError(w, r, err, "Failed to get user")
 		return
 	}
 
 	if user == nil {
-		fmt.Println(3)
 		common.RespondError(w, http.StatusNotFound, "Item not found")
 		return
 	}
@@ -166,7 +159,7 @@ func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 
 	user, err := h.db.GetUser(ctx, id)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get user")
+		h.respondError(w, r, err, "Failed to get user")
 		return
 	}
 	if user == nil {
@@ -176,7 +169,7 @@ func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 
 	err = h.db.DeleteUser(ctx, id)
 	if err != nil {
-		common.RespondError(w, http.StatusNotFound, "Error deleting user")
+		h.respondError(w, r, err, "Error deleting user")
 		return
 	}
 
@@ -186,7 +179,7 @@ func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) {
 
-	fmt.Println("h GetUser (no parms)")
+	h.logger.DebugContext(r.Context(), "GetUser")
 
 	vars := mux.Vars(r)
 	id := vars["id"]
@@ -196,7 +189,8 @@ func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) {
 	if id == "" {
 		claims, ok := ctx.Value("user").(*auth.Claims)
 		if !ok {
-			fmt.Println("Type assertion failed: anyValue is not of type auth.Claims")
+			h.logger.DebugContext(ctx, "Type assertion failed: anyValue is not of type auth.Claims")
+			common.RespondError(w, http.StatusUnauthorized, "Unauthorized")
 			return
 		}
 		id = claims.UserID
@@ -204,13 +198,11 @@ func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) {
 
 	user, err := h.db.GetUser(ctx, id)
 	if err != nil {
-		fmt.Println(2, err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get user")
+		h.respondError(w, r, err, "Failed to get user")
 		return
 	}
 
 	if user == nil {
-		fmt.Println(3)
 		common.RespondError(w, http.StatusNotFound, "Item not found")
 		return
 	}
@@ -219,46 +211,34 @@ func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) SelectUsers(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h SelectUsers")
+	h.logger.DebugContext(r.Context(), "SelectUsers")
 
-	order := h.ValidOrder(r)
-	sort := h.ValidSort(r)
-
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-
-	// Sensible defaults if missing/zero
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 {
-		limit = 10
+	q, ok := h.listQuery(w, r, database.UserList)
+	if !ok {
+		return
 	}
 
-	offset := (page - 1) * limit
-
 	ctx := r.Context()
-	users, total, err := h.db.SelectUsers(ctx, limit, offset, sort, order)
+	users, err := h.db.SelectUsers(ctx, q)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to list users")
+		h.respondError(w, r, err, "Failed to list users")
 		return
 	}
 
-	//common.RespondJSON(w, http.StatusOK, items)
-
-	common.RespondJSON2(w, http.StatusOK, map[string]any{
-		"data":  users,
-		"total": total,
-	})
+	common.RespondJSON(w, http.StatusOK, users)
 
 }
 
 func (h *Handler) SelectUserRoles(w http.ResponseWriter, r *http.Request) {
+	q, ok := h.listQuery(w, r, database.UserRoleList)
+	if !ok {
+		return
+	}
 
 	ctx := r.Context()
-	items, err := h.db.SelectUserRoles(ctx, 100, 0)
+	items, err := h.db.SelectUserRoles(ctx, q)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to list items")
+		h.respondError(w, r, err, "Failed to list items")
 		return
 	}
 
@@ -1,22 +1,26 @@
 package handler
 
 import (
-	"encoding/json"
-	"fmt"
 	"net/http"
 
 	"github.com/gorilla/mux"
 	common "github.com/htstinson/stinsondataapi/api/commonweb"
 	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/htstinson/stinsondataapi/api/pkg/database"
 )
 
 func (h *Handler) SelectUserSubscriberView(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("SelectUserSubscriberView")
+	h.logger.DebugContext(r.Context(), "SelectUserSubscriberView")
+
+	q, ok := h.listQuery(w, r, database.UserSubscriberViewList)
+	if !ok {
+		return
+	}
 
 	ctx := r.Context()
-	user_subscriber_views, err := h.db.SelectUserSubscriberView(ctx, "", 100, 0)
+	user_subscriber_views, err := h.db.SelectUserSubscriberView(ctx, "", q)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to select user_subscriber_view")
+		h.respondError(w, r, err, "Failed to select user_subscriber_view")
 		return
 	}
 
@@ -25,16 +29,21 @@ func (h *Handler) SelectUserSubscriberView(w http.ResponseWriter, r *http.Reques
 }
 
 func (h *Handler) SelectUserSubscriberViewByUserId(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("SelectUserSubscriberViewByUserId")
+	h.logger.DebugContext(r.Context(), "SelectUserSubscriberViewByUserId")
+
+	q, ok := h.listQuery(w, r, database.UserSubscriberViewList)
+	if !ok {
+		return
+	}
 
 	ctx := r.Context()
 
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	user_subscriber_views, err := h.db.SelectUserSubscriberView(ctx, id, 100, 0)
+	user_subscriber_views, err := h.db.SelectUserSubscriberView(ctx, id, q)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to select user_subscriber_view by userid")
+		h.respondError(w, r, err, "Failed to select user_subscriber_view by userid")
 		return
 	}
 
@@ -44,7 +53,7 @@ func (h *Handler) SelectUserSubscriberViewByUserId(w http.ResponseWriter, r *htt
 
 func (h *Handler) GetUserSubscriber(w http.ResponseWriter, r *http.Request) {
 
-	fmt.Println("h GetUserSubscriber")
+	h.logger.DebugContext(r.Context(), "GetUserSubscriber")
 
 	vars := mux.Vars(r)
 	id := vars["id"]
@@ -52,7 +61,7 @@ func (h *Handler) GetUserSubscriber(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	user_subscriber, err := h.db.GetUserSubscriber(ctx, id)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get user_subscriber")
+		h.respondError(w, r, err, "Failed to get user_subscriber")
 		return
 	}
 	if user_subscriber == nil {
@@ -64,7 +73,7 @@ func (h *Handler) GetUserSubscriber(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) UpdateUserSubscriber(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h UpdateUserSubscriber")
+	h.logger.DebugContext(r.Context(), "UpdateUserSubscriber")
 
 	vars := mux.Vars(r)
 	id := vars["id"]
@@ -72,22 +81,17 @@ func (h *Handler) UpdateUserSubscriber(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	var user_subscriber = model.User_Subscriber{}
-	if err := json.NewDecoder(r.Body).Decode(&user_subscriber); err != nil {
-		fmt.Println(1, err.Error())
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &user_subscriber) {
 		return
 	}
-	defer r.Body.Close()
 
 	current_user_subscriber, err := h.db.GetUserSubscriber(ctx, id)
 	if err != nil {
-		fmt.Println(2, err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get user_subscriber")
+		h.respondError(w, r, err, "Failed to get user_subscriber")
 		return
 	}
 
 	if current_user_subscriber == nil {
-		fmt.Println(3)
 		common.RespondError(w, http.StatusNotFound, "User_Subscriber not found")
 		return
 	}
@@ -97,45 +101,37 @@ func (h *Handler) UpdateUserSubscriber(w http.ResponseWriter, r *http.Request) {
 
 	err = h.db.UpdateUserSubscriber(ctx, user_subscriber)
 	if err != nil {
-		fmt.Println(4, err.Error())
-		common.RespondError(w, http.StatusNotFound, "Error updating subscriber")
+		h.respondError(w, r, err, "Error updating subscriber")
 		return
 	}
 
-	fmt.Println(5)
-
 	common.RespondJSON(w, http.StatusOK, user_subscriber)
 }
 
 func (h *Handler) CreateUserSubscriber(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h CreateUserSubscriber")
+	h.logger.DebugContext(r.Context(), "CreateUserSubscriber")
 
 	var user_subscriber *model.User_Subscriber
-	if err := json.NewDecoder(r.Body).Decode(&user_subscriber); err != nil {
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &user_subscriber) {
 		return
 	}
-	defer r.Body.Close()
 
 	ctx := r.Context()
 
 	_, err := h.db.LookupUserSubscriber(ctx, user_subscriber.User_ID, user_subscriber.Subscriber_Id)
-	if err != nil {
-		if err.Error() == "not found" {
-			// do nothing
-		} else {
-			fmt.Println(err.Error())
-			fmt.Println("duplicate user subscriber")
-			return
-		}
+	if err == nil {
+		common.RespondError(w, http.StatusConflict, "User subscriber already exists")
+		return
+	}
+	if !database.IsNotFound(err) {
+		h.respondError(w, r, err, "Failed to look up user_subscriber")
+		return
 	}
-
-	fmt.Println("ok")
 
 	new_user_subscriber, err := h.db.CreateUserSubscriber(ctx, user_subscriber.User_ID, user_subscriber.Subscriber_Id)
 	if err != nil {
-		fmt.Println("Could not create user_subscriber")
-		common.RespondError(w, http.StatusInternalServerError, "Failed to create user_subscriber")
+		h.logger.DebugContext(ctx, "Could not create user_subscriber")
+		h.respondError(w, r, err, "Failed to create user_subscriber")
 		return
 	}
 
@@ -150,7 +146,7 @@ func (h *Handler) DeleteUserSubscriber(w http.ResponseWriter, r *http.Request) {
 
 	user_subscriber, err := h.db.GetUserSubscriber(ctx, id)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get subscriber")
+		h.respondError(w, r, err, "Failed to get subscriber")
 		return
 	}
 	if user_subscriber == nil {
@@ -160,7 +156,7 @@ func (h *Handler) DeleteUserSubscriber(w http.ResponseWriter, r *http.Request) {
 
 	err = h.db.DeleteUserSubscriber(ctx, id)
 	if err != nil {
-		common.RespondError(w, http.StatusNotFound, "Error deleting subscriber")
+		h.respondError(w, r, err, "Error deleting subscriber")
 		return
 	}
 
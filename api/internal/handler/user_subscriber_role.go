@@ -1,29 +1,31 @@
 package handler
 
 import (
-	"encoding/json"
-	"fmt"
 	"net/http"
 
 	"github.com/gorilla/mux"
 	common "github.com/htstinson/stinsondataapi/api/commonweb"
 	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/htstinson/stinsondataapi/api/pkg/database"
 )
 
 func (h *Handler) SelectUserSubscriberRoleView(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h SelectUserSubscriberRoleView")
+	h.logger.DebugContext(r.Context(), "SelectUserSubscriberRoleView")
+
+	q, ok := h.listQuery(w, r, database.UserSubscriberRoleViewList)
+	if !ok {
+		return
+	}
 
 	var user_subscriber_view *model.User_Subscriber_View
-	if err := json.NewDecoder(r.Body).Decode(&user_subscriber_view); err != nil {
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &user_subscriber_view) {
 		return
 	}
-	defer r.Body.Close()
 
 	ctx := r.Context()
-	user_customer_roles_views, err := h.db.SelectUserSubscriberRoleView(ctx, *user_subscriber_view, 100, 0)
+	user_customer_roles_views, err := h.db.SelectUserSubscriberRoleView(ctx, *user_subscriber_view, q)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to select user_customer_roles_view")
+		h.respondError(w, r, err, "Failed to select user_customer_roles_view")
 		return
 	}
 
@@ -32,38 +34,33 @@ func (h *Handler) SelectUserSubscriberRoleView(w http.ResponseWriter, r *http.Re
 }
 
 func (h *Handler) CreateUserSubscriberRole(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h CreateUserSubscriberRole")
+	h.logger.DebugContext(r.Context(), "CreateUserSubscriberRole")
 
 	var user_subscriber_role *model.User_Subscriber_Role
 
-	if err := json.NewDecoder(r.Body).Decode(&user_subscriber_role); err != nil {
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &user_subscriber_role) {
 		return
 	}
-	defer r.Body.Close()
 
 	ctx := r.Context()
 
-	fmt.Println("user_subscriber_id", user_subscriber_role.User_Subscriber_ID)
-	fmt.Println("role_id", user_subscriber_role.Role_Id)
+	h.logger.DebugContext(ctx, "CreateUserSubscriberRole", "user_subscriber_id", user_subscriber_role.User_Subscriber_ID)
+	h.logger.DebugContext(ctx, "CreateUserSubscriberRole", "role_id", user_subscriber_role.Role_Id)
 
 	_, err := h.db.LookupUserSubscriberRole(ctx, user_subscriber_role.User_Subscriber_ID, user_subscriber_role.Role_Id)
-	if err != nil {
-		if err.Error() == "not found" {
-			// do nothing
-		} else {
-			fmt.Println(err.Error())
-			fmt.Println("duplicate user subscriber role")
-			return
-		}
+	if err == nil {
+		common.RespondError(w, http.StatusConflict, "User subscriber role already exists")
+		return
+	}
+	if !database.IsNotFound(err) {
+		h.respondError(w, r, err, "Failed to look up user_subscriber_role")
+		return
 	}
-
-	fmt.Println("ok")
 
 	new_user_subscriber_role, err := h.db.CreateUserSubscriberRole(ctx, user_subscriber_role.User_Subscriber_ID, user_subscriber_role.Role_Id)
 	if err != nil {
-		fmt.Println("Could not create user_subscriber_role")
-		common.RespondError(w, http.StatusInternalServerError, "Failed to create user_subscribe_role")
+		h.logger.DebugContext(ctx, "Could not create user_subscriber_role")
+		h.respondError(w, r, err, "Failed to create user_subscribe_role")
 		return
 	}
 
@@ -71,7 +68,7 @@ func (h *Handler) CreateUserSubscriberRole(w http.ResponseWriter, r *http.Reques
 }
 
 func (h *Handler) UpdateUserSubscriberRole(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("h UpdateUserSubscriberRole")
+	h.logger.DebugContext(r.Context(), "UpdateUserSubscriberRole")
 
 	vars := mux.Vars(r)
 	id := vars["id"]
@@ -80,39 +77,31 @@ func (h *Handler) UpdateUserSubscriberRole(w http.ResponseWriter, r *http.Reques
 
 	var user_subscriber_role = model.User_Subscriber_Role{}
 
-	if err := json.NewDecoder(r.Body).Decode(&user_subscriber_role); err != nil {
-		fmt.Println(1, err.Error())
-		common.RespondError(w, http.StatusBadRequest, "Invalid request payload")
+	if !h.decode(w, r, &user_subscriber_role) {
 		return
 	}
-	defer r.Body.Close()
 
-	fmt.Println("id", user_subscriber_role.Id)
-	fmt.Println("role id", user_subscriber_role.Role_Id)
-	fmt.Println("user_subscriber_id", user_subscriber_role.User_Subscriber_ID)
+	h.logger.DebugContext(ctx, "UpdateUserSubscriberRole", "id", user_subscriber_role.Id)
+	h.logger.DebugContext(ctx, "UpdateUserSubscriberRole", "role_id", user_subscriber_role.Role_Id)
+	h.logger.DebugContext(ctx, "UpdateUserSubscriberRole", "user_subscriber_id", user_subscriber_role.User_Subscriber_ID)
 
 	current_user_subscriber_role, err := h.db.GetUserSubscriberRole(ctx, id)
 	if err != nil {
-		fmt.Println(2, err.Error())
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get user_subscriber_role")
+		h.respondError(w, r, err, "Failed to get user_subscriber_role")
 		return
 	}
 
 	if current_user_subscriber_role == nil {
-		fmt.Println(3)
 		common.RespondError(w, http.StatusNotFound, "User_Subscriber not found")
 		return
 	}
 
 	err = h.db.UpdateUserSubscriberRole(ctx, user_subscriber_role)
 	if err != nil {
-		fmt.Println(4, err.Error())
-		common.RespondError(w, http.StatusNotFound, "Error updating user subscriber role")
+		h.respondError(w, r, err, "Error updating user subscriber role")
 		return
 	}
 
-	fmt.Println(5)
-
 	common.RespondJSON(w, http.StatusOK, user_subscriber_role)
 }
 
@@ -124,7 +113,7 @@ func (h *Handler) DeleteUserSubscriberRole(w http.ResponseWriter, r *http.Reques
 
 	user_subscriber_role, err := h.db.GetUserSubscriberRole(ctx, id)
 	if err != nil {
-		common.RespondError(w, http.StatusInternalServerError, "Failed to get user_subscriber_role")
+		h.respondError(w, r, err, "Failed to get user_subscriber_role")
 		return
 	}
 	if user_subscriber_role == nil {
@@ -134,7 +123,7 @@ func (h *Handler) DeleteUserSubscriberRole(w http.ResponseWriter, r *http.Reques
 
 	err = h.db.DeleteUserSubscriberRole(ctx, id)
 	if err != nil {
-		common.RespondError(w, http.StatusNotFound, "Error deleting user_subscriber_role")
+		h.respondError(w, r, err, "Error deleting user_subscriber_role")
 		return
 	}
 
@@ -0,0 +1,159 @@
+// Package health serves the liveness and readiness probes. Readiness runs a
+// set of named component checks and reports each one with its latency.
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	common "github.com/htstinson/stinsondataapi/api/commonweb"
+)
+
+// Check reports whether a component is usable. It should return promptly
+// once ctx is done.
+type Check func(ctx context.Context) error
+
+type component struct {
+	name     string
+	critical bool
+	check    Check
+}
+
+// ComponentReport is the result of one check.
+type ComponentReport struct {
+	Status    string  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the readiness response body.
+type Report struct {
+	Status     string                     `json:"status"`
+	Time       string                     `json:"time"`
+	Components map[string]ComponentReport `json:"components"`
+}
+
+// Checker runs the readiness checks. It reports not ready while draining.
+type Checker struct {
+	timeout    time.Duration
+	components []component
+	draining   atomic.Bool
+}
+
+// New returns a Checker that gives every check at most timeout to finish.
+func New(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Add registers a check. A failing critical check makes the service not
+// ready; any other failing check only marks it degraded.
+func (c *Checker) Add(name string, critical bool, check Check) {
+	c.components = append(c.components, component{name: name, critical: critical, check: check})
+}
+
+// SetDraining flips readiness off so that load balancers stop sending new
+// requests before the server shuts down.
+func (c *Checker) SetDraining(draining bool) {
+	c.draining.Store(draining)
+}
+
+// Run executes every check concurrently and builds the report.
+func (c *Checker) Run(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	report := Report{
+		Status:     "ready",
+		Time:       time.Now().Format(time.RFC3339),
+		Components: make(map[string]ComponentReport, len(c.components)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, comp := range c.components {
+		wg.Add(1)
+		go func(comp component) {
+			defer wg.Done()
+
+			start := time.Now()
+			err := comp.check(ctx)
+			result := ComponentReport{
+				Status:    "ok",
+				Critical:  comp.critical,
+				LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				result.Status = "fail"
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			report.Components[comp.name] = result
+			mu.Unlock()
+		}(comp)
+	}
+	wg.Wait()
+
+	for _, result := range report.Components {
+		if result.Status == "ok" {
+			continue
+		}
+		if result.Critical {
+			report.Status = "not_ready"
+			break
+		}
+		report.Status = "degraded"
+	}
+
+	if c.draining.Load() {
+		report.Status = "draining"
+	}
+
+	return report
+}
+
+// Live answers 200 as long as the process is serving requests.
+func (c *Checker) Live(w http.ResponseWriter, r *http.Request) {
+	common.RespondJSON(w, http.StatusOK, map[string]string{
+		"status": "alive",
+		"time":   time.Now().Format(time.RFC3339),
+	})
+}
+
+// Ready answers 200 when ready or degraded, and 503 when a critical check
+// fails or the server is draining.
+func (c *Checker) Ready(w http.ResponseWriter, r *http.Request) {
+	report := c.Run(r.Context())
+
+	status := http.StatusOK
+	if report.Status == "not_ready" || report.Status == "draining" {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	common.RespondJSON(w, status, report)
+}
+
+// Cached wraps check so that it runs at most once per ttl, for checks that
+// call paid or rate limited services.
+func Cached(check Check, ttl time.Duration) Check {
+	var mu sync.Mutex
+	var last time.Time
+	var lastErr error
+
+	return func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if !last.IsZero() && time.Since(last) < ttl {
+			return lastErr
+		}
+		lastErr = check(ctx)
+		last = time.Now()
+		return lastErr
+	}
+}
@@ -0,0 +1,165 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/htstinson/stinsondataapi/api/internal/secrets"
+)
+
+func ok(ctx context.Context) error { return nil }
+
+func failing(ctx context.Context) error { return errors.New("down") }
+
+func ready(t *testing.T, c *Checker) (int, Report) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	c.Ready(rec, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+
+	var report Report
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("decoding report: %v", err)
+	}
+	return rec.Code, report
+}
+
+func TestReady(t *testing.T) {
+	tests := []struct {
+		name     string
+		critical Check
+		optional Check
+		draining bool
+		code     int
+		status   string
+	}{
+		{"all ok", ok, ok, false, http.StatusOK, "ready"},
+		{"optional failing", ok, failing, false, http.StatusOK, "degraded"},
+		{"critical failing", failing, ok, false, http.StatusServiceUnavailable, "not_ready"},
+		{"both failing", failing, failing, false, http.StatusServiceUnavailable, "not_ready"},
+		{"draining", ok, ok, true, http.StatusServiceUnavailable, "draining"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(time.Second)
+			c.Add("database", true, tt.critical)
+			c.Add("secrets", false, tt.optional)
+			c.SetDraining(tt.draining)
+
+			code, report := ready(t, c)
+			if code != tt.code || report.Status != tt.status {
+				t.Fatalf("got %d %q, want %d %q", code, report.Status, tt.code, tt.status)
+			}
+			if len(report.Components) != 2 || !report.Components["database"].Critical {
+				t.Fatalf("components = %+v", report.Components)
+			}
+		})
+	}
+}
+
+func TestReadyTimeout(t *testing.T) {
+	c := New(50 * time.Millisecond)
+	c.Add("slow", true, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	start := time.Now()
+	code, report := ready(t, c)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("readiness took %s", elapsed)
+	}
+	if code != http.StatusServiceUnavailable || report.Components["slow"].Error == "" {
+		t.Fatalf("got %d %+v", code, report.Components["slow"])
+	}
+}
+
+func TestCached(t *testing.T) {
+	calls := 0
+	check := Cached(func(ctx context.Context) error {
+		calls++
+		return nil
+	}, time.Hour)
+
+	for range 3 {
+		if err := check(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("check ran %d times, want 1", calls)
+	}
+}
+
+// secretsManager answers DescribeSecret like AWS Secrets Manager for the
+// secrets named.
+func secretsManager(t *testing.T, names ...string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if target := r.Header.Get("X-Amz-Target"); target != "secretsmanager.DescribeSecret" {
+			http.Error(w, "unexpected "+target, http.StatusBadRequest)
+			return
+		}
+		var input struct{ SecretId string }
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		for _, name := range names {
+			if input.SecretId == name {
+				json.NewEncoder(w).Encode(map[string]string{"Name": name, "ARN": "arn:aws:secretsmanager:us-west-2:000000000000:secret:" + name})
+				return
+			}
+		}
+		w.Header().Set("X-Amzn-ErrorType", "ResourceNotFoundException")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"__type":  "ResourceNotFoundException",
+			"message": "Secrets Manager can't find the specified secret.",
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestReadySecretsManager(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_CONFIG_FILE", "/dev/null")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", "/dev/null")
+
+	srv := secretsManager(t, "RDS/apidb")
+	provider, err := secrets.NewAWS(context.Background(), "us-west-2", srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := secrets.NewCache(provider, time.Minute)
+
+	tests := []struct {
+		secret string
+		status string
+	}{
+		{"RDS/apidb", "ready"},
+		{"RDS/missing", "degraded"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.secret, func(t *testing.T) {
+			c := New(5 * time.Second)
+			c.Add("database", true, ok)
+			c.Add("secrets", false, func(ctx context.Context) error {
+				return store.Ping(ctx, tt.secret)
+			})
+
+			code, report := ready(t, c)
+			if code != http.StatusOK || report.Status != tt.status {
+				t.Fatalf("got %d %q (%+v), want %q", code, report.Status, report.Components["secrets"], tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+// Package logging builds the structured logger used throughout the API and
+// carries per request fields (request ID, user, subscriber, route) in the
+// context so that every record logged with a request context includes them.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// New returns a logger writing JSON (or text when format is "text") at level.
+func New(w io.Writer, level slog.Level, format string) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	if strings.EqualFold(format, "text") {
+		handler = slog.NewTextHandler(w, opts)
+	} else {
+		handler = slog.NewJSONHandler(w, opts)
+	}
+
+	return slog.New(&contextHandler{Handler: handler})
+}
+
+// ParseLevel maps "debug", "info", "warn" and "error" to a level, defaulting to info.
+func ParseLevel(s string) slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(s))); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// Fields are the per request values added to every record. They are filled in
+// as the request moves through the middleware, so they are safe to update
+// after the context has been handed down.
+type Fields struct {
+	mu           sync.RWMutex
+	requestID    string
+	route        string
+	userID       string
+	subscriberID string
+}
+
+type fieldsKey struct{}
+
+// WithFields starts a new set of request fields.
+func WithFields(ctx context.Context, requestID string, route string) (context.Context, *Fields) {
+	fields := &Fields{requestID: requestID, route: route}
+	return context.WithValue(ctx, fieldsKey{}, fields), fields
+}
+
+// FieldsFrom returns the request fields in ctx, or nil.
+func FieldsFrom(ctx context.Context) *Fields {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(fieldsKey{}).(*Fields)
+	return fields
+}
+
+// SetUser records the authenticated user for the rest of the request.
+func SetUser(ctx context.Context, userID string) {
+	if fields := FieldsFrom(ctx); fields != nil {
+		fields.mu.Lock()
+		fields.userID = userID
+		fields.mu.Unlock()
+	}
+}
+
+// SetSubscriber records the subscriber the request is acting on.
+func SetSubscriber(ctx context.Context, subscriberID string) {
+	if fields := FieldsFrom(ctx); fields != nil {
+		fields.mu.Lock()
+		fields.subscriberID = subscriberID
+		fields.mu.Unlock()
+	}
+}
+
+// Attrs returns the non-empty fields as log attributes.
+func (f *Fields) Attrs() []slog.Attr {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	attrs := make([]slog.Attr, 0, 4)
+	if f.requestID != "" {
+		attrs = append(attrs, slog.String("request_id", f.requestID))
+	}
+	if f.route != "" {
+		attrs = append(attrs, slog.String("route", f.route))
+	}
+	if f.userID != "" {
+		attrs = append(attrs, slog.String("user_id", f.userID))
+	}
+	if f.subscriberID != "" {
+		attrs = append(attrs, slog.String("subscriber_id", f.subscriberID))
+	}
+	return attrs
+}
+
+// contextHandler adds the request fields and trace IDs found in the record's context.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if fields := FieldsFrom(ctx); fields != nil {
+		record.AddAttrs(fields.Attrs()...)
+	}
+	if ctx != nil {
+		if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+			record.AddAttrs(
+				slog.String("trace_id", span.TraceID().String()),
+				slog.String("span_id", span.SpanID().String()),
+			)
+		}
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
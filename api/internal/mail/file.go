@@ -0,0 +1,39 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// File writes each message to <dir>/<id>.eml instead of sending it, for
+// development and tests.
+type File struct {
+	dir string
+}
+
+func NewFile(dir string) (*File, error) {
+	if dir == "" {
+		return nil, errors.New("file mail provider needs a directory")
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("error creating mail directory: %w", err)
+	}
+	return &File{dir: dir}, nil
+}
+
+// Send writes msg and returns the file name as its id.
+func (f *File) Send(ctx context.Context, msg *Message) (string, error) {
+	raw, err := msg.Bytes()
+	if err != nil {
+		return "", err
+	}
+
+	name := filepath.Base(msg.ID) + ".eml"
+	if err := os.WriteFile(filepath.Join(f.dir, name), raw, 0o640); err != nil {
+		return "", fmt.Errorf("error writing message: %w", err)
+	}
+	return name, nil
+}
@@ -0,0 +1,48 @@
+package mail
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+
+	common "github.com/htstinson/stinsondataapi/api/commonweb"
+	"github.com/htstinson/stinsondataapi/api/internal/secrets"
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/googleapi"
+)
+
+// Gmail sends as the Gmail account whose token is stored in the secrets,
+// which fills in the sender when a message has none.
+type Gmail struct {
+	store secrets.ReadWriter
+}
+
+func NewGmail(store secrets.ReadWriter) *Gmail {
+	return &Gmail{store: store}
+}
+
+// Send sends msg and returns Gmail's id for it. Messages Gmail rejects as
+// invalid are permanent; a missing token is not, so that queued email goes
+// out once the account is authorized.
+func (g *Gmail) Send(ctx context.Context, msg *Message) (string, error) {
+	raw, err := msg.Bytes()
+	if err != nil {
+		return "", err
+	}
+
+	srv, err := common.GmailService(ctx, g.store, gmail.GmailSendScope)
+	if err != nil {
+		return "", err
+	}
+
+	sent, err := srv.Users.Messages.Send("me", &gmail.Message{Raw: base64.URLEncoding.EncodeToString(raw)}).Context(ctx).Do()
+	if err != nil {
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusBadRequest {
+			return "", Permanent(err)
+		}
+		return "", err
+	}
+	return sent.Id, nil
+}
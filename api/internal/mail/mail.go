@@ -0,0 +1,174 @@
+// Package mail sends email through SMTP, the Gmail API, AWS SES or a local
+// directory behind one interface. Email is queued in the outbox and sent by
+// a Sender that retries failures, so that no request waits on a mail server.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/htstinson/stinsondataapi/api/internal/secrets"
+)
+
+// Message is an email ready to send.
+type Message struct {
+	// ID is the outbox id, used in the Message-ID header
+	ID      string
+	From    string
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+	// Unsubscribe is the one-click unsubscribe URL of the List-Unsubscribe
+	// header, or empty for email that cannot be unsubscribed from
+	Unsubscribe string
+}
+
+// Provider sends messages, returning the id the provider gave the message
+// when it has one.
+type Provider interface {
+	Send(ctx context.Context, msg *Message) (string, error)
+}
+
+// PermanentError is a refusal that sending again will not change, such as
+// an unknown recipient, so the message is not retried.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent marks err as not worth retrying.
+func Permanent(err error) error {
+	return &PermanentError{Err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent.
+func IsPermanent(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent)
+}
+
+// Options selects and configures a provider.
+type Options struct {
+	// Provider is "smtp", "gmail", "ses" or "file".
+	Provider string
+	SMTP     SMTPOptions
+	// Region is the AWS region SES is called in.
+	Region string
+	// Dir is the directory the file provider writes to.
+	Dir string
+}
+
+// New builds the provider named in opts. The Gmail provider reads its OAuth
+// client and token from store.
+func New(ctx context.Context, opts Options, store secrets.ReadWriter) (Provider, error) {
+	switch opts.Provider {
+	case "smtp":
+		return NewSMTP(opts.SMTP)
+	case "", "gmail":
+		return NewGmail(store), nil
+	case "ses":
+		return NewSES(ctx, opts.Region)
+	case "file":
+		return NewFile(opts.Dir)
+	default:
+		return nil, fmt.Errorf("unknown mail provider %q", opts.Provider)
+	}
+}
+
+// Bytes renders msg as a MIME message: plain text, or text and HTML
+// alternatives when it has HTML.
+func (m *Message) Bytes() ([]byte, error) {
+	to, err := mail.ParseAddress(m.To)
+	if err != nil {
+		return nil, Permanent(fmt.Errorf("invalid recipient: %w", err))
+	}
+
+	var buf bytes.Buffer
+	header := func(name string, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+	}
+	if m.From != "" {
+		from, err := mail.ParseAddress(m.From)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sender: %w", err)
+		}
+		header("From", from.String())
+	}
+	header("To", to.String())
+	header("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	if m.ID != "" {
+		header("Message-ID", "<"+m.ID+"@"+messageDomain(m.From)+">")
+	}
+	if m.Unsubscribe != "" {
+		header("List-Unsubscribe", "<"+m.Unsubscribe+">")
+		header("List-Unsubscribe-Post", "List-Unsubscribe=One-Click")
+	}
+	header("MIME-Version", "1.0")
+
+	if m.HTML == "" {
+		header("Content-Type", `text/plain; charset="utf-8"`)
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		return buf.Bytes(), writeQuoted(&buf, m.Text)
+	}
+
+	parts := multipart.NewWriter(&buf)
+	header("Content-Type", `multipart/alternative; boundary="`+parts.Boundary()+`"`)
+	buf.WriteString("\r\n")
+	for _, part := range []struct{ contentType, body string }{
+		{`text/plain; charset="utf-8"`, m.Text},
+		{`text/html; charset="utf-8"`, m.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuoted(w, part.body); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeQuoted(w interface{ Write([]byte) (int, error) }, body string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// messageDomain is the domain of the sender, which Message-IDs are made
+// unique within.
+func messageDomain(from string) string {
+	if address, err := mail.ParseAddress(from); err == nil {
+		if i := strings.LastIndex(address.Address, "@"); i >= 0 {
+			return address.Address[i+1:]
+		}
+	}
+	return "stinsondataapi"
+}
@@ -0,0 +1,144 @@
+package mail
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/htstinson/stinsondataapi/api/internal/model"
+)
+
+// Outbox is the queue a Sender works through. database.Repository
+// implements it.
+type Outbox interface {
+	ClaimEmails(ctx context.Context, limit int, lease time.Duration) ([]model.Email, error)
+	MarkEmailSent(ctx context.Context, id string, provider_message_id string) error
+	MarkEmailFailed(ctx context.Context, id string, message string, retry_at *time.Time) error
+}
+
+// SenderOptions are the sender address and how hard to try. A message is
+// tried up to MaxAttempts times, waiting RetryDelay after the first failure
+// and twice as long after each one after, up to MaxRetryDelay.
+type SenderOptions struct {
+	From          string
+	MaxAttempts   int
+	RetryDelay    time.Duration
+	MaxRetryDelay time.Duration
+	// BatchSize is how many messages are claimed at a time.
+	BatchSize int
+}
+
+// Sender sends the email queued in an Outbox through a Provider.
+type Sender struct {
+	outbox   Outbox
+	provider Provider
+	opts     SenderOptions
+}
+
+// NewSender fills in defaults for the options left unset: 8 attempts, one
+// minute doubling up to six hours, and batches of 20.
+func NewSender(outbox Outbox, provider Provider, opts SenderOptions) *Sender {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 8
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = time.Minute
+	}
+	if opts.MaxRetryDelay <= 0 {
+		opts.MaxRetryDelay = 6 * time.Hour
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 20
+	}
+	return &Sender{outbox: outbox, provider: provider, opts: opts}
+}
+
+// Start sends the email that is due every interval until ctx is cancelled.
+func (s *Sender) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := s.Flush(ctx); err != nil {
+					slog.ErrorContext(ctx, "email sender", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Flush sends the email that is due now, batch by batch, and returns how
+// many were sent.
+func (s *Sender) Flush(ctx context.Context) (int, error) {
+	sent := 0
+	for {
+		// Claimed messages are not due again until the lease has passed
+		emails, err := s.outbox.ClaimEmails(ctx, s.opts.BatchSize, 10*time.Minute)
+		if err != nil {
+			return sent, err
+		}
+		for _, email := range emails {
+			if s.send(ctx, email) {
+				sent++
+			}
+		}
+		if len(emails) < s.opts.BatchSize {
+			return sent, nil
+		}
+	}
+}
+
+// send sends one claimed email and records the outcome.
+func (s *Sender) send(ctx context.Context, email model.Email) bool {
+	msg := &Message{
+		ID:          email.Id,
+		From:        s.opts.From,
+		To:          email.To,
+		Subject:     email.Subject,
+		Text:        email.Text_Body,
+		HTML:        email.Html_Body,
+		Unsubscribe: email.Unsubscribe_URL,
+	}
+
+	id, err := s.provider.Send(ctx, msg)
+	if err == nil {
+		slog.InfoContext(ctx, "email sent", "email_id", email.Id, "template", email.Template, "attempts", email.Attempts)
+		if err := s.outbox.MarkEmailSent(ctx, email.Id, id); err != nil {
+			slog.ErrorContext(ctx, "email sender", "email_id", email.Id, "error", err)
+		}
+		return true
+	}
+
+	retryAt := s.retryAt(email.Attempts)
+	if IsPermanent(err) {
+		retryAt = nil
+	}
+	if retryAt == nil {
+		slog.ErrorContext(ctx, "email failed", "email_id", email.Id, "template", email.Template, "attempts", email.Attempts, "error", err)
+	} else {
+		slog.WarnContext(ctx, "email failed, will retry", "email_id", email.Id, "template", email.Template, "attempts", email.Attempts, "retry_at", retryAt, "error", err)
+	}
+	if err := s.outbox.MarkEmailFailed(ctx, email.Id, err.Error(), retryAt); err != nil {
+		slog.ErrorContext(ctx, "email sender", "email_id", email.Id, "error", err)
+	}
+	return false
+}
+
+// retryAt is when to try again after attempts failures, or nil once they
+// are used up.
+func (s *Sender) retryAt(attempts int) *time.Time {
+	if attempts >= s.opts.MaxAttempts {
+		return nil
+	}
+	delay := s.opts.RetryDelay
+	for i := 1; i < attempts && delay < s.opts.MaxRetryDelay; i++ {
+		delay *= 2
+	}
+	at := time.Now().Add(min(delay, s.opts.MaxRetryDelay))
+	return &at
+}
@@ -0,0 +1,109 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+// SES sends through the SendEmail action of the SES v2 API, signing the
+// requests itself with the default credential chain.
+type SES struct {
+	credentials aws.CredentialsProvider
+	region      string
+	signer      *v4.Signer
+	client      *http.Client
+}
+
+// NewSES loads the AWS credentials for region.
+func NewSES(ctx context.Context, region string) (*SES, error) {
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return nil, fmt.Errorf("error loading AWS config: %w", err)
+	}
+	return &SES{
+		credentials: cfg.Credentials,
+		region:      cfg.Region,
+		signer:      v4.NewSigner(),
+		client:      &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+type sesSendEmail struct {
+	FromEmailAddress string `json:"FromEmailAddress,omitempty"`
+	Destination      struct {
+		ToAddresses []string `json:"ToAddresses"`
+	} `json:"Destination"`
+	Content struct {
+		Raw struct {
+			Data []byte `json:"Data"`
+		} `json:"Raw"`
+	} `json:"Content"`
+}
+
+// Send sends msg as raw MIME. Requests SES rejects as invalid are
+// permanent; throttling and server errors are not.
+func (s *SES) Send(ctx context.Context, msg *Message) (string, error) {
+	raw, err := msg.Bytes()
+	if err != nil {
+		return "", err
+	}
+
+	var input sesSendEmail
+	input.FromEmailAddress = msg.From
+	input.Destination.ToAddresses = []string{msg.To}
+	input.Content.Raw.Data = raw
+	body, err := json.Marshal(input)
+	if err != nil {
+		return "", err
+	}
+
+	endpoint := fmt.Sprintf("https://email.%s.amazonaws.com/v2/email/outbound-emails", s.region)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	credentials, err := s.credentials.Retrieve(ctx)
+	if err != nil {
+		return "", fmt.Errorf("error getting AWS credentials: %w", err)
+	}
+	sum := sha256.Sum256(body)
+	if err := s.signer.SignHTTP(ctx, credentials, req, hex.EncodeToString(sum[:]), "ses", s.region, time.Now()); err != nil {
+		return "", fmt.Errorf("error signing SES request: %w", err)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	reply, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("SES answered %s: %s", resp.Status, bytes.TrimSpace(reply))
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+			return "", Permanent(err)
+		}
+		return "", err
+	}
+
+	var output struct {
+		MessageId string `json:"MessageId"`
+	}
+	if err := json.Unmarshal(reply, &output); err != nil {
+		return "", fmt.Errorf("error reading SES response: %w", err)
+	}
+	return output.MessageId, nil
+}
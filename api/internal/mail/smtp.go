@@ -0,0 +1,66 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+)
+
+// SMTPOptions locate an SMTP server. Username and Password are only sent
+// when Username is set, and net/smtp only sends them over TLS or to
+// localhost. A local mail catcher such as Mailpit is an SMTP server on
+// localhost:1025 without credentials.
+type SMTPOptions struct {
+	Addr     string
+	Username string
+	Password string
+}
+
+// SMTP sends through an SMTP server, upgrading to TLS when it offers
+// STARTTLS.
+type SMTP struct {
+	opts SMTPOptions
+	host string
+}
+
+func NewSMTP(opts SMTPOptions) (*SMTP, error) {
+	host, _, err := net.SplitHostPort(opts.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("smtp mail provider needs a host:port address: %w", err)
+	}
+	return &SMTP{opts: opts, host: host}, nil
+}
+
+// Send delivers msg. Refusals with a 5xx code are permanent.
+func (s *SMTP) Send(ctx context.Context, msg *Message) (string, error) {
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return "", fmt.Errorf("smtp mail provider needs a sender: %w", err)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return "", Permanent(fmt.Errorf("invalid recipient: %w", err))
+	}
+	raw, err := msg.Bytes()
+	if err != nil {
+		return "", err
+	}
+
+	var auth smtp.Auth
+	if s.opts.Username != "" {
+		auth = smtp.PlainAuth("", s.opts.Username, s.opts.Password, s.host)
+	}
+
+	if err := smtp.SendMail(s.opts.Addr, auth, from.Address, []string{to.Address}, raw); err != nil {
+		var reply *textproto.Error
+		if errors.As(err, &reply) && reply.Code >= 500 {
+			return "", Permanent(err)
+		}
+		return "", err
+	}
+	return "", nil
+}
@@ -0,0 +1,242 @@
+package mail
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/htstinson/stinsondataapi/api/internal/model"
+)
+
+// Names of the built in templates
+const (
+	TemplateEmailVerification = "email_verification"
+	TemplatePasswordReset     = "password_reset"
+	TemplatePasswordChanged   = "password_changed"
+	TemplateAccountLocked     = "account_locked"
+	TemplateMFAEnabled        = "mfa_enabled"
+	TemplateInvitation        = "invitation"
+	TemplateLoginNotice       = "login_notice"
+	TemplateMentionAlert      = "mention_alert"
+	TemplateMentionDigest     = "mention_digest"
+)
+
+// Unsubscribable reports whether email of category can be unsubscribed
+// from. Account email never can.
+func Unsubscribable(category string) bool {
+	return category != model.EmailAccount
+}
+
+const signature = `
+If this was not you, please contact support@stinsondata.com.
+
+Thank you!`
+
+const unsubscribeFooter = `{{if .Unsubscribe}}
+
+To stop these emails, visit {{.Unsubscribe}}{{end}}`
+
+const unsubscribeFooterHTML = `{{if .Unsubscribe}}<p style="font-size:small"><a href="{{.Unsubscribe}}">Unsubscribe</a> from these emails.</p>{{end}}`
+
+// defaults are the built in templates. Their data is a map whose keys each
+// template lists in its comment, plus Unsubscribe, the unsubscribe link of
+// categories that have one.
+var defaults = []model.EmailTemplate{
+	// Link, Expires
+	{
+		Name:     TemplateEmailVerification,
+		Category: model.EmailAccount,
+		Subject:  "Thousand Hills Digital - Confirm your email",
+		Text_Body: `Thank you for registering with Thousand Hills Digital.
+
+Use this link to confirm your email before {{.Expires}}:
+
+{{.Link}}
+
+If you did not register, you can ignore this message and the account will be removed.
+
+Thank you!`,
+		Html_Body: `<p>Thank you for registering with Thousand Hills Digital.</p>
+<p>Use this link to confirm your email before {{.Expires}}:</p>
+<p><a href="{{.Link}}">Confirm your email</a></p>
+<p>If you did not register, you can ignore this message and the account will be removed.</p>
+<p>Thank you!</p>`,
+	},
+	// Reason, Link, Expires
+	{
+		Name:     TemplatePasswordReset,
+		Category: model.EmailAccount,
+		Subject:  "Thousand Hills Digital - Reset your password",
+		Text_Body: `{{.Reason}}
+
+Use this link to choose a new password before {{.Expires}}:
+
+{{.Link}}
+
+If you did not ask for this, you can ignore this message.
+
+Thank you!`,
+		Html_Body: `<p>{{.Reason}}</p>
+<p>Use this link to choose a new password before {{.Expires}}:</p>
+<p><a href="{{.Link}}">Choose a new password</a></p>
+<p>If you did not ask for this, you can ignore this message.</p>
+<p>Thank you!</p>`,
+	},
+	// Time
+	{
+		Name:      TemplatePasswordChanged,
+		Category:  model.EmailSecurity,
+		Subject:   "Thousand Hills Digital - Password changed",
+		Text_Body: `The password of your account was changed at {{.Time}}.` + "\n" + signature + unsubscribeFooter,
+	},
+	// Until
+	{
+		Name:     TemplateAccountLocked,
+		Category: model.EmailSecurity,
+		Subject:  "Thousand Hills Digital - Account locked",
+		Text_Body: `Your account was locked after repeated failed logins and can be used again after {{.Until}}.
+
+If this was not you, you can reset your password now to unlock it, and please contact support@stinsondata.com.
+
+Thank you!` + unsubscribeFooter,
+	},
+	// Time
+	{
+		Name:     TemplateMFAEnabled,
+		Category: model.EmailSecurity,
+		Subject:  "Thousand Hills Digital - Two-step verification enabled",
+		Text_Body: `Two-step verification was enabled on your account at {{.Time}}.
+
+Keep your recovery codes somewhere safe; each can be used once if you lose your authenticator.
+` + signature + unsubscribeFooter,
+	},
+	// Subscriber, Role, Link, Expires
+	{
+		Name:     TemplateInvitation,
+		Category: model.EmailAccount,
+		Subject:  "Thousand Hills Digital - You have been invited to {{.Subscriber}}",
+		Text_Body: `You have been invited to join {{.Subscriber}} on Thousand Hills Digital as {{.Role}}.
+
+Use this link to accept before {{.Expires}}:
+
+{{.Link}}
+
+If you do not have an account yet, you will choose a password when you accept. If you were not expecting this, you can ignore this message.
+
+Thank you!`,
+		Html_Body: `<p>You have been invited to join {{.Subscriber}} on Thousand Hills Digital as {{.Role}}.</p>
+<p>Use this link to accept before {{.Expires}}:</p>
+<p><a href="{{.Link}}">Accept the invitation</a></p>
+<p>If you do not have an account yet, you will choose a password when you accept. If you were not expecting this, you can ignore this message.</p>
+<p>Thank you!</p>`,
+	},
+	// Time
+	{
+		Name:      TemplateLoginNotice,
+		Category:  model.EmailLogin,
+		Subject:   "Thousand Hills Digital - Login",
+		Text_Body: `Your account logged into Thousand Hills Digital at {{.Time}}.` + "\n" + signature + unsubscribeFooter,
+		Html_Body: `<p>Your account logged into Thousand Hills Digital at {{.Time}}.</p>
+<p>If this was not you, please contact support@stinsondata.com.</p>
+<p>Thank you!</p>` + unsubscribeFooterHTML,
+	},
+	// Search, Count, Subscriber
+	{
+		Name:     TemplateMentionAlert,
+		Category: model.EmailMention,
+		Subject:  "Thousand Hills Digital - {{.Count}} new results for {{.Search}}",
+		Text_Body: `The search {{.Search}} of {{.Subscriber}} found {{.Count}} new results.
+
+Thank you!` + unsubscribeFooter,
+		Html_Body: `<p>The search {{.Search}} of {{.Subscriber}} found {{.Count}} new results.</p>
+<p>Thank you!</p>` + unsubscribeFooterHTML,
+	},
+	// Subscriber, Frequency, Since, Count, Mentions (Search, Title, Link), More
+	{
+		Name:     TemplateMentionDigest,
+		Category: model.EmailMention,
+		Subject:  "Thousand Hills Digital - Your {{.Frequency}} digest for {{.Subscriber}}",
+		Text_Body: `{{.Count}} new results were found for {{.Subscriber}} since {{.Since}}.
+{{range .Mentions}}
+{{.Title}} ({{.Search}})
+{{.Link}}
+{{end}}{{if .More}}
+And {{.More}} more.
+{{end}}
+Thank you!` + unsubscribeFooter,
+		Html_Body: `<p>{{.Count}} new results were found for {{.Subscriber}} since {{.Since}}.</p>
+<ul>{{range .Mentions}}
+<li><a href="{{.Link}}">{{.Title}}</a> ({{.Search}})</li>{{end}}
+</ul>{{if .More}}
+<p>And {{.More}} more.</p>{{end}}
+<p>Thank you!</p>` + unsubscribeFooterHTML,
+	},
+}
+
+// Templates returns the built in templates.
+func Templates() []model.EmailTemplate {
+	return slices.Clone(defaults)
+}
+
+// Template returns the built in template called name.
+func Template(name string) (model.EmailTemplate, bool) {
+	i := slices.IndexFunc(defaults, func(t model.EmailTemplate) bool { return t.Name == name })
+	if i < 0 {
+		return model.EmailTemplate{}, false
+	}
+	return defaults[i], true
+}
+
+// Render executes t with data. The HTML body is an html/template, so that
+// data is escaped in it.
+func Render(t model.EmailTemplate, data any) (subject string, text string, html string, err error) {
+	if subject, err = renderText(t.Name+".subject", t.Subject, data); err != nil {
+		return "", "", "", err
+	}
+	// Subjects are one line, whatever the data held
+	subject = strings.Join(strings.Fields(subject), " ")
+
+	if text, err = renderText(t.Name+".text", t.Text_Body, data); err != nil {
+		return "", "", "", err
+	}
+
+	if t.Html_Body != "" {
+		tmpl, err := htmltemplate.New(t.Name + ".html").Parse(t.Html_Body)
+		if err != nil {
+			return "", "", "", err
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return "", "", "", err
+		}
+		html = buf.String()
+	}
+	return subject, text, html, nil
+}
+
+// Parse checks that the templates of t parse, before a subscriber's own is
+// saved.
+func Parse(t model.EmailTemplate) error {
+	if _, err := template.New("subject").Parse(t.Subject); err != nil {
+		return err
+	}
+	if _, err := template.New("text_body").Parse(t.Text_Body); err != nil {
+		return err
+	}
+	_, err := htmltemplate.New("html_body").Parse(t.Html_Body)
+	return err
+}
+
+func renderText(name string, text string, data any) (string, error) {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
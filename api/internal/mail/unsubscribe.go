@@ -0,0 +1,57 @@
+package mail
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// ErrInvalidUnsubscribe is returned for an unsubscribe token that was not
+// issued with the key.
+var ErrInvalidUnsubscribe = errors.New("invalid unsubscribe token")
+
+// UnsubscribeKey derives the key unsubscribe tokens are signed with from
+// secret, so that it differs from any other use of the secret.
+func UnsubscribeKey(secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("unsubscribe"))
+	return mac.Sum(nil)
+}
+
+// UnsubscribeToken returns the token of a link that unsubscribes email from
+// category. It does not expire, since links in old email must keep working,
+// and only changes with key.
+func UnsubscribeToken(key []byte, email string, category string) string {
+	payload := strings.ToLower(email) + "\n" + category
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + base64.RawURLEncoding.EncodeToString(sign(key, payload))
+}
+
+// ParseUnsubscribeToken returns the email and category of a token made by
+// UnsubscribeToken with key.
+func ParseUnsubscribeToken(key []byte, token string) (email string, category string, err error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", "", ErrInvalidUnsubscribe
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", "", ErrInvalidUnsubscribe
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, sign(key, string(payload))) {
+		return "", "", ErrInvalidUnsubscribe
+	}
+	email, category, ok = strings.Cut(string(payload), "\n")
+	if !ok || email == "" || category == "" {
+		return "", "", ErrInvalidUnsubscribe
+	}
+	return email, category, nil
+}
+
+func sign(key []byte, payload string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
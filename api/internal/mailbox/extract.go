@@ -0,0 +1,231 @@
+package mailbox
+
+import (
+	"encoding/base64"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"google.golang.org/api/gmail/v1"
+)
+
+// maxSnippet is the most runes of text kept after a link.
+const maxSnippet = 1000
+
+// Mention is a link found in a message, with the text of the link as its
+// title and the text that follows it, up to the next link, as its snippet.
+type Mention struct {
+	Link    string
+	Title   string
+	Snippet string
+}
+
+// Extract returns the mentions in msg, read from its HTML part or, when it
+// has none, its text part. Links through Google's redirector are followed
+// to where they lead, links back to Google, such as those that edit or
+// unsubscribe from an alert, are left out, and each link is returned once.
+func Extract(msg *gmail.Message) []Mention {
+	htmlBody, textBody := bodies(msg.Payload)
+	if htmlBody != "" {
+		return extractHTML(htmlBody)
+	}
+	return extractText(textBody)
+}
+
+// bodies returns the first HTML and text parts of part.
+func bodies(part *gmail.MessagePart) (htmlBody string, textBody string) {
+	if part == nil {
+		return "", ""
+	}
+	switch part.MimeType {
+	case "text/html":
+		return decodeBody(part.Body), ""
+	case "text/plain":
+		return "", decodeBody(part.Body)
+	}
+	for _, p := range part.Parts {
+		h, t := bodies(p)
+		if htmlBody == "" {
+			htmlBody = h
+		}
+		if textBody == "" {
+			textBody = t
+		}
+	}
+	return htmlBody, textBody
+}
+
+// decodeBody decodes Gmail's base64url data, which may or may not be
+// padded.
+func decodeBody(body *gmail.MessagePartBody) string {
+	if body == nil {
+		return ""
+	}
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(body.Data, "="))
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func extractHTML(body string) []Mention {
+	var (
+		mentions []Mention
+		seen     = map[string]bool{}
+		anchor   *Mention // the result link being read
+		current  *Mention // the result whose snippet is being read
+		title    strings.Builder
+		snippet  strings.Builder
+		skip     int // depth inside style and script
+	)
+
+	finish := func() {
+		if current != nil && !seen[current.Link] {
+			seen[current.Link] = true
+			current.Snippet = truncate(clean(snippet.String()), maxSnippet)
+			mentions = append(mentions, *current)
+		}
+		current = nil
+		snippet.Reset()
+	}
+
+	z := html.NewTokenizer(strings.NewReader(body))
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			finish()
+			return mentions
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			switch string(name) {
+			case "style", "script":
+				if tt == html.StartTagToken {
+					skip++
+				}
+			case "a":
+				// Any link ends the snippet of the one before
+				finish()
+				anchor = nil
+				title.Reset()
+				for hasAttr {
+					var key, val []byte
+					key, val, hasAttr = z.TagAttr()
+					if string(key) == "href" {
+						if link, ok := resultLink(string(val)); ok {
+							anchor = &Mention{Link: link}
+						}
+					}
+				}
+			case "br", "p", "div", "td", "li", "tr":
+				title.WriteByte(' ')
+				snippet.WriteByte(' ')
+			}
+
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "style", "script":
+				skip = max(skip-1, 0)
+			case "a":
+				if anchor != nil {
+					anchor.Title = clean(title.String())
+					if anchor.Title != "" {
+						current = anchor
+					}
+					anchor = nil
+				}
+			}
+
+		case html.TextToken:
+			if skip > 0 {
+				continue
+			}
+			if anchor != nil {
+				title.Write(z.Text())
+			} else if current != nil {
+				snippet.Write(z.Text())
+			}
+		}
+	}
+}
+
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"]+`)
+
+// extractText reads mentions from a text body, where each paragraph with a
+// link is taken as a result: its first line without a link is the title
+// and the rest is the snippet.
+func extractText(body string) []Mention {
+	var mentions []Mention
+	seen := map[string]bool{}
+
+	body = strings.ReplaceAll(body, "\r\n", "\n")
+	for _, paragraph := range strings.Split(body, "\n\n") {
+		var link string
+		var lines []string
+		for _, line := range strings.Split(paragraph, "\n") {
+			found := urlPattern.FindString(line)
+			if found == "" {
+				if line = clean(line); line != "" {
+					lines = append(lines, line)
+				}
+				continue
+			}
+			if resolved, ok := resultLink(found); ok && link == "" {
+				link = resolved
+			}
+		}
+		if link == "" || len(lines) == 0 || seen[link] {
+			continue
+		}
+		seen[link] = true
+		mentions = append(mentions, Mention{
+			Link:    link,
+			Title:   lines[0],
+			Snippet: truncate(strings.Join(lines[1:], " "), maxSnippet),
+		})
+	}
+	return mentions
+}
+
+// resultLink returns where href leads, following Google's redirector, and
+// whether it is a result rather than a link back to Google.
+func resultLink(href string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return "", false
+	}
+	if isGoogle(u.Hostname()) && u.Path == "/url" {
+		target := u.Query().Get("url")
+		if target == "" {
+			target = u.Query().Get("q")
+		}
+		if u, err = url.Parse(target); err != nil {
+			return "", false
+		}
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || isGoogle(u.Hostname()) {
+		return "", false
+	}
+	return u.String(), true
+}
+
+func isGoogle(host string) bool {
+	host = strings.ToLower(host)
+	return host == "google.com" || strings.HasSuffix(host, ".google.com")
+}
+
+// clean collapses runs of white space.
+func clean(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return strings.TrimSpace(string(runes[:n-1])) + "…"
+}
@@ -0,0 +1,263 @@
+// Package gmailfake is an in-memory stand-in for the parts of the Gmail API
+// the mailbox poller uses: listing labels, listing a label's messages and
+// reading a message. Serve it with net/http or httptest and point a
+// gmail.Service at it with option.WithEndpoint and
+// option.WithoutAuthentication.
+package gmailfake
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/mail"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/gmail/v1"
+)
+
+// Server holds the labels and messages of one mailbox and answers for any
+// user id.
+type Server struct {
+	mu       sync.Mutex
+	labels   []*gmail.Label
+	messages []*gmail.Message
+	mux      *http.ServeMux
+}
+
+func New() *Server {
+	s := &Server{mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /gmail/v1/users/{user}/labels", s.listLabels)
+	s.mux.HandleFunc("GET /gmail/v1/users/{user}/messages", s.listMessages)
+	s.mux.HandleFunc("GET /gmail/v1/users/{user}/messages/{id}", s.getMessage)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// AddLabel adds a user label, unless there is one called name, and returns
+// its id.
+func (s *Server) AddLabel(name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.label(name)
+}
+
+func (s *Server) label(name string) string {
+	for _, label := range s.labels {
+		if strings.EqualFold(label.Name, name) {
+			return label.Id
+		}
+	}
+	id := fmt.Sprintf("Label_%d", len(s.labels)+1)
+	s.labels = append(s.labels, &gmail.Label{Id: id, Name: name, Type: "user"})
+	return id
+}
+
+// AddMessage adds a message received at the time with an HTML and text
+// body, either of which may be empty, filed under the labels named, which
+// are added as needed. It returns the message's id.
+func (s *Server) AddMessage(received time.Time, subject string, htmlBody string, textBody string, labels ...string) string {
+	var parts []*gmail.MessagePart
+	if textBody != "" {
+		parts = append(parts, bodyPart("text/plain", textBody))
+	}
+	if htmlBody != "" {
+		parts = append(parts, bodyPart("text/html", htmlBody))
+	}
+
+	payload := &gmail.MessagePart{MimeType: "multipart/alternative", Parts: parts}
+	if len(parts) == 1 {
+		payload = parts[0]
+	}
+	payload.Headers = []*gmail.MessagePartHeader{
+		{Name: "Subject", Value: subject},
+		{Name: "Date", Value: received.Format(time.RFC1123Z)},
+	}
+	return s.add(received, payload, textBody, labels)
+}
+
+// AddRaw adds an RFC 5322 message, such as the .eml files the mail
+// package's file provider writes, filed under the labels named. Its Date
+// header is when it was received.
+func (s *Server) AddRaw(raw []byte, labels ...string) (string, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return "", err
+	}
+	received, err := msg.Header.Date()
+	if err != nil {
+		received = time.Now()
+	}
+
+	payload, err := readPart(msg.Header, msg.Body)
+	if err != nil {
+		return "", err
+	}
+	for name, values := range msg.Header {
+		for _, value := range values {
+			payload.Headers = append(payload.Headers, &gmail.MessagePartHeader{Name: name, Value: value})
+		}
+	}
+
+	return s.add(received, payload, msg.Header.Get("Subject"), labels), nil
+}
+
+func (s *Server) add(received time.Time, payload *gmail.MessagePart, snippet string, labels []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]string, 0, len(labels))
+	for _, name := range labels {
+		ids = append(ids, s.label(name))
+	}
+	id := fmt.Sprintf("%016x", len(s.messages)+1)
+	s.messages = append(s.messages, &gmail.Message{
+		Id:           id,
+		ThreadId:     id,
+		LabelIds:     ids,
+		InternalDate: received.UnixMilli(),
+		Snippet:      strings.Join(strings.Fields(snippet), " "),
+		Payload:      payload,
+	})
+	return id
+}
+
+func (s *Server) listLabels(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	respond(w, http.StatusOK, &gmail.ListLabelsResponse{Labels: s.labels})
+}
+
+// listMessages lists messages newest first. Of the search operators in q
+// only after: is understood.
+func (s *Server) listMessages(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var after int64
+	for _, term := range strings.Fields(query.Get("q")) {
+		if v, ok := strings.CutPrefix(term, "after:"); ok {
+			after, _ = strconv.ParseInt(v, 10, 64)
+		}
+	}
+	limit, err := strconv.Atoi(query.Get("maxResults"))
+	if err != nil || limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	offset, _ := strconv.Atoi(query.Get("pageToken"))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var matched []*gmail.Message
+	for i := len(s.messages) - 1; i >= 0; i-- {
+		m := s.messages[i]
+		if m.InternalDate/1000 <= after {
+			continue
+		}
+		if !containsAll(m.LabelIds, query["labelIds"]) {
+			continue
+		}
+		matched = append(matched, &gmail.Message{Id: m.Id, ThreadId: m.ThreadId})
+	}
+
+	resp := &gmail.ListMessagesResponse{ResultSizeEstimate: int64(len(matched))}
+	if offset < len(matched) {
+		end := min(offset+limit, len(matched))
+		resp.Messages = matched[offset:end]
+		if end < len(matched) {
+			resp.NextPageToken = strconv.Itoa(end)
+		}
+	}
+	respond(w, http.StatusOK, resp)
+}
+
+func (s *Server) getMessage(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := r.PathValue("id")
+	i := slices.IndexFunc(s.messages, func(m *gmail.Message) bool { return m.Id == id })
+	if i < 0 {
+		respond(w, http.StatusNotFound, map[string]any{
+			"error": map[string]any{"code": http.StatusNotFound, "message": "Requested entity was not found."},
+		})
+		return
+	}
+	respond(w, http.StatusOK, s.messages[i])
+}
+
+func respond(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func containsAll(have []string, want []string) bool {
+	for _, id := range want {
+		if !slices.Contains(have, id) {
+			return false
+		}
+	}
+	return true
+}
+
+func bodyPart(mimeType string, body string) *gmail.MessagePart {
+	return &gmail.MessagePart{
+		MimeType: mimeType,
+		Headers:  []*gmail.MessagePartHeader{{Name: "Content-Type", Value: mimeType + `; charset="utf-8"`}},
+		Body:     &gmail.MessagePartBody{Data: base64.URLEncoding.EncodeToString([]byte(body)), Size: int64(len(body))},
+	}
+}
+
+// readPart reads a MIME part into Gmail's form, decoding its transfer
+// encoding and descending into multiparts.
+func readPart(h interface{ Get(string) string }, body io.Reader) (*gmail.MessagePart, error) {
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		part := &gmail.MessagePart{MimeType: mediaType}
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := reader.NextRawPart()
+			if err == io.EOF {
+				return part, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			child, err := readPart(p.Header, p)
+			if err != nil {
+				return nil, err
+			}
+			part.Parts = append(part.Parts, child)
+		}
+	}
+
+	switch strings.ToLower(h.Get("Content-Transfer-Encoding")) {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	part := bodyPart(mediaType, string(data))
+	part.Headers = nil
+	return part, nil
+}
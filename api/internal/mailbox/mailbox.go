@@ -0,0 +1,213 @@
+// Package mailbox reads mentions from Gmail labels, such as the one Google
+// Alerts emails are filed under, into the search results of subscribers.
+// Each subscriber's sources name a label and the search definition engine
+// its messages are read into; every message is read once.
+package mailbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"google.golang.org/api/gmail/v1"
+)
+
+// Store is where sources are read from and results written to.
+// database.Repository implements it.
+type Store interface {
+	SelectActiveMailboxSources(ctx context.Context) ([]model.MailboxSource, error)
+	RecordMailboxPoll(ctx context.Context, id string, message string) error
+	SelectProcessedMailboxMessages(ctx context.Context, source_id string, message_ids []string) ([]string, error)
+	SaveMailboxMessage(ctx context.Context, subscriber model.Subscriber, source_id string, message_id string, results []model.CalibrateSearchResult) ([]model.CalibrateSearchResult, error)
+	GetSubscriber(ctx context.Context, id string) (*model.Subscriber, error)
+}
+
+// ServiceFunc returns the Gmail API client to read with. It is called for
+// every poll, so that a token stored after startup is picked up.
+type ServiceFunc func(ctx context.Context) (*gmail.Service, error)
+
+// Options are how far back a new source reads and who to tell of results.
+type Options struct {
+	// Lookback is how long before a source was created its messages are
+	// still read; older ones are left alone.
+	Lookback time.Duration
+	// Notify, when set, is called after a source stored new results.
+	Notify func(ctx context.Context, source model.MailboxSource, results int)
+}
+
+// Poller reads the messages of every active source.
+type Poller struct {
+	store   Store
+	service ServiceFunc
+	opts    Options
+}
+
+// NewPoller reads back a week by default.
+func NewPoller(store Store, service ServiceFunc, opts Options) *Poller {
+	if opts.Lookback <= 0 {
+		opts.Lookback = 7 * 24 * time.Hour
+	}
+	return &Poller{store: store, service: service, opts: opts}
+}
+
+// Start polls every interval until ctx is cancelled.
+func (p *Poller) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := p.Poll(ctx); err != nil {
+					slog.ErrorContext(ctx, "mailbox poller", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Poll reads the new messages of every active source and returns how many
+// results were stored. What stopped each source is recorded on it as its
+// last error.
+func (p *Poller) Poll(ctx context.Context) (int, error) {
+	sources, err := p.store.SelectActiveMailboxSources(ctx)
+	if err != nil || len(sources) == 0 {
+		return 0, err
+	}
+
+	srv, err := p.service(ctx)
+	var labels map[string]string
+	if err == nil {
+		labels, err = labelIDs(ctx, srv)
+	}
+	if err != nil {
+		for _, source := range sources {
+			p.record(ctx, source, err)
+		}
+		return 0, err
+	}
+
+	total := 0
+	for _, source := range sources {
+		stored, err := p.pollSource(ctx, srv, labels, source)
+		total += stored
+		if stored > 0 {
+			slog.InfoContext(ctx, "mailbox results stored", "source_id", source.Id, "subscriber_id", source.Subscriber_Id, "results", stored)
+			if p.opts.Notify != nil {
+				p.opts.Notify(ctx, source, stored)
+			}
+		}
+		p.record(ctx, source, err)
+	}
+	return total, nil
+}
+
+func (p *Poller) record(ctx context.Context, source model.MailboxSource, err error) {
+	message := ""
+	if err != nil {
+		message = err.Error()
+		slog.WarnContext(ctx, "mailbox poll failed", "source_id", source.Id, "subscriber_id", source.Subscriber_Id, "error", err)
+	}
+	if err := p.store.RecordMailboxPoll(ctx, source.Id, message); err != nil {
+		slog.ErrorContext(ctx, "mailbox poller", "source_id", source.Id, "error", err)
+	}
+}
+
+// labelIDs maps the lower cased names of the account's labels to their ids.
+func labelIDs(ctx context.Context, srv *gmail.Service) (map[string]string, error) {
+	resp, err := srv.Users.Labels.List("me").Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("error listing gmail labels: %w", err)
+	}
+	labels := make(map[string]string, len(resp.Labels))
+	for _, label := range resp.Labels {
+		labels[strings.ToLower(label.Name)] = label.Id
+	}
+	return labels, nil
+}
+
+// pollSource reads the messages of the source's label that it has not read
+// yet, newest first, and returns how many results were stored.
+func (p *Poller) pollSource(ctx context.Context, srv *gmail.Service, labels map[string]string, source model.MailboxSource) (int, error) {
+	labelID, ok := labels[strings.ToLower(source.Label)]
+	if !ok {
+		return 0, fmt.Errorf("the mailbox has no label called %q", source.Label)
+	}
+
+	subscriber, err := p.store.GetSubscriber(ctx, source.Subscriber_Id)
+	if err != nil {
+		return 0, err
+	}
+	if subscriber == nil {
+		return 0, errors.New("the subscriber no longer exists")
+	}
+	subscriberID, err := uuid.Parse(subscriber.Id)
+	if err != nil {
+		return 0, err
+	}
+	engineID, err := uuid.Parse(source.Search_Definition_Engine_Id)
+	if err != nil {
+		return 0, err
+	}
+
+	after := source.Created_At.Add(-p.opts.Lookback)
+	stored := 0
+	err = srv.Users.Messages.List("me").
+		LabelIds(labelID).
+		Q(fmt.Sprintf("after:%d", after.Unix())).
+		Pages(ctx, func(page *gmail.ListMessagesResponse) error {
+			ids := make([]string, 0, len(page.Messages))
+			for _, m := range page.Messages {
+				ids = append(ids, m.Id)
+			}
+			processed, err := p.store.SelectProcessedMailboxMessages(ctx, source.Id, ids)
+			if err != nil {
+				return err
+			}
+
+			for _, id := range ids {
+				if slices.Contains(processed, id) {
+					continue
+				}
+				msg, err := srv.Users.Messages.Get("me", id).Format("full").Context(ctx).Do()
+				if err != nil {
+					return fmt.Errorf("error reading gmail message %s: %w", id, err)
+				}
+
+				received := time.UnixMilli(msg.InternalDate)
+				searched := time.Now()
+				mentions := Extract(msg)
+				results := make([]model.CalibrateSearchResult, 0, len(mentions))
+				for _, mention := range mentions {
+					results = append(results, model.CalibrateSearchResult{
+						Link:                     &mention.Link,
+						Title:                    &mention.Title,
+						Snippet:                  &mention.Snippet,
+						SubscriberID:             subscriberID,
+						SearchDefinitionEngineID: &engineID,
+						SearchTime:               &searched,
+						Published:                &received,
+					})
+				}
+
+				// The results and the mark are saved together, so a message
+				// that fails is read again in full on the next poll
+				saved, err := p.store.SaveMailboxMessage(ctx, *subscriber, source.Id, id, results)
+				if err != nil {
+					return err
+				}
+				stored += len(saved)
+			}
+			return nil
+		})
+	return stored, err
+}
@@ -0,0 +1,202 @@
+package mailbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/htstinson/stinsondataapi/api/internal/mailbox/gmailfake"
+	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+)
+
+// store keeps sources, read messages and results in memory. SaveMailboxMessage
+// fails for the message ids in fail, storing nothing, as a rolled back
+// transaction would.
+type store struct {
+	sources   []model.MailboxSource
+	processed map[string]bool // source id + "/" + message id
+	results   []model.CalibrateSearchResult
+	errors    map[string]string // the last error recorded for each source
+	fail      map[string]bool
+}
+
+func newStore(sources ...model.MailboxSource) *store {
+	return &store{
+		sources:   sources,
+		processed: map[string]bool{},
+		errors:    map[string]string{},
+		fail:      map[string]bool{},
+	}
+}
+
+func (s *store) SelectActiveMailboxSources(ctx context.Context) ([]model.MailboxSource, error) {
+	return s.sources, nil
+}
+
+func (s *store) RecordMailboxPoll(ctx context.Context, id string, message string) error {
+	s.errors[id] = message
+	return nil
+}
+
+func (s *store) SelectProcessedMailboxMessages(ctx context.Context, source_id string, message_ids []string) ([]string, error) {
+	processed := []string{}
+	for _, id := range message_ids {
+		if s.processed[source_id+"/"+id] {
+			processed = append(processed, id)
+		}
+	}
+	return processed, nil
+}
+
+func (s *store) SaveMailboxMessage(ctx context.Context, subscriber model.Subscriber, source_id string, message_id string, results []model.CalibrateSearchResult) ([]model.CalibrateSearchResult, error) {
+	if s.fail[message_id] {
+		return nil, errors.New("connection reset")
+	}
+	if s.processed[source_id+"/"+message_id] {
+		return nil, nil
+	}
+	s.processed[source_id+"/"+message_id] = true
+	s.results = append(s.results, results...)
+	return results, nil
+}
+
+func (s *store) GetSubscriber(ctx context.Context, id string) (*model.Subscriber, error) {
+	return &model.Subscriber{Id: id, Schema_Name: "s"}, nil
+}
+
+// links returns the links of the stored results in the order stored.
+func (s *store) links() []string {
+	links := make([]string, 0, len(s.results))
+	for _, r := range s.results {
+		links = append(links, *r.Link)
+	}
+	return links
+}
+
+const (
+	subscriberID = "6f1c1d52-3a55-4c1e-9d7b-0c2b8f1b2a10"
+	engineID     = "0b8a4e0e-5f6d-4b1a-8f0e-2c9d7e6a5b43"
+)
+
+func source(id string, label string) model.MailboxSource {
+	return model.MailboxSource{
+		Id:                          id,
+		Subscriber_Id:               subscriberID,
+		Label:                       label,
+		Search_Definition_Engine_Id: engineID,
+		Created_At:                  time.Now(),
+	}
+}
+
+// newPoller serves fake over HTTP and returns a poller reading it into db.
+func newPoller(t *testing.T, fake *gmailfake.Server, db Store) *Poller {
+	t.Helper()
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	return NewPoller(db, func(ctx context.Context) (*gmail.Service, error) {
+		return gmail.NewService(ctx, option.WithEndpoint(srv.URL+"/"), option.WithoutAuthentication(), option.WithHTTPClient(srv.Client()))
+	}, Options{})
+}
+
+// alert adds a message to the label with a link per path, received ago
+// before now.
+func alert(fake *gmailfake.Server, ago time.Duration, label string, paths ...string) string {
+	var body strings.Builder
+	for _, path := range paths {
+		fmt.Fprintf(&body, `<p><a href="https://news.example.com/%s">Story %s</a> about the subscriber.</p>`, path, path)
+	}
+	return fake.AddMessage(time.Now().Add(-ago), "Google Alert", body.String(), "", label)
+}
+
+func TestPollSkipsProcessedMessages(t *testing.T) {
+	fake := gmailfake.New()
+	alert(fake, 2*time.Hour, "Alerts", "a1", "a2")
+	alert(fake, time.Hour, "Alerts", "b1")
+	alert(fake, time.Hour, "Other", "c1")
+
+	db := newStore(source("src-1", "alerts"))
+	p := newPoller(t, fake, db)
+
+	stored, err := p.Poll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored != 3 || !slices.Equal(db.links(), []string{"https://news.example.com/b1", "https://news.example.com/a1", "https://news.example.com/a2"}) {
+		t.Fatalf("stored %d: %v", stored, db.links())
+	}
+
+	stored, err = p.Poll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored != 0 || len(db.results) != 3 {
+		t.Fatalf("second poll stored %d, %d results in all", stored, len(db.results))
+	}
+	if db.errors["src-1"] != "" {
+		t.Fatalf("error recorded: %s", db.errors["src-1"])
+	}
+}
+
+func TestPollMissingLabel(t *testing.T) {
+	fake := gmailfake.New()
+	alert(fake, time.Hour, "Alerts", "a1")
+
+	db := newStore(source("src-1", "Missing"), source("src-2", "Alerts"))
+	p := newPoller(t, fake, db)
+
+	stored, err := p.Poll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored != 1 {
+		t.Fatalf("stored %d, want 1", stored)
+	}
+	if !strings.Contains(db.errors["src-1"], `no label called "Missing"`) {
+		t.Fatalf("src-1 error = %q", db.errors["src-1"])
+	}
+	if db.errors["src-2"] != "" {
+		t.Fatalf("src-2 error = %q", db.errors["src-2"])
+	}
+}
+
+func TestPollRereadsFailedMessage(t *testing.T) {
+	fake := gmailfake.New()
+	alert(fake, 3*time.Hour, "Alerts", "a1")
+	failing := alert(fake, 2*time.Hour, "Alerts", "b1", "b2")
+	alert(fake, time.Hour, "Alerts", "c1")
+
+	db := newStore(source("src-1", "Alerts"))
+	db.fail[failing] = true
+	p := newPoller(t, fake, db)
+
+	// The newest message is stored and the poll of the source stops at the
+	// failing one
+	stored, err := p.Poll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored != 1 || db.errors["src-1"] == "" {
+		t.Fatalf("stored %d, error %q", stored, db.errors["src-1"])
+	}
+
+	delete(db.fail, failing)
+	stored, err = p.Poll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"https://news.example.com/c1", "https://news.example.com/b1", "https://news.example.com/b2", "https://news.example.com/a1"}
+	if stored != 3 || !slices.Equal(db.links(), want) {
+		t.Fatalf("stored %d: %v, want %v", stored, db.links(), want)
+	}
+	if db.errors["src-1"] != "" {
+		t.Fatalf("error recorded: %s", db.errors["src-1"])
+	}
+}
@@ -0,0 +1,184 @@
+// Package metrics holds the Prometheus collectors for the API and the
+// handler that serves them.
+package metrics
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "api_http_requests_total",
+		Help: "HTTP requests by route template, method and status class.",
+	}, []string{"route", "method", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "api_http_request_duration_seconds",
+		Help:    "HTTP request latency by route template and method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	searchRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "api_search_runs_total",
+		Help: "Searches run by search engine, subscriber and outcome.",
+	}, []string{"engine", "subscriber", "outcome"})
+
+	searchResults = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "api_search_results_total",
+		Help: "Search results stored by search engine and subscriber.",
+	}, []string{"engine", "subscriber"})
+
+	wafSyncs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "api_waf_sync_total",
+		Help: "WAF IP set updates by IP set, operation and outcome.",
+	}, []string{"ip_set", "operation", "outcome"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		searchRuns,
+		searchResults,
+		wafSyncs,
+	)
+}
+
+// DBStatsSource is anything that reports sql.DB pool statistics.
+type DBStatsSource interface {
+	Stats() sql.DBStats
+}
+
+// RegisterDB exports the connection pool statistics of db under the given name.
+func RegisterDB(db DBStatsSource, name string) {
+	labels := prometheus.Labels{"db_name": name}
+	gauge := func(metric string, help string, value func(sql.DBStats) float64) prometheus.Collector {
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name:        "api_db_" + metric,
+			Help:        help,
+			ConstLabels: labels,
+		}, func() float64 { return value(db.Stats()) })
+	}
+	counter := func(metric string, help string, value func(sql.DBStats) float64) prometheus.Collector {
+		return prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name:        "api_db_" + metric,
+			Help:        help,
+			ConstLabels: labels,
+		}, func() float64 { return value(db.Stats()) })
+	}
+
+	registry.MustRegister(
+		gauge("max_open_connections", "Maximum number of open connections.", func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }),
+		gauge("open_connections", "Open connections, in use and idle.", func(s sql.DBStats) float64 { return float64(s.OpenConnections) }),
+		gauge("in_use_connections", "Connections currently in use.", func(s sql.DBStats) float64 { return float64(s.InUse) }),
+		gauge("idle_connections", "Idle connections.", func(s sql.DBStats) float64 { return float64(s.Idle) }),
+		counter("wait_count_total", "Connections waited for.", func(s sql.DBStats) float64 { return float64(s.WaitCount) }),
+		counter("wait_duration_seconds_total", "Time spent waiting for a connection.", func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }),
+		counter("max_idle_closed_total", "Connections closed due to SetMaxIdleConns.", func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }),
+		counter("max_lifetime_closed_total", "Connections closed due to SetConnMaxLifetime.", func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }),
+	)
+}
+
+// Handler serves the metrics. When token is set, requests must carry it as
+// "Authorization: Bearer <token>".
+func Handler(token string) http.Handler {
+	metrics := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	if token == "" {
+		return metrics
+	}
+
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		metrics.ServeHTTP(w, r)
+	})
+}
+
+// Middleware counts and times requests by their mux route template, so that
+// path parameters such as IDs do not each create a new series.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		route := "unmatched"
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+
+		httpRequests.WithLabelValues(route, r.Method, statusClass(recorder.status)).Inc()
+		httpDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+	})
+}
+
+// SearchRun records one search against a search engine for a subscriber.
+func SearchRun(engine string, subscriber string, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+	searchRuns.WithLabelValues(engine, subscriber, outcome).Inc()
+}
+
+// SearchResults records the number of results stored from one search.
+func SearchResults(engine string, subscriber string, count int) {
+	searchResults.WithLabelValues(engine, subscriber).Add(float64(count))
+}
+
+// WAFSync records the outcome of one WAF IP set update: "updated",
+// "unchanged" or "error".
+func WAFSync(ipSet string, operation string, outcome string) {
+	wafSyncs.WithLabelValues(ipSet, operation, outcome).Inc()
+}
+
+func statusClass(status int) string {
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rw *statusRecorder) WriteHeader(status int) {
+	if rw.status == 0 {
+		rw.status = status
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *statusRecorder) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	return rw.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rw *statusRecorder) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/htstinson/stinsondataapi/api/pkg/database"
+)
+
+// BuiltinAllowed are ranges that can never be blocked whatever the allowed table says.
+// They are the private and loopback ranges isPrivateIP treats as internal.
+var BuiltinAllowed = map[string]string{
+	"10.0.0.0/8":     "RFC1918 private range",
+	"172.16.0.0/12":  "RFC1918 private range",
+	"192.168.0.0/16": "RFC1918 private range",
+	"127.0.0.0/8":    "loopback",
+	"::1/128":        "IPv6 loopback",
+	"fe80::/10":      "IPv6 link-local",
+}
+
+// AllowedSource is the part of the repository the allow list loads from.
+type AllowedSource interface {
+	SelectAllowed(ctx context.Context, q database.ListQuery) (*database.Page[model.Allowed], error)
+}
+
+// AllowListConflict is returned when an address to be blocked overlaps an allowed range.
+type AllowListConflict struct {
+	IP      string
+	Allowed string
+	Notes   string
+}
+
+func (c *AllowListConflict) Error() string {
+	if c.Notes != "" {
+		return fmt.Sprintf("%s overlaps allow-listed %s (%s)", c.IP, c.Allowed, c.Notes)
+	}
+	return fmt.Sprintf("%s overlaps allow-listed %s", c.IP, c.Allowed)
+}
+
+type allowEntry struct {
+	prefix netip.Prefix
+	notes  string
+}
+
+// AllowList protects trusted ranges from being blocked by hand, by the log
+// parser or by the WAF sync.
+type AllowList struct {
+	source AllowedSource
+
+	mu       sync.RWMutex
+	entries  []allowEntry
+	loadedAt time.Time
+}
+
+func NewAllowList(source AllowedSource) *AllowList {
+	return &AllowList{source: source, entries: builtinEntries()}
+}
+
+// Refresh reloads the allowed table on top of the built in ranges.
+func (a *AllowList) Refresh(ctx context.Context) error {
+	entries := builtinEntries()
+
+	rows, err := database.SelectAll(database.ListQuery{Sort: "ip"}, func(q database.ListQuery) (*database.Page[model.Allowed], error) {
+		return a.source.SelectAllowed(ctx, q)
+	})
+	if err != nil {
+		return fmt.Errorf("error loading allowed: %w", err)
+	}
+
+	for _, row := range rows {
+		prefix, err := parsePrefix(row.IP)
+		if err != nil {
+			slog.WarnContext(ctx, "allow list skipping invalid entry", "ip", row.IP, "error", err)
+			continue
+		}
+		entries = append(entries, allowEntry{prefix: prefix, notes: row.Notes})
+	}
+
+	a.mu.Lock()
+	a.entries = entries
+	a.loadedAt = time.Now()
+	a.mu.Unlock()
+
+	return nil
+}
+
+// LoadedAt is when the allowed table was last fully loaded.
+func (a *AllowList) LoadedAt() time.Time {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.loadedAt
+}
+
+// Start refreshes the list every interval until ctx is cancelled.
+func (a *AllowList) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := a.Refresh(ctx); err != nil {
+					slog.ErrorContext(ctx, "allow list refresh failed", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Check returns an *AllowListConflict if ip overlaps an allowed range. Every
+// conflict is logged together with source, the caller that tried to block it.
+func (a *AllowList) Check(ip string, source string) error {
+	conflict, err := a.conflict(ip)
+	if err != nil {
+		return err
+	}
+	if conflict != nil {
+		logConflict(source, conflict)
+		return conflict
+	}
+	return nil
+}
+
+// conflict is Check without the logging.
+func (a *AllowList) conflict(ip string) (*AllowListConflict, error) {
+	prefix, err := parsePrefix(ip)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %q: %w", ip, err)
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	for _, entry := range a.entries {
+		if entry.prefix.Overlaps(prefix) {
+			return &AllowListConflict{IP: ip, Allowed: entry.prefix.String(), Notes: entry.notes}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func logConflict(source string, conflict *AllowListConflict) {
+	slog.Warn("allow list override", "source", source, "ip", conflict.IP, "allowed", conflict.Allowed, "notes", conflict.Notes)
+}
+
+func builtinEntries() []allowEntry {
+	entries := make([]allowEntry, 0, len(BuiltinAllowed))
+	for cidr, notes := range BuiltinAllowed {
+		entries = append(entries, allowEntry{prefix: netip.MustParsePrefix(cidr), notes: notes})
+	}
+	return entries
+}
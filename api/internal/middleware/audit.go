@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/htstinson/stinsondataapi/api/internal/auth"
+	"github.com/htstinson/stinsondataapi/api/pkg/database"
+)
+
+// AuditActor puts the database.Actor that audit events are attributed to in
+// the context: the request ID, the client address and, after
+// JWTAuth.Middleware, the user. The subscriber is the one named in the path,
+// or the token's active subscriber.
+func AuditActor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor := database.Actor{
+			RequestID:    RequestIDFromContext(r.Context()),
+			SubscriberID: mux.Vars(r)["subscriber_id"],
+		}
+		if ip, ok := ClientIPFromContext(r.Context()); ok {
+			actor.IP = ip.String()
+		}
+		if claims, ok := r.Context().Value("user").(*auth.Claims); ok {
+			actor.UserID = claims.UserID
+			actor.Username = claims.Username
+			if actor.SubscriberID == "" {
+				actor.SubscriberID = claims.SubscriberID
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(database.WithActor(r.Context(), actor)))
+	})
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/htstinson/stinsondataapi/api/pkg/database"
+)
+
+// auditLog records the audit events of unsubscribes. Like the request_id
+// column once was, it refuses request IDs over 100 characters.
+type auditLog struct {
+	database.Repository
+	events []model.AuditEvent
+}
+
+func (a *auditLog) Unsubscribe(ctx context.Context, email string, category string) error {
+	return nil
+}
+
+func (a *auditLog) CreateAuditEvent(ctx context.Context, event model.AuditEvent) error {
+	if len(event.Request_Id) > 100 {
+		return errors.New("value too long for type character varying(100)")
+	}
+	a.events = append(a.events, event)
+	return nil
+}
+
+func TestAuditRequestID(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		kept   bool
+	}{
+		{"client id", "req-123", true},
+		{"missing", "", false},
+		{"oversized", strings.Repeat("a", 5000), false},
+		{"too long", strings.Repeat("a", maxRequestIDLength+1), false},
+		{"control characters", "req\x00123", false},
+		{"spaces", "req 123", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := &auditLog{}
+			repo := database.WithAudit(log)
+			handler := RequestID(AuditActor(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := repo.Unsubscribe(r.Context(), "a@example.com", "alerts"); err != nil {
+					t.Fatal(err)
+				}
+			})))
+
+			r := httptest.NewRequest(http.MethodPost, "/email/unsubscribe", nil)
+			if tt.header != "" {
+				r.Header.Set("X-Request-ID", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, r)
+
+			if len(log.events) != 1 {
+				t.Fatalf("%d audit events recorded, want 1", len(log.events))
+			}
+			got := log.events[0].Request_Id
+			if got == "" || got != rec.Header().Get("X-Request-ID") {
+				t.Fatalf("request_id %q, response header %q", got, rec.Header().Get("X-Request-ID"))
+			}
+			if kept := got == tt.header; kept != tt.kept {
+				t.Fatalf("request_id %q, header kept = %v, want %v", got, kept, tt.kept)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"sync"
+	"time"
+
+	common "github.com/htstinson/stinsondataapi/api/commonweb"
+	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/htstinson/stinsondataapi/api/pkg/database"
+)
+
+// BlockedSource is the part of the repository the block list loads from.
+type BlockedSource interface {
+	SelectBlocked(ctx context.Context, q database.ListQuery) (*database.Page[model.Blocked], error)
+}
+
+// BlockList enforces the blocked table in memory so that blocked addresses are
+// refused even when the API is not sitting behind the AWS WAF.
+type BlockList struct {
+	source   BlockedSource
+	resolver *ClientIPResolver
+	allow    *AllowList
+
+	mu       sync.RWMutex
+	hosts    map[netip.Addr]struct{}
+	prefixes map[netip.Prefix]struct{}
+	loadedAt time.Time
+
+	// conflicts are the rows kept out by the allow list at the last
+	// refresh, so that each is only logged when it first appears.
+	conflicts map[string]struct{}
+}
+
+func NewBlockList(source BlockedSource, resolver *ClientIPResolver) *BlockList {
+	return &BlockList{
+		source:   source,
+		resolver: resolver,
+		hosts:    make(map[netip.Addr]struct{}),
+		prefixes: make(map[netip.Prefix]struct{}),
+	}
+}
+
+// SetAllowList keeps allow-listed ranges out of the list on every refresh.
+func (b *BlockList) SetAllowList(allow *AllowList) {
+	b.allow = allow
+}
+
+// Refresh reloads every row of the blocked table and swaps it in.
+func (b *BlockList) Refresh(ctx context.Context) error {
+	hosts := make(map[netip.Addr]struct{})
+	prefixes := make(map[netip.Prefix]struct{})
+	conflicts := make(map[string]struct{})
+
+	b.mu.RLock()
+	logged := b.conflicts
+	b.mu.RUnlock()
+
+	rows, err := database.SelectAll(database.ListQuery{Sort: "ip"}, func(q database.ListQuery) (*database.Page[model.Blocked], error) {
+		return b.source.SelectBlocked(ctx, q)
+	})
+	if err != nil {
+		return fmt.Errorf("error loading blocked: %w", err)
+	}
+
+	for _, row := range rows {
+		prefix, err := parsePrefix(row.IP)
+		if err != nil {
+			slog.WarnContext(ctx, "block list skipping invalid entry", "ip", row.IP, "error", err)
+			continue
+		}
+		if b.allow != nil {
+			conflict, _ := b.allow.conflict(row.IP)
+			if conflict != nil {
+				if _, seen := logged[row.IP]; !seen {
+					logConflict("BlockList", conflict)
+				}
+				conflicts[row.IP] = struct{}{}
+				continue
+			}
+		}
+		addPrefix(hosts, prefixes, prefix)
+	}
+
+	b.mu.Lock()
+	b.hosts = hosts
+	b.prefixes = prefixes
+	b.loadedAt = time.Now()
+	b.conflicts = conflicts
+	b.mu.Unlock()
+
+	slog.InfoContext(ctx, "block list loaded", "hosts", len(hosts), "ranges", len(prefixes))
+
+	return nil
+}
+
+// Start refreshes the list every interval until ctx is cancelled.
+func (b *BlockList) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := b.Refresh(ctx); err != nil {
+					slog.ErrorContext(ctx, "block list refresh failed", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Add blocks an address or CIDR immediately, without waiting for the next refresh.
+func (b *BlockList) Add(ip string) error {
+	prefix, err := parsePrefix(ip)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	addPrefix(b.hosts, b.prefixes, prefix)
+	b.mu.Unlock()
+
+	return nil
+}
+
+// Remove unblocks an address or CIDR immediately.
+func (b *BlockList) Remove(ip string) error {
+	prefix, err := parsePrefix(ip)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	if prefix.IsSingleIP() {
+		delete(b.hosts, prefix.Addr())
+	} else {
+		delete(b.prefixes, prefix)
+	}
+	b.mu.Unlock()
+
+	return nil
+}
+
+// Contains reports whether addr is covered by any blocked entry.
+func (b *BlockList) Contains(addr netip.Addr) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if _, ok := b.hosts[addr]; ok {
+		return true
+	}
+	for prefix := range b.prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Overlapping returns the blocked entries that overlap ip.
+func (b *BlockList) Overlapping(ip string) ([]string, error) {
+	prefix, err := parsePrefix(ip)
+	if err != nil {
+		return nil, err
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var overlapping []string
+	for host := range b.hosts {
+		if prefix.Contains(host) {
+			overlapping = append(overlapping, host.String())
+		}
+	}
+	for blocked := range b.prefixes {
+		if blocked.Overlaps(prefix) {
+			overlapping = append(overlapping, blocked.String())
+		}
+	}
+	return overlapping, nil
+}
+
+// LoadedAt is when the list was last fully loaded from the database.
+func (b *BlockList) LoadedAt() time.Time {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.loadedAt
+}
+
+// Middleware refuses requests from blocked addresses with 403.
+func (b *BlockList) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip, ok := ClientIPFromContext(r.Context())
+		if !ok {
+			ip = b.resolver.ClientIP(r)
+		}
+
+		if ip.IsValid() && b.Contains(ip) {
+			slog.WarnContext(r.Context(), "blocked address refused", "client_ip", ip.String(), "method", r.Method, "path", r.URL.Path)
+			common.RespondError(w, http.StatusForbidden, "Forbidden")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func addPrefix(hosts map[netip.Addr]struct{}, prefixes map[netip.Prefix]struct{}, prefix netip.Prefix) {
+	if prefix.IsSingleIP() {
+		hosts[prefix.Addr()] = struct{}{}
+		return
+	}
+	prefixes[prefix] = struct{}{}
+}
@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// DefaultTrustedProxies are the ranges our load balancers and local proxies live in.
+var DefaultTrustedProxies = []string{
+	"10.0.0.0/8",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"127.0.0.0/8",
+	"::1/128",
+	"fc00::/7",
+}
+
+// ClientIPResolver works out the real client address of a request. X-Forwarded-For
+// is only believed for hops that arrive through one of the trusted proxies.
+type ClientIPResolver struct {
+	trusted []netip.Prefix
+}
+
+// NewClientIPResolver builds a resolver from a list of trusted proxy CIDRs or addresses.
+func NewClientIPResolver(trustedProxies []string) (*ClientIPResolver, error) {
+	resolver := &ClientIPResolver{}
+
+	for _, entry := range trustedProxies {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		prefix, err := parsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+		}
+		resolver.trusted = append(resolver.trusted, prefix)
+	}
+
+	return resolver, nil
+}
+
+// ClientIP returns the address of the client that made the request. It walks
+// X-Forwarded-For from right to left and stops at the first untrusted hop.
+func (c *ClientIPResolver) ClientIP(r *http.Request) netip.Addr {
+	remote, ok := parseAddr(r.RemoteAddr)
+	if !ok || !c.isTrusted(remote) {
+		return remote
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+
+	client := remote
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, ok := parseAddr(strings.TrimSpace(hops[i]))
+		if !ok {
+			// A garbled hop means nothing further left can be believed.
+			break
+		}
+		client = hop
+		if !c.isTrusted(hop) {
+			break
+		}
+	}
+
+	return client
+}
+
+// Middleware stores the resolved client IP in the request context under "clientIP".
+func (c *ClientIPResolver) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := c.ClientIP(r)
+		ctx := context.WithValue(r.Context(), "clientIP", ip)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (c *ClientIPResolver) isTrusted(addr netip.Addr) bool {
+	for _, prefix := range c.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIPFromContext returns the address stored by ClientIPResolver.Middleware.
+func ClientIPFromContext(ctx context.Context) (netip.Addr, bool) {
+	ip, ok := ctx.Value("clientIP").(netip.Addr)
+	return ip, ok && ip.IsValid()
+}
+
+// parseAddr accepts "ip", "ip:port" and "[ipv6]:port" forms.
+func parseAddr(s string) (netip.Addr, bool) {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// parsePrefix accepts either a CIDR or a bare address, which is treated as a single host.
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// CanonicalPrefix normalises an address or CIDR to its CIDR form, e.g. "1.2.3.4" to "1.2.3.4/32".
+func CanonicalPrefix(s string) (string, error) {
+	prefix, err := parsePrefix(strings.TrimSpace(s))
+	if err != nil {
+		return "", err
+	}
+	return prefix.String(), nil
+}
@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// CORSOptions describe which cross-origin callers a route group accepts.
+// An allowed origin is an exact origin such as "https://app.example.com", a
+// wildcard subdomain such as "https://*.example.com", a regular expression
+// prefixed with "re:" that must match the whole origin, or "*" for any origin.
+type CORSOptions struct {
+	AllowedOrigins   []string
+	AllowCredentials bool
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	MaxAge           time.Duration
+}
+
+// CORSPolicy applies CORSOptions to the requests of one route group.
+type CORSPolicy struct {
+	anyOrigin      bool
+	exact          map[string]bool
+	patterns       []*regexp.Regexp
+	credentials    bool
+	allowedHeaders string
+	exposedHeaders string
+	maxAge         string
+}
+
+// NewCORSPolicy checks opts and compiles the origin patterns.
+func NewCORSPolicy(opts CORSOptions) (*CORSPolicy, error) {
+	p := &CORSPolicy{
+		exact:          make(map[string]bool),
+		credentials:    opts.AllowCredentials,
+		allowedHeaders: strings.Join(opts.AllowedHeaders, ", "),
+		exposedHeaders: strings.Join(opts.ExposedHeaders, ", "),
+	}
+	if opts.MaxAge > 0 {
+		p.maxAge = strconv.Itoa(int(opts.MaxAge.Seconds()))
+	}
+
+	var errs []error
+	for _, origin := range opts.AllowedOrigins {
+		origin = strings.TrimSpace(origin)
+		switch {
+		case origin == "*":
+			p.anyOrigin = true
+		case strings.HasPrefix(origin, "re:"):
+			re, err := regexp.Compile(`^(?:` + strings.TrimPrefix(origin, "re:") + `)$`)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("invalid origin pattern %q: %w", origin, err))
+				continue
+			}
+			p.patterns = append(p.patterns, re)
+		case strings.Contains(origin, "*"):
+			scheme, host, ok := strings.Cut(origin, "://*.")
+			if !ok || strings.Contains(host, "*") {
+				errs = append(errs, fmt.Errorf("invalid wildcard origin %q, expected scheme://*.domain", origin))
+				continue
+			}
+			p.patterns = append(p.patterns, regexp.MustCompile(`^`+regexp.QuoteMeta(scheme)+`://([a-z0-9-]+\.)+`+regexp.QuoteMeta(strings.ToLower(host))+`(:\d+)?$`))
+		default:
+			p.exact[strings.ToLower(strings.TrimRight(origin, "/"))] = true
+		}
+	}
+
+	if p.anyOrigin && p.credentials {
+		errs = append(errs, errors.New(`credentials cannot be allowed for origin "*"`))
+	}
+
+	return p, errors.Join(errs...)
+}
+
+// Allowed reports whether requests from origin are accepted.
+func (p *CORSPolicy) Allowed(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	if p.anyOrigin || p.exact[strings.ToLower(origin)] {
+		return true
+	}
+	for _, re := range p.patterns {
+		if re.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware sets the CORS response headers for allowed origins and answers
+// preflight requests with 204 instead of calling the handler. Requests from
+// other origins get no CORS headers, so browsers refuse them.
+func (p *CORSPolicy) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		w.Header().Add("Vary", "Origin")
+
+		allowed := p.Allowed(origin)
+		if allowed {
+			if p.anyOrigin {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+			if p.credentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+		}
+
+		if r.Method != http.MethodOptions {
+			if allowed && p.exposedHeaders != "" {
+				w.Header().Set("Access-Control-Expose-Headers", p.exposedHeaders)
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Never run handlers for OPTIONS; only the route match matters
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+		w.Header().Add("Vary", "Access-Control-Request-Headers")
+		if allowed {
+			if method := r.Header.Get("Access-Control-Request-Method"); method != "" {
+				w.Header().Set("Access-Control-Allow-Methods", method)
+			}
+			if p.allowedHeaders != "" {
+				w.Header().Set("Access-Control-Allow-Headers", p.allowedHeaders)
+			}
+			if p.maxAge != "" {
+				w.Header().Set("Access-Control-Max-Age", p.maxAge)
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// Preflight adds a catch-all OPTIONS route to group, which must use the
+// policy's Middleware, for the routes that do not list OPTIONS themselves.
+// It only matches when a route in the group accepts the requested method on
+// that path, so preflights for unknown routes still get 404 or 405. Call it
+// after the group's routes are added.
+func (p *CORSPolicy) Preflight(group *mux.Router) {
+	group.Methods(http.MethodOptions).MatcherFunc(func(r *http.Request, _ *mux.RouteMatch) bool {
+		// mux runs every matcher even after the method has not matched, so
+		// check it here too or the probe below would recurse
+		if r.Method != http.MethodOptions {
+			return false
+		}
+		method := r.Header.Get("Access-Control-Request-Method")
+		if method == "" || method == http.MethodOptions {
+			return false
+		}
+		probe := r.Clone(r.Context())
+		probe.Method = method
+		var match mux.RouteMatch
+		return group.Match(probe, &match) && match.MatchErr == nil
+	}).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Middleware has already answered
+	})
+}
@@ -2,14 +2,13 @@ package middleware
 
 import (
 	"fmt"
+	"log/slog"
 	"net"
 	"net/http"
-	"time"
 )
 
 // Get the direct TCP/IP connection address (Layer 3)
 func getTCPAddr(r *http.Request) string {
-	fmt.Printf("[%v] getTCPAddr.\n", time.Now().Format(time.RFC3339))
 	// RemoteAddr contains the actual TCP connection address (IP:port)
 	// This is the most reliable source of the client's direct IP
 	// but will be the proxy's IP if the client is behind a proxy
@@ -20,7 +19,7 @@ func getTCPAddr(r *http.Request) string {
 	ip, _, err := net.SplitHostPort(addr)
 	if err != nil {
 		// If there's an error splitting, just return the whole thing
-		fmt.Printf("[%v] getTCPAddr err. %v\n", time.Now().Format(time.RFC3339), err.Error())
+		slog.Debug("getTCPAddr", "remote_addr", addr, "error", err)
 		return addr
 	}
 
@@ -29,14 +28,12 @@ func getTCPAddr(r *http.Request) string {
 
 // Log middleware that captures the TCP address
 func IpLoggingMiddleware(next http.Handler) http.Handler {
-	//fmt.Printf("[%v] ipLoggingMiddleware.\n", time.Now().Format(time.RFC3339))
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Get the TCP address
 		//ipAddr := getTCPAddr(r)
 
 		// Log the connection information
-		fmt.Printf("[%v] [IpLoggingMiddleware] Method: %s, Path: %s\n", time.Now().Format(time.RFC3339), r.Method, r.URL.Path)
-		fmt.Printf("[%v] [IpLoggingMiddleware] X-Forwarded-For: %s\n", time.Now().Format(time.RFC3339), r.Header.Get("X-Forwarded-For"))
+		slog.DebugContext(r.Context(), "IpLoggingMiddleware", "method", r.Method, "path", r.URL.Path, "x_forwarded_for", r.Header.Get("X-Forwarded-For"))
 
 		// Continue to the next handler
 		next.ServeHTTP(w, r)
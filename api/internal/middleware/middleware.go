@@ -2,52 +2,159 @@ package middleware
 
 import (
 	"context"
-	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/htstinson/stinsondataapi/api/internal/logging"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func Logger(logger *log.Logger) func(http.Handler) http.Handler {
+// Logger attaches the request fields used by every log record to the context
+// and writes one access log record per request once it has been served.
+func Logger(logger *slog.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
-			next.ServeHTTP(w, r)
-			qp := r.URL.Query()
-			dir := qp.Get("dir")
-			fld := qp.Get("field")
-			fmt.Printf(
-				"[%v] [middleware Logger] %s %s %s %s %s %s %s\n",
-				time.Now().Format(time.RFC3339),
-				r.Method,
-				r.URL.Path,
-				dir,
-				fld,
-				r.Header.Get("X-Forwarded-For"), r.URL.User,
-				time.Since(start),
-			)
+
+			route := r.URL.Path
+			if current := mux.CurrentRoute(r); current != nil {
+				if template, err := current.GetPathTemplate(); err == nil {
+					route = template
+				}
+			}
+
+			ctx, _ := logging.WithFields(r.Context(), RequestIDFromContext(r.Context()), route)
+			if subscriberID := mux.Vars(r)["subscriber_id"]; subscriberID != "" {
+				logging.SetSubscriber(ctx, subscriberID)
+			}
+
+			recorder := &responseRecorder{ResponseWriter: w}
+			next.ServeHTTP(recorder, r.WithContext(ctx))
+
+			status := recorder.Status()
+			level := slog.LevelInfo
+			switch {
+			case status >= 500:
+				level = slog.LevelError
+			case status >= 400:
+				level = slog.LevelWarn
+			}
+
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", status),
+				slog.Int64("bytes", recorder.bytes),
+				slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+				slog.String("user_agent", r.UserAgent()),
+			}
+			if ip, ok := ClientIPFromContext(r.Context()); ok {
+				attrs = append(attrs, slog.String("client_ip", ip.String()))
+			}
+
+			logger.LogAttrs(ctx, level, "request", attrs...)
 		})
 	}
 }
 
+// responseRecorder remembers the status code and body size written by a handler.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (rw *responseRecorder) WriteHeader(status int) {
+	if rw.status == 0 {
+		rw.status = status
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *responseRecorder) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += int64(n)
+	return n, err
+}
+
+// Status is the status code sent, 200 if the handler never wrote one.
+func (rw *responseRecorder) Status() int {
+	if rw.status == 0 {
+		return http.StatusOK
+	}
+	return rw.status
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rw *responseRecorder) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// maxRequestIDLength is the longest X-Request-ID taken from a client.
+const maxRequestIDLength = 64
+
+// RequestID takes the request's ID from its X-Request-ID header, or makes
+// one up when the header is missing, too long or has anything but visible
+// ASCII in it, and echoes it in the response.
 func RequestID(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		requestID := r.Header.Get("X-Request-ID")
 		key := "requestID"
-		if requestID == "" {
+		if !validRequestID(requestID) {
 			requestID = uuid.New().String()
 		}
 		ctx := context.WithValue(r.Context(), key, requestID)
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("request.id", requestID))
 		w.Header().Set("X-Request-ID", requestID)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// RequestIDFromContext returns the ID stored by RequestID, or "".
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value("requestID").(string)
+	return requestID
+}
+
+// MaxBodySize limits request bodies to limit bytes. Reading past the limit
+// fails with *http.MaxBytesError, which DecodeJSON reports as a 413.
+func MaxBodySize(limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body != nil && r.Body != http.NoBody {
+				r.Body = http.MaxBytesReader(w, r.Body, limit)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// SecurityHeaders sets the browser hardening headers. HSTS is only sent on
+// requests that reached us over HTTPS, directly or through a proxy.
 func SecurityHeaders(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
+		if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+			w.Header().Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
+		}
 		w.Header().Set("X-Frame-Options", "DENY")
 		w.Header().Set("X-Content-Type-Options", "nosniff")
 		w.Header().Set("X-XSS-Protection", "1; mode=block")
@@ -55,21 +162,3 @@ func SecurityHeaders(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
-
-func CORS(next http.Handler) http.Handler {
-	//fmt.Println("h CORS middleware")
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, PATCH, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		w.Header().Set("Access-Control-Expose-Headers", "Content-Type, Authorization")
-
-		// Only handle OPTIONS preflight requests here
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return // Don't call next.ServeHTTP for OPTIONS
-		}
-
-		next.ServeHTTP(w, r)
-	})
-}
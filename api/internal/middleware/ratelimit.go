@@ -0,0 +1,194 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	common "github.com/htstinson/stinsondataapi/api/commonweb"
+	"github.com/htstinson/stinsondataapi/api/internal/auth"
+)
+
+// Limit is a token bucket that refills at Rate tokens per second up to Burst.
+// A zero Rate disables the limit.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimitPolicy is the set of limits applied to one route group.
+type RateLimitPolicy struct {
+	Name    string
+	PerIP   Limit
+	PerUser Limit
+}
+
+// ParseLimit reads limits written as "<count>/<unit>[:burst]", for example
+// "10/s", "300/m:50" or "1000/h". The burst defaults to the count.
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "0" || s == "off" {
+		return Limit{}, nil
+	}
+
+	burst := -1
+	if i := strings.Index(s, ":"); i >= 0 {
+		b, err := strconv.Atoi(s[i+1:])
+		if err != nil || b < 1 {
+			return Limit{}, fmt.Errorf("invalid burst in %q", s)
+		}
+		burst = b
+		s = s[:i]
+	}
+
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) != 2 {
+		return Limit{}, fmt.Errorf("invalid limit %q, expected <count>/<unit>", s)
+	}
+
+	count, err := strconv.Atoi(parts[0])
+	if err != nil || count < 1 {
+		return Limit{}, fmt.Errorf("invalid count in %q", s)
+	}
+
+	var per time.Duration
+	switch parts[1] {
+	case "s", "sec", "second":
+		per = time.Second
+	case "m", "min", "minute":
+		per = time.Minute
+	case "h", "hour":
+		per = time.Hour
+	default:
+		return Limit{}, fmt.Errorf("invalid unit in %q", s)
+	}
+
+	if burst < 0 {
+		burst = count
+	}
+
+	return Limit{Rate: float64(count) / per.Seconds(), Burst: burst}, nil
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter applies a RateLimitPolicy per client IP and per authenticated user.
+type RateLimiter struct {
+	policy   RateLimitPolicy
+	resolver *ClientIPResolver
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewRateLimiter(policy RateLimitPolicy, resolver *ClientIPResolver) *RateLimiter {
+	return &RateLimiter{
+		policy:    policy,
+		resolver:  resolver,
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Middleware answers 429 with Retry-After once either bucket is empty. The
+// per-user limit only applies after JWTAuth.Middleware has put claims in the context.
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if l.policy.PerIP.Rate > 0 {
+			ip, ok := ClientIPFromContext(r.Context())
+			if !ok {
+				ip = l.resolver.ClientIP(r)
+			}
+			if wait, ok := l.allow("ip:"+ip.String(), l.policy.PerIP); !ok {
+				l.reject(w, r, "ip "+ip.String(), wait)
+				return
+			}
+		}
+
+		if l.policy.PerUser.Rate > 0 {
+			if claims, ok := r.Context().Value("user").(*auth.Claims); ok {
+				if wait, ok := l.allow("user:"+claims.UserID, l.policy.PerUser); !ok {
+					l.reject(w, r, "user "+claims.UserID, wait)
+					return
+				}
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allow takes a token from the bucket for key, or reports how long until one is available.
+func (l *RateLimiter) allow(key string, limit Limit) (time.Duration, bool) {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+
+	return time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second)), false
+}
+
+// sweep drops buckets that have been idle long enough to be full again.
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+
+	idle := 10 * time.Minute
+	for _, limit := range []Limit{l.policy.PerIP, l.policy.PerUser} {
+		if limit.Rate > 0 {
+			if refill := time.Duration(float64(limit.Burst) / limit.Rate * float64(time.Second)); refill > idle {
+				idle = refill
+			}
+		}
+	}
+
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > idle {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func (l *RateLimiter) reject(w http.ResponseWriter, r *http.Request, who string, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	slog.WarnContext(r.Context(), "rate limit exceeded", "policy", l.policy.Name, "key", who, "method", r.Method, "path", r.URL.Path)
+
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	common.RespondError(w, http.StatusTooManyRequests, "Too many requests")
+}
@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// Allowed is an address or range that must never be blocked.
+type Allowed struct {
+	ID        string    `json:"id"`
+	IP        string    `json:"ip" validate:"required,ip|cidr"`
+	Notes     string    `json:"notes" validate:"max=500"`
+	CreatedAt time.Time `json:"created_at"`
+}
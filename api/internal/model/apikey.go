@@ -0,0 +1,47 @@
+package model
+
+import "time"
+
+// APIKey lets a user, or a service account, call the API without logging
+// in. A key acts in one subscriber with a subset of the permissions its
+// user has there. Only the hash of the key is stored; Prefix, its first
+// characters, is kept so that keys can be told apart.
+type APIKey struct {
+	Id            string     `json:"id"`
+	User_Id       string     `json:"user_id"`
+	Subscriber_Id string     `json:"subscriber_id"`
+	Name          string     `json:"name"`
+	Prefix        string     `json:"prefix"`
+	Permissions   []string   `json:"permissions"`
+	Created_By    string     `json:"created_by,omitempty"`
+	Created_At    time.Time  `json:"created_at"`
+	Expires_At    time.Time  `json:"expires_at"`
+	Last_Used_At  *time.Time `json:"last_used_at,omitempty"`
+	Last_Used_IP  string     `json:"last_used_ip,omitempty"`
+	Revoked_At    *time.Time `json:"revoked_at,omitempty"`
+}
+
+// APIKeyCreateRequest creates a key in Subscriber_Id, or the subscriber of
+// the caller's token when empty. Without Expires_At the key lasts the
+// configured default.
+type APIKeyCreateRequest struct {
+	Name          string     `json:"name" validate:"required,max=100"`
+	Subscriber_Id string     `json:"subscriber_id" validate:"omitempty,uuid"`
+	Permissions   []string   `json:"permissions" validate:"max=100,dive,required,max=100"`
+	Expires_At    *time.Time `json:"expires_at"`
+}
+
+// APIKeyCreated is a new key together with the key itself, which is only
+// ever shown here.
+type APIKeyCreated struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// ServiceAccountCreateRequest creates a service account, a user that cannot
+// log in and only acts through its API keys, in a subscriber with a role.
+type ServiceAccountCreateRequest struct {
+	Name          string `json:"name" validate:"required,identifier"`
+	Subscriber_Id string `json:"subscriber_id" validate:"required,uuid"`
+	Role_Id       string `json:"role_id" validate:"required,uuid"`
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Audit actions
+const (
+	AuditCreate        = "create"
+	AuditUpdate        = "update"
+	AuditDelete        = "delete"
+	AuditLogin         = "login"
+	AuditLoginFailed   = "login_failed"
+	AuditLockout       = "lockout"
+	AuditPasswordReset = "password_reset"
+	AuditEmailVerified = "email_verified"
+
+	AuditMFAEnabled       = "mfa_enabled"
+	AuditMFADisabled      = "mfa_disabled"
+	AuditMFARecoveryCodes = "mfa_recovery_codes"
+)
+
+// AuditEvent records one change made through the repository, or one login.
+// Changes maps each changed field to its before and after values.
+type AuditEvent struct {
+	Id            string                 `json:"id"`
+	Occurred_At   time.Time              `json:"occurred_at"`
+	Action        string                 `json:"action"`
+	Actor_Id      string                 `json:"actor_id,omitempty"`
+	Actor_Name    string                 `json:"actor_name,omitempty"`
+	Subscriber_Id string                 `json:"subscriber_id,omitempty"`
+	Entity_Type   string                 `json:"entity_type"`
+	Entity_Id     string                 `json:"entity_id,omitempty"`
+	Changes       map[string]AuditChange `json:"changes,omitempty"`
+	Request_Id    string                 `json:"request_id,omitempty"`
+	IP_Address    string                 `json:"ip_address,omitempty"`
+}
+
+// AuditChange is the value of a field before and after a change; Before is
+// absent for creates and After for deletes.
+type AuditChange struct {
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
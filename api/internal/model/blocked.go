@@ -5,7 +5,7 @@ import "time"
 // Blocked
 type Blocked struct {
 	ID        string    `json:"id"`
-	IP        string    `json:"ip"`
-	Notes     string    `json:"notes"`
+	IP        string    `json:"ip" validate:"required,ip|cidr"`
+	Notes     string    `json:"notes" validate:"max=500"`
 	CreatedAt time.Time `json:"created_at"`
 }
@@ -4,16 +4,16 @@ import "time"
 
 // Contact
 type Contact struct {
-	Id             string    `json:"id"`
-	ParentId       string    `json:"parent_id"` // The parent_id is the customer's id value.
-	LastName       *string   `json:"last_name"`
-	FirstName      *string   `json:"first_name"`
-	Email          *string   `json:"email"`
-	Phone          *string   `json:"phone"`
-	JobTitle       *string   `json:"job_title"`
-	Department     *string   `json:"department"`
-	Schema_Name_   string    `json:"schema_name"`
-	Subscriber_Id_ string    `json:"subscriber_id"`
+	Id             string    `json:"id" validate:"omitempty,uuid"`
+	ParentId       string    `json:"parent_id" validate:"required,uuid"` // The parent_id is the customer's id value.
+	LastName       *string   `json:"last_name" validate:"omitempty,max=100"`
+	FirstName      *string   `json:"first_name" validate:"omitempty,max=100"`
+	Email          *string   `json:"email" validate:"omitempty,email,max=254"`
+	Phone          *string   `json:"phone" validate:"omitempty,phone"`
+	JobTitle       *string   `json:"job_title" validate:"omitempty,max=100"`
+	Department     *string   `json:"department" validate:"omitempty,max=100"`
+	Schema_Name_   string    `json:"schema_name" validate:"omitempty,identifier"`
+	Subscriber_Id_ string    `json:"subscriber_id" validate:"omitempty,uuid"`
 	CreatedAt      time.Time `json:"created_at"`
 	ModifiedAt     time.Time `json:"modified_at"`
 }
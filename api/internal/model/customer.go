@@ -4,10 +4,10 @@ import "time"
 
 // Customer
 type Customer struct {
-	Id            string    `json:"id"`
-	Name          string    `json:"name"`
-	Profile_Id    string    `json:"profile_id"`
-	Subscriber_Id string    `json:"subscriber_id"`
-	Schema_Name   string    `json:"schema_name"`
+	Id            string    `json:"id" validate:"omitempty,uuid"`
+	Name          string    `json:"name" validate:"required,max=200"`
+	Profile_Id    string    `json:"profile_id" validate:"omitempty,uuid"`
+	Subscriber_Id string    `json:"subscriber_id" validate:"omitempty,uuid"`
+	Schema_Name   string    `json:"schema_name" validate:"omitempty,identifier"`
 	CreatedAt     time.Time `json:"created_at"`
 }
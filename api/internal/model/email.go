@@ -0,0 +1,70 @@
+package model
+
+import "time"
+
+// Email statuses
+const (
+	EmailPending    = "pending"
+	EmailSent       = "sent"
+	EmailFailed     = "failed"
+	EmailSuppressed = "suppressed"
+)
+
+// Email categories. Account email, such as verification and password reset
+// links, is always sent; the others can be unsubscribed from.
+const (
+	EmailAccount  = "account"
+	EmailSecurity = "security"
+	EmailLogin    = "login"
+	EmailMention  = "mention"
+)
+
+// Email is a message in the outbox. It is rendered when queued and kept
+// after it is sent, failed or suppressed by an unsubscribe, so the outbox is
+// also the log of what was sent. Attempts count the sends tried, the next
+// of which is due at Next_Attempt_At.
+type Email struct {
+	Id                  string     `json:"id"`
+	Subscriber_Id       string     `json:"subscriber_id,omitempty"`
+	Template            string     `json:"template"`
+	Category            string     `json:"category"`
+	To                  string     `json:"to"`
+	Subject             string     `json:"subject"`
+	Text_Body           string     `json:"text_body"`
+	Html_Body           string     `json:"html_body,omitempty"`
+	Unsubscribe_URL     string     `json:"unsubscribe_url,omitempty"`
+	Status              string     `json:"status"`
+	Attempts            int        `json:"attempts"`
+	Next_Attempt_At     time.Time  `json:"next_attempt_at"`
+	Last_Error          string     `json:"last_error,omitempty"`
+	Provider_Message_Id string     `json:"provider_message_id,omitempty"`
+	Created_At          time.Time  `json:"created_at"`
+	Sent_At             *time.Time `json:"sent_at,omitempty"`
+}
+
+// EmailTemplate is the subject and bodies of one kind of email, written as
+// Go templates. A subscriber's template replaces the built in one of the
+// same name for email sent on its behalf. Html_Body is optional.
+type EmailTemplate struct {
+	Subscriber_Id string     `json:"subscriber_id,omitempty"`
+	Name          string     `json:"name"`
+	Category      string     `json:"category"`
+	Subject       string     `json:"subject" validate:"required,max=255"`
+	Text_Body     string     `json:"text_body" validate:"required,max=65536"`
+	Html_Body     string     `json:"html_body" validate:"max=262144"`
+	Updated_At    *time.Time `json:"updated_at,omitempty"`
+}
+
+// EmailUnsubscribeRequest unsubscribes with the token of an unsubscribe
+// link.
+type EmailUnsubscribeRequest struct {
+	Token string `json:"token" validate:"required,max=1000"`
+}
+
+// EmailUnsubscribe says whether Email receives the category of email an
+// unsubscribe link is for.
+type EmailUnsubscribe struct {
+	Email        string `json:"email"`
+	Category     string `json:"category"`
+	Unsubscribed bool   `json:"unsubscribed"`
+}
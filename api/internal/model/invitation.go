@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+// Invitation statuses
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationRevoked  = "revoked"
+	InvitationExpired  = "expired"
+)
+
+// Invitation asks the owner of Email to join a subscriber with a role. It is
+// sent as a link with a token that expires, and accepting it creates the
+// user, or finds the one with that email, and adds them in one step.
+type Invitation struct {
+	Id            string     `json:"id"`
+	Email         string     `json:"email" validate:"required,email,max=255"`
+	Subscriber_Id string     `json:"subscriber_id" validate:"required,uuid"`
+	Role_Id       string     `json:"role_id" validate:"required,uuid"`
+	Invited_By    string     `json:"invited_by,omitempty"`
+	Status        string     `json:"status"`
+	User_Id       string     `json:"user_id,omitempty"`
+	Created_At    time.Time  `json:"created_at"`
+	Expires_At    time.Time  `json:"expires_at"`
+	Accepted_At   *time.Time `json:"accepted_at,omitempty"`
+	Revoked_At    *time.Time `json:"revoked_at,omitempty"`
+}
+
+// InvitationAcceptRequest accepts an invitation with the token of its link.
+// Password is only needed, and only used, when no user has the invited
+// email yet.
+type InvitationAcceptRequest struct {
+	Token    string `json:"token" validate:"required,max=100"`
+	Password string `json:"password" validate:"omitempty,max=72"`
+}
@@ -4,7 +4,7 @@ import "time"
 
 // Item
 type Item struct {
-	ID        string    `json:"id"`
-	Name      string    `json:"name"`
+	ID        string    `json:"id" validate:"omitempty,uuid"`
+	Name      string    `json:"name" validate:"required,max=100"`
 	CreatedAt time.Time `json:"created_at"`
 }
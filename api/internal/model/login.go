@@ -2,11 +2,47 @@ package model
 
 // All
 type LoginRequest struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Username string `json:"username" validate:"required,max=100"`
+	Password string `json:"password" validate:"required,max=72"`
 }
 
+// LoginResponse carries the token, or when a second factor is needed an
+// mfa_token and MFA saying what to do with it: "verify" a code, or "enroll"
+// because the user's subscriber requires MFA. RecoveryCodes are only set
+// when a login completes an enrolment.
 type LoginResponse struct {
-	Token     string `json:"token"`
-	ExpiresIn int64  `json:"expires_in"`
+	Token         string   `json:"token,omitempty"`
+	ExpiresIn     int64    `json:"expires_in"`
+	MFA_Token     string   `json:"mfa_token,omitempty"`
+	MFA           string   `json:"mfa,omitempty"`
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+// RegisterRequest registers a user, whose username is their email.
+type RegisterRequest struct {
+	Username string `json:"username" validate:"required,email,max=100"`
+	Password string `json:"password" validate:"required,max=72"`
+}
+
+// EmailVerificationRequest confirms an email with the token of the link
+// sent to it.
+type EmailVerificationRequest struct {
+	Token string `json:"token" validate:"required,max=100"`
+}
+
+// EmailVerificationResendRequest asks for another verification link for
+// Username.
+type EmailVerificationResendRequest struct {
+	Username string `json:"username" validate:"required,max=100"`
+}
+
+// PasswordForgotRequest asks for a password reset link for Username.
+type PasswordForgotRequest struct {
+	Username string `json:"username" validate:"required,max=100"`
+}
+
+// PasswordResetRequest sets a new password with the token of a reset link.
+type PasswordResetRequest struct {
+	Token    string `json:"token" validate:"required,max=100"`
+	Password string `json:"password" validate:"required,max=72"`
 }
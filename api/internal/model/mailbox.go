@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// MailboxSource is a Gmail label whose messages, such as Google Alerts, are
+// read into the results of one of the subscriber's search definition
+// engines, which ties them to its search definition. Each message is read
+// once; Messages counts those read so far.
+type MailboxSource struct {
+	Id                          string     `json:"id"`
+	Subscriber_Id               string     `json:"subscriber_id"`
+	Label                       string     `json:"label" validate:"required,max=225"`
+	Search_Definition_Engine_Id string     `json:"search_definition_engine_id" validate:"required,uuid"`
+	Paused                      bool       `json:"paused"`
+	Messages                    int        `json:"messages"`
+	Last_Polled_At              *time.Time `json:"last_polled_at,omitempty"`
+	Last_Error                  string     `json:"last_error,omitempty"`
+	Created_At                  time.Time  `json:"created_at"`
+}
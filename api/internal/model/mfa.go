@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// UserMFA is a user's TOTP enrolment. Secret is sealed and never sent; the
+// enrolment only counts once Enabled, after a code has been verified.
+type UserMFA struct {
+	User_Id             string     `json:"user_id"`
+	Secret              string     `json:"-"`
+	Enabled             bool       `json:"enabled"`
+	Created_At          time.Time  `json:"created_at"`
+	Enabled_At          *time.Time `json:"enabled_at,omitempty"`
+	Recovery_Codes_Left int        `json:"recovery_codes_left"`
+}
+
+// MFASetup is what an authenticator app needs to enrol: the secret and the
+// provisioning URI to show as a QR code.
+type MFASetup struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// MFACodeRequest carries a code from the authenticator app, or a recovery
+// code where one is accepted.
+type MFACodeRequest struct {
+	Code string `json:"code" validate:"required,max=32"`
+}
+
+// MFATokenRequest carries the mfa_token Login returned.
+type MFATokenRequest struct {
+	MFA_Token string `json:"mfa_token" validate:"required"`
+}
+
+// MFALoginRequest completes a login with the mfa_token Login returned.
+type MFALoginRequest struct {
+	MFA_Token string `json:"mfa_token" validate:"required"`
+	Code      string `json:"code" validate:"required,max=32"`
+}
+
+// MFARecoveryCodes are shown once, when they are generated.
+type MFARecoveryCodes struct {
+	Codes []string `json:"recovery_codes"`
+}
@@ -0,0 +1,98 @@
+package model
+
+import "time"
+
+// Notification events
+const (
+	NotificationLogin         = "login"
+	NotificationMention       = "mention"
+	NotificationSecurity      = "security"
+	NotificationMentionDigest = "digest"
+)
+
+// Digest frequencies
+const (
+	DigestOff    = "off"
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// NotificationChannels are the channels an event is sent on.
+type NotificationChannels struct {
+	Email   bool `json:"email"`
+	Webhook bool `json:"webhook"`
+}
+
+// NotificationDigest is how often a digest of new mentions is sent on each
+// channel.
+type NotificationDigest struct {
+	Email   string `json:"email" validate:"omitempty,oneof=off daily weekly"`
+	Webhook string `json:"webhook" validate:"omitempty,oneof=off daily weekly"`
+}
+
+// NotificationPreferences are what a user is notified of, and how. Those
+// without a subscriber are the user's defaults; those with one apply in
+// that subscriber instead. Webhook_Secret signs the webhooks and is never
+// sent back; Webhook_Signed says whether there is one.
+type NotificationPreferences struct {
+	User_Id         string               `json:"user_id"`
+	Subscriber_Id   string               `json:"subscriber_id,omitempty"`
+	Login_Alerts    NotificationChannels `json:"login_alerts"`
+	Mention_Alerts  NotificationChannels `json:"mention_alerts"`
+	Security_Events NotificationChannels `json:"security_events"`
+	Digest          NotificationDigest   `json:"digest"`
+	Webhook_URL     string               `json:"webhook_url,omitempty"`
+	Webhook_Secret  string               `json:"-"`
+	Webhook_Signed  bool                 `json:"webhook_signed"`
+	Inherited       bool                 `json:"inherited"`
+	Updated_At      *time.Time           `json:"updated_at,omitempty"`
+}
+
+// Channels returns the channels event is sent on.
+func (p NotificationPreferences) Channels(event string) NotificationChannels {
+	switch event {
+	case NotificationLogin:
+		return p.Login_Alerts
+	case NotificationMention:
+		return p.Mention_Alerts
+	case NotificationSecurity:
+		return p.Security_Events
+	}
+	return NotificationChannels{}
+}
+
+// NotificationPreferencesRequest sets preferences. A Webhook_Secret replaces
+// the current one and an empty one removes it; leaving it out keeps it.
+type NotificationPreferencesRequest struct {
+	Login_Alerts    NotificationChannels `json:"login_alerts"`
+	Mention_Alerts  NotificationChannels `json:"mention_alerts"`
+	Security_Events NotificationChannels `json:"security_events"`
+	Digest          NotificationDigest   `json:"digest"`
+	Webhook_URL     string               `json:"webhook_url" validate:"omitempty,url,max=2000"`
+	Webhook_Secret  *string              `json:"webhook_secret" validate:"omitempty,max=200"`
+}
+
+// Notification is the body of a webhook.
+type Notification struct {
+	Event         string         `json:"event"`
+	Template      string         `json:"template"`
+	User_Id       string         `json:"user_id"`
+	Subscriber_Id string         `json:"subscriber_id,omitempty"`
+	Time          time.Time      `json:"time"`
+	Data          map[string]any `json:"data,omitempty"`
+}
+
+// DigestRecipient is a user of a subscriber who may be sent its digests.
+type DigestRecipient struct {
+	User_Id       string
+	Username      string
+	Subscriber_Id string
+}
+
+// DigestMention is a search result listed in a digest.
+type DigestMention struct {
+	Search     string    `json:"search"`
+	Title      string    `json:"title"`
+	Link       string    `json:"link"`
+	Created_At time.Time `json:"created_at"`
+}
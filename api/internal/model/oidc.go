@@ -0,0 +1,49 @@
+package model
+
+import "time"
+
+// SubscriberOIDC is a subscriber's OpenID Connect provider, such as Google
+// Workspace or Microsoft Entra, that its users may log in with.
+// Client_Secret_Name names the client secret in the secrets provider; it is
+// left empty for public clients, which rely on PKCE alone.
+//
+// Users are matched by their identity at the provider, then by their email
+// when the provider has verified it; an existing account is only linked
+// when it already belongs to the subscriber. When JIT_Provisioning is on,
+// users not found are created and added to the subscriber with
+// Default_Role_Id. A non-empty Allowed_Domains refuses emails of any other
+// domain.
+type SubscriberOIDC struct {
+	Subscriber_Id      string    `json:"subscriber_id"`
+	Issuer             string    `json:"issuer" validate:"required,url,max=255"`
+	Client_Id          string    `json:"client_id" validate:"required,max=255"`
+	Client_Secret_Name string    `json:"client_secret_name" validate:"omitempty,max=255"`
+	Allowed_Domains    []string  `json:"allowed_domains" validate:"omitempty,dive,hostname,max=255"`
+	JIT_Provisioning   bool      `json:"jit_provisioning"`
+	Default_Role_Id    string    `json:"default_role_id" validate:"omitempty,uuid"`
+	Enabled            bool      `json:"enabled"`
+	Created_At         time.Time `json:"created_at"`
+	Updated_At         time.Time `json:"updated_at"`
+}
+
+// UserIdentity links a user to their subject at an OpenID provider.
+type UserIdentity struct {
+	Id            string     `json:"id"`
+	User_Id       string     `json:"user_id"`
+	Subscriber_Id string     `json:"subscriber_id"`
+	Issuer        string     `json:"issuer"`
+	Subject       string     `json:"subject"`
+	Email         string     `json:"email"`
+	Created_At    time.Time  `json:"created_at"`
+	Last_Login_At *time.Time `json:"last_login_at,omitempty"`
+}
+
+// OIDCState is a single sign-on started and not yet returned from the
+// provider, stored by the hash of its state parameter.
+type OIDCState struct {
+	State_Hash    string
+	Subscriber_Id string
+	Code_Verifier string
+	Nonce         string
+	Expires_At    time.Time
+}
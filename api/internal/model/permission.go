@@ -4,10 +4,10 @@ import "time"
 
 // Permission
 type Permission struct {
-	Id          string    `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Object_Id   string    `json:"object_id"`
+	Id          string    `json:"id" validate:"omitempty,uuid"`
+	Name        string    `json:"name" validate:"required,max=100"`
+	Description string    `json:"description" validate:"max=500"`
+	Object_Id   string    `json:"object_id" validate:"omitempty,uuid"`
 	CreatedAt   time.Time `json:"created_at"`
 }
 
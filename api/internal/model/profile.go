@@ -4,25 +4,25 @@ import "time"
 
 // Customer
 type Profile struct {
-	Id             string    `json:"id"`
-	Subscriber_Id  string    `json:"parentid"`
-	Legal_Name     *string   `json:"legal_name"`
-	Phone          *string   `json:"phone"`
-	Fax            *string   `json:"fax"`
-	Email          *string   `json:"email"`
-	Website        *string   `json:"website"`
-	LinkedIn       *string   `json:"linkedin"`
-	Facebook       *string   `json:"facebook"`
-	Instagram      *string   `json:"instagram"`
-	X              *string   `json:"x"`
-	YouTube        *string   `json:"youtube"`
-	Pinterest      *string   `json:"pinterest"`
-	GoogleBusiness *string   `json:"google_business"`
-	Yelp           *string   `json:"yelp"`
-	GlassDoor      *string   `json:"glassdoor"`
-	Github         *string   `json:"github"`
-	NextDoor       *string   `json:"nextdoor"`
-	Bizapedia      *string   `json:"bizapedia"`
+	Id             string    `json:"id" validate:"omitempty,uuid"`
+	Subscriber_Id  string    `json:"parentid" validate:"omitempty,uuid"`
+	Legal_Name     *string   `json:"legal_name" validate:"omitempty,max=200"`
+	Phone          *string   `json:"phone" validate:"omitempty,phone"`
+	Fax            *string   `json:"fax" validate:"omitempty,phone"`
+	Email          *string   `json:"email" validate:"omitempty,email,max=254"`
+	Website        *string   `json:"website" validate:"omitempty,url,max=500"`
+	LinkedIn       *string   `json:"linkedin" validate:"omitempty,url,max=500"`
+	Facebook       *string   `json:"facebook" validate:"omitempty,url,max=500"`
+	Instagram      *string   `json:"instagram" validate:"omitempty,url,max=500"`
+	X              *string   `json:"x" validate:"omitempty,url,max=500"`
+	YouTube        *string   `json:"youtube" validate:"omitempty,url,max=500"`
+	Pinterest      *string   `json:"pinterest" validate:"omitempty,url,max=500"`
+	GoogleBusiness *string   `json:"google_business" validate:"omitempty,url,max=500"`
+	Yelp           *string   `json:"yelp" validate:"omitempty,url,max=500"`
+	GlassDoor      *string   `json:"glassdoor" validate:"omitempty,url,max=500"`
+	Github         *string   `json:"github" validate:"omitempty,url,max=500"`
+	NextDoor       *string   `json:"nextdoor" validate:"omitempty,url,max=500"`
+	Bizapedia      *string   `json:"bizapedia" validate:"omitempty,url,max=500"`
 	CreatedAt      time.Time `json:"created_at"`
 	ModifiedAt     time.Time `json:"modified_at"`
 }
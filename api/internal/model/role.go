@@ -9,7 +9,7 @@ type Roles struct {
 }
 
 type Role struct {
-	Id        string    `json:"id"`
-	Name      string    `json:"name"`
+	Id        string    `json:"id" validate:"omitempty,uuid"`
+	Name      string    `json:"name" validate:"required,max=100"`
 	CreatedAt time.Time `json:"created_at"`
 }
@@ -3,8 +3,8 @@ package model
 import "time"
 
 type Role_Permission struct {
-	Role_Id       string    `json:"role_id"`
-	Permission_Id string    `json:"permission_id"`
+	Role_Id       string    `json:"role_id" validate:"required,uuid"`
+	Permission_Id string    `json:"permission_id" validate:"required,uuid"`
 	CreatedAt     time.Time `json:"created_at"`
 }
 
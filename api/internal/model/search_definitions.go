@@ -3,17 +3,17 @@ package model
 import "time"
 
 type SearchDefinition struct {
-	Id           string    `json:"id"`
+	Id           string    `json:"id" validate:"omitempty,uuid"`
 	CreatedAt    time.Time `json:"created_at"`
 	ModifiedAt   time.Time `json:"modified_at"`
-	Name         string    `json:"name"`
-	Query        string    `json:"query"`
-	Comment      *string   `json:"common"`
+	Name         string    `json:"name" validate:"required,max=200"`
+	Query        string    `json:"query" validate:"required,max=2048"`
+	Comment      *string   `json:"common" validate:"omitempty,max=1000"`
 	ExactMatch   bool      `json:"exact_match"`
-	MaxResults   int       `json:"max_results"`
+	MaxResults   int       `json:"max_results" validate:"min=0,max=100"`
 	SortByDate   bool      `json:"sort_by_date"`
 	StartDate    time.Time `json:"start_date"`
-	EndDate      time.Time `json:"end_date"`
-	SearchType   string    `json:"search_type"`
-	SubscriberId string    `json:"subscriber_id"`
+	EndDate      time.Time `json:"end_date" validate:"omitempty,gtefield=StartDate"`
+	SearchType   string    `json:"search_type" validate:"omitempty,oneof=custom"`
+	SubscriberId string    `json:"subscriber_id" validate:"omitempty,uuid"`
 }
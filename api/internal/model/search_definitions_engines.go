@@ -3,12 +3,12 @@ package model
 import "time"
 
 type SearchDefinitionEngines struct {
-	Id                  string    `json:"id"`
+	Id                  string    `json:"id" validate:"omitempty,uuid"`
 	CreatedAt           time.Time `json:"created_at"`
 	ModifiedAt          time.Time `json:"modified_at"`
-	SearchEngineId      string    `json:"search_engine_id"`
-	SearchDefinitionsId string    `json:"search_definitions_id"`
-	SubscriberId        string    `json:"subscriber_id"`
+	SearchEngineId      string    `json:"search_engine_id" validate:"required,uuid"`
+	SearchDefinitionsId string    `json:"search_definitions_id" validate:"required,uuid"`
+	SubscriberId        string    `json:"subscriber_id" validate:"omitempty,uuid"`
 }
 
 type SearchDefinitionEnginesView struct {
@@ -3,11 +3,11 @@ package model
 import "time"
 
 type SearchEngine struct {
-	Id             string    `json:"id"`
-	SubscriberId   string    `json:"subscriber_id"`
+	Id             string    `json:"id" validate:"omitempty,uuid"`
+	SubscriberId   string    `json:"subscriber_id" validate:"omitempty,uuid"`
 	CreatedAt      time.Time `json:"created_at"`
 	ModifiedAt     time.Time `json:"modified_at"`
-	Name           string    `json:"name"`
-	SearchEngineId string    `json:"search_engine_id"`
-	Comment        *string   `json:"comment"`
+	Name           string    `json:"name" validate:"required,max=100"`
+	SearchEngineId string    `json:"search_engine_id" validate:"required,max=100"`
+	Comment        *string   `json:"comment" validate:"omitempty,max=1000"`
 }
@@ -0,0 +1,22 @@
+package model
+
+// Tenant is a subscriber the user belongs to and their roles in it.
+type Tenant struct {
+	Subscriber_Id   string   `json:"subscriber_id"`
+	Subscriber_Name string   `json:"subscriber_name"`
+	Roles           []string `json:"roles"`
+}
+
+// SessionSubscriberRequest selects the active subscriber of a session.
+type SessionSubscriberRequest struct {
+	Subscriber_Id string `json:"subscriber_id" validate:"required,uuid"`
+}
+
+// Me is the current user, the subscriber their token is scoped to, and the
+// subscribers they can switch to.
+type Me struct {
+	User                 *User    `json:"user"`
+	Active_Subscriber_Id string   `json:"active_subscriber_id,omitempty"`
+	Roles                string   `json:"roles"`
+	Tenants              []Tenant `json:"tenants"`
+}
@@ -6,9 +6,11 @@ import (
 
 // Subscriber
 type Subscriber struct {
-	Id          string    `json:"id"`
-	Name        string    `json:"name"`
+	Id          string    `json:"id" validate:"omitempty,uuid"`
+	Name        string    `json:"name" validate:"required,min=3,max=100"`
 	CreatedAt   time.Time `json:"created_at"`
-	Schema_Name string    `json:"schema_name"`
-	Profile     *Profile  `json:"profile"`
+	Schema_Name string    `json:"schema_name" validate:"omitempty,identifier"`
+	// MFA_Required makes every user of the subscriber enrol in MFA
+	MFA_Required bool     `json:"mfa_required"`
+	Profile      *Profile `json:"profile"`
 }
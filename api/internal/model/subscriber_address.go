@@ -4,16 +4,16 @@ import "time"
 
 // Customer
 type Address struct {
-	Id           string    `json:"id"`
-	SubscriberId string    `json:"subscriber_id"`
+	Id           string    `json:"id" validate:"omitempty,uuid"`
+	SubscriberId string    `json:"subscriber_id" validate:"omitempty,uuid"`
 	CreatedAt    time.Time `json:"created_at"`
 	ModifiedAt   time.Time `json:"modified_at"`
-	AddressType  *string   `json:"address_type"`
-	AddressUse   *string   `json:"address_use"`
-	Street1      *string   `json:"street1"`
-	Street2      *string   `json:"street2"`
-	POBox        *string   `json:"po_box"`
-	City         *string   `json:"city"`
-	State        *string   `json:"state"`
-	Zip          *string   `json:"zip"`
+	AddressType  *string   `json:"address_type" validate:"omitempty,oneof=physical mailing billing shipping"`
+	AddressUse   *string   `json:"address_use" validate:"omitempty,max=50"`
+	Street1      *string   `json:"street1" validate:"omitempty,max=200"`
+	Street2      *string   `json:"street2" validate:"omitempty,max=200"`
+	POBox        *string   `json:"po_box" validate:"omitempty,max=50"`
+	City         *string   `json:"city" validate:"omitempty,max=100"`
+	State        *string   `json:"state" validate:"omitempty,max=50"`
+	Zip          *string   `json:"zip" validate:"omitempty,max=10"`
 }
@@ -3,11 +3,11 @@ package model
 import "time"
 
 type Background struct {
-	Id           string    `json:"id"`
-	SubscriberId string    `json:"subscriber_id"`
+	Id           string    `json:"id" validate:"omitempty,uuid"`
+	SubscriberId string    `json:"subscriber_id" validate:"omitempty,uuid"`
 	CreatedAt    time.Time `json:"created_at"`
 	ModifiedAt   time.Time `json:"modified_at"`
-	Topic        *string   `json:"topic"`
-	Summary      *string   `json:"summary"`
-	Details      *string   `json:"details"`
+	Topic        *string   `json:"topic" validate:"omitempty,max=200"`
+	Summary      *string   `json:"summary" validate:"omitempty,max=2000"`
+	Details      *string   `json:"details" validate:"omitempty,max=20000"`
 }
@@ -12,8 +12,8 @@ type Subscriber_Item_View struct {
 }
 
 type Subscriber_Item struct {
-	Id            string    `json:"id"`
-	Item_ID       string    `json:"item_id"`
-	Subscriber_Id string    `json:"subscriber_id"`
+	Id            string    `json:"id" validate:"omitempty,uuid"`
+	Item_ID       string    `json:"item_id" validate:"required,uuid"`
+	Subscriber_Id string    `json:"subscriber_id" validate:"required,uuid"`
 	CreatedAt_At  time.Time `json:"created_at"`
 }
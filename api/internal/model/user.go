@@ -1,26 +1,38 @@
 package model
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // User
 type User struct {
-	ID           string    `json:"id"`
-	Username     string    `json:"username"`
+	ID           string    `json:"id" validate:"omitempty,uuid"`
+	Username     string    `json:"username" validate:"required,email,max=100"`
 	PasswordHash string    `json:"-"` // Never send password hash in JSON
 	CreatedAt    time.Time `json:"created_at"`
 	Roles        string    `json:"roles"`
-	IP_address   string    `json:"ip_address"`
+	IP_address   string    `json:"ip_address" validate:"omitempty,ip"`
+	// Email_Verified_At is nil until a registered user confirms their email
+	Email_Verified_At *time.Time `json:"email_verified_at,omitempty"`
+	// Service_Account users cannot log in and only act through API keys
+	Service_Account bool `json:"service_account,omitempty"`
+
+	// Login pacing, only read by GetUserByUsername
+	FailedLogins    int        `json:"-"`
+	LastFailedLogin *time.Time `json:"-"`
+	LockedUntil     *time.Time `json:"-"`
 }
 
-type CurrentUser struct {
-	ID         string                  `json:"user_id"`
-	Username   string                  `json:"username"`
-	Roles      string                  `json:"roles"`
-	IP_address string                  `json:"ip_address"`
-	Subscribed []CurrentUserSubscribed `json:"subscribed"`
+// NormalizeUsername is how usernames, which are email addresses, are
+// stored and compared.
+func NormalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
 }
 
-type CurrentUserSubscribed struct {
-	ID            string `json:"id"`
-	Subscriber_ID string `json:"subscriber_id"`
+// CreateUserRequest creates a user. Without a password the user is sent a
+// link to choose one.
+type CreateUserRequest struct {
+	Username string `json:"username" validate:"required,email,max=100"`
+	Password string `json:"password" validate:"omitempty,max=72"`
 }
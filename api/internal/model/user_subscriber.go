@@ -4,17 +4,17 @@ import "time"
 
 // User_Customrer
 type User_Subscriber struct {
-	Id            string    `json:"id"`
-	User_ID       string    `json:"user_id"`
-	Subscriber_Id string    `json:"subscriber_id"`
+	Id            string    `json:"id" validate:"omitempty,uuid"`
+	User_ID       string    `json:"user_id" validate:"required,uuid"`
+	Subscriber_Id string    `json:"subscriber_id" validate:"required,uuid"`
 	Assigned_At   time.Time `json:"assigned_at"`
 }
 
 // User_Customrer_View
 type User_Subscriber_View struct {
-	Id              string    `json:"id"`
-	User_ID         string    `json:"user_id"`
-	Subscriber_Id   string    `json:"subscriber_id"`
+	Id              string    `json:"id" validate:"omitempty,uuid"`
+	User_ID         string    `json:"user_id" validate:"omitempty,uuid"`
+	Subscriber_Id   string    `json:"subscriber_id" validate:"omitempty,uuid"`
 	User_Username   string    `json:"user_username"`
 	Subscriber_Name string    `json:"subscriber_name"`
 	Assigned_At     time.Time `json:"assigned_at"`
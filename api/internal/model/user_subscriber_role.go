@@ -4,9 +4,9 @@ import "time"
 
 // User_Subscriber_Role
 type User_Subscriber_Role struct {
-	Id                 string    `json:"id"`
-	User_Subscriber_ID string    `json:"user_subscriber_id"`
-	Role_Id            string    `json:"role_id"`
+	Id                 string    `json:"id" validate:"omitempty,uuid"`
+	User_Subscriber_ID string    `json:"user_subscriber_id" validate:"required,uuid"`
+	Role_Id            string    `json:"role_id" validate:"required,uuid"`
 	Created_At         time.Time `json:"assigned_at"`
 	Updated_At         time.Time `json:"updated_at"`
 }
@@ -0,0 +1,301 @@
+// Package oidc is the relying party side of OpenID Connect: it discovers a
+// provider's endpoints and keys from its issuer URL and verifies the ID
+// tokens it issues. The authorization code flow itself goes through
+// golang.org/x/oauth2, with the endpoints found here.
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// discoveryTTL is how long a provider's configuration and keys are kept.
+	discoveryTTL = time.Hour
+	// refreshInterval is the least time between fetches of the keys when a
+	// token names a key that is not known, as after a rotation.
+	refreshInterval = time.Minute
+)
+
+var ErrInvalidToken = errors.New("invalid ID token")
+
+// Claims are the claims of an ID token that are used to find or provision
+// the user.
+type Claims struct {
+	Email             string `json:"email"`
+	EmailVerified     *bool  `json:"email_verified"`
+	PreferredUsername string `json:"preferred_username"`
+	Name              string `json:"name"`
+	Nonce             string `json:"nonce"`
+	jwt.RegisteredClaims
+}
+
+// EmailAddress is the user's email. preferred_username is not used even
+// when it looks like one: Microsoft Entra lets users change it and does not
+// verify it.
+func (c *Claims) EmailAddress() string {
+	return strings.ToLower(c.Email)
+}
+
+// Verified reports whether the provider says it has verified the email.
+func (c *Claims) Verified() bool {
+	return c.EmailVerified != nil && *c.EmailVerified
+}
+
+// Provider is a discovered OpenID provider.
+type Provider struct {
+	Issuer   string
+	Endpoint oauth2.Endpoint
+	jwksURL  string
+
+	client    *http.Client
+	mu        sync.Mutex
+	keys      map[string]any
+	fetched   time.Time
+	refreshed time.Time
+}
+
+// Client discovers providers and caches them by issuer.
+type Client struct {
+	http      *http.Client
+	mu        sync.Mutex
+	providers map[string]*Provider
+}
+
+// NewClient returns a Client that makes its requests with httpClient, or
+// with a client that times out after ten seconds when nil.
+func NewClient(httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Client{http: httpClient, providers: map[string]*Provider{}}
+}
+
+// HTTPClient is the client requests to providers are made with, to be put
+// in the context given to oauth2.
+func (c *Client) HTTPClient() *http.Client {
+	return c.http
+}
+
+// Provider returns the provider of issuer, discovering it from its
+// /.well-known/openid-configuration when not cached.
+func (c *Client) Provider(ctx context.Context, issuer string) (*Provider, error) {
+	c.mu.Lock()
+	p, ok := c.providers[issuer]
+	c.mu.Unlock()
+	if ok && time.Since(p.fetched) < discoveryTTL {
+		return p, nil
+	}
+
+	var doc struct {
+		Issuer                string `json:"issuer"`
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		JWKSURI               string `json:"jwks_uri"`
+	}
+	if err := c.getJSON(ctx, strings.TrimSuffix(issuer, "/")+"/.well-known/openid-configuration", &doc); err != nil {
+		return nil, fmt.Errorf("error discovering %s: %w", issuer, err)
+	}
+	if doc.Issuer != issuer {
+		return nil, fmt.Errorf("error discovering %s: the provider names itself %s", issuer, doc.Issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, fmt.Errorf("error discovering %s: endpoints missing", issuer)
+	}
+
+	p = &Provider{
+		Issuer:   issuer,
+		Endpoint: oauth2.Endpoint{AuthURL: doc.AuthorizationEndpoint, TokenURL: doc.TokenEndpoint},
+		jwksURL:  doc.JWKSURI,
+		client:   c.http,
+	}
+	if err := p.fetchKeys(ctx); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.providers[issuer] = p
+	c.mu.Unlock()
+	return p, nil
+}
+
+// Verify checks the signature of rawToken against the provider's keys, that
+// it was issued by the provider for clientID and has not expired, and that
+// it carries nonce, and returns its claims.
+func (p *Provider) Verify(ctx context.Context, rawToken string, clientID string, nonce string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(rawToken, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+		switch token.Method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+		default:
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	if !claims.VerifyIssuer(p.Issuer, true) {
+		return nil, fmt.Errorf("%w: issued by %s", ErrInvalidToken, claims.Issuer)
+	}
+	if !claims.VerifyAudience(clientID, true) {
+		return nil, fmt.Errorf("%w: not issued for this client", ErrInvalidToken)
+	}
+	if !claims.VerifyExpiresAt(time.Now(), true) {
+		return nil, fmt.Errorf("%w: expired", ErrInvalidToken)
+	}
+	if claims.Nonce != nonce {
+		return nil, fmt.Errorf("%w: nonce does not match", ErrInvalidToken)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidToken)
+	}
+
+	return claims, nil
+}
+
+// key returns the key kid names, fetching the keys again when it is not
+// known, at most once per refreshInterval.
+func (p *Provider) key(ctx context.Context, kid string) (any, error) {
+	p.mu.Lock()
+	key, ok := p.lookup(kid)
+	stale := !ok && time.Since(p.refreshed) >= refreshInterval
+	p.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+	if !stale {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+
+	if err := p.fetchKeys(ctx); err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key %q", kid)
+}
+
+// lookup finds the key kid names, or the only key when the token names none.
+func (p *Provider) lookup(kid string) (any, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+func (p *Provider) fetchKeys(ctx context.Context) error {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := getJSON(ctx, p.client, p.jwksURL, &set); err != nil {
+		return fmt.Errorf("error fetching keys of %s: %w", p.Issuer, err)
+	}
+
+	keys := map[string]any{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if key, err := k.publicKey(); err == nil {
+			keys[k.Kid] = key
+		}
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("error fetching keys of %s: no signing keys", p.Issuer)
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.fetched = time.Now()
+	p.refreshed = p.fetched
+	p.mu.Unlock()
+	return nil
+}
+
+// jwk is a JSON Web Key, of which RSA and EC P-256 keys are read.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jwk) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+}
+
+func (c *Client) getJSON(ctx context.Context, url string, v any) error {
+	return getJSON(ctx, c.http, url, v)
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s responded %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
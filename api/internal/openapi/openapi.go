@@ -0,0 +1,265 @@
+// Package openapi generates an OpenAPI 3.1 document from the mux route table.
+// Every route registered on the walked routers appears in the document;
+// Operation metadata, keyed by operation ID, adds summaries and the request
+// and response models, whose schemas are derived from their json and
+// validate struct tags.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/mux"
+	common "github.com/htstinson/stinsondataapi/api/commonweb"
+)
+
+// Operation documents one route. Routes are matched to their Operation by
+// operation ID, which is the route's name if it has one and otherwise the
+// name of its handler method, such as "CreateItem".
+type Operation struct {
+	Summary     string
+	Description string
+	// Request is a value of the request body type, nil for none.
+	Request any
+	// Response is a value of the success response body type, nil for none.
+	Response any
+	// Status is the success status; 0 means 200.
+	Status int
+	Query  []Parameter
+	// Permission is the permission an API key needs for the route; API
+	// keys are refused routes without one.
+	Permission string
+}
+
+// Parameter is a query string parameter.
+type Parameter struct {
+	Name        string
+	Description string
+	// Type is a JSON Schema type; "" means string.
+	Type string
+}
+
+// Generator builds the document from the routers passed to AddRoutes.
+type Generator struct {
+	operations map[string]Operation
+	prefix     string
+	doc        *Document
+	schemas    *schemas
+	problem    *Schema
+	ids        map[string]int
+
+	undocumented []string
+
+	once sync.Once
+	body []byte
+	err  error
+}
+
+// New returns a generator for an API served under prefix, such as "/api/v1".
+// problem is the error response model.
+func New(info Info, prefix string, operations map[string]Operation, problem any) *Generator {
+	g := &Generator{
+		operations: operations,
+		prefix:     prefix,
+		schemas:    newSchemas(),
+		ids:        make(map[string]int),
+		doc: &Document{
+			OpenAPI: "3.1.0",
+			Info:    info,
+			Servers: []Server{{URL: prefix}},
+			Paths:   make(map[string]*PathItem),
+			Components: Components{
+				SecuritySchemes: map[string]*SecurityScheme{
+					"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+					"apiKeyAuth": {Type: "apiKey", In: "header", Name: "Authorization", Description: "An API key sent as \"ApiKey <key>\". The scope listed for an operation is the permission the key needs; operations without apiKeyAuth refuse API keys."},
+				},
+			},
+		},
+	}
+	g.problem = g.schemas.of(problem)
+	return g
+}
+
+var pathParam = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+// AddRoutes adds every route of router. secured marks them as needing a
+// bearer token or API key.
+func (g *Generator) AddRoutes(router *mux.Router, secured bool) error {
+	return router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			// Subrouters and matcher-only routes such as CORS preflights
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil || route.GetHandler() == nil {
+			return nil
+		}
+
+		path := strings.TrimPrefix(template, g.prefix)
+		if path == "" {
+			path = "/"
+		}
+		path = pathParam.ReplaceAllString(path, "{$1}")
+
+		for _, method := range methods {
+			if method == http.MethodOptions {
+				continue
+			}
+			g.add(path, method, OperationID(route), secured)
+		}
+		return nil
+	})
+}
+
+func (g *Generator) add(path string, method string, id string, secured bool) {
+	meta, documented := g.operations[id]
+	if !documented {
+		g.undocumented = append(g.undocumented, method+" "+path)
+	}
+
+	// Operation IDs must be unique, but one handler may serve several routes
+	g.ids[id]++
+	opID := id
+	if n := g.ids[id]; n > 1 {
+		opID = fmt.Sprintf("%s%d", id, n)
+	}
+
+	op := &OperationObject{
+		OperationID: opID,
+		Summary:     meta.Summary,
+		Description: meta.Description,
+		Responses:   make(map[string]*Response),
+	}
+	if tag := tagFor(path); tag != "" {
+		op.Tags = []string{tag}
+	}
+
+	for _, match := range pathParam.FindAllStringSubmatch(path, -1) {
+		op.Parameters = append(op.Parameters, ParameterObject{
+			Name:     match[1],
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "string"},
+		})
+	}
+	for _, q := range meta.Query {
+		typ := q.Type
+		if typ == "" {
+			typ = "string"
+		}
+		op.Parameters = append(op.Parameters, ParameterObject{
+			Name:        q.Name,
+			In:          "query",
+			Description: q.Description,
+			Schema:      &Schema{Type: typ},
+		})
+	}
+
+	if meta.Request != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]*MediaType{"application/json": {Schema: g.schemas.of(meta.Request)}},
+		}
+	}
+
+	status := meta.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := &Response{Description: http.StatusText(status)}
+	if meta.Response != nil {
+		success.Content = map[string]*MediaType{"application/json": {Schema: g.schemas.of(meta.Response)}}
+	}
+	op.Responses[fmt.Sprint(status)] = success
+	op.Responses["default"] = &Response{
+		Description: "Error",
+		Content:     map[string]*MediaType{"application/problem+json": {Schema: g.problem}},
+	}
+
+	if secured {
+		op.Security = []map[string][]string{{"bearerAuth": {}}}
+		if meta.Permission != "" {
+			op.Security = append(op.Security, map[string][]string{"apiKeyAuth": {meta.Permission}})
+		}
+	}
+
+	item, ok := g.doc.Paths[path]
+	if !ok {
+		item = &PathItem{}
+		g.doc.Paths[path] = item
+	}
+	(*item)[strings.ToLower(method)] = op
+}
+
+// OperationID is the route's name, or the name of its handler method.
+func OperationID(route *mux.Route) string {
+	if name := route.GetName(); name != "" {
+		return name
+	}
+	handler := reflect.ValueOf(route.GetHandler())
+	if handler.Kind() != reflect.Func {
+		return handler.Type().String()
+	}
+	name := runtime.FuncForPC(handler.Pointer()).Name()
+	name = strings.TrimSuffix(name, "-fm")
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// tagFor groups operations by the first literal segment of their path.
+func tagFor(path string) string {
+	segment, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if strings.HasPrefix(segment, "{") {
+		return ""
+	}
+	return segment
+}
+
+// Undocumented lists the routes that have no Operation metadata.
+func (g *Generator) Undocumented() []string {
+	return g.undocumented
+}
+
+// Document returns the generated document.
+func (g *Generator) Document() *Document {
+	g.doc.Components.Schemas = g.schemas.components
+
+	tags := make(map[string]bool)
+	for _, item := range g.doc.Paths {
+		for _, op := range *item {
+			for _, tag := range op.Tags {
+				tags[tag] = true
+			}
+		}
+	}
+	g.doc.Tags = g.doc.Tags[:0]
+	for tag := range tags {
+		g.doc.Tags = append(g.doc.Tags, Tag{Name: tag})
+	}
+	sort.Slice(g.doc.Tags, func(i, j int) bool { return g.doc.Tags[i].Name < g.doc.Tags[j].Name })
+
+	return g.doc
+}
+
+// ServeHTTP serves the document as JSON. It is rendered on the first request,
+// so all routes must be added before the server starts.
+func (g *Generator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.once.Do(func() {
+		g.body, g.err = json.Marshal(g.Document())
+	})
+	if g.err != nil {
+		common.RespondProblem(w, g.err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(g.body)
+}
@@ -0,0 +1,192 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	uuidType = reflect.TypeOf(uuid.UUID{})
+)
+
+// Patterns for the custom validate rules, kept in step with package validate.
+var customPatterns = map[string]string{
+	"phone":      `^\+?[0-9 ().-]{7,20}$`,
+	"identifier": `^[a-z_][a-z0-9_]{0,62}$`,
+}
+
+// schemas turns Go types into JSON Schemas. Named struct types are added to
+// the components once and referenced from everywhere else.
+type schemas struct {
+	components map[string]*Schema
+}
+
+func newSchemas() *schemas {
+	return &schemas{components: make(map[string]*Schema)}
+}
+
+// of returns the schema for the type of v.
+func (s *schemas) of(v any) *Schema {
+	if v == nil {
+		return nil
+	}
+	return s.forType(reflect.TypeOf(v))
+}
+
+func (s *schemas) forType(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case uuidType:
+		return &Schema{Type: "string", Format: "uuid"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(s.forType(t.Elem()))
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: s.forType(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.forType(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		name := componentName(t)
+		if _, ok := s.components[name]; !ok {
+			// Reserve the name first so recursive types terminate
+			s.components[name] = &Schema{}
+			*s.components[name] = *s.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	return &Schema{}
+}
+
+// componentName is the name of a struct type, with the type arguments of a
+// generic type appended, so that Page[model.Item] is PageItem.
+func componentName(t reflect.Type) string {
+	name, args, generic := strings.Cut(t.Name(), "[")
+	if !generic {
+		return name
+	}
+	for _, arg := range strings.Split(strings.TrimSuffix(args, "]"), ",") {
+		name += arg[strings.LastIndex(arg, ".")+1:]
+	}
+	return name
+}
+
+func (s *schemas) object(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := s.forType(field.Type)
+		if applyRules(property, field.Type, field.Tag.Get("validate")) {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = property
+	}
+	return schema
+}
+
+// nullable allows null as well as the values of schema.
+func nullable(schema *Schema) *Schema {
+	if schema.Ref != "" {
+		return &Schema{OneOf: []*Schema{schema, {Type: "null"}}}
+	}
+	if t, ok := schema.Type.(string); ok {
+		schema.Type = []string{t, "null"}
+	}
+	return schema
+}
+
+// applyRules copies the constraints of a validate tag onto schema and
+// reports whether the field is required.
+func applyRules(schema *Schema, t reflect.Type, tag string) bool {
+	if tag == "" || schema.Ref != "" || schema.OneOf != nil {
+		return strings.HasPrefix(tag, "required")
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	required := false
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		n, numeric := atoi(param)
+		switch {
+		case name == "required":
+			required = true
+		case name == "min" && numeric:
+			switch t.Kind() {
+			case reflect.String:
+				schema.MinLength = &n
+			case reflect.Slice:
+				schema.MinItems = &n
+			default:
+				schema.Minimum = &n
+			}
+		case name == "max" && numeric:
+			switch t.Kind() {
+			case reflect.String:
+				schema.MaxLength = &n
+			case reflect.Slice:
+				schema.MaxItems = &n
+			default:
+				schema.Maximum = &n
+			}
+		case name == "oneof":
+			for _, value := range strings.Fields(param) {
+				schema.Enum = append(schema.Enum, value)
+			}
+			if _, ok := schema.Type.([]string); ok {
+				schema.Enum = append(schema.Enum, nil)
+			}
+		case name == "email":
+			schema.Format = "email"
+		case name == "uuid":
+			schema.Format = "uuid"
+		case name == "url" || name == "http_url":
+			schema.Format = "uri"
+		case name == "ip":
+			schema.Description = "IPv4 or IPv6 address"
+		case name == "ip|cidr":
+			schema.Description = "IP address or CIDR range"
+		case name == "gtefield":
+			schema.Description = "Not before " + param
+		case customPatterns[name] != "":
+			schema.Pattern = customPatterns[name]
+		}
+	}
+	return required
+}
+
+func atoi(s string) (int, bool) {
+	n, err := strconv.Atoi(s)
+	return n, err == nil
+}
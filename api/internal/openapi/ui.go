@@ -0,0 +1,46 @@
+package openapi
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	common "github.com/htstinson/stinsondataapi/api/commonweb"
+)
+
+//go:embed ui
+var uiFiles embed.FS
+
+var indexTemplate = template.Must(template.ParseFS(uiFiles, "ui/index.html"))
+
+// DocsHandler serves the bundled documentation UI. base is the path it is
+// mounted at, such as "/api/v1/docs"; base itself serves the page and
+// base+"/docs.js" and base+"/docs.css" its assets. The page reads the
+// document from specURL. Nothing is loaded from other origins, so the UI
+// works under the API's Content-Security-Policy.
+func DocsHandler(base string, specURL string) http.Handler {
+	base = strings.TrimSuffix(base, "/")
+
+	var index bytes.Buffer
+	if err := indexTemplate.Execute(&index, struct{ Base, SpecURL string }{base, specURL}); err != nil {
+		panic(err)
+	}
+	started := time.Now()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, base), "/")
+		switch name {
+		case "", "index.html":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			http.ServeContent(w, r, "index.html", started, bytes.NewReader(index.Bytes()))
+		case "docs.js", "docs.css":
+			http.ServeFileFS(w, r, uiFiles, path.Join("ui", name))
+		default:
+			common.ProblemHandler(http.StatusNotFound).ServeHTTP(w, r)
+		}
+	})
+}
@@ -0,0 +1,248 @@
+// Renders the OpenAPI document named by the body's data-spec attribute as a
+// list of operations grouped by tag, with their parameters, schemas and a
+// form to try them.
+(function () {
+  "use strict";
+
+  var specURL = document.body.getAttribute("data-spec");
+  var container = document.getElementById("operations");
+  var spec;
+
+  function el(tag, attrs, children) {
+    var node = document.createElement(tag);
+    Object.keys(attrs || {}).forEach(function (key) {
+      if (key === "text") {
+        node.textContent = attrs[key];
+      } else {
+        node.setAttribute(key, attrs[key]);
+      }
+    });
+    (children || []).forEach(function (child) {
+      if (child) {
+        node.appendChild(child);
+      }
+    });
+    return node;
+  }
+
+  function resolve(schema) {
+    if (schema && schema.$ref) {
+      return spec.components.schemas[schema.$ref.split("/").pop()];
+    }
+    return schema;
+  }
+
+  // example builds a sample value for a schema, following references once.
+  function example(schema, seen) {
+    seen = seen || {};
+    if (!schema) {
+      return null;
+    }
+    if (schema.$ref) {
+      if (seen[schema.$ref]) {
+        return {};
+      }
+      seen[schema.$ref] = true;
+      var value = example(resolve(schema), seen);
+      delete seen[schema.$ref];
+      return value;
+    }
+    if (schema.oneOf) {
+      return example(schema.oneOf[0], seen);
+    }
+    if (schema.enum) {
+      return schema.enum[0];
+    }
+    var type = Array.isArray(schema.type) ? schema.type[0] : schema.type;
+    switch (type) {
+      case "object":
+        var obj = {};
+        Object.keys(schema.properties || {}).forEach(function (name) {
+          obj[name] = example(schema.properties[name], seen);
+        });
+        return obj;
+      case "array":
+        return [example(schema.items, seen)];
+      case "integer":
+      case "number":
+        return schema.minimum || 0;
+      case "boolean":
+        return false;
+      case "string":
+        if (schema.format === "date-time") {
+          return new Date(0).toISOString();
+        }
+        if (schema.format === "uuid") {
+          return "00000000-0000-0000-0000-000000000000";
+        }
+        return schema.format || "string";
+    }
+    return null;
+  }
+
+  function schemaTable(schema) {
+    schema = resolve(schema);
+    if (!schema || !schema.properties) {
+      return null;
+    }
+    var required = schema.required || [];
+    var rows = Object.keys(schema.properties).map(function (name) {
+      var prop = schema.properties[name];
+      var type = prop.$ref ? prop.$ref.split("/").pop() : [].concat(prop.type || "").join(" | ");
+      var notes = [];
+      if (prop.format) notes.push(prop.format);
+      if (prop.enum) notes.push("one of " + prop.enum.join(", "));
+      if (prop.minLength !== undefined) notes.push("min length " + prop.minLength);
+      if (prop.maxLength !== undefined) notes.push("max length " + prop.maxLength);
+      if (prop.minimum !== undefined) notes.push("min " + prop.minimum);
+      if (prop.maximum !== undefined) notes.push("max " + prop.maximum);
+      if (prop.pattern) notes.push("pattern " + prop.pattern);
+      if (prop.description) notes.push(prop.description);
+      return el("tr", {}, [
+        el("td", { class: "path", text: name + (required.indexOf(name) >= 0 ? " *" : "") }),
+        el("td", { text: type }),
+        el("td", { text: notes.join("; ") })
+      ]);
+    });
+    return el("table", {}, [
+      el("thead", {}, [el("tr", {}, [el("th", { text: "Field" }), el("th", { text: "Type" }), el("th", { text: "Rules" })])]),
+      el("tbody", {}, rows)
+    ]);
+  }
+
+  function tryIt(path, method, op) {
+    var inputs = {};
+    var fields = (op.parameters || []).map(function (param) {
+      inputs[param.name] = el("input", { placeholder: param.name + " (" + param.in + ")" });
+      return inputs[param.name];
+    });
+    var body;
+    if (op.requestBody) {
+      body = el("textarea");
+      body.value = JSON.stringify(example(op.requestBody.content["application/json"].schema), null, 2);
+    }
+    var output = el("pre", { text: "" });
+    var button = el("button", { type: "button", text: "Send" });
+
+    button.addEventListener("click", function () {
+      var url = path;
+      var query = [];
+      (op.parameters || []).forEach(function (param) {
+        var value = inputs[param.name].value;
+        if (param.in === "path") {
+          url = url.replace("{" + param.name + "}", encodeURIComponent(value));
+        } else if (value) {
+          query.push(encodeURIComponent(param.name) + "=" + encodeURIComponent(value));
+        }
+      });
+      var headers = { "Content-Type": "application/json" };
+      var token = document.getElementById("token").value;
+      if (token) {
+        headers.Authorization = "Bearer " + token;
+      }
+      var server = (spec.servers && spec.servers[0] && spec.servers[0].url) || "";
+      fetch(server + url + (query.length ? "?" + query.join("&") : ""), {
+        method: method.toUpperCase(),
+        headers: headers,
+        body: body ? body.value : undefined
+      }).then(function (resp) {
+        return resp.text().then(function (text) {
+          try {
+            text = JSON.stringify(JSON.parse(text), null, 2);
+          } catch (e) {
+            // not JSON, show as is
+          }
+          output.textContent = resp.status + " " + resp.statusText + "\n\n" + text;
+        });
+      }).catch(function (err) {
+        output.textContent = String(err);
+      });
+    });
+
+    return el("div", {}, [el("h4", { text: "Try it" })].concat(fields, [body, button, output]));
+  }
+
+  function operation(path, method, op) {
+    var parts = [];
+    if (op.description) {
+      parts.push(el("p", { text: op.description }));
+    }
+    if (op.parameters && op.parameters.length) {
+      parts.push(el("h4", { text: "Parameters" }));
+      parts.push(el("table", {}, op.parameters.map(function (p) {
+        return el("tr", {}, [
+          el("td", { class: "path", text: p.name + (p.required ? " *" : "") }),
+          el("td", { text: p.in }),
+          el("td", { text: p.description || "" })
+        ]);
+      })));
+    }
+    if (op.requestBody) {
+      var reqSchema = op.requestBody.content["application/json"].schema;
+      parts.push(el("h4", { text: "Request body" }));
+      parts.push(schemaTable(reqSchema));
+      parts.push(el("pre", { text: JSON.stringify(example(reqSchema), null, 2) }));
+    }
+    Object.keys(op.responses).forEach(function (status) {
+      var resp = op.responses[status];
+      parts.push(el("h4", { text: "Response " + status + " " + resp.description }));
+      var content = resp.content && (resp.content["application/json"] || resp.content["application/problem+json"]);
+      if (content && status !== "default") {
+        parts.push(el("pre", { text: JSON.stringify(example(content.schema), null, 2) }));
+      }
+    });
+    parts.push(tryIt(path, method, op));
+
+    var node = el("details", { class: "operation" }, [
+      el("summary", {}, [
+        el("span", { class: "method " + method, text: method }),
+        el("span", { class: "path", text: path }),
+        el("span", { text: op.summary || "" }),
+        op.security ? el("span", { class: "lock", text: "token" }) : null
+      ]),
+      el("div", { class: "body" }, parts)
+    ]);
+    node.setAttribute("data-search", [method, path, op.summary || "", (op.tags || []).join(" ")].join(" ").toLowerCase());
+    return node;
+  }
+
+  function render() {
+    document.getElementById("title").textContent = spec.info.title + " " + spec.info.version;
+    document.getElementById("description").textContent = spec.info.description || "";
+    container.textContent = "";
+
+    var groups = {};
+    Object.keys(spec.paths).sort().forEach(function (path) {
+      var item = spec.paths[path];
+      Object.keys(item).forEach(function (method) {
+        var tag = (item[method].tags || ["other"])[0];
+        (groups[tag] = groups[tag] || []).push(operation(path, method, item[method]));
+      });
+    });
+    Object.keys(groups).sort().forEach(function (tag) {
+      container.appendChild(el("section", { "data-tag": tag }, [el("h2", { text: tag })].concat(groups[tag])));
+    });
+  }
+
+  document.getElementById("filter").addEventListener("input", function (event) {
+    var term = event.target.value.toLowerCase();
+    Array.prototype.forEach.call(container.querySelectorAll("section"), function (section) {
+      var visible = 0;
+      Array.prototype.forEach.call(section.querySelectorAll("details.operation"), function (op) {
+        var match = op.getAttribute("data-search").indexOf(term) >= 0;
+        op.style.display = match ? "" : "none";
+        visible += match ? 1 : 0;
+      });
+      section.style.display = visible ? "" : "none";
+    });
+  });
+
+  fetch(specURL).then(function (resp) {
+    return resp.json();
+  }).then(function (doc) {
+    spec = doc;
+    render();
+  }).catch(function (err) {
+    container.textContent = "Could not load " + specURL + ": " + err;
+  });
+})();
@@ -0,0 +1,124 @@
+// Package password holds the rules new passwords must meet and the pacing
+// of repeated login failures.
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxLength is the longest password in bytes; bcrypt ignores the rest.
+const MaxLength = 72
+
+// Policy is what a new password must meet.
+type Policy struct {
+	MinLength int
+	breached  map[[sha1.Size]byte]struct{}
+}
+
+// NewPolicy returns a policy requiring minLength characters and, when
+// breachedList names a file, refusing the passwords it lists. The file holds
+// one password per line, or its SHA-1 in hex optionally followed by
+// ":count" as in the Pwned Passwords downloads.
+func NewPolicy(minLength int, breachedList string) (*Policy, error) {
+	p := &Policy{MinLength: minLength, breached: make(map[[sha1.Size]byte]struct{})}
+	if breachedList == "" {
+		return p, nil
+	}
+
+	f, err := os.Open(breachedList)
+	if err != nil {
+		return nil, fmt.Errorf("error opening breached password list: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var sum [sha1.Size]byte
+		if hash, _, _ := strings.Cut(line, ":"); len(hash) == 2*sha1.Size {
+			if _, err := hex.Decode(sum[:], []byte(hash)); err == nil {
+				p.breached[sum] = struct{}{}
+				continue
+			}
+		}
+		p.breached[sha1.Sum([]byte(line))] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading breached password list: %w", err)
+	}
+
+	return p, nil
+}
+
+// BreachedCount is the number of passwords in the breached list.
+func (p *Policy) BreachedCount() int {
+	return len(p.breached)
+}
+
+// Check returns what is wrong with password as the new password of
+// username, or nil when it meets the policy.
+func (p *Policy) Check(password string, username string) []string {
+	var problems []string
+
+	if utf8.RuneCountInString(password) < p.MinLength {
+		problems = append(problems, fmt.Sprintf("must be at least %d characters", p.MinLength))
+	}
+	if len(password) > MaxLength {
+		problems = append(problems, fmt.Sprintf("must be at most %d bytes", MaxLength))
+	}
+	if username != "" && strings.EqualFold(password, username) {
+		problems = append(problems, "must not be the username")
+	}
+	if _, ok := p.breached[sha1.Sum([]byte(password))]; ok {
+		problems = append(problems, "appears in a list of breached passwords, choose another")
+	}
+
+	return problems
+}
+
+// Lockout paces login attempts after consecutive failures. From DelayAfter
+// failures on, each attempt must wait BaseDelay after the last failure,
+// doubling with every further failure up to MaxDelay; without MaxDelay it
+// does not grow. At Threshold failures
+// the account is locked for Duration. Zero values turn each part off.
+type Lockout struct {
+	DelayAfter int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+	Threshold  int
+	Duration   time.Duration
+}
+
+// Delay is how long after the last of failures consecutive failures the
+// next attempt must wait.
+func (l Lockout) Delay(failures int) time.Duration {
+	if l.DelayAfter <= 0 || l.BaseDelay <= 0 || failures < l.DelayAfter {
+		return 0
+	}
+	delay := l.BaseDelay
+	if l.MaxDelay <= 0 {
+		return delay
+	}
+	for i := l.DelayAfter; i < failures; i++ {
+		delay *= 2
+		if delay >= l.MaxDelay {
+			return l.MaxDelay
+		}
+	}
+	return delay
+}
+
+// Locks reports whether failures consecutive failures lock the account.
+func (l Lockout) Locks(failures int) bool {
+	return l.Threshold > 0 && l.Duration > 0 && failures >= l.Threshold
+}
@@ -0,0 +1,73 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/aws/aws-sdk-go-v2/otelaws"
+)
+
+// AWS reads secrets from AWS Secrets Manager.
+type AWS struct {
+	client *secretsmanager.Client
+}
+
+// NewAWS creates a Secrets Manager client for region using the default
+// credential chain. A non-empty endpoint replaces the regional one, for a
+// local stand-in such as LocalStack.
+func NewAWS(ctx context.Context, region string, endpoint string) (*AWS, error) {
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return nil, fmt.Errorf("error loading AWS config: %w", err)
+	}
+	otelaws.AppendMiddlewares(&cfg.APIOptions)
+
+	client := secretsmanager.NewFromConfig(cfg, func(o *secretsmanager.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
+	})
+	return &AWS{client: client}, nil
+}
+
+// GetSecret returns the AWSCURRENT version of name.
+func (a *AWS) GetSecret(ctx context.Context, name string) ([]byte, error) {
+	result, err := a.client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId:     aws.String(name),
+		VersionStage: aws.String("AWSCURRENT"),
+	})
+	if err != nil {
+		var notFound *types.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			return nil, fmt.Errorf("%s: %w", name, ErrNotFound)
+		}
+		return nil, err
+	}
+
+	if result.SecretString != nil {
+		return []byte(*result.SecretString), nil
+	}
+	return result.SecretBinary, nil
+}
+
+// PutSecret stores value as the new current version of name.
+func (a *AWS) PutSecret(ctx context.Context, name string, value []byte) error {
+	_, err := a.client.UpdateSecret(ctx, &secretsmanager.UpdateSecretInput{
+		SecretId:     aws.String(name),
+		SecretString: aws.String(string(value)),
+	})
+	return err
+}
+
+// Ping describes name, which needs no permission to decrypt it.
+func (a *AWS) Ping(ctx context.Context, name string) error {
+	_, err := a.client.DescribeSecret(ctx, &secretsmanager.DescribeSecretInput{
+		SecretId: aws.String(name),
+	})
+	return err
+}
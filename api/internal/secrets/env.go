@@ -0,0 +1,46 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Env reads secrets from environment variables. A name is upper cased and
+// every character other than a letter or digit becomes an underscore, so with
+// the prefix "SECRET_" the secret "RDS/apidb" is read from SECRET_RDS_APIDB.
+type Env struct {
+	prefix string
+}
+
+func NewEnv(prefix string) *Env {
+	return &Env{prefix: prefix}
+}
+
+func (e *Env) GetSecret(ctx context.Context, name string) ([]byte, error) {
+	value, ok := os.LookupEnv(e.variable(name))
+	if !ok {
+		return nil, fmt.Errorf("%s (%s): %w", name, e.variable(name), ErrNotFound)
+	}
+	return []byte(value), nil
+}
+
+func (e *Env) Ping(ctx context.Context, name string) error {
+	if _, ok := os.LookupEnv(e.variable(name)); !ok {
+		return fmt.Errorf("%s (%s): %w", name, e.variable(name), ErrNotFound)
+	}
+	return nil
+}
+
+func (e *Env) variable(name string) string {
+	return e.prefix + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, name)
+}
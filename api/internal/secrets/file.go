@@ -0,0 +1,61 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// File reads each secret from a file under a directory, named after the
+// secret, so "RDS/apidb" is read from <dir>/RDS/apidb. This suits secrets
+// mounted by Kubernetes or Docker, which are replaced in place on rotation.
+type File struct {
+	root *os.Root
+}
+
+func NewFile(dir string) (*File, error) {
+	if dir == "" {
+		return nil, errors.New("file secrets provider needs a directory")
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error opening secrets directory: %w", err)
+	}
+	return &File{root: root}, nil
+}
+
+// GetSecret returns the file contents without a trailing newline. Names
+// cannot reach outside the directory.
+func (f *File) GetSecret(ctx context.Context, name string) ([]byte, error) {
+	data, err := f.root.ReadFile(filepath.FromSlash(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%s: %w", name, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(data, "\r\n"), nil
+}
+
+// PutSecret replaces the file, creating its directory if needed.
+func (f *File) PutSecret(ctx context.Context, name string, value []byte) error {
+	path := filepath.FromSlash(name)
+	if dir := filepath.Dir(path); dir != "." {
+		if err := f.root.MkdirAll(dir, 0o700); err != nil {
+			return err
+		}
+	}
+	return f.root.WriteFile(path, value, 0o600)
+}
+
+func (f *File) Ping(ctx context.Context, name string) error {
+	_, err := f.root.Stat(filepath.FromSlash(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%s: %w", name, ErrNotFound)
+	}
+	return err
+}
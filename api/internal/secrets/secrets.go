@@ -0,0 +1,213 @@
+// Package secrets reads credentials from AWS Secrets Manager, environment
+// variables, local files or HashiCorp Vault behind one interface, so that the
+// API can run locally without AWS. Cache adds a TTL and picks up rotated
+// values.
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned when a provider has no secret by that name.
+var ErrNotFound = errors.New("secret not found")
+
+// Provider returns the current value of a named secret. Names are the ones
+// used in Secrets Manager, such as "RDS/apidb"; each provider maps them to
+// its own storage.
+type Provider interface {
+	GetSecret(ctx context.Context, name string) ([]byte, error)
+}
+
+// Writer is implemented by providers that can store a new value, which the
+// Gmail helpers need to save refreshed OAuth tokens.
+type Writer interface {
+	PutSecret(ctx context.Context, name string, value []byte) error
+}
+
+// ReadWriter is a provider that can also store values.
+type ReadWriter interface {
+	Provider
+	Writer
+}
+
+// Pinger is implemented by providers that can check a secret is reachable
+// without reading its value.
+type Pinger interface {
+	Ping(ctx context.Context, name string) error
+}
+
+// Options selects and configures a provider.
+type Options struct {
+	// Provider is "aws", "env", "file" or "vault".
+	Provider string
+	Region   string
+	// Endpoint replaces the Secrets Manager endpoint of the aws provider.
+	Endpoint string
+	// EnvPrefix is prepended to environment variable names.
+	EnvPrefix string
+	// Dir is the directory the file provider reads from.
+	Dir   string
+	Vault VaultOptions
+	// TTL is how long a value is served from the cache.
+	TTL time.Duration
+}
+
+// New builds the provider named in opts wrapped in a Cache.
+func New(ctx context.Context, opts Options) (*Cache, error) {
+	var provider Provider
+	var err error
+	switch opts.Provider {
+	case "", "aws":
+		provider, err = NewAWS(ctx, opts.Region, opts.Endpoint)
+	case "env":
+		provider = NewEnv(opts.EnvPrefix)
+	case "file":
+		provider, err = NewFile(opts.Dir)
+	case "vault":
+		provider, err = NewVault(opts.Vault)
+	default:
+		return nil, fmt.Errorf("unknown secrets provider %q", opts.Provider)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewCache(provider, opts.TTL), nil
+}
+
+// GetString is GetSecret for callers that want a string.
+func GetString(ctx context.Context, p Provider, name string) (string, error) {
+	value, err := p.GetSecret(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+type cacheEntry struct {
+	value     []byte
+	fetchedAt time.Time
+}
+
+// Cache serves secrets from memory for up to ttl. When a value expires it is
+// fetched again; if that fails the stale value is kept and the error logged,
+// so that a Secrets Manager outage does not take the API down. Readers pick
+// up a rotated value on their next read; the database, for one, reads its
+// credentials for every new connection.
+type Cache struct {
+	provider Provider
+	ttl      time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+// NewCache wraps provider. A ttl of zero disables caching.
+func NewCache(provider Provider, ttl time.Duration) *Cache {
+	return &Cache{provider: provider, ttl: ttl, entries: make(map[string]cacheEntry)}
+}
+
+// GetSecret returns the cached value of name, fetching it when missing or expired.
+func (c *Cache) GetSecret(ctx context.Context, name string) ([]byte, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[name]
+	c.mu.Unlock()
+
+	if ok && time.Since(entry.fetchedAt) < c.ttl {
+		return entry.value, nil
+	}
+
+	value, err := c.fetch(ctx, name)
+	if err != nil {
+		if ok {
+			slog.WarnContext(ctx, "secret refresh failed, serving cached value", "secret", name, "error", err)
+			return entry.value, nil
+		}
+		return nil, err
+	}
+	return value, nil
+}
+
+// Refresh fetches name again whatever its age, for callers that have just
+// been refused with the cached value.
+func (c *Cache) Refresh(ctx context.Context, name string) error {
+	_, err := c.fetch(ctx, name)
+	return err
+}
+
+// PutSecret stores value through the provider and caches it.
+func (c *Cache) PutSecret(ctx context.Context, name string, value []byte) error {
+	writer, ok := c.provider.(Writer)
+	if !ok {
+		return fmt.Errorf("secrets provider %T is read only", c.provider)
+	}
+	if err := writer.PutSecret(ctx, name, value); err != nil {
+		return err
+	}
+	c.store(name, value)
+	return nil
+}
+
+// Ping checks that name is reachable, reading it only when the provider
+// cannot check otherwise.
+func (c *Cache) Ping(ctx context.Context, name string) error {
+	if pinger, ok := c.provider.(Pinger); ok {
+		return pinger.Ping(ctx, name)
+	}
+	_, err := c.provider.GetSecret(ctx, name)
+	return err
+}
+
+// Start refetches every cached secret each interval until ctx is cancelled,
+// so that rotations are noticed even for values that are read rarely.
+func (c *Cache) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.mu.Lock()
+				names := make([]string, 0, len(c.entries))
+				for name := range c.entries {
+					names = append(names, name)
+				}
+				c.mu.Unlock()
+
+				for _, name := range names {
+					if err := c.Refresh(ctx, name); err != nil {
+						slog.ErrorContext(ctx, "secret refresh failed", "secret", name, "error", err)
+					}
+				}
+			}
+		}
+	}()
+}
+
+func (c *Cache) fetch(ctx context.Context, name string) ([]byte, error) {
+	value, err := c.provider.GetSecret(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	c.store(name, value)
+	return value, nil
+}
+
+func (c *Cache) store(name string, value []byte) {
+	c.mu.Lock()
+	previous, ok := c.entries[name]
+	c.entries[name] = cacheEntry{value: value, fetchedAt: time.Now()}
+	c.mu.Unlock()
+
+	if ok && !bytes.Equal(previous.value, value) {
+		slog.Info("secret rotated", "secret", name)
+	}
+}
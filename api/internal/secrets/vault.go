@@ -0,0 +1,135 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// VaultOptions locates a KV version 2 secrets engine.
+type VaultOptions struct {
+	Addr      string
+	Token     string
+	Mount     string
+	Namespace string
+}
+
+// Vault reads secrets from a HashiCorp Vault KV version 2 engine over its
+// HTTP API. A secret with a single "value" key is returned as that string;
+// any other secret is returned as its key/value map in JSON, which matches
+// how JSON secrets such as the RDS credentials are stored in Secrets Manager.
+type Vault struct {
+	opts   VaultOptions
+	client *http.Client
+}
+
+func NewVault(opts VaultOptions) (*Vault, error) {
+	if opts.Addr == "" || opts.Token == "" {
+		return nil, errors.New("vault secrets provider needs an address and a token")
+	}
+	if opts.Mount == "" {
+		opts.Mount = "secret"
+	}
+	opts.Addr = strings.TrimRight(opts.Addr, "/")
+
+	return &Vault{
+		opts: opts,
+		client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+		},
+	}, nil
+}
+
+func (v *Vault) GetSecret(ctx context.Context, name string) ([]byte, error) {
+	var body struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+	}
+	if err := v.do(ctx, http.MethodGet, "data", name, nil, &body); err != nil {
+		return nil, err
+	}
+
+	data := body.Data.Data
+	if value, ok := data["value"].(string); ok && len(data) == 1 {
+		return []byte(value), nil
+	}
+	return json.Marshal(data)
+}
+
+// PutSecret writes a new version. A JSON object is stored as its keys and
+// anything else under "value".
+func (v *Vault) PutSecret(ctx context.Context, name string, value []byte) error {
+	var data map[string]any
+	if err := json.Unmarshal(value, &data); err != nil || data == nil {
+		data = map[string]any{"value": string(value)}
+	}
+	return v.do(ctx, http.MethodPost, "data", name, map[string]any{"data": data}, nil)
+}
+
+// Ping reads the secret's metadata rather than its value.
+func (v *Vault) Ping(ctx context.Context, name string) error {
+	return v.do(ctx, http.MethodGet, "metadata", name, nil, nil)
+}
+
+func (v *Vault) do(ctx context.Context, method string, kind string, name string, in any, out any) error {
+	var reqBody io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+
+	endpoint := fmt.Sprintf("%s/v1/%s/%s/%s", v.opts.Addr, url.PathEscape(v.opts.Mount), kind, escapePath(name))
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", v.opts.Token)
+	if v.opts.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.opts.Namespace)
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error calling vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%s: %w", name, ErrNotFound)
+	case resp.StatusCode >= 300:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("vault returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// escapePath escapes each segment of a slash separated secret name.
+func escapePath(name string) string {
+	segments := strings.Split(name, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
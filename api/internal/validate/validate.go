@@ -0,0 +1,176 @@
+// Package validate checks request models against the rules declared in their
+// `validate` struct tags and reports every failure by JSON field name.
+//
+// Besides the validator's built in rules, "phone" accepts an optional leading
+// + followed by 7 to 20 digits, spaces, dots, dashes or parentheses, and
+// "identifier" accepts a lower case SQL identifier such as a schema name.
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Errors lists the messages for each invalid field, keyed by its JSON path
+// such as "email" or "subscribed[0].subscriber_id".
+type Errors map[string][]string
+
+func (e Errors) Error() string {
+	fields := make([]string, 0, len(e))
+	for field := range e {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	parts := make([]string, 0, len(fields))
+	for _, field := range fields {
+		parts = append(parts, field+": "+strings.Join(e[field], ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+var (
+	phonePattern      = regexp.MustCompile(`^\+?[0-9 ().-]{7,20}$`)
+	identifierPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+)
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	// Report fields by the name clients use
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+
+	v.RegisterValidation("phone", func(fl validator.FieldLevel) bool {
+		return phonePattern.MatchString(fl.Field().String())
+	})
+	v.RegisterValidation("identifier", func(fl validator.FieldLevel) bool {
+		return identifierPattern.MatchString(fl.Field().String())
+	})
+
+	return v
+}
+
+// Struct validates every field of s, which may be a struct or a pointer to
+// one. It returns Errors when any rule fails.
+func Struct(s any) error {
+	return check(s, func(v any) error { return validate.Struct(v) })
+}
+
+// Fields validates only the named fields of s, given by their Go names. It
+// is used for bodies that only select a record, such as a subscriber
+// identified by its id.
+func Fields(s any, fields ...string) error {
+	return check(s, func(v any) error { return validate.StructPartial(v, fields...) })
+}
+
+func check(s any, fn func(any) error) error {
+	v := reflect.ValueOf(s)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return Errors{"body": {"is required"}}
+		}
+		v = v.Elem()
+	}
+
+	if !v.CanAddr() {
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(v)
+		v = copied
+	}
+
+	err := fn(v.Addr().Interface())
+	if err == nil {
+		return nil
+	}
+
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return err
+	}
+
+	errs := make(Errors)
+	for _, fe := range fieldErrs {
+		field := fieldPath(fe.Namespace())
+		errs[field] = append(errs[field], message(fe))
+	}
+	return errs
+}
+
+// fieldPath drops the struct name the validator puts first.
+func fieldPath(namespace string) string {
+	_, path, ok := strings.Cut(namespace, ".")
+	if !ok {
+		return namespace
+	}
+	return path
+}
+
+func message(fe validator.FieldError) string {
+	param := fe.Param()
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at least %s characters", param)
+		}
+		if fe.Kind() == reflect.Slice {
+			return fmt.Sprintf("must have at least %s items", param)
+		}
+		return "must be at least " + param
+	case "max":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters", param)
+		}
+		if fe.Kind() == reflect.Slice {
+			return fmt.Sprintf("must have at most %s items", param)
+		}
+		return "must be at most " + param
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(param), ", ")
+	case "email":
+		return "must be an email address"
+	case "phone":
+		return "must be a phone number"
+	case "url", "http_url":
+		return "must be a URL"
+	case "uuid":
+		return "must be a UUID"
+	case "identifier":
+		return "must be a lower case identifier"
+	case "ip", "cidr", "ip|cidr":
+		return "must be an IP address or CIDR range"
+	case "gtefield":
+		return "must not be before " + fieldName(param)
+	}
+	return "is invalid (" + fe.Tag() + ")"
+}
+
+// fieldName is the JSON name of the other field in a cross field rule.
+func fieldName(goName string) string {
+	var b strings.Builder
+	for i, r := range goName {
+		if i > 0 && r >= 'A' && r <= 'Z' {
+			b.WriteByte('_')
+		}
+		b.WriteRune(r)
+	}
+	return strings.ToLower(b.String())
+}
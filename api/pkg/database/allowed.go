@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/htstinson/stinsondataapi/api/internal/model"
+)
+
+// AllowedList is how the allowed addresses may be listed.
+var AllowedList = ListSpec{
+	Sorts:       map[string]string{"ip": "ip", "created_at": "created_at"},
+	Filters:     map[string]string{"ip": "ip", "notes": "notes", "created_at": "created_at"},
+	DefaultSort: "ip",
+	Keys:        []string{"id"},
+}
+
+// Admin - Allowed
+func (d *Database) SelectAllowed(ctx context.Context, q ListQuery) (*Page[model.Allowed], error) {
+
+	page, err := selectList(ctx, d.DB, list{
+		Spec:    AllowedList,
+		Query:   q,
+		Columns: []string{"id", "ip", "notes", "created_at"},
+		From:    "allowed",
+	}, func(scan func(...any) error) (model.Allowed, error) {
+		var item model.Allowed
+		var notesNullable sql.NullString
+		err := scan(&item.ID, &item.IP, &notesNullable, &item.CreatedAt)
+		item.Notes = notesNullable.String
+		return item, err
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "SelectAllowed", "error", err)
+		return nil, fmt.Errorf("error listing allowed: %w", err)
+	}
+
+	return page, nil
+}
+
+func (d *Database) GetAllowed(ctx context.Context, id string) (*model.Allowed, error) {
+	var allowed model.Allowed
+	var notesNull sql.NullString
+
+	query := "SELECT id, ip, notes, created_at FROM allowed WHERE id = $1"
+
+	err := d.DB.QueryRowContext(ctx, query, id).Scan(&allowed.ID, &allowed.IP, &notesNull, &allowed.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting allowed: %w", err)
+	}
+	allowed.Notes = notesNull.String
+
+	return &allowed, nil
+}
+
+func (d *Database) CreateAllowed(ctx context.Context, allowed model.Allowed) (*model.Allowed, error) {
+	allowed.CreatedAt = time.Now()
+
+	var exists bool
+	err := d.DB.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM allowed WHERE ip = $1)", allowed.IP).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("error checking allowed: %w", err)
+	}
+	if exists {
+		return nil, ErrDuplicate
+	}
+
+	query := `
+        INSERT INTO allowed (ip, notes, created_at)
+        VALUES ($1, $2, $3)
+        RETURNING id
+    `
+
+	err = d.DB.QueryRowContext(ctx, query, allowed.IP, allowed.Notes, allowed.CreatedAt).Scan(&allowed.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error creating allowed: %w", err)
+	}
+
+	return &allowed, nil
+}
+
+func (d *Database) UpdateAllowed(ctx context.Context, allowed *model.Allowed) error {
+
+	query := `UPDATE allowed SET ip=$1, notes=$2 WHERE id = $3`
+
+	_, err := d.DB.ExecContext(ctx, query, allowed.IP, allowed.Notes, allowed.ID)
+
+	return err
+}
+
+func (d *Database) DeleteAllowed(ctx context.Context, id string) error {
+
+	query := `DELETE FROM allowed WHERE id = $1`
+
+	_, err := d.DB.ExecContext(ctx, query, id)
+
+	return err
+}
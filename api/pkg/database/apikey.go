@@ -0,0 +1,266 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/lib/pq"
+)
+
+// apiKeys is the api_keys table with its optional text columns as empty
+// strings.
+const apiKeys = `(SELECT id, key_hash, user_id, subscriber_id, name, prefix, permissions,
+        COALESCE(created_by, '') AS created_by, created_at, expires_at,
+        last_used_at, COALESCE(last_used_ip, '') AS last_used_ip, revoked_at
+    FROM api_keys) api_keys`
+
+var apiKeyColumns = []string{
+	"id", "user_id", "subscriber_id", "name", "prefix", "permissions", "created_by",
+	"created_at", "expires_at", "last_used_at", "last_used_ip", "revoked_at",
+}
+
+// APIKeyList is how API keys may be listed, newest first by default.
+var APIKeyList = ListSpec{
+	Sorts:       map[string]string{"created_at": "created_at", "expires_at": "expires_at", "name": "name"},
+	Filters:     map[string]string{"subscriber_id": "subscriber_id", "name": "name", "created_at": "created_at", "expires_at": "expires_at"},
+	DefaultSort: "created_at",
+	DefaultDesc: true,
+	Keys:        []string{"id"},
+}
+
+func scanAPIKey(scan func(...any) error) (model.APIKey, error) {
+	var key model.APIKey
+	err := scan(
+		&key.Id,
+		&key.User_Id,
+		&key.Subscriber_Id,
+		&key.Name,
+		&key.Prefix,
+		pq.Array(&key.Permissions),
+		&key.Created_By,
+		&key.Created_At,
+		&key.Expires_At,
+		&key.Last_Used_At,
+		&key.Last_Used_IP,
+		&key.Revoked_At,
+	)
+	return key, err
+}
+
+// CreateAPIKey stores key by the hash of the key itself.
+func (d *Database) CreateAPIKey(ctx context.Context, key *model.APIKey, key_hash string) (*model.APIKey, error) {
+	slog.DebugContext(ctx, "CreateAPIKey")
+
+	key.Id = uuid.New().String()
+	query := `
+        INSERT INTO api_keys (id, key_hash, user_id, subscriber_id, name, prefix, permissions, created_by, expires_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9)
+    `
+	_, err := d.DB.ExecContext(ctx, query,
+		key.Id,
+		key_hash,
+		key.User_Id,
+		key.Subscriber_Id,
+		key.Name,
+		key.Prefix,
+		pq.Array(key.Permissions),
+		key.Created_By,
+		key.Expires_At,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error creating api key: %w", err)
+	}
+
+	return d.GetAPIKey(ctx, key.Id)
+}
+
+// GetAPIKey returns the key with id, or nil when there is none.
+func (d *Database) GetAPIKey(ctx context.Context, id string) (*model.APIKey, error) {
+	slog.DebugContext(ctx, "GetAPIKey")
+
+	query := `SELECT ` + strings.Join(apiKeyColumns, ", ") + ` FROM ` + apiKeys + ` WHERE id = $1`
+
+	key, err := scanAPIKey(d.DB.QueryRowContext(ctx, query, id).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting api key: %w", err)
+	}
+
+	return &key, nil
+}
+
+// LookupAPIKey returns the usable key with the hash key_hash: one that has
+// not expired or been revoked, whose user still belongs to its subscriber.
+// Any other is ErrNotFound.
+func (d *Database) LookupAPIKey(ctx context.Context, key_hash string) (*model.APIKey, error) {
+	slog.DebugContext(ctx, "LookupAPIKey")
+
+	query := `
+        SELECT ` + strings.Join(apiKeyColumns, ", ") + ` FROM ` + apiKeys + `
+        WHERE key_hash = $1 AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+            AND EXISTS (
+                SELECT 1 FROM user_subscriber us
+                WHERE us.user_id = api_keys.user_id AND us.subscriber_id = api_keys.subscriber_id
+            )
+    `
+
+	key, err := scanAPIKey(d.DB.QueryRowContext(ctx, query, key_hash).Scan)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error looking up api key: %w", err)
+	}
+
+	return &key, nil
+}
+
+// SelectAPIKeys lists the keys of the user user_id.
+func (d *Database) SelectAPIKeys(ctx context.Context, user_id string, q ListQuery) (*Page[model.APIKey], error) {
+	slog.DebugContext(ctx, "SelectAPIKeys")
+
+	page, err := selectList(ctx, d.DB, list{
+		Spec:    APIKeyList,
+		Query:   q,
+		Columns: apiKeyColumns,
+		From:    apiKeys,
+		Where:   []string{"user_id = $1"},
+		Args:    []any{user_id},
+	}, scanAPIKey)
+	if err != nil {
+		slog.ErrorContext(ctx, "SelectAPIKeys", "error", err)
+		return nil, fmt.Errorf("error listing api keys: %w", err)
+	}
+
+	return page, nil
+}
+
+// RevokeAPIKey stops the key with id from working. One that is already
+// revoked is ErrNotFound.
+func (d *Database) RevokeAPIKey(ctx context.Context, id string) error {
+	slog.DebugContext(ctx, "RevokeAPIKey")
+
+	query := `UPDATE api_keys SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1 AND revoked_at IS NULL`
+
+	result, err := d.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("error revoking api key: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// RecordAPIKeyUse notes that the key with id was used from ip. Uses within
+// a minute of the last one recorded are not written, so that busy keys do
+// not cost a write per request.
+func (d *Database) RecordAPIKeyUse(ctx context.Context, id string, ip string) error {
+	query := `
+        UPDATE api_keys SET last_used_at = CURRENT_TIMESTAMP, last_used_ip = NULLIF($2, '')
+        WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < $3)
+    `
+
+	if _, err := d.DB.ExecContext(ctx, query, id, ip, time.Now().Add(-time.Minute)); err != nil {
+		return fmt.Errorf("error recording api key use: %w", err)
+	}
+
+	return nil
+}
+
+// SelectSubscriberPermissions returns the names of the permissions the user
+// has in the subscriber: those of their roles in it and those granted to
+// them directly.
+func (d *Database) SelectSubscriberPermissions(ctx context.Context, user_id string, subscriber_id string) ([]string, error) {
+	slog.DebugContext(ctx, "SelectSubscriberPermissions")
+
+	query := `
+        SELECT rpv.permission_name::text
+        FROM user_subscriber us
+        JOIN user_subscriber_role usr ON usr.user_subscriber_id = us.id
+        JOIN role_permissions_view rpv ON rpv.role_id = usr.role_id
+        WHERE us.user_id = $1 AND us.subscriber_id = $2
+        UNION
+        SELECT p.name::text
+        FROM user_permissions up
+        JOIN permissions p ON p.id = up.permission_id
+        WHERE up.user_id = $1
+        ORDER BY 1
+    `
+
+	rows, err := d.DB.QueryContext(ctx, query, user_id, subscriber_id)
+	if err != nil {
+		return nil, fmt.Errorf("error listing permissions: %w", err)
+	}
+	defer rows.Close()
+
+	permissions := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("error listing permissions: %w", err)
+		}
+		permissions = append(permissions, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error listing permissions: %w", err)
+	}
+
+	return permissions, nil
+}
+
+// CreateServiceAccount creates a service account named name, a user that
+// cannot log in, and adds it to the subscriber with the role in one
+// transaction.
+func (d *Database) CreateServiceAccount(ctx context.Context, name string, subscriber_id string, role_id string) (*model.User, error) {
+	slog.DebugContext(ctx, "CreateServiceAccount")
+
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating service account: %w", err)
+	}
+	defer tx.Rollback()
+
+	user := &model.User{
+		ID:              uuid.New().String(),
+		Username:        name,
+		CreatedAt:       time.Now(),
+		Service_Account: true,
+	}
+	user.Email_Verified_At = &user.CreatedAt
+
+	// No password hash matches any password
+	query := `
+        INSERT INTO users (id, username, password_hash, created_at, email_verified_at, service_account)
+        VALUES ($1, $2, '', $3, $3, true)
+    `
+	if _, err := tx.ExecContext(ctx, query, user.ID, user.Username, user.CreatedAt); err != nil {
+		return nil, fmt.Errorf("error creating service account: %w", err)
+	}
+
+	var user_subscriber_id string
+	query = `INSERT INTO user_subscriber (user_id, subscriber_id) VALUES ($1, $2) RETURNING id`
+	if err := tx.QueryRowContext(ctx, query, user.ID, subscriber_id).Scan(&user_subscriber_id); err != nil {
+		return nil, fmt.Errorf("error adding service account to subscriber: %w", err)
+	}
+
+	query = `INSERT INTO user_subscriber_role (id, user_subscriber_id, role_id) VALUES ($1, $2, $3)`
+	if _, err := tx.ExecContext(ctx, query, uuid.New().String(), user_subscriber_id, role_id); err != nil {
+		return nil, fmt.Errorf("error giving service account its role: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error creating service account: %w", err)
+	}
+
+	return user, nil
+}
@@ -0,0 +1,120 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/htstinson/stinsondataapi/api/internal/model"
+)
+
+// Actor is who a change is made by and the request it is made in. It is
+// carried in the context so that audit events can be attributed.
+type Actor struct {
+	UserID       string
+	Username     string
+	SubscriberID string
+	RequestID    string
+	IP           string
+}
+
+type actorKey struct{}
+
+// WithActor returns ctx carrying actor.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor in ctx, or the zero Actor.
+func ActorFrom(ctx context.Context) Actor {
+	actor, _ := ctx.Value(actorKey{}).(Actor)
+	return actor
+}
+
+// AuditList is how audit events may be listed, newest first by default.
+var AuditList = ListSpec{
+	Sorts: map[string]string{"occurred_at": "occurred_at"},
+	Filters: map[string]string{
+		"occurred_at":   "occurred_at",
+		"action":        "action",
+		"actor_id":      "actor_id",
+		"actor_name":    "actor_name",
+		"subscriber_id": "subscriber_id",
+		"entity_type":   "entity_type",
+		"entity_id":     "entity_id",
+		"request_id":    "request_id",
+		"ip_address":    "ip_address",
+	},
+	DefaultSort: "occurred_at",
+	DefaultDesc: true,
+	Keys:        []string{"id"},
+}
+
+// CreateAuditEvent appends event to the audit log. The table refuses updates
+// and deletes, so events cannot be changed once written.
+func (d *Database) CreateAuditEvent(ctx context.Context, event model.AuditEvent) error {
+	var changes []byte
+	if len(event.Changes) > 0 {
+		var err error
+		changes, err = json.Marshal(event.Changes)
+		if err != nil {
+			return fmt.Errorf("error encoding audit changes: %w", err)
+		}
+	}
+
+	query := `
+        INSERT INTO audit_events (action, actor_id, actor_name, subscriber_id, entity_type, entity_id, changes, request_id, ip_address)
+        VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), NULLIF($4, ''), $5, NULLIF($6, ''), $7, NULLIF($8, ''), NULLIF($9, ''))
+    `
+
+	_, err := d.DB.ExecContext(ctx, query,
+		event.Action, event.Actor_Id, event.Actor_Name, event.Subscriber_Id, event.Entity_Type,
+		event.Entity_Id, changes, event.Request_Id, event.IP_Address,
+	)
+	if err != nil {
+		return fmt.Errorf("error creating audit event: %w", err)
+	}
+
+	return nil
+}
+
+func (d *Database) SelectAuditEvents(ctx context.Context, q ListQuery) (*Page[model.AuditEvent], error) {
+	slog.DebugContext(ctx, "SelectAuditEvents")
+
+	page, err := selectList(ctx, d.DB, list{
+		Spec:  AuditList,
+		Query: q,
+		Columns: []string{
+			"id", "occurred_at", "action", "actor_id", "actor_name", "subscriber_id",
+			"entity_type", "entity_id", "changes", "request_id", "ip_address",
+		},
+		From: "audit_events",
+	}, func(scan func(...any) error) (model.AuditEvent, error) {
+		var event model.AuditEvent
+		var actorID, actorName, subscriberID, entityID, requestID, ip sql.NullString
+		var changes []byte
+		err := scan(&event.Id, &event.Occurred_At, &event.Action, &actorID, &actorName, &subscriberID,
+			&event.Entity_Type, &entityID, &changes, &requestID, &ip)
+		if err != nil {
+			return event, err
+		}
+		event.Actor_Id = actorID.String
+		event.Actor_Name = actorName.String
+		event.Subscriber_Id = subscriberID.String
+		event.Entity_Id = entityID.String
+		event.Request_Id = requestID.String
+		event.IP_Address = ip.String
+		if len(changes) > 0 {
+			err = json.Unmarshal(changes, &event.Changes)
+		}
+		return event, err
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "SelectAuditEvents", "error", err)
+		return nil, fmt.Errorf("error listing audit events: %w", err)
+	}
+
+	return page, nil
+}
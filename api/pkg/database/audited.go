@@ -0,0 +1,880 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/htstinson/stinsondataapi/api/internal/model"
+)
+
+// auditedRepository wraps a Repository so that every create, update and
+// delete made through it is written to the audit log, attributed to the
+// Actor in the context. Updates and deletes read the row first, and updates
+// read it again afterwards, so that the event holds what actually changed.
+// Calls that fail are not recorded. A new mutating method of Repository must
+// be added here as well, or it passes through unaudited. Changes to account
+// security, such as failed logins, password resets and MFA enrolments, are
+// recorded by the handlers instead.
+type auditedRepository struct {
+	Repository
+}
+
+// WithAudit returns repo with its changes recorded in the audit log.
+func WithAudit(repo Repository) Repository {
+	return &auditedRepository{Repository: repo}
+}
+
+// record writes one audit event. A failure to write it is logged rather
+// than returned, since the change it describes has already been made.
+func (a *auditedRepository) record(ctx context.Context, action string, entityType string, entityID string, subscriberID string, before any, after any) {
+	actor := ActorFrom(ctx)
+	if subscriberID == "" {
+		subscriberID = actor.SubscriberID
+	}
+
+	event := model.AuditEvent{
+		Action:        action,
+		Actor_Id:      actor.UserID,
+		Actor_Name:    actor.Username,
+		Subscriber_Id: subscriberID,
+		Entity_Type:   entityType,
+		Entity_Id:     entityID,
+		Changes:       Diff(before, after),
+		Request_Id:    actor.RequestID,
+		IP_Address:    actor.IP,
+	}
+
+	if err := a.Repository.CreateAuditEvent(ctx, event); err != nil {
+		slog.ErrorContext(ctx, "audit event not recorded", "action", action, "entity_type", entityType, "entity_id", entityID, "error", err)
+	}
+}
+
+// Diff compares the JSON encodings of before and after field by field and
+// returns the fields that differ. Either may be nil, for a create or a delete.
+func Diff(before any, after any) map[string]model.AuditChange {
+	b, a := fields(before), fields(after)
+
+	changes := make(map[string]model.AuditChange)
+	for name, value := range b {
+		if other, ok := a[name]; !ok || !bytes.Equal(value, other) {
+			changes[name] = model.AuditChange{Before: value, After: a[name]}
+		}
+	}
+	for name, value := range a {
+		if _, ok := b[name]; !ok {
+			changes[name] = model.AuditChange{After: value}
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+	return changes
+}
+
+// fields returns the JSON members of v, none for nil or a nil pointer.
+func fields(v any) map[string]json.RawMessage {
+	var m map[string]json.RawMessage
+	if v == nil {
+		return m
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return m
+	}
+	json.Unmarshal(b, &m)
+	return m
+}
+
+// found returns v when it was read without error, and nil otherwise.
+func found[T any](v T, err error) any {
+	if err != nil {
+		return nil
+	}
+	return v
+}
+
+func subscriberID(subscriber *model.Subscriber) string {
+	if subscriber == nil {
+		return ""
+	}
+	return subscriber.Id
+}
+
+// Calibrate Search Results
+
+func (a *auditedRepository) CreateSearchResult(ctx context.Context, subscriber model.Subscriber, row model.CalibrateSearchResult) (*model.CalibrateSearchResult, error) {
+	result, err := a.Repository.CreateSearchResult(ctx, subscriber, row)
+	if err != nil {
+		return result, err
+	}
+	a.record(ctx, model.AuditCreate, "search_result", result.ID.String(), subscriber.Id, nil, result)
+	return result, nil
+}
+
+// Search Definition Engines
+
+func (a *auditedRepository) CreateSearchDefinitionEngine(ctx context.Context, subscriber model.Subscriber, row model.SearchDefinitionEngines) (*model.SearchDefinitionEngines, error) {
+	engine, err := a.Repository.CreateSearchDefinitionEngine(ctx, subscriber, row)
+	if err != nil {
+		return engine, err
+	}
+	a.record(ctx, model.AuditCreate, "search_definition_engine", engine.Id, subscriber.Id, nil, engine)
+	return engine, nil
+}
+
+func (a *auditedRepository) DeleteSearchDefinitionEngine(ctx context.Context, subscriber *model.Subscriber, id string) error {
+	var before any
+	if subscriber != nil {
+		if current, err := a.Repository.GetSearchDefinitionEnginesView(ctx, *subscriber, id); err == nil && current.Id != "" {
+			before = current
+		}
+	}
+	if err := a.Repository.DeleteSearchDefinitionEngine(ctx, subscriber, id); err != nil {
+		return err
+	}
+	a.record(ctx, model.AuditDelete, "search_definition_engine", id, subscriberID(subscriber), before, nil)
+	return nil
+}
+
+// Search Definitions
+
+func (a *auditedRepository) getSearchDefinition(ctx context.Context, subscriber *model.Subscriber, id string) any {
+	if subscriber == nil {
+		return nil
+	}
+	if current, err := a.Repository.GetSearchDefinition(ctx, *subscriber, id, 1, 0); err == nil && current.Id != "" {
+		return current
+	}
+	return nil
+}
+
+func (a *auditedRepository) CreateSearchDefinition(ctx context.Context, subscriber model.Subscriber, row model.SearchDefinition) (*model.SearchDefinition, error) {
+	definition, err := a.Repository.CreateSearchDefinition(ctx, subscriber, row)
+	if err != nil {
+		return definition, err
+	}
+	a.record(ctx, model.AuditCreate, "search_definition", definition.Id, subscriber.Id, nil, definition)
+	return definition, nil
+}
+
+func (a *auditedRepository) UpdateSearchDefinition(ctx context.Context, subscriber *model.Subscriber, row model.SearchDefinition) (*model.SearchDefinition, error) {
+	before := a.getSearchDefinition(ctx, subscriber, row.Id)
+	definition, err := a.Repository.UpdateSearchDefinition(ctx, subscriber, row)
+	if err != nil {
+		return definition, err
+	}
+	a.record(ctx, model.AuditUpdate, "search_definition", row.Id, subscriberID(subscriber), before, a.getSearchDefinition(ctx, subscriber, row.Id))
+	return definition, nil
+}
+
+func (a *auditedRepository) DeleteSearchDefinition(ctx context.Context, subscriber *model.Subscriber, search_definition_id string) error {
+	before := a.getSearchDefinition(ctx, subscriber, search_definition_id)
+	if err := a.Repository.DeleteSearchDefinition(ctx, subscriber, search_definition_id); err != nil {
+		return err
+	}
+	a.record(ctx, model.AuditDelete, "search_definition", search_definition_id, subscriberID(subscriber), before, nil)
+	return nil
+}
+
+// Search Engines
+
+func (a *auditedRepository) CreateSearchEngine(ctx context.Context, search_engine model.SearchEngine, subscriber model.Subscriber) (*model.SearchEngine, error) {
+	engine, err := a.Repository.CreateSearchEngine(ctx, search_engine, subscriber)
+	if err != nil {
+		return engine, err
+	}
+	a.record(ctx, model.AuditCreate, "search_engine", engine.Id, subscriber.Id, nil, engine)
+	return engine, nil
+}
+
+func (a *auditedRepository) DeleteSearchEngine(ctx context.Context, subscriber *model.Subscriber, search_engine model.SearchEngine) error {
+	var before any
+	if subscriber != nil {
+		if current, err := a.Repository.GetSearchEngine(ctx, *subscriber, search_engine.Id, 1, 0); err == nil && current.Id != "" {
+			before = current
+		}
+	}
+	if err := a.Repository.DeleteSearchEngine(ctx, subscriber, search_engine); err != nil {
+		return err
+	}
+	a.record(ctx, model.AuditDelete, "search_engine", search_engine.Id, subscriberID(subscriber), before, nil)
+	return nil
+}
+
+// Item
+
+func (a *auditedRepository) CreateItem(ctx context.Context, item *model.Item) error {
+	if err := a.Repository.CreateItem(ctx, item); err != nil {
+		return err
+	}
+	a.record(ctx, model.AuditCreate, "item", item.ID, "", nil, item)
+	return nil
+}
+
+func (a *auditedRepository) UpdateItem(ctx context.Context, item *model.Item) error {
+	before := found(a.Repository.GetItem(ctx, item.ID))
+	if err := a.Repository.UpdateItem(ctx, item); err != nil {
+		return err
+	}
+	a.record(ctx, model.AuditUpdate, "item", item.ID, "", before, found(a.Repository.GetItem(ctx, item.ID)))
+	return nil
+}
+
+func (a *auditedRepository) DeleteItem(ctx context.Context, id string) error {
+	before := found(a.Repository.GetItem(ctx, id))
+	if err := a.Repository.DeleteItem(ctx, id); err != nil {
+		return err
+	}
+	a.record(ctx, model.AuditDelete, "item", id, "", before, nil)
+	return nil
+}
+
+// User
+
+func (a *auditedRepository) CreateUser(ctx context.Context, username string, password string) (*model.User, error) {
+	user, err := a.Repository.CreateUser(ctx, username, password)
+	if err != nil {
+		return user, err
+	}
+	a.record(ctx, model.AuditCreate, "user", user.ID, "", nil, user)
+	return user, nil
+}
+
+func (a *auditedRepository) RegisterUser(ctx context.Context, username string, password string) (*model.User, error) {
+	user, err := a.Repository.RegisterUser(ctx, username, password)
+	if err != nil {
+		return user, err
+	}
+	a.record(ctx, model.AuditCreate, "user", user.ID, "", nil, user)
+	return user, nil
+}
+
+func (a *auditedRepository) DeleteUnverifiedUsers(ctx context.Context, before time.Time) ([]string, error) {
+	ids, err := a.Repository.DeleteUnverifiedUsers(ctx, before)
+	for _, id := range ids {
+		a.record(ctx, model.AuditDelete, "user", id, "", nil, nil)
+	}
+	return ids, err
+}
+
+func (a *auditedRepository) UpdateUser(ctx context.Context, item *model.User) error {
+	before := found(a.Repository.GetUser(ctx, item.ID))
+	if err := a.Repository.UpdateUser(ctx, item); err != nil {
+		return err
+	}
+	a.record(ctx, model.AuditUpdate, "user", item.ID, "", before, found(a.Repository.GetUser(ctx, item.ID)))
+	return nil
+}
+
+func (a *auditedRepository) DeleteUser(ctx context.Context, id string) error {
+	before := found(a.Repository.GetUser(ctx, id))
+	if err := a.Repository.DeleteUser(ctx, id); err != nil {
+		return err
+	}
+	a.record(ctx, model.AuditDelete, "user", id, "", before, nil)
+	return nil
+}
+
+// User_Subscriber
+
+func (a *auditedRepository) CreateUserSubscriber(ctx context.Context, user_id string, subscriber_id string) (*model.User_Subscriber, error) {
+	user_subscriber, err := a.Repository.CreateUserSubscriber(ctx, user_id, subscriber_id)
+	if err != nil {
+		return user_subscriber, err
+	}
+	a.record(ctx, model.AuditCreate, "user_subscriber", user_subscriber.Id, subscriber_id, nil, user_subscriber)
+	return user_subscriber, nil
+}
+
+func (a *auditedRepository) UpdateUserSubscriber(ctx context.Context, user_subscriber model.User_Subscriber) error {
+	before := found(a.Repository.GetUserSubscriber(ctx, user_subscriber.Id))
+	if err := a.Repository.UpdateUserSubscriber(ctx, user_subscriber); err != nil {
+		return err
+	}
+	after := found(a.Repository.GetUserSubscriber(ctx, user_subscriber.Id))
+	a.record(ctx, model.AuditUpdate, "user_subscriber", user_subscriber.Id, user_subscriber.Subscriber_Id, before, after)
+	return nil
+}
+
+func (a *auditedRepository) DeleteUserSubscriber(ctx context.Context, id string) error {
+	current, err := a.Repository.GetUserSubscriber(ctx, id)
+	before, subscriber_id := found(current, err), ""
+	if err == nil && current != nil {
+		subscriber_id = current.Subscriber_Id
+	}
+	if err := a.Repository.DeleteUserSubscriber(ctx, id); err != nil {
+		return err
+	}
+	a.record(ctx, model.AuditDelete, "user_subscriber", id, subscriber_id, before, nil)
+	return nil
+}
+
+// User_Subscriber_Role
+
+func (a *auditedRepository) CreateUserSubscriberRole(ctx context.Context, user_subscriber_id string, role_id string) (*model.User_Subscriber_Role, error) {
+	role, err := a.Repository.CreateUserSubscriberRole(ctx, user_subscriber_id, role_id)
+	if err != nil {
+		return role, err
+	}
+	a.record(ctx, model.AuditCreate, "user_subscriber_role", role.Id, "", nil, role)
+	return role, nil
+}
+
+func (a *auditedRepository) UpdateUserSubscriberRole(ctx context.Context, user_subscriber_role model.User_Subscriber_Role) error {
+	before := found(a.Repository.GetUserSubscriberRole(ctx, user_subscriber_role.Id))
+	if err := a.Repository.UpdateUserSubscriberRole(ctx, user_subscriber_role); err != nil {
+		return err
+	}
+	after := found(a.Repository.GetUserSubscriberRole(ctx, user_subscriber_role.Id))
+	a.record(ctx, model.AuditUpdate, "user_subscriber_role", user_subscriber_role.Id, "", before, after)
+	return nil
+}
+
+func (a *auditedRepository) DeleteUserSubscriberRole(ctx context.Context, id string) error {
+	before := found(a.Repository.GetUserSubscriberRole(ctx, id))
+	if err := a.Repository.DeleteUserSubscriberRole(ctx, id); err != nil {
+		return err
+	}
+	a.record(ctx, model.AuditDelete, "user_subscriber_role", id, "", before, nil)
+	return nil
+}
+
+// Customer
+
+func (a *auditedRepository) CreateCustomer(ctx context.Context, customer *model.Customer, subscriber *model.Subscriber) (*model.Customer, error) {
+	created, err := a.Repository.CreateCustomer(ctx, customer, subscriber)
+	if err != nil {
+		return created, err
+	}
+	a.record(ctx, model.AuditCreate, "customer", created.Id, created.Subscriber_Id, nil, created)
+	return created, nil
+}
+
+func (a *auditedRepository) UpdateCustomer(ctx context.Context, customer *model.Customer) error {
+	before := found(a.Repository.GetCustomer(ctx, *customer))
+	if err := a.Repository.UpdateCustomer(ctx, customer); err != nil {
+		return err
+	}
+	after := found(a.Repository.GetCustomer(ctx, *customer))
+	a.record(ctx, model.AuditUpdate, "customer", customer.Id, customer.Subscriber_Id, before, after)
+	return nil
+}
+
+func (a *auditedRepository) DeleteCustomer(ctx context.Context, customer *model.Customer) error {
+	before := found(a.Repository.GetCustomer(ctx, *customer))
+	if err := a.Repository.DeleteCustomer(ctx, customer); err != nil {
+		return err
+	}
+	a.record(ctx, model.AuditDelete, "customer", customer.Id, customer.Subscriber_Id, before, nil)
+	return nil
+}
+
+// Subscriber
+
+func (a *auditedRepository) CreateSubscriber(ctx context.Context, subscriber *model.Subscriber) (*model.Subscriber, error) {
+	created, err := a.Repository.CreateSubscriber(ctx, subscriber)
+	if err != nil {
+		return created, err
+	}
+	a.record(ctx, model.AuditCreate, "subscriber", created.Id, created.Id, nil, created)
+	return created, nil
+}
+
+func (a *auditedRepository) UpdateSubscriber(ctx context.Context, subscriber *model.Subscriber) error {
+	before := found(a.Repository.GetSubscriber(ctx, subscriber.Id))
+	if err := a.Repository.UpdateSubscriber(ctx, subscriber); err != nil {
+		return err
+	}
+	after := found(a.Repository.GetSubscriber(ctx, subscriber.Id))
+	a.record(ctx, model.AuditUpdate, "subscriber", subscriber.Id, subscriber.Id, before, after)
+	return nil
+}
+
+func (a *auditedRepository) DeleteSubscriber(ctx context.Context, subscriber *model.Subscriber) error {
+	before := found(a.Repository.GetSubscriber(ctx, subscriber.Id))
+	if err := a.Repository.DeleteSubscriber(ctx, subscriber); err != nil {
+		return err
+	}
+	a.record(ctx, model.AuditDelete, "subscriber", subscriber.Id, subscriber.Id, before, nil)
+	return nil
+}
+
+// Subscriber Addresses
+
+func (a *auditedRepository) CreateSubscriberAddress(ctx context.Context, subscriber *model.Subscriber, address model.Address) error {
+	if err := a.Repository.CreateSubscriberAddress(ctx, subscriber, address); err != nil {
+		return err
+	}
+	a.record(ctx, model.AuditCreate, "address", address.Id, subscriberID(subscriber), nil, address)
+	return nil
+}
+
+func (a *auditedRepository) UpdateSubscriberAddress(ctx context.Context, subscriber *model.Subscriber, address model.Address) error {
+	before := found(a.Repository.GetSubscriberAddress(ctx, subscriber.Schema_Name, address.Id))
+	if err := a.Repository.UpdateSubscriberAddress(ctx, subscriber, address); err != nil {
+		return err
+	}
+	after := found(a.Repository.GetSubscriberAddress(ctx, subscriber.Schema_Name, address.Id))
+	a.record(ctx, model.AuditUpdate, "address", address.Id, subscriber.Id, before, after)
+	return nil
+}
+
+func (a *auditedRepository) DeleteSubscriberAddress(ctx context.Context, subscriber_schema_name string, address_id string) error {
+	current, err := a.Repository.GetSubscriberAddress(ctx, subscriber_schema_name, address_id)
+	before, subscriber_id := found(current, err), ""
+	if err == nil && current != nil {
+		subscriber_id = current.SubscriberId
+	}
+	if err := a.Repository.DeleteSubscriberAddress(ctx, subscriber_schema_name, address_id); err != nil {
+		return err
+	}
+	a.record(ctx, model.AuditDelete, "address", address_id, subscriber_id, before, nil)
+	return nil
+}
+
+// Subscriber Backgrounds
+
+func (a *auditedRepository) CreateSubscriberBackground(ctx context.Context, subscriber *model.Subscriber, background model.Background) error {
+	if err := a.Repository.CreateSubscriberBackground(ctx, subscriber, background); err != nil {
+		return err
+	}
+	a.record(ctx, model.AuditCreate, "background", background.Id, subscriberID(subscriber), nil, background)
+	return nil
+}
+
+func (a *auditedRepository) UpdateSubscriberBackground(ctx context.Context, subscriber *model.Subscriber, background model.Background) error {
+	before := found(a.Repository.GetSubscriberBackground(ctx, subscriber.Schema_Name, background.Id))
+	if err := a.Repository.UpdateSubscriberBackground(ctx, subscriber, background); err != nil {
+		return err
+	}
+	after := found(a.Repository.GetSubscriberBackground(ctx, subscriber.Schema_Name, background.Id))
+	a.record(ctx, model.AuditUpdate, "background", background.Id, subscriber.Id, before, after)
+	return nil
+}
+
+func (a *auditedRepository) DeleteSubscriberBackground(ctx context.Context, subscriber_schema_name string, background_id string) error {
+	current, err := a.Repository.GetSubscriberBackground(ctx, subscriber_schema_name, background_id)
+	before, subscriber_id := found(current, err), ""
+	if err == nil && current != nil {
+		subscriber_id = current.SubscriberId
+	}
+	if err := a.Repository.DeleteSubscriberBackground(ctx, subscriber_schema_name, background_id); err != nil {
+		return err
+	}
+	a.record(ctx, model.AuditDelete, "background", background_id, subscriber_id, before, nil)
+	return nil
+}
+
+// Subscriber_Item
+
+func (a *auditedRepository) CreateSubscriberItem(ctx context.Context, item_id string, subscriber_id string) (*model.Subscriber_Item, error) {
+	subscriber_item, err := a.Repository.CreateSubscriberItem(ctx, item_id, subscriber_id)
+	if err != nil {
+		return subscriber_item, err
+	}
+	a.record(ctx, model.AuditCreate, "subscriber_item", subscriber_item.Id, subscriber_id, nil, subscriber_item)
+	return subscriber_item, nil
+}
+
+func (a *auditedRepository) DeleteSubscriberItem(ctx context.Context, id string) error {
+	current, err := a.Repository.GetSubscriberItem(ctx, id)
+	before, subscriber_id := found(current, err), ""
+	if err == nil && current != nil {
+		subscriber_id = current.Subscriber_Id
+	}
+	if err := a.Repository.DeleteSubscriberItem(ctx, id); err != nil {
+		return err
+	}
+	a.record(ctx, model.AuditDelete, "subscriber_item", id, subscriber_id, before, nil)
+	return nil
+}
+
+// Blocked
+
+func (a *auditedRepository) CreateBlocked(ctx context.Context, blocked model.Blocked) (*model.Blocked, error) {
+	created, err := a.Repository.CreateBlocked(ctx, blocked)
+	if err != nil {
+		return created, err
+	}
+	a.record(ctx, model.AuditCreate, "blocked", created.ID, "", nil, created)
+	return created, nil
+}
+
+func (a *auditedRepository) UpdateBlocked(ctx context.Context, item *model.Blocked) error {
+	before := found(a.Repository.GetBlocked(ctx, item.ID))
+	if err := a.Repository.UpdateBlocked(ctx, item); err != nil {
+		return err
+	}
+	a.record(ctx, model.AuditUpdate, "blocked", item.ID, "", before, found(a.Repository.GetBlocked(ctx, item.ID)))
+	return nil
+}
+
+func (a *auditedRepository) DeleteBlocked(ctx context.Context, id string) error {
+	before := found(a.Repository.GetBlocked(ctx, id))
+	if err := a.Repository.DeleteBlocked(ctx, id); err != nil {
+		return err
+	}
+	a.record(ctx, model.AuditDelete, "blocked", id, "", before, nil)
+	return nil
+}
+
+// Allowed
+
+func (a *auditedRepository) CreateAllowed(ctx context.Context, allowed model.Allowed) (*model.Allowed, error) {
+	created, err := a.Repository.CreateAllowed(ctx, allowed)
+	if err != nil {
+		return created, err
+	}
+	a.record(ctx, model.AuditCreate, "allowed", created.ID, "", nil, created)
+	return created, nil
+}
+
+func (a *auditedRepository) UpdateAllowed(ctx context.Context, allowed *model.Allowed) error {
+	before := found(a.Repository.GetAllowed(ctx, allowed.ID))
+	if err := a.Repository.UpdateAllowed(ctx, allowed); err != nil {
+		return err
+	}
+	a.record(ctx, model.AuditUpdate, "allowed", allowed.ID, "", before, found(a.Repository.GetAllowed(ctx, allowed.ID)))
+	return nil
+}
+
+func (a *auditedRepository) DeleteAllowed(ctx context.Context, id string) error {
+	before := found(a.Repository.GetAllowed(ctx, id))
+	if err := a.Repository.DeleteAllowed(ctx, id); err != nil {
+		return err
+	}
+	a.record(ctx, model.AuditDelete, "allowed", id, "", before, nil)
+	return nil
+}
+
+// Roles
+
+func (a *auditedRepository) CreateRole(ctx context.Context, name string) (*model.Role, error) {
+	role, err := a.Repository.CreateRole(ctx, name)
+	if err != nil {
+		return role, err
+	}
+	a.record(ctx, model.AuditCreate, "role", role.Id, "", nil, role)
+	return role, nil
+}
+
+func (a *auditedRepository) UpdateRole(ctx context.Context, role *model.Role) error {
+	before := found(a.Repository.GetRole(ctx, role.Id))
+	if err := a.Repository.UpdateRole(ctx, role); err != nil {
+		return err
+	}
+	a.record(ctx, model.AuditUpdate, "role", role.Id, "", before, found(a.Repository.GetRole(ctx, role.Id)))
+	return nil
+}
+
+func (a *auditedRepository) DeleteRole(ctx context.Context, id string) error {
+	before := found(a.Repository.GetRole(ctx, id))
+	if err := a.Repository.DeleteRole(ctx, id); err != nil {
+		return err
+	}
+	a.record(ctx, model.AuditDelete, "role", id, "", before, nil)
+	return nil
+}
+
+// Permission
+
+func (a *auditedRepository) CreatePermission(ctx context.Context, name string, description string, object_id string) (*model.Permission, error) {
+	permission, err := a.Repository.CreatePermission(ctx, name, description, object_id)
+	if err != nil {
+		return permission, err
+	}
+	a.record(ctx, model.AuditCreate, "permission", permission.Id, "", nil, permission)
+	return permission, nil
+}
+
+func (a *auditedRepository) UpdatePermission(ctx context.Context, permission *model.Permission) error {
+	before := found(a.Repository.GetPermission(ctx, permission.Id))
+	if err := a.Repository.UpdatePermission(ctx, permission); err != nil {
+		return err
+	}
+	a.record(ctx, model.AuditUpdate, "permission", permission.Id, "", before, found(a.Repository.GetPermission(ctx, permission.Id)))
+	return nil
+}
+
+func (a *auditedRepository) DeletePermission(ctx context.Context, id string) error {
+	before := found(a.Repository.GetPermission(ctx, id))
+	if err := a.Repository.DeletePermission(ctx, id); err != nil {
+		return err
+	}
+	a.record(ctx, model.AuditDelete, "permission", id, "", before, nil)
+	return nil
+}
+
+// Profiles
+
+func (a *auditedRepository) CreateProfile(ctx context.Context, subscriber model.Subscriber, profile model.Profile) (*model.Profile, error) {
+	created, err := a.Repository.CreateProfile(ctx, subscriber, profile)
+	if err != nil {
+		return created, err
+	}
+	a.record(ctx, model.AuditCreate, "profile", created.Id, subscriber.Id, nil, created)
+	return created, nil
+}
+
+func (a *auditedRepository) UpdateProfile(ctx context.Context, subscriber *model.Subscriber, profile *model.Profile) error {
+	before := found(a.Repository.GetProfile(ctx, subscriber))
+	if err := a.Repository.UpdateProfile(ctx, subscriber, profile); err != nil {
+		return err
+	}
+	after := found(a.Repository.GetProfile(ctx, subscriber))
+	a.record(ctx, model.AuditUpdate, "profile", profile.Id, subscriberID(subscriber), before, after)
+	return nil
+}
+
+// DeleteProfile has no lookup by ID, so its event records no before values.
+func (a *auditedRepository) DeleteProfile(ctx context.Context, id string) error {
+	if err := a.Repository.DeleteProfile(ctx, id); err != nil {
+		return err
+	}
+	a.record(ctx, model.AuditDelete, "profile", id, "", nil, nil)
+	return nil
+}
+
+// Contacts
+
+func (a *auditedRepository) CreateContact(ctx context.Context, contact *model.Contact) (*model.Contact, error) {
+	created, err := a.Repository.CreateContact(ctx, contact)
+	if err != nil {
+		return created, err
+	}
+	a.record(ctx, model.AuditCreate, "contact", created.Id, created.Subscriber_Id_, nil, created)
+	return created, nil
+}
+
+func (a *auditedRepository) UpdateContact(ctx context.Context, contact *model.Contact) error {
+	before := found(a.Repository.GetContact(ctx, *contact))
+	if err := a.Repository.UpdateContact(ctx, contact); err != nil {
+		return err
+	}
+	after := found(a.Repository.GetContact(ctx, *contact))
+	a.record(ctx, model.AuditUpdate, "contact", contact.Id, contact.Subscriber_Id_, before, after)
+	return nil
+}
+
+func (a *auditedRepository) DeleteContact(ctx context.Context, contact *model.Contact) error {
+	before := found(a.Repository.GetContact(ctx, *contact))
+	if err := a.Repository.DeleteContact(ctx, contact); err != nil {
+		return err
+	}
+	a.record(ctx, model.AuditDelete, "contact", contact.Id, contact.Subscriber_Id_, before, nil)
+	return nil
+}
+
+// Single sign-on
+
+func (a *auditedRepository) SaveSubscriberOIDC(ctx context.Context, cfg *model.SubscriberOIDC) error {
+	current, err := a.Repository.GetSubscriberOIDC(ctx, cfg.Subscriber_Id)
+	before := found(current, err)
+	if err := a.Repository.SaveSubscriberOIDC(ctx, cfg); err != nil {
+		return err
+	}
+	action := model.AuditUpdate
+	if err == nil && current == nil {
+		action = model.AuditCreate
+	}
+	after := found(a.Repository.GetSubscriberOIDC(ctx, cfg.Subscriber_Id))
+	a.record(ctx, action, "subscriber_oidc", cfg.Subscriber_Id, cfg.Subscriber_Id, before, after)
+	return nil
+}
+
+func (a *auditedRepository) DeleteSubscriberOIDC(ctx context.Context, subscriber_id string) error {
+	before := found(a.Repository.GetSubscriberOIDC(ctx, subscriber_id))
+	if err := a.Repository.DeleteSubscriberOIDC(ctx, subscriber_id); err != nil {
+		return err
+	}
+	a.record(ctx, model.AuditDelete, "subscriber_oidc", subscriber_id, subscriber_id, before, nil)
+	return nil
+}
+
+func (a *auditedRepository) CreateUserIdentity(ctx context.Context, identity *model.UserIdentity) (*model.UserIdentity, error) {
+	created, err := a.Repository.CreateUserIdentity(ctx, identity)
+	if err != nil {
+		return created, err
+	}
+	a.record(ctx, model.AuditCreate, "user_identity", created.Id, created.Subscriber_Id, nil, created)
+	return created, nil
+}
+
+// Invitations
+
+func (a *auditedRepository) CreateInvitation(ctx context.Context, invitation *model.Invitation, token_hash string) (*model.Invitation, error) {
+	created, err := a.Repository.CreateInvitation(ctx, invitation, token_hash)
+	if err != nil {
+		return created, err
+	}
+	a.record(ctx, model.AuditCreate, "invitation", created.Id, created.Subscriber_Id, nil, created)
+	return created, nil
+}
+
+func (a *auditedRepository) RevokeInvitation(ctx context.Context, id string) error {
+	current, err := a.Repository.GetInvitation(ctx, id)
+	before := found(current, err)
+	if err := a.Repository.RevokeInvitation(ctx, id); err != nil {
+		return err
+	}
+	subscriberID := ""
+	if current != nil {
+		subscriberID = current.Subscriber_Id
+	}
+	after := found(a.Repository.GetInvitation(ctx, id))
+	a.record(ctx, model.AuditUpdate, "invitation", id, subscriberID, before, after)
+	return nil
+}
+
+func (a *auditedRepository) AcceptInvitation(ctx context.Context, token_hash string, user_id string, password_hash string) (*model.Invitation, error) {
+	before := found(a.Repository.GetPendingInvitation(ctx, token_hash))
+	accepted, err := a.Repository.AcceptInvitation(ctx, token_hash, user_id, password_hash)
+	if err != nil {
+		return accepted, err
+	}
+	a.record(ctx, model.AuditUpdate, "invitation", accepted.Id, accepted.Subscriber_Id, before, accepted)
+	return accepted, nil
+}
+
+// API keys and service accounts
+
+func (a *auditedRepository) CreateAPIKey(ctx context.Context, key *model.APIKey, key_hash string) (*model.APIKey, error) {
+	created, err := a.Repository.CreateAPIKey(ctx, key, key_hash)
+	if err != nil {
+		return created, err
+	}
+	a.record(ctx, model.AuditCreate, "api_key", created.Id, created.Subscriber_Id, nil, created)
+	return created, nil
+}
+
+func (a *auditedRepository) RevokeAPIKey(ctx context.Context, id string) error {
+	current, err := a.Repository.GetAPIKey(ctx, id)
+	before := found(current, err)
+	if err := a.Repository.RevokeAPIKey(ctx, id); err != nil {
+		return err
+	}
+	subscriberID := ""
+	if current != nil {
+		subscriberID = current.Subscriber_Id
+	}
+	after := found(a.Repository.GetAPIKey(ctx, id))
+	a.record(ctx, model.AuditUpdate, "api_key", id, subscriberID, before, after)
+	return nil
+}
+
+func (a *auditedRepository) CreateServiceAccount(ctx context.Context, name string, subscriber_id string, role_id string) (*model.User, error) {
+	user, err := a.Repository.CreateServiceAccount(ctx, name, subscriber_id, role_id)
+	if err != nil {
+		return user, err
+	}
+	a.record(ctx, model.AuditCreate, "user", user.ID, subscriber_id, nil, user)
+	return user, nil
+}
+
+// Email templates and unsubscribes
+
+func (a *auditedRepository) SaveEmailTemplate(ctx context.Context, template *model.EmailTemplate) (*model.EmailTemplate, error) {
+	current, err := a.Repository.GetEmailTemplate(ctx, template.Subscriber_Id, template.Name)
+	before := found(current, err)
+	saved, err := a.Repository.SaveEmailTemplate(ctx, template)
+	if err != nil {
+		return saved, err
+	}
+	action := model.AuditUpdate
+	if current == nil {
+		action = model.AuditCreate
+		before = nil
+	}
+	a.record(ctx, action, "email_template", template.Name, template.Subscriber_Id, before, saved)
+	return saved, nil
+}
+
+func (a *auditedRepository) DeleteEmailTemplate(ctx context.Context, subscriber_id string, name string) error {
+	before := found(a.Repository.GetEmailTemplate(ctx, subscriber_id, name))
+	if err := a.Repository.DeleteEmailTemplate(ctx, subscriber_id, name); err != nil {
+		return err
+	}
+	a.record(ctx, model.AuditDelete, "email_template", name, subscriber_id, before, nil)
+	return nil
+}
+
+func (a *auditedRepository) SaveNotificationPreferences(ctx context.Context, preferences *model.NotificationPreferences) (*model.NotificationPreferences, error) {
+	current, err := a.Repository.GetNotificationPreferences(ctx, preferences.User_Id, preferences.Subscriber_Id)
+	before := found(current, err)
+	saved, err := a.Repository.SaveNotificationPreferences(ctx, preferences)
+	if err != nil {
+		return saved, err
+	}
+	action := model.AuditUpdate
+	if current == nil {
+		action = model.AuditCreate
+		before = nil
+	}
+	a.record(ctx, action, "notification_preferences", preferences.User_Id, preferences.Subscriber_Id, before, saved)
+	return saved, nil
+}
+
+func (a *auditedRepository) DeleteNotificationPreferences(ctx context.Context, user_id string, subscriber_id string) error {
+	before := found(a.Repository.GetNotificationPreferences(ctx, user_id, subscriber_id))
+	if err := a.Repository.DeleteNotificationPreferences(ctx, user_id, subscriber_id); err != nil {
+		return err
+	}
+	a.record(ctx, model.AuditDelete, "notification_preferences", user_id, subscriber_id, before, nil)
+	return nil
+}
+
+func (a *auditedRepository) CreateMailboxSource(ctx context.Context, source *model.MailboxSource) (*model.MailboxSource, error) {
+	created, err := a.Repository.CreateMailboxSource(ctx, source)
+	if err != nil {
+		return created, err
+	}
+	a.record(ctx, model.AuditCreate, "mailbox_source", created.Id, created.Subscriber_Id, nil, created)
+	return created, nil
+}
+
+func (a *auditedRepository) UpdateMailboxSource(ctx context.Context, source *model.MailboxSource) (*model.MailboxSource, error) {
+	before := found(a.Repository.GetMailboxSource(ctx, source.Id))
+	updated, err := a.Repository.UpdateMailboxSource(ctx, source)
+	if err != nil {
+		return updated, err
+	}
+	a.record(ctx, model.AuditUpdate, "mailbox_source", updated.Id, updated.Subscriber_Id, before, updated)
+	return updated, nil
+}
+
+func (a *auditedRepository) DeleteMailboxSource(ctx context.Context, id string) error {
+	current, err := a.Repository.GetMailboxSource(ctx, id)
+	before := found(current, err)
+	if err := a.Repository.DeleteMailboxSource(ctx, id); err != nil {
+		return err
+	}
+	subscriberID := ""
+	if current != nil {
+		subscriberID = current.Subscriber_Id
+	}
+	a.record(ctx, model.AuditDelete, "mailbox_source", id, subscriberID, before, nil)
+	return nil
+}
+
+func (a *auditedRepository) SaveMailboxMessage(ctx context.Context, subscriber model.Subscriber, source_id string, message_id string, results []model.CalibrateSearchResult) ([]model.CalibrateSearchResult, error) {
+	stored, err := a.Repository.SaveMailboxMessage(ctx, subscriber, source_id, message_id, results)
+	if err != nil {
+		return stored, err
+	}
+	for _, result := range stored {
+		a.record(ctx, model.AuditCreate, "search_result", result.ID.String(), subscriber.Id, nil, result)
+	}
+	return stored, nil
+}
+
+func (a *auditedRepository) Unsubscribe(ctx context.Context, email string, category string) error {
+	if err := a.Repository.Unsubscribe(ctx, email, category); err != nil {
+		return err
+	}
+	a.record(ctx, model.AuditCreate, "email_unsubscribe", email, "", nil, map[string]string{"category": category})
+	return nil
+}
@@ -3,52 +3,46 @@ package database
 import (
 	"context"
 	"database/sql"
-	"errors"
 	"fmt"
-	"strings"
+	"log/slog"
 	"time"
 
 	"github.com/htstinson/stinsondataapi/api/internal/model"
 )
 
-// Admin - Blocked
-func (d *Database) SelectBlocked(ctx context.Context, limit int, offset int, sort string, order string) ([]model.Blocked, error) {
-
-	q := fmt.Sprintf("SELECT id, ip, notes, created_at FROM blocked ORDER BY %s %s LIMIT $1 OFFSET $2", sort, strings.ToUpper(order))
-
-	rows, err := d.DB.QueryContext(ctx, q, limit, offset)
-
-	if err != nil {
-		fmt.Printf("[%v] [database][SelectBlocked] error: %s.\n", time.Now().Format(time.RFC3339), err.Error())
-		fmt.Printf("[%v] [database][SelectBlocked] query: %s.\n", time.Now().Format(time.RFC3339), q)
-		fmt.Printf("[%v] [database][SelectBlocked] limit %v offset %v sort %s order %s.\n", time.Now().Format(time.RFC3339), limit, offset, strings.ToUpper(sort), strings.ToUpper(order))
-		return nil, fmt.Errorf("query error")
-	}
-	defer rows.Close()
-
-	var items []model.Blocked
+// BlockedList is how the blocked addresses may be listed.
+var BlockedList = ListSpec{
+	Sorts:       map[string]string{"ip": "ip", "created_at": "created_at"},
+	Filters:     map[string]string{"ip": "ip", "notes": "notes", "created_at": "created_at"},
+	DefaultSort: "ip",
+	Keys:        []string{"id"},
+}
 
-	for rows.Next() {
+// Admin - Blocked
+func (d *Database) SelectBlocked(ctx context.Context, q ListQuery) (*Page[model.Blocked], error) {
+
+	page, err := selectList(ctx, d.DB, list{
+		Spec:    BlockedList,
+		Query:   q,
+		Columns: []string{"id", "ip", "notes", "created_at"},
+		From:    "blocked",
+	}, func(scan func(...any) error) (model.Blocked, error) {
 		var item model.Blocked
 		var notesNullable sql.NullString
-		if err := rows.Scan(&item.ID, &item.IP, &notesNullable, &item.CreatedAt); err != nil {
-			fmt.Printf("[%v] [database][ListBlocked] error: %s.\n", time.Now().Format(time.RFC3339), err.Error())
-			return nil, fmt.Errorf("error scanning blocked: %w", err)
-		}
-		if notesNullable.Valid {
-			item.Notes = notesNullable.String
-		} else {
-			item.Notes = ""
-		}
-
-		items = append(items, item)
+		err := scan(&item.ID, &item.IP, &notesNullable, &item.CreatedAt)
+		item.Notes = notesNullable.String
+		return item, err
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "SelectBlocked", "error", err)
+		return nil, fmt.Errorf("error listing blocked: %w", err)
 	}
 
-	return items, nil
+	return page, nil
 }
 
 func (d *Database) UpdateBlocked(ctx context.Context, blocked *model.Blocked) error {
-	fmt.Println("d UpdateBlocked", blocked.IP, blocked.Notes)
+	slog.DebugContext(ctx, "UpdateBlocked", "ip", blocked.IP)
 
 	query := `UPDATE blocked SET ip=$1, notes=$2 WHERE id = $3`
 
@@ -71,7 +65,7 @@ func (d *Database) GetBlockedByIP(ctx context.Context, ip string) (*model.Blocke
 	}
 
 	if err != nil {
-		fmt.Println("d GetBlockedByIP", err.Error())
+		slog.ErrorContext(ctx, "GetBlockedByIP", "error", err)
 		return nil, fmt.Errorf("error getting blocked: %w", err)
 	}
 
@@ -98,7 +92,7 @@ func (d *Database) GetBlocked(ctx context.Context, id string) (*model.Blocked, e
 	}
 
 	if err != nil {
-		fmt.Println("d GetBlocked", err.Error())
+		slog.ErrorContext(ctx, "GetBlocked", "error", err)
 		return nil, fmt.Errorf("error getting blocked: %w", err)
 	}
 
@@ -113,12 +107,12 @@ func (d *Database) GetBlocked(ctx context.Context, id string) (*model.Blocked, e
 }
 
 func (d *Database) CreateBlocked(ctx context.Context, blocked model.Blocked) (*model.Blocked, error) {
-	fmt.Println("d CreateBlocked(ctx, blocked)")
+	slog.DebugContext(ctx, "CreateBlocked")
 	blocked.CreatedAt = time.Now()
 
 	_, err := d.GetBlockedByIP(ctx, blocked.IP)
 	if err == nil {
-		return nil, errors.New("duplicate")
+		return nil, ErrDuplicate
 	}
 
 	query := `
@@ -128,7 +122,7 @@ func (d *Database) CreateBlocked(ctx context.Context, blocked model.Blocked) (*m
 
 	_, err = d.DB.ExecContext(ctx, query, blocked.IP, blocked.Notes, blocked.CreatedAt)
 	if err != nil {
-		fmt.Println(err.Error())
+		slog.ErrorContext(ctx, "CreateBlocked", "error", err)
 		return nil, fmt.Errorf("error creating blocked: %w", err)
 	}
 
@@ -3,41 +3,44 @@ package database
 import (
 	"context"
 	"fmt"
+	"log/slog"
 
 	"github.com/htstinson/stinsondataapi/api/internal/model"
 )
 
-func (d *Database) SelectCalibrateMention(ctx context.Context, subscriber model.Subscriber, search_result_id string) (*[]model.CalibrateMention, error) {
-	fmt.Println("d SelectCalibrateMention")
+// CalibrateMentionList is how the mentions of a search result may be listed.
+var CalibrateMentionList = ListSpec{
+	Sorts:       map[string]string{"created_at": "created_at", "rating": "rating"},
+	Filters:     map[string]string{"rating": "rating", "author": "author", "title": "title", "created_at": "created_at"},
+	DefaultSort: "created_at",
+	DefaultDesc: true,
+	Keys:        []string{"id"},
+}
+
+func (d *Database) SelectCalibrateMention(ctx context.Context, subscriber model.Subscriber, search_result_id string, q ListQuery) (*Page[model.CalibrateMention], error) {
+	slog.DebugContext(ctx, "SelectCalibrateMention")
 
 	table := "calibrate_mentions"
 	schema_name := subscriber.Schema_Name
 
-	query := fmt.Sprintf(`SELECT id, created_at, modified_at, calibrate_result_id, rating, subscriber_id, title, body,
-	rating_date, author, location, headline 
-	FROM %s.%s WHERE search_result_id = $1`, schema_name, table)
-
-	rows, err := d.DB.QueryContext(ctx, query, search_result_id)
-	if err != nil {
-		fmt.Println(err.Error())
-	}
-
-	fmt.Println(rows.Err())
-
-	var items []model.CalibrateMention
-	for rows.Next() {
+	page, err := selectList(ctx, d.DB, list{
+		Spec:  CalibrateMentionList,
+		Query: q,
+		Columns: []string{"id", "created_at", "modified_at", "calibrate_result_id", "rating", "subscriber_id", "title", "body",
+			"rating_date", "author", "location", "headline"},
+		From:  fmt.Sprintf("%s.%s", schema_name, table),
+		Where: []string{"search_result_id = $1"},
+		Args:  []any{search_result_id},
+	}, func(scan func(...any) error) (model.CalibrateMention, error) {
 		var item model.CalibrateMention
-
-		err = rows.Scan(&item.ID, &item.CreatedAt, &item.CalibrateResultID, &item.Rating, &item.SubscriberID, &item.Title, &item.Body,
+		err := scan(&item.ID, &item.CreatedAt, &item.ModifiedAt, &item.CalibrateResultID, &item.Rating, &item.SubscriberID, &item.Title, &item.Body,
 			&item.RatingDate, &item.Author, &item.Location, &item.Headline)
-
-		if err != nil {
-			fmt.Println(err.Error())
-			return &items, nil
-		}
-
-		items = append(items, item)
+		return item, err
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "SelectCalibrateMention", "error", err)
+		return nil, fmt.Errorf("error listing calibrate mentions: %w", err)
 	}
 
-	return &items, nil
+	return page, nil
 }
@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,7 +11,7 @@ import (
 )
 
 func (d *Database) CreateSearchResult(ctx context.Context, subscriber model.Subscriber, row model.CalibrateSearchResult) (*model.CalibrateSearchResult, error) {
-	fmt.Println("d CreateSearchResult")
+	slog.DebugContext(ctx, "CreateSearchResult")
 
 	table := "calibrate_search_results"
 	schema_name := subscriber.Schema_Name
@@ -20,54 +21,57 @@ func (d *Database) CreateSearchResult(ctx context.Context, subscriber model.Subs
 
 	id := uuid.New().String()
 
-	fmt.Println("published", row.Published.Format(time.RFC3339))
+	slog.DebugContext(ctx, "CreateSearchResult", "published", row.Published.Format(time.RFC3339))
 
 	_, err := d.DB.ExecContext(ctx, query,
 		id, row.Link, row.Snippet, row.Title, row.SearchDefinitionEngineID, row.SearchTime, row.SubscriberID, row.Published)
 
 	if err != nil {
-		fmt.Println(err.Error())
+		slog.ErrorContext(ctx, "CreateSearchResult", "error", err)
 		return nil, fmt.Errorf("error creating search result: %w", err)
 	}
 
 	return &row, nil
 }
 
-func (d *Database) SelectSearchResultView(ctx context.Context, subscriber model.Subscriber, sde string) (*[]model.CalibrateSearchResultView, error) {
-	fmt.Println("d SelectSearchResultView")
+// SearchResultList is how the results of a search definition engine may be
+// listed.
+var SearchResultList = ListSpec{
+	Sorts:       map[string]string{"created_at": "result_created_at"},
+	Filters:     map[string]string{"title": "title", "link": "link", "snippet": "snippet", "published": "published", "search_time": "search_time", "created_at": "result_created_at"},
+	DefaultSort: "created_at",
+	DefaultDesc: true,
+	Keys:        []string{"result_id"},
+}
+
+func (d *Database) SelectSearchResultView(ctx context.Context, subscriber model.Subscriber, sde string, q ListQuery) (*Page[model.CalibrateSearchResultView], error) {
+	slog.DebugContext(ctx, "SelectSearchResultView")
 
 	table := "v_calibrate_search_results"
 	schema_name := subscriber.Schema_Name
 
-	query := fmt.Sprintf(`SELECT result_id, link, snippet, title, search_time, result_created_at, subscriber_id,
-	search_definition_id, search_definition_name, query, search_definition_comment, exact_match, max_results, sort_by_date,
-	start_date, end_date, search_type, search_engine_id, search_engine_name, search_engine_identifier, search_engine_comment,
-	search_definition_engine_id, published FROM %s.%s WHERE search_definition_engine_id = $1`, schema_name, table)
-
-	rows, err := d.DB.QueryContext(ctx, query, sde)
-	if err != nil {
-		fmt.Println(err.Error())
-	}
-
-	fmt.Println(rows.Err())
-
-	var items []model.CalibrateSearchResultView
-	for rows.Next() {
+	page, err := selectList(ctx, d.DB, list{
+		Spec:  SearchResultList,
+		Query: q,
+		Columns: []string{"result_id", "link", "snippet", "title", "search_time", "result_created_at", "subscriber_id",
+			"search_definition_id", "search_definition_name", "query", "search_definition_comment", "exact_match", "max_results", "sort_by_date",
+			"start_date", "end_date", "search_type", "search_engine_id", "search_engine_name", "search_engine_identifier", "search_engine_comment",
+			"search_definition_engine_id", "published"},
+		From:  fmt.Sprintf("%s.%s", schema_name, table),
+		Where: []string{"search_definition_engine_id = $1"},
+		Args:  []any{sde},
+	}, func(scan func(...any) error) (model.CalibrateSearchResultView, error) {
 		var item model.CalibrateSearchResultView
-
-		err = rows.Scan(&item.ResultId, &item.Link, &item.Snippet, &item.Title, &item.SearchTime, &item.ResultCreatedAt, &item.SubscriberId,
+		err := scan(&item.ResultId, &item.Link, &item.Snippet, &item.Title, &item.SearchTime, &item.ResultCreatedAt, &item.SubscriberId,
 			&item.SearchDefinitionId, &item.SearchDefinitionName, &item.Query, &item.SearchDefinitionComment, &item.ExactMatch, &item.MaxResults, &item.SortByDate,
 			&item.StartDate, &item.EndDate, &item.SearchType, &item.SearchEngineId, &item.SearchEngineName, &item.SearchEngineIdentifier, &item.SearchEngineComment,
 			&item.SearchDefinitionEngineID, &item.Published)
-
-		if err != nil {
-			fmt.Println(err.Error())
-			return &items, nil
-		}
-
-		fmt.Println("published", item.Published.Format(time.RFC3339))
-		items = append(items, item)
+		return item, err
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "SelectSearchResultView", "error", err)
+		return nil, fmt.Errorf("error listing search results: %w", err)
 	}
 
-	return &items, nil
+	return page, nil
 }
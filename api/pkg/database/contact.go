@@ -4,49 +4,51 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"log/slog"
 
 	"github.com/htstinson/stinsondataapi/api/internal/model"
 )
 
-func (d *Database) SelectContacts(ctx context.Context, customer model.Customer, limit, offset int) ([]model.Contact, error) {
-
-	fmt.Println("d SelectContacts")
+// ContactList is how the contacts of a customer may be listed.
+var ContactList = ListSpec{
+	Sorts:       map[string]string{"last_name": "lastname", "first_name": "firstname", "created_at": "created_at"},
+	Filters:     map[string]string{"last_name": "lastname", "first_name": "firstname", "email": "email", "phone": "phone", "job_title": "job_title", "department": "department"},
+	DefaultSort: "last_name",
+	Keys:        []string{"id"},
+}
 
-	query := fmt.Sprintf(`SELECT id, parent_id, lastname, firstname, 
-	email, phone, job_title, department, created_at FROM %s.contacts 
-	WHERE parent_id = '%s' ORDER BY lastname, firstname ASC LIMIT $1 OFFSET $2`, customer.Schema_Name, customer.Id)
+func (d *Database) SelectContacts(ctx context.Context, customer model.Customer, q ListQuery) (*Page[model.Contact], error) {
 
-	rows, err := d.DB.QueryContext(ctx,
-		query,
-		limit, offset,
-	)
-	if err != nil {
-		fmt.Println(err.Error())
-		return nil, fmt.Errorf("error listing contacts: %w", err)
-	}
-	defer rows.Close()
+	slog.DebugContext(ctx, "SelectContacts")
 
-	var contacts []model.Contact
-	for rows.Next() {
+	page, err := selectList(ctx, d.DB, list{
+		Spec:  ContactList,
+		Query: q,
+		Columns: []string{"id", "parent_id", "lastname", "firstname",
+			"email", "phone", "job_title", "department", "created_at"},
+		From:  fmt.Sprintf("%s.contacts", customer.Schema_Name),
+		Where: []string{"parent_id = $1"},
+		Args:  []any{customer.Id},
+	}, func(scan func(...any) error) (model.Contact, error) {
 		var contact model.Contact
-		if err := rows.Scan(&contact.Id, &contact.ParentId,
+		err := scan(&contact.Id, &contact.ParentId,
 			&contact.LastName, &contact.FirstName, &contact.Email,
 			&contact.Phone, &contact.JobTitle, &contact.Department,
-			&contact.CreatedAt); err != nil {
-			fmt.Println(err.Error())
-			return nil, fmt.Errorf("error scanning contact: %w", err)
-		}
+			&contact.CreatedAt)
 
 		contact.Schema_Name_ = customer.Schema_Name
 		contact.Subscriber_Id_ = customer.Subscriber_Id
-
-		contacts = append(contacts, contact)
+		return contact, err
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "SelectContacts", "error", err)
+		return nil, fmt.Errorf("error listing contacts: %w", err)
 	}
-	return contacts, nil
+	return page, nil
 }
 
 func (d *Database) CreateContact(ctx context.Context, contact *model.Contact) (*model.Contact, error) {
-	fmt.Println("d CreateContact")
+	slog.DebugContext(ctx, "CreateContact")
 
 	query := fmt.Sprintf(`INSERT INTO %s.contacts (parent_id, 
 	lastname, firstname, 
@@ -61,7 +63,7 @@ func (d *Database) CreateContact(ctx context.Context, contact *model.Contact) (*
 		contact.JobTitle,
 		contact.Department)
 	if err != nil {
-		fmt.Println(err.Error())
+		slog.ErrorContext(ctx, "CreateContact", "error", err)
 		return nil, fmt.Errorf("error creating customer: %w", err)
 	}
 
@@ -79,19 +81,17 @@ func (d *Database) DeleteContact(ctx context.Context, contact *model.Contact) er
 }
 
 func (d *Database) GetContact(ctx context.Context, c model.Contact) (*model.Contact, error) {
-	fmt.Println("d GetContact")
+	slog.DebugContext(ctx, "GetContact")
 
 	query := fmt.Sprintf(`SELECT parent_id, lastname, firstname, created_at FROM %s.contacts WHERE id = $1`, c.Schema_Name_)
 
-	fmt.Println(query)
-
 	contact := &model.Contact{
 		Id: c.Id,
 	}
 
 	err := d.DB.QueryRowContext(ctx, query, c.Id).Scan(&contact.ParentId, &contact.LastName, &contact.FirstName, &contact.CreatedAt)
 	if err != nil {
-		fmt.Println(err.Error())
+		slog.ErrorContext(ctx, "GetContact", "error", err)
 	}
 
 	if err == sql.ErrNoRows {
@@ -104,7 +104,7 @@ func (d *Database) GetContact(ctx context.Context, c model.Contact) (*model.Cont
 }
 
 func (d *Database) UpdateContact(ctx context.Context, contact *model.Contact) error {
-	fmt.Println("d UpdateContact")
+	slog.DebugContext(ctx, "UpdateContact")
 
 	query := fmt.Sprintf(`UPDATE %s.contacts 
 	SET lastname = $2, 
@@ -124,7 +124,7 @@ func (d *Database) UpdateContact(ctx context.Context, contact *model.Contact) er
 		contact.Department,
 	)
 	if err != nil {
-		fmt.Println(err.Error())
+		slog.ErrorContext(ctx, "UpdateContact", "error", err)
 	}
 
 	return err
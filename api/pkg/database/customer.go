@@ -4,63 +4,53 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"log/slog"
 
 	"github.com/htstinson/stinsondataapi/api/internal/model"
 )
 
-func (d *Database) SelectCustomers(ctx context.Context, subscriber model.Subscriber, limit int, offset int, sort string, order string) ([]model.Customer, int, error) {
-
-	fmt.Println("d SelectCustomers")
-
-	if order == "" {
-		order = "asc"
-	}
-
-	if sort == "" {
-		sort = "name"
-	}
+// CustomerList is how the customers of a subscriber may be listed.
+var CustomerList = ListSpec{
+	Sorts:       map[string]string{"name": "name", "created_at": "created_at"},
+	Filters:     map[string]string{"name": "name", "created_at": "created_at"},
+	DefaultSort: "name",
+	Keys:        []string{"id"},
+}
 
-	query := fmt.Sprintf("SELECT id, name, created_at, COUNT(*) OVER() AS total FROM %s.customers ORDER BY %s %s LIMIT $1 OFFSET $2", subscriber.Schema_Name, sort, order)
+func (d *Database) SelectCustomers(ctx context.Context, subscriber model.Subscriber, q ListQuery) (*Page[model.Customer], error) {
 
-	rows, err := d.DB.QueryContext(ctx,
-		query,
-		limit,
-		offset,
-	)
-	if err != nil {
-		fmt.Println(err.Error())
-		return nil, 0, fmt.Errorf("error listing customers: %w", err)
-	}
-	defer rows.Close()
+	slog.DebugContext(ctx, "SelectCustomers")
 
-	var total int
-	var customers []model.Customer
-	for rows.Next() {
+	page, err := selectList(ctx, d.DB, list{
+		Spec:    CustomerList,
+		Query:   q,
+		Columns: []string{"id", "name", "created_at"},
+		From:    fmt.Sprintf("%s.customers", subscriber.Schema_Name),
+	}, func(scan func(...any) error) (model.Customer, error) {
 		var customer model.Customer
-		if err := rows.Scan(&customer.Id, &customer.Name, &customer.CreatedAt, &total); err != nil {
-			fmt.Println(err.Error())
-			return nil, 0, fmt.Errorf("error scanning customer: %w", err)
-		}
+		err := scan(&customer.Id, &customer.Name, &customer.CreatedAt)
 
 		customer.Schema_Name = subscriber.Schema_Name
 		customer.Subscriber_Id = subscriber.Id
-
-		customers = append(customers, customer)
+		return customer, err
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "SelectCustomers", "error", err)
+		return nil, fmt.Errorf("error listing customers: %w", err)
 	}
-	return customers, total, nil
+	return page, nil
 }
 
 func (d *Database) CreateCustomer(ctx context.Context, customer *model.Customer, subscriber *model.Subscriber) (*model.Customer, error) {
-	fmt.Println("d CreateCustomer")
+	slog.DebugContext(ctx, "CreateCustomer")
 
 	query := fmt.Sprintf(`INSERT INTO %s.customers (id, name, parent_id) VALUES ($1, $2, $3)`, customer.Schema_Name)
-	fmt.Println(query)
-	fmt.Println("customer.Id", customer.Id)
-	fmt.Println("customer.Name", customer.Name)
-	fmt.Println("profile.Id", subscriber.Profile.Id)
-	fmt.Println("subscriber.Id", subscriber.Id)
-	fmt.Println("subscriber.Name", subscriber.Name)
-	fmt.Println("subscriber.Schema_Name", subscriber.Schema_Name)
+	slog.DebugContext(ctx, "CreateCustomer", "customer_id", customer.Id)
+	slog.DebugContext(ctx, "CreateCustomer", "customer_name", customer.Name)
+	slog.DebugContext(ctx, "CreateCustomer", "profile_id", subscriber.Profile.Id)
+	slog.DebugContext(ctx, "CreateCustomer", "subscriber_id", subscriber.Id)
+	slog.DebugContext(ctx, "CreateCustomer", "subscriber_name", subscriber.Name)
+	slog.DebugContext(ctx, "CreateCustomer", "subscriber_schema_name", subscriber.Schema_Name)
 
 	_, err := d.DB.ExecContext(ctx, query,
 		customer.Id,
@@ -69,7 +59,7 @@ func (d *Database) CreateCustomer(ctx context.Context, customer *model.Customer,
 	)
 
 	if err != nil {
-		fmt.Println(err.Error())
+		slog.ErrorContext(ctx, "CreateCustomer", "error", err)
 		return nil, fmt.Errorf("error creating customer: %w", err)
 	}
 
@@ -78,7 +68,7 @@ func (d *Database) CreateCustomer(ctx context.Context, customer *model.Customer,
 }
 
 func (d *Database) GetCustomer(ctx context.Context, temp_customer model.Customer) (*model.Customer, error) {
-	fmt.Println("d GetCustomer")
+	slog.DebugContext(ctx, "GetCustomer")
 
 	query := fmt.Sprintf(`SELECT name, created_at FROM %s.customers WHERE id = $1`, temp_customer.Schema_Name)
 
@@ -91,12 +81,12 @@ func (d *Database) GetCustomer(ctx context.Context, temp_customer model.Customer
 	err := d.DB.QueryRowContext(ctx, query, temp_customer.Id).Scan(&customer.Name, &customer.CreatedAt)
 
 	if err == sql.ErrNoRows {
-		fmt.Println(err.Error())
+		slog.ErrorContext(ctx, "GetCustomer", "error", err)
 		return nil, nil
 	}
 
 	if err != nil {
-		fmt.Println(err.Error())
+		slog.ErrorContext(ctx, "GetCustomer", "error", err)
 		return nil, fmt.Errorf("error getting customer: %w", err)
 	}
 
@@ -105,31 +95,26 @@ func (d *Database) GetCustomer(ctx context.Context, temp_customer model.Customer
 
 func (d *Database) DeleteCustomer(ctx context.Context, customer *model.Customer) error {
 
-	fmt.Println("d DeleteCustomer")
+	slog.DebugContext(ctx, "DeleteCustomer")
 
 	query := fmt.Sprintf(`DELETE FROM %s.customers WHERE id = $1`, customer.Schema_Name)
-	fmt.Println(query)
-	fmt.Println(customer)
 
 	_, err := d.DB.ExecContext(ctx, query, customer.Id)
 	if err != nil {
-		fmt.Println(err.Error())
+		slog.ErrorContext(ctx, "DeleteCustomer", "error", err)
 	}
 
 	return err
 }
 
 func (d *Database) UpdateCustomer(ctx context.Context, customer *model.Customer) error {
-	fmt.Println("d UpdateCustomer")
+	slog.DebugContext(ctx, "UpdateCustomer")
 
 	query := fmt.Sprintf(`UPDATE %s.customers SET name = $2 WHERE id = $1`, customer.Schema_Name)
 
-	fmt.Println(query)
-	fmt.Println(customer.Name)
-
 	_, err := d.DB.ExecContext(ctx, query, customer.Id, customer.Name)
 	if err != nil {
-		fmt.Println(err.Error())
+		slog.ErrorContext(ctx, "UpdateCustomer", "error", err)
 		return err
 	}
 
@@ -3,35 +3,37 @@ package database
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/htstinson/stinsondataapi/api/internal/model"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 type Repository interface {
 
 	//Calibrate
 
-	SelectCalibrateMention(ctx context.Context, subscriber model.Subscriber, search_result_id string) (*[]model.CalibrateMention, error)
+	SelectCalibrateMention(ctx context.Context, subscriber model.Subscriber, search_result_id string, q ListQuery) (*Page[model.CalibrateMention], error)
 
 	// Calibrate Search Results
 	CreateSearchResult(ctx context.Context, subscriber model.Subscriber, row model.CalibrateSearchResult) (*model.CalibrateSearchResult, error)
-	SelectSearchResultView(ctx context.Context, subscriber model.Subscriber, sde string) (*[]model.CalibrateSearchResultView, error)
+	SelectSearchResultView(ctx context.Context, subscriber model.Subscriber, sde string, q ListQuery) (*Page[model.CalibrateSearchResultView], error)
 
 	CreateSearchDefinitionEngine(ctx context.Context, subscriber model.Subscriber, row model.SearchDefinitionEngines) (*model.SearchDefinitionEngines, error)
-	SelectSearchDefinitionEnginesSubscriberView(ctx context.Context, subscriber model.Subscriber, limit, offset int) ([]model.SearchDefinitionEnginesView, error)
-	SelectSearchDefinitionEnginesView(ctx context.Context, search_definition model.SearchDefinition, limit, offset int) ([]model.SearchDefinitionEnginesView, error)
+	SelectSearchDefinitionEnginesSubscriberView(ctx context.Context, subscriber model.Subscriber, q ListQuery) (*Page[model.SearchDefinitionEnginesView], error)
+	SelectSearchDefinitionEnginesView(ctx context.Context, search_definition model.SearchDefinition, q ListQuery) (*Page[model.SearchDefinitionEnginesView], error)
 	GetSearchDefinitionEnginesView(ctx context.Context, subscriber model.Subscriber, search_definitions_engines_id string) (model.SearchDefinitionEnginesView, error)
 	DeleteSearchDefinitionEngine(ctx context.Context, subscriber *model.Subscriber, id string) error
 
-	SelectSearchDefinitions(ctx context.Context, customer model.Subscriber, limit, offset int) ([]model.SearchDefinition, error)
+	SelectSearchDefinitions(ctx context.Context, subscriber model.Subscriber, q ListQuery) (*Page[model.SearchDefinition], error)
 	CreateSearchDefinition(ctx context.Context, subscriber model.Subscriber, row model.SearchDefinition) (*model.SearchDefinition, error)
 	UpdateSearchDefinition(ctx context.Context, subscriber *model.Subscriber, row model.SearchDefinition) (*model.SearchDefinition, error)
 
-	SelectSearchEngines(ctx context.Context, subscriber model.Subscriber, limit, offset int) ([]model.SearchEngine, error)
+	SelectSearchEngines(ctx context.Context, subscriber model.Subscriber, q ListQuery) (*Page[model.SearchEngine], error)
 
 	GetSearchDefinition(ctx context.Context, subscriber model.Subscriber, definition_id string, limit int, offset int) (model.SearchDefinition, error)
 	DeleteSearchDefinition(ctx context.Context, subscriber *model.Subscriber, search_definition_id string) error
@@ -44,7 +46,7 @@ type Repository interface {
 	// Item
 	GetItem(ctx context.Context, id string) (*model.Item, error)
 	CreateItem(ctx context.Context, item *model.Item) error
-	SelectItems(ctx context.Context, limit, offset int) ([]model.Item, error)
+	SelectItems(ctx context.Context, q ListQuery) (*Page[model.Item], error)
 	UpdateItem(ctx context.Context, item *model.Item) error
 	DeleteItem(ctx context.Context, id string) error
 
@@ -52,13 +54,101 @@ type Repository interface {
 	GetUser(ctx context.Context, id string) (*model.User, error)
 	GetUserByUsername(ctx context.Context, username string) (*model.User, error)
 	CreateUser(ctx context.Context, username string, password string) (*model.User, error)
-	SelectUsers(ctx context.Context, limit int, offset int, sort string, order string) ([]model.User, int, error)
+	SelectUsers(ctx context.Context, q ListQuery) (*Page[model.User], error)
 	UpdateUser(ctx context.Context, item *model.User) error
 	DeleteUser(ctx context.Context, id string) error
+	RecordFailedLogin(ctx context.Context, id string) (int, error)
+	LockUser(ctx context.Context, id string, until time.Time) error
+	ResetFailedLogins(ctx context.Context, id string) error
+
+	// MFA
+	GetUserMFA(ctx context.Context, user_id string) (*model.UserMFA, error)
+	SaveUserMFA(ctx context.Context, user_id string, secret string) error
+	EnableUserMFA(ctx context.Context, user_id string, code_hashes []string) error
+	DeleteUserMFA(ctx context.Context, user_id string) error
+	UseMFAStep(ctx context.Context, user_id string, step int64) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, user_id string, code_hashes []string) error
+	UseRecoveryCode(ctx context.Context, user_id string, code_hash string) (bool, error)
+	MFARequired(ctx context.Context, user_id string) (bool, error)
+
+	// OIDC
+	GetSubscriberOIDC(ctx context.Context, subscriber_id string) (*model.SubscriberOIDC, error)
+	SaveSubscriberOIDC(ctx context.Context, cfg *model.SubscriberOIDC) error
+	DeleteSubscriberOIDC(ctx context.Context, subscriber_id string) error
+	CreateOIDCState(ctx context.Context, state model.OIDCState) error
+	UseOIDCState(ctx context.Context, state_hash string) (*model.OIDCState, error)
+	GetUserIdentity(ctx context.Context, issuer string, subject string) (*model.UserIdentity, error)
+	CreateUserIdentity(ctx context.Context, identity *model.UserIdentity) (*model.UserIdentity, error)
+	RecordIdentityLogin(ctx context.Context, id string, email string) error
+
+	// Invitations
+	CreateInvitation(ctx context.Context, invitation *model.Invitation, token_hash string) (*model.Invitation, error)
+	GetInvitation(ctx context.Context, id string) (*model.Invitation, error)
+	GetPendingInvitation(ctx context.Context, token_hash string) (*model.Invitation, error)
+	SelectInvitations(ctx context.Context, q ListQuery) (*Page[model.Invitation], error)
+	RevokeInvitation(ctx context.Context, id string) error
+	AcceptInvitation(ctx context.Context, token_hash string, user_id string, password_hash string) (*model.Invitation, error)
+
+	// Registration
+	RegisterUser(ctx context.Context, username, password string) (*model.User, error)
+	CreateEmailVerification(ctx context.Context, user_id string, token_hash string, expires_at time.Time) error
+	UseEmailVerification(ctx context.Context, token_hash string) (string, error)
+	DeleteUnverifiedUsers(ctx context.Context, before time.Time) ([]string, error)
+
+	// API keys and service accounts
+	CreateAPIKey(ctx context.Context, key *model.APIKey, key_hash string) (*model.APIKey, error)
+	GetAPIKey(ctx context.Context, id string) (*model.APIKey, error)
+	LookupAPIKey(ctx context.Context, key_hash string) (*model.APIKey, error)
+	SelectAPIKeys(ctx context.Context, user_id string, q ListQuery) (*Page[model.APIKey], error)
+	RevokeAPIKey(ctx context.Context, id string) error
+	RecordAPIKeyUse(ctx context.Context, id string, ip string) error
+	SelectSubscriberPermissions(ctx context.Context, user_id string, subscriber_id string) ([]string, error)
+	CreateServiceAccount(ctx context.Context, name string, subscriber_id string, role_id string) (*model.User, error)
+
+	// Email outbox, templates and unsubscribes
+	CreateEmail(ctx context.Context, email *model.Email) (*model.Email, error)
+	GetEmail(ctx context.Context, id string) (*model.Email, error)
+	SelectEmails(ctx context.Context, q ListQuery) (*Page[model.Email], error)
+	ClaimEmails(ctx context.Context, limit int, lease time.Duration) ([]model.Email, error)
+	MarkEmailSent(ctx context.Context, id string, provider_message_id string) error
+	MarkEmailFailed(ctx context.Context, id string, message string, retry_at *time.Time) error
+	GetEmailTemplate(ctx context.Context, subscriber_id string, name string) (*model.EmailTemplate, error)
+	SelectEmailTemplates(ctx context.Context, subscriber_id string) ([]model.EmailTemplate, error)
+	SaveEmailTemplate(ctx context.Context, template *model.EmailTemplate) (*model.EmailTemplate, error)
+	DeleteEmailTemplate(ctx context.Context, subscriber_id string, name string) error
+	Unsubscribe(ctx context.Context, email string, category string) error
+	IsUnsubscribed(ctx context.Context, email string, category string) (bool, error)
+
+	// Notification preferences
+	GetNotificationPreferences(ctx context.Context, user_id string, subscriber_id string) (*model.NotificationPreferences, error)
+	SelectNotificationPreferences(ctx context.Context, user_id string) ([]model.NotificationPreferences, error)
+	SaveNotificationPreferences(ctx context.Context, preferences *model.NotificationPreferences) (*model.NotificationPreferences, error)
+	DeleteNotificationPreferences(ctx context.Context, user_id string, subscriber_id string) error
+	SelectDigestRecipients(ctx context.Context) ([]model.DigestRecipient, error)
+	ClaimNotificationDigest(ctx context.Context, user_id string, subscriber_id string, channel string, due time.Time, now time.Time) (bool, *time.Time, error)
+	SelectDigestMentions(ctx context.Context, subscriber model.Subscriber, since time.Time, limit int) ([]model.DigestMention, int, error)
+	SelectSubscriberUsers(ctx context.Context, subscriber_id string) ([]model.User, error)
+
+	// Mailbox sources
+	CreateMailboxSource(ctx context.Context, source *model.MailboxSource) (*model.MailboxSource, error)
+	GetMailboxSource(ctx context.Context, id string) (*model.MailboxSource, error)
+	SelectMailboxSources(ctx context.Context, subscriber_id string) ([]model.MailboxSource, error)
+	SelectActiveMailboxSources(ctx context.Context) ([]model.MailboxSource, error)
+	UpdateMailboxSource(ctx context.Context, source *model.MailboxSource) (*model.MailboxSource, error)
+	DeleteMailboxSource(ctx context.Context, id string) error
+	RecordMailboxPoll(ctx context.Context, id string, message string) error
+	SelectProcessedMailboxMessages(ctx context.Context, source_id string, message_ids []string) ([]string, error)
+	SaveMailboxMessage(ctx context.Context, subscriber model.Subscriber, source_id string, message_id string, results []model.CalibrateSearchResult) ([]model.CalibrateSearchResult, error)
+
+	// Password resets
+	CreatePasswordReset(ctx context.Context, user_id string, token_hash string, expires_at time.Time) error
+	GetPasswordReset(ctx context.Context, token_hash string) (string, error)
+	UsePasswordReset(ctx context.Context, token_hash string) (string, error)
 
 	// User_Subscriber
-	SelectUserSubscriberView(ctx context.Context, user_id string, limit, offset int) ([]model.User_Subscriber_View, error)
+	SelectUserSubscriberView(ctx context.Context, user_id string, q ListQuery) (*Page[model.User_Subscriber_View], error)
 	LookupUserSubscribersByUserId(ctx context.Context, user_id string) ([]model.User_Subscriber_View, error)
+	SelectUserTenants(ctx context.Context, user_id string) ([]model.Tenant, error)
 	UpdateUserSubscriber(ctx context.Context, user_subscriber model.User_Subscriber) error
 	GetUserSubscriber(ctx context.Context, id string) (*model.User_Subscriber, error)
 	CreateUserSubscriber(ctx context.Context, user_id string, subscriber_id string) (*model.User_Subscriber, error)
@@ -66,7 +156,7 @@ type Repository interface {
 	DeleteUserSubscriber(ctx context.Context, id string) error
 
 	// User_Subscriber_Role
-	SelectUserSubscriberRoleView(ctx context.Context, user_customer_view model.User_Subscriber_View, limit, offset int) ([]model.User_Subscriber_Role_View, error)
+	SelectUserSubscriberRoleView(ctx context.Context, user_subscriber_view model.User_Subscriber_View, q ListQuery) (*Page[model.User_Subscriber_Role_View], error)
 	CreateUserSubscriberRole(ctx context.Context, user_subscriber_id string, role_id string) (*model.User_Subscriber_Role, error)
 	LookupUserSubscriberRole(ctx context.Context, user_subscriber_id string, role_id string) (*model.User_Subscriber_Role, error)
 	UpdateUserSubscriberRole(ctx context.Context, user_subscriber_role model.User_Subscriber_Role) error
@@ -74,7 +164,7 @@ type Repository interface {
 	DeleteUserSubscriberRole(ctx context.Context, id string) error
 
 	// Customer
-	SelectCustomers(ctx context.Context, subscriber model.Subscriber, limit int, offset int, sort string, order string) ([]model.Customer, int, error)
+	SelectCustomers(ctx context.Context, subscriber model.Subscriber, q ListQuery) (*Page[model.Customer], error)
 	CreateCustomer(ctx context.Context, customer *model.Customer, subscriber *model.Subscriber) (*model.Customer, error)
 	GetCustomer(ctx context.Context, customer model.Customer) (*model.Customer, error)
 	DeleteCustomer(ctx context.Context, customer *model.Customer) error
@@ -84,41 +174,49 @@ type Repository interface {
 	GetSubscriber(ctx context.Context, id string) (*model.Subscriber, error)
 	GetSubscriberByName(ctx context.Context, name string) (*model.Subscriber, error)
 	CreateSubscriber(ctx context.Context, subscriber *model.Subscriber) (*model.Subscriber, error)
-	SelectSubscribers(ctx context.Context, limit, offset int) ([]model.Subscriber, error)
+	SelectSubscribers(ctx context.Context, q ListQuery) (*Page[model.Subscriber], error)
 	UpdateSubscriber(ctx context.Context, subscriber *model.Subscriber) error
 	DeleteSubscriber(ctx context.Context, subscriber *model.Subscriber) error
 
-	SelectUserRoles(ctx context.Context, limit, offset int) ([]model.User, error)
+	SelectUserRoles(ctx context.Context, q ListQuery) (*Page[model.User], error)
 
-	SelectSubscriberAddresses(ctx context.Context, subscriber model.Subscriber, limit int, offset int, sort string, order string) (*[]model.Address, int, error)
+	SelectSubscriberAddresses(ctx context.Context, subscriber model.Subscriber, q ListQuery) (*Page[model.Address], error)
 	UpdateSubscriberAddress(ctx context.Context, subscriber *model.Subscriber, address model.Address) error
 	CreateSubscriberAddress(ctx context.Context, subscriber *model.Subscriber, address model.Address) error
 	GetSubscriberAddress(ctx context.Context, subscriber_schema_name string, address_id string) (*model.Address, error)
 	DeleteSubscriberAddress(ctx context.Context, subscriber_schema_name string, address_id string) error
 
-	SelectSubscriberBackgrounds(ctx context.Context, subscriber model.Subscriber, limit int, offset int, sort string, order string) (*[]model.Background, int, error)
+	SelectSubscriberBackgrounds(ctx context.Context, subscriber model.Subscriber, q ListQuery) (*Page[model.Background], error)
 	UpdateSubscriberBackground(ctx context.Context, subscriber *model.Subscriber, background model.Background) error
 	CreateSubscriberBackground(ctx context.Context, subscriber *model.Subscriber, background model.Background) error
 	GetSubscriberBackground(ctx context.Context, subscriber_schema_name string, background_id string) (*model.Background, error)
 	DeleteSubscriberBackground(ctx context.Context, subscriber_schema_name string, background_id string) error
 
 	//Subsriber_Item
-	SelectSubscriberItemView(ctx context.Context, subscriber_id string, limit int, offset int) ([]model.Subscriber_Item_View, error)
+	SelectSubscriberItemView(ctx context.Context, subscriber_id string, q ListQuery) (*Page[model.Subscriber_Item_View], error)
 	CreateSubscriberItem(ctx context.Context, item_id string, subscriber_id string) (*model.Subscriber_Item, error)
 	LookupSubscriberItem(ctx context.Context, item_id string, subscriber_id string) (*model.Subscriber_Item, error)
 	DeleteSubscriberItem(ctx context.Context, id string) error
 	GetSubscriberItem(ctx context.Context, id string) (*model.Subscriber_Item, error)
 
 	// Blocked
-	SelectBlocked(ctx context.Context, limit, offset int, sort string, order string) ([]model.Blocked, error)
+	SelectBlocked(ctx context.Context, q ListQuery) (*Page[model.Blocked], error)
 	GetBlocked(ctx context.Context, id string) (*model.Blocked, error)
 	UpdateBlocked(ctx context.Context, item *model.Blocked) error
 	CreateBlocked(ctx context.Context, blocked model.Blocked) (*model.Blocked, error)
 	DeleteBlocked(ctx context.Context, id string) error
 
+	// Allowed
+	SelectAllowed(ctx context.Context, q ListQuery) (*Page[model.Allowed], error)
+	GetAllowed(ctx context.Context, id string) (*model.Allowed, error)
+	UpdateAllowed(ctx context.Context, allowed *model.Allowed) error
+	CreateAllowed(ctx context.Context, allowed model.Allowed) (*model.Allowed, error)
+	DeleteAllowed(ctx context.Context, id string) error
+
 	// Roles
 	SelectRolesByUser(ctx context.Context, userID string) (model.Roles, error)
-	SelectRoles(ctx context.Context, limit, offset int) ([]model.Role, error)
+
+	SelectRoles(ctx context.Context, q ListQuery) (*Page[model.Role], error)
 	GetRole(ctx context.Context, id string) (*model.Role, error)
 	UpdateRole(ctx context.Context, role *model.Role) error
 	CreateRole(ctx context.Context, name string) (*model.Role, error)
@@ -127,34 +225,41 @@ type Repository interface {
 	// Permission
 	GetPermission(ctx context.Context, id string) (*model.Permission, error)
 	CreatePermission(ctx context.Context, name string, description string, object_id string) (*model.Permission, error)
-	SelectPermissions(ctx context.Context, limit, offset int) ([]model.Permission, error)
-	SelectPermissions_View(ctx context.Context, limit, offset int) ([]model.Permission_View, error)
+	SelectPermissions(ctx context.Context, q ListQuery) (*Page[model.Permission], error)
+	SelectPermissions_View(ctx context.Context, q ListQuery) (*Page[model.Permission_View], error)
 	UpdatePermission(ctx context.Context, permission *model.Permission) error
 	DeletePermission(ctx context.Context, id string) error
 
 	// User Permissions
-	SelectUserPermissions(ctx context.Context, limit, offset int) ([]model.User_Permission, error)
+	SelectUserPermissions(ctx context.Context, q ListQuery) (*Page[model.User_Permission], error)
 
 	//Role Permissions
-	SelectRolePermissionsView(ctx context.Context, limit, offset int) ([]model.Role_Permission_View, error)
+	SelectRolePermissionsView(ctx context.Context, q ListQuery) (*Page[model.Role_Permission_View], error)
 
 	// Profiles
 	//GetProfile(ctx context.Context, id string) (*model.Profile, error)
 	GetProfile(ctx context.Context, subscriber *model.Subscriber) (*model.Profile, error)
 	CreateProfile(ctx context.Context, subscriber model.Subscriber, profile model.Profile) (*model.Profile, error)
-	SelectProfiles(ctx context.Context, limit, offset int) ([]model.Profile, error)
+	SelectProfiles(ctx context.Context, q ListQuery) (*Page[model.Profile], error)
 	UpdateProfile(ctx context.Context, subscriber *model.Subscriber, profile *model.Profile) error
 	DeleteProfile(ctx context.Context, id string) error
 
 	// Contacts
-	SelectContacts(ctx context.Context, customer model.Customer, limit, offset int) ([]model.Contact, error)
+	SelectContacts(ctx context.Context, customer model.Customer, q ListQuery) (*Page[model.Contact], error)
 	CreateContact(ctx context.Context, contact *model.Contact) (*model.Contact, error)
 	DeleteContact(ctx context.Context, contact *model.Contact) error
 	GetContact(ctx context.Context, contact model.Contact) (*model.Contact, error)
 	UpdateContact(ctx context.Context, contact *model.Contact) error
 
+	// Audit
+	CreateAuditEvent(ctx context.Context, event model.AuditEvent) error
+	SelectAuditEvents(ctx context.Context, q ListQuery) (*Page[model.AuditEvent], error)
+
 	RowCount(tablename string) (int, error)
 
+	Ping(ctx context.Context) error
+	PendingMigrations(ctx context.Context) (int, error)
+	Stats() sql.DBStats
 	Close() error
 }
 
@@ -171,18 +276,48 @@ type Config struct {
 	DBName      string
 	Search_Path string
 	SSLMode     string
+
+	// Credentials, when set, is called for every new connection and replaces
+	// Host, Port, User and Password, so that rotated passwords are used as
+	// soon as pooled connections are recycled.
+	Credentials func(ctx context.Context) (Config, error)
 }
 
-func New(cfg Config) (Repository, error) {
-	connStr := fmt.Sprintf(
+func (cfg Config) connString() string {
+	return fmt.Sprintf(
 		"host=%s port=%d user=%s password=%s dbname=%s search_path=%s sslmode=%s\n",
 		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName, cfg.Search_Path, cfg.SSLMode,
 	)
+}
+
+// connector opens each connection with the current credentials.
+type connector struct {
+	cfg Config
+}
 
-	db, err := sql.Open("postgres", connStr)
+func (c connector) Connect(ctx context.Context) (driver.Conn, error) {
+	cfg := c.cfg
+	if cfg.Credentials != nil {
+		creds, err := cfg.Credentials(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error reading database credentials: %w", err)
+		}
+		cfg.Host, cfg.Port, cfg.User, cfg.Password = creds.Host, creds.Port, creds.User, creds.Password
+	}
+
+	pqConnector, err := pq.NewConnector(cfg.connString())
 	if err != nil {
-		return nil, fmt.Errorf("error opening database: %w", err)
+		return nil, err
 	}
+	return pqConnector.Connect(ctx)
+}
+
+func (c connector) Driver() driver.Driver {
+	return &pq.Driver{}
+}
+
+func New(cfg Config) (Repository, error) {
+	db := sql.OpenDB(connector{cfg: cfg})
 
 	// Configure connection pool
 	db.SetMaxOpenConns(25)
@@ -202,40 +337,65 @@ func New(cfg Config) (Repository, error) {
 	return &Database{DB: db, Config: cfg}, nil
 }
 
-func initializeSchema(db *sql.DB) error {
+// SQLDB returns the *sql.DB behind repo, looking through wrappers such as
+// the ones added by WithTracing and WithAudit.
+func SQLDB(repo Repository) (*sql.DB, error) {
+	for {
+		switch r := repo.(type) {
+		case *Database:
+			return r.DB, nil
+		case *tracedRepository:
+			repo = r.Repository
+		case *auditedRepository:
+			repo = r.Repository
+		default:
+			return nil, fmt.Errorf("no database behind %T", repo)
+		}
+	}
+}
 
-	// Create Users table
-	queries := []string{
-		`CREATE TABLE IF NOT EXISTS blocked (
+// migrations are applied in order at startup, each once: schema_version
+// holds how many have been applied, and only the ones after it are run.
+// Append new ones to the end and never reorder or remove them. Statements
+// stay idempotent for databases created before the version was recorded
+// per migration.
+var migrations = []string{
+	`CREATE TABLE IF NOT EXISTS blocked (
             id VARCHAR(36) PRIMARY KEY,
             ip VARCHAR(20) UNIQUE NOT NULL,
             notes VARCHAR(255),
             created_at TIMESTAMP WITH TIME ZONE NOT NULL
         )`,
-		`ALTER TABLE blocked ALTER COLUMN id SET DEFAULT gen_random_uuid();`,
-		`ALTER TABLE blocked ALTER COLUMN created_at SET DEFAULT CURRENT_TIMESTAMP;`,
-		`CREATE INDEX IF NOT EXISTS blocked_ip_idx ON blocked(ip)`,
-		`CREATE TABLE IF NOT EXISTS users (
+	`ALTER TABLE blocked ALTER COLUMN id SET DEFAULT gen_random_uuid();`,
+	`ALTER TABLE blocked ALTER COLUMN created_at SET DEFAULT CURRENT_TIMESTAMP;`,
+	`CREATE INDEX IF NOT EXISTS blocked_ip_idx ON blocked(ip)`,
+	`CREATE TABLE IF NOT EXISTS allowed (
+            id VARCHAR(36) PRIMARY KEY DEFAULT gen_random_uuid(),
+            ip VARCHAR(43) UNIQUE NOT NULL,
+            notes VARCHAR(255),
+            created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+        )`,
+	`CREATE TABLE IF NOT EXISTS users (
             id VARCHAR(36) PRIMARY KEY,
             username VARCHAR(255) UNIQUE NOT NULL,
             password_hash VARCHAR(255) NOT NULL,
             created_at TIMESTAMP WITH TIME ZONE NOT NULL
         )`,
-		`CREATE INDEX IF NOT EXISTS users_username_idx ON users(username)`,
-		`CREATE TABLE IF NOT EXISTS people (
+	`CREATE INDEX IF NOT EXISTS users_username_idx ON users(username)`,
+	`CREATE TABLE IF NOT EXISTS people (
             id VARCHAR(36) PRIMARY KEY,
             firstname VARCHAR(255) UNIQUE NOT NULL,
             lastname VARCHAR(255) NOT NULL,
             created_at TIMESTAMP WITH TIME ZONE NOT NULL
         )`,
-		`CREATE INDEX IF NOT EXISTS users_username_idx ON users(username)`,
-		`CREATE TABLE IF NOT EXISTS items (
+	`CREATE INDEX IF NOT EXISTS users_username_idx ON users(username)`,
+	`CREATE TABLE IF NOT EXISTS items (
             id VARCHAR(36) PRIMARY KEY,
             name VARCHAR(255) NOT NULL,
             created_at TIMESTAMP WITH TIME ZONE NOT NULL
         );
         CREATE INDEX IF NOT EXISTS items_created_at_idx ON items(created_at DESC);`,
-		`CREATE TABLE IF NOT EXISTS accounts (
+	`CREATE TABLE IF NOT EXISTS accounts (
             id VARCHAR(36) PRIMARY KEY,
             name VARCHAR(255) NOT NULL,
 			description VARCHAR(255) NOT NULL,
@@ -243,41 +403,308 @@ func initializeSchema(db *sql.DB) error {
             created_at TIMESTAMP WITH TIME ZONE NOT NULL
         );
         CREATE INDEX IF NOT EXISTS accounts_created_at_idx ON items(created_at DESC);`,
+	`CREATE TABLE IF NOT EXISTS audit_events (
+            id VARCHAR(36) PRIMARY KEY DEFAULT gen_random_uuid(),
+            occurred_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            action VARCHAR(20) NOT NULL,
+            actor_id VARCHAR(36),
+            actor_name VARCHAR(255),
+            subscriber_id VARCHAR(36),
+            entity_type VARCHAR(50) NOT NULL,
+            entity_id VARCHAR(255),
+            changes JSONB,
+            request_id VARCHAR(100),
+            ip_address VARCHAR(45)
+        );
+        CREATE INDEX IF NOT EXISTS audit_events_occurred_at_idx ON audit_events(occurred_at DESC);
+        CREATE INDEX IF NOT EXISTS audit_events_entity_idx ON audit_events(entity_type, entity_id);
+        CREATE INDEX IF NOT EXISTS audit_events_actor_idx ON audit_events(actor_id);
+        CREATE INDEX IF NOT EXISTS audit_events_subscriber_idx ON audit_events(subscriber_id);`,
+	// The audit log is append-only: rows can be inserted but never changed or removed.
+	`CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+        BEGIN
+            RAISE EXCEPTION 'audit_events is append-only';
+        END;
+        $$ LANGUAGE plpgsql;
+        DROP TRIGGER IF EXISTS audit_events_append_only ON audit_events;
+        CREATE TRIGGER audit_events_append_only BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_events
+            FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only();`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS failed_logins INT NOT NULL DEFAULT 0;
+        ALTER TABLE users ADD COLUMN IF NOT EXISTS last_failed_login TIMESTAMP WITH TIME ZONE;
+        ALTER TABLE users ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP WITH TIME ZONE;`,
+	`CREATE TABLE IF NOT EXISTS password_resets (
+            token_hash VARCHAR(64) PRIMARY KEY,
+            user_id VARCHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+            created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+            used_at TIMESTAMP WITH TIME ZONE
+        );
+        CREATE INDEX IF NOT EXISTS password_resets_user_idx ON password_resets(user_id);`,
+	`CREATE TABLE IF NOT EXISTS user_mfa (
+            user_id VARCHAR(36) PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+            secret VARCHAR(255) NOT NULL,
+            enabled BOOLEAN NOT NULL DEFAULT false,
+            created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            enabled_at TIMESTAMP WITH TIME ZONE,
+            last_used_step BIGINT
+        )`,
+	`CREATE TABLE IF NOT EXISTS user_mfa_recovery_codes (
+            user_id VARCHAR(36) NOT NULL REFERENCES user_mfa(user_id) ON DELETE CASCADE,
+            code_hash VARCHAR(64) NOT NULL,
+            used_at TIMESTAMP WITH TIME ZONE,
+            PRIMARY KEY (user_id, code_hash)
+        )`,
+	`ALTER TABLE subscribers ADD COLUMN IF NOT EXISTS mfa_required BOOLEAN NOT NULL DEFAULT false`,
+	`CREATE TABLE IF NOT EXISTS subscriber_oidc (
+            subscriber_id VARCHAR(36) PRIMARY KEY,
+            issuer VARCHAR(255) NOT NULL,
+            client_id VARCHAR(255) NOT NULL,
+            client_secret_name VARCHAR(255) NOT NULL DEFAULT '',
+            allowed_domains TEXT[] NOT NULL DEFAULT '{}',
+            jit_provisioning BOOLEAN NOT NULL DEFAULT false,
+            default_role_id VARCHAR(36),
+            enabled BOOLEAN NOT NULL DEFAULT true,
+            created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+        )`,
+	`CREATE TABLE IF NOT EXISTS oidc_states (
+            state_hash VARCHAR(64) PRIMARY KEY,
+            subscriber_id VARCHAR(36) NOT NULL,
+            code_verifier VARCHAR(128) NOT NULL,
+            nonce VARCHAR(64) NOT NULL,
+            expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+        )`,
+	`CREATE TABLE IF NOT EXISTS user_identities (
+            id VARCHAR(36) PRIMARY KEY,
+            user_id VARCHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+            subscriber_id VARCHAR(36) NOT NULL,
+            issuer VARCHAR(255) NOT NULL,
+            subject VARCHAR(255) NOT NULL,
+            email VARCHAR(255) NOT NULL DEFAULT '',
+            created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            last_login_at TIMESTAMP WITH TIME ZONE,
+            UNIQUE (issuer, subject)
+        );
+        CREATE INDEX IF NOT EXISTS user_identities_user_idx ON user_identities(user_id);`,
+	`CREATE TABLE IF NOT EXISTS invitations (
+            id VARCHAR(36) PRIMARY KEY,
+            token_hash VARCHAR(64) UNIQUE NOT NULL,
+            email VARCHAR(255) NOT NULL,
+            subscriber_id VARCHAR(36) NOT NULL,
+            role_id VARCHAR(36) NOT NULL,
+            invited_by VARCHAR(36),
+            user_id VARCHAR(36) REFERENCES users(id) ON DELETE SET NULL,
+            created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+            accepted_at TIMESTAMP WITH TIME ZONE,
+            revoked_at TIMESTAMP WITH TIME ZONE
+        );
+        CREATE INDEX IF NOT EXISTS invitations_subscriber_idx ON invitations(subscriber_id, email);`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP;
+        ALTER TABLE users ALTER COLUMN email_verified_at DROP DEFAULT;
+        CREATE INDEX IF NOT EXISTS users_username_lower_idx ON users(lower(username));`,
+	`CREATE TABLE IF NOT EXISTS email_verifications (
+            token_hash VARCHAR(64) PRIMARY KEY,
+            user_id VARCHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+            created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+        );
+        CREATE INDEX IF NOT EXISTS email_verifications_user_idx ON email_verifications(user_id);`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS service_account BOOLEAN NOT NULL DEFAULT false`,
+	`CREATE TABLE IF NOT EXISTS api_keys (
+            id VARCHAR(36) PRIMARY KEY,
+            key_hash VARCHAR(64) NOT NULL UNIQUE,
+            user_id VARCHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+            subscriber_id VARCHAR(36) NOT NULL,
+            name VARCHAR(100) NOT NULL,
+            prefix VARCHAR(16) NOT NULL,
+            permissions TEXT[] NOT NULL DEFAULT '{}',
+            created_by VARCHAR(36),
+            created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+            last_used_at TIMESTAMP WITH TIME ZONE,
+            last_used_ip VARCHAR(45),
+            revoked_at TIMESTAMP WITH TIME ZONE
+        );
+        CREATE INDEX IF NOT EXISTS api_keys_user_idx ON api_keys(user_id);`,
+	`CREATE TABLE IF NOT EXISTS email_outbox (
+            id VARCHAR(36) PRIMARY KEY,
+            subscriber_id VARCHAR(36),
+            template VARCHAR(100) NOT NULL,
+            category VARCHAR(50) NOT NULL,
+            to_address VARCHAR(255) NOT NULL,
+            subject VARCHAR(255) NOT NULL,
+            text_body TEXT NOT NULL,
+            html_body TEXT,
+            unsubscribe_url TEXT,
+            status VARCHAR(20) NOT NULL DEFAULT 'pending',
+            attempts INTEGER NOT NULL DEFAULT 0,
+            next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            last_error TEXT,
+            provider_message_id VARCHAR(255),
+            created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            sent_at TIMESTAMP WITH TIME ZONE
+        );
+        CREATE INDEX IF NOT EXISTS email_outbox_due_idx ON email_outbox(next_attempt_at) WHERE status = 'pending';`,
+	`CREATE TABLE IF NOT EXISTS email_templates (
+            subscriber_id VARCHAR(36) NOT NULL,
+            name VARCHAR(100) NOT NULL,
+            subject VARCHAR(255) NOT NULL,
+            text_body TEXT NOT NULL,
+            html_body TEXT NOT NULL DEFAULT '',
+            updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            PRIMARY KEY (subscriber_id, name)
+        )`,
+	`CREATE TABLE IF NOT EXISTS email_unsubscribes (
+            email VARCHAR(255) NOT NULL,
+            category VARCHAR(50) NOT NULL,
+            created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            PRIMARY KEY (email, category)
+        )`,
+	`CREATE TABLE IF NOT EXISTS notification_preferences (
+            user_id VARCHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+            subscriber_id VARCHAR(36) NOT NULL DEFAULT '',
+            login_email BOOLEAN NOT NULL DEFAULT false,
+            login_webhook BOOLEAN NOT NULL DEFAULT false,
+            mention_email BOOLEAN NOT NULL DEFAULT false,
+            mention_webhook BOOLEAN NOT NULL DEFAULT false,
+            security_email BOOLEAN NOT NULL DEFAULT true,
+            security_webhook BOOLEAN NOT NULL DEFAULT false,
+            digest_email VARCHAR(10) NOT NULL DEFAULT 'off',
+            digest_webhook VARCHAR(10) NOT NULL DEFAULT 'off',
+            webhook_url TEXT,
+            webhook_secret VARCHAR(200),
+            updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            PRIMARY KEY (user_id, subscriber_id)
+        )`,
+	`CREATE TABLE IF NOT EXISTS mailbox_sources (
+            id VARCHAR(36) PRIMARY KEY,
+            subscriber_id VARCHAR(36) NOT NULL,
+            label VARCHAR(225) NOT NULL,
+            search_definition_engine_id VARCHAR(36) NOT NULL,
+            paused BOOLEAN NOT NULL DEFAULT false,
+            last_polled_at TIMESTAMP WITH TIME ZONE,
+            last_error TEXT,
+            created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+        );
+        CREATE INDEX IF NOT EXISTS mailbox_sources_subscriber_idx ON mailbox_sources(subscriber_id)`,
+	`CREATE TABLE IF NOT EXISTS mailbox_messages (
+            source_id VARCHAR(36) NOT NULL REFERENCES mailbox_sources(id) ON DELETE CASCADE,
+            message_id VARCHAR(64) NOT NULL,
+            results INTEGER NOT NULL DEFAULT 0,
+            processed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            PRIMARY KEY (source_id, message_id)
+        )`,
+	// Request IDs come from clients, so they are not limited in the schema
+	`ALTER TABLE audit_events ALTER COLUMN request_id TYPE TEXT`,
+	`CREATE TABLE IF NOT EXISTS notification_digests (
+            user_id VARCHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+            subscriber_id VARCHAR(36) NOT NULL,
+            channel VARCHAR(10) NOT NULL,
+            sent_at TIMESTAMP WITH TIME ZONE NOT NULL,
+            PRIMARY KEY (user_id, subscriber_id, channel)
+        )`,
+	// Wide enough for IPv6 ranges, as allowed is
+	`ALTER TABLE blocked ALTER COLUMN ip TYPE VARCHAR(43)`,
+}
+
+func initializeSchema(db *sql.DB) error {
+	query := `CREATE TABLE IF NOT EXISTS schema_version (
+            id INT PRIMARY KEY DEFAULT 1,
+            version INT NOT NULL,
+            applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+        )`
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("error creating schema version: %w", err)
+	}
+
+	var version int
+	err := db.QueryRow(`SELECT version FROM schema_version WHERE id = 1`).Scan(&version)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("error reading schema version: %w", err)
 	}
 
-	for _, query := range queries {
-		if _, err := db.Exec(query); err != nil {
-			return fmt.Errorf("error creating schema: %w", err)
+	// Each migration is recorded together with its statement, so that the
+	// version only counts the ones that were applied
+	for i := version; i < len(migrations); i++ {
+		if err := applyMigration(db, i); err != nil {
+			return fmt.Errorf("error applying migration %d: %w", i+1, err)
 		}
 	}
 	return nil
 }
 
+func applyMigration(db *sql.DB, i int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(migrations[i]); err != nil {
+		return err
+	}
+	query := `
+        INSERT INTO schema_version (id, version) VALUES (1, $1)
+        ON CONFLICT (id) DO UPDATE SET version = GREATEST(schema_version.version, EXCLUDED.version), applied_at = CURRENT_TIMESTAMP
+    `
+	if _, err := tx.Exec(query, i+1); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Ping checks that the database can be reached.
+func (d *Database) Ping(ctx context.Context) error {
+	return d.DB.PingContext(ctx)
+}
+
+// PendingMigrations reports how many migrations this build has that the
+// database has not recorded as applied.
+func (d *Database) PendingMigrations(ctx context.Context) (int, error) {
+	var version int
+	err := d.DB.QueryRowContext(ctx, `SELECT version FROM schema_version WHERE id = 1`).Scan(&version)
+	if err == sql.ErrNoRows {
+		return len(migrations), nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error reading schema version: %w", err)
+	}
+	if version >= len(migrations) {
+		return 0, nil
+	}
+	return len(migrations) - version, nil
+}
+
 func (d *Database) Close() error {
 	return d.DB.Close()
 }
 
+// Stats reports the connection pool statistics.
+func (d *Database) Stats() sql.DBStats {
+	return d.DB.Stats()
+}
+
 // any table
 func (d *Database) RowCount(tablename string) (int, error) {
-	fmt.Println("d RowCount")
+	slog.Debug("RowCount")
 
 	var count int
 
 	q := fmt.Sprintf("SELECT COUNT(*) FROM %s", tablename)
-	fmt.Println(q)
 
 	ctx := context.Background()
 
 	rows, err := d.DB.QueryContext(ctx, q)
 	if err != nil {
-		fmt.Println(err.Error())
+		slog.ErrorContext(ctx, "RowCount", "error", err)
 	}
 	defer rows.Close()
 
 	if rows.Next() {
 		err = rows.Scan(&count)
 		if err != nil {
-			fmt.Println(err.Error())
+			slog.ErrorContext(ctx, "RowCount", "error", err)
 		}
 	}
 
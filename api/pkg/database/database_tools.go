@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"log/slog"
 	"os"
 	"os/exec"
 	"regexp"
@@ -20,36 +21,36 @@ func Copy_Schema(db *sql.DB, config Config, useIAM bool, NewSchema string) error
 	dumpFile := "schema_dump.sql"
 	err := dumpSchemaStructure(config, dumpFile, useIAM)
 	if err != nil {
-		fmt.Printf("Failed to dump schema structure: %v", err)
+		slog.Error("Failed to dump schema structure", "error", err)
 		return err
 	}
 
 	// Step 2: Create new schema and modify the dump file
-	fmt.Println("Creating and modifying schema dump...")
+	slog.Debug("Creating and modifying schema dump...")
 	modifiedDump := "modified_schema.sql"
 	err = createAndModifyDump(dumpFile, modifiedDump, NewSchema)
 	if err != nil {
-		fmt.Printf("Failed to create and modify dump: %v", err)
+		slog.Error("Failed to create and modify dump", "error", err)
 		return err
 	}
 
 	// Step 3: Apply the modified structure
-	fmt.Println("Applying modified schema structure...")
+	slog.Debug("Applying modified schema structure...")
 	err = applyModifiedStructure(config, modifiedDump, useIAM)
 	if err != nil {
-		fmt.Printf("Failed to apply modified structure: %v", err)
+		slog.Error("Failed to apply modified structure", "error", err)
 		return err
 	}
 
 	// Step 4: Copy data from public to new schema
-	fmt.Println("Copying data to new schema...")
+	slog.Debug("Copying data to new schema...")
 	err = copyData(db, NewSchema)
 	if err != nil {
-		fmt.Printf("Failed to copy data: %v", err)
+		slog.Error("Failed to copy data", "error", err)
 		return err
 	}
 
-	fmt.Println("Schema copying completed successfully!")
+	slog.Debug("Schema copying completed successfully!")
 
 	return err
 }
@@ -79,7 +80,7 @@ func getIAMToken(config Config) (string, error) {
 
 // Dump the structure of public schema using pg_dump
 func dumpSchemaStructure(config Config, dumpFile string, useIAM bool) error {
-	fmt.Println("Dumping public schema structure...")
+	slog.Debug("Dumping public schema structure...")
 
 	var cmd *exec.Cmd
 
@@ -192,7 +193,7 @@ func copyData(db *sql.DB, newSchema string) error {
 			return fmt.Errorf("failed to scan table name: %w", err)
 		}
 
-		fmt.Printf("Copying data for table: %s\n", tableName)
+		slog.Info("Copying data for table", "table", tableName)
 
 		// Copy data using INSERT INTO ... SELECT
 		query := fmt.Sprintf("INSERT INTO %s.%s SELECT * FROM public.%s",
@@ -201,7 +202,7 @@ func copyData(db *sql.DB, newSchema string) error {
 		_, err := db.Exec(query)
 		if err != nil {
 			// Continue with other tables even if one fails
-			fmt.Printf("Warning: Failed to copy data for table %s: %v\n", tableName, err)
+			slog.Warn("Failed to copy data for table", "table", tableName, "error", err)
 			continue
 		}
 	}
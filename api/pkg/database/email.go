@@ -0,0 +1,345 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/htstinson/stinsondataapi/api/internal/model"
+)
+
+// emailOutbox is the email_outbox table with its optional text columns as
+// empty strings.
+const emailOutbox = `(SELECT id, COALESCE(subscriber_id, '') AS subscriber_id, template, category,
+        to_address, subject, text_body, COALESCE(html_body, '') AS html_body,
+        COALESCE(unsubscribe_url, '') AS unsubscribe_url, status, attempts, next_attempt_at,
+        COALESCE(last_error, '') AS last_error, COALESCE(provider_message_id, '') AS provider_message_id,
+        created_at, sent_at
+    FROM email_outbox) email_outbox`
+
+var emailColumns = []string{
+	"id", "subscriber_id", "template", "category", "to_address", "subject", "text_body", "html_body",
+	"unsubscribe_url", "status", "attempts", "next_attempt_at", "last_error", "provider_message_id",
+	"created_at", "sent_at",
+}
+
+// EmailList is how the outbox may be listed, newest first by default.
+var EmailList = ListSpec{
+	Sorts:       map[string]string{"created_at": "created_at", "next_attempt_at": "next_attempt_at"},
+	Filters:     map[string]string{"status": "status", "template": "template", "category": "category", "to": "to_address", "subscriber_id": "subscriber_id", "created_at": "created_at"},
+	DefaultSort: "created_at",
+	DefaultDesc: true,
+	Keys:        []string{"id"},
+}
+
+func scanEmail(scan func(...any) error) (model.Email, error) {
+	var email model.Email
+	err := scan(
+		&email.Id,
+		&email.Subscriber_Id,
+		&email.Template,
+		&email.Category,
+		&email.To,
+		&email.Subject,
+		&email.Text_Body,
+		&email.Html_Body,
+		&email.Unsubscribe_URL,
+		&email.Status,
+		&email.Attempts,
+		&email.Next_Attempt_At,
+		&email.Last_Error,
+		&email.Provider_Message_Id,
+		&email.Created_At,
+		&email.Sent_At,
+	)
+	return email, err
+}
+
+// CreateEmail queues email, due straight away unless it is suppressed.
+func (d *Database) CreateEmail(ctx context.Context, email *model.Email) (*model.Email, error) {
+	slog.DebugContext(ctx, "CreateEmail")
+
+	email.Id = uuid.New().String()
+	if email.Status == "" {
+		email.Status = model.EmailPending
+	}
+	query := `
+        INSERT INTO email_outbox (id, subscriber_id, template, category, to_address, subject, text_body, html_body, unsubscribe_url, status)
+        VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, ''), $10)
+        RETURNING attempts, next_attempt_at, created_at
+    `
+	err := d.DB.QueryRowContext(ctx, query,
+		email.Id,
+		email.Subscriber_Id,
+		email.Template,
+		email.Category,
+		email.To,
+		email.Subject,
+		email.Text_Body,
+		email.Html_Body,
+		email.Unsubscribe_URL,
+		email.Status,
+	).Scan(&email.Attempts, &email.Next_Attempt_At, &email.Created_At)
+	if err != nil {
+		return nil, fmt.Errorf("error queueing email: %w", err)
+	}
+
+	return email, nil
+}
+
+// SelectEmails lists the outbox.
+func (d *Database) SelectEmails(ctx context.Context, q ListQuery) (*Page[model.Email], error) {
+	slog.DebugContext(ctx, "SelectEmails")
+
+	page, err := selectList(ctx, d.DB, list{
+		Spec:    EmailList,
+		Query:   q,
+		Columns: emailColumns,
+		From:    emailOutbox,
+	}, scanEmail)
+	if err != nil {
+		slog.ErrorContext(ctx, "SelectEmails", "error", err)
+		return nil, fmt.Errorf("error listing emails: %w", err)
+	}
+
+	return page, nil
+}
+
+// ClaimEmails returns up to limit pending emails that are due and puts their
+// next attempt lease away, so that other instances do not claim them while
+// they are sent. An email whose sender dies is claimed again once the lease
+// has passed. Each claim counts as an attempt.
+func (d *Database) ClaimEmails(ctx context.Context, limit int, lease time.Duration) ([]model.Email, error) {
+	query := `
+        WITH due AS (
+            SELECT id FROM email_outbox
+            WHERE status = 'pending' AND next_attempt_at <= CURRENT_TIMESTAMP
+            ORDER BY next_attempt_at
+            LIMIT $1
+            FOR UPDATE SKIP LOCKED
+        )
+        UPDATE email_outbox SET attempts = attempts + 1, next_attempt_at = $2
+        FROM due WHERE email_outbox.id = due.id
+        RETURNING email_outbox.id
+    `
+
+	rows, err := d.DB.QueryContext(ctx, query, limit, time.Now().Add(lease))
+	if err != nil {
+		return nil, fmt.Errorf("error claiming emails: %w", err)
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error claiming emails: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error claiming emails: %w", err)
+	}
+
+	emails := make([]model.Email, 0, len(ids))
+	for _, id := range ids {
+		email, err := d.GetEmail(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if email != nil {
+			emails = append(emails, *email)
+		}
+	}
+
+	return emails, nil
+}
+
+// GetEmail returns the email with id, or nil when there is none.
+func (d *Database) GetEmail(ctx context.Context, id string) (*model.Email, error) {
+	query := `SELECT ` + strings.Join(emailColumns, ", ") + ` FROM ` + emailOutbox + ` WHERE id = $1`
+
+	email, err := scanEmail(d.DB.QueryRowContext(ctx, query, id).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting email: %w", err)
+	}
+
+	return &email, nil
+}
+
+// MarkEmailSent records that the email with id was sent, with the id the
+// provider gave it.
+func (d *Database) MarkEmailSent(ctx context.Context, id string, provider_message_id string) error {
+	query := `
+        UPDATE email_outbox SET status = 'sent', sent_at = CURRENT_TIMESTAMP, last_error = NULL,
+            provider_message_id = NULLIF($2, '')
+        WHERE id = $1
+    `
+
+	if _, err := d.DB.ExecContext(ctx, query, id, provider_message_id); err != nil {
+		return fmt.Errorf("error marking email sent: %w", err)
+	}
+
+	return nil
+}
+
+// MarkEmailFailed records why sending the email with id failed. It is tried
+// again at retry_at, or never when retry_at is nil.
+func (d *Database) MarkEmailFailed(ctx context.Context, id string, message string, retry_at *time.Time) error {
+	query := `
+        UPDATE email_outbox SET last_error = $2,
+            status = CASE WHEN $3::timestamptz IS NULL THEN 'failed' ELSE 'pending' END,
+            next_attempt_at = COALESCE($3, next_attempt_at)
+        WHERE id = $1
+    `
+
+	if _, err := d.DB.ExecContext(ctx, query, id, message, retry_at); err != nil {
+		return fmt.Errorf("error marking email failed: %w", err)
+	}
+
+	return nil
+}
+
+// GetEmailTemplate returns the subscriber's own template called name, or nil
+// when it uses the built in one.
+func (d *Database) GetEmailTemplate(ctx context.Context, subscriber_id string, name string) (*model.EmailTemplate, error) {
+	query := `
+        SELECT subscriber_id, name, subject, text_body, html_body, updated_at
+        FROM email_templates WHERE subscriber_id = $1 AND name = $2
+    `
+
+	var template model.EmailTemplate
+	err := d.DB.QueryRowContext(ctx, query, subscriber_id, name).Scan(
+		&template.Subscriber_Id,
+		&template.Name,
+		&template.Subject,
+		&template.Text_Body,
+		&template.Html_Body,
+		&template.Updated_At,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting email template: %w", err)
+	}
+
+	return &template, nil
+}
+
+// SelectEmailTemplates returns the subscriber's own templates by name.
+func (d *Database) SelectEmailTemplates(ctx context.Context, subscriber_id string) ([]model.EmailTemplate, error) {
+	slog.DebugContext(ctx, "SelectEmailTemplates")
+
+	query := `
+        SELECT subscriber_id, name, subject, text_body, html_body, updated_at
+        FROM email_templates WHERE subscriber_id = $1 ORDER BY name
+    `
+
+	rows, err := d.DB.QueryContext(ctx, query, subscriber_id)
+	if err != nil {
+		return nil, fmt.Errorf("error listing email templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := []model.EmailTemplate{}
+	for rows.Next() {
+		var template model.EmailTemplate
+		err := rows.Scan(
+			&template.Subscriber_Id,
+			&template.Name,
+			&template.Subject,
+			&template.Text_Body,
+			&template.Html_Body,
+			&template.Updated_At,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error listing email templates: %w", err)
+		}
+		templates = append(templates, template)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error listing email templates: %w", err)
+	}
+
+	return templates, nil
+}
+
+// SaveEmailTemplate creates or replaces the subscriber's template.
+func (d *Database) SaveEmailTemplate(ctx context.Context, template *model.EmailTemplate) (*model.EmailTemplate, error) {
+	slog.DebugContext(ctx, "SaveEmailTemplate")
+
+	query := `
+        INSERT INTO email_templates (subscriber_id, name, subject, text_body, html_body, updated_at)
+        VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP)
+        ON CONFLICT (subscriber_id, name) DO UPDATE SET
+            subject = EXCLUDED.subject, text_body = EXCLUDED.text_body,
+            html_body = EXCLUDED.html_body, updated_at = EXCLUDED.updated_at
+    `
+	_, err := d.DB.ExecContext(ctx, query,
+		template.Subscriber_Id,
+		template.Name,
+		template.Subject,
+		template.Text_Body,
+		template.Html_Body,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error saving email template: %w", err)
+	}
+
+	return d.GetEmailTemplate(ctx, template.Subscriber_Id, template.Name)
+}
+
+// DeleteEmailTemplate returns the subscriber to the built in template called
+// name. Having no template of its own is ErrNotFound.
+func (d *Database) DeleteEmailTemplate(ctx context.Context, subscriber_id string, name string) error {
+	slog.DebugContext(ctx, "DeleteEmailTemplate")
+
+	query := `DELETE FROM email_templates WHERE subscriber_id = $1 AND name = $2`
+
+	result, err := d.DB.ExecContext(ctx, query, subscriber_id, name)
+	if err != nil {
+		return fmt.Errorf("error deleting email template: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Unsubscribe stops the category of email being sent to email. Doing so
+// again changes nothing.
+func (d *Database) Unsubscribe(ctx context.Context, email string, category string) error {
+	slog.DebugContext(ctx, "Unsubscribe")
+
+	query := `
+        INSERT INTO email_unsubscribes (email, category) VALUES (lower($1), $2)
+        ON CONFLICT (email, category) DO NOTHING
+    `
+
+	if _, err := d.DB.ExecContext(ctx, query, email, category); err != nil {
+		return fmt.Errorf("error unsubscribing: %w", err)
+	}
+
+	return nil
+}
+
+// IsUnsubscribed reports whether email has unsubscribed from the category.
+func (d *Database) IsUnsubscribed(ctx context.Context, email string, category string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM email_unsubscribes WHERE email = lower($1) AND category = $2)`
+
+	var unsubscribed bool
+	if err := d.DB.QueryRowContext(ctx, query, email, category).Scan(&unsubscribed); err != nil {
+		return false, fmt.Errorf("error checking unsubscribes: %w", err)
+	}
+
+	return unsubscribed, nil
+}
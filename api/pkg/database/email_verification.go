@@ -0,0 +1,109 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// CreateEmailVerification stores a verification token for the user, by the
+// hash of the token, and withdraws any the user has not used yet.
+func (d *Database) CreateEmailVerification(ctx context.Context, user_id string, token_hash string, expires_at time.Time) error {
+	slog.DebugContext(ctx, "CreateEmailVerification")
+
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error creating email verification: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM email_verifications WHERE user_id = $1`, user_id); err != nil {
+		return fmt.Errorf("error withdrawing email verifications: %w", err)
+	}
+
+	query := `
+        INSERT INTO email_verifications (token_hash, user_id, expires_at)
+        VALUES ($1, $2, $3)
+    `
+
+	if _, err := tx.ExecContext(ctx, query, token_hash, user_id, expires_at); err != nil {
+		return fmt.Errorf("error creating email verification: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// UseEmailVerification marks the user of the token with the hash token_hash
+// verified, spends the token, and returns the user. A token that is
+// unknown, expired or already used is ErrNotFound.
+func (d *Database) UseEmailVerification(ctx context.Context, token_hash string) (string, error) {
+	slog.DebugContext(ctx, "UseEmailVerification")
+
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("error using email verification: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+        DELETE FROM email_verifications
+        WHERE token_hash = $1 AND expires_at > CURRENT_TIMESTAMP
+        RETURNING user_id
+    `
+
+	var user_id string
+	err = tx.QueryRowContext(ctx, query, token_hash).Scan(&user_id)
+	if err == sql.ErrNoRows {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("error using email verification: %w", err)
+	}
+
+	query = `UPDATE users SET email_verified_at = COALESCE(email_verified_at, CURRENT_TIMESTAMP) WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, query, user_id); err != nil {
+		return "", fmt.Errorf("error verifying user: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("error using email verification: %w", err)
+	}
+
+	return user_id, nil
+}
+
+// DeleteUnverifiedUsers deletes the users who registered before before and
+// never verified their email, unless they have since been added to a
+// subscriber, and returns their ids.
+func (d *Database) DeleteUnverifiedUsers(ctx context.Context, before time.Time) ([]string, error) {
+	slog.DebugContext(ctx, "DeleteUnverifiedUsers")
+
+	query := `
+        DELETE FROM users
+        WHERE email_verified_at IS NULL AND created_at < $1
+            AND NOT EXISTS (SELECT 1 FROM user_subscriber us WHERE us.user_id = users.id)
+        RETURNING id
+    `
+
+	rows, err := d.DB.QueryContext(ctx, query, before)
+	if err != nil {
+		return nil, fmt.Errorf("error deleting unverified users: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("error deleting unverified users: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error deleting unverified users: %w", err)
+	}
+
+	return ids, nil
+}
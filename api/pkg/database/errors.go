@@ -0,0 +1,66 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNotFound is returned by lookups that treat a missing row as an error.
+	ErrNotFound = errors.New("not found")
+
+	// ErrDuplicate is returned when a row with the same key already exists.
+	ErrDuplicate = errors.New("duplicate")
+)
+
+// Postgres error codes, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	pqUniqueViolation     = "23505"
+	pqForeignKeyViolation = "23503"
+	pqNotNullViolation    = "23502"
+	pqCheckViolation      = "23514"
+	pqInvalidText         = "22P02"
+	pqStringTooLong       = "22001"
+	pqInvalidDatetime     = "22007"
+)
+
+func pqCode(err error) pq.ErrorCode {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code
+	}
+	return ""
+}
+
+// IsNotFound reports whether err means the row does not exist.
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound) || errors.Is(err, sql.ErrNoRows)
+}
+
+// IsDuplicate reports whether err is a unique key conflict, found either by
+// a check before inserting or by Postgres.
+func IsDuplicate(err error) bool {
+	return errors.Is(err, ErrDuplicate) || pqCode(err) == pqUniqueViolation
+}
+
+// IsForeignKeyViolation reports whether err is a reference to a row that does
+// not exist, or a delete of a row that is still referenced.
+func IsForeignKeyViolation(err error) bool {
+	return pqCode(err) == pqForeignKeyViolation
+}
+
+// IsInvalidInput reports whether Postgres rejected a value, such as a
+// malformed UUID, a missing required column or a string that is too long,
+// or a list was queried in a way its ListSpec does not allow.
+func IsInvalidInput(err error) bool {
+	if errors.Is(err, ErrInvalidListQuery) {
+		return true
+	}
+	switch pqCode(err) {
+	case pqNotNullViolation, pqCheckViolation, pqInvalidText, pqStringTooLong, pqInvalidDatetime:
+		return true
+	}
+	return false
+}
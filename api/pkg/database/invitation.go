@@ -0,0 +1,249 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/htstinson/stinsondataapi/api/internal/model"
+)
+
+// invitations is the invitations table with each row's status worked out.
+const invitations = `(SELECT id, token_hash, email, subscriber_id, role_id,
+        COALESCE(invited_by, '') AS invited_by, COALESCE(user_id, '') AS user_id,
+        created_at, expires_at, accepted_at, revoked_at,
+        CASE
+            WHEN accepted_at IS NOT NULL THEN 'accepted'
+            WHEN revoked_at IS NOT NULL THEN 'revoked'
+            WHEN expires_at <= CURRENT_TIMESTAMP THEN 'expired'
+            ELSE 'pending'
+        END AS status
+    FROM invitations) invitations`
+
+var invitationColumns = []string{
+	"id", "email", "subscriber_id", "role_id", "invited_by", "status",
+	"user_id", "created_at", "expires_at", "accepted_at", "revoked_at",
+}
+
+// InvitationList is how invitations may be listed, newest first by default.
+var InvitationList = ListSpec{
+	Sorts:       map[string]string{"created_at": "created_at", "expires_at": "expires_at", "email": "email"},
+	Filters:     map[string]string{"email": "email", "subscriber_id": "subscriber_id", "role_id": "role_id", "status": "status", "created_at": "created_at"},
+	DefaultSort: "created_at",
+	DefaultDesc: true,
+	Keys:        []string{"id"},
+}
+
+func scanInvitation(scan func(...any) error) (model.Invitation, error) {
+	var invitation model.Invitation
+	err := scan(
+		&invitation.Id,
+		&invitation.Email,
+		&invitation.Subscriber_Id,
+		&invitation.Role_Id,
+		&invitation.Invited_By,
+		&invitation.Status,
+		&invitation.User_Id,
+		&invitation.Created_At,
+		&invitation.Expires_At,
+		&invitation.Accepted_At,
+		&invitation.Revoked_At,
+	)
+	return invitation, err
+}
+
+// CreateInvitation stores an invitation by the hash of its token, and
+// revokes any other pending one for the same email and subscriber.
+func (d *Database) CreateInvitation(ctx context.Context, invitation *model.Invitation, token_hash string) (*model.Invitation, error) {
+	slog.DebugContext(ctx, "CreateInvitation")
+
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating invitation: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+        UPDATE invitations SET revoked_at = CURRENT_TIMESTAMP
+        WHERE email = $1 AND subscriber_id = $2 AND accepted_at IS NULL AND revoked_at IS NULL
+    `
+	if _, err := tx.ExecContext(ctx, query, invitation.Email, invitation.Subscriber_Id); err != nil {
+		return nil, fmt.Errorf("error revoking invitations: %w", err)
+	}
+
+	invitation.Id = uuid.New().String()
+	query = `
+        INSERT INTO invitations (id, token_hash, email, subscriber_id, role_id, invited_by, expires_at)
+        VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7)
+    `
+	_, err = tx.ExecContext(ctx, query,
+		invitation.Id,
+		token_hash,
+		invitation.Email,
+		invitation.Subscriber_Id,
+		invitation.Role_Id,
+		invitation.Invited_By,
+		invitation.Expires_At,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error creating invitation: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error creating invitation: %w", err)
+	}
+
+	return d.GetInvitation(ctx, invitation.Id)
+}
+
+// GetInvitation returns the invitation with id, or nil when there is none.
+func (d *Database) GetInvitation(ctx context.Context, id string) (*model.Invitation, error) {
+	slog.DebugContext(ctx, "GetInvitation")
+
+	query := `SELECT ` + strings.Join(invitationColumns, ", ") + ` FROM ` + invitations + ` WHERE id = $1`
+
+	invitation, err := scanInvitation(d.DB.QueryRowContext(ctx, query, id).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting invitation: %w", err)
+	}
+
+	return &invitation, nil
+}
+
+// GetPendingInvitation returns the pending invitation with the token hash
+// token_hash. One that is unknown, expired, revoked or accepted is
+// ErrNotFound.
+func (d *Database) GetPendingInvitation(ctx context.Context, token_hash string) (*model.Invitation, error) {
+	slog.DebugContext(ctx, "GetPendingInvitation")
+
+	query := `SELECT ` + strings.Join(invitationColumns, ", ") + ` FROM ` + invitations + ` WHERE token_hash = $1 AND status = 'pending'`
+
+	invitation, err := scanInvitation(d.DB.QueryRowContext(ctx, query, token_hash).Scan)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting invitation: %w", err)
+	}
+
+	return &invitation, nil
+}
+
+func (d *Database) SelectInvitations(ctx context.Context, q ListQuery) (*Page[model.Invitation], error) {
+	slog.DebugContext(ctx, "SelectInvitations")
+
+	page, err := selectList(ctx, d.DB, list{
+		Spec:    InvitationList,
+		Query:   q,
+		Columns: invitationColumns,
+		From:    invitations,
+	}, scanInvitation)
+	if err != nil {
+		slog.ErrorContext(ctx, "SelectInvitations", "error", err)
+		return nil, fmt.Errorf("error listing invitations: %w", err)
+	}
+
+	return page, nil
+}
+
+// RevokeInvitation withdraws a pending invitation. One that is not pending
+// is ErrNotFound.
+func (d *Database) RevokeInvitation(ctx context.Context, id string) error {
+	slog.DebugContext(ctx, "RevokeInvitation")
+
+	query := `
+        UPDATE invitations SET revoked_at = CURRENT_TIMESTAMP
+        WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+    `
+
+	result, err := d.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("error revoking invitation: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// AcceptInvitation accepts the pending invitation with the token hash
+// token_hash for the user user_id, or, when user_id is empty, for a new
+// user with the invited email and password_hash. The user is added to the
+// subscriber with the role unless they have it already. It all happens in
+// one transaction, so an invitation is accepted completely or not at all.
+// An invitation that is not pending is ErrNotFound.
+func (d *Database) AcceptInvitation(ctx context.Context, token_hash string, user_id string, password_hash string) (*model.Invitation, error) {
+	slog.DebugContext(ctx, "AcceptInvitation")
+
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error accepting invitation: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id, email, subscriber_id, role_id string
+	query := `
+        UPDATE invitations SET accepted_at = CURRENT_TIMESTAMP
+        WHERE token_hash = $1 AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+        RETURNING id, email, subscriber_id, role_id
+    `
+	err = tx.QueryRowContext(ctx, query, token_hash).Scan(&id, &email, &subscriber_id, &role_id)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error accepting invitation: %w", err)
+	}
+
+	// The link came to the invited email, which verifies it
+	if user_id == "" {
+		user_id = uuid.New().String()
+		query = `INSERT INTO users (id, username, password_hash, created_at, email_verified_at) VALUES ($1, $2, $3, $4, $4)`
+		if _, err := tx.ExecContext(ctx, query, user_id, email, password_hash, time.Now()); err != nil {
+			return nil, fmt.Errorf("error creating user: %w", err)
+		}
+	} else {
+		query = `UPDATE users SET email_verified_at = COALESCE(email_verified_at, CURRENT_TIMESTAMP) WHERE id = $1`
+		if _, err := tx.ExecContext(ctx, query, user_id); err != nil {
+			return nil, fmt.Errorf("error verifying user: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE invitations SET user_id = $2 WHERE id = $1`, id, user_id); err != nil {
+		return nil, fmt.Errorf("error accepting invitation: %w", err)
+	}
+
+	var user_subscriber_id string
+	query = `SELECT id FROM user_subscriber WHERE user_id = $1 AND subscriber_id = $2`
+	err = tx.QueryRowContext(ctx, query, user_id, subscriber_id).Scan(&user_subscriber_id)
+	if err == sql.ErrNoRows {
+		query = `INSERT INTO user_subscriber (user_id, subscriber_id) VALUES ($1, $2) RETURNING id`
+		err = tx.QueryRowContext(ctx, query, user_id, subscriber_id).Scan(&user_subscriber_id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error creating user_subscriber: %w", err)
+	}
+
+	query = `
+        INSERT INTO user_subscriber_role (id, user_subscriber_id, role_id)
+        SELECT $1, $2, $3
+        WHERE NOT EXISTS (SELECT 1 FROM user_subscriber_role WHERE user_subscriber_id = $2 AND role_id = $3)
+    `
+	if _, err := tx.ExecContext(ctx, query, uuid.New().String(), user_subscriber_id, role_id); err != nil {
+		return nil, fmt.Errorf("error creating user_subscriber_role: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error accepting invitation: %w", err)
+	}
+
+	return d.GetInvitation(ctx, id)
+}
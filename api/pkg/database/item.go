@@ -60,23 +60,28 @@ func (d *Database) CreateItem(ctx context.Context, item *model.Item) error {
 	return nil
 }
 
-func (d *Database) SelectItems(ctx context.Context, limit, offset int) ([]model.Item, error) {
-	rows, err := d.DB.QueryContext(ctx,
-		"SELECT id, name, created_at FROM items ORDER BY created_at DESC LIMIT $1 OFFSET $2",
-		limit, offset,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("error listing items: %w", err)
-	}
-	defer rows.Close()
+// ItemList is how items may be listed.
+var ItemList = ListSpec{
+	Sorts:       map[string]string{"name": "name", "created_at": "created_at"},
+	Filters:     map[string]string{"name": "name", "created_at": "created_at"},
+	DefaultSort: "created_at",
+	DefaultDesc: true,
+	Keys:        []string{"id"},
+}
 
-	var items []model.Item
-	for rows.Next() {
+func (d *Database) SelectItems(ctx context.Context, q ListQuery) (*Page[model.Item], error) {
+	page, err := selectList(ctx, d.DB, list{
+		Spec:    ItemList,
+		Query:   q,
+		Columns: []string{"id", "name", "created_at"},
+		From:    "items",
+	}, func(scan func(...any) error) (model.Item, error) {
 		var item model.Item
-		if err := rows.Scan(&item.ID, &item.Name, &item.CreatedAt); err != nil {
-			return nil, fmt.Errorf("error scanning item: %w", err)
-		}
-		items = append(items, item)
+		err := scan(&item.ID, &item.Name, &item.CreatedAt)
+		return item, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing items: %w", err)
 	}
-	return items, nil
+	return page, nil
 }
@@ -0,0 +1,342 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// DefaultLimit is the page size when a query sets none.
+	DefaultLimit = 50
+
+	// MaxLimit is the largest page a query may ask for.
+	MaxLimit = 1000
+)
+
+// ErrInvalidListQuery is returned for a ListQuery its ListSpec does not allow.
+var ErrInvalidListQuery = errors.New("invalid list query")
+
+// FilterOps are the comparisons a Filter may use, mapped to their SQL
+// operators. "like" matches a case-insensitive substring of the column's
+// text.
+var FilterOps = map[string]string{
+	"eq":   "=",
+	"ne":   "<>",
+	"lt":   "<",
+	"lte":  "<=",
+	"gt":   ">",
+	"gte":  ">=",
+	"like": "ILIKE",
+}
+
+// ListSpec describes how a list may be queried. Sorts and Filters map the
+// field names clients use, which match the json names of the model, to
+// columns. Keys are the columns that identify a row; they break ties between
+// equal sort values so that cursors never skip or repeat a row. Sort and key
+// columns must not be nullable.
+type ListSpec struct {
+	Sorts       map[string]string
+	Filters     map[string]string
+	DefaultSort string
+	DefaultDesc bool
+	Keys        []string
+}
+
+// ListQuery selects a page of a list. A query pages either by Offset or by
+// Cursor, the NextCursor of the previous page; a cursor is tied to the sort
+// it was issued for.
+type ListQuery struct {
+	Limit   int
+	Offset  int
+	Cursor  string
+	Sort    string
+	Desc    bool
+	Filters []Filter
+}
+
+// Filter restricts a list to the rows whose Field compares to Value by Op,
+// one of the FilterOps.
+type Filter struct {
+	Field string
+	Op    string
+	Value string
+}
+
+// Page is one page of a list. Total counts every row matching the filters;
+// NextCursor is empty on the last page.
+type Page[T any] struct {
+	Data       []T    `json:"data"`
+	Total      int    `json:"total"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// cursor is the position after the last row of a page: the sort it was
+// issued for and the text of that row's sort value followed by its keys.
+type cursor struct {
+	Sort   string   `json:"s"`
+	Desc   bool     `json:"d,omitempty"`
+	Values []string `json:"v"`
+}
+
+func encodeCursor(c cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(b, &c)
+	return c, err
+}
+
+// Normalize fills in the defaults of q.
+func (s ListSpec) Normalize(q ListQuery) ListQuery {
+	if q.Limit == 0 {
+		q.Limit = DefaultLimit
+	}
+	if q.Sort == "" {
+		q.Sort = s.DefaultSort
+		q.Desc = q.Desc || s.DefaultDesc
+	}
+	filters := make([]Filter, len(q.Filters))
+	for i, f := range q.Filters {
+		if f.Op == "" {
+			f.Op = "eq"
+		}
+		filters[i] = f
+	}
+	q.Filters = filters
+	return q
+}
+
+// Validate reports what q asks for that s does not allow, keyed by the query
+// string parameter at fault. q must have been normalized.
+func (s ListSpec) Validate(q ListQuery) map[string][]string {
+	errs := make(map[string][]string)
+
+	if q.Limit < 1 || q.Limit > MaxLimit {
+		errs["limit"] = append(errs["limit"], fmt.Sprintf("must be between 1 and %d", MaxLimit))
+	}
+	if q.Offset < 0 {
+		errs["offset"] = append(errs["offset"], "must not be negative")
+	}
+	if _, ok := s.Sorts[q.Sort]; !ok {
+		errs["sort"] = append(errs["sort"], "must be one of "+strings.Join(s.SortFields(), ", "))
+	}
+	for _, f := range q.Filters {
+		param := "filter[" + f.Field + "]"
+		if _, ok := s.Filters[f.Field]; !ok {
+			errs[param] = append(errs[param], "is not a filter, use one of "+strings.Join(s.FilterFields(), ", "))
+		} else if _, ok := FilterOps[f.Op]; !ok {
+			errs[param] = append(errs[param], "has no operator "+f.Op)
+		}
+	}
+	if q.Cursor != "" {
+		c, err := decodeCursor(q.Cursor)
+		switch {
+		case err != nil || len(c.Values) != len(s.Keys)+1:
+			errs["cursor"] = append(errs["cursor"], "is not a cursor of this list")
+		case c.Sort != q.Sort || c.Desc != q.Desc:
+			errs["cursor"] = append(errs["cursor"], "was issued for another sort")
+		}
+		if q.Offset != 0 {
+			errs["cursor"] = append(errs["cursor"], "cannot be combined with offset or page")
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// SortFields lists the fields s can sort by.
+func (s ListSpec) SortFields() []string {
+	return fieldNames(s.Sorts)
+}
+
+// FilterFields lists the fields s can filter by.
+func (s ListSpec) FilterFields() []string {
+	return fieldNames(s.Filters)
+}
+
+func fieldNames(fields map[string]string) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// list is a list query against one table or view. Columns are the columns
+// each row is scanned from; Where holds conditions on them, numbered from $1,
+// whose values are Args.
+type list struct {
+	Spec    ListSpec
+	Query   ListQuery
+	Columns []string
+	From    string
+	Where   []string
+	Args    []any
+}
+
+// listSQL is a built list: the page query, which selects the columns
+// followed by the row's cursor values and the total, and a query counting
+// the rows that match, which takes the first countArgs of args.
+type listSQL struct {
+	page      string
+	count     string
+	args      []any
+	countArgs int
+}
+
+func (l list) build(q ListQuery) (listSQL, error) {
+	if errs := l.Spec.Validate(q); errs != nil {
+		return listSQL{}, fmt.Errorf("%w: %v", ErrInvalidListQuery, errs)
+	}
+
+	args := append([]any{}, l.Args...)
+	where := append([]string{}, l.Where...)
+	for _, f := range q.Filters {
+		column := l.Spec.Filters[f.Field]
+		value := f.Value
+		if f.Op == "like" {
+			column += "::text"
+			value = "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value) + "%"
+		}
+		args = append(args, value)
+		where = append(where, fmt.Sprintf("%s %s $%d", column, FilterOps[f.Op], len(args)))
+	}
+
+	// The sort and key columns are selected under fixed names so that the
+	// keyset condition and the cursor need not know the table.
+	keys := []string{"list_sort"}
+	inner := append([]string{}, l.Columns...)
+	inner = append(inner, l.Spec.Sorts[q.Sort]+" AS list_sort")
+	for i, key := range l.Spec.Keys {
+		keys = append(keys, fmt.Sprintf("list_key%d", i))
+		inner = append(inner, fmt.Sprintf("%s AS list_key%d", key, i))
+	}
+
+	cte := fmt.Sprintf("WITH list AS (SELECT %s FROM %s", strings.Join(inner, ", "), l.From)
+	if len(where) > 0 {
+		cte += " WHERE " + strings.Join(where, " AND ")
+	}
+	cte += ")"
+	countArgs := len(args)
+
+	direction, compare := "ASC", ">"
+	if q.Desc {
+		direction, compare = "DESC", "<"
+	}
+
+	var after string
+	if q.Cursor != "" {
+		c, _ := decodeCursor(q.Cursor)
+		placeholders := make([]string, len(c.Values))
+		for i, v := range c.Values {
+			args = append(args, v)
+			placeholders[i] = fmt.Sprintf("$%d", len(args))
+		}
+		after = fmt.Sprintf(" WHERE (%s) %s (%s)", strings.Join(keys, ", "), compare, strings.Join(placeholders, ", "))
+	}
+
+	order := make([]string, len(keys))
+	cursorValues := make([]string, len(keys))
+	for i, key := range keys {
+		order[i] = key + " " + direction
+		cursorValues[i] = key + "::text"
+	}
+
+	// One row more than the page is read to learn whether another page follows.
+	args = append(args, q.Limit+1, q.Offset)
+	page := fmt.Sprintf("%s SELECT %s, ARRAY[%s], (SELECT COUNT(*) FROM list) FROM list%s ORDER BY %s LIMIT $%d OFFSET $%d",
+		cte, strings.Join(l.Columns, ", "), strings.Join(cursorValues, ", "), after, strings.Join(order, ", "), len(args)-1, len(args))
+
+	return listSQL{page: page, count: cte + " SELECT COUNT(*) FROM list", args: args, countArgs: countArgs}, nil
+}
+
+// selectList runs l and returns its page. scanRow reads one item; it is
+// given a scan function taking the destinations of l.Columns.
+func selectList[T any](ctx context.Context, db *sql.DB, l list, scanRow func(scan func(dest ...any) error) (T, error)) (*Page[T], error) {
+	q := l.Spec.Normalize(l.Query)
+	built, err := l.build(q)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, built.page, built.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &Page[T]{Data: []T{}}
+	var values, last []string
+	more := false
+	for rows.Next() {
+		if len(page.Data) == q.Limit {
+			more = true
+			break
+		}
+		item, err := scanRow(func(dest ...any) error {
+			return rows.Scan(append(dest, pq.Array(&values), &page.Total)...)
+		})
+		if err != nil {
+			return nil, err
+		}
+		page.Data = append(page.Data, item)
+		last = values
+		values = nil
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if more {
+		page.NextCursor = encodeCursor(cursor{Sort: q.Sort, Desc: q.Desc, Values: last})
+	}
+
+	// An empty page past the end still reports how many rows there are.
+	if len(page.Data) == 0 && (q.Offset > 0 || q.Cursor != "") {
+		if err := db.QueryRowContext(ctx, built.count, built.args[:built.countArgs]...).Scan(&page.Total); err != nil {
+			return nil, err
+		}
+	}
+
+	return page, nil
+}
+
+// SelectAll reads every page of a list by following NextCursor. selectPage
+// is usually a Repository Select method bound to its other arguments.
+func SelectAll[T any](q ListQuery, selectPage func(ListQuery) (*Page[T], error)) ([]T, error) {
+	q.Limit = MaxLimit
+	q.Offset = 0
+	q.Cursor = ""
+
+	var all []T
+	for {
+		page, err := selectPage(q)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page.Data...)
+		if page.NextCursor == "" {
+			return all, nil
+		}
+		q.Cursor = page.NextCursor
+	}
+}
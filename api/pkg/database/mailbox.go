@@ -0,0 +1,231 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/lib/pq"
+)
+
+// Mailbox sources
+
+const mailboxSourceColumns = `
+        SELECT s.id, s.subscriber_id, s.label, s.search_definition_engine_id, s.paused,
+            (SELECT count(*) FROM mailbox_messages m WHERE m.source_id = s.id),
+            s.last_polled_at, COALESCE(s.last_error, ''), s.created_at
+        FROM mailbox_sources s`
+
+func scanMailboxSource(scan func(...any) error) (model.MailboxSource, error) {
+	var source model.MailboxSource
+	err := scan(
+		&source.Id,
+		&source.Subscriber_Id,
+		&source.Label,
+		&source.Search_Definition_Engine_Id,
+		&source.Paused,
+		&source.Messages,
+		&source.Last_Polled_At,
+		&source.Last_Error,
+		&source.Created_At,
+	)
+	return source, err
+}
+
+func (d *Database) selectMailboxSources(ctx context.Context, where string, args ...any) ([]model.MailboxSource, error) {
+	rows, err := d.DB.QueryContext(ctx, mailboxSourceColumns+" "+where+" ORDER BY s.created_at", args...)
+	if err != nil {
+		return nil, fmt.Errorf("error listing mailbox sources: %w", err)
+	}
+	defer rows.Close()
+
+	sources := []model.MailboxSource{}
+	for rows.Next() {
+		source, err := scanMailboxSource(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("error listing mailbox sources: %w", err)
+		}
+		sources = append(sources, source)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error listing mailbox sources: %w", err)
+	}
+
+	return sources, nil
+}
+
+// CreateMailboxSource starts reading the label into the subscriber's search
+// definition engine.
+func (d *Database) CreateMailboxSource(ctx context.Context, source *model.MailboxSource) (*model.MailboxSource, error) {
+	slog.DebugContext(ctx, "CreateMailboxSource")
+
+	query := `
+        INSERT INTO mailbox_sources (id, subscriber_id, label, search_definition_engine_id, paused)
+        VALUES ($1, $2, $3, $4, $5)
+    `
+	id := uuid.New().String()
+	_, err := d.DB.ExecContext(ctx, query,
+		id,
+		source.Subscriber_Id,
+		source.Label,
+		source.Search_Definition_Engine_Id,
+		source.Paused,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error creating mailbox source: %w", err)
+	}
+
+	return d.GetMailboxSource(ctx, id)
+}
+
+// GetMailboxSource returns nil when there is no such source.
+func (d *Database) GetMailboxSource(ctx context.Context, id string) (*model.MailboxSource, error) {
+	source, err := scanMailboxSource(d.DB.QueryRowContext(ctx, mailboxSourceColumns+" WHERE s.id = $1", id).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting mailbox source: %w", err)
+	}
+
+	return &source, nil
+}
+
+// SelectMailboxSources returns the subscriber's sources.
+func (d *Database) SelectMailboxSources(ctx context.Context, subscriber_id string) ([]model.MailboxSource, error) {
+	slog.DebugContext(ctx, "SelectMailboxSources")
+	return d.selectMailboxSources(ctx, "WHERE s.subscriber_id = $1", subscriber_id)
+}
+
+// SelectActiveMailboxSources returns the sources of every subscriber that
+// are not paused, for the poller.
+func (d *Database) SelectActiveMailboxSources(ctx context.Context) ([]model.MailboxSource, error) {
+	return d.selectMailboxSources(ctx, "WHERE NOT s.paused")
+}
+
+// UpdateMailboxSource changes the label, search definition engine and
+// pausing of the source.
+func (d *Database) UpdateMailboxSource(ctx context.Context, source *model.MailboxSource) (*model.MailboxSource, error) {
+	slog.DebugContext(ctx, "UpdateMailboxSource")
+
+	query := `
+        UPDATE mailbox_sources SET label = $2, search_definition_engine_id = $3, paused = $4
+        WHERE id = $1
+    `
+	result, err := d.DB.ExecContext(ctx, query,
+		source.Id,
+		source.Label,
+		source.Search_Definition_Engine_Id,
+		source.Paused,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error updating mailbox source: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return nil, ErrNotFound
+	}
+
+	return d.GetMailboxSource(ctx, source.Id)
+}
+
+// DeleteMailboxSource stops reading the label. The results already read
+// are kept.
+func (d *Database) DeleteMailboxSource(ctx context.Context, id string) error {
+	slog.DebugContext(ctx, "DeleteMailboxSource")
+
+	result, err := d.DB.ExecContext(ctx, `DELETE FROM mailbox_sources WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("error deleting mailbox source: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// RecordMailboxPoll notes that the source was polled, and the error that
+// stopped it if any.
+func (d *Database) RecordMailboxPoll(ctx context.Context, id string, message string) error {
+	query := `UPDATE mailbox_sources SET last_polled_at = CURRENT_TIMESTAMP, last_error = NULLIF($2, '') WHERE id = $1`
+
+	if _, err := d.DB.ExecContext(ctx, query, id, message); err != nil {
+		return fmt.Errorf("error recording mailbox poll: %w", err)
+	}
+
+	return nil
+}
+
+// SelectProcessedMailboxMessages returns those of the Gmail message ids
+// that have already been read from the source.
+func (d *Database) SelectProcessedMailboxMessages(ctx context.Context, source_id string, message_ids []string) ([]string, error) {
+	query := `SELECT message_id FROM mailbox_messages WHERE source_id = $1 AND message_id = ANY($2)`
+
+	rows, err := d.DB.QueryContext(ctx, query, source_id, pq.Array(message_ids))
+	if err != nil {
+		return nil, fmt.Errorf("error listing mailbox messages: %w", err)
+	}
+	defer rows.Close()
+
+	processed := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("error listing mailbox messages: %w", err)
+		}
+		processed = append(processed, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error listing mailbox messages: %w", err)
+	}
+
+	return processed, nil
+}
+
+// SaveMailboxMessage stores the search results read from the message and
+// records that the source has read it, in one transaction, so that a
+// message is stored completely or not at all. It returns the results with
+// their ids, or none when the message had already been read.
+func (d *Database) SaveMailboxMessage(ctx context.Context, subscriber model.Subscriber, source_id string, message_id string, results []model.CalibrateSearchResult) ([]model.CalibrateSearchResult, error) {
+	slog.DebugContext(ctx, "SaveMailboxMessage")
+
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error recording mailbox message: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+        INSERT INTO mailbox_messages (source_id, message_id, results) VALUES ($1, $2, $3)
+        ON CONFLICT (source_id, message_id) DO NOTHING
+    `
+	res, err := tx.ExecContext(ctx, query, source_id, message_id, len(results))
+	if err != nil {
+		return nil, fmt.Errorf("error recording mailbox message: %w", err)
+	}
+	// Another poll got there first
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return nil, err
+	}
+
+	query = fmt.Sprintf(`INSERT INTO %s.calibrate_search_results (id, link, snippet, title, search_definition_engine_id, search_time, subscriber_id, published)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`, subscriber.Schema_Name)
+	stored := make([]model.CalibrateSearchResult, 0, len(results))
+	for _, row := range results {
+		row.ID = uuid.New()
+		if _, err := tx.ExecContext(ctx, query,
+			row.ID, row.Link, row.Snippet, row.Title, row.SearchDefinitionEngineID, row.SearchTime, row.SubscriberID, row.Published); err != nil {
+			return nil, fmt.Errorf("error creating search result: %w", err)
+		}
+		stored = append(stored, row)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error recording mailbox message: %w", err)
+	}
+
+	return stored, nil
+}
@@ -0,0 +1,191 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"github.com/htstinson/stinsondataapi/api/internal/model"
+)
+
+// GetUserMFA returns the user's TOTP enrolment, or nil when there is none.
+func (d *Database) GetUserMFA(ctx context.Context, user_id string) (*model.UserMFA, error) {
+	slog.DebugContext(ctx, "GetUserMFA")
+
+	query := `
+        SELECT user_id, secret, enabled, created_at, enabled_at,
+            (SELECT COUNT(*) FROM user_mfa_recovery_codes c WHERE c.user_id = m.user_id AND c.used_at IS NULL)
+        FROM user_mfa m
+        WHERE user_id = $1
+    `
+
+	var mfa model.UserMFA
+	err := d.DB.QueryRowContext(ctx, query, user_id).Scan(
+		&mfa.User_Id, &mfa.Secret, &mfa.Enabled, &mfa.Created_At, &mfa.Enabled_At, &mfa.Recovery_Codes_Left,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting user mfa: %w", err)
+	}
+
+	return &mfa, nil
+}
+
+// SaveUserMFA starts an enrolment with the sealed secret, replacing any
+// enrolment not yet enabled. An enabled one is left alone and ErrDuplicate
+// returned.
+func (d *Database) SaveUserMFA(ctx context.Context, user_id string, secret string) error {
+	slog.DebugContext(ctx, "SaveUserMFA")
+
+	query := `
+        INSERT INTO user_mfa (user_id, secret) VALUES ($1, $2)
+        ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, created_at = CURRENT_TIMESTAMP, last_used_step = NULL
+        WHERE user_mfa.enabled = false
+    `
+
+	result, err := d.DB.ExecContext(ctx, query, user_id, secret)
+	if err != nil {
+		return fmt.Errorf("error saving user mfa: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return ErrDuplicate
+	}
+
+	return nil
+}
+
+// EnableUserMFA turns on the user's enrolment and replaces their recovery
+// codes with the ones hashed in code_hashes.
+func (d *Database) EnableUserMFA(ctx context.Context, user_id string, code_hashes []string) error {
+	slog.DebugContext(ctx, "EnableUserMFA")
+
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error enabling user mfa: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `UPDATE user_mfa SET enabled = true, enabled_at = CURRENT_TIMESTAMP WHERE user_id = $1`, user_id)
+	if err != nil {
+		return fmt.Errorf("error enabling user mfa: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return ErrNotFound
+	}
+
+	if err := replaceRecoveryCodes(ctx, tx, user_id, code_hashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteUserMFA removes the user's enrolment and recovery codes.
+func (d *Database) DeleteUserMFA(ctx context.Context, user_id string) error {
+	slog.DebugContext(ctx, "DeleteUserMFA")
+
+	// The recovery codes go with it, by ON DELETE CASCADE
+	if _, err := d.DB.ExecContext(ctx, `DELETE FROM user_mfa WHERE user_id = $1`, user_id); err != nil {
+		return fmt.Errorf("error deleting user mfa: %w", err)
+	}
+
+	return nil
+}
+
+// UseMFAStep records that the user has used the code of step, and reports
+// false when that step or a later one was used already, so that a code
+// cannot be replayed.
+func (d *Database) UseMFAStep(ctx context.Context, user_id string, step int64) (bool, error) {
+	slog.DebugContext(ctx, "UseMFAStep")
+
+	query := `
+        UPDATE user_mfa SET last_used_step = $2
+        WHERE user_id = $1 AND (last_used_step IS NULL OR last_used_step < $2)
+    `
+
+	result, err := d.DB.ExecContext(ctx, query, user_id, step)
+	if err != nil {
+		return false, fmt.Errorf("error using mfa step: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error using mfa step: %w", err)
+	}
+
+	return rows == 1, nil
+}
+
+// ReplaceRecoveryCodes replaces the user's recovery codes with the ones
+// hashed in code_hashes.
+func (d *Database) ReplaceRecoveryCodes(ctx context.Context, user_id string, code_hashes []string) error {
+	slog.DebugContext(ctx, "ReplaceRecoveryCodes")
+
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error replacing recovery codes: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(ctx, tx, user_id, code_hashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, user_id string, code_hashes []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_mfa_recovery_codes WHERE user_id = $1`, user_id); err != nil {
+		return fmt.Errorf("error deleting recovery codes: %w", err)
+	}
+	for _, hash := range code_hashes {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO user_mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, user_id, hash); err != nil {
+			return fmt.Errorf("error creating recovery code: %w", err)
+		}
+	}
+	return nil
+}
+
+// UseRecoveryCode marks the user's unused recovery code with the hash
+// code_hash used, and reports false when there is none.
+func (d *Database) UseRecoveryCode(ctx context.Context, user_id string, code_hash string) (bool, error) {
+	slog.DebugContext(ctx, "UseRecoveryCode")
+
+	query := `
+        UPDATE user_mfa_recovery_codes SET used_at = CURRENT_TIMESTAMP
+        WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+    `
+
+	result, err := d.DB.ExecContext(ctx, query, user_id, code_hash)
+	if err != nil {
+		return false, fmt.Errorf("error using recovery code: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error using recovery code: %w", err)
+	}
+
+	return rows == 1, nil
+}
+
+// MFARequired reports whether any subscriber the user belongs to requires
+// MFA of its users.
+func (d *Database) MFARequired(ctx context.Context, user_id string) (bool, error) {
+	slog.DebugContext(ctx, "MFARequired")
+
+	query := `
+        SELECT EXISTS (
+            SELECT 1 FROM user_subscriber us JOIN subscribers s ON s.id = us.subscriber_id
+            WHERE us.user_id = $1 AND s.mfa_required
+        )
+    `
+
+	var required bool
+	if err := d.DB.QueryRowContext(ctx, query, user_id).Scan(&required); err != nil {
+		return false, fmt.Errorf("error checking mfa policy: %w", err)
+	}
+
+	return required, nil
+}
@@ -0,0 +1,283 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/htstinson/stinsondataapi/api/internal/model"
+)
+
+// Notification preferences
+
+const notificationColumns = `user_id, subscriber_id,
+            login_email, login_webhook, mention_email, mention_webhook,
+            security_email, security_webhook, digest_email, digest_webhook,
+            COALESCE(webhook_url, ''), COALESCE(webhook_secret, ''), updated_at`
+
+func scanNotificationPreferences(scan func(...any) error) (model.NotificationPreferences, error) {
+	var p model.NotificationPreferences
+	err := scan(
+		&p.User_Id,
+		&p.Subscriber_Id,
+		&p.Login_Alerts.Email,
+		&p.Login_Alerts.Webhook,
+		&p.Mention_Alerts.Email,
+		&p.Mention_Alerts.Webhook,
+		&p.Security_Events.Email,
+		&p.Security_Events.Webhook,
+		&p.Digest.Email,
+		&p.Digest.Webhook,
+		&p.Webhook_URL,
+		&p.Webhook_Secret,
+		&p.Updated_At,
+	)
+	p.Webhook_Signed = p.Webhook_Secret != ""
+	return p, err
+}
+
+// GetNotificationPreferences returns the user's preferences in the
+// subscriber, or their defaults when subscriber_id is empty, and nil when
+// they have not set them.
+func (d *Database) GetNotificationPreferences(ctx context.Context, user_id string, subscriber_id string) (*model.NotificationPreferences, error) {
+	query := `SELECT ` + notificationColumns + ` FROM notification_preferences WHERE user_id = $1 AND subscriber_id = $2`
+
+	p, err := scanNotificationPreferences(d.DB.QueryRowContext(ctx, query, user_id, subscriber_id).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting notification preferences: %w", err)
+	}
+
+	return &p, nil
+}
+
+// SelectNotificationPreferences returns the preferences the user set in each
+// subscriber, leaving out their defaults.
+func (d *Database) SelectNotificationPreferences(ctx context.Context, user_id string) ([]model.NotificationPreferences, error) {
+	slog.DebugContext(ctx, "SelectNotificationPreferences")
+
+	query := `SELECT ` + notificationColumns + ` FROM notification_preferences
+        WHERE user_id = $1 AND subscriber_id <> '' ORDER BY subscriber_id`
+
+	rows, err := d.DB.QueryContext(ctx, query, user_id)
+	if err != nil {
+		return nil, fmt.Errorf("error listing notification preferences: %w", err)
+	}
+	defer rows.Close()
+
+	preferences := []model.NotificationPreferences{}
+	for rows.Next() {
+		p, err := scanNotificationPreferences(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("error listing notification preferences: %w", err)
+		}
+		preferences = append(preferences, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error listing notification preferences: %w", err)
+	}
+
+	return preferences, nil
+}
+
+// SaveNotificationPreferences creates or replaces the preferences of the
+// user in p.Subscriber_Id, or their defaults when it is empty.
+func (d *Database) SaveNotificationPreferences(ctx context.Context, p *model.NotificationPreferences) (*model.NotificationPreferences, error) {
+	slog.DebugContext(ctx, "SaveNotificationPreferences")
+
+	query := `
+        INSERT INTO notification_preferences (user_id, subscriber_id,
+            login_email, login_webhook, mention_email, mention_webhook,
+            security_email, security_webhook, digest_email, digest_webhook,
+            webhook_url, webhook_secret, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, ''), NULLIF($12, ''), CURRENT_TIMESTAMP)
+        ON CONFLICT (user_id, subscriber_id) DO UPDATE SET
+            login_email = EXCLUDED.login_email, login_webhook = EXCLUDED.login_webhook,
+            mention_email = EXCLUDED.mention_email, mention_webhook = EXCLUDED.mention_webhook,
+            security_email = EXCLUDED.security_email, security_webhook = EXCLUDED.security_webhook,
+            digest_email = EXCLUDED.digest_email, digest_webhook = EXCLUDED.digest_webhook,
+            webhook_url = EXCLUDED.webhook_url, webhook_secret = EXCLUDED.webhook_secret,
+            updated_at = EXCLUDED.updated_at
+    `
+	_, err := d.DB.ExecContext(ctx, query,
+		p.User_Id,
+		p.Subscriber_Id,
+		p.Login_Alerts.Email,
+		p.Login_Alerts.Webhook,
+		p.Mention_Alerts.Email,
+		p.Mention_Alerts.Webhook,
+		p.Security_Events.Email,
+		p.Security_Events.Webhook,
+		p.Digest.Email,
+		p.Digest.Webhook,
+		p.Webhook_URL,
+		p.Webhook_Secret,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error saving notification preferences: %w", err)
+	}
+
+	return d.GetNotificationPreferences(ctx, p.User_Id, p.Subscriber_Id)
+}
+
+// DeleteNotificationPreferences returns the user to their defaults in the
+// subscriber. Having set none there is ErrNotFound.
+func (d *Database) DeleteNotificationPreferences(ctx context.Context, user_id string, subscriber_id string) error {
+	slog.DebugContext(ctx, "DeleteNotificationPreferences")
+
+	query := `DELETE FROM notification_preferences WHERE user_id = $1 AND subscriber_id = $2`
+
+	result, err := d.DB.ExecContext(ctx, query, user_id, subscriber_id)
+	if err != nil {
+		return fmt.Errorf("error deleting notification preferences: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// SelectSubscriberUsers returns the users of the subscriber who can be
+// notified: those with a verified email, leaving out service accounts.
+func (d *Database) SelectSubscriberUsers(ctx context.Context, subscriber_id string) ([]model.User, error) {
+	slog.DebugContext(ctx, "SelectSubscriberUsers")
+
+	query := `
+        SELECT u.id, u.username, u.ip_address, u.created_at, u.email_verified_at, u.service_account
+        FROM users u
+        JOIN user_subscriber us ON us.user_id = u.id
+        WHERE us.subscriber_id = $1 AND u.email_verified_at IS NOT NULL AND NOT u.service_account
+        ORDER BY u.username
+    `
+
+	rows, err := d.DB.QueryContext(ctx, query, subscriber_id)
+	if err != nil {
+		return nil, fmt.Errorf("error listing subscriber users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []model.User{}
+	for rows.Next() {
+		var user model.User
+		if err := rows.Scan(&user.ID, &user.Username, &user.IP_address, &user.CreatedAt, &user.Email_Verified_At, &user.Service_Account); err != nil {
+			return nil, fmt.Errorf("error listing subscriber users: %w", err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error listing subscriber users: %w", err)
+	}
+
+	return users, nil
+}
+
+// Notification digests
+
+// SelectDigestRecipients returns the users of each subscriber, with the
+// subscriber, who may want digests: those whose preferences there, or whose
+// defaults, have a digest on. Whether they apply is for the caller to say.
+func (d *Database) SelectDigestRecipients(ctx context.Context) ([]model.DigestRecipient, error) {
+	slog.DebugContext(ctx, "SelectDigestRecipients")
+
+	query := `
+        SELECT u.id, u.username, us.subscriber_id
+        FROM users u
+        JOIN user_subscriber us ON us.user_id = u.id
+        WHERE u.email_verified_at IS NOT NULL AND NOT u.service_account
+            AND EXISTS (
+                SELECT 1 FROM notification_preferences p
+                WHERE p.user_id = u.id AND p.subscriber_id IN (us.subscriber_id, '')
+                    AND (p.digest_email <> 'off' OR p.digest_webhook <> 'off'))
+        ORDER BY us.subscriber_id, u.username
+    `
+
+	rows, err := d.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error listing digest recipients: %w", err)
+	}
+	defer rows.Close()
+
+	recipients := []model.DigestRecipient{}
+	for rows.Next() {
+		var r model.DigestRecipient
+		if err := rows.Scan(&r.User_Id, &r.Username, &r.Subscriber_Id); err != nil {
+			return nil, fmt.Errorf("error listing digest recipients: %w", err)
+		}
+		recipients = append(recipients, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error listing digest recipients: %w", err)
+	}
+
+	return recipients, nil
+}
+
+// ClaimNotificationDigest records that the user's digest of the subscriber
+// on channel is sent at now, unless one was sent after due. It returns
+// whether the digest is claimed and when the one before it was sent, nil
+// for the first. Claiming first means concurrent pollers send it once.
+func (d *Database) ClaimNotificationDigest(ctx context.Context, user_id string, subscriber_id string, channel string, due time.Time, now time.Time) (bool, *time.Time, error) {
+	query := `
+        WITH previous AS (
+            SELECT sent_at FROM notification_digests
+            WHERE user_id = $1 AND subscriber_id = $2 AND channel = $3
+        )
+        INSERT INTO notification_digests (user_id, subscriber_id, channel, sent_at) VALUES ($1, $2, $3, $5)
+        ON CONFLICT (user_id, subscriber_id, channel) DO UPDATE SET sent_at = EXCLUDED.sent_at
+        WHERE notification_digests.sent_at <= $4
+        RETURNING (SELECT sent_at FROM previous)
+    `
+
+	var previous sql.NullTime
+	err := d.DB.QueryRowContext(ctx, query, user_id, subscriber_id, channel, due, now).Scan(&previous)
+	if err == sql.ErrNoRows {
+		return false, nil, nil
+	}
+	if err != nil {
+		return false, nil, fmt.Errorf("error claiming notification digest: %w", err)
+	}
+	if !previous.Valid {
+		return true, nil, nil
+	}
+	return true, &previous.Time, nil
+}
+
+// SelectDigestMentions returns the subscriber's newest search results found
+// after since, at most limit of them, and how many there are in all.
+func (d *Database) SelectDigestMentions(ctx context.Context, subscriber model.Subscriber, since time.Time, limit int) ([]model.DigestMention, int, error) {
+	slog.DebugContext(ctx, "SelectDigestMentions")
+
+	query := fmt.Sprintf(`
+        SELECT COALESCE(search_definition_name, ''), COALESCE(title, ''), COALESCE(link, ''), result_created_at, count(*) OVER ()
+        FROM %s.v_calibrate_search_results
+        WHERE result_created_at > $1
+        ORDER BY result_created_at DESC
+        LIMIT $2
+    `, subscriber.Schema_Name)
+
+	rows, err := d.DB.QueryContext(ctx, query, since, limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing digest mentions: %w", err)
+	}
+	defer rows.Close()
+
+	mentions := []model.DigestMention{}
+	total := 0
+	for rows.Next() {
+		var m model.DigestMention
+		if err := rows.Scan(&m.Search, &m.Title, &m.Link, &m.Created_At, &total); err != nil {
+			return nil, 0, fmt.Errorf("error listing digest mentions: %w", err)
+		}
+		mentions = append(mentions, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error listing digest mentions: %w", err)
+	}
+
+	return mentions, total, nil
+}
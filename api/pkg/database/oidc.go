@@ -0,0 +1,216 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/lib/pq"
+)
+
+// GetSubscriberOIDC returns the subscriber's OpenID provider, or nil when it
+// has none.
+func (d *Database) GetSubscriberOIDC(ctx context.Context, subscriber_id string) (*model.SubscriberOIDC, error) {
+	slog.DebugContext(ctx, "GetSubscriberOIDC")
+
+	query := `
+        SELECT subscriber_id, issuer, client_id, client_secret_name, allowed_domains,
+            jit_provisioning, COALESCE(default_role_id, ''), enabled, created_at, updated_at
+        FROM subscriber_oidc
+        WHERE subscriber_id = $1
+    `
+
+	var cfg model.SubscriberOIDC
+	err := d.DB.QueryRowContext(ctx, query, subscriber_id).Scan(
+		&cfg.Subscriber_Id,
+		&cfg.Issuer,
+		&cfg.Client_Id,
+		&cfg.Client_Secret_Name,
+		pq.Array(&cfg.Allowed_Domains),
+		&cfg.JIT_Provisioning,
+		&cfg.Default_Role_Id,
+		&cfg.Enabled,
+		&cfg.Created_At,
+		&cfg.Updated_At,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting subscriber oidc: %w", err)
+	}
+
+	return &cfg, nil
+}
+
+// SaveSubscriberOIDC creates or replaces the subscriber's OpenID provider.
+func (d *Database) SaveSubscriberOIDC(ctx context.Context, cfg *model.SubscriberOIDC) error {
+	slog.DebugContext(ctx, "SaveSubscriberOIDC")
+
+	query := `
+        INSERT INTO subscriber_oidc (subscriber_id, issuer, client_id, client_secret_name, allowed_domains,
+            jit_provisioning, default_role_id, enabled)
+        VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8)
+        ON CONFLICT (subscriber_id) DO UPDATE SET
+            issuer = EXCLUDED.issuer,
+            client_id = EXCLUDED.client_id,
+            client_secret_name = EXCLUDED.client_secret_name,
+            allowed_domains = EXCLUDED.allowed_domains,
+            jit_provisioning = EXCLUDED.jit_provisioning,
+            default_role_id = EXCLUDED.default_role_id,
+            enabled = EXCLUDED.enabled,
+            updated_at = CURRENT_TIMESTAMP
+    `
+
+	_, err := d.DB.ExecContext(ctx, query,
+		cfg.Subscriber_Id,
+		cfg.Issuer,
+		cfg.Client_Id,
+		cfg.Client_Secret_Name,
+		pq.Array(cfg.Allowed_Domains),
+		cfg.JIT_Provisioning,
+		cfg.Default_Role_Id,
+		cfg.Enabled,
+	)
+	if err != nil {
+		return fmt.Errorf("error saving subscriber oidc: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteSubscriberOIDC removes the subscriber's OpenID provider. The
+// identities linked through it are kept, should it be set up again.
+func (d *Database) DeleteSubscriberOIDC(ctx context.Context, subscriber_id string) error {
+	slog.DebugContext(ctx, "DeleteSubscriberOIDC")
+
+	if _, err := d.DB.ExecContext(ctx, `DELETE FROM subscriber_oidc WHERE subscriber_id = $1`, subscriber_id); err != nil {
+		return fmt.Errorf("error deleting subscriber oidc: %w", err)
+	}
+
+	return nil
+}
+
+// CreateOIDCState stores a sign-on that has been sent to the provider, and
+// clears those that expired without coming back.
+func (d *Database) CreateOIDCState(ctx context.Context, state model.OIDCState) error {
+	slog.DebugContext(ctx, "CreateOIDCState")
+
+	if _, err := d.DB.ExecContext(ctx, `DELETE FROM oidc_states WHERE expires_at < CURRENT_TIMESTAMP`); err != nil {
+		return fmt.Errorf("error clearing oidc states: %w", err)
+	}
+
+	query := `
+        INSERT INTO oidc_states (state_hash, subscriber_id, code_verifier, nonce, expires_at)
+        VALUES ($1, $2, $3, $4, $5)
+    `
+
+	_, err := d.DB.ExecContext(ctx, query, state.State_Hash, state.Subscriber_Id, state.Code_Verifier, state.Nonce, state.Expires_At)
+	if err != nil {
+		return fmt.Errorf("error creating oidc state: %w", err)
+	}
+
+	return nil
+}
+
+// UseOIDCState removes and returns the sign-on with the state hash
+// state_hash, so that it completes once. A state that is unknown, expired
+// or already used is ErrNotFound.
+func (d *Database) UseOIDCState(ctx context.Context, state_hash string) (*model.OIDCState, error) {
+	slog.DebugContext(ctx, "UseOIDCState")
+
+	query := `
+        DELETE FROM oidc_states
+        WHERE state_hash = $1 AND expires_at > CURRENT_TIMESTAMP
+        RETURNING state_hash, subscriber_id, code_verifier, nonce, expires_at
+    `
+
+	var state model.OIDCState
+	err := d.DB.QueryRowContext(ctx, query, state_hash).Scan(
+		&state.State_Hash, &state.Subscriber_Id, &state.Code_Verifier, &state.Nonce, &state.Expires_At,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error using oidc state: %w", err)
+	}
+
+	return &state, nil
+}
+
+// GetUserIdentity returns the identity of subject at issuer, or nil when no
+// user is linked to it.
+func (d *Database) GetUserIdentity(ctx context.Context, issuer string, subject string) (*model.UserIdentity, error) {
+	slog.DebugContext(ctx, "GetUserIdentity")
+
+	query := `
+        SELECT id, user_id, subscriber_id, issuer, subject, email, created_at, last_login_at
+        FROM user_identities
+        WHERE issuer = $1 AND subject = $2
+    `
+
+	var identity model.UserIdentity
+	err := d.DB.QueryRowContext(ctx, query, issuer, subject).Scan(
+		&identity.Id,
+		&identity.User_Id,
+		&identity.Subscriber_Id,
+		&identity.Issuer,
+		&identity.Subject,
+		&identity.Email,
+		&identity.Created_At,
+		&identity.Last_Login_At,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting user identity: %w", err)
+	}
+
+	return &identity, nil
+}
+
+// CreateUserIdentity links a user to their identity at a provider.
+func (d *Database) CreateUserIdentity(ctx context.Context, identity *model.UserIdentity) (*model.UserIdentity, error) {
+	slog.DebugContext(ctx, "CreateUserIdentity")
+
+	identity.Id = uuid.New().String()
+
+	query := `
+        INSERT INTO user_identities (id, user_id, subscriber_id, issuer, subject, email, last_login_at)
+        VALUES ($1, $2, $3, $4, $5, $6, CURRENT_TIMESTAMP)
+        RETURNING created_at, last_login_at
+    `
+
+	err := d.DB.QueryRowContext(ctx, query,
+		identity.Id,
+		identity.User_Id,
+		identity.Subscriber_Id,
+		identity.Issuer,
+		identity.Subject,
+		identity.Email,
+	).Scan(&identity.Created_At, &identity.Last_Login_At)
+	if err != nil {
+		return nil, fmt.Errorf("error creating user identity: %w", err)
+	}
+
+	return identity, nil
+}
+
+// RecordIdentityLogin notes a login through the identity, with the email
+// the provider now gives.
+func (d *Database) RecordIdentityLogin(ctx context.Context, id string, email string) error {
+	slog.DebugContext(ctx, "RecordIdentityLogin")
+
+	query := `UPDATE user_identities SET email = $2, last_login_at = CURRENT_TIMESTAMP WHERE id = $1`
+
+	if _, err := d.DB.ExecContext(ctx, query, id, email); err != nil {
+		return fmt.Errorf("error recording identity login: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// CreatePasswordReset stores a reset token for the user, by the hash of the
+// token, and withdraws any the user has not used yet.
+func (d *Database) CreatePasswordReset(ctx context.Context, user_id string, token_hash string, expires_at time.Time) error {
+	slog.DebugContext(ctx, "CreatePasswordReset")
+
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error creating password reset: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `UPDATE password_resets SET used_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND used_at IS NULL`, user_id)
+	if err != nil {
+		return fmt.Errorf("error withdrawing password resets: %w", err)
+	}
+
+	query := `
+        INSERT INTO password_resets (token_hash, user_id, expires_at)
+        VALUES ($1, $2, $3)
+    `
+
+	if _, err := tx.ExecContext(ctx, query, token_hash, user_id, expires_at); err != nil {
+		return fmt.Errorf("error creating password reset: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// GetPasswordReset returns the user of the reset token with the hash
+// token_hash without using it, or "" when the token is unknown, expired or
+// already used.
+func (d *Database) GetPasswordReset(ctx context.Context, token_hash string) (string, error) {
+	query := `
+        SELECT user_id FROM password_resets
+        WHERE token_hash = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+    `
+
+	var user_id string
+	err := d.DB.QueryRowContext(ctx, query, token_hash).Scan(&user_id)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("error getting password reset: %w", err)
+	}
+
+	return user_id, nil
+}
+
+// UsePasswordReset marks the reset token with the hash token_hash used and
+// returns its user. A token that is unknown, expired or already used is
+// ErrNotFound.
+func (d *Database) UsePasswordReset(ctx context.Context, token_hash string) (string, error) {
+	slog.DebugContext(ctx, "UsePasswordReset")
+
+	query := `
+        UPDATE password_resets SET used_at = CURRENT_TIMESTAMP
+        WHERE token_hash = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+        RETURNING user_id
+    `
+
+	var user_id string
+	err := d.DB.QueryRowContext(ctx, query, token_hash).Scan(&user_id)
+	if err == sql.ErrNoRows {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("error using password reset: %w", err)
+	}
+
+	return user_id, nil
+}
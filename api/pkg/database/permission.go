@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,7 +13,7 @@ import (
 
 // Permission
 func (d *Database) GetPermission(ctx context.Context, id string) (*model.Permission, error) {
-	fmt.Println("d GetPermission")
+	slog.DebugContext(ctx, "GetPermission")
 
 	var permission model.Permission
 
@@ -32,7 +33,7 @@ func (d *Database) GetPermission(ctx context.Context, id string) (*model.Permiss
 }
 
 func (d *Database) CreatePermission(ctx context.Context, name string, description string, object_id string) (*model.Permission, error) {
-	fmt.Println("d CreatePermission")
+	slog.DebugContext(ctx, "CreatePermission")
 
 	permission := &model.Permission{
 		Id:          uuid.New().String(),
@@ -61,64 +62,67 @@ func (d *Database) CreatePermission(ctx context.Context, name string, descriptio
 
 }
 
-func (d *Database) SelectPermissions(ctx context.Context, limit, offset int) ([]model.Permission, error) {
+// PermissionList is how permissions may be listed.
+var PermissionList = ListSpec{
+	Sorts:       map[string]string{"name": "name", "created_at": "created_at"},
+	Filters:     map[string]string{"name": "name", "description": "description", "object_id": "object_id"},
+	DefaultSort: "name",
+	Keys:        []string{"id"},
+}
 
-	fmt.Println("database.go SelectPermissions()")
+// PermissionViewList is how permissions with their objects may be listed.
+var PermissionViewList = ListSpec{
+	Sorts:       map[string]string{"name": "name", "v_object_name": "object_name"},
+	Filters:     map[string]string{"name": "name", "object_id": "object_id", "v_object_name": "object_name", "v_object_type": "object_type"},
+	DefaultSort: "name",
+	Keys:        []string{"id"},
+}
 
-	rows, err := d.DB.QueryContext(ctx,
-		"SELECT id, name, description, object_id, created_at FROM permissions ORDER BY name ASC LIMIT $1 OFFSET $2",
-		limit, offset,
-	)
-	if err != nil {
-		fmt.Println(err.Error())
-		return nil, fmt.Errorf("error selecting permissions: %w", err)
-	}
-	defer rows.Close()
+func (d *Database) SelectPermissions(ctx context.Context, q ListQuery) (*Page[model.Permission], error) {
 
-	var permissions []model.Permission
-	for rows.Next() {
-		var permission model.Permission
-		if err := rows.Scan(&permission.Id, &permission.Name, &permission.Description, &permission.Object_Id, &permission.CreatedAt); err != nil {
-			fmt.Println(err.Error())
-			return nil, fmt.Errorf("error scanning customer: %w", err)
-		}
+	slog.DebugContext(ctx, "database.go SelectPermissions")
 
-		permissions = append(permissions, permission)
+	page, err := selectList(ctx, d.DB, list{
+		Spec:    PermissionList,
+		Query:   q,
+		Columns: []string{"id", "name", "description", "object_id", "created_at"},
+		From:    "permissions",
+	}, func(scan func(...any) error) (model.Permission, error) {
+		var permission model.Permission
+		err := scan(&permission.Id, &permission.Name, &permission.Description, &permission.Object_Id, &permission.CreatedAt)
+		return permission, err
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "SelectPermissions", "error", err)
+		return nil, fmt.Errorf("error selecting permissions: %w", err)
 	}
-	return permissions, nil
+	return page, nil
 }
 
-func (d *Database) SelectPermissions_View(ctx context.Context, limit, offset int) ([]model.Permission_View, error) {
+func (d *Database) SelectPermissions_View(ctx context.Context, q ListQuery) (*Page[model.Permission_View], error) {
 
-	fmt.Println("database.go SelectPermissions_View")
+	slog.DebugContext(ctx, "database.go SelectPermissions_View")
 
-	rows, err := d.DB.QueryContext(ctx,
-		"SELECT id, name, description, object_id, object_name, object_description, object_type FROM permissions_view ORDER BY name ASC LIMIT $1 OFFSET $2",
-		limit, offset,
-	)
+	page, err := selectList(ctx, d.DB, list{
+		Spec:    PermissionViewList,
+		Query:   q,
+		Columns: []string{"id", "name", "description", "object_id", "object_name", "object_description", "object_type"},
+		From:    "permissions_view",
+	}, func(scan func(...any) error) (model.Permission_View, error) {
+		var permission_view model.Permission_View
+		err := scan(&permission_view.Id, &permission_view.Name, &permission_view.Description,
+			&permission_view.Object_Id, &permission_view.V_Object_Name, &permission_view.V_Object_Description, &permission_view.V_Object_Type)
+		return permission_view, err
+	})
 	if err != nil {
-		fmt.Println(err.Error())
+		slog.ErrorContext(ctx, "SelectPermissions_View", "error", err)
 		return nil, fmt.Errorf("error selecting permissions_view: %w", err)
 	}
-	defer rows.Close()
-
-	var permissions_view []model.Permission_View
-	for rows.Next() {
-		var permission_view model.Permission_View
-		if err := rows.Scan(&permission_view.Id, &permission_view.Name, &permission_view.Description,
-			&permission_view.Object_Id, &permission_view.V_Object_Name, &permission_view.V_Object_Description, &permission_view.V_Object_Type); err != nil {
-
-			fmt.Println(err.Error())
-			return nil, fmt.Errorf("error scanning customer: %w", err)
-		}
-
-		permissions_view = append(permissions_view, permission_view)
-	}
-	return permissions_view, nil
+	return page, nil
 }
 
 func (d *Database) UpdatePermission(ctx context.Context, permission *model.Permission) error {
-	fmt.Println("d UpdatePermission")
+	slog.DebugContext(ctx, "UpdatePermission")
 
 	query := `UPDATE permissions SET name = $1, description = $2 WHERE id = $3`
 
@@ -128,7 +132,7 @@ func (d *Database) UpdatePermission(ctx context.Context, permission *model.Permi
 }
 
 func (d *Database) DeletePermission(ctx context.Context, id string) error {
-	fmt.Println("d DeletePermission")
+	slog.DebugContext(ctx, "DeletePermission")
 
 	query := `DELETE FROM permissions WHERE id = $1`
 
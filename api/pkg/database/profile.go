@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,7 +13,7 @@ import (
 
 /*
 func (d *Database) GetProfile(ctx context.Context, id string) (*model.Profile, error) {
-	fmt.Println("d GetProfile")
+	slog.DebugContext(ctx, "GetProfile")
 
 	var profile model.Profile
 
@@ -33,7 +34,7 @@ func (d *Database) GetProfile(ctx context.Context, id string) (*model.Profile, e
 */
 
 func (d *Database) GetProfile(ctx context.Context, subscriber *model.Subscriber) (*model.Profile, error) {
-	fmt.Println("d GetProfile")
+	slog.DebugContext(ctx, "GetProfile")
 
 	var profile model.Profile
 
@@ -53,7 +54,7 @@ func (d *Database) GetProfile(ctx context.Context, subscriber *model.Subscriber)
 		return nil, nil
 	}
 	if err != nil {
-		fmt.Println(err.Error())
+		slog.ErrorContext(ctx, "GetProfile", "error", err)
 		return nil, fmt.Errorf("error getting profile: %w", err)
 	}
 
@@ -61,7 +62,7 @@ func (d *Database) GetProfile(ctx context.Context, subscriber *model.Subscriber)
 }
 
 func (d *Database) CreateProfile(ctx context.Context, subscriber model.Subscriber, profile model.Profile) (*model.Profile, error) {
-	fmt.Println("d CreateProfile")
+	slog.DebugContext(ctx, "CreateProfile")
 
 	profile.Id = uuid.New().String()
 	profile.CreatedAt = time.Now()
@@ -84,35 +85,37 @@ func (d *Database) CreateProfile(ctx context.Context, subscriber model.Subscribe
 
 }
 
-func (d *Database) SelectProfiles(ctx context.Context, limit, offset int) ([]model.Profile, error) {
+// ProfileList is how profiles may be listed.
+var ProfileList = ListSpec{
+	Sorts:       map[string]string{"created_at": "created_at"},
+	Filters:     map[string]string{"parentid": "parent_id", "created_at": "created_at"},
+	DefaultSort: "created_at",
+	Keys:        []string{"id"},
+}
 
-	fmt.Println("database.go SelectProfiles()")
+func (d *Database) SelectProfiles(ctx context.Context, q ListQuery) (*Page[model.Profile], error) {
 
-	rows, err := d.DB.QueryContext(ctx,
-		"SELECT id, parent_id, created_at, modified_at FROM profiles ORDER BY created_at LIMIT $1 OFFSET $2",
-		limit, offset,
-	)
-	if err != nil {
-		fmt.Println(err.Error())
-		return nil, fmt.Errorf("error listing profiles: %w", err)
-	}
-	defer rows.Close()
+	slog.DebugContext(ctx, "database.go SelectProfiles")
 
-	var profiles []model.Profile
-	for rows.Next() {
+	page, err := selectList(ctx, d.DB, list{
+		Spec:    ProfileList,
+		Query:   q,
+		Columns: []string{"id", "parent_id", "created_at", "modified_at"},
+		From:    "profiles",
+	}, func(scan func(...any) error) (model.Profile, error) {
 		var profile model.Profile
-		if err := rows.Scan(&profile.Id, &profile.Subscriber_Id, &profile.CreatedAt, profile.ModifiedAt); err != nil {
-			fmt.Println(err.Error())
-			return nil, fmt.Errorf("error scanning profile: %w", err)
-		}
-
-		profiles = append(profiles, profile)
+		err := scan(&profile.Id, &profile.Subscriber_Id, &profile.CreatedAt, &profile.ModifiedAt)
+		return profile, err
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "SelectProfiles", "error", err)
+		return nil, fmt.Errorf("error listing profiles: %w", err)
 	}
-	return profiles, nil
+	return page, nil
 }
 
 func (d *Database) UpdateProfile(ctx context.Context, subscriber *model.Subscriber, profile *model.Profile) error {
-	fmt.Println("d UpdateProfile")
+	slog.DebugContext(ctx, "UpdateProfile")
 
 	query := fmt.Sprintf(`UPDATE %s.profile SET parent_id=$1, legal_name=$2, phone=$3, fax=$4, email=$5, website=$6, linkedin=$7, facebook=$8, instagram=$9, x=$10,
 			youtube=$11, pinterest=$12, google_business=$13, yelp=$14, glassdoor=$15, github=$16, nextdoor=$17, bizapedia=$18 WHERE id=$19`, subscriber.Schema_Name)
@@ -125,7 +128,7 @@ func (d *Database) UpdateProfile(ctx context.Context, subscriber *model.Subscrib
 }
 
 func (d *Database) DeleteProfile(ctx context.Context, id string) error {
-	fmt.Println("d DeleteProfile")
+	slog.DebugContext(ctx, "DeleteProfile")
 
 	query := `DELETE FROM profiles WHERE id = $1`
 
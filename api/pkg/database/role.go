@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,7 +14,7 @@ import (
 //Roles
 
 func (d *Database) SelectRolesByUser(ctx context.Context, userId string) (model.Roles, error) {
-	fmt.Println("d SelectRolesByUser")
+	slog.DebugContext(ctx, "SelectRolesByUser")
 
 	var roles = model.Roles{}
 
@@ -38,35 +39,37 @@ func (d *Database) SelectRolesByUser(ctx context.Context, userId string) (model.
 	return roles, err
 }
 
-func (d *Database) SelectRoles(ctx context.Context, limit, offset int) ([]model.Role, error) {
-	fmt.Println("d SelectRoles")
+// RoleList is how roles may be listed.
+var RoleList = ListSpec{
+	Sorts:       map[string]string{"name": "name"},
+	Filters:     map[string]string{"name": "name"},
+	DefaultSort: "name",
+	Keys:        []string{"id"},
+}
 
-	rows, err := d.DB.QueryContext(ctx,
-		"SELECT id, name FROM roles ORDER BY name ASC LIMIT $1 OFFSET $2",
-		limit, offset,
-	)
-	if err != nil {
-		fmt.Println(err.Error())
-		return nil, fmt.Errorf("error listing items: %w", err)
-	}
-	defer rows.Close()
+func (d *Database) SelectRoles(ctx context.Context, q ListQuery) (*Page[model.Role], error) {
+	slog.DebugContext(ctx, "SelectRoles")
 
-	var roles []model.Role
-	for rows.Next() {
+	page, err := selectList(ctx, d.DB, list{
+		Spec:    RoleList,
+		Query:   q,
+		Columns: []string{"id", "name"},
+		From:    "roles",
+	}, func(scan func(...any) error) (model.Role, error) {
 		var role model.Role
-		if err := rows.Scan(&role.Id, &role.Name); err != nil {
-			fmt.Println(err.Error())
-			return nil, fmt.Errorf("error scanning user: %w", err)
-		}
-
-		roles = append(roles, role)
+		err := scan(&role.Id, &role.Name)
+		return role, err
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "SelectRoles", "error", err)
+		return nil, fmt.Errorf("error listing roles: %w", err)
 	}
 
-	return roles, err
+	return page, nil
 }
 
 func (d *Database) GetRole(ctx context.Context, id string) (*model.Role, error) {
-	fmt.Println("d GetRole")
+	slog.DebugContext(ctx, "GetRole")
 
 	var role model.Role
 
@@ -86,7 +89,7 @@ func (d *Database) GetRole(ctx context.Context, id string) (*model.Role, error)
 }
 
 func (d *Database) UpdateRole(ctx context.Context, role *model.Role) error {
-	fmt.Println("d UpdateRole")
+	slog.DebugContext(ctx, "UpdateRole")
 
 	query := `UPDATE roles SET name = $1 WHERE id = $2`
 
@@ -96,7 +99,7 @@ func (d *Database) UpdateRole(ctx context.Context, role *model.Role) error {
 }
 
 func (d *Database) CreateRole(ctx context.Context, name string) (*model.Role, error) {
-	fmt.Println("d CreateRole")
+	slog.DebugContext(ctx, "CreateRole")
 
 	role := &model.Role{
 		Id:        uuid.New().String(),
@@ -117,7 +120,7 @@ func (d *Database) CreateRole(ctx context.Context, name string) (*model.Role, er
 }
 
 func (d *Database) DeleteRole(ctx context.Context, id string) error {
-	fmt.Println("d DeleteRole")
+	slog.DebugContext(ctx, "DeleteRole")
 
 	query := `DELETE FROM roles WHERE id = $1`
 
@@ -3,35 +3,38 @@ package database
 import (
 	"context"
 	"fmt"
+	"log/slog"
 
 	"github.com/htstinson/stinsondataapi/api/internal/model"
 )
 
-func (d *Database) SelectRolePermissionsView(ctx context.Context, limit, offset int) ([]model.Role_Permission_View, error) {
-	fmt.Println("database.go SelectRolePermissionsView()")
+// RolePermissionViewList is how the permissions of roles may be listed.
+var RolePermissionViewList = ListSpec{
+	Sorts:       map[string]string{"v_role_name": "role_name", "v_permission_name": "permission_name", "created_at": "created_at"},
+	Filters:     map[string]string{"role_id": "role_id", "permission_id": "permission_id", "object_id": "object_id", "v_role_name": "role_name", "v_object_type": "object_type"},
+	DefaultSort: "v_role_name",
+	Keys:        []string{"role_id", "permission_id"},
+}
 
-	rows, err := d.DB.QueryContext(ctx,
-		"SELECT role_id, role_name, permission_id, permission_name, object_id, object_name, object_type, created_at FROM role_permissions_view ORDER BY Role_name, permission_name, object_name ASC LIMIT $1 OFFSET $2",
-		limit, offset,
-	)
-	if err != nil {
-		fmt.Println(err.Error())
-		return nil, fmt.Errorf("error listing rows: %w", err)
-	}
-	defer rows.Close()
+func (d *Database) SelectRolePermissionsView(ctx context.Context, q ListQuery) (*Page[model.Role_Permission_View], error) {
+	slog.DebugContext(ctx, "database.go SelectRolePermissionsView")
 
-	var role_permissions_view []model.Role_Permission_View
-	for rows.Next() {
+	page, err := selectList(ctx, d.DB, list{
+		Spec:    RolePermissionViewList,
+		Query:   q,
+		Columns: []string{"role_id", "role_name", "permission_id", "permission_name", "object_id", "object_name", "object_type", "created_at"},
+		From:    "role_permissions_view",
+	}, func(scan func(...any) error) (model.Role_Permission_View, error) {
 		var role_permission_view model.Role_Permission_View
-		if err := rows.Scan(&role_permission_view.Role_Id, &role_permission_view.V_Role_Name,
+		err := scan(&role_permission_view.Role_Id, &role_permission_view.V_Role_Name,
 			&role_permission_view.Permission_Id, &role_permission_view.V_Permission_Name,
 			&role_permission_view.Object_Id, &role_permission_view.V_Object_Name, &role_permission_view.V_Object_Type,
-			&role_permission_view.CreatedAt); err != nil {
-			fmt.Println(err.Error())
-			return nil, fmt.Errorf("error scanning user_permission: %w", err)
-		}
-
-		role_permissions_view = append(role_permissions_view, role_permission_view)
+			&role_permission_view.CreatedAt)
+		return role_permission_view, err
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "SelectRolePermissionsView", "error", err)
+		return nil, fmt.Errorf("error listing rows: %w", err)
 	}
-	return role_permissions_view, nil
+	return page, nil
 }
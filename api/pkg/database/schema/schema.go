@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"log/slog"
 	"strings"
 )
 
@@ -16,7 +17,7 @@ type Schema struct {
 // CopySchema creates a new schema with the specified name and copies all tables,
 // sequences, functions, and data from the source schema to the new schema.
 func (schema *Schema) CopySchema(ctx context.Context) error {
-	fmt.Println("S CopySchema")
+	slog.DebugContext(ctx, "CopySchema")
 	// Copying from schema '%s' to new schema '%s'\n", schema.FromSchemaName, schema.ToSchemaName
 
 	// Step 1: Create the new schema
@@ -73,7 +74,7 @@ func (schema *Schema) CopySchema(ctx context.Context) error {
 		return err
 	}
 
-	fmt.Printf("Schema '%s' successfully created and populated\n", schema.ToSchemaName)
+	slog.InfoContext(ctx, "Schema successfully created and populated", "schema", schema.ToSchemaName)
 	return nil
 }
 
@@ -100,7 +101,7 @@ func (schema *Schema) getTableNames(ctx context.Context) ([]string, error) {
 		return nil, fmt.Errorf("error iterating tables: %w", err)
 	}
 
-	fmt.Printf("Found %d tables in source schema\n", len(tables))
+	slog.InfoContext(ctx, "Found tables in source schema", "count", len(tables))
 	return tables, nil
 }
 
@@ -127,13 +128,13 @@ func (schema *Schema) getSequenceNames(ctx context.Context) ([]string, error) {
 		return nil, fmt.Errorf("error iterating sequences: %w", err)
 	}
 
-	fmt.Printf("Found %d sequences in source schema\n", len(sequences))
+	slog.InfoContext(ctx, "Found sequences in source schema", "count", len(sequences))
 	return sequences, nil
 }
 
 // getViewNames gets all view names from the source schema
 func (schema *Schema) getViewNames(ctx context.Context) ([]string, error) {
-	fmt.Println("s getViewNames")
+	slog.DebugContext(ctx, "getViewNames")
 
 	q := fmt.Sprintf("SELECT table_name FROM information_schema.views WHERE table_schema = '%s'", schema.FromSchemaName)
 	viewRows, err := schema.DB.QueryContext(ctx, q)
@@ -154,13 +155,13 @@ func (schema *Schema) getViewNames(ctx context.Context) ([]string, error) {
 		return nil, fmt.Errorf("error iterating views: %w", err)
 	}
 
-	fmt.Printf("Found %d views in source schema\n", len(views))
+	slog.InfoContext(ctx, "Found views in source schema", "count", len(views))
 	return views, nil
 }
 
 // createStructures creates sequences and tables in the target schema
 func (schema *Schema) createStructures(ctx context.Context, sequences []string, tables []string) error {
-	fmt.Println("s createStructures")
+	slog.DebugContext(ctx, "createStructures")
 
 	// Creating tables and sequences in new schema
 
@@ -179,7 +180,7 @@ func (schema *Schema) createStructures(ctx context.Context, sequences []string,
 
 	// Create sequences first (since tables may depend on them)
 	for _, seqName := range sequences {
-		fmt.Printf("Creating sequence: %s\n", seqName)
+		slog.InfoContext(ctx, "Creating sequence", "sequence", seqName)
 
 		// Check if sequence already exists in target schema
 		var exists bool
@@ -196,7 +197,7 @@ func (schema *Schema) createStructures(ctx context.Context, sequences []string,
 		}
 
 		if exists {
-			fmt.Printf("Sequence %s already exists in target schema, skipping\n", seqName)
+			slog.InfoContext(ctx, "Sequence already exists in target schema, skipping", "sequence", seqName)
 			continue
 		}
 
@@ -227,7 +228,7 @@ func (schema *Schema) createStructures(ctx context.Context, sequences []string,
 
 	// For each table, get its structure and recreate it in the new schema
 	for _, tableName := range tables {
-		fmt.Printf("Processing table: %s\n", tableName)
+		slog.InfoContext(ctx, "Processing table", "table", tableName)
 
 		// Check if table already exists in target schema
 		var exists bool
@@ -244,7 +245,7 @@ func (schema *Schema) createStructures(ctx context.Context, sequences []string,
 		}
 
 		if exists {
-			fmt.Printf("Table %s already exists in target schema, skipping structure creation\n", tableName)
+			slog.InfoContext(ctx, "Table already exists in target schema, skipping structure creation", "table", tableName)
 			continue
 		}
 
@@ -376,7 +377,7 @@ func (schema *Schema) createStructures(ctx context.Context, sequences []string,
 
 // copyTableData copies data from source schema tables to target schema tables
 func (schema *Schema) copyTableData(ctx context.Context, tables []string) error {
-	fmt.Println("s copyTableData")
+	slog.DebugContext(ctx, "copyTableData")
 
 	// Copying data to new schema
 
@@ -394,7 +395,7 @@ func (schema *Schema) copyTableData(ctx context.Context, tables []string) error
 	}()
 
 	for _, tableName := range tables {
-		fmt.Printf("Copying data for table: %s\n", tableName)
+		slog.InfoContext(ctx, "Copying data for table", "table", tableName)
 
 		// Get columns for this table
 		colRows, err := schema.DB.QueryContext(ctx, `
@@ -420,7 +421,7 @@ func (schema *Schema) copyTableData(ctx context.Context, tables []string) error
 		colRows.Close()
 
 		if len(columns) == 0 {
-			fmt.Printf("No columns found for table %s, skipping\n", tableName)
+			slog.InfoContext(ctx, "No columns found for table, skipping", "table", tableName)
 			continue
 		}
 
@@ -435,7 +436,7 @@ func (schema *Schema) copyTableData(ctx context.Context, tables []string) error
 		}
 
 		if count > 0 {
-			fmt.Printf("Target table %s already has %d rows, skipping data copy\n", tableName, count)
+			slog.InfoContext(ctx, "Target table already has rows, skipping data copy", "table", tableName, "rows", count)
 			continue
 		}
 
@@ -446,7 +447,7 @@ func (schema *Schema) copyTableData(ctx context.Context, tables []string) error
 		`, schema.ToSchemaName, tableName, columnList, columnList, schema.FromSchemaName, tableName))
 
 		if err != nil {
-			fmt.Printf("Warning: Failed to copy data for table %s: %v\n", tableName, err)
+			slog.WarnContext(ctx, "Failed to copy data for table", "table", tableName, "error", err)
 			// Continue with other tables instead of failing completely
 		}
 	}
@@ -462,7 +463,7 @@ func (schema *Schema) copyTableData(ctx context.Context, tables []string) error
 
 // createForeignKeys creates foreign key constraints in the target schema
 func (schema *Schema) createForeignKeys(ctx context.Context) error {
-	fmt.Println("s createForeignKeys")
+	slog.DebugContext(ctx, "createForeignKeys")
 
 	// Creating foreign key constraints
 
@@ -532,7 +533,7 @@ func (schema *Schema) createForeignKeys(ctx context.Context) error {
 
 		_, err = tx.ExecContext(ctx, fkSQL)
 		if err != nil {
-			fmt.Printf("Warning: Failed to apply foreign key constraint: %v\n", err)
+			slog.WarnContext(ctx, "Failed to apply foreign key constraint", "error", err)
 			// Continue with other constraints instead of failing completely
 		} else {
 			appliedCount++
@@ -548,13 +549,13 @@ func (schema *Schema) createForeignKeys(ctx context.Context) error {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	fmt.Printf("Successfully applied %d foreign key constraints\n", appliedCount)
+	slog.InfoContext(ctx, "Successfully applied foreign key constraints", "count", appliedCount)
 	return nil
 }
 
 // createIndexes creates indexes in the target schema
 func (schema *Schema) createIndexes(ctx context.Context) error {
-	fmt.Println("Creating indexes")
+	slog.DebugContext(ctx, "Creating indexes")
 
 	// Check if source schema has any indexes
 	var idxCount int
@@ -610,25 +611,25 @@ func (schema *Schema) createIndexes(ctx context.Context) error {
 		// Use a separate transaction for each index
 		tx, err := schema.DB.BeginTx(ctx, nil)
 		if err != nil {
-			fmt.Printf("Warning: Failed to begin transaction for index: %v\n", err)
+			slog.WarnContext(ctx, "Failed to begin transaction for index", "error", err)
 			continue
 		}
 
 		_, err = tx.ExecContext(ctx, idxSQL)
 		if err != nil {
 			tx.Rollback()
-			fmt.Printf("Warning: Failed to create index: %v\n", err)
+			slog.WarnContext(ctx, "Failed to create index", "error", err)
 		} else {
 			err = tx.Commit()
 			if err != nil {
-				fmt.Printf("Warning: Failed to commit index transaction: %v\n", err)
+				slog.WarnContext(ctx, "Failed to commit index transaction", "error", err)
 			} else {
 				appliedCount++
 			}
 		}
 	}
 
-	fmt.Printf("Successfully created %d indexes\n", appliedCount)
+	slog.InfoContext(ctx, "Successfully created indexes", "count", appliedCount)
 	return nil
 }
 
@@ -671,7 +672,7 @@ func (schema *Schema) createViews(ctx context.Context, views []string) error {
 		}
 
 		if exists {
-			fmt.Printf("View %s already exists in target schema, skipping\n", viewName)
+			slog.InfoContext(ctx, "View already exists in target schema, skipping", "view", viewName)
 			continue
 		}
 
@@ -693,7 +694,7 @@ func (schema *Schema) createViews(ctx context.Context, views []string) error {
 		// Create the view
 		_, err = tx.ExecContext(ctx, viewDef)
 		if err != nil {
-			fmt.Printf("Warning: Failed to create view %s: %v\n", viewName, err)
+			slog.WarnContext(ctx, "Failed to create view", "view", viewName, "error", err)
 			// Continue with other views instead of failing completely
 		} else {
 			createdCount++
@@ -705,6 +706,6 @@ func (schema *Schema) createViews(ctx context.Context, views []string) error {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	fmt.Printf("Successfully created %d views\n", createdCount)
+	slog.InfoContext(ctx, "Successfully created views", "count", createdCount)
 	return nil
 }
@@ -3,46 +3,47 @@ package database
 import (
 	"context"
 	"fmt"
+	"log/slog"
 
 	"github.com/htstinson/stinsondataapi/api/internal/model"
 )
 
-func (d *Database) SelectSearchDefinitions(ctx context.Context, subscriber model.Subscriber, limit int, offset int) ([]model.SearchDefinition, error) {
-
-	fmt.Println("d SelectSearchDefinitions")
+// SearchDefinitionList is how the search definitions of a subscriber may be
+// listed.
+var SearchDefinitionList = ListSpec{
+	Sorts:       map[string]string{"name": "name", "created_at": "created_at", "modified_at": "modified_at"},
+	Filters:     map[string]string{"name": "name", "query": "query", "search_type": "search_type", "created_at": "created_at"},
+	DefaultSort: "name",
+	Keys:        []string{"id"},
+}
 
-	query := fmt.Sprintf(`SELECT id, created_at, modified_at, name, comment, query, exact_match, max_results, sort_by_date, start_date, end_date, 
-	search_type, subscriber_id FROM %s.calibrate_search_definition 
-	ORDER BY name ASC LIMIT $1 OFFSET $2`, subscriber.Schema_Name)
+func (d *Database) SelectSearchDefinitions(ctx context.Context, subscriber model.Subscriber, q ListQuery) (*Page[model.SearchDefinition], error) {
 
-	rows, err := d.DB.QueryContext(ctx,
-		query,
-		limit, offset,
-	)
-	if err != nil {
-		fmt.Println(err.Error())
-		return nil, fmt.Errorf("error listing search_definitions: %w", err)
-	}
-	defer rows.Close()
+	slog.DebugContext(ctx, "SelectSearchDefinitions")
 
-	var searchdefinitions []model.SearchDefinition
-	for rows.Next() {
+	page, err := selectList(ctx, d.DB, list{
+		Spec:  SearchDefinitionList,
+		Query: q,
+		Columns: []string{"id", "created_at", "modified_at", "name", "comment", "query", "exact_match", "max_results", "sort_by_date", "start_date", "end_date",
+			"search_type", "subscriber_id"},
+		From: fmt.Sprintf("%s.calibrate_search_definition", subscriber.Schema_Name),
+	}, func(scan func(...any) error) (model.SearchDefinition, error) {
 		var searchdefinition model.SearchDefinition
-		if err := rows.Scan(&searchdefinition.Id, &searchdefinition.CreatedAt, &searchdefinition.ModifiedAt, &searchdefinition.Name, &searchdefinition.Comment,
+		err := scan(&searchdefinition.Id, &searchdefinition.CreatedAt, &searchdefinition.ModifiedAt, &searchdefinition.Name, &searchdefinition.Comment,
 			&searchdefinition.Query, &searchdefinition.ExactMatch, &searchdefinition.MaxResults, &searchdefinition.SortByDate, &searchdefinition.StartDate,
-			&searchdefinition.EndDate, &searchdefinition.SearchType, &searchdefinition.SubscriberId); err != nil {
-			fmt.Println(err.Error())
-			return nil, fmt.Errorf("error scanning search_definition: %w", err)
-		}
-
-		searchdefinitions = append(searchdefinitions, searchdefinition)
+			&searchdefinition.EndDate, &searchdefinition.SearchType, &searchdefinition.SubscriberId)
+		return searchdefinition, err
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "SelectSearchDefinitions", "error", err)
+		return nil, fmt.Errorf("error listing search_definitions: %w", err)
 	}
-	return searchdefinitions, nil
+	return page, nil
 }
 
 func (d *Database) GetSearchDefinition(ctx context.Context, subscriber model.Subscriber, definition_id string, limit int, offset int) (model.SearchDefinition, error) {
 
-	fmt.Println("d GetSearchDefinition")
+	slog.DebugContext(ctx, "GetSearchDefinition")
 
 	query := fmt.Sprintf(`SELECT id, created_at, modified_at, name, comment, query, exact_match, max_results, sort_by_date, start_date, end_date, 
 	search_type, subscriber_id FROM %s.calibrate_search_definition WHERE id='%s'
@@ -55,7 +56,7 @@ func (d *Database) GetSearchDefinition(ctx context.Context, subscriber model.Sub
 		limit, offset,
 	)
 	if err != nil {
-		fmt.Println(err.Error())
+		slog.ErrorContext(ctx, "GetSearchDefinition", "error", err)
 		return searchdefinition, fmt.Errorf("error listing search_definitions: %w", err)
 	}
 	defer rows.Close()
@@ -65,7 +66,7 @@ func (d *Database) GetSearchDefinition(ctx context.Context, subscriber model.Sub
 		if err := rows.Scan(&searchdefinition.Id, &searchdefinition.CreatedAt, &searchdefinition.ModifiedAt, &searchdefinition.Name, &searchdefinition.Comment,
 			&searchdefinition.Query, &searchdefinition.ExactMatch, &searchdefinition.MaxResults, &searchdefinition.SortByDate, &searchdefinition.StartDate,
 			&searchdefinition.EndDate, &searchdefinition.SearchType, &searchdefinition.SubscriberId); err != nil {
-			fmt.Println(err.Error())
+			slog.ErrorContext(ctx, "GetSearchDefinition", "error", err)
 			return searchdefinition, fmt.Errorf("error scanning search_definition: %w", err)
 		}
 
@@ -75,20 +76,20 @@ func (d *Database) GetSearchDefinition(ctx context.Context, subscriber model.Sub
 
 func (d *Database) DeleteSearchDefinition(ctx context.Context, subscriber *model.Subscriber, search_definition_id string) error {
 
-	fmt.Println("d DeleteSearchDefinition")
+	slog.DebugContext(ctx, "DeleteSearchDefinition")
 
 	query := fmt.Sprintf(`DELETE FROM %s.calibrate_search_definition WHERE id = $1`, subscriber.Schema_Name)
 
 	_, err := d.DB.ExecContext(ctx, query, search_definition_id)
 	if err != nil {
-		fmt.Println(err.Error())
+		slog.ErrorContext(ctx, "DeleteSearchDefinition", "error", err)
 	}
 
 	return err
 }
 
 func (d *Database) CreateSearchDefinition(ctx context.Context, subscriber model.Subscriber, row model.SearchDefinition) (*model.SearchDefinition, error) {
-	fmt.Println("d CreateSearchDefinition")
+	slog.DebugContext(ctx, "CreateSearchDefinition")
 
 	table := "calibrate_search_definition"
 	schema_name := subscriber.Schema_Name
@@ -104,7 +105,7 @@ func (d *Database) CreateSearchDefinition(ctx context.Context, subscriber model.
 		row.Id, row.Name, row.Comment, row.Query, row.ExactMatch, row.MaxResults, row.SortByDate, row.StartDate, row.EndDate, row.SearchType, row.SubscriberId)
 
 	if err != nil {
-		fmt.Println(err.Error())
+		slog.ErrorContext(ctx, "CreateSearchDefinition", "error", err)
 		return nil, fmt.Errorf("error creating search definition: %w", err)
 	}
 
@@ -112,7 +113,7 @@ func (d *Database) CreateSearchDefinition(ctx context.Context, subscriber model.
 }
 
 func (d *Database) UpdateSearchDefinition(ctx context.Context, subscriber *model.Subscriber, row model.SearchDefinition) (*model.SearchDefinition, error) {
-	fmt.Println("d UpdateSearchDefinition")
+	slog.DebugContext(ctx, "UpdateSearchDefinition")
 
 	table := "calibrate_search_definition"
 	schema_name := subscriber.Schema_Name
@@ -121,7 +122,7 @@ func (d *Database) UpdateSearchDefinition(ctx context.Context, subscriber *model
 
 	_, err := d.DB.ExecContext(ctx, query, row.Name, row.Query, row.StartDate, row.EndDate, row.Comment, row.Id)
 	if err != nil {
-		fmt.Println(err.Error())
+		slog.ErrorContext(ctx, "UpdateSearchDefinition", "error", err)
 	}
 
 	return &row, err
@@ -3,82 +3,74 @@ package database
 import (
 	"context"
 	"fmt"
+	"log/slog"
 
 	"github.com/htstinson/stinsondataapi/api/internal/model"
 )
 
-func (d *Database) SelectSearchDefinitionEnginesView(ctx context.Context, search_definition model.SearchDefinition, limit, offset int) ([]model.SearchDefinitionEnginesView, error) {
-	fmt.Println("d SelectSearchDefinitionEnginesView")
+// SearchDefinitionEngineList is how the engines of search definitions may be
+// listed.
+var SearchDefinitionEngineList = ListSpec{
+	Sorts:       map[string]string{"search_engine_name": "search_engine_name", "search_definition_name": "search_definition_name", "created_at": "created_at"},
+	Filters:     map[string]string{"search_engine_name": "search_engine_name", "search_definition_name": "search_definition_name", "engine_id": "engine_id", "definition_id": "definition_id"},
+	DefaultSort: "search_engine_name",
+	Keys:        []string{"id"},
+}
+
+var searchDefinitionEngineColumns = []string{"id", "created_at", "modified_at", "search_engine_Id", "search_engine_name", "search_definition_name",
+	"search_query", "engine_id", "definition_id"}
+
+func scanSearchDefinitionEngine(scan func(...any) error) (model.SearchDefinitionEnginesView, error) {
+	var row model.SearchDefinitionEnginesView
+	err := scan(&row.Id, &row.CreatedAt, &row.ModifiedAt, &row.SearchEngineId, &row.SearchEngineName,
+		&row.SearchDefinitionName, &row.SearchQuery, &row.EngineId, &row.DefinitionId)
+	return row, err
+}
+
+func (d *Database) SelectSearchDefinitionEnginesView(ctx context.Context, search_definition model.SearchDefinition, q ListQuery) (*Page[model.SearchDefinitionEnginesView], error) {
+	slog.DebugContext(ctx, "SelectSearchDefinitionEnginesView")
 
 	subscriber, err := d.GetSubscriber(ctx, search_definition.SubscriberId)
 	if err != nil {
-		fmt.Println(err.Error())
+		slog.ErrorContext(ctx, "SelectSearchDefinitionEnginesView", "error", err)
 		return nil, err
 	}
 
-	query := fmt.Sprintf(`SELECT id, created_at, modified_at, search_engine_Id, search_engine_name, search_definition_name, 
-		search_query, engine_id, definition_id FROM %s.search_definition_engines_view WHERE definition_id = '%s' 
-		ORDER BY search_engine_name ASC LIMIT $1 OFFSET $2`, subscriber.Schema_Name, search_definition.Id)
-
-	rows, err := d.DB.QueryContext(ctx,
-		query,
-		limit, offset,
-	)
+	page, err := selectList(ctx, d.DB, list{
+		Spec:    SearchDefinitionEngineList,
+		Query:   q,
+		Columns: searchDefinitionEngineColumns,
+		From:    fmt.Sprintf("%s.search_definition_engines_view", subscriber.Schema_Name),
+		Where:   []string{"definition_id = $1"},
+		Args:    []any{search_definition.Id},
+	}, scanSearchDefinitionEngine)
 	if err != nil {
-		fmt.Println(err.Error())
+		slog.ErrorContext(ctx, "SelectSearchDefinitionEnginesView", "error", err)
 		return nil, fmt.Errorf("error listing search_definition_engines_view: %w", err)
 	}
-	defer rows.Close()
-
-	var results []model.SearchDefinitionEnginesView
-	for rows.Next() {
-		var row model.SearchDefinitionEnginesView
-		if err := rows.Scan(&row.Id, &row.CreatedAt, &row.ModifiedAt, &row.SearchEngineId, &row.SearchEngineName,
-			&row.SearchDefinitionName, &row.SearchQuery, &row.EngineId, &row.DefinitionId); err != nil {
-			fmt.Println(err.Error())
-			return nil, fmt.Errorf("error scanning search_definition: %w", err)
-		}
-
-		results = append(results, row)
-	}
 
-	return results, nil
+	return page, nil
 }
 
-func (d *Database) SelectSearchDefinitionEnginesSubscriberView(ctx context.Context, subscriber model.Subscriber, limit, offset int) ([]model.SearchDefinitionEnginesView, error) {
-	fmt.Println("d SelectSearchDefinitionEnginesSubscriberView")
+func (d *Database) SelectSearchDefinitionEnginesSubscriberView(ctx context.Context, subscriber model.Subscriber, q ListQuery) (*Page[model.SearchDefinitionEnginesView], error) {
+	slog.DebugContext(ctx, "SelectSearchDefinitionEnginesSubscriberView")
 
-	query := fmt.Sprintf(`SELECT id, created_at, modified_at, search_engine_Id, search_engine_name, search_definition_name, 
-		search_query, engine_id, definition_id FROM %s.search_definition_engines_view 
-		ORDER BY search_engine_name ASC LIMIT $1 OFFSET $2`, subscriber.Schema_Name)
-
-	rows, err := d.DB.QueryContext(ctx,
-		query,
-		limit, offset,
-	)
+	page, err := selectList(ctx, d.DB, list{
+		Spec:    SearchDefinitionEngineList,
+		Query:   q,
+		Columns: searchDefinitionEngineColumns,
+		From:    fmt.Sprintf("%s.search_definition_engines_view", subscriber.Schema_Name),
+	}, scanSearchDefinitionEngine)
 	if err != nil {
-		fmt.Println(err.Error())
+		slog.ErrorContext(ctx, "SelectSearchDefinitionEnginesSubscriberView", "error", err)
 		return nil, fmt.Errorf("error listing search_definition_engines_view: %w", err)
 	}
-	defer rows.Close()
-
-	var results []model.SearchDefinitionEnginesView
-	for rows.Next() {
-		var row model.SearchDefinitionEnginesView
-		if err := rows.Scan(&row.Id, &row.CreatedAt, &row.ModifiedAt, &row.SearchEngineId, &row.SearchEngineName,
-			&row.SearchDefinitionName, &row.SearchQuery, &row.EngineId, &row.DefinitionId); err != nil {
-			fmt.Println(err.Error())
-			return nil, fmt.Errorf("error scanning search_definition: %w", err)
-		}
-
-		results = append(results, row)
-	}
 
-	return results, nil
+	return page, nil
 }
 
 func (d *Database) GetSearchDefinitionEnginesView(ctx context.Context, subscriber model.Subscriber, search_definitions_engines_id string) (model.SearchDefinitionEnginesView, error) {
-	fmt.Println("d GetSearchDefinitionEnginesView")
+	slog.DebugContext(ctx, "GetSearchDefinitionEnginesView")
 
 	limit := 1
 	offset := 0
@@ -91,16 +83,15 @@ func (d *Database) GetSearchDefinitionEnginesView(ctx context.Context, subscribe
 
 	rows, err := d.DB.QueryContext(ctx, query, search_definitions_engines_id, limit, offset)
 	if err != nil {
-		fmt.Println(err.Error())
+		slog.ErrorContext(ctx, "GetSearchDefinitionEnginesView", "error", err)
 		return row, fmt.Errorf("error selecting search_engine: %w", err)
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var row model.SearchDefinitionEnginesView
 		if err := rows.Scan(&row.Id, &row.CreatedAt, &row.ModifiedAt, &row.SearchEngineId, &row.SearchEngineName,
 			&row.SearchDefinitionName, &row.SearchQuery, &row.EngineId, &row.DefinitionId); err != nil {
-			fmt.Println(err.Error())
+			slog.ErrorContext(ctx, "GetSearchDefinitionEnginesView", "error", err)
 			return row, fmt.Errorf("error scanning search_definition_engines_view: %w", err)
 		}
 
@@ -110,20 +101,20 @@ func (d *Database) GetSearchDefinitionEnginesView(ctx context.Context, subscribe
 
 func (d *Database) DeleteSearchDefinitionEngine(ctx context.Context, subscriber *model.Subscriber, id string) error {
 
-	fmt.Println("d DeleteSearchDefinitionEngine")
+	slog.DebugContext(ctx, "DeleteSearchDefinitionEngine")
 
 	query := fmt.Sprintf(`DELETE FROM %s.search_definition_engines WHERE id = $1`, subscriber.Schema_Name)
 
 	_, err := d.DB.ExecContext(ctx, query, id)
 	if err != nil {
-		fmt.Println(err.Error())
+		slog.ErrorContext(ctx, "DeleteSearchDefinitionEngine", "error", err)
 	}
 
 	return err
 }
 
 func (d *Database) CreateSearchDefinitionEngine(ctx context.Context, subscriber model.Subscriber, row model.SearchDefinitionEngines) (*model.SearchDefinitionEngines, error) {
-	fmt.Println("d CreateSearchDefinitionEngine")
+	slog.DebugContext(ctx, "CreateSearchDefinitionEngine")
 
 	table := "search_definition_engines"
 	schema_name := subscriber.Schema_Name
@@ -135,7 +126,7 @@ func (d *Database) CreateSearchDefinitionEngine(ctx context.Context, subscriber
 		row.Id, row.SearchEngineId, row.SearchDefinitionsId)
 
 	if err != nil {
-		fmt.Println(err.Error())
+		slog.ErrorContext(ctx, "CreateSearchDefinitionEngine", "error", err)
 		return nil, fmt.Errorf("error creating search definition engine: %w", err)
 	}
 
@@ -3,43 +3,42 @@ package database
 import (
 	"context"
 	"fmt"
+	"log/slog"
 
 	"github.com/htstinson/stinsondataapi/api/internal/model"
 )
 
-func (d *Database) SelectSearchEngines(ctx context.Context, subscriber model.Subscriber, limit, offset int) ([]model.SearchEngine, error) {
-	fmt.Println("d SelectSearchEngines")
+// SearchEngineList is how the search engines of a subscriber may be listed.
+var SearchEngineList = ListSpec{
+	Sorts:       map[string]string{"name": "name", "created_at": "created_at"},
+	Filters:     map[string]string{"name": "name", "search_engine_id": "search_engine_id"},
+	DefaultSort: "name",
+	Keys:        []string{"id"},
+}
 
-	query := fmt.Sprintf(`SELECT id, created_at, modified_at, name, search_engine_Id, comment FROM %s.calibrate_search_engines ORDER BY name ASC LIMIT $1 OFFSET $2`, subscriber.Schema_Name)
+func (d *Database) SelectSearchEngines(ctx context.Context, subscriber model.Subscriber, q ListQuery) (*Page[model.SearchEngine], error) {
+	slog.DebugContext(ctx, "SelectSearchEngines")
 
-	rows, err := d.DB.QueryContext(ctx,
-		query,
-		limit, offset,
-	)
+	page, err := selectList(ctx, d.DB, list{
+		Spec:    SearchEngineList,
+		Query:   q,
+		Columns: []string{"id", "created_at", "modified_at", "name", "search_engine_Id", "comment"},
+		From:    fmt.Sprintf("%s.calibrate_search_engines", subscriber.Schema_Name),
+	}, func(scan func(...any) error) (model.SearchEngine, error) {
+		var searchengine model.SearchEngine
+		err := scan(&searchengine.Id, &searchengine.CreatedAt, &searchengine.ModifiedAt, &searchengine.Name, &searchengine.SearchEngineId, &searchengine.Comment)
+		return searchengine, err
+	})
 	if err != nil {
-		fmt.Println(err.Error())
+		slog.ErrorContext(ctx, "SelectSearchEngines", "error", err)
 		return nil, fmt.Errorf("error listing search_engines: %w", err)
 	}
-	defer rows.Close()
 
-	var searchengines []model.SearchEngine
-	for rows.Next() {
-		var searchengine model.SearchEngine
-		if err := rows.Scan(&searchengine.Id, &searchengine.CreatedAt, &searchengine.ModifiedAt, &searchengine.Name, &searchengine.SearchEngineId, &searchengine.Comment); err != nil {
-			fmt.Println(err.Error())
-			return nil, fmt.Errorf("error scanning search_definition: %w", err)
-		}
-
-		fmt.Println(searchengine.Name)
-
-		searchengines = append(searchengines, searchengine)
-	}
-
-	return searchengines, nil
+	return page, nil
 }
 
 func (d *Database) CreateSearchEngine(ctx context.Context, search_engine model.SearchEngine, subscriber model.Subscriber) (*model.SearchEngine, error) {
-	fmt.Println("d CreateSearchEngine")
+	slog.DebugContext(ctx, "CreateSearchEngine")
 
 	table := "calibrate_search_engines"
 	schema_name := subscriber.Schema_Name
@@ -59,7 +58,7 @@ func (d *Database) CreateSearchEngine(ctx context.Context, search_engine model.S
 	)
 
 	if err != nil {
-		fmt.Println(err.Error())
+		slog.ErrorContext(ctx, "CreateSearchEngine", "error", err)
 		return nil, fmt.Errorf("error creating search engine: %w", err)
 	}
 
@@ -68,20 +67,20 @@ func (d *Database) CreateSearchEngine(ctx context.Context, search_engine model.S
 
 func (d *Database) DeleteSearchEngine(ctx context.Context, subscriber *model.Subscriber, search_engine model.SearchEngine) error {
 
-	fmt.Println("d DeleteSearchEngine")
+	slog.DebugContext(ctx, "DeleteSearchEngine")
 
 	query := fmt.Sprintf(`DELETE FROM %s.calibrate_search_engines WHERE id = $1`, subscriber.Schema_Name)
 
 	_, err := d.DB.ExecContext(ctx, query, search_engine.Id)
 	if err != nil {
-		fmt.Println(err.Error())
+		slog.ErrorContext(ctx, "DeleteSearchEngine", "error", err)
 	}
 
 	return err
 }
 
 func (d *Database) GetSearchEngine(ctx context.Context, subscriber model.Subscriber, search_engine_id string, limit int, offset int) (model.SearchEngine, error) {
-	fmt.Println("d GetSearchEngine")
+	slog.DebugContext(ctx, "GetSearchEngine")
 
 	query := fmt.Sprintf(`SELECT id, created_at, modified_at, name, search_engine_Id, comment 
 	FROM %s.calibrate_search_engines WHERE id = $1 ORDER BY name ASC LIMIT $2 OFFSET $3`, subscriber.Schema_Name)
@@ -90,18 +89,16 @@ func (d *Database) GetSearchEngine(ctx context.Context, subscriber model.Subscri
 
 	rows, err := d.DB.QueryContext(ctx, query, search_engine_id, limit, offset)
 	if err != nil {
-		fmt.Println(err.Error())
+		slog.ErrorContext(ctx, "GetSearchEngine", "error", err)
 		return searchengine, fmt.Errorf("error selecting search_engine: %w", err)
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		if err := rows.Scan(&searchengine.Id, &searchengine.CreatedAt, &searchengine.ModifiedAt, &searchengine.Name, &searchengine.SearchEngineId, &searchengine.Comment); err != nil {
-			fmt.Println(err.Error())
+			slog.ErrorContext(ctx, "GetSearchEngine", "error", err)
 			return searchengine, fmt.Errorf("error scanning search_engine: %w", err)
 		}
-
-		fmt.Println(searchengine.Name)
 	}
 	return searchengine, nil
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/htstinson/stinsondataapi/api/internal/model"
@@ -11,14 +12,14 @@ import (
 
 // subscribers
 func (d *Database) GetSubscriber(ctx context.Context, id string) (*model.Subscriber, error) {
-	fmt.Println("d GetSubscriber")
+	slog.DebugContext(ctx, "GetSubscriber")
 
 	var subscriber model.Subscriber
 
 	err := d.DB.QueryRowContext(ctx,
-		"SELECT id, name, created_at, schema_name FROM subscribers WHERE id = $1",
+		"SELECT id, name, created_at, schema_name, mfa_required FROM subscribers WHERE id = $1",
 		id,
-	).Scan(&subscriber.Id, &subscriber.Name, &subscriber.CreatedAt, &subscriber.Schema_Name)
+	).Scan(&subscriber.Id, &subscriber.Name, &subscriber.CreatedAt, &subscriber.Schema_Name, &subscriber.MFA_Required)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -31,11 +32,11 @@ func (d *Database) GetSubscriber(ctx context.Context, id string) (*model.Subscri
 }
 
 func (d *Database) GetSubscriberByName(ctx context.Context, name string) (*model.Subscriber, error) {
-	fmt.Printf("[%v] [GetSubscriberByName] %s\n", time.Now().Format(time.RFC3339), name)
+	slog.DebugContext(ctx, "GetSubscriberByName", "name", name)
 
 	subscriber := &model.Subscriber{}
 	query := `
-        SELECT id, name, created_at, schema_name FROM subscribers WHERE username = $1
+        SELECT id, name, created_at, schema_name, mfa_required FROM subscribers WHERE username = $1
     `
 
 	err := d.DB.QueryRowContext(ctx, query, name).Scan(
@@ -43,6 +44,7 @@ func (d *Database) GetSubscriberByName(ctx context.Context, name string) (*model
 		&subscriber.Name,
 		&subscriber.CreatedAt,
 		&subscriber.Schema_Name,
+		&subscriber.MFA_Required,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -55,15 +57,16 @@ func (d *Database) GetSubscriberByName(ctx context.Context, name string) (*model
 }
 
 func (d *Database) CreateSubscriber(ctx context.Context, subscriber *model.Subscriber) (*model.Subscriber, error) {
-	fmt.Println("d CreateSubscriber")
+	slog.DebugContext(ctx, "CreateSubscriber")
 
-	query := `INSERT INTO subscribers (id, name, created_at, schema_name) VALUES ($1, $2, $3, $4)`
+	query := `INSERT INTO subscribers (id, name, created_at, schema_name, mfa_required) VALUES ($1, $2, $3, $4, $5)`
 
 	_, err := d.DB.ExecContext(ctx, query,
 		subscriber.Id,
 		subscriber.Name,
 		time.Now(),
 		subscriber.Schema_Name,
+		subscriber.MFA_Required,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("error creating subscriber: %w", err)
@@ -73,72 +76,74 @@ func (d *Database) CreateSubscriber(ctx context.Context, subscriber *model.Subsc
 
 }
 
-func (d *Database) SelectSubscribers(ctx context.Context, limit, offset int) ([]model.Subscriber, error) {
+// SubscriberList is how subscribers may be listed.
+var SubscriberList = ListSpec{
+	Sorts:       map[string]string{"name": "name", "created_at": "created_at"},
+	Filters:     map[string]string{"name": "name", "schema_name": "schema_name", "created_at": "created_at", "mfa_required": "mfa_required"},
+	DefaultSort: "name",
+	Keys:        []string{"id"},
+}
 
-	fmt.Println("d SelectSubscribers()")
+func (d *Database) SelectSubscribers(ctx context.Context, q ListQuery) (*Page[model.Subscriber], error) {
 
-	rows, err := d.DB.QueryContext(ctx,
-		"SELECT id, name, created_at, schema_name FROM subscribers ORDER BY name ASC LIMIT $1 OFFSET $2",
-		limit, offset,
-	)
-	if err != nil {
-		fmt.Println(err.Error())
-		return nil, fmt.Errorf("error listing subscribers: %w", err)
-	}
-	defer rows.Close()
+	slog.DebugContext(ctx, "SelectSubscribers")
 
-	var subscribers []model.Subscriber
-	for rows.Next() {
+	page, err := selectList(ctx, d.DB, list{
+		Spec:    SubscriberList,
+		Query:   q,
+		Columns: []string{"id", "name", "created_at", "schema_name", "mfa_required"},
+		From:    "subscribers",
+	}, func(scan func(...any) error) (model.Subscriber, error) {
 		var subscriber model.Subscriber
-		if err := rows.Scan(&subscriber.Id, &subscriber.Name, &subscriber.CreatedAt, &subscriber.Schema_Name); err != nil {
-			fmt.Println(err.Error())
-			return nil, fmt.Errorf("error scanning subscriber: %w", err)
-		}
-
-		subscribers = append(subscribers, subscriber)
+		err := scan(&subscriber.Id, &subscriber.Name, &subscriber.CreatedAt, &subscriber.Schema_Name, &subscriber.MFA_Required)
+		return subscriber, err
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "SelectSubscribers", "error", err)
+		return nil, fmt.Errorf("error listing subscribers: %w", err)
 	}
-	return subscribers, nil
+	return page, nil
 }
 
 func (d *Database) UpdateSubscriber(ctx context.Context, subscriber *model.Subscriber) error {
-	fmt.Println("d UpdateSubscriber")
+	slog.DebugContext(ctx, "UpdateSubscriber")
 
-	query := `UPDATE subscribers SET name = $1 WHERE id = $2`
+	query := `UPDATE subscribers SET name = $1, mfa_required = $2 WHERE id = $3`
 
-	_, err := d.DB.ExecContext(ctx, query, subscriber.Name, subscriber.Id)
+	_, err := d.DB.ExecContext(ctx, query, subscriber.Name, subscriber.MFA_Required, subscriber.Id)
 
 	return err
 }
 
 func (d *Database) DeleteSubscriber(ctx context.Context, subscriber *model.Subscriber) error {
-	fmt.Println("d DeleteSubscriber")
+	slog.DebugContext(ctx, "DeleteSubscriber")
 
 	query := `DELETE from common.user_subscriber_role where user_subscriber_id in
 				(select id from common.user_subscriber where subscriber_id = $1);`
 	result, err := d.DB.ExecContext(ctx, query, subscriber.Id)
 	if err != nil {
-		fmt.Println(err.Error())
+		slog.ErrorContext(ctx, "DeleteSubscriber", "error", err)
 	}
 
 	query = `DELETE from common.user_subscriber where subscriber_id = $1;`
 	result, err = d.DB.ExecContext(ctx, query, subscriber.Id)
 	if err != nil {
-		fmt.Println(err.Error())
+		slog.ErrorContext(ctx, "DeleteSubscriber", "error", err)
 	}
 
 	query = `DELETE FROM common.subscribers WHERE id = $1`
 	result, err = d.DB.ExecContext(ctx, query, subscriber.Id)
 	if err != nil {
-		fmt.Println(err.Error())
-	} else {
-		fmt.Println(result.RowsAffected())
+		slog.ErrorContext(ctx, "DeleteSubscriber", "error", err)
+	} else if rows, err := result.RowsAffected(); err == nil {
+		slog.DebugContext(ctx, "DeleteSubscriber", "rows_affected", rows)
 	}
 
 	query = fmt.Sprintf(`DROP SCHEMA %s cascade`, subscriber.Schema_Name)
 
 	result, err = d.DB.ExecContext(ctx, query)
 	if err != nil {
-		fmt.Println(err.Error())
+		slog.ErrorContext(ctx, "DeleteSubscriber", "error", err)
 	}
 
 	return err
@@ -4,63 +4,46 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"log/slog"
 
 	"github.com/htstinson/stinsondataapi/api/internal/model"
 )
 
-// Addresses
-func (d *Database) SelectSubscriberAddresses(ctx context.Context, subscriber model.Subscriber,
-	limit int, offset int, sort string, order string) (*[]model.Address, int, error) {
-
-	if order == "" {
-		order = "asc"
-	}
-
-	if sort == "" {
-		sort = "address_use"
-	}
-
-	query := fmt.Sprintf(`SELECT id, created_at, modified_at, 
-		address_type, address_use, street1, street2, po_box, city, state, zip,
-		COUNT(*) OVER() AS total 
-		FROM %s.addresses ORDER BY %s %s LIMIT $1 OFFSET $2`, subscriber.Schema_Name, sort, order)
-
-	rows, err := d.DB.QueryContext(ctx,
-		query,
-		limit,
-		offset,
-	)
-	if err != nil {
-		fmt.Println(err.Error())
-		return nil, 0, fmt.Errorf("error listing addresses: %w", err)
-	}
-	defer rows.Close()
-
-	if err == sql.ErrNoRows {
-		return nil, 0, nil
-	}
+// AddressList is how the addresses of a subscriber may be listed.
+var AddressList = ListSpec{
+	Sorts:       map[string]string{"address_use": "address_use", "address_type": "address_type", "created_at": "created_at", "modified_at": "modified_at"},
+	Filters:     map[string]string{"address_use": "address_use", "address_type": "address_type", "city": "city", "state": "state", "zip": "zip"},
+	DefaultSort: "address_use",
+	Keys:        []string{"id"},
+}
 
-	var total int
-	var addresses []model.Address
-	for rows.Next() {
+// Addresses
+func (d *Database) SelectSubscriberAddresses(ctx context.Context, subscriber model.Subscriber, q ListQuery) (*Page[model.Address], error) {
+
+	page, err := selectList(ctx, d.DB, list{
+		Spec:  AddressList,
+		Query: q,
+		Columns: []string{"id", "created_at", "modified_at",
+			"address_type", "address_use", "street1", "street2", "po_box", "city", "state", "zip"},
+		From: fmt.Sprintf("%s.addresses", subscriber.Schema_Name),
+	}, func(scan func(...any) error) (model.Address, error) {
 		var address model.Address
-		if err := rows.Scan(&address.Id, &address.CreatedAt, &address.ModifiedAt,
-			&address.AddressType, &address.AddressUse, &address.Street1, &address.Street2, &address.POBox, &address.City, &address.State, &address.Zip,
-			&total); err != nil {
-			fmt.Println(err.Error())
-			return nil, 0, fmt.Errorf("error scanning address: %w", err)
-		}
+		err := scan(&address.Id, &address.CreatedAt, &address.ModifiedAt,
+			&address.AddressType, &address.AddressUse, &address.Street1, &address.Street2, &address.POBox, &address.City, &address.State, &address.Zip)
 
 		address.SubscriberId = subscriber.Id
-
-		addresses = append(addresses, address)
+		return address, err
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "SelectSubscriberAddresses", "error", err)
+		return nil, fmt.Errorf("error listing addresses: %w", err)
 	}
 
-	return &addresses, total, nil
+	return page, nil
 }
 
 func (d *Database) GetSubscriberAddress(ctx context.Context, subscriber_schema_name string, address_id string) (*model.Address, error) {
-	fmt.Println("d Get Subscriber Address")
+	slog.DebugContext(ctx, "Get Subscriber Address")
 
 	query := fmt.Sprintf(`SELECT id, subscriber_id, created_at, modified_at, 
 		address_type, address_use, street1, street2, po_box, 
@@ -89,7 +72,7 @@ func (d *Database) GetSubscriberAddress(ctx context.Context, subscriber_schema_n
 }
 
 func (d *Database) UpdateSubscriberAddress(ctx context.Context, subscriber *model.Subscriber, address model.Address) error {
-	fmt.Println("d UpdateSubscriberAddress")
+	slog.DebugContext(ctx, "UpdateSubscriberAddress")
 
 	query := fmt.Sprintf(`UPDATE %s.addresses SET 
 	address_type = $1, 
@@ -113,7 +96,7 @@ func (d *Database) UpdateSubscriberAddress(ctx context.Context, subscriber *mode
 		address.Zip,
 		address.Id)
 	if err != nil {
-		fmt.Println(err.Error())
+		slog.ErrorContext(ctx, "UpdateSubscriberAddress", "error", err)
 		return err
 	}
 
@@ -121,7 +104,7 @@ func (d *Database) UpdateSubscriberAddress(ctx context.Context, subscriber *mode
 }
 
 func (d *Database) CreateSubscriberAddress(ctx context.Context, subscriber *model.Subscriber, address model.Address) error {
-	fmt.Println("d Create Subscriber Address")
+	slog.DebugContext(ctx, "Create Subscriber Address")
 
 	query := fmt.Sprintf(`INSERT INTO %s.addresses (subscriber_id, address_type, 
 	address_use, street1, street2, po_box, city, state, zip) 
@@ -138,7 +121,7 @@ func (d *Database) CreateSubscriberAddress(ctx context.Context, subscriber *mode
 		address.State,
 		address.Zip)
 	if err != nil {
-		fmt.Println(err.Error())
+		slog.ErrorContext(ctx, "CreateSubscriberAddress", "error", err)
 		return err
 	}
 
@@ -146,7 +129,7 @@ func (d *Database) CreateSubscriberAddress(ctx context.Context, subscriber *mode
 }
 
 func (d *Database) DeleteSubscriberAddress(ctx context.Context, subscriber_schema_name string, address_id string) error {
-	fmt.Println("d DeleteSubscriberAddress")
+	slog.DebugContext(ctx, "DeleteSubscriberAddress")
 
 	query := fmt.Sprintf(`DELETE FROM %s.addresses WHERE id = $1`, subscriber_schema_name)
 
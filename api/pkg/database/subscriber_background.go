@@ -4,65 +4,48 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"log/slog"
 
 	"github.com/htstinson/stinsondataapi/api/internal/model"
 )
 
-// Background
-func (d *Database) SelectSubscriberBackgrounds(ctx context.Context, subscriber model.Subscriber,
-	limit int, offset int, sort string, order string) (*[]model.Background, int, error) {
-
-	fmt.Println("d SelectSubsriberBackgrounds")
-
-	if order == "" {
-		order = "asc"
-	}
-
-	if sort == "" {
-		sort = "topic"
-	}
-
-	query := fmt.Sprintf(`SELECT id, created_at, modified_at, 
-		topic, summary, details,
-		COUNT(*) OVER() AS total 
-		FROM %s.background ORDER BY %s %s LIMIT $1 OFFSET $2`, subscriber.Schema_Name, sort, order)
+// BackgroundList is how the backgrounds of a subscriber may be listed.
+var BackgroundList = ListSpec{
+	Sorts:       map[string]string{"topic": "topic", "created_at": "created_at", "modified_at": "modified_at"},
+	Filters:     map[string]string{"topic": "topic", "summary": "summary", "details": "details"},
+	DefaultSort: "topic",
+	Keys:        []string{"id"},
+}
 
-	rows, err := d.DB.QueryContext(ctx,
-		query,
-		limit,
-		offset,
-	)
-	if err != nil {
-		fmt.Println(err.Error())
-		return nil, 0, fmt.Errorf("error listing backgrounds: %w", err)
-	}
-	defer rows.Close()
+// Background
+func (d *Database) SelectSubscriberBackgrounds(ctx context.Context, subscriber model.Subscriber, q ListQuery) (*Page[model.Background], error) {
 
-	if err == sql.ErrNoRows {
-		return nil, 0, nil
-	}
+	slog.DebugContext(ctx, "SelectSubsriberBackgrounds")
 
-	var total int
-	var backgrounds []model.Background
-	for rows.Next() {
+	page, err := selectList(ctx, d.DB, list{
+		Spec:  BackgroundList,
+		Query: q,
+		Columns: []string{"id", "created_at", "modified_at",
+			"topic", "summary", "details"},
+		From: fmt.Sprintf("%s.background", subscriber.Schema_Name),
+	}, func(scan func(...any) error) (model.Background, error) {
 		var background model.Background
-		if err := rows.Scan(&background.Id, &background.CreatedAt, &background.ModifiedAt,
-			&background.Topic, &background.Summary, &background.Details,
-			&total); err != nil {
-			fmt.Println(err.Error())
-			return nil, 0, fmt.Errorf("error scanning background: %w", err)
-		}
+		err := scan(&background.Id, &background.CreatedAt, &background.ModifiedAt,
+			&background.Topic, &background.Summary, &background.Details)
 
 		background.SubscriberId = subscriber.Id
-
-		backgrounds = append(backgrounds, background)
+		return background, err
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "SelectSubscriberBackgrounds", "error", err)
+		return nil, fmt.Errorf("error listing backgrounds: %w", err)
 	}
 
-	return &backgrounds, total, nil
+	return page, nil
 }
 
 func (d *Database) GetSubscriberBackground(ctx context.Context, subscriber_schema_name string, background_id string) (*model.Background, error) {
-	fmt.Println("d Get Subscriber Background")
+	slog.DebugContext(ctx, "Get Subscriber Background")
 
 	query := fmt.Sprintf(`SELECT id, subscriber_id, created_at, modified_at, 
 		topic, summary, details FROM %s.background WHERE id = $1`, subscriber_schema_name)
@@ -88,7 +71,7 @@ func (d *Database) GetSubscriberBackground(ctx context.Context, subscriber_schem
 }
 
 func (d *Database) UpdateSubscriberBackground(ctx context.Context, subscriber *model.Subscriber, background model.Background) error {
-	fmt.Println("d UpdateSubscriberBackground")
+	slog.DebugContext(ctx, "UpdateSubscriberBackground")
 
 	query := fmt.Sprintf(`UPDATE %s.background SET 
 	topic = $1, 
@@ -102,7 +85,7 @@ func (d *Database) UpdateSubscriberBackground(ctx context.Context, subscriber *m
 		background.Details,
 		background.Id)
 	if err != nil {
-		fmt.Println(err.Error())
+		slog.ErrorContext(ctx, "UpdateSubscriberBackground", "error", err)
 		return err
 	}
 
@@ -110,7 +93,7 @@ func (d *Database) UpdateSubscriberBackground(ctx context.Context, subscriber *m
 }
 
 func (d *Database) CreateSubscriberBackground(ctx context.Context, subscriber *model.Subscriber, background model.Background) error {
-	fmt.Println("d Create Subscriber Background")
+	slog.DebugContext(ctx, "Create Subscriber Background")
 
 	query := fmt.Sprintf(`INSERT INTO %s.background (subscriber_id, topic, summary, details) 
 	values ($1, $2, $3, $4)`, subscriber.Schema_Name)
@@ -121,7 +104,7 @@ func (d *Database) CreateSubscriberBackground(ctx context.Context, subscriber *m
 		background.Summary,
 		background.Details)
 	if err != nil {
-		fmt.Println(err.Error())
+		slog.ErrorContext(ctx, "CreateSubscriberBackground", "error", err)
 		return err
 	}
 
@@ -129,7 +112,7 @@ func (d *Database) CreateSubscriberBackground(ctx context.Context, subscriber *m
 }
 
 func (d *Database) DeleteSubscriberBackground(ctx context.Context, subscriber_schema_name string, background_id string) error {
-	fmt.Println("d DeleteSubscriberBackground")
+	slog.DebugContext(ctx, "DeleteSubscriberBackground")
 
 	query := fmt.Sprintf(`DELETE FROM %s.background WHERE id = $1`, subscriber_schema_name)
 
@@ -3,56 +3,57 @@ package database
 import (
 	"context"
 	"database/sql"
-	"errors"
 	"fmt"
+	"log/slog"
 
 	"github.com/google/uuid"
 	"github.com/htstinson/stinsondataapi/api/internal/model"
 )
 
-func (d *Database) SelectSubscriberItemView(ctx context.Context, subscriber_id string, limit int, offset int) ([]model.Subscriber_Item_View, error) {
-	fmt.Println("d SelectSubscriberItem")
+// SubscriberItemViewList is how the items of subscribers may be listed.
+var SubscriberItemViewList = ListSpec{
+	Sorts:       map[string]string{"subscriber_name": "subscriber_name", "item_name": "item_name"},
+	Filters:     map[string]string{"item_id": "item_id", "item_name": "item_name", "subscriber_name": "subscriber_name"},
+	DefaultSort: "subscriber_name",
+	Keys:        []string{"id"},
+}
+
+func (d *Database) SelectSubscriberItemView(ctx context.Context, subscriber_id string, q ListQuery) (*Page[model.Subscriber_Item_View], error) {
+	slog.DebugContext(ctx, "SelectSubscriberItem")
 
-	where_clause := " "
+	slog.DebugContext(ctx, "SelectSubscriberItemView", "subscriberid", subscriber_id)
 
-	fmt.Println("SubscriberId", subscriber_id)
+	l := list{
+		Spec:    SubscriberItemViewList,
+		Query:   q,
+		Columns: []string{"id", "item_id", "subscriber_id", "item_name", "subscriber_name"},
+		From:    "subscriber_items_view",
+	}
 
 	if subscriber_id != "" {
 		_, err := ValidateUUID(subscriber_id)
 		if err != nil {
-			fmt.Printf("Invalid UUID error: %v\n", err)
+			slog.WarnContext(ctx, "SelectSubscriberItemView", "error", err)
 			return nil, err
-		} else {
-			// validated
-			where_clause = fmt.Sprintf(` where subscriber_id = '%s' `, subscriber_id)
 		}
+		l.Where = []string{"subscriber_id = $1"}
+		l.Args = []any{subscriber_id}
 	}
 
-	query := fmt.Sprintf("SELECT id, item_id, subscriber_id, item_name, subscriber_name FROM subscriber_items_view%sORDER BY subscriber_name, item_name ASC LIMIT $1 OFFSET $2", where_clause)
-
-	rows, err := d.DB.QueryContext(ctx, query, limit, offset)
+	page, err := selectList(ctx, d.DB, l, func(scan func(...any) error) (model.Subscriber_Item_View, error) {
+		var subscriber_item_view model.Subscriber_Item_View
+		err := scan(&subscriber_item_view.Id, &subscriber_item_view.Item_ID, &subscriber_item_view.Subscriber_Id, &subscriber_item_view.Item_Name, &subscriber_item_view.Subscriber_Name)
+		return subscriber_item_view, err
+	})
 	if err != nil {
-		fmt.Println(err.Error())
+		slog.ErrorContext(ctx, "SelectSubscriberItemView", "error", err)
 		return nil, fmt.Errorf("error listing rows: %w", err)
 	}
-	defer rows.Close()
-
-	var subscriber_item_views []model.Subscriber_Item_View
-	for rows.Next() {
-		var subscriber_item_view model.Subscriber_Item_View
-		if err := rows.Scan(&subscriber_item_view.Id, &subscriber_item_view.Item_ID, &subscriber_item_view.Subscriber_Id, &subscriber_item_view.Item_Name, &subscriber_item_view.Subscriber_Name); err != nil {
-			fmt.Println(err.Error())
-			return nil, fmt.Errorf("error scanning user_subscriber: %w", err)
-		}
-
-		subscriber_item_views = append(subscriber_item_views, subscriber_item_view)
-
-	}
-	return subscriber_item_views, nil
+	return page, nil
 }
 
 func (d *Database) CreateSubscriberItem(ctx context.Context, item_id string, subscriber_id string) (*model.Subscriber_Item, error) {
-	fmt.Println("d CreateSubscriberItem")
+	slog.DebugContext(ctx, "CreateSubscriberItem")
 
 	subscriber_item := &model.Subscriber_Item{
 		Id:            uuid.New().String(),
@@ -77,7 +78,7 @@ func (d *Database) CreateSubscriberItem(ctx context.Context, item_id string, sub
 }
 
 func (d *Database) LookupSubscriberItem(ctx context.Context, item_id string, subscriber_id string) (*model.Subscriber_Item, error) {
-	fmt.Println("d LookupUserSubscriber")
+	slog.DebugContext(ctx, "LookupUserSubscriber")
 
 	var subscriber_item = model.Subscriber_Item{}
 
@@ -87,7 +88,7 @@ func (d *Database) LookupSubscriberItem(ctx context.Context, item_id string, sub
 	).Scan(&subscriber_item.Id, &subscriber_item.Item_ID, &subscriber_item.Subscriber_Id)
 
 	if err == sql.ErrNoRows {
-		return nil, errors.New("not found")
+		return nil, ErrNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("error getting subscriber_item: %w", err)
@@ -97,8 +98,8 @@ func (d *Database) LookupSubscriberItem(ctx context.Context, item_id string, sub
 }
 
 func (d *Database) DeleteSubscriberItem(ctx context.Context, id string) error {
-	fmt.Println("d DeleteSubscriberItem")
-	fmt.Println(id)
+	slog.DebugContext(ctx, "DeleteSubscriberItem")
+	slog.DebugContext(ctx, "DeleteSubscriberItem", "id", id)
 
 	query := `DELETE FROM subscriber_items WHERE id = $1`
 
@@ -108,7 +109,7 @@ func (d *Database) DeleteSubscriberItem(ctx context.Context, id string) error {
 }
 
 func (d *Database) GetSubscriberItem(ctx context.Context, id string) (*model.Subscriber_Item, error) {
-	fmt.Println("d GetSubscriberItem")
+	slog.DebugContext(ctx, "GetSubscriberItem")
 
 	var subscriberitem model.Subscriber_Item
 
@@ -0,0 +1,1252 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/htstinson/stinsondataapi/api/internal/tracing"
+)
+
+// tracedRepository wraps a Repository so that every call made with a context
+// is recorded as a span. Methods without a context pass straight through.
+type tracedRepository struct {
+	Repository
+}
+
+// WithTracing returns repo with a span around each of its methods.
+func WithTracing(repo Repository) Repository {
+	return &tracedRepository{Repository: repo}
+}
+
+func (t *tracedRepository) SelectCalibrateMention(ctx context.Context, subscriber model.Subscriber, search_result_id string, q ListQuery) (*Page[model.CalibrateMention], error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectCalibrateMention")
+	r0, err := t.Repository.SelectCalibrateMention(ctx, subscriber, search_result_id, q)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) CreateSearchResult(ctx context.Context, subscriber model.Subscriber, row model.CalibrateSearchResult) (*model.CalibrateSearchResult, error) {
+	ctx, span := tracing.Start(ctx, "Repository.CreateSearchResult")
+	r0, err := t.Repository.CreateSearchResult(ctx, subscriber, row)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) SelectSearchResultView(ctx context.Context, subscriber model.Subscriber, sde string, q ListQuery) (*Page[model.CalibrateSearchResultView], error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectSearchResultView")
+	r0, err := t.Repository.SelectSearchResultView(ctx, subscriber, sde, q)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) CreateSearchDefinitionEngine(ctx context.Context, subscriber model.Subscriber, row model.SearchDefinitionEngines) (*model.SearchDefinitionEngines, error) {
+	ctx, span := tracing.Start(ctx, "Repository.CreateSearchDefinitionEngine")
+	r0, err := t.Repository.CreateSearchDefinitionEngine(ctx, subscriber, row)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) SelectSearchDefinitionEnginesSubscriberView(ctx context.Context, subscriber model.Subscriber, q ListQuery) (*Page[model.SearchDefinitionEnginesView], error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectSearchDefinitionEnginesSubscriberView")
+	r0, err := t.Repository.SelectSearchDefinitionEnginesSubscriberView(ctx, subscriber, q)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) SelectSearchDefinitionEnginesView(ctx context.Context, search_definition model.SearchDefinition, q ListQuery) (*Page[model.SearchDefinitionEnginesView], error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectSearchDefinitionEnginesView")
+	r0, err := t.Repository.SelectSearchDefinitionEnginesView(ctx, search_definition, q)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) GetSearchDefinitionEnginesView(ctx context.Context, subscriber model.Subscriber, search_definitions_engines_id string) (model.SearchDefinitionEnginesView, error) {
+	ctx, span := tracing.Start(ctx, "Repository.GetSearchDefinitionEnginesView")
+	r0, err := t.Repository.GetSearchDefinitionEnginesView(ctx, subscriber, search_definitions_engines_id)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) DeleteSearchDefinitionEngine(ctx context.Context, subscriber *model.Subscriber, id string) error {
+	ctx, span := tracing.Start(ctx, "Repository.DeleteSearchDefinitionEngine")
+	err := t.Repository.DeleteSearchDefinitionEngine(ctx, subscriber, id)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) SelectSearchDefinitions(ctx context.Context, subscriber model.Subscriber, q ListQuery) (*Page[model.SearchDefinition], error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectSearchDefinitions")
+	r0, err := t.Repository.SelectSearchDefinitions(ctx, subscriber, q)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) CreateSearchDefinition(ctx context.Context, subscriber model.Subscriber, row model.SearchDefinition) (*model.SearchDefinition, error) {
+	ctx, span := tracing.Start(ctx, "Repository.CreateSearchDefinition")
+	r0, err := t.Repository.CreateSearchDefinition(ctx, subscriber, row)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) UpdateSearchDefinition(ctx context.Context, subscriber *model.Subscriber, row model.SearchDefinition) (*model.SearchDefinition, error) {
+	ctx, span := tracing.Start(ctx, "Repository.UpdateSearchDefinition")
+	r0, err := t.Repository.UpdateSearchDefinition(ctx, subscriber, row)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) SelectSearchEngines(ctx context.Context, subscriber model.Subscriber, q ListQuery) (*Page[model.SearchEngine], error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectSearchEngines")
+	r0, err := t.Repository.SelectSearchEngines(ctx, subscriber, q)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) GetSearchDefinition(ctx context.Context, subscriber model.Subscriber, definition_id string, limit int, offset int) (model.SearchDefinition, error) {
+	ctx, span := tracing.Start(ctx, "Repository.GetSearchDefinition")
+	r0, err := t.Repository.GetSearchDefinition(ctx, subscriber, definition_id, limit, offset)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) DeleteSearchDefinition(ctx context.Context, subscriber *model.Subscriber, search_definition_id string) error {
+	ctx, span := tracing.Start(ctx, "Repository.DeleteSearchDefinition")
+	err := t.Repository.DeleteSearchDefinition(ctx, subscriber, search_definition_id)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) CreateSearchEngine(ctx context.Context, search_engine model.SearchEngine, subscriber model.Subscriber) (*model.SearchEngine, error) {
+	ctx, span := tracing.Start(ctx, "Repository.CreateSearchEngine")
+	r0, err := t.Repository.CreateSearchEngine(ctx, search_engine, subscriber)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) DeleteSearchEngine(ctx context.Context, subscriber *model.Subscriber, search_engine model.SearchEngine) error {
+	ctx, span := tracing.Start(ctx, "Repository.DeleteSearchEngine")
+	err := t.Repository.DeleteSearchEngine(ctx, subscriber, search_engine)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) GetSearchEngine(ctx context.Context, subscriber model.Subscriber, search_engine_id string, limit int, offset int) (model.SearchEngine, error) {
+	ctx, span := tracing.Start(ctx, "Repository.GetSearchEngine")
+	r0, err := t.Repository.GetSearchEngine(ctx, subscriber, search_engine_id, limit, offset)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) GetItem(ctx context.Context, id string) (*model.Item, error) {
+	ctx, span := tracing.Start(ctx, "Repository.GetItem")
+	r0, err := t.Repository.GetItem(ctx, id)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) CreateItem(ctx context.Context, item *model.Item) error {
+	ctx, span := tracing.Start(ctx, "Repository.CreateItem")
+	err := t.Repository.CreateItem(ctx, item)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) SelectItems(ctx context.Context, q ListQuery) (*Page[model.Item], error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectItems")
+	r0, err := t.Repository.SelectItems(ctx, q)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) UpdateItem(ctx context.Context, item *model.Item) error {
+	ctx, span := tracing.Start(ctx, "Repository.UpdateItem")
+	err := t.Repository.UpdateItem(ctx, item)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) DeleteItem(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "Repository.DeleteItem")
+	err := t.Repository.DeleteItem(ctx, id)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) GetUser(ctx context.Context, id string) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "Repository.GetUser")
+	r0, err := t.Repository.GetUser(ctx, id)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) GetUserByUsername(ctx context.Context, username string) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "Repository.GetUserByUsername")
+	r0, err := t.Repository.GetUserByUsername(ctx, username)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) CreateUser(ctx context.Context, username string, password string) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "Repository.CreateUser")
+	r0, err := t.Repository.CreateUser(ctx, username, password)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) SelectUsers(ctx context.Context, q ListQuery) (*Page[model.User], error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectUsers")
+	r0, err := t.Repository.SelectUsers(ctx, q)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) UpdateUser(ctx context.Context, item *model.User) error {
+	ctx, span := tracing.Start(ctx, "Repository.UpdateUser")
+	err := t.Repository.UpdateUser(ctx, item)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) DeleteUser(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "Repository.DeleteUser")
+	err := t.Repository.DeleteUser(ctx, id)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) RecordFailedLogin(ctx context.Context, id string) (int, error) {
+	ctx, span := tracing.Start(ctx, "Repository.RecordFailedLogin")
+	r0, err := t.Repository.RecordFailedLogin(ctx, id)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) LockUser(ctx context.Context, id string, until time.Time) error {
+	ctx, span := tracing.Start(ctx, "Repository.LockUser")
+	err := t.Repository.LockUser(ctx, id, until)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) ResetFailedLogins(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "Repository.ResetFailedLogins")
+	err := t.Repository.ResetFailedLogins(ctx, id)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) GetUserMFA(ctx context.Context, user_id string) (*model.UserMFA, error) {
+	ctx, span := tracing.Start(ctx, "Repository.GetUserMFA")
+	r0, err := t.Repository.GetUserMFA(ctx, user_id)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) SaveUserMFA(ctx context.Context, user_id string, secret string) error {
+	ctx, span := tracing.Start(ctx, "Repository.SaveUserMFA")
+	err := t.Repository.SaveUserMFA(ctx, user_id, secret)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) EnableUserMFA(ctx context.Context, user_id string, code_hashes []string) error {
+	ctx, span := tracing.Start(ctx, "Repository.EnableUserMFA")
+	err := t.Repository.EnableUserMFA(ctx, user_id, code_hashes)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) DeleteUserMFA(ctx context.Context, user_id string) error {
+	ctx, span := tracing.Start(ctx, "Repository.DeleteUserMFA")
+	err := t.Repository.DeleteUserMFA(ctx, user_id)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) UseMFAStep(ctx context.Context, user_id string, step int64) (bool, error) {
+	ctx, span := tracing.Start(ctx, "Repository.UseMFAStep")
+	r0, err := t.Repository.UseMFAStep(ctx, user_id, step)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) ReplaceRecoveryCodes(ctx context.Context, user_id string, code_hashes []string) error {
+	ctx, span := tracing.Start(ctx, "Repository.ReplaceRecoveryCodes")
+	err := t.Repository.ReplaceRecoveryCodes(ctx, user_id, code_hashes)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) UseRecoveryCode(ctx context.Context, user_id string, code_hash string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "Repository.UseRecoveryCode")
+	r0, err := t.Repository.UseRecoveryCode(ctx, user_id, code_hash)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) MFARequired(ctx context.Context, user_id string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "Repository.MFARequired")
+	r0, err := t.Repository.MFARequired(ctx, user_id)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) GetSubscriberOIDC(ctx context.Context, subscriber_id string) (*model.SubscriberOIDC, error) {
+	ctx, span := tracing.Start(ctx, "Repository.GetSubscriberOIDC")
+	r0, err := t.Repository.GetSubscriberOIDC(ctx, subscriber_id)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) SaveSubscriberOIDC(ctx context.Context, cfg *model.SubscriberOIDC) error {
+	ctx, span := tracing.Start(ctx, "Repository.SaveSubscriberOIDC")
+	err := t.Repository.SaveSubscriberOIDC(ctx, cfg)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) DeleteSubscriberOIDC(ctx context.Context, subscriber_id string) error {
+	ctx, span := tracing.Start(ctx, "Repository.DeleteSubscriberOIDC")
+	err := t.Repository.DeleteSubscriberOIDC(ctx, subscriber_id)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) CreateOIDCState(ctx context.Context, state model.OIDCState) error {
+	ctx, span := tracing.Start(ctx, "Repository.CreateOIDCState")
+	err := t.Repository.CreateOIDCState(ctx, state)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) UseOIDCState(ctx context.Context, state_hash string) (*model.OIDCState, error) {
+	ctx, span := tracing.Start(ctx, "Repository.UseOIDCState")
+	r0, err := t.Repository.UseOIDCState(ctx, state_hash)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) GetUserIdentity(ctx context.Context, issuer string, subject string) (*model.UserIdentity, error) {
+	ctx, span := tracing.Start(ctx, "Repository.GetUserIdentity")
+	r0, err := t.Repository.GetUserIdentity(ctx, issuer, subject)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) CreateUserIdentity(ctx context.Context, identity *model.UserIdentity) (*model.UserIdentity, error) {
+	ctx, span := tracing.Start(ctx, "Repository.CreateUserIdentity")
+	r0, err := t.Repository.CreateUserIdentity(ctx, identity)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) RecordIdentityLogin(ctx context.Context, id string, email string) error {
+	ctx, span := tracing.Start(ctx, "Repository.RecordIdentityLogin")
+	err := t.Repository.RecordIdentityLogin(ctx, id, email)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) CreateInvitation(ctx context.Context, invitation *model.Invitation, token_hash string) (*model.Invitation, error) {
+	ctx, span := tracing.Start(ctx, "Repository.CreateInvitation")
+	r0, err := t.Repository.CreateInvitation(ctx, invitation, token_hash)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) GetInvitation(ctx context.Context, id string) (*model.Invitation, error) {
+	ctx, span := tracing.Start(ctx, "Repository.GetInvitation")
+	r0, err := t.Repository.GetInvitation(ctx, id)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) GetPendingInvitation(ctx context.Context, token_hash string) (*model.Invitation, error) {
+	ctx, span := tracing.Start(ctx, "Repository.GetPendingInvitation")
+	r0, err := t.Repository.GetPendingInvitation(ctx, token_hash)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) SelectInvitations(ctx context.Context, q ListQuery) (*Page[model.Invitation], error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectInvitations")
+	r0, err := t.Repository.SelectInvitations(ctx, q)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) RevokeInvitation(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "Repository.RevokeInvitation")
+	err := t.Repository.RevokeInvitation(ctx, id)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) AcceptInvitation(ctx context.Context, token_hash string, user_id string, password_hash string) (*model.Invitation, error) {
+	ctx, span := tracing.Start(ctx, "Repository.AcceptInvitation")
+	r0, err := t.Repository.AcceptInvitation(ctx, token_hash, user_id, password_hash)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) RegisterUser(ctx context.Context, username string, password string) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "Repository.RegisterUser")
+	r0, err := t.Repository.RegisterUser(ctx, username, password)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) CreateEmailVerification(ctx context.Context, user_id string, token_hash string, expires_at time.Time) error {
+	ctx, span := tracing.Start(ctx, "Repository.CreateEmailVerification")
+	err := t.Repository.CreateEmailVerification(ctx, user_id, token_hash, expires_at)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) UseEmailVerification(ctx context.Context, token_hash string) (string, error) {
+	ctx, span := tracing.Start(ctx, "Repository.UseEmailVerification")
+	r0, err := t.Repository.UseEmailVerification(ctx, token_hash)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) DeleteUnverifiedUsers(ctx context.Context, before time.Time) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "Repository.DeleteUnverifiedUsers")
+	r0, err := t.Repository.DeleteUnverifiedUsers(ctx, before)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) CreateAPIKey(ctx context.Context, key *model.APIKey, key_hash string) (*model.APIKey, error) {
+	ctx, span := tracing.Start(ctx, "Repository.CreateAPIKey")
+	r0, err := t.Repository.CreateAPIKey(ctx, key, key_hash)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) GetAPIKey(ctx context.Context, id string) (*model.APIKey, error) {
+	ctx, span := tracing.Start(ctx, "Repository.GetAPIKey")
+	r0, err := t.Repository.GetAPIKey(ctx, id)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) LookupAPIKey(ctx context.Context, key_hash string) (*model.APIKey, error) {
+	ctx, span := tracing.Start(ctx, "Repository.LookupAPIKey")
+	r0, err := t.Repository.LookupAPIKey(ctx, key_hash)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) SelectAPIKeys(ctx context.Context, user_id string, q ListQuery) (*Page[model.APIKey], error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectAPIKeys")
+	r0, err := t.Repository.SelectAPIKeys(ctx, user_id, q)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) RevokeAPIKey(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "Repository.RevokeAPIKey")
+	err := t.Repository.RevokeAPIKey(ctx, id)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) RecordAPIKeyUse(ctx context.Context, id string, ip string) error {
+	ctx, span := tracing.Start(ctx, "Repository.RecordAPIKeyUse")
+	err := t.Repository.RecordAPIKeyUse(ctx, id, ip)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) SelectSubscriberPermissions(ctx context.Context, user_id string, subscriber_id string) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectSubscriberPermissions")
+	r0, err := t.Repository.SelectSubscriberPermissions(ctx, user_id, subscriber_id)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) CreateServiceAccount(ctx context.Context, name string, subscriber_id string, role_id string) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "Repository.CreateServiceAccount")
+	r0, err := t.Repository.CreateServiceAccount(ctx, name, subscriber_id, role_id)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) CreateEmail(ctx context.Context, email *model.Email) (*model.Email, error) {
+	ctx, span := tracing.Start(ctx, "Repository.CreateEmail")
+	r0, err := t.Repository.CreateEmail(ctx, email)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) GetEmail(ctx context.Context, id string) (*model.Email, error) {
+	ctx, span := tracing.Start(ctx, "Repository.GetEmail")
+	r0, err := t.Repository.GetEmail(ctx, id)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) SelectEmails(ctx context.Context, q ListQuery) (*Page[model.Email], error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectEmails")
+	r0, err := t.Repository.SelectEmails(ctx, q)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) ClaimEmails(ctx context.Context, limit int, lease time.Duration) ([]model.Email, error) {
+	ctx, span := tracing.Start(ctx, "Repository.ClaimEmails")
+	r0, err := t.Repository.ClaimEmails(ctx, limit, lease)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) MarkEmailSent(ctx context.Context, id string, provider_message_id string) error {
+	ctx, span := tracing.Start(ctx, "Repository.MarkEmailSent")
+	err := t.Repository.MarkEmailSent(ctx, id, provider_message_id)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) MarkEmailFailed(ctx context.Context, id string, message string, retry_at *time.Time) error {
+	ctx, span := tracing.Start(ctx, "Repository.MarkEmailFailed")
+	err := t.Repository.MarkEmailFailed(ctx, id, message, retry_at)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) GetEmailTemplate(ctx context.Context, subscriber_id string, name string) (*model.EmailTemplate, error) {
+	ctx, span := tracing.Start(ctx, "Repository.GetEmailTemplate")
+	r0, err := t.Repository.GetEmailTemplate(ctx, subscriber_id, name)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) SelectEmailTemplates(ctx context.Context, subscriber_id string) ([]model.EmailTemplate, error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectEmailTemplates")
+	r0, err := t.Repository.SelectEmailTemplates(ctx, subscriber_id)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) SaveEmailTemplate(ctx context.Context, template *model.EmailTemplate) (*model.EmailTemplate, error) {
+	ctx, span := tracing.Start(ctx, "Repository.SaveEmailTemplate")
+	r0, err := t.Repository.SaveEmailTemplate(ctx, template)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) DeleteEmailTemplate(ctx context.Context, subscriber_id string, name string) error {
+	ctx, span := tracing.Start(ctx, "Repository.DeleteEmailTemplate")
+	err := t.Repository.DeleteEmailTemplate(ctx, subscriber_id, name)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) Unsubscribe(ctx context.Context, email string, category string) error {
+	ctx, span := tracing.Start(ctx, "Repository.Unsubscribe")
+	err := t.Repository.Unsubscribe(ctx, email, category)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) IsUnsubscribed(ctx context.Context, email string, category string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "Repository.IsUnsubscribed")
+	r0, err := t.Repository.IsUnsubscribed(ctx, email, category)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) GetNotificationPreferences(ctx context.Context, user_id string, subscriber_id string) (*model.NotificationPreferences, error) {
+	ctx, span := tracing.Start(ctx, "Repository.GetNotificationPreferences")
+	r0, err := t.Repository.GetNotificationPreferences(ctx, user_id, subscriber_id)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) SelectNotificationPreferences(ctx context.Context, user_id string) ([]model.NotificationPreferences, error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectNotificationPreferences")
+	r0, err := t.Repository.SelectNotificationPreferences(ctx, user_id)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) SaveNotificationPreferences(ctx context.Context, preferences *model.NotificationPreferences) (*model.NotificationPreferences, error) {
+	ctx, span := tracing.Start(ctx, "Repository.SaveNotificationPreferences")
+	r0, err := t.Repository.SaveNotificationPreferences(ctx, preferences)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) DeleteNotificationPreferences(ctx context.Context, user_id string, subscriber_id string) error {
+	ctx, span := tracing.Start(ctx, "Repository.DeleteNotificationPreferences")
+	err := t.Repository.DeleteNotificationPreferences(ctx, user_id, subscriber_id)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) SelectDigestRecipients(ctx context.Context) ([]model.DigestRecipient, error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectDigestRecipients")
+	r0, err := t.Repository.SelectDigestRecipients(ctx)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) ClaimNotificationDigest(ctx context.Context, user_id string, subscriber_id string, channel string, due time.Time, now time.Time) (bool, *time.Time, error) {
+	ctx, span := tracing.Start(ctx, "Repository.ClaimNotificationDigest")
+	r0, r1, err := t.Repository.ClaimNotificationDigest(ctx, user_id, subscriber_id, channel, due, now)
+	tracing.End(span, err)
+	return r0, r1, err
+}
+
+func (t *tracedRepository) SelectDigestMentions(ctx context.Context, subscriber model.Subscriber, since time.Time, limit int) ([]model.DigestMention, int, error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectDigestMentions")
+	r0, r1, err := t.Repository.SelectDigestMentions(ctx, subscriber, since, limit)
+	tracing.End(span, err)
+	return r0, r1, err
+}
+
+func (t *tracedRepository) SelectSubscriberUsers(ctx context.Context, subscriber_id string) ([]model.User, error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectSubscriberUsers")
+	r0, err := t.Repository.SelectSubscriberUsers(ctx, subscriber_id)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) CreateMailboxSource(ctx context.Context, source *model.MailboxSource) (*model.MailboxSource, error) {
+	ctx, span := tracing.Start(ctx, "Repository.CreateMailboxSource")
+	r0, err := t.Repository.CreateMailboxSource(ctx, source)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) GetMailboxSource(ctx context.Context, id string) (*model.MailboxSource, error) {
+	ctx, span := tracing.Start(ctx, "Repository.GetMailboxSource")
+	r0, err := t.Repository.GetMailboxSource(ctx, id)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) SelectMailboxSources(ctx context.Context, subscriber_id string) ([]model.MailboxSource, error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectMailboxSources")
+	r0, err := t.Repository.SelectMailboxSources(ctx, subscriber_id)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) SelectActiveMailboxSources(ctx context.Context) ([]model.MailboxSource, error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectActiveMailboxSources")
+	r0, err := t.Repository.SelectActiveMailboxSources(ctx)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) UpdateMailboxSource(ctx context.Context, source *model.MailboxSource) (*model.MailboxSource, error) {
+	ctx, span := tracing.Start(ctx, "Repository.UpdateMailboxSource")
+	r0, err := t.Repository.UpdateMailboxSource(ctx, source)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) DeleteMailboxSource(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "Repository.DeleteMailboxSource")
+	err := t.Repository.DeleteMailboxSource(ctx, id)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) RecordMailboxPoll(ctx context.Context, id string, message string) error {
+	ctx, span := tracing.Start(ctx, "Repository.RecordMailboxPoll")
+	err := t.Repository.RecordMailboxPoll(ctx, id, message)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) SelectProcessedMailboxMessages(ctx context.Context, source_id string, message_ids []string) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectProcessedMailboxMessages")
+	r0, err := t.Repository.SelectProcessedMailboxMessages(ctx, source_id, message_ids)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) SaveMailboxMessage(ctx context.Context, subscriber model.Subscriber, source_id string, message_id string, results []model.CalibrateSearchResult) ([]model.CalibrateSearchResult, error) {
+	ctx, span := tracing.Start(ctx, "Repository.SaveMailboxMessage")
+	r0, err := t.Repository.SaveMailboxMessage(ctx, subscriber, source_id, message_id, results)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) CreatePasswordReset(ctx context.Context, user_id string, token_hash string, expires_at time.Time) error {
+	ctx, span := tracing.Start(ctx, "Repository.CreatePasswordReset")
+	err := t.Repository.CreatePasswordReset(ctx, user_id, token_hash, expires_at)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) GetPasswordReset(ctx context.Context, token_hash string) (string, error) {
+	ctx, span := tracing.Start(ctx, "Repository.GetPasswordReset")
+	r0, err := t.Repository.GetPasswordReset(ctx, token_hash)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) UsePasswordReset(ctx context.Context, token_hash string) (string, error) {
+	ctx, span := tracing.Start(ctx, "Repository.UsePasswordReset")
+	r0, err := t.Repository.UsePasswordReset(ctx, token_hash)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) SelectUserSubscriberView(ctx context.Context, user_id string, q ListQuery) (*Page[model.User_Subscriber_View], error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectUserSubscriberView")
+	r0, err := t.Repository.SelectUserSubscriberView(ctx, user_id, q)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) LookupUserSubscribersByUserId(ctx context.Context, user_id string) ([]model.User_Subscriber_View, error) {
+	ctx, span := tracing.Start(ctx, "Repository.LookupUserSubscribersByUserId")
+	r0, err := t.Repository.LookupUserSubscribersByUserId(ctx, user_id)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) SelectUserTenants(ctx context.Context, user_id string) ([]model.Tenant, error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectUserTenants")
+	r0, err := t.Repository.SelectUserTenants(ctx, user_id)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) UpdateUserSubscriber(ctx context.Context, user_subscriber model.User_Subscriber) error {
+	ctx, span := tracing.Start(ctx, "Repository.UpdateUserSubscriber")
+	err := t.Repository.UpdateUserSubscriber(ctx, user_subscriber)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) GetUserSubscriber(ctx context.Context, id string) (*model.User_Subscriber, error) {
+	ctx, span := tracing.Start(ctx, "Repository.GetUserSubscriber")
+	r0, err := t.Repository.GetUserSubscriber(ctx, id)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) CreateUserSubscriber(ctx context.Context, user_id string, subscriber_id string) (*model.User_Subscriber, error) {
+	ctx, span := tracing.Start(ctx, "Repository.CreateUserSubscriber")
+	r0, err := t.Repository.CreateUserSubscriber(ctx, user_id, subscriber_id)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) LookupUserSubscriber(ctx context.Context, user_id string, subscriber_id string) (*model.User_Subscriber, error) {
+	ctx, span := tracing.Start(ctx, "Repository.LookupUserSubscriber")
+	r0, err := t.Repository.LookupUserSubscriber(ctx, user_id, subscriber_id)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) DeleteUserSubscriber(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "Repository.DeleteUserSubscriber")
+	err := t.Repository.DeleteUserSubscriber(ctx, id)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) SelectUserSubscriberRoleView(ctx context.Context, user_subscriber_view model.User_Subscriber_View, q ListQuery) (*Page[model.User_Subscriber_Role_View], error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectUserSubscriberRoleView")
+	r0, err := t.Repository.SelectUserSubscriberRoleView(ctx, user_subscriber_view, q)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) CreateUserSubscriberRole(ctx context.Context, user_subscriber_id string, role_id string) (*model.User_Subscriber_Role, error) {
+	ctx, span := tracing.Start(ctx, "Repository.CreateUserSubscriberRole")
+	r0, err := t.Repository.CreateUserSubscriberRole(ctx, user_subscriber_id, role_id)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) LookupUserSubscriberRole(ctx context.Context, user_subscriber_id string, role_id string) (*model.User_Subscriber_Role, error) {
+	ctx, span := tracing.Start(ctx, "Repository.LookupUserSubscriberRole")
+	r0, err := t.Repository.LookupUserSubscriberRole(ctx, user_subscriber_id, role_id)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) UpdateUserSubscriberRole(ctx context.Context, user_subscriber_role model.User_Subscriber_Role) error {
+	ctx, span := tracing.Start(ctx, "Repository.UpdateUserSubscriberRole")
+	err := t.Repository.UpdateUserSubscriberRole(ctx, user_subscriber_role)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) GetUserSubscriberRole(ctx context.Context, id string) (*model.User_Subscriber_Role, error) {
+	ctx, span := tracing.Start(ctx, "Repository.GetUserSubscriberRole")
+	r0, err := t.Repository.GetUserSubscriberRole(ctx, id)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) DeleteUserSubscriberRole(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "Repository.DeleteUserSubscriberRole")
+	err := t.Repository.DeleteUserSubscriberRole(ctx, id)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) SelectCustomers(ctx context.Context, subscriber model.Subscriber, q ListQuery) (*Page[model.Customer], error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectCustomers")
+	r0, err := t.Repository.SelectCustomers(ctx, subscriber, q)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) CreateCustomer(ctx context.Context, customer *model.Customer, subscriber *model.Subscriber) (*model.Customer, error) {
+	ctx, span := tracing.Start(ctx, "Repository.CreateCustomer")
+	r0, err := t.Repository.CreateCustomer(ctx, customer, subscriber)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) GetCustomer(ctx context.Context, customer model.Customer) (*model.Customer, error) {
+	ctx, span := tracing.Start(ctx, "Repository.GetCustomer")
+	r0, err := t.Repository.GetCustomer(ctx, customer)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) DeleteCustomer(ctx context.Context, customer *model.Customer) error {
+	ctx, span := tracing.Start(ctx, "Repository.DeleteCustomer")
+	err := t.Repository.DeleteCustomer(ctx, customer)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) UpdateCustomer(ctx context.Context, customer *model.Customer) error {
+	ctx, span := tracing.Start(ctx, "Repository.UpdateCustomer")
+	err := t.Repository.UpdateCustomer(ctx, customer)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) GetSubscriber(ctx context.Context, id string) (*model.Subscriber, error) {
+	ctx, span := tracing.Start(ctx, "Repository.GetSubscriber")
+	r0, err := t.Repository.GetSubscriber(ctx, id)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) GetSubscriberByName(ctx context.Context, name string) (*model.Subscriber, error) {
+	ctx, span := tracing.Start(ctx, "Repository.GetSubscriberByName")
+	r0, err := t.Repository.GetSubscriberByName(ctx, name)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) CreateSubscriber(ctx context.Context, subscriber *model.Subscriber) (*model.Subscriber, error) {
+	ctx, span := tracing.Start(ctx, "Repository.CreateSubscriber")
+	r0, err := t.Repository.CreateSubscriber(ctx, subscriber)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) SelectSubscribers(ctx context.Context, q ListQuery) (*Page[model.Subscriber], error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectSubscribers")
+	r0, err := t.Repository.SelectSubscribers(ctx, q)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) UpdateSubscriber(ctx context.Context, subscriber *model.Subscriber) error {
+	ctx, span := tracing.Start(ctx, "Repository.UpdateSubscriber")
+	err := t.Repository.UpdateSubscriber(ctx, subscriber)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) DeleteSubscriber(ctx context.Context, subscriber *model.Subscriber) error {
+	ctx, span := tracing.Start(ctx, "Repository.DeleteSubscriber")
+	err := t.Repository.DeleteSubscriber(ctx, subscriber)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) SelectUserRoles(ctx context.Context, q ListQuery) (*Page[model.User], error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectUserRoles")
+	r0, err := t.Repository.SelectUserRoles(ctx, q)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) SelectSubscriberAddresses(ctx context.Context, subscriber model.Subscriber, q ListQuery) (*Page[model.Address], error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectSubscriberAddresses")
+	r0, err := t.Repository.SelectSubscriberAddresses(ctx, subscriber, q)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) UpdateSubscriberAddress(ctx context.Context, subscriber *model.Subscriber, address model.Address) error {
+	ctx, span := tracing.Start(ctx, "Repository.UpdateSubscriberAddress")
+	err := t.Repository.UpdateSubscriberAddress(ctx, subscriber, address)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) CreateSubscriberAddress(ctx context.Context, subscriber *model.Subscriber, address model.Address) error {
+	ctx, span := tracing.Start(ctx, "Repository.CreateSubscriberAddress")
+	err := t.Repository.CreateSubscriberAddress(ctx, subscriber, address)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) GetSubscriberAddress(ctx context.Context, subscriber_schema_name string, address_id string) (*model.Address, error) {
+	ctx, span := tracing.Start(ctx, "Repository.GetSubscriberAddress")
+	r0, err := t.Repository.GetSubscriberAddress(ctx, subscriber_schema_name, address_id)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) DeleteSubscriberAddress(ctx context.Context, subscriber_schema_name string, address_id string) error {
+	ctx, span := tracing.Start(ctx, "Repository.DeleteSubscriberAddress")
+	err := t.Repository.DeleteSubscriberAddress(ctx, subscriber_schema_name, address_id)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) SelectSubscriberBackgrounds(ctx context.Context, subscriber model.Subscriber, q ListQuery) (*Page[model.Background], error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectSubscriberBackgrounds")
+	r0, err := t.Repository.SelectSubscriberBackgrounds(ctx, subscriber, q)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) UpdateSubscriberBackground(ctx context.Context, subscriber *model.Subscriber, background model.Background) error {
+	ctx, span := tracing.Start(ctx, "Repository.UpdateSubscriberBackground")
+	err := t.Repository.UpdateSubscriberBackground(ctx, subscriber, background)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) CreateSubscriberBackground(ctx context.Context, subscriber *model.Subscriber, background model.Background) error {
+	ctx, span := tracing.Start(ctx, "Repository.CreateSubscriberBackground")
+	err := t.Repository.CreateSubscriberBackground(ctx, subscriber, background)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) GetSubscriberBackground(ctx context.Context, subscriber_schema_name string, background_id string) (*model.Background, error) {
+	ctx, span := tracing.Start(ctx, "Repository.GetSubscriberBackground")
+	r0, err := t.Repository.GetSubscriberBackground(ctx, subscriber_schema_name, background_id)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) DeleteSubscriberBackground(ctx context.Context, subscriber_schema_name string, background_id string) error {
+	ctx, span := tracing.Start(ctx, "Repository.DeleteSubscriberBackground")
+	err := t.Repository.DeleteSubscriberBackground(ctx, subscriber_schema_name, background_id)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) SelectSubscriberItemView(ctx context.Context, subscriber_id string, q ListQuery) (*Page[model.Subscriber_Item_View], error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectSubscriberItemView")
+	r0, err := t.Repository.SelectSubscriberItemView(ctx, subscriber_id, q)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) CreateSubscriberItem(ctx context.Context, item_id string, subscriber_id string) (*model.Subscriber_Item, error) {
+	ctx, span := tracing.Start(ctx, "Repository.CreateSubscriberItem")
+	r0, err := t.Repository.CreateSubscriberItem(ctx, item_id, subscriber_id)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) LookupSubscriberItem(ctx context.Context, item_id string, subscriber_id string) (*model.Subscriber_Item, error) {
+	ctx, span := tracing.Start(ctx, "Repository.LookupSubscriberItem")
+	r0, err := t.Repository.LookupSubscriberItem(ctx, item_id, subscriber_id)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) DeleteSubscriberItem(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "Repository.DeleteSubscriberItem")
+	err := t.Repository.DeleteSubscriberItem(ctx, id)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) GetSubscriberItem(ctx context.Context, id string) (*model.Subscriber_Item, error) {
+	ctx, span := tracing.Start(ctx, "Repository.GetSubscriberItem")
+	r0, err := t.Repository.GetSubscriberItem(ctx, id)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) SelectBlocked(ctx context.Context, q ListQuery) (*Page[model.Blocked], error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectBlocked")
+	r0, err := t.Repository.SelectBlocked(ctx, q)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) GetBlocked(ctx context.Context, id string) (*model.Blocked, error) {
+	ctx, span := tracing.Start(ctx, "Repository.GetBlocked")
+	r0, err := t.Repository.GetBlocked(ctx, id)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) UpdateBlocked(ctx context.Context, item *model.Blocked) error {
+	ctx, span := tracing.Start(ctx, "Repository.UpdateBlocked")
+	err := t.Repository.UpdateBlocked(ctx, item)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) CreateBlocked(ctx context.Context, blocked model.Blocked) (*model.Blocked, error) {
+	ctx, span := tracing.Start(ctx, "Repository.CreateBlocked")
+	r0, err := t.Repository.CreateBlocked(ctx, blocked)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) DeleteBlocked(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "Repository.DeleteBlocked")
+	err := t.Repository.DeleteBlocked(ctx, id)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) SelectAllowed(ctx context.Context, q ListQuery) (*Page[model.Allowed], error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectAllowed")
+	r0, err := t.Repository.SelectAllowed(ctx, q)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) GetAllowed(ctx context.Context, id string) (*model.Allowed, error) {
+	ctx, span := tracing.Start(ctx, "Repository.GetAllowed")
+	r0, err := t.Repository.GetAllowed(ctx, id)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) UpdateAllowed(ctx context.Context, allowed *model.Allowed) error {
+	ctx, span := tracing.Start(ctx, "Repository.UpdateAllowed")
+	err := t.Repository.UpdateAllowed(ctx, allowed)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) CreateAllowed(ctx context.Context, allowed model.Allowed) (*model.Allowed, error) {
+	ctx, span := tracing.Start(ctx, "Repository.CreateAllowed")
+	r0, err := t.Repository.CreateAllowed(ctx, allowed)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) DeleteAllowed(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "Repository.DeleteAllowed")
+	err := t.Repository.DeleteAllowed(ctx, id)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) SelectRolesByUser(ctx context.Context, userID string) (model.Roles, error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectRolesByUser")
+	r0, err := t.Repository.SelectRolesByUser(ctx, userID)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) SelectRoles(ctx context.Context, q ListQuery) (*Page[model.Role], error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectRoles")
+	r0, err := t.Repository.SelectRoles(ctx, q)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) GetRole(ctx context.Context, id string) (*model.Role, error) {
+	ctx, span := tracing.Start(ctx, "Repository.GetRole")
+	r0, err := t.Repository.GetRole(ctx, id)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) UpdateRole(ctx context.Context, role *model.Role) error {
+	ctx, span := tracing.Start(ctx, "Repository.UpdateRole")
+	err := t.Repository.UpdateRole(ctx, role)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) CreateRole(ctx context.Context, name string) (*model.Role, error) {
+	ctx, span := tracing.Start(ctx, "Repository.CreateRole")
+	r0, err := t.Repository.CreateRole(ctx, name)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) DeleteRole(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "Repository.DeleteRole")
+	err := t.Repository.DeleteRole(ctx, id)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) GetPermission(ctx context.Context, id string) (*model.Permission, error) {
+	ctx, span := tracing.Start(ctx, "Repository.GetPermission")
+	r0, err := t.Repository.GetPermission(ctx, id)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) CreatePermission(ctx context.Context, name string, description string, object_id string) (*model.Permission, error) {
+	ctx, span := tracing.Start(ctx, "Repository.CreatePermission")
+	r0, err := t.Repository.CreatePermission(ctx, name, description, object_id)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) SelectPermissions(ctx context.Context, q ListQuery) (*Page[model.Permission], error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectPermissions")
+	r0, err := t.Repository.SelectPermissions(ctx, q)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) SelectPermissions_View(ctx context.Context, q ListQuery) (*Page[model.Permission_View], error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectPermissions_View")
+	r0, err := t.Repository.SelectPermissions_View(ctx, q)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) UpdatePermission(ctx context.Context, permission *model.Permission) error {
+	ctx, span := tracing.Start(ctx, "Repository.UpdatePermission")
+	err := t.Repository.UpdatePermission(ctx, permission)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) DeletePermission(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "Repository.DeletePermission")
+	err := t.Repository.DeletePermission(ctx, id)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) SelectUserPermissions(ctx context.Context, q ListQuery) (*Page[model.User_Permission], error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectUserPermissions")
+	r0, err := t.Repository.SelectUserPermissions(ctx, q)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) SelectRolePermissionsView(ctx context.Context, q ListQuery) (*Page[model.Role_Permission_View], error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectRolePermissionsView")
+	r0, err := t.Repository.SelectRolePermissionsView(ctx, q)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) GetProfile(ctx context.Context, subscriber *model.Subscriber) (*model.Profile, error) {
+	ctx, span := tracing.Start(ctx, "Repository.GetProfile")
+	r0, err := t.Repository.GetProfile(ctx, subscriber)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) CreateProfile(ctx context.Context, subscriber model.Subscriber, profile model.Profile) (*model.Profile, error) {
+	ctx, span := tracing.Start(ctx, "Repository.CreateProfile")
+	r0, err := t.Repository.CreateProfile(ctx, subscriber, profile)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) SelectProfiles(ctx context.Context, q ListQuery) (*Page[model.Profile], error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectProfiles")
+	r0, err := t.Repository.SelectProfiles(ctx, q)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) UpdateProfile(ctx context.Context, subscriber *model.Subscriber, profile *model.Profile) error {
+	ctx, span := tracing.Start(ctx, "Repository.UpdateProfile")
+	err := t.Repository.UpdateProfile(ctx, subscriber, profile)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) DeleteProfile(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "Repository.DeleteProfile")
+	err := t.Repository.DeleteProfile(ctx, id)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) SelectContacts(ctx context.Context, customer model.Customer, q ListQuery) (*Page[model.Contact], error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectContacts")
+	r0, err := t.Repository.SelectContacts(ctx, customer, q)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) CreateContact(ctx context.Context, contact *model.Contact) (*model.Contact, error) {
+	ctx, span := tracing.Start(ctx, "Repository.CreateContact")
+	r0, err := t.Repository.CreateContact(ctx, contact)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) DeleteContact(ctx context.Context, contact *model.Contact) error {
+	ctx, span := tracing.Start(ctx, "Repository.DeleteContact")
+	err := t.Repository.DeleteContact(ctx, contact)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) GetContact(ctx context.Context, contact model.Contact) (*model.Contact, error) {
+	ctx, span := tracing.Start(ctx, "Repository.GetContact")
+	r0, err := t.Repository.GetContact(ctx, contact)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) UpdateContact(ctx context.Context, contact *model.Contact) error {
+	ctx, span := tracing.Start(ctx, "Repository.UpdateContact")
+	err := t.Repository.UpdateContact(ctx, contact)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) CreateAuditEvent(ctx context.Context, event model.AuditEvent) error {
+	ctx, span := tracing.Start(ctx, "Repository.CreateAuditEvent")
+	err := t.Repository.CreateAuditEvent(ctx, event)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) SelectAuditEvents(ctx context.Context, q ListQuery) (*Page[model.AuditEvent], error) {
+	ctx, span := tracing.Start(ctx, "Repository.SelectAuditEvents")
+	r0, err := t.Repository.SelectAuditEvents(ctx, q)
+	tracing.End(span, err)
+	return r0, err
+}
+
+func (t *tracedRepository) Ping(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "Repository.Ping")
+	err := t.Repository.Ping(ctx)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedRepository) PendingMigrations(ctx context.Context) (int, error) {
+	ctx, span := tracing.Start(ctx, "Repository.PendingMigrations")
+	r0, err := t.Repository.PendingMigrations(ctx)
+	tracing.End(span, err)
+	return r0, err
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"log/slog"
 	"strings"
 	"time"
 
@@ -14,89 +15,86 @@ import (
 
 //User
 
-func (d *Database) SelectUsers(ctx context.Context, limit int, offset int, sort string, order string) ([]model.User, int, error) {
-	fmt.Println("d SelectUsers")
-
-	if order == "" {
-		order = "asc"
-	}
-
-	if sort == "" {
-		sort = "username"
-	}
+// UserList is how users may be listed.
+var UserList = ListSpec{
+	Sorts:       map[string]string{"username": "username", "created_at": "created_at"},
+	Filters:     map[string]string{"username": "username", "ip_address": "ip_address", "created_at": "created_at", "service_account": "service_account"},
+	DefaultSort: "username",
+	Keys:        []string{"id"},
+}
 
-	query := fmt.Sprintf(`SELECT id, username, ip_address, created_at, COUNT(*) OVER() AS total FROM users ORDER BY %s %s LIMIT $1 OFFSET $2`, sort, order)
+func (d *Database) SelectUsers(ctx context.Context, q ListQuery) (*Page[model.User], error) {
+	slog.DebugContext(ctx, "SelectUsers")
 
-	rows, err := d.DB.QueryContext(ctx,
-		query,
-		limit,
-		offset,
-	)
+	page, err := selectList(ctx, d.DB, list{
+		Spec:    UserList,
+		Query:   q,
+		Columns: []string{"id", "username", "ip_address", "created_at", "email_verified_at", "service_account"},
+		From:    "users",
+	}, func(scan func(...any) error) (model.User, error) {
+		var user model.User
+		err := scan(&user.ID, &user.Username, &user.IP_address, &user.CreatedAt, &user.Email_Verified_At, &user.Service_Account)
+		return user, err
+	})
 	if err != nil {
-		fmt.Println(err.Error())
-		return nil, 0, fmt.Errorf("error listing items: %w", err)
+		slog.ErrorContext(ctx, "SelectUsers", "error", err)
+		return nil, fmt.Errorf("error listing users: %w", err)
 	}
-	defer rows.Close()
-
-	var total int
-	var users []model.User
-	var created_at *string
-	for rows.Next() {
-		var user model.User
-		if err := rows.Scan(&user.ID, &user.Username, &user.IP_address, &created_at, &total); err != nil {
-			fmt.Println(err.Error())
-			return nil, 0, fmt.Errorf("error scanning user: %w", err)
-		}
-
-		fmt.Println("created_at", *created_at)
-
-		t, err := time.Parse("2006-01-02 15:04:05.999999-07", *created_at)
-		if err != nil {
-			fmt.Println(err.Error())
-		} else {
-			user.CreatedAt = t
-		}
+	return page, nil
+}
 
-		user.Roles = strings.Replace(user.Roles, "{", "", -1)
-		user.Roles = strings.Replace(user.Roles, "}", "", -1)
-		users = append(users, user)
-	}
-	return users, total, nil
+// UserRoleList is how users with their roles may be listed.
+var UserRoleList = ListSpec{
+	Sorts:       map[string]string{"username": "username"},
+	Filters:     map[string]string{"id": "user_id", "username": "username"},
+	DefaultSort: "username",
+	Keys:        []string{"user_id"},
 }
 
-func (d *Database) SelectUserRoles(ctx context.Context, limit, offset int) ([]model.User, error) {
-	fmt.Println("d SelectUserRoles")
-	rows, err := d.DB.QueryContext(ctx,
-		"SELECT user_id, username, ip_address, role_name FROM user_roles_view ORDER BY username ASC LIMIT $1 OFFSET $2",
-		limit, offset,
-	)
-	if err != nil {
-		fmt.Println(err.Error())
-		return nil, fmt.Errorf("error listing items: %w", err)
-	}
-	defer rows.Close()
+func (d *Database) SelectUserRoles(ctx context.Context, q ListQuery) (*Page[model.User], error) {
+	slog.DebugContext(ctx, "SelectUserRoles")
 
-	var users []model.User
-	for rows.Next() {
+	page, err := selectList(ctx, d.DB, list{
+		Spec:    UserRoleList,
+		Query:   q,
+		Columns: []string{"user_id", "username", "ip_address", "role_name"},
+		From:    "user_roles_view",
+	}, func(scan func(...any) error) (model.User, error) {
 		var user model.User
-		if err := rows.Scan(&user.ID, &user.Username, &user.IP_address, &user.Roles); err != nil {
-			fmt.Println(err.Error())
-			return nil, fmt.Errorf("error scanning user: %w", err)
-		}
+		err := scan(&user.ID, &user.Username, &user.IP_address, &user.Roles)
 		user.Roles = strings.Replace(user.Roles, "{", "", -1)
 		user.Roles = strings.Replace(user.Roles, "}", "", -1)
-		users = append(users, user)
+		return user, err
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "SelectUserRoles", "error", err)
+		return nil, fmt.Errorf("error listing user roles: %w", err)
 	}
-	return users, nil
+	return page, nil
 }
 
+// GetUser returns the user without their password hash but with the login
+// pacing columns, which logins that do not start with a password need too.
 func (d *Database) GetUser(ctx context.Context, id string) (*model.User, error) {
 	var user model.User
 
-	err := d.DB.QueryRowContext(ctx,
-		"SELECT id, username, ip_address, created_at FROM users WHERE id = $1",
-		id,
-	).Scan(&user.ID, &user.Username, &user.IP_address, &user.CreatedAt)
+	query := `
+        SELECT id, username, ip_address, created_at, email_verified_at, service_account, failed_logins, last_failed_login, locked_until
+        FROM users
+        WHERE id = $1
+    `
+
+	err := d.DB.QueryRowContext(ctx, query, id).Scan(
+		&user.ID,
+		&user.Username,
+		&user.IP_address,
+		&user.CreatedAt,
+		&user.Email_Verified_At,
+		&user.Service_Account,
+		&user.FailedLogins,
+		&user.LastFailedLogin,
+		&user.LockedUntil,
+	)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -108,7 +106,19 @@ func (d *Database) GetUser(ctx context.Context, id string) (*model.User, error)
 	return &user, nil
 }
 
+// CreateUser creates a user whose email is taken as verified, as for users
+// created by an admin, an invitation or single sign-on.
 func (d *Database) CreateUser(ctx context.Context, username, password string) (*model.User, error) {
+	return d.createUser(ctx, username, password, true)
+}
+
+// RegisterUser creates a user who registered themselves and cannot log in
+// until they verify their email.
+func (d *Database) RegisterUser(ctx context.Context, username, password string) (*model.User, error) {
+	return d.createUser(ctx, username, password, false)
+}
+
+func (d *Database) createUser(ctx context.Context, username, password string, verified bool) (*model.User, error) {
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return nil, fmt.Errorf("error hashing password: %w", err)
@@ -120,10 +130,13 @@ func (d *Database) CreateUser(ctx context.Context, username, password string) (*
 		PasswordHash: string(hash),
 		CreatedAt:    time.Now(),
 	}
+	if verified {
+		user.Email_Verified_At = &user.CreatedAt
+	}
 
 	query := `
-        INSERT INTO users (id, username, password_hash, created_at)
-        VALUES ($1, $2, $3, $4)
+        INSERT INTO users (id, username, password_hash, created_at, email_verified_at)
+        VALUES ($1, $2, $3, $4, $5)
     `
 
 	_, err = d.DB.ExecContext(ctx, query,
@@ -131,6 +144,7 @@ func (d *Database) CreateUser(ctx context.Context, username, password string) (*
 		user.Username,
 		user.PasswordHash,
 		user.CreatedAt,
+		user.Email_Verified_At,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("error creating user: %w", err)
@@ -140,13 +154,15 @@ func (d *Database) CreateUser(ctx context.Context, username, password string) (*
 }
 
 func (d *Database) GetUserByUsername(ctx context.Context, username string) (*model.User, error) {
-	fmt.Printf("[%v] [GetUserByUsername] %s\n", time.Now().Format(time.RFC3339), username)
+	slog.DebugContext(ctx, "GetUserByUsername", "username", username)
 
 	user := &model.User{}
 	query := `
-        SELECT id, username, password_hash, ip_address, created_at
+        SELECT id, username, password_hash, ip_address, created_at, email_verified_at, service_account, failed_logins, last_failed_login, locked_until
         FROM users
-        WHERE username = $1
+        WHERE lower(username) = lower($1)
+        ORDER BY username = $1 DESC
+        LIMIT 1
     `
 
 	err := d.DB.QueryRowContext(ctx, query, username).Scan(
@@ -155,6 +171,11 @@ func (d *Database) GetUserByUsername(ctx context.Context, username string) (*mod
 		&user.PasswordHash,
 		&user.IP_address,
 		&user.CreatedAt,
+		&user.Email_Verified_At,
+		&user.Service_Account,
+		&user.FailedLogins,
+		&user.LastFailedLogin,
+		&user.LockedUntil,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -176,7 +197,7 @@ func (d *Database) DeleteUser(ctx context.Context, id string) error {
 }
 
 func (d *Database) UpdateUser(ctx context.Context, user *model.User) error {
-	fmt.Println("d UpdateUser")
+	slog.DebugContext(ctx, "UpdateUser")
 
 	query := `UPDATE users SET username = $1, ip_address = $2, password_hash = $3 WHERE id = $4`
 
@@ -191,3 +212,47 @@ func (d *Database) UpdateUser(ctx context.Context, user *model.User) error {
 
 	return err
 }
+
+// RecordFailedLogin counts a failed login of the user and returns the number
+// of consecutive failures.
+func (d *Database) RecordFailedLogin(ctx context.Context, id string) (int, error) {
+	slog.DebugContext(ctx, "RecordFailedLogin")
+
+	query := `
+        UPDATE users SET failed_logins = failed_logins + 1, last_failed_login = CURRENT_TIMESTAMP
+        WHERE id = $1
+        RETURNING failed_logins
+    `
+
+	var failures int
+	if err := d.DB.QueryRowContext(ctx, query, id).Scan(&failures); err != nil {
+		return 0, fmt.Errorf("error recording failed login: %w", err)
+	}
+
+	return failures, nil
+}
+
+// LockUser refuses logins of the user until until.
+func (d *Database) LockUser(ctx context.Context, id string, until time.Time) error {
+	slog.DebugContext(ctx, "LockUser")
+
+	_, err := d.DB.ExecContext(ctx, `UPDATE users SET locked_until = $1 WHERE id = $2`, until, id)
+	if err != nil {
+		return fmt.Errorf("error locking user: %w", err)
+	}
+
+	return nil
+}
+
+// ResetFailedLogins clears the failure count and any lock of the user.
+func (d *Database) ResetFailedLogins(ctx context.Context, id string) error {
+	slog.DebugContext(ctx, "ResetFailedLogins")
+
+	query := `UPDATE users SET failed_logins = 0, last_failed_login = NULL, locked_until = NULL WHERE id = $1`
+
+	if _, err := d.DB.ExecContext(ctx, query, id); err != nil {
+		return fmt.Errorf("error resetting failed logins: %w", err)
+	}
+
+	return nil
+}
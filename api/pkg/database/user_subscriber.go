@@ -3,53 +3,94 @@ package database
 import (
 	"context"
 	"database/sql"
-	"errors"
 	"fmt"
+	"log/slog"
 
 	"github.com/google/uuid"
 	"github.com/htstinson/stinsondataapi/api/internal/model"
+	"github.com/lib/pq"
 )
 
-func (d *Database) SelectUserSubscriberView(ctx context.Context, user_id string, limit int, offset int) ([]model.User_Subscriber_View, error) {
-	fmt.Println("d SelectUserSubscriberView()")
+// UserSubscriberViewList is how the subscribers of users may be listed.
+var UserSubscriberViewList = ListSpec{
+	Sorts:       map[string]string{"user_username": "user_username", "subscriber_name": "subscriber_name"},
+	Filters:     map[string]string{"subscriber_id": "subscriber_id", "user_username": "user_username", "subscriber_name": "subscriber_name"},
+	DefaultSort: "user_username",
+	Keys:        []string{"id"},
+}
+
+func (d *Database) SelectUserSubscriberView(ctx context.Context, user_id string, q ListQuery) (*Page[model.User_Subscriber_View], error) {
+	slog.DebugContext(ctx, "SelectUserSubscriberView")
 
-	where_clause := " "
+	l := list{
+		Spec:    UserSubscriberViewList,
+		Query:   q,
+		Columns: []string{"id", "user_id", "subscriber_id", "user_username", "subscriber_name"},
+		From:    "user_subscriber_view",
+	}
 
 	if user_id != "" {
 		_, err := ValidateUUID(user_id)
 		if err != nil {
-			fmt.Printf("Invalid UUID error: %v\n", err)
+			slog.WarnContext(ctx, "SelectUserSubscriberView", "error", err)
 			return nil, err
-		} else {
-			where_clause = fmt.Sprintf(` where user_id = '%s' `, user_id)
 		}
+		l.Where = []string{"user_id = $1"}
+		l.Args = []any{user_id}
 	}
 
-	query := fmt.Sprintf("SELECT id, user_id, subscriber_id, user_username, subscriber_name FROM user_subscriber_view%sORDER BY user_username, subscriber_name ASC LIMIT $1 OFFSET $2", where_clause)
-
-	rows, err := d.DB.QueryContext(ctx, query, limit, offset)
+	page, err := selectList(ctx, d.DB, l, func(scan func(...any) error) (model.User_Subscriber_View, error) {
+		var user_subscriber_view model.User_Subscriber_View
+		err := scan(&user_subscriber_view.Id, &user_subscriber_view.User_ID, &user_subscriber_view.Subscriber_Id, &user_subscriber_view.User_Username, &user_subscriber_view.Subscriber_Name)
+		return user_subscriber_view, err
+	})
 	if err != nil {
-		fmt.Println(err.Error())
+		slog.ErrorContext(ctx, "SelectUserSubscriberView", "error", err)
 		return nil, fmt.Errorf("error listing rows: %w", err)
 	}
+	return page, nil
+}
+
+// SelectUserTenants returns the subscribers the user belongs to, by name,
+// each with the names of the user's roles in it.
+func (d *Database) SelectUserTenants(ctx context.Context, user_id string) ([]model.Tenant, error) {
+	slog.DebugContext(ctx, "SelectUserTenants")
+
+	query := `
+        SELECT s.id, s.name,
+            COALESCE(array_agg(DISTINCT r.name::text) FILTER (WHERE r.name IS NOT NULL), '{}')
+        FROM user_subscriber us
+        JOIN subscribers s ON s.id = us.subscriber_id
+        LEFT JOIN user_subscriber_role usr ON usr.user_subscriber_id = us.id
+        LEFT JOIN roles r ON r.id = usr.role_id
+        WHERE us.user_id = $1
+        GROUP BY s.id, s.name
+        ORDER BY s.name
+    `
+
+	rows, err := d.DB.QueryContext(ctx, query, user_id)
+	if err != nil {
+		return nil, fmt.Errorf("error listing tenants: %w", err)
+	}
 	defer rows.Close()
 
-	var user_subscriber_views []model.User_Subscriber_View
+	tenants := []model.Tenant{}
 	for rows.Next() {
-		var user_subscriber_view model.User_Subscriber_View
-		if err := rows.Scan(&user_subscriber_view.Id, &user_subscriber_view.User_ID, &user_subscriber_view.Subscriber_Id, &user_subscriber_view.User_Username, &user_subscriber_view.Subscriber_Name); err != nil {
-			fmt.Println(err.Error())
-			return nil, fmt.Errorf("error scanning user_subscriber: %w", err)
+		var tenant model.Tenant
+		if err := rows.Scan(&tenant.Subscriber_Id, &tenant.Subscriber_Name, pq.Array(&tenant.Roles)); err != nil {
+			return nil, fmt.Errorf("error listing tenants: %w", err)
 		}
-
-		user_subscriber_views = append(user_subscriber_views, user_subscriber_view)
-
+		tenants = append(tenants, tenant)
 	}
-	return user_subscriber_views, nil
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error listing tenants: %w", err)
+	}
+
+	return tenants, nil
 }
 
 func (d *Database) UpdateUserSubscriber(ctx context.Context, user_subscriber model.User_Subscriber) error {
-	fmt.Println("d UpdateUserSubscriber")
+	slog.DebugContext(ctx, "UpdateUserSubscriber")
 
 	query := `UPDATE user_subscriber SET user_id = $1, subscriber_id = $2 WHERE id = $3`
 
@@ -59,7 +100,7 @@ func (d *Database) UpdateUserSubscriber(ctx context.Context, user_subscriber mod
 }
 
 func (d *Database) GetUserSubscriber(ctx context.Context, id string) (*model.User_Subscriber, error) {
-	fmt.Println("d GetUserSubscriber")
+	slog.DebugContext(ctx, "GetUserSubscriber")
 
 	var user_subscriber model.User_Subscriber
 
@@ -79,7 +120,7 @@ func (d *Database) GetUserSubscriber(ctx context.Context, id string) (*model.Use
 }
 
 func (d *Database) CreateUserSubscriber(ctx context.Context, user_id string, subscriber_id string) (*model.User_Subscriber, error) {
-	fmt.Println("d CreateUserSubscriber")
+	slog.DebugContext(ctx, "CreateUserSubscriber")
 
 	user_subscriber := &model.User_Subscriber{
 		Id:            uuid.New().String(),
@@ -104,7 +145,7 @@ func (d *Database) CreateUserSubscriber(ctx context.Context, user_id string, sub
 }
 
 func (d *Database) DeleteUserSubscriber(ctx context.Context, id string) error {
-	fmt.Println("d DeleteUserSubscriber")
+	slog.DebugContext(ctx, "DeleteUserSubscriber")
 
 	query := `DELETE FROM user_subscriber WHERE id = $1`
 
@@ -114,7 +155,7 @@ func (d *Database) DeleteUserSubscriber(ctx context.Context, id string) error {
 }
 
 func (d *Database) LookupUserSubscriber(ctx context.Context, user_id string, subscriber_id string) (*model.User_Subscriber, error) {
-	fmt.Println("d LookupUserSubscriber")
+	slog.DebugContext(ctx, "LookupUserSubscriber")
 
 	var user_subscriber = model.User_Subscriber{}
 
@@ -124,7 +165,7 @@ func (d *Database) LookupUserSubscriber(ctx context.Context, user_id string, sub
 	).Scan(&user_subscriber.Id, &user_subscriber.User_ID, &user_subscriber.Subscriber_Id)
 
 	if err == sql.ErrNoRows {
-		return nil, errors.New("not found")
+		return nil, ErrNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("error getting user_subscriber: %w", err)
@@ -134,14 +175,14 @@ func (d *Database) LookupUserSubscriber(ctx context.Context, user_id string, sub
 }
 
 func (d *Database) LookupUserSubscribersByUserId(ctx context.Context, user_id string) ([]model.User_Subscriber_View, error) {
-	fmt.Println("d LookupUserSubscribersByUser")
+	slog.DebugContext(ctx, "LookupUserSubscribersByUser")
 
 	rows, err := d.DB.QueryContext(ctx,
 		"SELECT id, user_id, subscriber_id, user_username, subscriber_name FROM user_subscriber_view WHERE user_id = $1",
 		user_id,
 	)
 	if err != nil {
-		fmt.Println(err.Error())
+		slog.ErrorContext(ctx, "LookupUserSubscribersByUserId", "error", err)
 		return nil, fmt.Errorf("error listing rows: %w", err)
 	}
 	defer rows.Close()
@@ -150,7 +191,7 @@ func (d *Database) LookupUserSubscribersByUserId(ctx context.Context, user_id st
 	for rows.Next() {
 		var user_subscriber_view model.User_Subscriber_View
 		if err := rows.Scan(&user_subscriber_view.Id, &user_subscriber_view.User_ID, &user_subscriber_view.Subscriber_Id, &user_subscriber_view.User_Username, &user_subscriber_view.Subscriber_Name); err != nil {
-			fmt.Println(err.Error())
+			slog.ErrorContext(ctx, "LookupUserSubscribersByUserId", "error", err)
 			return nil, fmt.Errorf("error scanning user_subscriber: %w", err)
 		}
 
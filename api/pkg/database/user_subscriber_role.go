@@ -3,67 +3,57 @@ package database
 import (
 	"context"
 	"database/sql"
-	"errors"
 	"fmt"
+	"log/slog"
 
 	"github.com/google/uuid"
 	"github.com/htstinson/stinsondataapi/api/internal/model"
 )
 
-func (d *Database) SelectUserSubscriberRoleView(ctx context.Context, user_subscriber_view model.User_Subscriber_View, limit, offset int) ([]model.User_Subscriber_Role_View, error) {
-	fmt.Println("d SelectUserSubscriberRolesView()")
-
-	var rows *sql.Rows
-	var query string
-	var err error
-
-	// User_ID was not provided
-	if user_subscriber_view.User_ID == "" {
-		query = `SELECT id, user_subscriber_id, role_id, role_name, user_id, username, 
-		subscriber_id, subscriber_name, created_at, updated_at 
-		FROM common.user_subscriber_role_view 
-		ORDER BY username, subscriber_name, role_name ASC LIMIT $1 OFFSET $2`
-
-		rows, err = d.DB.QueryContext(ctx, query, limit, offset)
-		if err != nil {
-			fmt.Println(err.Error())
-			return nil, fmt.Errorf("error selecting rows: %w", err)
-		}
-		defer rows.Close()
-
-	} else {
-		// User_ID was provided
-		query = `SELECT id, user_subscriber_id, role_id, role_name, user_id, username, subscriber_id, subscriber_name, created_at, updated_at 
-		FROM common.user_subscriber_role_view where user_id = $1
-		ORDER BY username, subscriber_name, role_name ASC LIMIT $2 OFFSET $3`
-
-		rows, err = d.DB.QueryContext(ctx, query, user_subscriber_view.User_ID, limit, offset)
-		if err != nil {
-			fmt.Println(err.Error())
-			return nil, fmt.Errorf("error selecting rows: %w", err)
-		}
-		defer rows.Close()
+// UserSubscriberRoleViewList is how the roles of user subscribers may be
+// listed.
+var UserSubscriberRoleViewList = ListSpec{
+	Sorts:       map[string]string{"user_username": "username", "subscriber_name": "subscriber_name", "role_name": "role_name", "created_at": "created_at"},
+	Filters:     map[string]string{"subscriber_id": "subscriber_id", "role_id": "role_id", "role_name": "role_name", "user_username": "username", "subscriber_name": "subscriber_name"},
+	DefaultSort: "user_username",
+	Keys:        []string{"id"},
+}
+
+func (d *Database) SelectUserSubscriberRoleView(ctx context.Context, user_subscriber_view model.User_Subscriber_View, q ListQuery) (*Page[model.User_Subscriber_Role_View], error) {
+	slog.DebugContext(ctx, "SelectUserSubscriberRolesView")
+
+	l := list{
+		Spec:  UserSubscriberRoleViewList,
+		Query: q,
+		Columns: []string{"id", "user_subscriber_id", "role_id", "role_name", "user_id", "username",
+			"subscriber_id", "subscriber_name", "created_at", "updated_at"},
+		From: "common.user_subscriber_role_view",
 	}
 
-	var user_subscriber_role_views []model.User_Subscriber_Role_View
-	for rows.Next() {
+	// Only the roles of User_ID when it is provided
+	if user_subscriber_view.User_ID != "" {
+		l.Where = []string{"user_id = $1"}
+		l.Args = []any{user_subscriber_view.User_ID}
+	}
+
+	page, err := selectList(ctx, d.DB, l, func(scan func(...any) error) (model.User_Subscriber_Role_View, error) {
 		var user_subscriber_role_view model.User_Subscriber_Role_View
-		if err := rows.Scan(&user_subscriber_role_view.Id, &user_subscriber_role_view.User_Subscriber_ID,
+		err := scan(&user_subscriber_role_view.Id, &user_subscriber_role_view.User_Subscriber_ID,
 			&user_subscriber_role_view.Role_Id, &user_subscriber_role_view.Role_Name,
 			&user_subscriber_role_view.User_ID, &user_subscriber_role_view.User_Name,
 			&user_subscriber_role_view.Subscriber_Id, &user_subscriber_role_view.Subscriber_Name,
-			&user_subscriber_role_view.Created_At, &user_subscriber_role_view.Updated_At); err != nil {
-			fmt.Println(err.Error())
-			return nil, fmt.Errorf("error scanning user_subscriber_role: %w", err)
-		}
-
-		user_subscriber_role_views = append(user_subscriber_role_views, user_subscriber_role_view)
+			&user_subscriber_role_view.Created_At, &user_subscriber_role_view.Updated_At)
+		return user_subscriber_role_view, err
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "SelectUserSubscriberRoleView", "error", err)
+		return nil, fmt.Errorf("error selecting rows: %w", err)
 	}
-	return user_subscriber_role_views, nil
+	return page, nil
 }
 
 func (d *Database) CreateUserSubscriberRole(ctx context.Context, user_subscriber_id string, role_id string) (*model.User_Subscriber_Role, error) {
-	fmt.Println("d CreateUserSubscriberRole")
+	slog.DebugContext(ctx, "CreateUserSubscriberRole")
 
 	user_subscriber_role := &model.User_Subscriber_Role{
 		Id:                 uuid.New().String(),
@@ -89,7 +79,7 @@ func (d *Database) CreateUserSubscriberRole(ctx context.Context, user_subscriber
 }
 
 func (d *Database) LookupUserSubscriberRole(ctx context.Context, user_subscriber_id string, role_id string) (*model.User_Subscriber_Role, error) {
-	fmt.Println("d LookupUserSubscriberRole")
+	slog.DebugContext(ctx, "LookupUserSubscriberRole")
 
 	var user_subscriber_role = model.User_Subscriber_Role{}
 
@@ -99,7 +89,7 @@ func (d *Database) LookupUserSubscriberRole(ctx context.Context, user_subscriber
 	).Scan(&user_subscriber_role.Id, &user_subscriber_role.User_Subscriber_ID, &user_subscriber_role.Role_Id)
 
 	if err == sql.ErrNoRows {
-		return nil, errors.New("not found")
+		return nil, ErrNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("error getting user_subscriber_role: %w", err)
@@ -109,7 +99,7 @@ func (d *Database) LookupUserSubscriberRole(ctx context.Context, user_subscriber
 }
 
 func (d *Database) UpdateUserSubscriberRole(ctx context.Context, user_subscriber_role model.User_Subscriber_Role) error {
-	fmt.Println("d UpdateUserSubscriberRole")
+	slog.DebugContext(ctx, "UpdateUserSubscriberRole")
 
 	query := `UPDATE user_subscriber_role SET user_subscriber_id = $1, role_id = $2 WHERE id = $3`
 
@@ -119,7 +109,7 @@ func (d *Database) UpdateUserSubscriberRole(ctx context.Context, user_subscriber
 }
 
 func (d *Database) GetUserSubscriberRole(ctx context.Context, id string) (*model.User_Subscriber_Role, error) {
-	fmt.Println("d GetUserSubscriberRole")
+	slog.DebugContext(ctx, "GetUserSubscriberRole")
 
 	var user_subscriber_role model.User_Subscriber_Role
 
@@ -139,7 +129,7 @@ func (d *Database) GetUserSubscriberRole(ctx context.Context, id string) (*model
 }
 
 func (d *Database) DeleteUserSubscriberRole(ctx context.Context, id string) error {
-	fmt.Println("d DeleteUserSubscriberRole")
+	slog.DebugContext(ctx, "DeleteUserSubscriberRole")
 
 	query := `DELETE FROM user_subscriber_role WHERE id = $1`
 
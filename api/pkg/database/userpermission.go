@@ -3,31 +3,35 @@ package database
 import (
 	"context"
 	"fmt"
+	"log/slog"
 
 	"github.com/htstinson/stinsondataapi/api/internal/model"
 )
 
-func (d *Database) SelectUserPermissions(ctx context.Context, limit, offset int) ([]model.User_Permission, error) {
-	fmt.Println("database.go SelectUserPermission()")
-	rows, err := d.DB.QueryContext(ctx,
-		"SELECT user_id, permission_id, created_at FROM user_permissions ORDER BY user_id ASC LIMIT $1 OFFSET $2",
-		limit, offset,
-	)
-	if err != nil {
-		fmt.Println(err.Error())
-		return nil, fmt.Errorf("error listing rows: %w", err)
-	}
-	defer rows.Close()
+// UserPermissionList is how the permissions granted to users may be listed.
+var UserPermissionList = ListSpec{
+	Sorts:       map[string]string{"created_at": "created_at"},
+	Filters:     map[string]string{"user_id": "user_id", "permission_id": "permission_id"},
+	DefaultSort: "created_at",
+	Keys:        []string{"user_id", "permission_id"},
+}
 
-	var user_permissions []model.User_Permission
-	for rows.Next() {
-		var user_permission model.User_Permission
-		if err := rows.Scan(&user_permission.User_Id, &user_permission.Permission_Id, &user_permission.CreatedAt); err != nil {
-			fmt.Println(err.Error())
-			return nil, fmt.Errorf("error scanning user_permission: %w", err)
-		}
+func (d *Database) SelectUserPermissions(ctx context.Context, q ListQuery) (*Page[model.User_Permission], error) {
+	slog.DebugContext(ctx, "database.go SelectUserPermission")
 
-		user_permissions = append(user_permissions, user_permission)
+	page, err := selectList(ctx, d.DB, list{
+		Spec:    UserPermissionList,
+		Query:   q,
+		Columns: []string{"user_id", "permission_id", "created_at"},
+		From:    "user_permissions",
+	}, func(scan func(...any) error) (model.User_Permission, error) {
+		var user_permission model.User_Permission
+		err := scan(&user_permission.User_Id, &user_permission.Permission_Id, &user_permission.CreatedAt)
+		return user_permission, err
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "SelectUserPermissions", "error", err)
+		return nil, fmt.Errorf("error listing rows: %w", err)
 	}
-	return user_permissions, nil
+	return page, nil
 }
@@ -5,10 +5,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strings"
-	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 type SalesforceCreds struct {
@@ -48,9 +50,9 @@ func SalesForceLogin(SalesforceCreds *SalesforceCreds) (*SalesforceAuthResponse,
 	var auth *SalesforceAuthResponse
 	auth, err := GetSalesforceToken(clientID, clientSecret, username, password, loginURL)
 	if err != nil {
-		fmt.Printf("[%v] [SalesForceLogin] Error getting token: %v.\n", time.Now().Format(time.RFC3339), err)
+		slog.Error("SalesForceLogin error getting token", "error", err)
 	} else {
-		fmt.Printf("[%v] [SalesForceLogin] Token retrieved.\n", time.Now().Format(time.RFC3339))
+		slog.Debug("SalesForceLogin token retrieved")
 	}
 
 	return auth, err
@@ -90,7 +92,7 @@ func GetSalesforceToken(clientID, clientSecret, username, password, loginURL str
 
 	// Create a client with the custom transport
 	client := &http.Client{
-		Transport: transport,
+		Transport: otelhttp.NewTransport(transport),
 	}
 
 	// Send request
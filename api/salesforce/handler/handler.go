@@ -4,8 +4,8 @@ import (
 	"bytes"
 	"encoding/json"
 	"io"
+	"log/slog"
 	"os"
-	"time"
 
 	"fmt"
 	"log"
@@ -13,6 +13,7 @@ import (
 	"net/url"
 
 	common "github.com/htstinson/stinsondataapi/api/commonweb"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 
 	auth "github.com/htstinson/stinsondataapi/api/salesforce/auth"
 	salesforcemodel "github.com/htstinson/stinsondataapi/api/salesforce/model"
@@ -31,7 +32,7 @@ func New(creds *auth.SalesforceCreds) (*SalesforceHandler, error) {
 
 	authResponse, err := auth.SalesForceLogin(creds)
 	if err != nil {
-		fmt.Printf("[%v] Error: %s\n", time.Now().Format(time.RFC3339), err.Error())
+		slog.Error("New", "error", err)
 		return SalesforceHandler, err
 	}
 
@@ -71,7 +72,7 @@ func (h *SalesforceHandler) Get(endpoint string, query string) ([]byte, error) {
 	req.Header.Set("Content-Type", "application/json")
 
 	// Send request
-	client := &http.Client{}
+	client := &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("error sending request: %v", err)
@@ -98,7 +99,7 @@ func (h *SalesforceHandler) ListAccounts(w http.ResponseWriter, r *http.Request)
 
 	data, err := h.Get("/services/data/v59.0/query?q=", query)
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
+		slog.ErrorContext(r.Context(), "ListAccounts", "error", err)
 		return
 	}
 
@@ -106,7 +107,7 @@ func (h *SalesforceHandler) ListAccounts(w http.ResponseWriter, r *http.Request)
 
 	err = json.Unmarshal(data, &response)
 	if err != nil {
-		fmt.Printf("[%v] Error: %s\n", time.Now().Format(time.RFC3339), err.Error())
+		slog.ErrorContext(r.Context(), "ListAccounts", "error", err)
 	}
 
 	common.RespondJSON(w, http.StatusOK, response.Records)
@@ -146,7 +147,7 @@ func (h *SalesforceHandler) UpdateAccount(w http.ResponseWriter, r *http.Request
 
 	currentAccount, err := h.GetAccountById(id)
 	if err != nil {
-		fmt.Printf("[%v] Error: %s\n", time.Now().Format(time.RFC3339), err.Error())
+		slog.ErrorContext(r.Context(), "UpdateAccount", "error", err)
 		return
 	}
 
@@ -173,13 +174,13 @@ func (h *SalesforceHandler) CreateAccount(w http.ResponseWriter, r *http.Request
 	var bodyBytes bytes.Buffer
 	_, err := bodyBytes.ReadFrom(r.Body)
 	if err != nil {
-		fmt.Printf("Error reading body: %v\n", err)
+		slog.ErrorContext(r.Context(), "error reading body", "error", err)
 		http.Error(w, "can't read body", http.StatusBadRequest)
 		return
 	}
 
 	// Display the body
-	fmt.Printf("Body: %s\n", bodyBytes.String())
+	slog.DebugContext(r.Context(), "CreateAccount", "body", bodyBytes.String())
 
 	// Restore the body for further processing
 	r.Body = io.NopCloser(bytes.NewReader(bodyBytes.Bytes()))
@@ -193,7 +194,7 @@ func (h *SalesforceHandler) CreateAccount(w http.ResponseWriter, r *http.Request
 
 	_, err = h.SalesforcePost("/services/data/v62.0/sobjects/Account", account)
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
+		slog.ErrorContext(r.Context(), "CreateAccount", "error", err)
 		return
 	}
 
@@ -206,7 +207,7 @@ func (h *SalesforceHandler) GetAccountById(id string) (salesforcemodel.Account,
 
 	data, err := h.Get("/services/data/v59.0/query?q=", query)
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
+		slog.Error("GetAccountById", "error", err)
 		return salesforcemodel.Account{}, err
 	}
 
@@ -242,7 +243,7 @@ func (h *SalesforceHandler) SalesforcePatch(endpoint string, payload interface{}
 	req.Header.Set("Content-Type", "application/json")
 
 	// Send request
-	client := &http.Client{}
+	client := &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("error sending request: %v", err)
@@ -284,7 +285,7 @@ func (h *SalesforceHandler) SalesforcePost(endpoint string, payload interface{})
 	req.Header.Set("Content-Type", "application/json")
 
 	// Send request
-	client := &http.Client{}
+	client := &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("error sending request: %v", err)
@@ -335,7 +336,7 @@ func (h *SalesforceHandler) ListContacts(w http.ResponseWriter, r *http.Request)
 
 	data, err := h.Get("/services/data/v59.0/query?q=", query)
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
+		slog.ErrorContext(r.Context(), "ListContacts", "error", err)
 		return
 	}
 
@@ -343,7 +344,7 @@ func (h *SalesforceHandler) ListContacts(w http.ResponseWriter, r *http.Request)
 
 	err = json.Unmarshal(data, &response)
 	if err != nil {
-		fmt.Printf("[%v] Error: %s\n", time.Now().Format(time.RFC3339), err.Error())
+		slog.ErrorContext(r.Context(), "ListContacts", "error", err)
 	}
 
 	common.RespondJSON(w, http.StatusOK, data)
@@ -358,7 +359,7 @@ func (h *SalesforceHandler) GetContactById(w http.ResponseWriter, r *http.Reques
 
 	data, err := h.Get("/services/data/v59.0/query?q=", query)
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
+		slog.ErrorContext(r.Context(), "GetContactById", "error", err)
 		return
 	}
 
@@ -371,7 +372,7 @@ func (h *SalesforceHandler) GetContactById(w http.ResponseWriter, r *http.Reques
 
 	contact, err := json.Marshal(response.Records[0])
 	if err != nil {
-		fmt.Printf("[%v] Error: %s\n", time.Now().Format(time.RFC3339), err.Error())
+		slog.ErrorContext(r.Context(), "GetContactById", "error", err)
 	}
 
 	common.RespondJSON(w, http.StatusOK, contact)
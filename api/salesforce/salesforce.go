@@ -1,16 +1,16 @@
 package salesforce
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"log"
+	"log/slog"
 	"os"
-	"time"
 
 	"github.com/htstinson/stinsondataapi/api/salesforce/auth"
 	"github.com/htstinson/stinsondataapi/api/salesforce/handler"
 
-	common "github.com/htstinson/stinsondataapi/api/commonweb"
+	"github.com/htstinson/stinsondataapi/api/internal/secrets"
 )
 
 type Salesforce struct {
@@ -19,15 +19,15 @@ type Salesforce struct {
 	logger  *log.Logger
 }
 
-func New() (Salesforce, error) {
+func New(ctx context.Context, store secrets.Provider) (Salesforce, error) {
 
 	var salesforce = Salesforce{}
 	var logger = log.New(os.Stdout, "[API] ", log.LstdFlags)
 	var SalesforceCreds = &auth.SalesforceCreds{}
 
-	salesforceCreds, err := common.GetSecretString("Salesforce", "us-west-2")
+	salesforceCreds, err := store.GetSecret(ctx, "Salesforce")
 	if err != nil {
-		fmt.Printf("[%v] Error: Salesforce Creds %s\n", time.Now().Format(time.RFC3339), err.Error())
+		slog.Error("Salesforce creds", "error", err)
 		return salesforce, err
 	}
 	json.Unmarshal(salesforceCreds, &SalesforceCreds)
@@ -36,7 +36,7 @@ func New() (Salesforce, error) {
 
 	handler, err := handler.New(SalesforceCreds)
 	if err != nil {
-		fmt.Printf("[%v] Error: %s\n", time.Now().Format(time.RFC3339), err.Error())
+		slog.Error("New", "error", err)
 		return salesforce, err
 	}
 
@@ -1,8 +1,7 @@
 package salesforcetime
 
 import (
-	"fmt"
-
+	"log/slog"
 	"strings"
 	"time"
 )
@@ -24,7 +23,7 @@ func (st *SalesforceTime) UnmarshalJSON(data []byte) error {
 			t, err = time.Parse("2006-01-02", str)
 			if err != nil {
 
-				fmt.Printf("[%v] ********* error parsing time ************* %s\n", time.Now().Format(time.RFC3339), err.Error())
+				slog.Error("error parsing time", "error", err)
 				*st = SalesforceTime{}
 				//return err
 				return nil